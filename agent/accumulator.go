@@ -18,6 +18,12 @@ type MetricMaker interface {
 	MakeMetric(metric telegraf.Metric) telegraf.Metric
 }
 
+// errorCounter is a MetricMaker counting the errors added to its
+// accumulator.
+type errorCounter interface {
+	IncrErrors()
+}
+
 type accumulator struct {
 	maker     MetricMaker
 	metrics   chan<- telegraf.Metric
@@ -110,6 +116,9 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
+	if counter, ok := ac.maker.(errorCounter); ok {
+		counter.IncrErrors()
+	}
 	log.Printf("E! [%s]: Error in plugin: %v", ac.maker.Name(), err)
 }
 
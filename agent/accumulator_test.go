@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -141,6 +143,54 @@ func TestAddTrackingMetricGroupEmpty(t *testing.T) {
 	}
 }
 
+func TestTrackingDeliveredAfterWrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		second    *slowOutput
+		delivered bool
+	}{
+		{name: "written by all outputs", second: &slowOutput{succeed: 1}, delivered: true},
+		{name: "write failed by an output", second: &slowOutput{fail: true}, delivered: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newReloadConfig(&initInput{}, "input", &slowOutput{succeed: 1}, "output")
+			c.Outputs = append(c.Outputs, models.NewRunningOutput("second",
+				tt.second, &models.OutputConfig{Name: "second"}, 0, 0))
+			a, err := NewAgent(c)
+			require.NoError(t, err)
+
+			src := make(chan telegraf.Metric, 1)
+			acc := NewAccumulator(&TestMetricMaker{}, src).WithTracking(1)
+			m, err := metric.New("cpu", nil, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+			require.NoError(t, err)
+			id := acc.AddTrackingMetric(m)
+
+			done := make(chan error)
+			go func() {
+				done <- a.runOutputs(time.Now(), src)
+			}()
+
+			// the outputs are flushed hourly, so only write on shutdown
+			select {
+			case <-acc.Delivered():
+				t.Fatal("metric delivered before being written")
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			close(src)
+			require.NoError(t, <-done)
+			select {
+			case info := <-acc.Delivered():
+				require.Equal(t, id, info.ID())
+				require.Equal(t, tt.delivered, info.Delivered())
+			default:
+				t.Fatal("metric not delivered after shutdown")
+			}
+		})
+	}
+}
+
 type TestMetricMaker struct {
 }
 
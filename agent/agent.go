@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +19,25 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// State handed over from the previous agent on reload, see Reload.
+	initialized bool
+	reused      map[*models.RunningInput]bool
+	connected   map[*models.RunningOutput]bool
+	drain       map[*models.RunningOutput]*models.RunningOutput
+	// deadLetters are the outputs of the dead letter queues sending to
+	// another output, set on the queues when the agent starts.
+	deadLetters map[*models.DeadLetterQueue]*models.RunningOutput
+
+	// Outputs handed over to the next agent, they are not closed on shutdown.
+	retained map[*models.RunningOutput]bool
+	// Outputs still writing at the shutdown flush timeout, they are not
+	// closed either.
+	cutOff map[*models.RunningOutput]bool
+
+	// Health is told when the plugins are started and the outputs flushed,
+	// it is optional.
+	Health *Health
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -39,12 +59,24 @@ func (a *Agent) Run(ctx context.Context) error {
 		return ctx.Err()
 	}
 
+	if !a.initialized {
+		log.Printf("D! [agent] Initializing plugins")
+		err := a.initPlugins()
+		if err != nil {
+			return err
+		}
+	}
+	a.takeOverPlugins()
+	defer a.closeProcessors()
+
 	log.Printf("D! [agent] Connecting outputs")
 	err := a.connectOutputs(ctx)
 	if err != nil {
 		return err
 	}
 
+	a.drainOutputs()
+
 	inputC := make(chan telegraf.Metric, 100)
 	procC := make(chan telegraf.Metric, 100)
 	outputC := make(chan telegraf.Metric, 100)
@@ -57,6 +89,9 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	a.Health.start(a.Config.Outputs)
+	defer a.Health.stop()
+
 	var wg sync.WaitGroup
 
 	src := inputC
@@ -139,6 +174,11 @@ func (a *Agent) Run(ctx context.Context) error {
 
 // Test runs the inputs once and prints the output to stdout in line protocol.
 func (a *Agent) Test(ctx context.Context) error {
+	if err := a.initPlugins(); err != nil {
+		return err
+	}
+	defer a.closeProcessors()
+
 	var wg sync.WaitGroup
 	metricC := make(chan telegraf.Metric)
 	nulC := make(chan telegraf.Metric)
@@ -212,6 +252,126 @@ func (a *Agent) Test(ctx context.Context) error {
 	return nil
 }
 
+// initPlugins resolves the secrets of all plugins and runs the Init
+// function on the plugins that implement it.  Plugins reused from a previous
+// agent are already initialized.
+func (a *Agent) initPlugins() error {
+	for _, input := range a.Config.Inputs {
+		if a.reused[input] {
+			continue
+		}
+		if err := a.resolveSecrets(input.Input); err != nil {
+			return fmt.Errorf("could not resolve secrets of %s: %v",
+				input.Name(), err)
+		}
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize %s: %v",
+				input.Name(), err)
+		}
+	}
+	for _, processor := range a.Config.Processors {
+		if err := a.resolveSecrets(processor.Processor); err != nil {
+			return fmt.Errorf("could not resolve secrets of processors.%s: %v",
+				processor.Name, err)
+		}
+		if err := processor.Init(); err != nil {
+			return fmt.Errorf("could not initialize processors.%s: %v",
+				processor.Name, err)
+		}
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		if err := a.resolveSecrets(aggregator.Aggregator); err != nil {
+			return fmt.Errorf("could not resolve secrets of %s: %v",
+				aggregator.Name(), err)
+		}
+		if err := aggregator.Init(); err != nil {
+			return fmt.Errorf("could not initialize %s: %v",
+				aggregator.Name(), err)
+		}
+	}
+	for _, output := range a.Config.Outputs {
+		if a.connected[output] {
+			continue
+		}
+		if err := a.resolveSecrets(output.Output); err != nil {
+			return fmt.Errorf("could not resolve secrets of outputs.%s: %v",
+				output.Name, err)
+		}
+		if err := output.Init(); err != nil {
+			return fmt.Errorf("could not initialize outputs.%s: %v",
+				output.Name, err)
+		}
+	}
+	return a.resolveDeadLetterOutputs()
+}
+
+// resolveDeadLetterOutputs finds the outputs of the dead letter queues
+// sending to another output.  The name must match exactly one other output,
+// and the queues must not send the metrics back to an output they come from.
+func (a *Agent) resolveDeadLetterOutputs() error {
+	a.deadLetters = make(map[*models.DeadLetterQueue]*models.RunningOutput)
+	targets := make(map[*models.RunningOutput]*models.RunningOutput)
+	for _, output := range a.Config.Outputs {
+		queue := output.Config.DeadLetter
+		if queue == nil || queue.OutputName == "" {
+			continue
+		}
+		var matches []*models.RunningOutput
+		for _, other := range a.Config.Outputs {
+			if other != output && other.Name == queue.OutputName {
+				matches = append(matches, other)
+			}
+		}
+		switch {
+		case len(matches) == 0:
+			return fmt.Errorf("outputs.%s: dead_letter_queue_output %q not found",
+				output.Name, queue.OutputName)
+		case len(matches) > 1:
+			return fmt.Errorf("outputs.%s: dead_letter_queue_output %q is ambiguous, %d outputs have this name",
+				output.Name, queue.OutputName, len(matches))
+		}
+		a.deadLetters[queue] = matches[0]
+		targets[output] = matches[0]
+	}
+
+	for _, output := range a.Config.Outputs {
+		seen := map[*models.RunningOutput]bool{output: true}
+		chain := []string{"outputs." + output.Name}
+		for next := targets[output]; next != nil; next = targets[next] {
+			chain = append(chain, "outputs."+next.Name)
+			if seen[next] {
+				return fmt.Errorf("dead letter queues form a cycle: %s",
+					strings.Join(chain, " -> "))
+			}
+			seen[next] = true
+		}
+	}
+	return nil
+}
+
+// takeOverPlugins applies the configuration to the plugins that may be
+// shared with the previous agent on reload: it sets the default tags of the
+// reused inputs and the outputs of the dead letter queues.  The previous
+// agent must have stopped, as it gathers and writes with these plugins until
+// then.
+func (a *Agent) takeOverPlugins() {
+	for _, input := range a.Config.Inputs {
+		if a.reused[input] {
+			input.SetDefaultTags(a.Config.Tags)
+		}
+	}
+	for queue, output := range a.deadLetters {
+		queue.Output = output
+	}
+}
+
+func (a *Agent) resolveSecrets(plugin interface{}) error {
+	if a.Config.Secrets == nil {
+		return nil
+	}
+	return a.Config.Secrets.ResolveStruct(plugin)
+}
+
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
@@ -225,13 +385,14 @@ func (a *Agent) runInputs(
 	for _, input := range a.Config.Inputs {
 		interval := a.Config.Agent.Interval.Duration
 		precision := a.Config.Agent.Precision.Duration
-		jitter := a.Config.Agent.CollectionJitter.Duration
 
 		// Overwrite agent interval if this plugin has its own.
 		if input.Config.Interval != 0 {
 			interval = input.Config.Interval
 		}
 
+		jitter := collectionJitter(a.Config.Agent.CollectionJitter.Duration, input, interval)
+
 		acc := NewAccumulator(input, dst)
 		acc.SetPrecision(precision, interval)
 
@@ -255,6 +416,26 @@ func (a *Agent) runInputs(
 	return nil
 }
 
+// collectionJitter returns the jitter of the collection of the input, its own
+// collection_jitter if set or else the one of the agent.  The jitter is
+// bounded by the interval so that a collection is never skipped.
+func collectionJitter(
+	agentJitter time.Duration,
+	input *models.RunningInput,
+	interval time.Duration,
+) time.Duration {
+	jitter := agentJitter
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
+	if jitter > interval {
+		log.Printf("W! [agent] input %q collection_jitter %s is larger than its interval, using %s",
+			input.Name(), jitter, interval)
+		jitter = interval
+	}
+	return jitter
+}
+
 // gather runs an input's gather function periodically until the context is
 // done.
 func (a *Agent) gatherOnInterval(
@@ -269,15 +450,26 @@ func (a *Agent) gatherOnInterval(
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// the errors of the service inputs are not caused by their gathers
+	threshold := a.Config.Agent.GatherErrorThreshold
+	if _, ok := input.Input.(telegraf.ServiceInput); ok {
+		threshold = 0
+	}
+	breaker := newGatherBreaker(input, interval, threshold,
+		a.Config.Agent.GatherErrorMaxBackoff.Duration)
+
 	for {
-		err := internal.SleepContext(ctx, internal.RandomDuration(jitter))
-		if err != nil {
-			return
-		}
+		if breaker.allow() {
+			err := internal.SleepContext(ctx, internal.RandomDuration(jitter))
+			if err != nil {
+				return
+			}
 
-		err = a.gatherOnce(acc, input, interval)
-		if err != nil {
-			acc.AddError(err)
+			err = a.gatherOnce(acc, input, interval)
+			if err != nil {
+				acc.AddError(err)
+			}
+			breaker.done()
 		}
 
 		select {
@@ -331,6 +523,17 @@ func (a *Agent) runProcessors(
 	return nil
 }
 
+// closeProcessors closes the processors implementing io.Closer, once no
+// metrics are applied to them anymore.
+func (a *Agent) closeProcessors() {
+	for _, processor := range a.Config.Processors {
+		if err := processor.Close(); err != nil {
+			log.Printf("E! [agent] Error closing processors.%s: %v",
+				processor.Name, err)
+		}
+	}
+}
+
 // applyProcessors applies all processors to a metric.
 func (a *Agent) applyProcessors(m telegraf.Metric) []telegraf.Metric {
 	metrics := []telegraf.Metric{m}
@@ -366,6 +569,8 @@ func (a *Agent) runAggregators(
 
 			if !dropOriginal {
 				dst <- metric
+			} else {
+				metric.Drop()
 			}
 		}
 		cancel()
@@ -435,7 +640,8 @@ func (a *Agent) push(
 // runOutputs triggers the periodic write for Outputs.
 //
 // When the context is done, outputs continue to run until their buffer is
-// closed, afterwich they run flush once more.
+// closed, afterwich they run flush once more, for at most the
+// shutdown_flush_timeout.
 func (a *Agent) runOutputs(
 	startTime time.Time,
 	src <-chan telegraf.Metric,
@@ -445,7 +651,7 @@ func (a *Agent) runOutputs(
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	var wg sync.WaitGroup
+	flushes := make([]*finalFlush, 0, len(a.Config.Outputs))
 	for _, output := range a.Config.Outputs {
 		interval := interval
 		// Overwrite agent flush_interval if this plugin has its own.
@@ -453,9 +659,10 @@ func (a *Agent) runOutputs(
 			interval = output.Config.FlushInterval
 		}
 
-		wg.Add(1)
+		f := &finalFlush{output: output, done: make(chan struct{})}
+		flushes = append(flushes, f)
 		go func(output *models.RunningOutput) {
-			defer wg.Done()
+			defer close(f.done)
 
 			if a.Config.Agent.RoundInterval {
 				err := internal.SleepContext(
@@ -480,8 +687,18 @@ func (a *Agent) runOutputs(
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
+	for _, f := range flushes {
+		f.written, f.dropped = f.output.BufferCounts()
+	}
 	cancel()
-	wg.Wait()
+
+	a.cutOff = make(map[*models.RunningOutput]bool)
+	for _, r := range a.waitFlushes(flushes) {
+		r.log()
+		if r.cutOff {
+			a.cutOff[r.output] = true
+		}
+	}
 
 	return nil
 }
@@ -502,13 +719,16 @@ func (a *Agent) flush(
 	logError := func(err error) {
 		if err != nil {
 			log.Printf("E! [agent] Error writing to output [%s]: %v", output.Name, err)
+			return
 		}
+		a.Health.flushed(output)
 	}
 
 	for {
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
+			output.FlushRollup()
 			logError(a.flushOnce(output, interval, output.Write))
 			return
 		default:
@@ -526,6 +746,7 @@ func (a *Agent) flush(
 				logError(a.flushOnce(output, interval, output.WriteBatch))
 			}
 		case <-ctx.Done():
+			output.FlushRollup()
 			logError(a.flushOnce(output, interval, output.Write))
 			return
 		}
@@ -564,6 +785,10 @@ func (a *Agent) flushOnce(
 // connectOutputs connects to all outputs.
 func (a *Agent) connectOutputs(ctx context.Context) error {
 	for _, output := range a.Config.Outputs {
+		if a.connected[output] {
+			continue
+		}
+
 		log.Printf("D! [agent] Attempting connection to output: %s\n", output.Name)
 		err := output.Output.Connect()
 		if err != nil {
@@ -589,6 +814,9 @@ func (a *Agent) connectOutputs(ctx context.Context) error {
 func (a *Agent) closeOutputs() error {
 	var err error
 	for _, output := range a.Config.Outputs {
+		if a.retained[output] || a.cutOff[output] {
+			continue
+		}
 		err = output.Output.Close()
 	}
 	return err
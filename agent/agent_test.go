@@ -2,8 +2,12 @@ package agent
 
 import (
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -109,3 +113,125 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+func TestAgent_CollectionJitter(t *testing.T) {
+	input := models.NewRunningInput(nil, &models.InputConfig{Name: "cpu"})
+	assert.Equal(t, time.Second, collectionJitter(time.Second, input, 10*time.Second))
+
+	input.Config.CollectionJitter = 5 * time.Second
+	assert.Equal(t, 5*time.Second, collectionJitter(time.Second, input, 10*time.Second))
+	assert.Equal(t, 5*time.Second, collectionJitter(0, input, 10*time.Second))
+
+	// bounded by the interval
+	assert.Equal(t, 2*time.Second, collectionJitter(time.Second, input, 2*time.Second))
+}
+
+func TestAgent_ResolveDeadLetterOutputs(t *testing.T) {
+	queue, err := models.NewDeadLetterQueue("", "file", 0)
+	assert.NoError(t, err)
+	c := config.NewConfig()
+	source := models.NewRunningOutput("file", nil, &models.OutputConfig{DeadLetter: queue}, 0, 0)
+	c.Outputs = append(c.Outputs, source)
+	a := &Agent{Config: c}
+
+	// an output can not be its own dead letter queue
+	assert.Error(t, a.resolveDeadLetterOutputs())
+
+	sink := models.NewRunningOutput("file", nil, &models.OutputConfig{}, 0, 0)
+	c.Outputs = append(c.Outputs, sink)
+	assert.NoError(t, a.resolveDeadLetterOutputs())
+	assert.Nil(t, queue.Output)
+	a.takeOverPlugins()
+	assert.Equal(t, sink, queue.Output)
+}
+
+func TestAgent_ResolveDeadLetterOutputsAmbiguous(t *testing.T) {
+	queue, err := models.NewDeadLetterQueue("", "file", 0)
+	assert.NoError(t, err)
+	c := config.NewConfig()
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("influxdb", nil, &models.OutputConfig{DeadLetter: queue}, 0, 0),
+		models.NewRunningOutput("file", nil, &models.OutputConfig{}, 0, 0),
+		models.NewRunningOutput("file", nil, &models.OutputConfig{}, 0, 0))
+	a := &Agent{Config: c}
+
+	assert.EqualError(t, a.resolveDeadLetterOutputs(),
+		`outputs.influxdb: dead_letter_queue_output "file" is ambiguous, 2 outputs have this name`)
+}
+
+func TestAgent_ResolveDeadLetterOutputsCycle(t *testing.T) {
+	newOutput := func(name, deadLetter string) *models.RunningOutput {
+		queue, err := models.NewDeadLetterQueue("", deadLetter, 0)
+		assert.NoError(t, err)
+		return models.NewRunningOutput(name, nil, &models.OutputConfig{DeadLetter: queue}, 0, 0)
+	}
+
+	c := config.NewConfig()
+	c.Outputs = append(c.Outputs, newOutput("influxdb", "http"), newOutput("http", "influxdb"))
+	a := &Agent{Config: c}
+	assert.EqualError(t, a.resolveDeadLetterOutputs(),
+		"dead letter queues form a cycle: outputs.influxdb -> outputs.http -> outputs.influxdb")
+
+	// the cycle does not have to go through the first output
+	c = config.NewConfig()
+	c.Outputs = append(c.Outputs, newOutput("influxdb", "http"), newOutput("http", "kafka"),
+		newOutput("kafka", "http"))
+	a = &Agent{Config: c}
+	assert.EqualError(t, a.resolveDeadLetterOutputs(),
+		"dead letter queues form a cycle: outputs.influxdb -> outputs.http -> outputs.kafka -> outputs.http")
+
+	// chains are allowed
+	c = config.NewConfig()
+	c.Outputs = append(c.Outputs, newOutput("influxdb", "http"), newOutput("http", "file"),
+		models.NewRunningOutput("file", nil, &models.OutputConfig{}, 0, 0))
+	a = &Agent{Config: c}
+	assert.NoError(t, a.resolveDeadLetterOutputs())
+}
+
+func TestAgent_OutputFilters(t *testing.T) {
+	newOutput := func(output telegraf.Output, filter models.Filter) *models.RunningOutput {
+		assert.NoError(t, filter.Compile())
+		return models.NewRunningOutput("test", output,
+			&models.OutputConfig{Name: "test", Filter: filter}, 0, 0)
+	}
+
+	// the trimmed output is the first and the last one, the last output
+	// receiving the source metric rather than a copy
+	for _, trimmedFirst := range []bool{true, false} {
+		trimmed, full := &reloadOutput{}, &reloadOutput{}
+		c := config.NewConfig()
+		c.Agent.RoundInterval = false
+		c.Outputs = []*models.RunningOutput{
+			newOutput(trimmed, models.Filter{
+				FieldPass:  []string{"usage_*"},
+				TagInclude: []string{"host"},
+			}),
+			newOutput(full, models.Filter{}),
+		}
+		if !trimmedFirst {
+			c.Outputs[0], c.Outputs[1] = c.Outputs[1], c.Outputs[0]
+		}
+		a, err := NewAgent(c)
+		assert.NoError(t, err)
+
+		m, err := metric.New("cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_user": 1.5, "usage_idle": 98.5, "uptime": int64(60)},
+			time.Unix(0, 0))
+		assert.NoError(t, err)
+		src := make(chan telegraf.Metric, 1)
+		src <- m
+		close(src)
+		assert.NoError(t, a.runOutputs(time.Now(), src))
+
+		assert.Len(t, trimmed.written, 1)
+		assert.Equal(t, map[string]string{"host": "server01"}, trimmed.written[0].Tags())
+		assert.Equal(t, map[string]interface{}{"usage_user": 1.5, "usage_idle": 98.5},
+			trimmed.written[0].Fields())
+
+		assert.Len(t, full.written, 1)
+		assert.Equal(t, map[string]string{"host": "server01", "cpu": "cpu0"}, full.written[0].Tags())
+		assert.Equal(t, map[string]interface{}{"usage_user": 1.5, "usage_idle": 98.5, "uptime": int64(60)},
+			full.written[0].Fields())
+	}
+}
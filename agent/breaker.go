@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// gatherBreaker backs off the gathers of an input after threshold
+// consecutive failed gathers, skipping twice as many intervals after each
+// new failure up to maxBackoff, until a gather succeeds.  A gather failed if
+// the input reported an error during it.
+type gatherBreaker struct {
+	input      *models.RunningInput
+	interval   time.Duration
+	threshold  int
+	maxBackoff time.Duration
+
+	// errors is the error count of the input before the gather
+	errors   int64
+	failures int
+	backoff  time.Duration
+	// skip is the number of intervals left to skip
+	skip int
+}
+
+func newGatherBreaker(
+	input *models.RunningInput,
+	interval time.Duration,
+	threshold int,
+	maxBackoff time.Duration,
+) *gatherBreaker {
+	return &gatherBreaker{
+		input:      input,
+		interval:   interval,
+		threshold:  threshold,
+		maxBackoff: maxBackoff,
+	}
+}
+
+// allow returns true if the input is gathered on this interval.
+func (b *gatherBreaker) allow() bool {
+	if b.skip > 0 {
+		b.skip--
+		return false
+	}
+	b.errors = b.input.Errors()
+	return true
+}
+
+// done records the result of the gather allowed last.
+func (b *gatherBreaker) done() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	if b.input.Errors() == b.errors {
+		if b.backoff > 0 {
+			log.Printf("I! [agent] input %q recovered, gathering it every %s again",
+				b.input.Name(), b.interval)
+			b.input.Quarantined.Set(0)
+		}
+		b.failures = 0
+		b.backoff = 0
+		return
+	}
+
+	b.failures++
+	if b.failures < b.threshold {
+		return
+	}
+	if b.backoff == 0 {
+		b.backoff = b.interval
+	}
+	if b.backoff < b.maxBackoff {
+		b.backoff *= 2
+		if b.backoff > b.maxBackoff {
+			b.backoff = b.maxBackoff
+		}
+	}
+	if b.backoff <= b.interval {
+		return
+	}
+	b.skip = int(b.backoff/b.interval) - 1
+	b.input.Quarantined.Set(1)
+	log.Printf("W! [agent] input %q failed %d consecutive gathers, gathering it every %s",
+		b.input.Name(), b.failures, b.interval*time.Duration(b.skip+1))
+}
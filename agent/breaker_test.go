@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+// failingInput fails its gathers while fail is set, and counts them.
+type failingInput struct {
+	sync.Mutex
+	fail    bool
+	gathers int
+}
+
+func (i *failingInput) SampleConfig() string { return "" }
+func (i *failingInput) Description() string  { return "" }
+func (i *failingInput) Gather(acc telegraf.Accumulator) error {
+	i.Lock()
+	defer i.Unlock()
+	i.gathers++
+	if i.fail {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+// gatherPattern returns which of n intervals the breaker allowed to gather,
+// failing the gathers as given.
+func gatherPattern(b *gatherBreaker, n int, fail func(i int) bool) []bool {
+	var allowed []bool
+	for i := 0; i < n; i++ {
+		ok := b.allow()
+		allowed = append(allowed, ok)
+		if ok {
+			if fail(i) {
+				b.input.IncrErrors()
+			}
+			b.done()
+		}
+	}
+	return allowed
+}
+
+func TestGatherBreakerBackoff(t *testing.T) {
+	input := models.NewRunningInput(&failingInput{}, &models.InputConfig{Name: "breaker_backoff"})
+	b := newGatherBreaker(input, time.Second, 2, 4*time.Second)
+
+	// backed off after 2 failures, skipping 1 interval then 3 intervals at
+	// most
+	always := func(int) bool { return true }
+	require.Equal(t, []bool{
+		true, true, false, true, false, false, false, true, false, false, false, true,
+	}, gatherPattern(b, 12, always))
+	require.Equal(t, int64(1), input.Quarantined.Get())
+	require.Equal(t, int64(5), input.Errors())
+
+	// a successful gather recovers the interval
+	b.skip = 0
+	require.Equal(t, []bool{true, true, true}, gatherPattern(b, 3, func(int) bool { return false }))
+	require.Equal(t, int64(0), input.Quarantined.Get())
+
+	// the consecutive failures are counted again
+	require.Equal(t, []bool{true, true, true, false}, gatherPattern(b, 4, func(i int) bool { return i > 0 }))
+}
+
+func TestGatherBreakerDisabled(t *testing.T) {
+	input := models.NewRunningInput(&failingInput{}, &models.InputConfig{Name: "breaker_disabled"})
+	b := newGatherBreaker(input, time.Second, 0, time.Minute)
+
+	always := func(int) bool { return true }
+	require.Equal(t, []bool{true, true, true, true}, gatherPattern(b, 4, always))
+	require.Equal(t, int64(0), input.Quarantined.Get())
+}
+
+func TestGatherOnIntervalBackoff(t *testing.T) {
+	c := newReloadConfig(&initInput{}, "input", &reloadOutput{}, "output")
+	c.Agent.GatherErrorThreshold = 1
+	c.Agent.GatherErrorMaxBackoff.Duration = time.Hour
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	failing := &failingInput{fail: true}
+	healthy := &failingInput{}
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, input := range []*failingInput{failing, healthy} {
+		wg.Add(1)
+		go func(input *failingInput) {
+			defer wg.Done()
+			ri := models.NewRunningInput(input, &models.InputConfig{Name: "breaker_interval"})
+			acc := NewAccumulator(ri, make(chan telegraf.Metric, 100))
+			a.gatherOnInterval(ctx, acc, ri, 20*time.Millisecond, 0)
+		}(input)
+	}
+	wg.Wait()
+
+	// about 20 intervals, the failing input is gathered on the intervals 0, 2,
+	// 6 and 14 only, the other input is not affected
+	failing.Lock()
+	defer failing.Unlock()
+	healthy.Lock()
+	defer healthy.Unlock()
+	require.True(t, failing.gathers >= 3 && failing.gathers <= 4, "%d gathers", failing.gathers)
+	require.True(t, healthy.gathers >= 15, "%d gathers", healthy.gathers)
+}
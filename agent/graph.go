@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// TestGraph initializes all plugins and prints the resolved routing of
+// metrics from the inputs through the processors and aggregators to the
+// outputs.  If sample is not nil, the result of each plugin filter for the
+// sample metric is printed as well.
+//
+// No plugin is started or connected, so nothing is gathered or written.  If
+// any plugin fails to initialize its error is returned and nothing is printed.
+func (a *Agent) TestGraph(w io.Writer, sample telegraf.Metric) error {
+	err := a.initPlugins()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "Inputs:")
+	for _, input := range a.Config.Inputs {
+		interval := a.Config.Agent.Interval.Duration
+		if input.Config.Interval != 0 {
+			interval = input.Config.Interval
+		}
+		fmt.Fprintf(w, "  %s (interval: %s)%s\n", input.Name(), interval,
+			filterResult(&input.Config.Filter, sample))
+	}
+
+	fmt.Fprintln(w, "Processors:")
+	for _, processor := range a.Config.Processors {
+		fmt.Fprintf(w, "  processors.%s (order: %d)%s\n", processor.Name,
+			processor.Config.Order, filterResult(&processor.Config.Filter, sample))
+	}
+
+	fmt.Fprintln(w, "Aggregators:")
+	for _, aggregator := range a.Config.Aggregators {
+		fmt.Fprintf(w, "  %s (period: %s, drop_original: %t)%s\n",
+			aggregator.Name(), aggregator.Period(),
+			aggregator.Config.DropOriginal,
+			filterResult(&aggregator.Config.Filter, sample))
+	}
+
+	fmt.Fprintln(w, "Outputs:")
+	for _, output := range a.Config.Outputs {
+		fmt.Fprintf(w, "  outputs.%s%s\n", output.Name,
+			filterResult(&output.Config.Filter, sample))
+	}
+
+	return nil
+}
+
+// filterResult describes if the sample metric passes the filter.
+func filterResult(filter *models.Filter, sample telegraf.Metric) string {
+	if sample == nil {
+		return ""
+	}
+	if filter.Select(sample) {
+		return ": sample metric accepted"
+	}
+	return ": sample metric rejected"
+}
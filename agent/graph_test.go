@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+type initInput struct {
+	err error
+}
+
+func (i *initInput) SampleConfig() string              { return "" }
+func (i *initInput) Description() string               { return "" }
+func (i *initInput) Gather(telegraf.Accumulator) error { return nil }
+func (i *initInput) Init() error                       { return i.err }
+
+type graphOutput struct {
+	connected bool
+}
+
+func (o *graphOutput) SampleConfig() string            { return "" }
+func (o *graphOutput) Description() string             { return "" }
+func (o *graphOutput) Connect() error                  { o.connected = true; return nil }
+func (o *graphOutput) Close() error                    { return nil }
+func (o *graphOutput) Write(_ []telegraf.Metric) error { return nil }
+
+func newGraphAgent(t *testing.T, input telegraf.Input, output telegraf.Output) *Agent {
+	c := config.NewConfig()
+	c.Agent.Interval.Duration = 10 * time.Second
+
+	c.Inputs = append(c.Inputs, models.NewRunningInput(input,
+		&models.InputConfig{Name: "test"}))
+
+	outputFilter := models.Filter{NamePass: []string{"cpu"}}
+	require.NoError(t, outputFilter.Compile())
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("test", output,
+		&models.OutputConfig{Name: "test", Filter: outputFilter}, 0, 0))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	return a
+}
+
+func TestGraph(t *testing.T) {
+	output := &graphOutput{}
+	a := newGraphAgent(t, &initInput{}, output)
+
+	sample, err := metric.New("cpu", nil,
+		map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = a.TestGraph(&buf, sample)
+	require.NoError(t, err)
+	require.False(t, output.connected)
+
+	expected := "Inputs:\n" +
+		"  inputs.test (interval: 10s): sample metric accepted\n" +
+		"Processors:\n" +
+		"Aggregators:\n" +
+		"Outputs:\n" +
+		"  outputs.test: sample metric accepted\n"
+	require.Equal(t, expected, buf.String())
+}
+
+func TestGraphFilterRejected(t *testing.T) {
+	a := newGraphAgent(t, &initInput{}, &graphOutput{})
+
+	sample, err := metric.New("mem", nil,
+		map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = a.TestGraph(&buf, sample)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "  outputs.test: sample metric rejected\n")
+}
+
+func TestGraphInitError(t *testing.T) {
+	a := newGraphAgent(t, &initInput{err: errors.New("missing server")}, &graphOutput{})
+
+	var buf bytes.Buffer
+	err := a.TestGraph(&buf, nil)
+	require.EqualError(t, err, "could not initialize inputs.test: missing server")
+	require.Empty(t, buf.String())
+}
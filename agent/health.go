@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// Health serves the liveness and readiness of Telegraf over HTTP.  It is
+// shared by the agents created on reload, so the probes keep answering while
+// the configuration is reloaded.
+//
+// /healthz answers as long as the process runs.  /ready answers once the
+// agent started all of its plugins and, unless disabled with SetRequireWrite,
+// every output flushed successfully at least once.
+type Health struct {
+	mu sync.Mutex
+	// requireWrite delays readiness until every output flushed once
+	requireWrite bool
+	started      bool
+	// pending are the outputs that did not flush successfully yet
+	pending map[*models.RunningOutput]bool
+
+	address  string
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewHealth returns a Health that is not ready.
+func NewHealth() *Health {
+	return &Health{requireWrite: true}
+}
+
+// SetRequireWrite sets if the outputs must flush once before being ready.
+func (h *Health) SetRequireWrite(requireWrite bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requireWrite = requireWrite
+}
+
+// Listen serves the endpoints on the address, or stops serving them if the
+// address is empty.  Calling it again with the same address does nothing.
+func (h *Health) Listen(address string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if address == h.address {
+		return nil
+	}
+	h.close()
+	if address == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %v", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.serveHealthz)
+	mux.HandleFunc("/ready", h.serveReady)
+	h.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	h.listener = listener
+	h.address = address
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! [agent] Error serving health endpoints: %v", err)
+		}
+	}(h.server)
+	log.Printf("I! [agent] Serving health endpoints on %s", listener.Addr())
+	return nil
+}
+
+// Close stops serving the endpoints.
+func (h *Health) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.close()
+}
+
+func (h *Health) close() {
+	if h.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h.server.Shutdown(ctx)
+	h.server = nil
+	h.listener = nil
+	h.address = ""
+}
+
+// Addr returns the address the endpoints are served on, or nil.
+func (h *Health) Addr() net.Addr {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.listener == nil {
+		return nil
+	}
+	return h.listener.Addr()
+}
+
+// start marks the plugins of an agent as started, with the outputs that
+// still have to flush.
+func (h *Health) start(outputs []*models.RunningOutput) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started = true
+	h.pending = make(map[*models.RunningOutput]bool)
+	for _, output := range outputs {
+		h.pending[output] = true
+	}
+}
+
+// stop marks the agent as stopped, it is not ready until the next agent
+// started.
+func (h *Health) stop() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started = false
+	h.pending = nil
+}
+
+// flushed records a successful flush of the output.
+func (h *Health) flushed(output *models.RunningOutput) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.pending, output)
+}
+
+// Ready returns nil if Telegraf is ready, or the reason it is not.
+func (h *Health) Ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.started {
+		return fmt.Errorf("plugins not started")
+	}
+	if h.requireWrite && len(h.pending) > 0 {
+		return fmt.Errorf("%d of the outputs did not write yet", len(h.pending))
+	}
+	return nil
+}
+
+func (h *Health) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, "ok\n")
+}
+
+func (h *Health) serveReady(w http.ResponseWriter, r *http.Request) {
+	if err := h.Ready(); err != nil {
+		http.Error(w, "not ready: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprint(w, "ready\n")
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func getHealth(t *testing.T, h *Health, path string) (int, string) {
+	resp, err := http.Get("http://" + h.Addr().String() + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	h := NewHealth()
+	require.NoError(t, h.Listen("localhost:0"))
+	defer h.Close()
+
+	status, body := getHealth(t, h, "/healthz")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "ok\n", body)
+
+	status, body = getHealth(t, h, "/ready")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "not ready: plugins not started\n", body)
+
+	first := &models.RunningOutput{Name: "first"}
+	second := &models.RunningOutput{Name: "second"}
+	h.start([]*models.RunningOutput{first, second})
+	status, body = getHealth(t, h, "/ready")
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "not ready: 2 of the outputs did not write yet\n", body)
+
+	h.flushed(first)
+	h.flushed(first)
+	require.EqualError(t, h.Ready(), "1 of the outputs did not write yet")
+
+	h.flushed(second)
+	status, body = getHealth(t, h, "/ready")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "ready\n", body)
+
+	h.stop()
+	require.EqualError(t, h.Ready(), "plugins not started")
+
+	// the same address keeps the server, an empty one stops it
+	addr := h.Addr()
+	require.NoError(t, h.Listen("localhost:0"))
+	require.Equal(t, addr, h.Addr())
+	require.NoError(t, h.Listen(""))
+	require.Nil(t, h.Addr())
+}
+
+func TestHealthWithoutRequireWrite(t *testing.T) {
+	h := NewHealth()
+	h.SetRequireWrite(false)
+	require.EqualError(t, h.Ready(), "plugins not started")
+
+	h.start([]*models.RunningOutput{{Name: "test"}})
+	require.NoError(t, h.Ready())
+}
+
+func TestAgentHealth(t *testing.T) {
+	output := &reloadOutput{}
+	c := newReloadConfig(&initInput{}, "input", output, "output")
+	c.Agent.FlushInterval.Duration = 10 * time.Millisecond
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	a.Health = NewHealth()
+	require.EqualError(t, a.Health.Ready(), "plugins not started")
+
+	addReloadMetrics(t, a.Config.Outputs[0], 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for a.Health.Ready() != nil {
+		require.True(t, time.Now().Before(deadline), "agent not ready")
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+	require.EqualError(t, a.Health.Ready(), "plugins not started")
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/secret"
+)
+
+// Reload returns a new Agent for the config c that takes over the plugins of
+// the running agent whose configuration did not change:
+//
+// - Unchanged inputs are reused and keep their state, service inputs are
+// always restarted.
+// - Plugins referencing secrets are always replaced, so that their secrets
+// are resolved again.  Such outputs reconnect on every reload and are handled
+// like changed outputs.
+// - Unchanged outputs are reused, they stay connected and keep the metrics in
+// their buffer.
+// - The metrics remaining in the buffer of a changed output are moved into
+// the new instance of the output when the new Agent is started.
+//
+// All new plugins are initialized before anything is handed over, if any of
+// them fails the running agent is left untouched and an error is returned.
+// Reload must be called while the running agent is still running, the new
+// Agent must only be started once Run of the running agent returned.  The
+// changes to the reused plugins, like their default tags and dead letter
+// queues, are only applied then.
+func (a *Agent) Reload(c *config.Config) (*Agent, error) {
+	next, err := NewAgent(c)
+	if err != nil {
+		return nil, err
+	}
+
+	next.reused = make(map[*models.RunningInput]bool)
+	next.connected = make(map[*models.RunningOutput]bool)
+	next.drain = make(map[*models.RunningOutput]*models.RunningOutput)
+
+	inputs := make([]*models.RunningInput, len(c.Inputs))
+	copy(inputs, c.Inputs)
+	for _, old := range a.Config.Inputs {
+		if _, ok := old.Input.(telegraf.ServiceInput); ok {
+			continue
+		}
+		if secret.HasReferences(old.Fingerprint) {
+			continue
+		}
+		for i, input := range inputs {
+			if !next.reused[input] && input.Fingerprint == old.Fingerprint {
+				inputs[i] = old
+				next.reused[old] = true
+				break
+			}
+		}
+	}
+
+	outputs := make([]*models.RunningOutput, len(c.Outputs))
+	copy(outputs, c.Outputs)
+	var changed []*models.RunningOutput
+	for _, old := range a.Config.Outputs {
+		if secret.HasReferences(old.Fingerprint) {
+			changed = append(changed, old)
+			continue
+		}
+		found := false
+		for i, output := range outputs {
+			if !next.connected[output] && output.Fingerprint == old.Fingerprint {
+				outputs[i] = old
+				next.connected[old] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			changed = append(changed, old)
+		}
+	}
+
+	// Changed outputs hand their buffer over to a new output of the same
+	// plugin, if there is one.
+	taken := make(map[*models.RunningOutput]bool)
+	for _, old := range changed {
+		for _, output := range outputs {
+			if next.connected[output] || taken[output] || output.Name != old.Name {
+				continue
+			}
+			next.drain[old] = output
+			taken[output] = true
+			break
+		}
+	}
+
+	c.Inputs = inputs
+	c.Outputs = outputs
+	if err := next.initPlugins(); err != nil {
+		next.closeProcessors()
+		return nil, err
+	}
+	next.initialized = true
+
+	a.retained = make(map[*models.RunningOutput]bool)
+	for output := range next.connected {
+		a.retained[output] = true
+	}
+
+	log.Printf("I! [agent] Reusing %d of %d inputs and %d of %d outputs",
+		len(next.reused), len(c.Inputs), len(next.connected), len(c.Outputs))
+	return next, nil
+}
+
+// drainOutputs moves the metrics remaining in the buffer of changed outputs
+// of the previous agent into their replacement.
+func (a *Agent) drainOutputs() {
+	for old, output := range a.drain {
+		metrics := old.Drain()
+		for _, metric := range metrics {
+			output.AddMetric(metric)
+		}
+		if len(metrics) > 0 {
+			log.Printf("I! [agent] Moved %d buffered metrics from the previous "+
+				"instance of output %s", len(metrics), output.Name)
+		}
+	}
+	a.drain = nil
+}
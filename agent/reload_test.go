@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+type reloadOutput struct {
+	connects int
+	closes   int
+	written  []telegraf.Metric
+	fail     bool
+}
+
+func (o *reloadOutput) SampleConfig() string { return "" }
+func (o *reloadOutput) Description() string  { return "" }
+func (o *reloadOutput) Connect() error       { o.connects++; return nil }
+func (o *reloadOutput) Close() error         { o.closes++; return nil }
+func (o *reloadOutput) Write(metrics []telegraf.Metric) error {
+	if o.fail {
+		return errors.New("write failed")
+	}
+	o.written = append(o.written, metrics...)
+	return nil
+}
+
+func newReloadConfig(
+	input telegraf.Input,
+	inputFingerprint string,
+	output telegraf.Output,
+	outputFingerprint string,
+) *config.Config {
+	c := config.NewConfig()
+	c.Agent.Interval.Duration = time.Hour
+	c.Agent.FlushInterval.Duration = time.Hour
+	c.Agent.RoundInterval = false
+
+	ri := models.NewRunningInput(input, &models.InputConfig{Name: "test"})
+	ri.Fingerprint = inputFingerprint
+	c.Inputs = append(c.Inputs, ri)
+
+	ro := models.NewRunningOutput("test", output,
+		&models.OutputConfig{Name: "test"}, 0, 0)
+	ro.Fingerprint = outputFingerprint
+	c.Outputs = append(c.Outputs, ro)
+	return c
+}
+
+func addReloadMetrics(t *testing.T, output *models.RunningOutput, n int) {
+	for i := 0; i < n; i++ {
+		m, err := metric.New("cpu", nil,
+			map[string]interface{}{"value": i}, time.Unix(int64(i), 0))
+		require.NoError(t, err)
+		output.AddMetric(m)
+	}
+}
+
+// runAndStop runs the agent for a short time and stops it.
+func runAndStop(t *testing.T, a *Agent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := a.Run(ctx)
+	require.NoError(t, err)
+}
+
+func TestReloadUnchangedOutputRetainsBuffer(t *testing.T) {
+	input := &initInput{}
+	output := &reloadOutput{fail: true}
+	a, err := NewAgent(newReloadConfig(input, "input", output, "output"))
+	require.NoError(t, err)
+	require.NoError(t, a.connectOutputs(context.Background()))
+
+	running := a.Config.Outputs[0]
+	addReloadMetrics(t, running, 3)
+	require.Error(t, running.Write())
+
+	next, err := a.Reload(newReloadConfig(&initInput{}, "input", &reloadOutput{}, "output"))
+	require.NoError(t, err)
+
+	require.Equal(t, input, next.Config.Inputs[0].Input)
+	require.Equal(t, running, next.Config.Outputs[0])
+
+	require.NoError(t, a.closeOutputs())
+	require.Equal(t, 0, output.closes)
+
+	require.NoError(t, next.connectOutputs(context.Background()))
+	require.Equal(t, 1, output.connects)
+	require.Equal(t, 3, next.Config.Outputs[0].BufferLength())
+
+	output.fail = false
+	require.NoError(t, next.Config.Outputs[0].Write())
+	require.Len(t, output.written, 3)
+}
+
+func TestReloadChangedOutputDrainsBuffer(t *testing.T) {
+	output := &reloadOutput{fail: true}
+	a, err := NewAgent(newReloadConfig(&initInput{}, "input", output, "output"))
+	require.NoError(t, err)
+
+	addReloadMetrics(t, a.Config.Outputs[0], 3)
+
+	changed := &reloadOutput{}
+	next, err := a.Reload(newReloadConfig(&initInput{}, "input", changed, "changed"))
+	require.NoError(t, err)
+	require.Equal(t, changed, next.Config.Outputs[0].Output)
+
+	require.NoError(t, a.closeOutputs())
+	require.Equal(t, 1, output.closes)
+
+	runAndStop(t, next)
+	require.Equal(t, 1, changed.connects)
+	require.Len(t, changed.written, 3)
+	require.Equal(t, 0, a.Config.Outputs[0].BufferLength())
+}
+
+// hostTag returns the host default tag the input adds to its metrics.
+func hostTag(t *testing.T, input *models.RunningInput) string {
+	m, err := metric.New("cpu", nil, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.NoError(t, err)
+	return input.MakeMetric(m).Tags()["host"]
+}
+
+func TestReloadAppliesChangesOnStart(t *testing.T) {
+	newConfig := func(host, sinkFingerprint string) *config.Config {
+		c := newReloadConfig(&initInput{}, "input", &reloadOutput{}, "output")
+		c.Tags = map[string]string{"host": host}
+		c.Inputs[0].SetDefaultTags(c.Tags)
+
+		queue, err := models.NewDeadLetterQueue("", "sink", 0)
+		require.NoError(t, err)
+		c.Outputs[0].Config.DeadLetter = queue
+		sink := models.NewRunningOutput("sink", &reloadOutput{},
+			&models.OutputConfig{Name: "sink"}, 0, 0)
+		sink.Fingerprint = sinkFingerprint
+		c.Outputs = append(c.Outputs, sink)
+		return c
+	}
+
+	a, err := NewAgent(newConfig("old", "sink"))
+	require.NoError(t, err)
+	require.NoError(t, a.initPlugins())
+	a.takeOverPlugins()
+	input := a.Config.Inputs[0]
+	queue := a.Config.Outputs[0].Config.DeadLetter
+	oldSink := a.Config.Outputs[1]
+	require.Equal(t, oldSink, queue.Output)
+
+	next, err := a.Reload(newConfig("new", "changed"))
+	require.NoError(t, err)
+	require.Equal(t, input, next.Config.Inputs[0])
+	require.Equal(t, queue, next.Config.Outputs[0].Config.DeadLetter)
+
+	// the running agent still uses the reused plugins as configured before
+	require.Equal(t, "old", hostTag(t, input))
+	require.Equal(t, oldSink, queue.Output)
+
+	runAndStop(t, next)
+	require.Equal(t, "new", hostTag(t, input))
+	require.Equal(t, next.Config.Outputs[1], queue.Output)
+}
+
+func TestReloadChangedInput(t *testing.T) {
+	input := &initInput{}
+	a, err := NewAgent(newReloadConfig(input, "input", &reloadOutput{}, "output"))
+	require.NoError(t, err)
+
+	changed := &initInput{}
+	next, err := a.Reload(newReloadConfig(changed, "changed", &reloadOutput{}, "output"))
+	require.NoError(t, err)
+	require.Equal(t, changed, next.Config.Inputs[0].Input)
+}
+
+func TestReloadInitErrorKeepsRunningConfig(t *testing.T) {
+	output := &reloadOutput{}
+	a, err := NewAgent(newReloadConfig(&initInput{}, "input", output, "output"))
+	require.NoError(t, err)
+
+	broken := &initInput{err: errors.New("missing server")}
+	_, err = a.Reload(newReloadConfig(broken, "changed", &reloadOutput{}, "output"))
+	require.EqualError(t, err, "could not initialize inputs.test: missing server")
+
+	require.NoError(t, a.closeOutputs())
+	require.Equal(t, 1, output.closes)
+}
+
+type secretInput struct {
+	initInput
+	Password string
+}
+
+func TestReloadResolvesSecretsAgain(t *testing.T) {
+	os.Setenv("TEST_AGENT_PASSWORD", "first-password")
+	defer os.Unsetenv("TEST_AGENT_PASSWORD")
+
+	fingerprint := `input:{"password"="@{secret:env:TEST_AGENT_PASSWORD}"}`
+	input := &secretInput{Password: "@{secret:env:TEST_AGENT_PASSWORD}"}
+	a, err := NewAgent(newReloadConfig(input, fingerprint, &reloadOutput{}, "output"))
+	require.NoError(t, err)
+	require.NoError(t, a.initPlugins())
+	require.Equal(t, "first-password", input.Password)
+
+	os.Setenv("TEST_AGENT_PASSWORD", "second-password")
+	changed := &secretInput{Password: "@{secret:env:TEST_AGENT_PASSWORD}"}
+	next, err := a.Reload(newReloadConfig(changed, fingerprint, &reloadOutput{}, "output"))
+	require.NoError(t, err)
+	require.Equal(t, changed, next.Config.Inputs[0].Input)
+	require.Equal(t, "second-password", changed.Password)
+}
+
+func TestInitMissingSecret(t *testing.T) {
+	input := &secretInput{Password: "@{secret:env:TEST_AGENT_MISSING}"}
+	a, err := NewAgent(newReloadConfig(input, "input", &reloadOutput{}, "output"))
+	require.NoError(t, err)
+	require.EqualError(t, a.initPlugins(), "could not resolve secrets of inputs.test: "+
+		"Password: could not resolve @{secret:env:TEST_AGENT_MISSING}: "+
+		"environment variable TEST_AGENT_MISSING is not set")
+}
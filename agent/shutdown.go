@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// finalFlush is the flush of an output on shutdown, done once its channel is
+// closed.
+type finalFlush struct {
+	output *models.RunningOutput
+	done   chan struct{}
+
+	// counts of the output when the final flush started
+	written int64
+	dropped int64
+}
+
+// flushReport counts the metrics of an output during its final flush.
+type flushReport struct {
+	output  *models.RunningOutput
+	flushed int64
+	dropped int64
+	spilled int
+	// cutOff is true if the output was still writing at the deadline
+	cutOff bool
+}
+
+// waitFlushes waits for the final flush of the outputs, for at most the
+// shutdown_flush_timeout.  The metrics left in the buffers of the outputs,
+// as their final write failed or they are still writing, are spilled to the
+// shutdown_spill_dir if set and else dropped.
+//
+// The outputs handed over to a reloaded agent are always waited for and keep
+// their buffer.
+func (a *Agent) waitFlushes(flushes []*finalFlush) []*flushReport {
+	timeout := a.Config.Agent.ShutdownFlushTimeout.Duration
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	expired := false
+	for _, f := range flushes {
+		if expired {
+			break
+		}
+		select {
+		case <-f.done:
+		case <-deadline:
+			expired = true
+			log.Printf("W! [agent] Outputs did not flush within the shutdown flush timeout of %s",
+				timeout)
+		}
+	}
+
+	reports := make([]*flushReport, 0, len(flushes))
+	for _, f := range flushes {
+		reports = append(reports, a.finishFlush(f))
+	}
+	return reports
+}
+
+func (a *Agent) finishFlush(f *finalFlush) *flushReport {
+	r := &flushReport{output: f.output}
+	select {
+	case <-f.done:
+	default:
+		if a.retained[f.output] {
+			// the next agent must not write the output concurrently
+			log.Printf("I! [agent] Waiting for output %s handed over to the reloaded agent",
+				f.output.Name)
+			<-f.done
+		} else {
+			r.cutOff = true
+		}
+	}
+
+	var metrics []telegraf.Metric
+	pending := 0
+	if !a.retained[f.output] {
+		pending = f.output.BufferLength()
+		metrics = f.output.Drain()
+	}
+
+	written, dropped := f.output.BufferCounts()
+	r.flushed = written - f.written
+	// the metrics of a write still in progress are not in the buffer anymore
+	r.dropped = dropped - f.dropped + int64(pending-len(metrics))
+
+	if len(metrics) > 0 && a.Config.Agent.ShutdownSpillDir != "" {
+		n, err := a.spill(f.output, metrics)
+		if err != nil {
+			log.Printf("E! [agent] Could not spill the buffer of output %s: %v", f.output.Name, err)
+		}
+		r.spilled = n
+		metrics = metrics[n:]
+	}
+	for _, m := range metrics {
+		m.Reject()
+	}
+	r.dropped += int64(len(metrics))
+	return r
+}
+
+// spill appends the metrics in line protocol to the spill file of the
+// output, and returns the number written.
+func (a *Agent) spill(output *models.RunningOutput, metrics []telegraf.Metric) (int, error) {
+	filename := filepath.Join(a.Config.Agent.ShutdownSpillDir, output.Name+".influx")
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return 0, err
+	}
+
+	serializer := influx.NewSerializer()
+	for i, m := range metrics {
+		octets, err := serializer.Serialize(m)
+		if err == nil {
+			_, err = file.Write(octets)
+		}
+		if err != nil {
+			file.Close()
+			return i, fmt.Errorf("%s: %v", filename, err)
+		}
+		m.Accept()
+	}
+	return len(metrics), file.Close()
+}
+
+func (r *flushReport) log() {
+	level := "I!"
+	if r.cutOff || r.dropped > 0 {
+		level = "W!"
+	}
+	msg := fmt.Sprintf("%s [outputs.%s] Flushed %d metrics on shutdown, dropped %d metrics",
+		level, r.output.Name, r.flushed, r.dropped)
+	if r.spilled > 0 {
+		msg += fmt.Sprintf(", spilled %d metrics", r.spilled)
+	}
+	if r.cutOff {
+		msg += ", cut off while writing"
+	}
+	log.Print(msg)
+}
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+// slowOutput writes its first writes, fails or blocks the next ones until
+// released.
+type slowOutput struct {
+	sync.Mutex
+	writes  int
+	succeed int
+	fail    bool
+	release chan struct{}
+}
+
+func (o *slowOutput) SampleConfig() string { return "" }
+func (o *slowOutput) Description() string  { return "" }
+func (o *slowOutput) Connect() error       { return nil }
+func (o *slowOutput) Close() error         { return nil }
+func (o *slowOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	o.writes++
+	writes := o.writes
+	o.Unlock()
+
+	if writes <= o.succeed {
+		return nil
+	}
+	if o.fail {
+		return errors.New("write failed")
+	}
+	<-o.release
+	return nil
+}
+
+// runShutdown adds n metrics to the output and shuts the outputs down,
+// returning the logged flush report.
+func runShutdown(t *testing.T, a *Agent, n int) string {
+	addReloadMetrics(t, a.Config.Outputs[0], n)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	src := make(chan telegraf.Metric)
+	close(src)
+	done := make(chan error)
+	go func() {
+		done <- a.runOutputs(time.Now(), src)
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("outputs not shut down")
+	}
+
+	log.SetOutput(os.Stderr)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "on shutdown") {
+			return line[strings.Index(line, "[outputs."):]
+		}
+	}
+	return ""
+}
+
+func newShutdownAgent(t *testing.T, output telegraf.Output, timeout time.Duration) *Agent {
+	c := newReloadConfig(&initInput{}, "input", output, "output")
+	c.Agent.ShutdownFlushTimeout.Duration = timeout
+	c.Outputs[0].MetricBatchSize = 2
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	return a
+}
+
+func TestShutdownFlushTimeout(t *testing.T) {
+	output := &slowOutput{succeed: 1, release: make(chan struct{})}
+	defer close(output.release)
+	a := newShutdownAgent(t, output, 50*time.Millisecond)
+
+	// the first batch is written, the second one in progress at the deadline
+	// and the last metric still buffered
+	start := time.Now()
+	report := runShutdown(t, a, 5)
+	require.True(t, time.Since(start) < time.Second)
+	require.Equal(t, "[outputs.test] Flushed 2 metrics on shutdown, dropped 3 metrics, cut off while writing", report)
+	require.True(t, a.cutOff[a.Config.Outputs[0]])
+}
+
+func TestShutdownSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	output := &slowOutput{succeed: 1, release: make(chan struct{})}
+	defer close(output.release)
+	a := newShutdownAgent(t, output, 50*time.Millisecond)
+	a.Config.Agent.ShutdownSpillDir = dir
+
+	report := runShutdown(t, a, 5)
+	require.Equal(t, "[outputs.test] Flushed 2 metrics on shutdown, dropped 2 metrics, spilled 1 metrics, cut off while writing", report)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "test.influx"))
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=0i 0\n", string(data))
+}
+
+func TestShutdownFailedWrite(t *testing.T) {
+	a := newShutdownAgent(t, &slowOutput{fail: true}, 0)
+
+	// the metrics not written by the final flush are dropped
+	report := runShutdown(t, a, 3)
+	require.Equal(t, "[outputs.test] Flushed 0 metrics on shutdown, dropped 3 metrics", report)
+	require.Empty(t, a.cutOff)
+}
+
+func TestShutdownRetainedOutput(t *testing.T) {
+	output := &slowOutput{succeed: 1, release: make(chan struct{})}
+	a := newShutdownAgent(t, output, 10*time.Millisecond)
+	a.retained = map[*models.RunningOutput]bool{a.Config.Outputs[0]: true}
+
+	// the outputs handed over are waited for and keep their buffer
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(output.release)
+	}()
+	report := runShutdown(t, a, 5)
+	require.Equal(t, "[outputs.test] Flushed 5 metrics on shutdown, dropped 0 metrics", report)
+	require.Empty(t, a.cutOff)
+}
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
@@ -23,6 +24,7 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
 	"github.com/kardianos/service"
 )
@@ -34,6 +36,10 @@ var pprofAddr = flag.String("pprof-addr", "",
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fTestGraph = flag.Bool("test-graph", false,
+	"initialize all plugins, print the plugin graph, and exit")
+var fTestGraphMetric = flag.String("test-graph-metric", "",
+	"metric in line protocol to test against the plugin filters with --test-graph")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directories (comma-delimited) containing additional *.conf files")
@@ -75,83 +81,130 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
-	reload := make(chan bool, 1)
-	reload <- true
-	for <-reload {
-		reload <- false
+	// Setup default logging. This may need to change after reading the config
+	// file, but we can configure it to use our logger implementation now.
+	logger.SetupLogging(false, false, "")
+	log.Printf("I! Starting Telegraf %s", version)
 
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		log.Fatalf("E! [telegraf] Error running agent: %v", err)
+	}
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		log.Fatalf("E! [telegraf] Error running agent: %v", err)
+	}
+
+	health := agent.NewHealth()
+	defer health.Close()
+
+	for ag != nil {
 		ctx, cancel := context.WithCancel(context.Background())
 
+		ag.Health = health
+		health.SetRequireWrite(ag.Config.Agent.HealthRequireWrite)
+		if err := health.Listen(ag.Config.Agent.HealthServiceAddress); err != nil {
+			log.Printf("E! [telegraf] Error serving health endpoints: %v", err)
+		}
+
+		var next *agent.Agent
+		done := make(chan struct{})
 		signals := make(chan os.Signal)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
-		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config")
-					<-reload
-					reload <- true
+		go func(ag *agent.Agent) {
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						log.Printf("I! Reloading Telegraf config")
+						n, err := reloadAgent(ag, inputFilters, outputFilters)
+						if err != nil {
+							log.Printf("E! [telegraf] Error reloading config, "+
+								"keeping the current config: %v", err)
+							continue
+						}
+						next = n
+					}
+					cancel()
+					return
+				case <-stop:
+					cancel()
+					return
+				case <-done:
+					return
 				}
-				cancel()
-			case <-stop:
-				cancel()
 			}
-		}()
+		}(ag)
 
-		err := runAgent(ctx, inputFilters, outputFilters)
+		err := runAgent(ctx, ag)
+		close(done)
+		signal.Stop(signals)
 		if err != nil {
 			log.Fatalf("E! [telegraf] Error running agent: %v", err)
 		}
+		cancel()
+
+		ag = next
 	}
 }
 
-func runAgent(ctx context.Context,
+// loadConfig loads and validates the configuration.
+func loadConfig(
 	inputFilters []string,
 	outputFilters []string,
-) error {
-	// Setup default logging. This may need to change after reading the config
-	// file, but we can configure it to use our logger implementation now.
-	logger.SetupLogging(false, false, "")
-	log.Printf("I! Starting Telegraf %s", version)
-
-	// If no other options are specified, load the config file and run.
+) (*config.Config, error) {
 	c := config.NewConfig()
 	c.OutputFilters = outputFilters
 	c.InputFilters = inputFilters
 	err := c.LoadConfig(*fConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if *fConfigDirectory != "" {
 		for _, dir := range strings.Split(*fConfigDirectory, ",") {
 			if err := c.LoadDirectory(dir); err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
-	if !*fTest && len(c.Outputs) == 0 {
-		return errors.New("Error: no outputs found, did you provide a valid config file?")
+	if !*fTest && !*fTestGraph && len(c.Outputs) == 0 {
+		return nil, errors.New("Error: no outputs found, did you provide a valid config file?")
 	}
 	if len(c.Inputs) == 0 {
-		return errors.New("Error: no inputs found, did you provide a valid config file?")
+		return nil, errors.New("Error: no inputs found, did you provide a valid config file?")
 	}
 
 	if int64(c.Agent.Interval.Duration) <= 0 {
-		return fmt.Errorf("Agent interval must be positive, found %s",
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
 			c.Agent.Interval.Duration)
 	}
 
 	if int64(c.Agent.FlushInterval.Duration) <= 0 {
-		return fmt.Errorf("Agent flush_interval must be positive; found %s",
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
 			c.Agent.Interval.Duration)
 	}
 
-	ag, err := agent.NewAgent(c)
+	return c, nil
+}
+
+// reloadAgent loads the configuration again and prepares an agent taking
+// over the unchanged plugins of the running agent.
+func reloadAgent(
+	ag *agent.Agent,
+	inputFilters []string,
+	outputFilters []string,
+) (*agent.Agent, error) {
+	c, err := loadConfig(inputFilters, outputFilters)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return ag.Reload(c)
+}
+
+func runAgent(ctx context.Context, ag *agent.Agent) error {
+	c := ag.Config
 
 	// Setup logging as configured.
 	logger.SetupLogging(
@@ -164,6 +217,19 @@ func runAgent(ctx context.Context,
 		return ag.Test(ctx)
 	}
 
+	if *fTestGraph {
+		var sample telegraf.Metric
+		if *fTestGraphMetric != "" {
+			var err error
+			parser := influx.NewParser(influx.NewMetricHandler())
+			sample, err = parser.ParseLine(*fTestGraphMetric)
+			if err != nil {
+				return fmt.Errorf("Error parsing --test-graph-metric: %v", err)
+			}
+		}
+		return ag.TestGraph(os.Stdout, sample)
+	}
+
 	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
 	log.Printf("I! Loaded aggregators: %s", strings.Join(c.AggregatorNames(), " "))
 	log.Printf("I! Loaded processors: %s", strings.Join(c.ProcessorNames(), " "))
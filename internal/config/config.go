@@ -21,10 +21,13 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/secret"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/binary"
+	"github.com/influxdata/telegraf/plugins/parsers/xpath"
 	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/toml"
@@ -62,6 +65,9 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// Secrets resolves the references to secrets in the plugin options.
+	Secrets *secret.Resolver
 }
 
 func NewConfig() *Config {
@@ -71,6 +77,10 @@ func NewConfig() *Config {
 			Interval:      internal.Duration{Duration: 10 * time.Second},
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
+
+			HealthRequireWrite: true,
+
+			GatherErrorMaxBackoff: internal.Duration{Duration: 10 * time.Minute},
 		},
 
 		Tags:          make(map[string]string),
@@ -79,6 +89,7 @@ func NewConfig() *Config {
 		Processors:    make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		Secrets:       secret.NewResolver(),
 	}
 	return c
 }
@@ -145,6 +156,26 @@ type AgentConfig struct {
 	Quiet        bool
 	Hostname     string
 	OmitHostname bool
+
+	// HealthServiceAddress is the address of the /healthz and /ready
+	// endpoints, they are disabled if empty.
+	HealthServiceAddress string
+	// HealthRequireWrite delays readiness until all outputs flushed once.
+	HealthRequireWrite bool
+
+	// ShutdownFlushTimeout bounds the final flush of the outputs on
+	// shutdown, it is unbounded if zero.
+	ShutdownFlushTimeout internal.Duration
+	// ShutdownSpillDir receives the metrics remaining in the buffers of the
+	// outputs on shutdown, they are dropped if empty.
+	ShutdownSpillDir string
+
+	// GatherErrorThreshold is the number of consecutive failed gathers of an
+	// input after which its gathers are backed off, never if zero.
+	GatherErrorThreshold int
+	// GatherErrorMaxBackoff caps the interval between the gathers of the
+	// inputs backed off.
+	GatherErrorMaxBackoff internal.Duration
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -276,6 +307,27 @@ var header = `# Telegraf Configuration
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the /healthz and /ready HTTP endpoints, for the liveness
+  ## and readiness probes of Kubernetes.  Disabled by default.
+  # health_service_address = ":8888"
+  ## If true, Telegraf is only ready once every output flushed successfully.
+  # health_require_write = true
+
+  ## Maximum time the outputs are given to flush their buffer on shutdown.
+  ## The outputs still writing afterwards are cut off.  Unbounded by default.
+  # shutdown_flush_timeout = "30s"
+  ## Directory receiving the metrics left in the buffers of the outputs on
+  ## shutdown, in line protocol to a file named after each output.  They are
+  ## dropped by default.
+  # shutdown_spill_dir = "/var/lib/telegraf/spill"
+
+  ## Number of consecutive failed gathers of an input after which it is
+  ## gathered less often, twice as rarely after each new failure with up to
+  ## gather_error_max_backoff between the gathers, until a gather succeeds.
+  ## Disabled by default.
+  # gather_error_threshold = 5
+  # gather_error_max_backoff = "10m"
+
 
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
@@ -649,6 +701,17 @@ func (c *Config) LoadConfig(path string) error {
 
 		switch name {
 		case "agent", "global_tags", "tags":
+		case "secretstores":
+			for storeName, storeVal := range subTable.Fields {
+				storeTable, ok := storeVal.(*ast.Table)
+				if !ok {
+					return fmt.Errorf("Unsupported config format: %s, file %s",
+						storeName, path)
+				}
+				if err = c.addSecretStore(storeName, storeTable); err != nil {
+					return fmt.Errorf("Error parsing %s, %s", path, err)
+				}
+			}
 		case "outputs":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
@@ -794,6 +857,33 @@ func parseConfig(contents []byte) (*ast.Table, error) {
 	return toml.Parse(contents)
 }
 
+// addSecretStore adds the secret store of the [secretstores.<name>] table.
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	var storeType string
+	if node, ok := table.Fields["type"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				storeType = str.Value
+			}
+		}
+	}
+	delete(table.Fields, "type")
+
+	switch storeType {
+	case "http":
+		store := &secret.HTTPStore{}
+		if err := toml.UnmarshalTable(table, store); err != nil {
+			return err
+		}
+		if store.URL == "" {
+			return fmt.Errorf("secret store %s: url must be set", name)
+		}
+		return c.Secrets.Add(name, store)
+	default:
+		return fmt.Errorf("secret store %s: unsupported type %q", name, storeType)
+	}
+}
+
 func (c *Config) addAggregator(name string, table *ast.Table) error {
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
@@ -849,6 +939,8 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	fingerprint := fmt.Sprintf("%s:%d:%d:%s", name, c.Agent.MetricBatchSize,
+		c.Agent.MetricBufferLimit, tableFingerprint(table))
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -872,6 +964,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	ro.Fingerprint = fingerprint
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -890,6 +983,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	fingerprint := name + ":" + tableFingerprint(table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -913,6 +1007,15 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		})
 	}
 
+	switch t := input.(type) {
+	case parsers.RouteParserInput:
+		routeParsers, err := buildRouteParsers(name, table)
+		if err != nil {
+			return err
+		}
+		t.SetRouteParsers(routeParsers)
+	}
+
 	pluginConfig, err := buildInput(name, table)
 	if err != nil {
 		return err
@@ -923,11 +1026,46 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	rp.Fingerprint = fingerprint
 	rp.SetDefaultTags(c.Tags)
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
 
+// tableFingerprint returns a string representation of the table that is
+// identical for identical plugin configurations, regardless of the order of
+// the keys.
+func tableFingerprint(tbl *ast.Table) string {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for _, key := range keys {
+		buf.WriteString(strconv.Quote(key))
+		buf.WriteString("=")
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			buf.WriteString(v.Value.Source())
+		case *ast.Table:
+			buf.WriteString(tableFingerprint(v))
+		case []*ast.Table:
+			buf.WriteString("[")
+			for _, t := range v {
+				buf.WriteString(tableFingerprint(t))
+				buf.WriteString(",")
+			}
+			buf.WriteString("]")
+		}
+		buf.WriteString(",")
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
 // buildAggregator parses Aggregator specific items from the ast.Table,
 // builds the filter and returns a
 // models.AggregatorConfig to be inserted into models.RunningAggregator
@@ -1082,7 +1220,7 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 		}
 	}
 
-	fields := []string{"pass", "fieldpass"}
+	fields := []string{"pass", "fieldpass", "fieldinclude"}
 	for _, field := range fields {
 		if node, ok := tbl.Fields[field]; ok {
 			if kv, ok := node.(*ast.KeyValue); ok {
@@ -1097,7 +1235,7 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 		}
 	}
 
-	fields = []string{"drop", "fielddrop"}
+	fields = []string{"drop", "fielddrop", "fieldexclude"}
 	for _, field := range fields {
 		if node, ok := tbl.Fields[field]; ok {
 			if kv, ok := node.(*ast.KeyValue); ok {
@@ -1179,6 +1317,8 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	delete(tbl.Fields, "namepass")
 	delete(tbl.Fields, "fielddrop")
 	delete(tbl.Fields, "fieldpass")
+	delete(tbl.Fields, "fieldexclude")
+	delete(tbl.Fields, "fieldinclude")
 	delete(tbl.Fields, "drop")
 	delete(tbl.Fields, "pass")
 	delete(tbl.Fields, "tagdrop")
@@ -1206,6 +1346,19 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["collection_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.CollectionJitter = dur
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1243,6 +1396,7 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "collection_jitter")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -1252,6 +1406,44 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	return cp, nil
 }
 
+// buildRouteParsers builds the parser of each of the route tables of the
+// input, by path.
+func buildRouteParsers(name string, tbl *ast.Table) (map[string]parsers.Parser, error) {
+	routeParsers := make(map[string]parsers.Parser)
+	node, ok := tbl.Fields["route"]
+	if !ok {
+		return routeParsers, nil
+	}
+	routes, ok := node.([]*ast.Table)
+	if !ok {
+		return nil, fmt.Errorf("%s: route must be an array of tables", name)
+	}
+
+	for _, route := range routes {
+		var path string
+		if node, ok := route.Fields["path"]; ok {
+			if kv, ok := node.(*ast.KeyValue); ok {
+				if str, ok := kv.Value.(*ast.String); ok {
+					path = str.Value
+				}
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("%s: route without path", name)
+		}
+		if _, ok := routeParsers[path]; ok {
+			return nil, fmt.Errorf("%s: duplicate route %s", name, path)
+		}
+
+		parser, err := buildParser(name, route)
+		if err != nil {
+			return nil, fmt.Errorf("%s: route %s: %v", name, path, err)
+		}
+		routeParsers[path] = parser
+	}
+	return routeParsers, nil
+}
+
 // buildParser grabs the necessary entries from the ast.Table for creating
 // a parsers.Parser object, and creates it, which can then be added onto
 // an Input object.
@@ -1357,6 +1549,26 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["json_condition"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.JSONCondition = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_array_index_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.JSONArrayIndexTags = append(c.JSONArrayIndexTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["data_type"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1621,6 +1833,194 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["protobuf_descriptor_set"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufDescriptorSet = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_message_type"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufMessageType = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_metric_path"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufMetricPath = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_index_tag"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufIndexTag = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_tag_paths"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.ProtobufTagPaths = append(c.ProtobufTagPaths, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_field_paths"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.ProtobufFieldPaths = append(c.ProtobufFieldPaths, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timestamp_path"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimestampPath = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["binary_endianness"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.BinaryEndianness = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["binary_record_length"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				c.BinaryRecordLength = int(v)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["binary_field"]; ok {
+		subtbls, ok := node.([]*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s: binary_field must be an array of tables", name)
+		}
+		for _, subtbl := range subtbls {
+			var field binary.Field
+			if err := toml.UnmarshalTable(subtbl, &field); err != nil {
+				return nil, fmt.Errorf("%s: binary_field: %v", name, err)
+			}
+			c.BinaryFields = append(c.BinaryFields, field)
+		}
+	}
+
+	if node, ok := tbl.Fields["binary_timestamp_field"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.BinaryTimestampField = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["binary_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.BinaryTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["kv_separator"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.KVSeparator = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["kv_pair_separator"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.KVPairSeparator = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["kv_tag_keys"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.KVTagKeys = append(c.KVTagKeys, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["kv_int_keys"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.KVIntKeys = append(c.KVIntKeys, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["kv_string_keys"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.KVStringKeys = append(c.KVStringKeys, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["xpath"]; ok {
+		subtbls, ok := node.([]*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s: xpath must be an array of tables", name)
+		}
+		for _, subtbl := range subtbls {
+			var xc xpath.Config
+			if err := toml.UnmarshalTable(subtbl, &xc); err != nil {
+				return nil, fmt.Errorf("%s: xpath: %v", name, err)
+			}
+			c.XPathConfigs = append(c.XPathConfigs, xc)
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
@@ -1632,6 +2032,8 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "json_string_fields")
 	delete(tbl.Fields, "json_time_format")
 	delete(tbl.Fields, "json_time_key")
+	delete(tbl.Fields, "json_array_index_tags")
+	delete(tbl.Fields, "json_condition")
 	delete(tbl.Fields, "data_type")
 	delete(tbl.Fields, "collectd_auth_file")
 	delete(tbl.Fields, "collectd_security_level")
@@ -1660,6 +2062,25 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "csv_timestamp_column")
 	delete(tbl.Fields, "csv_timestamp_format")
 	delete(tbl.Fields, "csv_trim_space")
+	delete(tbl.Fields, "protobuf_descriptor_set")
+	delete(tbl.Fields, "protobuf_message_type")
+	delete(tbl.Fields, "protobuf_metric_path")
+	delete(tbl.Fields, "protobuf_index_tag")
+	delete(tbl.Fields, "protobuf_tag_paths")
+	delete(tbl.Fields, "protobuf_field_paths")
+	delete(tbl.Fields, "protobuf_timestamp_path")
+	delete(tbl.Fields, "protobuf_timestamp_format")
+	delete(tbl.Fields, "binary_endianness")
+	delete(tbl.Fields, "binary_record_length")
+	delete(tbl.Fields, "binary_field")
+	delete(tbl.Fields, "binary_timestamp_field")
+	delete(tbl.Fields, "binary_timestamp_format")
+	delete(tbl.Fields, "kv_separator")
+	delete(tbl.Fields, "kv_pair_separator")
+	delete(tbl.Fields, "kv_tag_keys")
+	delete(tbl.Fields, "kv_int_keys")
+	delete(tbl.Fields, "kv_string_keys")
+	delete(tbl.Fields, "xpath")
 
 	return c, nil
 }
@@ -1668,7 +2089,10 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 // a serializers.Serializer object, and creates it, which can then be added onto
 // an Output object.
 func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error) {
-	c := &serializers.Config{TimestampUnits: time.Duration(1 * time.Second)}
+	c := &serializers.Config{
+		TimestampUnits:         time.Duration(1 * time.Second),
+		TemplateLineTerminator: "\n",
+	}
 
 	if node, ok := tbl.Fields["data_format"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -1734,6 +2158,14 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["influx_uint_overflow"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.InfluxUintOverflow = str.Value
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["graphite_tag_support"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Boolean); ok {
@@ -1774,15 +2206,76 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["template_metric"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.TemplateMetric = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["template_batch"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.TemplateBatch = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["template_line_terminator"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.TemplateLineTerminator = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["parquet_row_group_rows"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				c.ParquetRowGroupRows = int(v)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["parquet_row_group_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size internal.Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				return nil, fmt.Errorf("Unable to parse parquet_row_group_size as a size, %s", err)
+			}
+			c.ParquetRowGroupSize = size.Size
+		}
+	}
+
+	if node, ok := tbl.Fields["parquet_compression"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ParquetCompression = str.Value
+			}
+		}
+	}
+
 	delete(tbl.Fields, "influx_max_line_bytes")
 	delete(tbl.Fields, "influx_sort_fields")
 	delete(tbl.Fields, "influx_uint_support")
+	delete(tbl.Fields, "influx_uint_overflow")
 	delete(tbl.Fields, "graphite_tag_support")
 	delete(tbl.Fields, "data_format")
 	delete(tbl.Fields, "prefix")
 	delete(tbl.Fields, "template")
 	delete(tbl.Fields, "json_timestamp_units")
 	delete(tbl.Fields, "splunkmetric_hec_routing")
+	delete(tbl.Fields, "template_metric")
+	delete(tbl.Fields, "template_batch")
+	delete(tbl.Fields, "template_line_terminator")
+	delete(tbl.Fields, "parquet_row_group_rows")
+	delete(tbl.Fields, "parquet_row_group_size")
+	delete(tbl.Fields, "parquet_compression")
 	return serializers.NewSerializer(c)
 }
 
@@ -1846,9 +2339,155 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["field_transform"]; ok {
+		subtbls, ok := node.([]*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s: field_transform must be an array of tables", name)
+		}
+		for _, subtbl := range subtbls {
+			transform, err := buildFieldTransform(subtbl)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			oc.FieldTransforms = append(oc.FieldTransforms, transform)
+		}
+	}
+
+	if node, ok := tbl.Fields["rollup_window"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				window, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				function := "last"
+				if node, ok := tbl.Fields["rollup_function"]; ok {
+					if kv, ok := node.(*ast.KeyValue); ok {
+						if str, ok := kv.Value.(*ast.String); ok {
+							function = str.Value
+						}
+					}
+				}
+
+				oc.Rollup, err = models.NewRollup(window, function)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", name, err)
+				}
+			}
+		}
+	}
+
+	var deadLetterFile, deadLetterOutput string
+	var deadLetterMaxSize int64
+	if node, ok := tbl.Fields["dead_letter_queue_file"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				deadLetterFile = str.Value
+			}
+		}
+	}
+	if node, ok := tbl.Fields["dead_letter_queue_output"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				deadLetterOutput = str.Value
+			}
+		}
+	}
+	if node, ok := tbl.Fields["dead_letter_queue_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size internal.Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				return nil, fmt.Errorf("%s: invalid dead_letter_queue_max_size: %v", name, err)
+			}
+			deadLetterMaxSize = size.Size
+		}
+	}
+	if deadLetterFile != "" || deadLetterOutput != "" {
+		var err error
+		oc.DeadLetter, err = models.NewDeadLetterQueue(deadLetterFile, deadLetterOutput, deadLetterMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
 	delete(tbl.Fields, "flush_interval")
 	delete(tbl.Fields, "metric_buffer_limit")
 	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "field_transform")
+	delete(tbl.Fields, "rollup_window")
+	delete(tbl.Fields, "rollup_function")
+	delete(tbl.Fields, "dead_letter_queue_file")
+	delete(tbl.Fields, "dead_letter_queue_output")
+	delete(tbl.Fields, "dead_letter_queue_max_size")
 
 	return oc, nil
 }
+
+// buildFieldTransform builds a models.FieldTransform from a field_transform
+// table of an output, scale and offset may be given as integers or floats.
+func buildFieldTransform(tbl *ast.Table) (*models.FieldTransform, error) {
+	transform := models.NewFieldTransform()
+
+	if node, ok := tbl.Fields["fields"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						transform.Fields = append(transform.Fields, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	for key, dest := range map[string]*float64{
+		"scale":  &transform.Scale,
+		"offset": &transform.Offset,
+	} {
+		node, ok := tbl.Fields[key]
+		if !ok {
+			continue
+		}
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch value := kv.Value.(type) {
+			case *ast.Float:
+				v, err := value.Float()
+				if err != nil {
+					return nil, err
+				}
+				*dest = v
+			case *ast.Integer:
+				v, err := value.Int()
+				if err != nil {
+					return nil, err
+				}
+				*dest = float64(v)
+			default:
+				return nil, fmt.Errorf("field_transform %s must be a number", key)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["round"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			integer, ok := kv.Value.(*ast.Integer)
+			if !ok {
+				return nil, fmt.Errorf("field_transform round must be an integer")
+			}
+			v, err := integer.Int()
+			if err != nil {
+				return nil, err
+			}
+			if v < 0 {
+				return nil, fmt.Errorf("field_transform round must not be negative")
+			}
+			transform.Round = int(v)
+		}
+	}
+
+	if err := transform.Compile(); err != nil {
+		return nil, err
+	}
+	return transform, nil
+}
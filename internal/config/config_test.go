@@ -1,18 +1,29 @@
 package config
 
 import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/secret"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
+	"github.com/influxdata/telegraf/plugins/inputs/http_listener_v2"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	_ "github.com/influxdata/telegraf/plugins/outputs/discard"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/binary"
+	"github.com/influxdata/telegraf/plugins/parsers/xpath"
+	"github.com/influxdata/toml"
 
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
@@ -177,3 +188,244 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_TableFingerprint(t *testing.T) {
+	a, err := parseConfig([]byte(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+  interval = "5s"
+  [inputs.memcached.tagpass]
+    goodtag = ["mytag"]
+`))
+	assert.NoError(t, err)
+	b, err := parseConfig([]byte(`
+[[inputs.memcached]]
+  interval = "5s"
+  servers = ["localhost"]
+  [inputs.memcached.tagpass]
+    goodtag = ["mytag"]
+`))
+	assert.NoError(t, err)
+	c, err := parseConfig([]byte(`
+[[inputs.memcached]]
+  interval = "10s"
+  servers = ["localhost"]
+  [inputs.memcached.tagpass]
+    goodtag = ["mytag"]
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, tableFingerprint(a), tableFingerprint(b))
+	assert.NotEqual(t, tableFingerprint(a), tableFingerprint(c))
+}
+
+func TestConfig_LoadSecretStores(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secretstores.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	// The references are left in the options until the plugins are
+	// initialized.
+	listener := c.Inputs[0].Input.(*http_listener_v2.HTTPListenerV2)
+	require.Equal(t, "@{secret:vault:listener_password}", listener.BasicPassword)
+
+	require.Error(t, c.Secrets.Add("vault", secret.EnvStore{}))
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/secretstores_invalid.toml")
+	require.EqualError(t, err, "Error parsing ./testdata/secretstores_invalid.toml, "+
+		`secret store keyring: unsupported type "keyring"`)
+}
+
+func TestConfig_LoadRouteParsers(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/http_listener_v2_routes.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	listener := c.Inputs[0].Input.(*http_listener_v2.HTTPListenerV2)
+	require.Equal(t, []http_listener_v2.Route{
+		{Path: "/json", Tags: map[string]string{"source": "json"}},
+		{Path: "/influx"},
+	}, listener.Routes)
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	for path, body := range map[string]string{
+		"/json":   `{"name": "json_metric", "value": 42}`,
+		"/influx": "influx_metric value=42\n",
+	} {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		res := httptest.NewRecorder()
+		listener.ServeHTTP(res, req)
+		require.Equal(t, http.StatusNoContent, res.Code, path)
+	}
+
+	acc.AssertContainsTaggedFields(t, "json_metric",
+		map[string]interface{}{"value": float64(42)},
+		map[string]string{"source": "json"})
+	acc.AssertContainsTaggedFields(t, "influx_metric",
+		map[string]interface{}{"value": float64(42)},
+		map[string]string{})
+}
+
+func TestConfig_LoadFieldTransforms(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/field_transforms.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	transforms := c.Outputs[0].Config.FieldTransforms
+	require.Len(t, transforms, 2)
+	require.Equal(t, []string{"used", "free"}, transforms[0].Fields)
+	require.Equal(t, 0.000001, transforms[0].Scale)
+	require.Equal(t, 0.0, transforms[0].Offset)
+	require.Equal(t, 2, transforms[0].Round)
+	require.Equal(t, []string{"temp_*"}, transforms[1].Fields)
+	require.Equal(t, 1.0, transforms[1].Scale)
+	require.Equal(t, -273.15, transforms[1].Offset)
+	require.Equal(t, -1, transforms[1].Round)
+
+	require.Empty(t, c.Outputs[1].Config.FieldTransforms)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/field_transforms_invalid.toml")
+	require.EqualError(t, err, "Error parsing ./testdata/field_transforms_invalid.toml, "+
+		"discard: field_transform without fields")
+}
+
+func TestConfig_LoadRollup(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/rollup.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	rollup := c.Outputs[0].Config.Rollup
+	require.NotNil(t, rollup)
+	require.Equal(t, time.Minute, rollup.Window)
+	require.Equal(t, "sum", rollup.Function)
+
+	require.Nil(t, c.Outputs[1].Config.Rollup)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/rollup_invalid.toml")
+	require.EqualError(t, err, "Error parsing ./testdata/rollup_invalid.toml, "+
+		"discard: invalid rollup_function \"max\", must be one of [last mean sum]")
+}
+
+func TestConfig_LoadCollectionJitter(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/collection_jitter.toml"))
+	require.Len(t, c.Inputs, 2)
+
+	require.Equal(t, time.Second, c.Agent.CollectionJitter.Duration)
+	require.Equal(t, 5*time.Second, c.Inputs[0].Config.CollectionJitter)
+	require.Equal(t, time.Duration(0), c.Inputs[1].Config.CollectionJitter)
+}
+
+func TestConfig_LoadDeadLetter(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/dead_letter.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	queue := c.Outputs[0].Config.DeadLetter
+	require.NotNil(t, queue)
+	require.Equal(t, "discard", queue.OutputName)
+	require.Equal(t, int64(models.DefaultDeadLetterMaxSize), queue.MaxSize)
+
+	queue = c.Outputs[1].Config.DeadLetter
+	require.NotNil(t, queue)
+	require.Equal(t, "/var/lib/telegraf/dead.lp", queue.File)
+	require.Equal(t, int64(1000*1000), queue.MaxSize)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/dead_letter_invalid.toml")
+	require.EqualError(t, err, "Error parsing ./testdata/dead_letter_invalid.toml, "+
+		"discard: exactly one of dead_letter_queue_file and dead_letter_queue_output must be set")
+}
+
+func TestConfig_LoadOutputFilter(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/output_filter.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	f := c.Outputs[0].Config.Filter
+	require.Equal(t, []string{"usage_*"}, f.FieldPass)
+	require.Equal(t, []string{"usage_guest"}, f.FieldDrop)
+	require.Equal(t, []string{"host"}, f.TagInclude)
+	require.Equal(t, []string{"cpu"}, c.Outputs[1].Config.Filter.TagExclude)
+}
+
+func TestConfig_BuildBinaryParser(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "binary"
+binary_endianness = "little"
+binary_record_length = 8
+
+[[binary_field]]
+  name = "temperature"
+  offset = 0
+  length = 2
+  type = "int"
+  scale = 0.1
+
+[[binary_field]]
+  name = "status"
+  offset = 2
+  length = 1
+  type = "bitfield"
+  bit_offset = 1
+  bits = 2
+`))
+	require.NoError(t, err)
+
+	p, err := buildParser("file", tbl)
+	require.NoError(t, err)
+	parser := p.(*binary.Parser)
+	require.Equal(t, "little", parser.Endianness)
+	require.Equal(t, 8, parser.RecordLength)
+	require.Len(t, parser.Fields, 2)
+	require.Equal(t, "temperature", parser.Fields[0].Name)
+	require.Equal(t, 0.1, parser.Fields[0].Scale)
+	require.Equal(t, 1, parser.Fields[1].BitOffset)
+	require.Equal(t, 2, parser.Fields[1].Bits)
+	require.Empty(t, tbl.Fields)
+}
+
+func TestConfig_BuildXPathParser(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "xpath_json"
+
+[[xpath]]
+  metric_selection = "//cpu"
+  timestamp = "@time"
+  timestamp_format = "unix"
+  [xpath.tags]
+    cpu = "@name"
+  [xpath.fields]
+    usage_idle = "number(usage_idle)"
+
+[[xpath]]
+  metric_name = "'disk'"
+  field_selection = "disk/*"
+`))
+	require.NoError(t, err)
+
+	p, err := buildParser("file", tbl)
+	require.NoError(t, err)
+	parser := p.(*xpath.Parser)
+	require.Equal(t, "json", parser.Format)
+	require.Equal(t, []xpath.Config{
+		{
+			MetricSelection: "//cpu",
+			Timestamp:       "@time",
+			TimestampFormat: "unix",
+			Tags:            map[string]string{"cpu": "@name"},
+			Fields:          map[string]string{"usage_idle": "number(usage_idle)"},
+		},
+		{
+			MetricName:     "'disk'",
+			FieldSelection: "disk/*",
+		},
+	}, parser.Configs)
+	require.Empty(t, tbl.Fields)
+}
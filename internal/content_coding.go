@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ContentEncoder compresses request bodies with a HTTP Content-Encoding.  It
+// is safe for concurrent use, the compressors are pooled between calls.
+type ContentEncoder interface {
+	// Encoding is the value of the Content-Encoding header, empty for the
+	// identity encoding.
+	Encoding() string
+	Encode(data []byte) ([]byte, error)
+}
+
+// NewContentEncoder returns the encoder of the content encoding, one of
+// "gzip", "zstd" or "identity".  The level is the compression level of the
+// encoding, 0 selects its default level.
+func NewContentEncoder(encoding string, level int) (ContentEncoder, error) {
+	switch encoding {
+	case "", "identity":
+		if level != 0 {
+			return nil, fmt.Errorf("compression level not supported by the identity encoding")
+		}
+		return identityEncoder{}, nil
+	case "gzip":
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if level != gzip.DefaultCompression && (level < gzip.BestSpeed || level > gzip.BestCompression) {
+			return nil, fmt.Errorf("invalid gzip compression level %d, must be between 1 and 9", level)
+		}
+		return newGzipEncoder(level), nil
+	case "zstd":
+		if level < 0 || level > 22 {
+			return nil, fmt.Errorf("invalid zstd compression level %d, must be between 1 and 22", level)
+		}
+		if level == 0 {
+			level = 3
+		}
+		return newZstdEncoder(zstd.EncoderLevelFromZstd(level)), nil
+	default:
+		return nil, fmt.Errorf("invalid content encoding %q", encoding)
+	}
+}
+
+type identityEncoder struct{}
+
+func (identityEncoder) Encoding() string {
+	return ""
+}
+
+func (identityEncoder) Encode(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+type gzipEncoder struct {
+	pool sync.Pool
+}
+
+func newGzipEncoder(level int) *gzipEncoder {
+	e := &gzipEncoder{}
+	e.pool.New = func() interface{} {
+		// the level was checked by NewContentEncoder
+		w, _ := gzip.NewWriterLevel(nil, level)
+		return w
+	}
+	return e
+}
+
+func (e *gzipEncoder) Encoding() string {
+	return "gzip"
+}
+
+func (e *gzipEncoder) Encode(data []byte) ([]byte, error) {
+	w := e.pool.Get().(*gzip.Writer)
+	defer e.pool.Put(w)
+
+	var buf bytes.Buffer
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type zstdEncoder struct {
+	pool sync.Pool
+}
+
+func newZstdEncoder(level zstd.EncoderLevel) *zstdEncoder {
+	e := &zstdEncoder{}
+	e.pool.New = func() interface{} {
+		// the encoders compress the whole body at once, so without
+		// goroutines of their own
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+		return w
+	}
+	return e
+}
+
+func (e *zstdEncoder) Encoding() string {
+	return "zstd"
+}
+
+func (e *zstdEncoder) Encode(data []byte) ([]byte, error) {
+	w := e.pool.Get().(*zstd.Encoder)
+	defer e.pool.Put(w)
+	return w.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+}
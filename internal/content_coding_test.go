@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentEncoderRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("cpu,host=server01 usage_idle=98.2 1500000000000000000\n"), 100)
+
+	decoders := map[string]func([]byte) ([]byte, error){
+		"": func(b []byte) ([]byte, error) {
+			return b, nil
+		},
+		"gzip": func(b []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.ReadAll(r)
+		},
+		"zstd": func(b []byte) ([]byte, error) {
+			d, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer d.Close()
+			return d.DecodeAll(b, nil)
+		},
+	}
+
+	for _, tt := range []struct {
+		encoding string
+		level    int
+	}{
+		{"identity", 0},
+		{"gzip", 0},
+		{"gzip", 9},
+		{"zstd", 0},
+		{"zstd", 1},
+		{"zstd", 22},
+	} {
+		encoder, err := NewContentEncoder(tt.encoding, tt.level)
+		require.NoError(t, err)
+
+		// the pooled compressors are reused concurrently
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 3; j++ {
+					encoded, err := encoder.Encode(data)
+					require.NoError(t, err)
+					decoded, err := decoders[encoder.Encoding()](encoded)
+					require.NoError(t, err)
+					require.Equal(t, data, decoded)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestContentEncoderInvalid(t *testing.T) {
+	_, err := NewContentEncoder("deflate", 0)
+	require.Error(t, err)
+	_, err = NewContentEncoder("gzip", 10)
+	require.Error(t, err)
+	_, err = NewContentEncoder("zstd", -1)
+	require.Error(t, err)
+	_, err = NewContentEncoder("identity", 3)
+	require.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package filewatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the time between two checks of the file.
+	DefaultPollInterval = time.Second
+	// DefaultDebounce is the time a changed file must stay unchanged before
+	// it is read.
+	DefaultDebounce = time.Second
+)
+
+// Config is the configuration of a Watcher.
+type Config struct {
+	Path string
+	// PollInterval is the time between two checks of the file.
+	PollInterval time.Duration
+	// Debounce is the time a changed file must stay unchanged before it is
+	// read, so that a file written in several steps is read once complete.
+	// Both durations default to one second.
+	Debounce time.Duration
+}
+
+// Watcher calls a function with the contents of a file, like the data file
+// of a processor, each time it changes.
+//
+// The file is polled rather than watched with inotify as the file is often
+// replaced by a rename, like by editors and for the secrets mounted in a
+// container, which replaces the inode inotify watches.  A file replaced
+// by another one, even with the same modification time and size, is read
+// again.  While the file is missing the previous contents are kept, and it
+// is read again once it appears.
+type Watcher struct {
+	config   Config
+	onChange func(data []byte)
+
+	// current is the file of the contents last given to onChange, pending
+	// is a changed file waiting for Debounce since pendingSince.
+	current      os.FileInfo
+	pending      os.FileInfo
+	pendingSince time.Time
+	missing      bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New reads the file, calls onChange with its contents and then watches the
+// file until Close is called.  The calls of onChange are never concurrent.
+func New(config Config, onChange func(data []byte)) (*Watcher, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("path must be set")
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.Debounce <= 0 {
+		config.Debounce = DefaultDebounce
+	}
+
+	w := &Watcher{
+		config:   config,
+		onChange: onChange,
+		done:     make(chan struct{}),
+	}
+
+	info, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	w.current = info
+	onChange(data)
+
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching the file, onChange is not called anymore once it
+// returns.
+func (w *Watcher) Close() {
+	close(w.done)
+	w.wg.Wait()
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check stats the file and reads it if it changed and then stayed unchanged
+// for Debounce.
+func (w *Watcher) check(now time.Time) {
+	info, err := os.Stat(w.config.Path)
+	if err != nil {
+		if !w.missing {
+			log.Printf("W! [filewatch] could not check %s, keeping its previous contents: %v",
+				w.config.Path, err)
+		}
+		w.missing = true
+		w.pending = nil
+		return
+	}
+	if w.missing {
+		log.Printf("I! [filewatch] %s is available again", w.config.Path)
+		w.missing = false
+	}
+
+	switch {
+	case w.pending != nil && changed(w.pending, info):
+		// still being written
+		w.pending, w.pendingSince = info, now
+	case w.pending == nil && changed(w.current, info):
+		w.pending, w.pendingSince = info, now
+	}
+	if w.pending == nil || now.Sub(w.pendingSince) < w.config.Debounce {
+		return
+	}
+
+	data, err := ioutil.ReadFile(w.config.Path)
+	if err != nil {
+		// the file is checked again at the next poll
+		log.Printf("W! [filewatch] could not read %s, keeping its previous contents: %v",
+			w.config.Path, err)
+		w.pending = nil
+		return
+	}
+	w.current, w.pending = w.pending, nil
+	w.onChange(data)
+}
+
+// changed reports if the file b is another file than a or was modified.
+func changed(a, b os.FileInfo) bool {
+	return !os.SameFile(a, b) || !a.ModTime().Equal(b.ModTime()) || a.Size() != b.Size()
+}
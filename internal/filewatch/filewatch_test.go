@@ -0,0 +1,140 @@
+package filewatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig polls often so that the tests are quick.
+func testConfig(path string) Config {
+	return Config{
+		Path:         path,
+		PollInterval: 10 * time.Millisecond,
+		Debounce:     30 * time.Millisecond,
+	}
+}
+
+func watch(t *testing.T, config Config) (*Watcher, chan string) {
+	changes := make(chan string, 10)
+	w, err := New(config, func(data []byte) {
+		changes <- string(data)
+	})
+	require.NoError(t, err)
+	return w, changes
+}
+
+func requireChange(t *testing.T, changes chan string, expected string) {
+	select {
+	case data := <-changes:
+		require.Equal(t, expected, data)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change to %q", expected)
+	}
+}
+
+func requireNoChange(t *testing.T, changes chan string) {
+	select {
+	case data := <-changes:
+		t.Fatalf("unexpected change to %q", data)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+}
+
+func TestWatcherInitialRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "table.csv")
+	_, err = New(testConfig(path), func([]byte) {})
+	require.Error(t, err)
+
+	writeFile(t, path, "host,team\nserver01,web\n")
+	w, changes := watch(t, testConfig(path))
+	defer w.Close()
+	requireChange(t, changes, "host,team\nserver01,web\n")
+	requireNoChange(t, changes)
+}
+
+func TestWatcherRenameUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "table.csv")
+	writeFile(t, path, "host,team\nserver01,web\n")
+	w, changes := watch(t, testConfig(path))
+	defer w.Close()
+	requireChange(t, changes, "host,team\nserver01,web\n")
+
+	// the new contents have the same size and are renamed over the file
+	tmp := filepath.Join(dir, ".table.csv.tmp")
+	writeFile(t, tmp, "host,team\nserver01,ops\n")
+	require.NoError(t, os.Rename(tmp, path))
+	requireChange(t, changes, "host,team\nserver01,ops\n")
+	requireNoChange(t, changes)
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "table.csv")
+	writeFile(t, path, "a")
+	config := testConfig(path)
+	config.Debounce = 300 * time.Millisecond
+	w, changes := watch(t, config)
+	defer w.Close()
+	requireChange(t, changes, "a")
+
+	// the changes within the debounce are read at once
+	writeFile(t, path, "ab")
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, path, "abc")
+	requireChange(t, changes, "abc")
+	requireNoChange(t, changes)
+}
+
+func TestWatcherTransientMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "table.csv")
+	writeFile(t, path, "host,team\nserver01,web\n")
+	w, changes := watch(t, testConfig(path))
+	defer w.Close()
+	requireChange(t, changes, "host,team\nserver01,web\n")
+
+	// the previous contents are kept while the file is missing
+	require.NoError(t, os.Remove(path))
+	requireNoChange(t, changes)
+
+	writeFile(t, path, "host,team\nserver02,db\n")
+	requireChange(t, changes, "host,team\nserver02,db\n")
+}
+
+func TestWatcherClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "table.csv")
+	writeFile(t, path, "a")
+	w, changes := watch(t, testConfig(path))
+	requireChange(t, changes, "a")
+	w.Close()
+
+	writeFile(t, path, "b")
+	requireNoChange(t, changes)
+}
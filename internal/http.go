@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// RetryTransport is a http.RoundTripper that retries idempotent requests
+// that failed with a connection error or a 5xx status code.  Like in
+// http.Transport, requests with an Idempotency-Key or X-Idempotency-Key header
+// are idempotent whatever their method.
+type RetryTransport struct {
+	// Transport is the underlying RoundTripper used to perform the requests.
+	Transport http.RoundTripper
+
+	// MaxAttempts is the maximum number of attempts per request, including
+	// the first one.
+	MaxAttempts int
+
+	// Backoff is the wait time before the first retry, it is doubled
+	// after each attempt up to MaxBackoff.  MaxBackoff also bounds the wait
+	// time requested by the server with a Retry-After header.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Attempts counts all requests sent, Retries counts the requests sent
+	// again after a failure and Failures counts the requests that still
+	// failed after the last attempt.
+	Attempts selfstat.Stat
+	Retries  selfstat.Stat
+	Failures selfstat.Stat
+}
+
+// NewRetryTransport wraps transport, or http.DefaultTransport if nil, in a
+// RetryTransport.  The counters are registered as the internal_http_retry
+// measurement with the given tags.
+func NewRetryTransport(
+	transport http.RoundTripper,
+	maxAttempts int,
+	backoff time.Duration,
+	tags map[string]string,
+) *RetryTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &RetryTransport{
+		Transport:   transport,
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		MaxBackoff:  backoff * 32,
+		Attempts:    selfstat.Register("http_retry", "attempts", tags),
+		Retries:     selfstat.Register("http_retry", "retries", tags),
+		Failures:    selfstat.Register("http_retry", "failures", tags),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotent(req) || t.MaxAttempts <= 1 {
+		t.Attempts.Incr(1)
+		resp, err := t.Transport.RoundTrip(req)
+		if shouldRetry(resp, err) {
+			t.Failures.Incr(1)
+		}
+		return resp, err
+	}
+
+	getBody, err := replayableBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := t.Backoff
+	for attempt := 1; ; attempt++ {
+		// the request of the caller must not be modified, each attempt
+		// sends a copy with a fresh body
+		r := req.WithContext(req.Context())
+		if getBody != nil {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		t.Attempts.Incr(1)
+		resp, err := t.Transport.RoundTrip(r)
+		if !shouldRetry(resp, err) {
+			return resp, err
+		}
+		if attempt >= t.MaxAttempts {
+			t.Failures.Incr(1)
+			return resp, err
+		}
+
+		wait := backoff
+		if resp != nil {
+			if d, ok := retryAfter(resp, time.Now()); ok {
+				wait = d
+				if t.MaxBackoff > 0 && wait > t.MaxBackoff {
+					wait = t.MaxBackoff
+				}
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := SleepContext(req.Context(), wait); err != nil {
+			t.Failures.Incr(1)
+			return nil, err
+		}
+
+		t.Retries.Incr(1)
+		backoff *= 2
+		if t.MaxBackoff > 0 && backoff > t.MaxBackoff {
+			backoff = t.MaxBackoff
+		}
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// replayableBody returns a function returning a fresh copy of the request
+// body, buffering it in memory if the request cannot provide one itself.
+func replayableBody(req *http.Request) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		return req.GetBody, nil
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+	}, nil
+}
+
+// retryAfter parses the Retry-After header, which is either a number of
+// seconds or a HTTP date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRetryTransport(maxAttempts int, name string) *RetryTransport {
+	return NewRetryTransport(nil, maxAttempts, time.Millisecond,
+		map[string]string{"test": name})
+}
+
+func TestRetryTransportSuccessAfterRetry(t *testing.T) {
+	var calls int
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	transport := newTestRetryTransport(3, "success")
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequest("PUT", ts.URL, ioutil.NopCloser(strings.NewReader("payload")))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"payload", "payload", "payload"}, bodies)
+	assert.Equal(t, int64(3), transport.Attempts.Get())
+	assert.Equal(t, int64(2), transport.Retries.Get())
+	assert.Equal(t, int64(0), transport.Failures.Get())
+}
+
+func TestRetryTransportExhausted(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	transport := newTestRetryTransport(3, "exhausted")
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, int64(1), transport.Failures.Get())
+}
+
+func TestRetryTransportNotIdempotent(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: newTestRetryTransport(3, "post")}
+
+	resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("payload"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryTransportIdempotencyKey(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: newTestRetryTransport(3, "idempotency_key")}
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("payload"))
+	require.NoError(t, err)
+	req.Header.Set("Idempotency-Key", "1")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryTransportRetryAfter(t *testing.T) {
+	var calls int
+	var first time.Time
+	var delay time.Duration
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		delay = time.Since(first)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	transport := newTestRetryTransport(2, "retry_after")
+	transport.MaxBackoff = 2 * time.Second
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, delay >= time.Second, "retried after %s", delay)
+}
+
+func TestRetryTransportRetryAfterMaxBackoff(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	transport := newTestRetryTransport(2, "retry_after_max_backoff")
+	transport.MaxBackoff = 10 * time.Millisecond
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryTransportDoesNotModifyRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	body := ioutil.NopCloser(strings.NewReader("payload"))
+	req, err := http.NewRequest("PUT", ts.URL, body)
+	require.NoError(t, err)
+
+	resp, err := newTestRetryTransport(3, "request").RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, body, req.Body)
+}
+
+func TestRetryTransportContextCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: newTestRetryTransport(2, "canceled")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req.WithContext(ctx))
+	require.Error(t, err)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"Tue, 01 Jan 2019 00:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 31 Dec 2018 23:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.value != "" {
+			resp.Header.Set("Retry-After", tt.value)
+		}
+		d, ok := retryAfter(resp, now)
+		assert.Equal(t, tt.ok, ok, tt.value)
+		assert.Equal(t, tt.expected, d, tt.value)
+	}
+}
@@ -24,6 +24,9 @@ type Buffer struct {
 	batchFirst int // index of the first metric in the batch
 	batchSize  int // number of metrics currently in the batch
 
+	written int64 // number of metrics written by this buffer
+	dropped int64 // number of metrics dropped by this buffer
+
 	MetricsAdded   selfstat.Stat
 	MetricsWritten selfstat.Stat
 	MetricsDropped selfstat.Stat
@@ -79,6 +82,16 @@ func (b *Buffer) Len() int {
 	return b.length()
 }
 
+// Counts returns the number of metrics written and dropped since the buffer
+// was created.  Unlike the selfstats, they are not shared by the buffers of
+// the outputs with the same name.
+func (b *Buffer) Counts() (written, dropped int64) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.written, b.dropped
+}
+
 func (b *Buffer) length() int {
 	return min(b.size+b.batchSize, b.cap)
 }
@@ -88,12 +101,14 @@ func (b *Buffer) metricAdded() {
 }
 
 func (b *Buffer) metricWritten(metric telegraf.Metric) {
+	b.written++
 	AgentMetricsWritten.Incr(1)
 	b.MetricsWritten.Incr(1)
 	metric.Accept()
 }
 
 func (b *Buffer) metricDropped(metric telegraf.Metric) {
+	b.dropped++
 	AgentMetricsDropped.Incr(1)
 	b.MetricsDropped.Incr(1)
 	metric.Reject()
@@ -163,6 +178,25 @@ func (b *Buffer) Batch(batchSize int) []telegraf.Metric {
 	return out
 }
 
+// Drain removes all metrics from the buffer and returns them, ordered from
+// oldest to newest.  The metrics of an outstanding batch are not returned,
+// they are added back to the buffer if the batch is rejected.
+func (b *Buffer) Drain() []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	out := make([]telegraf.Metric, 0, b.size)
+	for b.size > 0 {
+		out = append(out, b.buf[b.first])
+		b.buf[b.first] = nil
+		b.first = b.next(b.first)
+		b.size--
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+	return out
+}
+
 // Accept marks the batch, acquired from Batch(), as successfully written.
 func (b *Buffer) Accept(batch []telegraf.Metric) {
 	b.Lock()
@@ -176,6 +210,28 @@ func (b *Buffer) Accept(batch []telegraf.Metric) {
 	b.BufferSize.Set(int64(b.length()))
 }
 
+// AcceptPartial marks the batch, acquired from Batch(), as processed, the
+// metrics of failed as dropped and the others as successfully written.
+func (b *Buffer) AcceptPartial(batch []telegraf.Metric, failed []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := make(map[telegraf.Metric]bool, len(failed))
+	for _, m := range failed {
+		dropped[m] = true
+	}
+	for _, m := range batch {
+		if dropped[m] {
+			b.metricDropped(m)
+		} else {
+			b.metricWritten(m)
+		}
+	}
+
+	b.resetBatch()
+	b.BufferSize.Set(int64(b.length()))
+}
+
 // Reject returns the batch, acquired from Batch(), to the buffer and marks it
 // as unsent.
 func (b *Buffer) Reject(batch []telegraf.Metric) {
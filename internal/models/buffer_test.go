@@ -714,3 +714,24 @@ func TestBuffer_AddOverwriteAndRejectOffset(t *testing.T) {
 	require.Equal(t, 13, reject)
 	require.Equal(t, 5, accept)
 }
+
+func TestBuffer_Drain(t *testing.T) {
+	b := setup(NewBuffer("test", 5))
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3), MetricTime(4),
+		MetricTime(5), MetricTime(6), MetricTime(7))
+
+	drained := b.Drain()
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(3),
+			MetricTime(4),
+			MetricTime(5),
+			MetricTime(6),
+			MetricTime(7),
+		}, drained)
+	require.Equal(t, 0, b.Len())
+
+	b.Add(MetricTime(8))
+	batch := b.Batch(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(8)}, batch)
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// DefaultDeadLetterMaxSize is the default maximum size of a dead letter file.
+const DefaultDeadLetterMaxSize = 10 * 1024 * 1024
+
+// DeadLetterReasonTag is the tag holding the reason of the failure of the
+// dead-lettered metrics.
+const DeadLetterReasonTag = "dead_letter_reason"
+
+// PermanentError is returned by the Write of an output for metrics that can
+// never be written, like the metrics rejected by the server with a 4xx status
+// or that can not be serialized.  These metrics are not retried, they are
+// sent to the dead letter queue of the output if it has one and else
+// dropped.
+type PermanentError struct {
+	// Metrics are the metrics that failed, the other metrics of the batch
+	// were written.  All the metrics of the batch failed if empty.
+	Metrics []telegraf.Metric
+	Err     error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// DeadLetterQueue receives the metrics an output failed to write
+// permanently, tagged with the reason of the failure.  They are appended in
+// line protocol to a file, up to MaxSize bytes, or added to another output,
+// up to the metric_buffer_limit of this output.
+type DeadLetterQueue struct {
+	File       string
+	OutputName string
+	MaxSize    int64
+
+	// Output is the output named OutputName, set once all the outputs are
+	// loaded.
+	Output *RunningOutput
+
+	mu         sync.Mutex
+	serializer *influx.Serializer
+}
+
+// NewDeadLetterQueue returns a DeadLetterQueue to the file or to the output
+// named outputName, exactly one of them must be set.
+func NewDeadLetterQueue(file, outputName string, maxSize int64) (*DeadLetterQueue, error) {
+	if (file == "") == (outputName == "") {
+		return nil, fmt.Errorf("exactly one of dead_letter_queue_file and dead_letter_queue_output must be set")
+	}
+	if maxSize < 0 {
+		return nil, fmt.Errorf("invalid dead_letter_queue_max_size %d", maxSize)
+	}
+	if maxSize == 0 {
+		maxSize = DefaultDeadLetterMaxSize
+	}
+
+	serializer := influx.NewSerializer()
+	serializer.SetFieldSortOrder(influx.SortFields)
+	return &DeadLetterQueue{
+		File:       file,
+		OutputName: outputName,
+		MaxSize:    maxSize,
+		serializer: serializer,
+	}, nil
+}
+
+// Add dead-letters copies of the metrics tagged with the reason, and returns
+// the number of metrics dropped as the queue is full.  The copies are not
+// tracked, the originals are rejected.
+func (q *DeadLetterQueue) Add(metrics []telegraf.Metric, reason string) (int, error) {
+	tagged := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		m = metric.Unwrap(m).Copy()
+		m.AddTag(DeadLetterReasonTag, reason)
+		tagged = append(tagged, m)
+	}
+
+	if q.File == "" {
+		if q.Output == nil {
+			return len(tagged), fmt.Errorf("output %q not found", q.OutputName)
+		}
+		for _, m := range tagged {
+			q.Output.AddMetric(m)
+		}
+		return 0, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.appendFile(tagged)
+}
+
+func (q *DeadLetterQueue) appendFile(metrics []telegraf.Metric) (int, error) {
+	file, err := os.OpenFile(q.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return len(metrics), err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return len(metrics), err
+	}
+
+	size := info.Size()
+	var dropped int
+	for i, m := range metrics {
+		octets, err := q.serializer.Serialize(m)
+		if err != nil || size+int64(len(octets)) > q.MaxSize {
+			dropped++
+			continue
+		}
+		n, err := file.Write(octets)
+		size += int64(n)
+		if err != nil {
+			return dropped + len(metrics) - i, err
+		}
+	}
+	return dropped, file.Close()
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// rejectOutput writes the metrics but the ones with the tag bad, failed
+// permanently.
+type rejectOutput struct {
+	mockOutput
+}
+
+func (m *rejectOutput) Write(metrics []telegraf.Metric) error {
+	var rejected, written []telegraf.Metric
+	for _, metric := range metrics {
+		if metric.HasTag("bad") {
+			rejected = append(rejected, metric)
+		} else {
+			written = append(written, metric)
+		}
+	}
+	if err := m.mockOutput.Write(written); err != nil {
+		return err
+	}
+	if len(rejected) > 0 {
+		return &PermanentError{Metrics: rejected, Err: fmt.Errorf("400 bad request")}
+	}
+	return nil
+}
+
+func deadLetterMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{},
+			map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"bad": "true"},
+			map[string]interface{}{"usage": 2.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{},
+			map[string]interface{}{"used": 3.0}, time.Unix(0, 0)),
+	}
+}
+
+func TestRunningOutputDeadLetterOutput(t *testing.T) {
+	queue, err := NewDeadLetterQueue("", "sink", 0)
+	require.NoError(t, err)
+	sinkOutput := &mockOutput{}
+	queue.Output = NewRunningOutput("sink", sinkOutput, &OutputConfig{}, 1000, 10000)
+
+	m := &rejectOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{DeadLetter: queue}, 1000, 10000)
+	for _, metric := range deadLetterMetrics() {
+		ro.AddMetric(metric)
+	}
+
+	// the permanent failure is not retried
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 2)
+	require.Equal(t, 0, ro.BufferLength())
+
+	require.NoError(t, queue.Output.Write())
+	require.Len(t, sinkOutput.Metrics(), 1)
+	dead := sinkOutput.Metrics()[0]
+	require.Equal(t, map[string]string{"bad": "true", "dead_letter_reason": "400 bad request"}, dead.Tags())
+	require.Equal(t, 2.0, dead.Fields()["usage"])
+}
+
+func TestRunningOutputDeadLetterTracking(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	queue, err := NewDeadLetterQueue(filepath.Join(dir, "dead.lp"), "", 0)
+	require.NoError(t, err)
+
+	m := &rejectOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{DeadLetter: queue}, 1000, 10000)
+	var d deliveries
+	for _, dm := range deadLetterMetrics() {
+		tm, _ := metric.WithTracking(dm, d.onDelivery)
+		ro.AddMetric(tm)
+	}
+
+	// the metrics failed permanently are rejected even if dead-lettered
+	require.NoError(t, ro.Write())
+	require.Len(t, d.get(), 3)
+	var rejected int
+	for _, info := range d.get() {
+		if !info.Delivered() {
+			rejected++
+		}
+	}
+	require.Equal(t, 1, rejected)
+}
+
+func TestRunningOutputDeadLetterFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dead.lp")
+
+	queue, err := NewDeadLetterQueue(path, "", 0)
+	require.NoError(t, err)
+	m := &rejectOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{DeadLetter: queue}, 1000, 10000)
+	for _, metric := range deadLetterMetrics() {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "cpu,bad=true,dead_letter_reason=400\\ bad\\ request usage=2 0\n", string(data))
+}
+
+func TestDeadLetterFileMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dead.lp")
+
+	queue, err := NewDeadLetterQueue(path, "", 40)
+	require.NoError(t, err)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{},
+			map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{},
+			map[string]interface{}{"usage": 2.0}, time.Unix(0, 0)),
+	}
+
+	dropped, err := queue.Add(metrics, "full")
+	require.NoError(t, err)
+	require.Equal(t, 1, dropped)
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "cpu,dead_letter_reason=full usage=1 0\n", string(data))
+}
+
+func TestRunningOutputTransientErrorRetried(t *testing.T) {
+	queue, err := NewDeadLetterQueue("", "sink", 0)
+	require.NoError(t, err)
+	sinkOutput := &mockOutput{}
+	queue.Output = NewRunningOutput("sink", sinkOutput, &OutputConfig{}, 1000, 10000)
+
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{DeadLetter: queue}, 1000, 10000)
+	for _, metric := range deadLetterMetrics() {
+		ro.AddMetric(metric)
+	}
+
+	require.Error(t, ro.Write())
+	require.Equal(t, 3, ro.BufferLength())
+	require.Equal(t, 0, queue.Output.BufferLength())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 3)
+}
+
+func TestNewDeadLetterQueue(t *testing.T) {
+	_, err := NewDeadLetterQueue("", "", 0)
+	require.Error(t, err)
+	_, err = NewDeadLetterQueue("/tmp/dead.lp", "file", 0)
+	require.Error(t, err)
+
+	queue, err := NewDeadLetterQueue("/tmp/dead.lp", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(DefaultDeadLetterMaxSize), queue.MaxSize)
+}
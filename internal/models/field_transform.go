@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+)
+
+// FieldTransform scales, offsets and rounds the numeric fields matching the
+// Fields globs: value*Scale + Offset, rounded to Round decimal places if Round
+// is not negative.
+type FieldTransform struct {
+	Fields []string
+	Scale  float64
+	Offset float64
+	Round  int
+
+	fields filter.Filter
+}
+
+// NewFieldTransform returns a FieldTransform that leaves the values as they
+// are until configured.
+func NewFieldTransform() *FieldTransform {
+	return &FieldTransform{
+		Scale: 1,
+		Round: -1,
+	}
+}
+
+// Compile compiles the field globs.
+func (t *FieldTransform) Compile() error {
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("field_transform without fields")
+	}
+	var err error
+	t.fields, err = filter.Compile(t.Fields)
+	if err != nil {
+		return fmt.Errorf("Error compiling field_transform 'fields', %s", err)
+	}
+	return nil
+}
+
+// Apply transforms the matching fields of the metric.  Integer fields are
+// converted to floats, fields that are not numbers are left untouched.
+func (t *FieldTransform) Apply(metric telegraf.Metric) {
+	for _, field := range metric.FieldList() {
+		if !t.fields.Match(field.Key) {
+			continue
+		}
+
+		var v float64
+		switch value := field.Value.(type) {
+		case float64:
+			v = value
+		case int64:
+			v = float64(value)
+		case uint64:
+			v = float64(value)
+		default:
+			continue
+		}
+
+		v = v*t.Scale + t.Offset
+		if t.Round >= 0 {
+			v = round(v, t.Round)
+		}
+		metric.AddField(field.Key, v)
+	}
+}
+
+// round rounds v half away from zero to the number of decimal places.
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	if v < 0 {
+		return math.Ceil(v*p-0.5) / p
+	}
+	return math.Floor(v*p+0.5) / p
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// RollupFunctions are the functions combining the values of a field within a
+// rollup window.
+var RollupFunctions = []string{"last", "mean", "sum"}
+
+// Rollup combines the points of each series, with the same name and tags,
+// within a window of time into a single point before they are written by an
+// output.  The numeric fields are combined with the Function, while the
+// others keep their last value.
+type Rollup struct {
+	Window   time.Duration
+	Function string
+
+	windows map[rollupKey]*rollupWindow
+	seq     int
+}
+
+type rollupKey struct {
+	id    uint64
+	start int64
+}
+
+type rollupWindow struct {
+	seq    int
+	metric telegraf.Metric
+	count  int
+	fields map[string]*rollupField
+}
+
+type rollupField struct {
+	last  interface{}
+	count int
+	sum   float64
+	isum  int64
+	usum  uint64
+	mixed bool
+}
+
+// NewRollup returns a Rollup, or an error if the function is not known.
+func NewRollup(window time.Duration, function string) (*Rollup, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("rollup_window must be positive")
+	}
+	valid := false
+	for _, f := range RollupFunctions {
+		valid = valid || f == function
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid rollup_function %q, must be one of %v",
+			function, RollupFunctions)
+	}
+	return &Rollup{
+		Window:   window,
+		Function: function,
+		windows:  make(map[rollupKey]*rollupWindow),
+	}, nil
+}
+
+// Add adds the metric to the window of its series and time.
+//
+// Takes ownership of metric
+func (r *Rollup) Add(metric telegraf.Metric) {
+	key := rollupKey{
+		id:    metric.HashID(),
+		start: metric.Time().Truncate(r.Window).UnixNano(),
+	}
+	w, ok := r.windows[key]
+	if !ok {
+		r.seq++
+		w = &rollupWindow{
+			seq:    r.seq,
+			metric: metric,
+			fields: make(map[string]*rollupField),
+		}
+		r.windows[key] = w
+	}
+	w.count++
+
+	for _, field := range metric.FieldList() {
+		f, ok := w.fields[field.Key]
+		if !ok {
+			f = &rollupField{}
+			w.fields[field.Key] = f
+		}
+		f.add(field.Value)
+	}
+
+	if metric != w.metric {
+		if metric.Time().After(w.metric.Time()) {
+			w.metric.SetTime(metric.Time())
+		}
+		metric.Drop()
+	}
+}
+
+func (f *rollupField) add(value interface{}) {
+	if f.last != nil && reflect.TypeOf(f.last) != reflect.TypeOf(value) {
+		f.mixed = true
+	}
+	f.last = value
+	f.count++
+	switch v := value.(type) {
+	case float64:
+		f.sum += v
+	case int64:
+		f.sum += float64(v)
+		f.isum += v
+	case uint64:
+		f.sum += float64(v)
+		f.usum += v
+	default:
+		f.mixed = true
+	}
+}
+
+// value returns the combined value of the field.
+func (f *rollupField) value(function string) interface{} {
+	if f.mixed || function == "last" {
+		return f.last
+	}
+	switch function {
+	case "mean":
+		return f.sum / float64(f.count)
+	case "sum":
+		switch f.last.(type) {
+		case int64:
+			return f.isum
+		case uint64:
+			return f.usum
+		}
+		return f.sum
+	}
+	return f.last
+}
+
+// Push returns the metrics of the windows ending by now, or of all the
+// windows if all is true, and removes them.  A metric is at the time of the
+// last point of its window.
+func (r *Rollup) Push(now time.Time, all bool) []telegraf.Metric {
+	var windows []*rollupWindow
+	for key, w := range r.windows {
+		if !all && key.start+int64(r.Window) > now.UnixNano() {
+			continue
+		}
+		windows = append(windows, w)
+		delete(r.windows, key)
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].seq < windows[j].seq
+	})
+
+	metrics := make([]telegraf.Metric, 0, len(windows))
+	for _, w := range windows {
+		// a single point is left as is
+		if w.count > 1 {
+			for key, f := range w.fields {
+				w.metric.AddField(key, f.value(r.Function))
+			}
+		}
+		metrics = append(metrics, w.metric)
+	}
+	return metrics
+}
+
+// Len returns the number of pending windows.
+func (r *Rollup) Len() int {
+	return len(r.windows)
+}
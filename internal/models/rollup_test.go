@@ -0,0 +1,115 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func rollupMetrics() []telegraf.Metric {
+	start := time.Unix(60, 0)
+	var metrics []telegraf.Metric
+	for i, v := range []int64{1, 2, 6} {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"count": v,
+				"usage": float64(v) / 2,
+				"state": []string{"idle", "busy", "idle"}[i],
+			},
+			start.Add(time.Duration(i)*10*time.Second)))
+	}
+	// another series
+	metrics = append(metrics, testutil.MustMetric("cpu",
+		map[string]string{"host": "b"},
+		map[string]interface{}{"count": int64(10)},
+		start.Add(5*time.Second)))
+	return metrics
+}
+
+func TestRollupFunctions(t *testing.T) {
+	tests := []struct {
+		function string
+		fields   map[string]interface{}
+	}{
+		{
+			function: "last",
+			fields: map[string]interface{}{
+				"count": int64(6),
+				"usage": 3.0,
+				"state": "idle",
+			},
+		},
+		{
+			function: "mean",
+			fields: map[string]interface{}{
+				"count": 3.0,
+				"usage": 1.5,
+				"state": "idle",
+			},
+		},
+		{
+			function: "sum",
+			fields: map[string]interface{}{
+				"count": int64(9),
+				"usage": 4.5,
+				"state": "idle",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			r, err := NewRollup(time.Minute, tt.function)
+			require.NoError(t, err)
+			for _, m := range rollupMetrics() {
+				r.Add(m)
+			}
+
+			// the window ends at 2m
+			require.Empty(t, r.Push(time.Unix(119, 0), false))
+			metrics := r.Push(time.Unix(120, 0), false)
+			require.Len(t, metrics, 2)
+			require.Equal(t, 0, r.Len())
+
+			require.Equal(t, map[string]string{"host": "a"}, metrics[0].Tags())
+			require.Equal(t, tt.fields, metrics[0].Fields())
+			require.Equal(t, time.Unix(80, 0), metrics[0].Time())
+
+			// a single point is not changed
+			require.Equal(t, map[string]string{"host": "b"}, metrics[1].Tags())
+			require.Equal(t, map[string]interface{}{"count": int64(10)}, metrics[1].Fields())
+			require.Equal(t, time.Unix(65, 0), metrics[1].Time())
+		})
+	}
+}
+
+func TestRollupWindows(t *testing.T) {
+	r, err := NewRollup(time.Minute, "sum")
+	require.NoError(t, err)
+	for _, sec := range []int64{0, 30, 60, 90, 120} {
+		r.Add(testutil.MustMetric("cpu", map[string]string{},
+			map[string]interface{}{"count": int64(1)}, time.Unix(sec, 0)))
+	}
+
+	metrics := r.Push(time.Unix(120, 0), false)
+	require.Len(t, metrics, 2)
+	require.Equal(t, int64(2), metrics[0].Fields()["count"])
+	require.Equal(t, int64(2), metrics[1].Fields()["count"])
+	require.Equal(t, 1, r.Len())
+
+	metrics = r.Push(time.Unix(120, 0), true)
+	require.Len(t, metrics, 1)
+	require.Equal(t, int64(1), metrics[0].Fields()["count"])
+}
+
+func TestRollupInvalid(t *testing.T) {
+	_, err := NewRollup(time.Minute, "max")
+	require.Error(t, err)
+	_, err = NewRollup(0, "sum")
+	require.Error(t, err)
+}
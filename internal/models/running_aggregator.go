@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -69,6 +70,15 @@ func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// Init calls the Init function of the aggregator if it implements
+// telegraf.Initializer.
+func (r *RunningAggregator) Init() error {
+	if p, ok := r.Aggregator.(telegraf.Initializer); ok {
+		return p.Init()
+	}
+	return nil
+}
+
 func (r *RunningAggregator) Period() time.Duration {
 	return r.Config.Period
 }
@@ -108,27 +118,28 @@ func (r *RunningAggregator) metricDropped(metric telegraf.Metric) {
 
 // Add a metric to the aggregator and return true if the original metric
 // should be dropped.
-func (r *RunningAggregator) Add(metric telegraf.Metric) bool {
-	if ok := r.Config.Filter.Select(metric); !ok {
+func (r *RunningAggregator) Add(m telegraf.Metric) bool {
+	if ok := r.Config.Filter.Select(m); !ok {
 		return false
 	}
 
-	metric = metric.Copy()
+	// the aggregations are not tracked, so the original alone is delivered
+	m = metric.Unwrap(m).Copy()
 
-	r.Config.Filter.Modify(metric)
-	if len(metric.FieldList()) == 0 {
+	r.Config.Filter.Modify(m)
+	if len(m.FieldList()) == 0 {
 		return r.Config.DropOriginal
 	}
 
 	r.Lock()
 	defer r.Unlock()
 
-	if r.periodStart.IsZero() || metric.Time().After(r.periodEnd) {
-		r.metricDropped(metric)
+	if r.periodStart.IsZero() || m.Time().After(r.periodEnd) {
+		r.metricDropped(m)
 		return r.Config.DropOriginal
 	}
 
-	r.Aggregator.Add(metric)
+	r.Aggregator.Add(m)
 	return r.Config.DropOriginal
 }
 
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -15,8 +16,19 @@ type RunningInput struct {
 
 	defaultTags map[string]string
 
+	// Fingerprint is identical for inputs with the same configuration.
+	Fingerprint string
+
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+	GatherErrors    selfstat.Stat
+	// Quarantined is 1 while the gathers of the input are backed off after
+	// consecutive errors
+	Quarantined selfstat.Stat
+
+	// errors counts the errors reported by this input, the stats being
+	// shared by the inputs with the same name
+	errors int64
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
@@ -33,6 +45,16 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"gather_time_ns",
 			map[string]string{"input": config.Name},
 		),
+		GatherErrors: selfstat.Register(
+			"gather",
+			"gather_errors",
+			map[string]string{"input": config.Name},
+		),
+		Quarantined: selfstat.Register(
+			"gather",
+			"quarantined",
+			map[string]string{"input": config.Name},
+		),
 	}
 }
 
@@ -40,6 +62,8 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 type InputConfig struct {
 	Name     string
 	Interval time.Duration
+	// CollectionJitter overrides the collection_jitter of the agent.
+	CollectionJitter time.Duration
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -52,6 +76,15 @@ func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// Init calls the Init function of the input if it implements
+// telegraf.Initializer.
+func (r *RunningInput) Init() error {
+	if p, ok := r.Input.(telegraf.Initializer); ok {
+		return p.Init()
+	}
+	return nil
+}
+
 func (r *RunningInput) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
@@ -89,6 +122,17 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	return err
 }
 
+// IncrErrors counts an error reported by the input.
+func (r *RunningInput) IncrErrors() {
+	r.GatherErrors.Incr(1)
+	atomic.AddInt64(&r.errors, 1)
+}
+
+// Errors returns the number of errors reported by the input.
+func (r *RunningInput) Errors() int64 {
+	return atomic.LoadInt64(&r.errors)
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
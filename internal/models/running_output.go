@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -26,6 +27,15 @@ type OutputConfig struct {
 	FlushInterval     time.Duration
 	MetricBufferLimit int
 	MetricBatchSize   int
+
+	// FieldTransforms are applied in order to the metrics added to the output.
+	FieldTransforms []*FieldTransform
+
+	// Rollup combines the points of each series within a window, if set.
+	Rollup *Rollup
+
+	// DeadLetter receives the metrics failed permanently, if set.
+	DeadLetter *DeadLetterQueue
 }
 
 // RunningOutput contains the output configuration
@@ -39,6 +49,9 @@ type RunningOutput struct {
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	// Fingerprint is identical for outputs with the same configuration.
+	Fingerprint string
+
 	MetricsFiltered selfstat.Stat
 	WriteTime       selfstat.Stat
 
@@ -96,29 +109,55 @@ func (ro *RunningOutput) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
 
+// Init calls the Init function of the output if it implements
+// telegraf.Initializer.
+func (ro *RunningOutput) Init() error {
+	if p, ok := ro.Output.(telegraf.Initializer); ok {
+		return p.Init()
+	}
+	return nil
+}
+
 // AddMetric adds a metric to the output.
 //
 // Takes ownership of metric
-func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
-	if ok := ro.Config.Filter.Select(metric); !ok {
-		ro.metricFiltered(metric)
+func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
+	if ok := ro.Config.Filter.Select(m); !ok {
+		ro.metricFiltered(m)
 		return
 	}
 
-	ro.Config.Filter.Modify(metric)
-	if len(metric.FieldList()) == 0 {
-		ro.metricFiltered(metric)
+	ro.Config.Filter.Modify(m)
+	if len(m.FieldList()) == 0 {
+		ro.metricFiltered(m)
 		return
 	}
 
+	// applied when the metric is added rather than written, so retried
+	// batches are not transformed again
+	for _, transform := range ro.Config.FieldTransforms {
+		transform.Apply(m)
+	}
+
+	// the aggregations are not tracked, the metrics aggregated are delivered
+	// once added
 	if output, ok := ro.Output.(telegraf.AggregatingOutput); ok {
 		ro.aggMutex.Lock()
-		output.Add(metric)
+		output.Add(metric.Unwrap(m))
 		ro.aggMutex.Unlock()
+		m.Drop()
 		return
 	}
 
-	ro.buffer.Add(metric)
+	if ro.Config.Rollup != nil {
+		ro.aggMutex.Lock()
+		ro.Config.Rollup.Add(metric.Unwrap(m))
+		ro.aggMutex.Unlock()
+		m.Drop()
+		return
+	}
+
+	ro.buffer.Add(m)
 
 	count := atomic.AddInt64(&ro.newMetricsCount, 1)
 	if count == int64(ro.MetricBatchSize) {
@@ -141,6 +180,12 @@ func (ro *RunningOutput) Write() error {
 		ro.aggMutex.Unlock()
 	}
 
+	if ro.Config.Rollup != nil {
+		ro.aggMutex.Lock()
+		ro.buffer.Add(ro.Config.Rollup.Push(time.Now(), false)...)
+		ro.aggMutex.Unlock()
+	}
+
 	atomic.StoreInt64(&ro.newMetricsCount, 0)
 
 	// Only process the metrics in the buffer now.  Metrics added while we are
@@ -153,16 +198,25 @@ func (ro *RunningOutput) Write() error {
 			break
 		}
 
-		err := ro.write(batch)
+		err := ro.writeBuffered(batch)
 		if err != nil {
-			ro.buffer.Reject(batch)
 			return err
 		}
-		ro.buffer.Accept(batch)
 	}
 	return nil
 }
 
+// FlushRollup adds the metrics of all the rollup windows to the buffer, even
+// those not ended yet, before the last write at shutdown.
+func (ro *RunningOutput) FlushRollup() {
+	if ro.Config.Rollup == nil {
+		return
+	}
+	ro.aggMutex.Lock()
+	ro.buffer.Add(ro.Config.Rollup.Push(time.Now(), true)...)
+	ro.aggMutex.Unlock()
+}
+
 // WriteBatch writes a single batch of metrics to the output.
 func (ro *RunningOutput) WriteBatch() error {
 	batch := ro.buffer.Batch(ro.MetricBatchSize)
@@ -170,13 +224,43 @@ func (ro *RunningOutput) WriteBatch() error {
 		return nil
 	}
 
+	return ro.writeBuffered(batch)
+}
+
+// writeBuffered writes a batch of the buffer.  On a PermanentError the failed
+// metrics are dead-lettered and not retried, on other errors the batch is
+// returned to the buffer.
+func (ro *RunningOutput) writeBuffered(batch []telegraf.Metric) error {
 	err := ro.write(batch)
-	if err != nil {
+	if err == nil {
+		ro.buffer.Accept(batch)
+		return nil
+	}
+
+	perr, ok := err.(*PermanentError)
+	if !ok {
 		ro.buffer.Reject(batch)
 		return err
 	}
-	ro.buffer.Accept(batch)
 
+	failed := perr.Metrics
+	if len(failed) == 0 {
+		failed = batch
+	}
+	if ro.Config.DeadLetter == nil {
+		log.Printf("E! [outputs.%s] dropping %d metrics failed permanently: %v",
+			ro.Name, len(failed), perr)
+	} else {
+		dropped, err := ro.Config.DeadLetter.Add(failed, perr.Error())
+		if err != nil {
+			log.Printf("E! [outputs.%s] could not dead-letter %d metrics failed permanently: %v",
+				ro.Name, dropped, err)
+		} else if dropped > 0 {
+			log.Printf("W! [outputs.%s] dead letter queue is full, dropped %d metrics failed permanently: %v",
+				ro.Name, dropped, perr)
+		}
+	}
+	ro.buffer.AcceptPartial(batch, failed)
 	return nil
 }
 
@@ -193,6 +277,23 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	return err
 }
 
+// BufferLength returns the number of metrics in the buffer.
+func (ro *RunningOutput) BufferLength() int {
+	return ro.buffer.Len()
+}
+
+// BufferCounts returns the number of metrics written and dropped by the
+// output since it was created.
+func (ro *RunningOutput) BufferCounts() (written, dropped int64) {
+	return ro.buffer.Counts()
+}
+
+// Drain removes all metrics from the buffer and returns them, ordered from
+// oldest to newest.
+func (ro *RunningOutput) Drain() []telegraf.Metric {
+	return ro.buffer.Drain()
+}
+
 func (ro *RunningOutput) LogBufferStatus() {
 	nBuffer := ro.buffer.Len()
 	log.Printf("D! [outputs.%s] buffer fullness: %d / %d metrics. ",
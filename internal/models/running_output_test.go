@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -412,6 +414,159 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+// Test that field transforms only apply to the output they are configured on.
+func TestRunningOutputFieldTransforms(t *testing.T) {
+	megabytes := NewFieldTransform()
+	megabytes.Fields = []string{"used*"}
+	megabytes.Scale = 0.000001
+	megabytes.Round = 1
+	require.NoError(t, megabytes.Compile())
+
+	celsius := NewFieldTransform()
+	celsius.Fields = []string{"temp"}
+	celsius.Offset = -273.15
+	require.NoError(t, celsius.Compile())
+
+	mb := &mockOutput{}
+	ro1 := NewRunningOutput("mb", mb, &OutputConfig{
+		FieldTransforms: []*FieldTransform{megabytes, celsius},
+	}, 1000, 10000)
+	raw := &mockOutput{}
+	ro2 := NewRunningOutput("raw", raw, &OutputConfig{}, 1000, 10000)
+
+	m := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{
+			"used":       int64(1234567),
+			"used_bytes": uint64(7654321),
+			"temp":       300.0,
+			"label":      "a",
+			"free":       int64(42),
+		},
+		time.Unix(0, 0))
+	ro1.AddMetric(m.Copy())
+	ro2.AddMetric(m)
+	require.NoError(t, ro1.Write())
+	require.NoError(t, ro2.Write())
+
+	require.Len(t, mb.Metrics(), 1)
+	fields := mb.Metrics()[0].Fields()
+	require.Equal(t, 1.2, fields["used"])
+	require.Equal(t, 7.7, fields["used_bytes"])
+	require.InDelta(t, 26.85, fields["temp"], 1e-9)
+	require.Equal(t, "a", fields["label"])
+	require.Equal(t, int64(42), fields["free"])
+
+	require.Len(t, raw.Metrics(), 1)
+	require.Equal(t, map[string]interface{}{
+		"used":       int64(1234567),
+		"used_bytes": uint64(7654321),
+		"temp":       300.0,
+		"label":      "a",
+		"free":       int64(42),
+	}, raw.Metrics()[0].Fields())
+}
+
+func TestRunningOutputRollup(t *testing.T) {
+	rollup, err := NewRollup(time.Minute, "mean")
+	require.NoError(t, err)
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{Rollup: rollup}, 1000, 10000)
+
+	ro.AddMetric(testutil.MustMetric("cpu", map[string]string{},
+		map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)))
+	ro.AddMetric(testutil.MustMetric("cpu", map[string]string{},
+		map[string]interface{}{"usage": 3.0}, time.Unix(10, 0)))
+	// the window of a later time is not ended
+	now := time.Now().Add(time.Hour)
+	ro.AddMetric(testutil.MustMetric("mem", map[string]string{},
+		map[string]interface{}{"used": 5.0}, now))
+	ro.AddMetric(testutil.MustMetric("mem", map[string]string{},
+		map[string]interface{}{"used": 7.0}, now))
+
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 1)
+	require.Equal(t, "cpu", m.Metrics()[0].Name())
+	require.Equal(t, 2.0, m.Metrics()[0].Fields()["usage"])
+
+	ro.FlushRollup()
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 2)
+	require.Equal(t, "mem", m.Metrics()[1].Name())
+	require.Equal(t, 6.0, m.Metrics()[1].Fields()["used"])
+}
+
+// deliveries records the tracking results of the metrics.
+type deliveries struct {
+	sync.Mutex
+	infos []telegraf.DeliveryInfo
+}
+
+func (d *deliveries) onDelivery(info telegraf.DeliveryInfo) {
+	d.Lock()
+	defer d.Unlock()
+	d.infos = append(d.infos, info)
+}
+
+func (d *deliveries) get() []telegraf.DeliveryInfo {
+	d.Lock()
+	defer d.Unlock()
+	return d.infos
+}
+
+func TestRunningOutputTracking(t *testing.T) {
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 1000, 10000)
+
+	var d deliveries
+	tm, id := metric.WithTracking(testutil.TestMetric(101, "metric1"), d.onDelivery)
+	ro.AddMetric(tm)
+
+	// the batch failing is retried, not delivered
+	require.Error(t, ro.Write())
+	require.Empty(t, d.get())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, d.get(), 1)
+	require.Equal(t, id, d.get()[0].ID())
+	require.True(t, d.get()[0].Delivered())
+}
+
+func TestRunningOutputTrackingRollup(t *testing.T) {
+	rollup, err := NewRollup(time.Minute, "mean")
+	require.NoError(t, err)
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{Rollup: rollup}, 1000, 10000)
+
+	// the metrics aggregated are delivered once added
+	var d deliveries
+	group, _ := metric.WithGroupTracking([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{},
+			map[string]interface{}{"usage": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{},
+			map[string]interface{}{"usage": 3.0}, time.Unix(10, 0)),
+	}, d.onDelivery)
+	for _, tm := range group {
+		ro.AddMetric(tm)
+	}
+	require.Len(t, d.get(), 1)
+	require.True(t, d.get()[0].Delivered())
+
+	// the aggregation is not tracked
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 1)
+	m.Metrics()[0].Accept()
+	require.Len(t, d.get(), 1)
+}
+
+func TestFieldTransformRound(t *testing.T) {
+	require.Equal(t, 2.0, round(1.5, 0))
+	require.Equal(t, -2.0, round(-1.5, 0))
+	require.Equal(t, 1.23, round(1.2345, 2))
+	require.Equal(t, -1.23, round(-1.2345, 2))
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"io"
 	"sync"
 
 	"github.com/influxdata/telegraf"
@@ -31,6 +32,24 @@ func (rp *RunningProcessor) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
 
+// Init calls the Init function of the processor if it implements
+// telegraf.Initializer.
+func (rp *RunningProcessor) Init() error {
+	if p, ok := rp.Processor.(telegraf.Initializer); ok {
+		return p.Init()
+	}
+	return nil
+}
+
+// Close calls the Close function of the processor if it implements
+// io.Closer.
+func (rp *RunningProcessor) Close() error {
+	if p, ok := rp.Processor.(io.Closer); ok {
+		return p.Close()
+	}
+	return nil
+}
+
 func containsMetric(item telegraf.Metric, metrics []telegraf.Metric) bool {
 	for _, m := range metrics {
 		if item == m {
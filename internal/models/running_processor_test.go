@@ -1,11 +1,15 @@
 package models
 
 import (
+	"fmt"
 	"sort"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors/converter"
+	"github.com/influxdata/telegraf/plugins/processors/rename"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/require"
@@ -187,3 +191,65 @@ func TestRunningProcessor_Order(t *testing.T) {
 		RunningProcessors{rp1, rp2, rp3},
 		procs)
 }
+
+// metadataSerializer serializes the unit metadata of the fields.
+type metadataSerializer struct{}
+
+func (s *metadataSerializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	var out []byte
+	for _, field := range m.FieldList() {
+		out = append(out, fmt.Sprintf("%s %v %v\n", field.Key, field.Value, field.Metadata["unit"])...)
+	}
+	return out, nil
+}
+
+func (s *metadataSerializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var out []byte
+	for _, m := range metrics {
+		b, _ := s.Serialize(m)
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+func TestRunningProcessor_FieldMetadata(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{Name: "test"})
+	m, err := metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{"uptime": "3600"},
+		time.Unix(0, 0))
+	require.NoError(t, err)
+	m.SetFieldMetadata("uptime", "unit", "seconds")
+	m = ri.MakeMetric(m)
+
+	processors := RunningProcessors{
+		&RunningProcessor{
+			Processor: &rename.Rename{
+				Replaces: []rename.Replace{{Field: "uptime", Dest: "uptime_total"}},
+			},
+			Config: &ProcessorConfig{},
+		},
+		&RunningProcessor{
+			Processor: &converter.Converter{
+				Fields: &converter.Conversion{Integer: []string{"uptime_total"}},
+			},
+			Config: &ProcessorConfig{},
+		},
+	}
+	metrics := []telegraf.Metric{m}
+	for _, rp := range processors {
+		require.NoError(t, rp.Config.Filter.Compile())
+		metrics = rp.Apply(metrics...)
+	}
+
+	output := &mockOutput{}
+	ro := NewRunningOutput("test", output, &OutputConfig{}, 1000, 10000)
+	for _, m := range metrics {
+		ro.AddMetric(m)
+	}
+	require.NoError(t, ro.Write())
+
+	out, err := (&metadataSerializer{}).SerializeBatch(output.Metrics())
+	require.NoError(t, err)
+	require.Equal(t, "uptime_total 3600 seconds\n", string(out))
+}
@@ -0,0 +1,146 @@
+package sampler
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// ModeRate keeps one metric of every Rate metrics.
+	ModeRate = "rate"
+	// ModeProbability keeps each metric with the probability Probability.
+	ModeProbability = "probability"
+	// ModeReservoir keeps the metrics of at most Size series, chosen
+	// uniformly among the series seen.
+	ModeReservoir = "reservoir"
+)
+
+// Config is the configuration of a Sampler.
+type Config struct {
+	Mode        string
+	Rate        int
+	Probability float64
+	Size        int
+	// PerSeries decides by series rather than by metric for the rate and
+	// probability modes, a series is then always kept or always dropped.
+	// The reservoir mode always decides by series.
+	PerSeries bool
+}
+
+// Sampler decides which metrics of a stream to keep.  It is safe for
+// concurrent use.
+type Sampler struct {
+	config Config
+
+	mu    sync.Mutex
+	count int
+	rand  *rand.Rand
+	// kept are the hashes of the series in the reservoir, largest is a max
+	// heap of the same hashes.
+	kept    map[uint64]bool
+	largest hashHeap
+}
+
+// New returns a Sampler for the configuration.
+func New(config Config) (*Sampler, error) {
+	switch config.Mode {
+	case ModeRate:
+		if config.Rate < 1 {
+			return nil, fmt.Errorf("rate must be at least 1")
+		}
+	case ModeProbability:
+		if config.Probability < 0 || config.Probability > 1 {
+			return nil, fmt.Errorf("probability must be between 0 and 1")
+		}
+	case ModeReservoir:
+		if config.Size < 1 {
+			return nil, fmt.Errorf("size must be at least 1")
+		}
+	default:
+		return nil, fmt.Errorf("invalid mode %q, must be rate, probability or reservoir", config.Mode)
+	}
+
+	return &Sampler{
+		config: config,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		kept:   make(map[uint64]bool),
+	}, nil
+}
+
+// Sample reports if the metric is kept.
+func (s *Sampler) Sample(metric telegraf.Metric) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.config.Mode {
+	case ModeRate:
+		if s.config.PerSeries {
+			return seriesHash(metric)%uint64(s.config.Rate) == 0
+		}
+		s.count++
+		if s.count < s.config.Rate {
+			return false
+		}
+		s.count = 0
+		return true
+	case ModeProbability:
+		if s.config.PerSeries {
+			// the hash is uniform over [0, 2^64)
+			return s.config.Probability == 1 ||
+				float64(seriesHash(metric)) < s.config.Probability*math.Exp2(64)
+		}
+		return s.rand.Float64() < s.config.Probability
+	default:
+		return s.reserve(seriesHash(metric))
+	}
+}
+
+// reserve keeps the series with the smallest hashes seen so far, which are a
+// uniform sample of the series.  Once all series have been seen the
+// reservoir does not change anymore.
+func (s *Sampler) reserve(hash uint64) bool {
+	if s.kept[hash] {
+		return true
+	}
+	if len(s.largest) >= s.config.Size {
+		if hash > s.largest[0] {
+			return false
+		}
+		delete(s.kept, heap.Pop(&s.largest).(uint64))
+	}
+	s.kept[hash] = true
+	heap.Push(&s.largest, hash)
+	return true
+}
+
+// seriesHash returns the hash of the series of the metric, mixed so that
+// all of its bits are uniformly distributed.
+func seriesHash(metric telegraf.Metric) uint64 {
+	h := metric.HashID()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// hashHeap is a max heap of hashes.
+type hashHeap []uint64
+
+func (h hashHeap) Len() int            { return len(h) }
+func (h hashHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h hashHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hashHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *hashHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
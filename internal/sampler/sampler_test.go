@@ -0,0 +1,127 @@
+package sampler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func seriesMetric(i int) telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{"host": fmt.Sprintf("host%d", i)},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0))
+}
+
+// keptSeries samples n series and returns the kept ones.
+func keptSeries(s *Sampler, n int) map[int]bool {
+	kept := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		if s.Sample(seriesMetric(i)) {
+			kept[i] = true
+		}
+	}
+	return kept
+}
+
+func TestRate(t *testing.T) {
+	s, err := New(Config{Mode: ModeRate, Rate: 10})
+	require.NoError(t, err)
+
+	m := seriesMetric(0)
+	var kept []int
+	for i := 0; i < 10000; i++ {
+		if s.Sample(m) {
+			kept = append(kept, i)
+		}
+	}
+	require.Len(t, kept, 1000)
+	require.Equal(t, []int{9, 19, 29}, kept[:3])
+}
+
+func TestProbability(t *testing.T) {
+	s, err := New(Config{Mode: ModeProbability, Probability: 0.1})
+	require.NoError(t, err)
+
+	m := seriesMetric(0)
+	kept := 0
+	for i := 0; i < 100000; i++ {
+		if s.Sample(m) {
+			kept++
+		}
+	}
+	require.InDelta(t, 10000, kept, 500)
+
+	for _, p := range []float64{0, 1} {
+		s, err := New(Config{Mode: ModeProbability, Probability: p})
+		require.NoError(t, err)
+		require.Equal(t, p == 1, s.Sample(m))
+	}
+}
+
+func TestPerSeriesStable(t *testing.T) {
+	for _, config := range []Config{
+		{Mode: ModeRate, Rate: 10, PerSeries: true},
+		{Mode: ModeProbability, Probability: 0.1, PerSeries: true},
+	} {
+		s, err := New(config)
+		require.NoError(t, err)
+
+		kept := keptSeries(s, 10000)
+		require.InDelta(t, 1000, len(kept), 100, config.Mode)
+
+		// the same series are kept again, by another sampler too
+		require.Equal(t, kept, keptSeries(s, 10000), config.Mode)
+		other, err := New(config)
+		require.NoError(t, err)
+		require.Equal(t, kept, keptSeries(other, 10000), config.Mode)
+	}
+}
+
+func TestPerSeriesProbabilityBounds(t *testing.T) {
+	for _, p := range []float64{0, 1} {
+		s, err := New(Config{Mode: ModeProbability, Probability: p, PerSeries: true})
+		require.NoError(t, err)
+		require.Len(t, keptSeries(s, 1000), int(p*1000))
+	}
+}
+
+func TestReservoir(t *testing.T) {
+	s, err := New(Config{Mode: ModeReservoir, Size: 100})
+	require.NoError(t, err)
+
+	// the first series fill the reservoir
+	require.Len(t, keptSeries(s, 100), 100)
+
+	// once all series were seen, the same series are always kept
+	keptSeries(s, 10000)
+	kept := keptSeries(s, 10000)
+	require.Len(t, kept, 100)
+	require.Equal(t, kept, keptSeries(s, 10000))
+
+	// the series kept are the same whatever the order they are seen in
+	other, err := New(Config{Mode: ModeReservoir, Size: 100})
+	require.NoError(t, err)
+	for i := 9999; i >= 0; i-- {
+		other.Sample(seriesMetric(i))
+	}
+	require.Equal(t, kept, keptSeries(other, 10000))
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, config := range []Config{
+		{},
+		{Mode: "random"},
+		{Mode: ModeRate},
+		{Mode: ModeProbability, Probability: 1.5},
+		{Mode: ModeProbability, Probability: -1},
+		{Mode: ModeReservoir},
+	} {
+		_, err := New(config)
+		require.Error(t, err, config.Mode)
+	}
+}
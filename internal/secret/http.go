@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/tidwall/gjson"
+)
+
+const defaultHTTPTimeout = 5 * time.Second
+
+// maxSecretSize is the maximum size of the responses of a HTTPStore.
+const maxSecretSize = 64 * 1024
+
+// HTTPStore fetches secrets with a GET request to the URL followed by the
+// key.  If Path is set the response is a JSON document holding the secret at
+// this GJSON path, like the data.data.value of the KV engine of Vault.
+type HTTPStore struct {
+	URL     string            `toml:"url"`
+	Path    string            `toml:"json_path"`
+	Headers map[string]string `toml:"headers"`
+	Timeout internal.Duration `toml:"timeout"`
+
+	client *http.Client
+}
+
+func (s *HTTPStore) Get(key string) (string, error) {
+	if s.client == nil {
+		timeout := s.Timeout.Duration
+		if timeout == 0 {
+			timeout = defaultHTTPTimeout
+		}
+		s.client = &http.Client{Timeout: timeout}
+	}
+
+	u := s.URL + url.PathEscape(key)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSecretSize))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("received status code %d from %s", resp.StatusCode, s.URL)
+	}
+
+	if s.Path == "" {
+		return strings.TrimRight(string(body), "\r\n"), nil
+	}
+	result := gjson.GetBytes(body, s.Path)
+	if !result.Exists() {
+		return "", fmt.Errorf("no value at %s in the response", s.Path)
+	}
+	return result.String(), nil
+}
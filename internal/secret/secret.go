@@ -0,0 +1,248 @@
+// Package secret resolves references to secrets in the configuration of the
+// plugins.  A reference has the form:
+//
+//	@{secret:<store>:<key>}
+//
+// and is replaced by the value of the key in the store, either as the whole
+// value of an option or inside of it.  The env and file stores are always
+// available, other stores are added by name.
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// referenceRe matches the references to secrets.
+var referenceRe = regexp.MustCompile(`@\{secret:([A-Za-z0-9_-]+):([^}]+)\}`)
+
+// Redacted replaces resolved secrets in redacted strings.
+const Redacted = "[redacted]"
+
+// Store returns the value of secrets by key.
+type Store interface {
+	Get(key string) (string, error)
+}
+
+// EnvStore returns the value of the environment variable named after the
+// key.
+type EnvStore struct{}
+
+func (EnvStore) Get(key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", key)
+	}
+	return value, nil
+}
+
+// FileStore returns the content of the file at the path of the key, without
+// trailing newlines.  This allows to use Docker and Kubernetes secrets
+// mounted as files.
+type FileStore struct{}
+
+func (FileStore) Get(key string) (string, error) {
+	buf, err := ioutil.ReadFile(key)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(buf, "\r\n")), nil
+}
+
+// Resolver resolves the references to secrets using its stores.  Resolved
+// secrets are cached until Reset is called.
+type Resolver struct {
+	sync.Mutex
+	stores map[string]Store
+	cache  map[string]string
+}
+
+// NewResolver returns a Resolver with the env and file stores.
+func NewResolver() *Resolver {
+	return &Resolver{
+		stores: map[string]Store{
+			"env":  EnvStore{},
+			"file": FileStore{},
+		},
+		cache: make(map[string]string),
+	}
+}
+
+// Add adds the store under the name.
+func (r *Resolver) Add(name string, store Store) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.stores[name]; ok {
+		return fmt.Errorf("duplicate secret store %s", name)
+	}
+	r.stores[name] = store
+	return nil
+}
+
+// Reset forgets the cached secrets so they are fetched again from the
+// stores.
+func (r *Resolver) Reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.cache = make(map[string]string)
+}
+
+// HasReferences reports if s holds references to secrets.
+func HasReferences(s string) bool {
+	return referenceRe.MatchString(s)
+}
+
+// Resolve returns s with its references to secrets replaced by their value.
+func (r *Resolver) Resolve(s string) (string, error) {
+	if !strings.Contains(s, "@{secret:") {
+		return s, nil
+	}
+
+	var err error
+	resolved := referenceRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		var value string
+		value, err = r.get(ref)
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+func (r *Resolver) get(ref string) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+	if value, ok := r.cache[ref]; ok {
+		return value, nil
+	}
+
+	m := referenceRe.FindStringSubmatch(ref)
+	store, ok := r.stores[m[1]]
+	if !ok {
+		return "", fmt.Errorf("unknown secret store %s in %s", m[1], ref)
+	}
+	value, err := store.Get(m[2])
+	if err != nil {
+		return "", fmt.Errorf("could not resolve %s: %v", ref, err)
+	}
+
+	r.cache[ref] = value
+	addRedacted(value)
+	return value, nil
+}
+
+// ResolveStruct resolves the references to secrets in the exported string
+// fields of the struct pointed to by v, including strings in slices and
+// maps and in nested structs.
+func (r *Resolver) ResolveStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+	return r.resolveValue(rv.Elem(), make(map[uintptr]bool))
+}
+
+func (r *Resolver) resolveValue(v reflect.Value, visited map[uintptr]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return nil
+		}
+		visited[v.Pointer()] = true
+		return r.resolveValue(v.Elem(), visited)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath != "" && !f.Anonymous {
+				// unexported, the exported fields of embedded structs
+				// are still resolved
+				continue
+			}
+			if err := r.resolveValue(v.Field(i), visited); err != nil {
+				return fmt.Errorf("%s: %v", t.Field(i).Name, err)
+			}
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		s, err := r.Resolve(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.resolveValue(v.Index(i), visited); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			s, err := r.Resolve(v.MapIndex(key).String())
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(key, reflect.ValueOf(s).Convert(v.Type().Elem()))
+		}
+	}
+	return nil
+}
+
+var redacted = struct {
+	sync.RWMutex
+	values map[string]bool
+}{values: make(map[string]bool)}
+
+// redacting is set once the first secret is added to redacted, it lets
+// Redact skip the lock while no secrets are in use.
+var redacting int32
+
+// minRedactedLength is the length of the shortest secrets redacted, shorter
+// ones would garble the redacted strings.
+const minRedactedLength = 4
+
+func addRedacted(value string) {
+	if len(value) < minRedactedLength {
+		return
+	}
+	redacted.Lock()
+	redacted.values[value] = true
+	redacted.Unlock()
+	atomic.StoreInt32(&redacting, 1)
+}
+
+// Redacting returns true if any secrets have been resolved, and Redact
+// could change its input.
+func Redacting() bool {
+	return atomic.LoadInt32(&redacting) != 0
+}
+
+// Redact returns s with all secrets resolved so far replaced by Redacted.
+func Redact(s string) string {
+	if !Redacting() {
+		return s
+	}
+	redacted.RLock()
+	defer redacted.RUnlock()
+	for value := range redacted.values {
+		if strings.Contains(s, value) {
+			s = strings.Replace(s, value, Redacted, -1)
+		}
+	}
+	return s
+}
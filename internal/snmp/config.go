@@ -0,0 +1,144 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/soniah/gosnmp"
+)
+
+// ClientConfig holds the options of the connections to the SNMP agents of the
+// plugins using SNMP.
+type ClientConfig struct {
+	// Timeout to wait for a response.
+	Timeout internal.Duration
+	Retries int
+	// Values: 1, 2, 3
+	Version uint8
+
+	// Parameters for Version 1 & 2
+	Community string
+
+	// Parameters for Version 2 & 3
+	MaxRepetitions uint8
+
+	// Parameters for Version 3
+	ContextName string
+	// Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+	SecLevel string
+	SecName  string
+	// Values: "MD5", "SHA", "". Default: ""
+	AuthProtocol string
+	AuthPassword string
+	// Values: "DES", "AES", "". Default: ""
+	PrivProtocol string
+	PrivPassword string
+	EngineID     string
+	EngineBoots  uint32
+	EngineTime   uint32
+}
+
+// NewClient returns a client of the agent, in the format ADDR[:PORT], that is
+// not connected yet.
+func NewClient(agent string, c ClientConfig) (*gosnmp.GoSNMP, error) {
+	gs := &gosnmp.GoSNMP{}
+
+	host, portStr, err := net.SplitHostPort(agent)
+	if err != nil {
+		if err, ok := err.(*net.AddrError); !ok || err.Err != "missing port in address" {
+			return nil, fmt.Errorf("parsing host: %v", err)
+		}
+		host = agent
+		portStr = "161"
+	}
+	gs.Target = host
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("parsing port: %v", err)
+	}
+	gs.Port = uint16(port)
+
+	gs.Timeout = c.Timeout.Duration
+
+	gs.Retries = c.Retries
+
+	switch c.Version {
+	case 3:
+		gs.Version = gosnmp.Version3
+	case 2, 0:
+		gs.Version = gosnmp.Version2c
+	case 1:
+		gs.Version = gosnmp.Version1
+	default:
+		return nil, fmt.Errorf("invalid version")
+	}
+
+	if c.Version < 3 {
+		if c.Community == "" {
+			gs.Community = "public"
+		} else {
+			gs.Community = c.Community
+		}
+	}
+
+	gs.MaxRepetitions = c.MaxRepetitions
+
+	if c.Version == 3 {
+		gs.ContextName = c.ContextName
+
+		sp := &gosnmp.UsmSecurityParameters{}
+		gs.SecurityParameters = sp
+		gs.SecurityModel = gosnmp.UserSecurityModel
+
+		switch strings.ToLower(c.SecLevel) {
+		case "noauthnopriv", "":
+			gs.MsgFlags = gosnmp.NoAuthNoPriv
+		case "authnopriv":
+			gs.MsgFlags = gosnmp.AuthNoPriv
+		case "authpriv":
+			gs.MsgFlags = gosnmp.AuthPriv
+		default:
+			return nil, fmt.Errorf("invalid secLevel")
+		}
+
+		sp.UserName = c.SecName
+
+		switch strings.ToLower(c.AuthProtocol) {
+		case "md5":
+			sp.AuthenticationProtocol = gosnmp.MD5
+		case "sha":
+			sp.AuthenticationProtocol = gosnmp.SHA
+		case "":
+			sp.AuthenticationProtocol = gosnmp.NoAuth
+		default:
+			return nil, fmt.Errorf("invalid authProtocol")
+		}
+
+		sp.AuthenticationPassphrase = c.AuthPassword
+
+		switch strings.ToLower(c.PrivProtocol) {
+		case "des":
+			sp.PrivacyProtocol = gosnmp.DES
+		case "aes":
+			sp.PrivacyProtocol = gosnmp.AES
+		case "":
+			sp.PrivacyProtocol = gosnmp.NoPriv
+		default:
+			return nil, fmt.Errorf("invalid privProtocol")
+		}
+
+		sp.PrivacyPassphrase = c.PrivPassword
+
+		sp.AuthoritativeEngineID = c.EngineID
+
+		sp.AuthoritativeEngineBoots = c.EngineBoots
+
+		sp.AuthoritativeEngineTime = c.EngineTime
+	}
+
+	return gs, nil
+}
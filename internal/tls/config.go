@@ -14,6 +14,11 @@ type ClientConfig struct {
 	TLSKey             string `toml:"tls_key"`
 	InsecureSkipVerify bool   `toml:"insecure_skip_verify"`
 
+	// PEM encoded content, used in place of the files above.
+	TLSCAPEM   string `toml:"tls_ca_pem"`
+	TLSCertPEM string `toml:"tls_cert_pem"`
+	TLSKeyPEM  string `toml:"tls_key_pem"`
+
 	// Deprecated in 1.7; use TLS variables above
 	SSLCA   string `toml:"ssl_ca"`
 	SSLCert string `toml:"ssl_cert"`
@@ -45,16 +50,28 @@ func (c *ClientConfig) TLSConfig() (*tls.Config, error) {
 	// want TLS, this will require using another option to determine.  In the
 	// case of an HTTP plugin, you could use `https`.  Other plugins may need
 	// the dedicated option `TLSEnable`.
-	if c.TLSCA == "" && c.TLSKey == "" && c.TLSCert == "" && !c.InsecureSkipVerify {
+	if c.TLSCA == "" && c.TLSKey == "" && c.TLSCert == "" &&
+		c.TLSCAPEM == "" && c.TLSKeyPEM == "" && c.TLSCertPEM == "" &&
+		!c.InsecureSkipVerify {
 		return nil, nil
 	}
 
+	if err := c.checkPEMConflicts(); err != nil {
+		return nil, err
+	}
+
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: c.InsecureSkipVerify,
 		Renegotiation:      tls.RenegotiateNever,
 	}
 
-	if c.TLSCA != "" {
+	if c.TLSCAPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.TLSCAPEM)) {
+			return nil, fmt.Errorf("could not parse any PEM certificates from tls_ca_pem")
+		}
+		tlsConfig.RootCAs = pool
+	} else if c.TLSCA != "" {
 		pool, err := makeCertPool([]string{c.TLSCA})
 		if err != nil {
 			return nil, err
@@ -62,7 +79,25 @@ func (c *ClientConfig) TLSConfig() (*tls.Config, error) {
 		tlsConfig.RootCAs = pool
 	}
 
-	if c.TLSCert != "" && c.TLSKey != "" {
+	certPEM, keyPEM := c.TLSCertPEM, c.TLSKeyPEM
+	if certPEM != "" || keyPEM != "" {
+		var err error
+		if certPEM == "" && c.TLSCert != "" {
+			if certPEM, err = readPEM(c.TLSCert); err != nil {
+				return nil, err
+			}
+		}
+		if keyPEM == "" && c.TLSKey != "" {
+			if keyPEM, err = readPEM(c.TLSKey); err != nil {
+				return nil, err
+			}
+		}
+		if certPEM != "" && keyPEM != "" {
+			if err := loadCertificatePEM(tlsConfig, certPEM, keyPEM); err != nil {
+				return nil, err
+			}
+		}
+	} else if c.TLSCert != "" && c.TLSKey != "" {
 		err := loadCertificate(tlsConfig, c.TLSCert, c.TLSKey)
 		if err != nil {
 			return nil, err
@@ -72,6 +107,21 @@ func (c *ClientConfig) TLSConfig() (*tls.Config, error) {
 	return tlsConfig, nil
 }
 
+// checkPEMConflicts returns an error if both the inline PEM content and the
+// file path are set for the same option.
+func (c *ClientConfig) checkPEMConflicts() error {
+	if c.TLSCAPEM != "" && c.TLSCA != "" {
+		return fmt.Errorf("only one of tls_ca and tls_ca_pem may be set")
+	}
+	if c.TLSCertPEM != "" && c.TLSCert != "" {
+		return fmt.Errorf("only one of tls_cert and tls_cert_pem may be set")
+	}
+	if c.TLSKeyPEM != "" && c.TLSKey != "" {
+		return fmt.Errorf("only one of tls_key and tls_key_pem may be set")
+	}
+	return nil
+}
+
 // TLSConfig returns a tls.Config, may be nil without error if TLS is not
 // configured.
 func (c *ServerConfig) TLSConfig() (*tls.Config, error) {
@@ -117,6 +167,25 @@ func makeCertPool(certFiles []string) (*x509.CertPool, error) {
 	return pool, nil
 }
 
+func readPEM(file string) (string, error) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("could not read %q: %v", file, err)
+	}
+	return string(pem), nil
+}
+
+func loadCertificatePEM(config *tls.Config, certPEM, keyPEM string) error {
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return fmt.Errorf("could not load PEM keypair: %v", err)
+	}
+
+	config.Certificates = []tls.Certificate{cert}
+	config.BuildNameToCertificate()
+	return nil
+}
+
 func loadCertificate(config *tls.Config, certFile, keyFile string) error {
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
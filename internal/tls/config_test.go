@@ -86,6 +86,49 @@ func TestClientConfig(t *testing.T) {
 				SSLKey:  pki.ClientKeyPath(),
 			},
 		},
+		{
+			name: "inline pem",
+			client: tls.ClientConfig{
+				TLSCAPEM:   pki.ReadCACert(),
+				TLSCertPEM: pki.ReadClientCert(),
+				TLSKeyPEM:  pki.ReadClientKey(),
+			},
+		},
+		{
+			name: "inline pem cert with key file",
+			client: tls.ClientConfig{
+				TLSCA:      pki.CACertPath(),
+				TLSCertPEM: pki.ReadClientCert(),
+				TLSKey:     pki.ClientKeyPath(),
+			},
+		},
+		{
+			name: "invalid inline ca",
+			client: tls.ClientConfig{
+				TLSCAPEM: pki.ReadClientKey(),
+			},
+			expNil: true,
+			expErr: true,
+		},
+		{
+			name: "invalid inline cert",
+			client: tls.ClientConfig{
+				TLSCertPEM: pki.ReadClientKey(),
+				TLSKeyPEM:  pki.ReadClientKey(),
+			},
+			expNil: true,
+			expErr: true,
+		},
+		{
+			name: "inline pem conflicts with file",
+			client: tls.ClientConfig{
+				TLSCert:    pki.ClientCertPath(),
+				TLSCertPEM: pki.ReadClientCert(),
+				TLSKeyPEM:  pki.ReadClientKey(),
+			},
+			expNil: true,
+			expErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -224,3 +267,42 @@ func TestConnect(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 200, resp.StatusCode)
 }
+
+func TestConnectInlinePEM(t *testing.T) {
+	clientConfig := tls.ClientConfig{
+		TLSCAPEM:   pki.ReadCACert(),
+		TLSCertPEM: pki.ReadClientCert(),
+		TLSKeyPEM:  pki.ReadClientKey(),
+	}
+
+	serverConfig := tls.ServerConfig{
+		TLSCert:           pki.ServerCertPath(),
+		TLSKey:            pki.ServerKeyPath(),
+		TLSAllowedCACerts: []string{pki.CACertPath()},
+	}
+
+	serverTLSConfig, err := serverConfig.TLSConfig()
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = serverTLSConfig
+
+	ts.StartTLS()
+	defer ts.Close()
+
+	clientTLSConfig, err := clientConfig.TLSConfig()
+	require.NoError(t, err)
+
+	client := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: clientTLSConfig,
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+}
@@ -28,6 +28,10 @@ The commands & flags are:
   --sample-config                print out full sample configuration
   --test                         gather metrics, print them out, and exit;
                                  processors, aggregators, and outputs are not run
+  --test-graph                   initialize all plugins, print the routing of metrics
+                                 from inputs to outputs, and exit
+  --test-graph-metric <metric>   metric in line protocol to check against the plugin
+                                 filters with --test-graph
   --usage <plugin>               print usage for a plugin, ie, 'telegraf --usage mysql'
   --version                      display the version and exit
 
@@ -42,6 +46,9 @@ Examples:
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
+  # check the plugin routing of a metric without running telegraf
+  telegraf --config telegraf.conf --test-graph --test-graph-metric 'cpu,cpu=cpu0 usage_idle=99'
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
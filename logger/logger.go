@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/influxdata/telegraf/internal/secret"
 	"github.com/influxdata/wlog"
 )
 
@@ -24,6 +25,10 @@ type telegrafLog struct {
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
+	if secret.Redacting() {
+		b = []byte(secret.Redact(string(b)))
+	}
+
 	var line []byte
 	if !prefixRegex.Match(b) {
 		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
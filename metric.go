@@ -25,12 +25,19 @@ type Tag struct {
 type Field struct {
 	Key   string
 	Value interface{}
+
+	// Metadata annotates the field for the outputs, like with a type hint or
+	// an exemplar, nil if the field has none.  It is shared with the copies
+	// of the metric and must not be modified.
+	Metadata map[string]interface{}
 }
 
 type Metric interface {
 	// Getting data structure functions
 	Name() string
 	Tags() map[string]string
+	// TagList and FieldList return the sorted tags and the fields of the
+	// metric, the lists may be shared with copies and must not be modified.
 	TagList() []*Tag
 	Fields() map[string]interface{}
 	FieldList() []*Field
@@ -54,12 +61,18 @@ type Metric interface {
 	AddField(key string, value interface{})
 	RemoveField(key string)
 
+	// Field metadata functions.  Replacing the value of a field with
+	// AddField keeps its metadata, removing it drops its metadata.
+	FieldMetadata(key string) map[string]interface{}
+	SetFieldMetadata(field, key string, value interface{})
+
 	SetTime(t time.Time)
 
 	// HashID returns an unique identifier for the series.
 	HashID() uint64
 
-	// Copy returns a deep copy of the Metric.
+	// Copy returns a deep copy of the Metric.  Implementations may defer
+	// copying the tags and fields until the first modification.
 	Copy() Metric
 
 	// Accept marks the metric as processed successfully and written to an
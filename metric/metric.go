@@ -122,13 +122,18 @@ func (m *metric) AddTag(key, value string) {
 		}
 
 		if key == tag.Key {
-			tag.Value = value
+			tags := make([]*telegraf.Tag, len(m.tags))
+			copy(tags, m.tags)
+			tags[i] = &telegraf.Tag{Key: key, Value: value}
+			m.tags = tags
 			return
 		}
 
-		m.tags = append(m.tags, nil)
-		copy(m.tags[i+1:], m.tags[i:])
-		m.tags[i] = &telegraf.Tag{Key: key, Value: value}
+		tags := make([]*telegraf.Tag, len(m.tags)+1)
+		copy(tags, m.tags[:i])
+		tags[i] = &telegraf.Tag{Key: key, Value: value}
+		copy(tags[i+1:], m.tags[i:])
+		m.tags = tags
 		return
 	}
 
@@ -156,9 +161,10 @@ func (m *metric) GetTag(key string) (string, bool) {
 func (m *metric) RemoveTag(key string) {
 	for i, tag := range m.tags {
 		if tag.Key == key {
-			copy(m.tags[i:], m.tags[i+1:])
-			m.tags[len(m.tags)-1] = nil
-			m.tags = m.tags[:len(m.tags)-1]
+			tags := make([]*telegraf.Tag, len(m.tags)-1)
+			copy(tags, m.tags[:i])
+			copy(tags[i:], m.tags[i+1:])
+			m.tags = tags
 			return
 		}
 	}
@@ -167,13 +173,22 @@ func (m *metric) RemoveTag(key string) {
 func (m *metric) AddField(key string, value interface{}) {
 	for i, field := range m.fields {
 		if key == field.Key {
-			m.fields[i] = &telegraf.Field{Key: key, Value: convertField(value)}
+			m.replaceField(i, &telegraf.Field{Key: key, Value: convertField(value), Metadata: field.Metadata})
 			return
 		}
 	}
 	m.fields = append(m.fields, &telegraf.Field{Key: key, Value: convertField(value)})
 }
 
+// replaceField replaces the field at index i in a new list, as the list may be
+// shared with copies.
+func (m *metric) replaceField(i int, field *telegraf.Field) {
+	fields := make([]*telegraf.Field, len(m.fields))
+	copy(fields, m.fields)
+	fields[i] = field
+	m.fields = fields
+}
+
 func (m *metric) HasField(key string) bool {
 	for _, field := range m.fields {
 		if field.Key == key {
@@ -195,36 +210,69 @@ func (m *metric) GetField(key string) (interface{}, bool) {
 func (m *metric) RemoveField(key string) {
 	for i, field := range m.fields {
 		if field.Key == key {
-			copy(m.fields[i:], m.fields[i+1:])
-			m.fields[len(m.fields)-1] = nil
-			m.fields = m.fields[:len(m.fields)-1]
+			fields := make([]*telegraf.Field, len(m.fields)-1)
+			copy(fields, m.fields[:i])
+			copy(fields[i:], m.fields[i+1:])
+			m.fields = fields
 			return
 		}
 	}
 }
 
-func (m *metric) SetTime(t time.Time) {
-	m.tm = t
+// FieldMetadata returns the metadata of the field, nil if the field has none
+// or does not exist.
+func (m *metric) FieldMetadata(key string) map[string]interface{} {
+	for _, field := range m.fields {
+		if field.Key == key {
+			return field.Metadata
+		}
+	}
+	return nil
 }
 
-func (m *metric) Copy() telegraf.Metric {
-	m2 := &metric{
-		name:      m.name,
-		tags:      make([]*telegraf.Tag, len(m.tags)),
-		fields:    make([]*telegraf.Field, len(m.fields)),
-		tm:        m.tm,
-		tp:        m.tp,
-		aggregate: m.aggregate,
-	}
+// SetFieldMetadata sets the metadata key of the field, or removes it if the
+// value is nil.  Nothing is set if the field does not exist.
+func (m *metric) SetFieldMetadata(field, key string, value interface{}) {
+	for i, f := range m.fields {
+		if f.Key != field {
+			continue
+		}
 
-	for i, tag := range m.tags {
-		m2.tags[i] = tag
+		metadata := make(map[string]interface{}, len(f.Metadata)+1)
+		for k, v := range f.Metadata {
+			metadata[k] = v
+		}
+		if value == nil {
+			delete(metadata, key)
+		} else {
+			metadata[key] = value
+		}
+		if len(metadata) == 0 {
+			metadata = nil
+		}
+		m.replaceField(i, &telegraf.Field{Key: f.Key, Value: f.Value, Metadata: metadata})
+		return
 	}
+}
 
-	for i, field := range m.fields {
-		m2.fields[i] = field
-	}
-	return m2
+func (m *metric) SetTime(t time.Time) {
+	m.tm = t
+}
+
+// Copy returns a copy of the metric that shares the tag and field lists
+// with the original.
+//
+// The lists are never modified in place: tags and fields are replaced,
+// inserted or removed in a new list, only appending may use the spare
+// capacity of a list.  The lists of the copy are limited to their length, so
+// appending to them allocates a new list and the original can still append to
+// its own lists without the copy seeing it.  The original is not modified, so
+// the same metric can be copied concurrently.
+func (m *metric) Copy() telegraf.Metric {
+	m2 := *m
+	m2.tags = m.tags[:len(m.tags):len(m.tags)]
+	m2.fields = m.fields[:len(m.fields):len(m.fields)]
+	return &m2
 }
 
 func (m *metric) SetAggregate(b bool) {
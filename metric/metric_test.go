@@ -340,3 +340,154 @@ func TestCopyAggreate(t *testing.T) {
 	m2 := m1.Copy()
 	assert.True(t, m2.IsAggregate())
 }
+
+func TestCopyTagsIndependent(t *testing.T) {
+	m1 := baseMetric()
+	m1.AddTag("host", "localhost")
+	m2 := m1.Copy()
+
+	m2.AddTag("host", "example.org")
+	m2.AddTag("dc", "us-east-1")
+	m1.AddTag("cpu", "cpu0")
+
+	assert.Equal(t, map[string]string{"host": "localhost", "cpu": "cpu0"}, m1.Tags())
+	assert.Equal(t, map[string]string{"host": "example.org", "dc": "us-east-1"}, m2.Tags())
+
+	m2.RemoveTag("host")
+	assert.True(t, m1.HasTag("host"))
+	assert.False(t, m2.HasTag("host"))
+}
+
+func TestCopyFieldsIndependent(t *testing.T) {
+	m1 := baseMetric()
+	m2 := m1.Copy()
+	m3 := m2.Copy()
+
+	m2.AddField("value", 99.0)
+	m2.AddField("idle", 1.0)
+	m3.RemoveField("value")
+
+	assert.Equal(t, map[string]interface{}{"value": 1.0}, m1.Fields())
+	assert.Equal(t, map[string]interface{}{"value": 99.0, "idle": 1.0}, m2.Fields())
+	assert.Equal(t, map[string]interface{}{}, m3.Fields())
+}
+
+func TestCopyOriginalModified(t *testing.T) {
+	m1 := baseMetric()
+	m1.AddTag("host", "localhost")
+	m1.AddTag("dc", "us-east-1")
+	m1.AddField("idle", 1.0)
+	m2 := m1.Copy()
+
+	m1.AddTag("cpu", "cpu0")
+	m1.AddTag("host", "example.org")
+	m1.RemoveTag("dc")
+	m1.AddField("value", 99.0)
+	m1.RemoveField("idle")
+	m1.AddField("user", 2.0)
+
+	assert.Equal(t, map[string]string{"host": "localhost", "dc": "us-east-1"}, m2.Tags())
+	assert.Equal(t, map[string]interface{}{"value": 1.0, "idle": 1.0}, m2.Fields())
+}
+
+func TestCopyDoesNotModifyOriginal(t *testing.T) {
+	m := baseMetric().(*metric)
+	m.fields = append(make([]*telegraf.Field, 0, 4), m.fields...)
+	before := *m
+
+	m.Copy()
+	assert.Equal(t, before, *m)
+	assert.Equal(t, 4, cap(m.fields))
+}
+
+func TestFieldMetadata(t *testing.T) {
+	m := baseMetric()
+	require.Nil(t, m.FieldMetadata("value"))
+
+	m.SetFieldMetadata("value", "unit", "seconds")
+	m.SetFieldMetadata("value", "exemplar", 0.5)
+	m.SetFieldMetadata("missing", "unit", "seconds")
+	require.Equal(t, map[string]interface{}{"unit": "seconds", "exemplar": 0.5}, m.FieldMetadata("value"))
+	require.Nil(t, m.FieldMetadata("missing"))
+	require.False(t, m.HasField("missing"))
+
+	// replacing the value keeps the metadata, removing the field drops it
+	m.AddField("value", int64(2))
+	require.Equal(t, map[string]interface{}{"unit": "seconds", "exemplar": 0.5}, m.FieldMetadata("value"))
+	m.RemoveField("value")
+	m.AddField("value", 3.0)
+	require.Nil(t, m.FieldMetadata("value"))
+
+	m.SetFieldMetadata("value", "unit", "seconds")
+	m.SetFieldMetadata("value", "unit", nil)
+	require.Nil(t, m.FieldMetadata("value"))
+}
+
+func TestCopyFieldMetadataIndependent(t *testing.T) {
+	m1 := baseMetric()
+	m1.SetFieldMetadata("value", "unit", "seconds")
+	m2 := m1.Copy()
+
+	m2.SetFieldMetadata("value", "unit", "milliseconds")
+	m2.SetFieldMetadata("value", "exemplar", 0.5)
+
+	require.Equal(t, map[string]interface{}{"unit": "seconds"}, m1.FieldMetadata("value"))
+	require.Equal(t, map[string]interface{}{"unit": "milliseconds", "exemplar": 0.5}, m2.FieldMetadata("value"))
+
+	tm, _ := WithTracking(m1.Copy(), func(telegraf.DeliveryInfo) {})
+	require.Equal(t, map[string]interface{}{"unit": "seconds"}, tm.Copy().FieldMetadata("value"))
+}
+
+// deepCopy is the copy done by Copy prior to sharing the tags and fields.
+func deepCopy(m *metric) telegraf.Metric {
+	m2 := &metric{
+		name:      m.name,
+		tags:      make([]*telegraf.Tag, len(m.tags)),
+		fields:    make([]*telegraf.Field, len(m.fields)),
+		tm:        m.tm,
+		tp:        m.tp,
+		aggregate: m.aggregate,
+	}
+	copy(m2.tags, m.tags)
+	copy(m2.fields, m.fields)
+	return m2
+}
+
+func benchMetric() telegraf.Metric {
+	m, _ := New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0", "dc": "us-east-1"},
+		map[string]interface{}{"usage_idle": 99.0, "usage_user": 0.5, "usage_system": 0.5},
+		time.Unix(0, 0))
+	return m
+}
+
+// readOnly simulates a processor that only looks at the metric.
+func readOnly(m telegraf.Metric) bool {
+	_, ok := m.GetTag("host")
+	return ok && m.HasField("usage_idle")
+}
+
+func BenchmarkCopyReadOnly(b *testing.B) {
+	m := benchMetric()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		readOnly(m.Copy())
+	}
+}
+
+func BenchmarkDeepCopyReadOnly(b *testing.B) {
+	m := benchMetric().(*metric)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		readOnly(deepCopy(m))
+	}
+}
+
+func BenchmarkCopyModify(b *testing.B) {
+	m := benchMetric()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		m2 := m.Copy()
+		m2.AddTag("host", "example.org")
+	}
+}
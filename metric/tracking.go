@@ -24,6 +24,16 @@ func WithGroupTracking(metric []telegraf.Metric, fn NotifyFunc) ([]telegraf.Metr
 	return newTrackingMetricGroup(metric, fn)
 }
 
+// Unwrap returns the metric without its tracking, for the metrics kept past
+// their delivery like the ones aggregated.  The tracking metric must still be
+// accepted, rejected or dropped.
+func Unwrap(m telegraf.Metric) telegraf.Metric {
+	if tm, ok := m.(*trackingMetric); ok {
+		return tm.Metric
+	}
+	return m
+}
+
 func EnableDebugFinalizer() {
 	finalizer = debugFinalizer
 }
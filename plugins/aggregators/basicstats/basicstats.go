@@ -3,13 +3,15 @@ package basicstats
 import (
 	"log"
 	"math"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 )
 
 type BasicStats struct {
-	Stats []string `toml:"stats"`
+	Stats      []string `toml:"stats"`
+	RateFields []string `toml:"rate_fields"`
 
 	cache       map[uint64]aggregate
 	statsConfig *configuredStats
@@ -44,6 +46,12 @@ type basicstats struct {
 	sum   float64
 	mean  float64
 	M2    float64 //intermedia value for variance/stdev
+
+	// rate of counters
+	first    time.Time
+	last     time.Time
+	lastv    float64
+	increase float64
 }
 
 var sampleConfig = `
@@ -53,6 +61,9 @@ var sampleConfig = `
   ## If true, the original metric will be dropped by the
   ## aggregator and will not get sent to the output plugins.
   drop_original = false
+
+  ## Counter fields to push the per second rate of, as <field>_rate
+  # rate_fields = []
 `
 
 func (m *BasicStats) SampleConfig() string {
@@ -81,6 +92,9 @@ func (m *BasicStats) Add(in telegraf.Metric) {
 					mean:  fv,
 					sum:   fv,
 					M2:    0.0,
+					first: in.Time(),
+					last:  in.Time(),
+					lastv: fv,
 				}
 			}
 		}
@@ -97,6 +111,9 @@ func (m *BasicStats) Add(in telegraf.Metric) {
 						mean:  fv,
 						sum:   fv,
 						M2:    0.0,
+						first: in.Time(),
+						last:  in.Time(),
+						lastv: fv,
 					}
 					continue
 				}
@@ -125,6 +142,14 @@ func (m *BasicStats) Add(in telegraf.Metric) {
 				}
 				//sum compute
 				tmp.sum += fv
+				//rate compute, a decrease is a counter reset from zero
+				if fv >= tmp.lastv {
+					tmp.increase += fv - tmp.lastv
+				} else {
+					tmp.increase += fv
+				}
+				tmp.lastv = fv
+				tmp.last = in.Time()
 				//store final data
 				m.cache[id].fields[field.Key] = tmp
 			}
@@ -167,6 +192,12 @@ func (m *BasicStats) Push(acc telegraf.Accumulator) {
 				}
 			}
 			//if count == 1 StdDev = infinite => so I won't send data
+
+			if m.isRateField(k) {
+				if elapsed := v.last.Sub(v.first).Seconds(); elapsed > 0 {
+					fields[k+"_rate"] = v.increase / elapsed
+				}
+			}
 		}
 
 		if len(fields) > 0 {
@@ -175,6 +206,15 @@ func (m *BasicStats) Push(acc telegraf.Accumulator) {
 	}
 }
 
+func (m *BasicStats) isRateField(name string) bool {
+	for _, field := range m.RateFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
 func parseStats(names []string) *configuredStats {
 
 	parsed := &configuredStats{}
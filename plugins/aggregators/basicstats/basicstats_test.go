@@ -530,3 +530,65 @@ func TestBasicStatsWithDefaultStats(t *testing.T) {
 	assert.True(t, acc.HasField("m1", "a_s2"))
 	assert.False(t, acc.HasField("m1", "a_sum"))
 }
+
+func TestBasicStatsWithRate(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		rate   float64
+	}{
+		{
+			name:   "increasing counter",
+			values: []int64{100, 110, 130, 160},
+			rate:   2,
+		},
+		{
+			name:   "counter reset",
+			values: []int64{100, 110, 10, 20},
+			rate:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregator := NewBasicStats()
+			aggregator.Stats = []string{"max"}
+			aggregator.RateFields = []string{"bytes"}
+
+			start := time.Unix(0, 0)
+			for i, v := range tt.values {
+				aggregator.Add(testutil.MustMetric("net",
+					map[string]string{},
+					map[string]interface{}{"bytes": v, "packets": v},
+					start.Add(time.Duration(i)*10*time.Second)))
+			}
+
+			acc := testutil.Accumulator{}
+			aggregator.Push(&acc)
+
+			max := float64(tt.values[0])
+			for _, v := range tt.values {
+				max = math.Max(max, float64(v))
+			}
+			expectedFields := map[string]interface{}{
+				"bytes_max":   max,
+				"bytes_rate":  tt.rate,
+				"packets_max": max,
+			}
+			acc.AssertContainsTaggedFields(t, "net", expectedFields, map[string]string{})
+		})
+	}
+}
+
+func TestBasicStatsWithRateSingleValue(t *testing.T) {
+	aggregator := NewBasicStats()
+	aggregator.Stats = []string{}
+	aggregator.RateFields = []string{"bytes"}
+
+	aggregator.Add(testutil.MustMetric("net", map[string]string{},
+		map[string]interface{}{"bytes": int64(100)}, time.Unix(0, 0)))
+
+	acc := testutil.Accumulator{}
+	aggregator.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+}
@@ -0,0 +1,208 @@
+package derivative
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// Derivative computes the rate of change of the fields of each series.
+type Derivative struct {
+	Variable    string  `toml:"variable"`
+	Suffix      string  `toml:"suffix"`
+	MaxRollOver float64 `toml:"max_roll_over"`
+
+	cache map[uint64]*aggregate
+}
+
+type aggregate struct {
+	name    string
+	tags    map[string]string
+	updated bool
+
+	// time and values of the last metric added
+	last   time.Time
+	values map[string]float64
+
+	fields map[string]*derivative
+}
+
+// derivative is the sum of the changes of a field and of the matching
+// changes of time or of the variable.
+type derivative struct {
+	delta float64
+	by    float64
+}
+
+// NewDerivative creates a new derivative aggregator.
+func NewDerivative() *Derivative {
+	d := &Derivative{
+		Suffix: "_rate",
+	}
+	d.cache = make(map[uint64]*aggregate)
+	return d
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## The derivative is computed with respect to time in seconds by default.
+  ## If set, the change of this field is used instead, for example to compute
+  ## the bytes per request from two counters.
+  # variable = ""
+
+  ## Suffix added to the name of the fields to form the name of the
+  ## derivative.
+  # suffix = "_rate"
+
+  ## Decreasing values are handled as counter resets and are ignored.  If
+  ## set, they are instead handled as counters wrapping around at this value.
+  # max_roll_over = 0.0
+`
+
+func (d *Derivative) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Derivative) Description() string {
+	return "Calculate the rate of change of each field of a series."
+}
+
+func (d *Derivative) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := d.cache[id]
+	if !ok {
+		a = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			values: make(map[string]float64),
+			fields: make(map[string]*derivative),
+		}
+		d.cache[id] = a
+	} else if !in.Time().After(a.last) {
+		return
+	}
+
+	values := make(map[string]float64)
+	for k, v := range in.Fields() {
+		if fv, ok := convert(v); ok {
+			values[k] = fv
+		}
+	}
+
+	if ok {
+		d.addChanges(a, values, in.Time().Sub(a.last).Seconds())
+	}
+
+	a.updated = true
+	a.last = in.Time()
+	for k, v := range values {
+		a.values[k] = v
+	}
+}
+
+// addChanges adds the change of each field since the last metric of the
+// series, elapsed is the time since the last metric in seconds.
+func (d *Derivative) addChanges(a *aggregate, values map[string]float64, elapsed float64) {
+	by := elapsed
+	if d.Variable != "" {
+		prev, ok := a.values[d.Variable]
+		value, found := values[d.Variable]
+		if !ok || !found {
+			return
+		}
+		by, ok = d.delta(prev, value)
+		if !ok {
+			return
+		}
+	}
+
+	for k, value := range values {
+		if k == d.Variable {
+			continue
+		}
+		prev, ok := a.values[k]
+		if !ok {
+			continue
+		}
+		delta, ok := d.delta(prev, value)
+		if !ok {
+			continue
+		}
+
+		f, ok := a.fields[k]
+		if !ok {
+			f = &derivative{}
+			a.fields[k] = f
+		}
+		f.delta += delta
+		f.by += by
+	}
+}
+
+// delta returns the change from prev to value, taking counter resets and
+// roll overs into account.
+func (d *Derivative) delta(prev, value float64) (float64, bool) {
+	delta := value - prev
+	if delta >= 0 {
+		return delta, true
+	}
+	if d.MaxRollOver > 0 {
+		return d.MaxRollOver - prev + value, true
+	}
+	return 0, false
+}
+
+func (d *Derivative) Push(acc telegraf.Accumulator) {
+	for _, a := range d.cache {
+		fields := map[string]interface{}{}
+		for k, f := range a.fields {
+			if f.by == 0 {
+				continue
+			}
+			fields[k+d.Suffix] = f.delta / f.by
+		}
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags)
+		}
+	}
+}
+
+// Reset clears the derivatives, while keeping the last values of the series
+// so the next period starts from them.  Series without metrics during the
+// period are removed.
+func (d *Derivative) Reset() {
+	for id, a := range d.cache {
+		if !a.updated {
+			delete(d.cache, id)
+			continue
+		}
+		a.updated = false
+		a.fields = make(map[string]*derivative)
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("derivative", func() telegraf.Aggregator {
+		return NewDerivative()
+	})
+}
@@ -0,0 +1,134 @@
+package derivative
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1500000000, 0)
+
+func newMetric(t *testing.T, seconds int, fields map[string]interface{}) telegraf.Metric {
+	m, err := metric.New("net",
+		map[string]string{"interface": "eth0"},
+		fields,
+		start.Add(time.Duration(seconds)*time.Second),
+	)
+	require.NoError(t, err)
+	return m
+}
+
+func TestSteadyRate(t *testing.T) {
+	acc := testutil.Accumulator{}
+	derivative := NewDerivative()
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(100), "state": "up"}))
+	derivative.Add(newMetric(t, 10, map[string]interface{}{"bytes": int64(200), "state": "up"}))
+	derivative.Add(newMetric(t, 20, map[string]interface{}{"bytes": int64(300), "state": "up"}))
+	derivative.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"bytes_rate": float64(10)},
+		map[string]string{"interface": "eth0"})
+}
+
+func TestSingleMetricNoRate(t *testing.T) {
+	acc := testutil.Accumulator{}
+	derivative := NewDerivative()
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(100)}))
+	derivative.Push(&acc)
+
+	require.Empty(t, acc.Metrics)
+}
+
+func TestRateAcrossPeriods(t *testing.T) {
+	acc := testutil.Accumulator{}
+	derivative := NewDerivative()
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(100)}))
+	derivative.Add(newMetric(t, 10, map[string]interface{}{"bytes": int64(200)}))
+	derivative.Push(&acc)
+	derivative.Reset()
+
+	// The last metric of the previous period is the start of this one.
+	derivative.Add(newMetric(t, 20, map[string]interface{}{"bytes": int64(400)}))
+	acc.ClearMetrics()
+	derivative.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"bytes_rate": float64(20)},
+		map[string]string{"interface": "eth0"})
+}
+
+func TestSeriesRemovedAfterIdlePeriod(t *testing.T) {
+	derivative := NewDerivative()
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(100)}))
+	derivative.Reset()
+	require.Len(t, derivative.cache, 1)
+	derivative.Reset()
+	require.Len(t, derivative.cache, 0)
+}
+
+func TestCounterReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	derivative := NewDerivative()
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(100)}))
+	derivative.Add(newMetric(t, 10, map[string]interface{}{"bytes": int64(200)}))
+	derivative.Add(newMetric(t, 20, map[string]interface{}{"bytes": int64(50)}))
+	derivative.Add(newMetric(t, 30, map[string]interface{}{"bytes": int64(150)}))
+	derivative.Push(&acc)
+
+	// The decrease is skipped, leaving 200 bytes over 20 seconds.
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"bytes_rate": float64(10)},
+		map[string]string{"interface": "eth0"})
+}
+
+func TestMaxRollOver(t *testing.T) {
+	acc := testutil.Accumulator{}
+	derivative := NewDerivative()
+	derivative.MaxRollOver = 1000
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(900)}))
+	derivative.Add(newMetric(t, 10, map[string]interface{}{"bytes": int64(100)}))
+	derivative.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"bytes_rate": float64(20)},
+		map[string]string{"interface": "eth0"})
+}
+
+func TestVariable(t *testing.T) {
+	acc := testutil.Accumulator{}
+	derivative := NewDerivative()
+	derivative.Variable = "requests"
+	derivative.Suffix = "_per_request"
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(1000), "requests": int64(10)}))
+	derivative.Add(newMetric(t, 10, map[string]interface{}{"bytes": int64(3000), "requests": int64(20)}))
+	derivative.Add(newMetric(t, 20, map[string]interface{}{"bytes": int64(4000), "requests": int64(30)}))
+	derivative.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"bytes_per_request": float64(150)},
+		map[string]string{"interface": "eth0"})
+}
+
+func TestVariableMissing(t *testing.T) {
+	acc := testutil.Accumulator{}
+	derivative := NewDerivative()
+	derivative.Variable = "requests"
+
+	derivative.Add(newMetric(t, 0, map[string]interface{}{"bytes": int64(1000)}))
+	derivative.Add(newMetric(t, 10, map[string]interface{}{"bytes": int64(3000)}))
+	derivative.Push(&acc)
+
+	require.Empty(t, acc.Metrics)
+}
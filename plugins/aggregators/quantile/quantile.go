@@ -0,0 +1,146 @@
+package quantile
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to emit, between 0 and 1.
+  # quantiles = [0.25, 0.5, 0.75, 0.9, 0.99]
+
+  ## Compression of the t-digest, larger values give more accurate
+  ## quantiles but use more memory.
+  # compression = 100.0
+
+  ## If true, the digests are kept between periods and the quantiles are
+  ## computed over all values seen since Telegraf started.
+  # cumulative = false
+`
+
+type Quantile struct {
+	Quantiles   []float64 `toml:"quantiles"`
+	Compression float64   `toml:"compression"`
+	Cumulative  bool      `toml:"cumulative"`
+
+	cache map[uint64]aggregate
+}
+
+type aggregate struct {
+	name    string
+	tags    map[string]string
+	digests map[string]*tdigest
+}
+
+func NewQuantile() *Quantile {
+	q := &Quantile{
+		Quantiles:   []float64{0.25, 0.5, 0.75, 0.9, 0.99},
+		Compression: 100,
+	}
+	q.cache = make(map[uint64]aggregate)
+	return q
+}
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+func (q *Quantile) Init() error {
+	if q.Compression <= 0 {
+		return fmt.Errorf("compression must be positive")
+	}
+	for _, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			return fmt.Errorf("invalid quantile %v, must be between 0 and 1", quantile)
+		}
+	}
+	return nil
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		a = aggregate{
+			name:    in.Name(),
+			tags:    in.Tags(),
+			digests: make(map[string]*tdigest),
+		}
+		q.cache[id] = a
+	}
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		digest, ok := a.digests[k]
+		if !ok {
+			digest = newTDigest(q.Compression)
+			a.digests[k] = digest
+		}
+		digest.add(fv)
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	for _, a := range q.cache {
+		fields := make(map[string]interface{})
+		for k, digest := range a.digests {
+			fields[k+"_count"] = int64(digest.count)
+			fields[k+"_min"] = digest.min
+			fields[k+"_max"] = digest.max
+			for _, quantile := range q.Quantiles {
+				fields[k+"_"+fieldSuffix(quantile)] = digest.quantile(quantile)
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags)
+		}
+	}
+}
+
+func (q *Quantile) Reset() {
+	if q.Cumulative {
+		return
+	}
+	q.cache = make(map[uint64]aggregate)
+}
+
+// fieldSuffix returns the suffix of the field for a quantile as a
+// percentile, for example p99 for 0.99 and p99.9 for 0.999.
+func fieldSuffix(quantile float64) string {
+	return "p" + strconv.FormatFloat(quantile*100, 'g', 6, 64)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return NewQuantile()
+	})
+}
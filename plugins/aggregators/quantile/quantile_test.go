@@ -0,0 +1,168 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("response",
+		map[string]string{"host": "server01"},
+		fields,
+		time.Unix(1500000000, 0),
+	)
+	return m
+}
+
+// exact returns the exact value at quantile q of the sorted values.
+func exact(sorted []float64, q float64) float64 {
+	i := int(q * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// rank returns the fraction of the sorted values below v.
+func rank(sorted []float64, v float64) float64 {
+	return float64(sort.SearchFloat64s(sorted, v)) / float64(len(sorted))
+}
+
+func TestQuantilesAccurate(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	distributions := map[string]func() float64{
+		"uniform":     func() float64 { return r.Float64() * 1000 },
+		"normal":      func() float64 { return r.NormFloat64()*20 + 100 },
+		"exponential": func() float64 { return r.ExpFloat64() * 50 },
+	}
+
+	for name, next := range distributions {
+		t.Run(name, func(t *testing.T) {
+			digest := newTDigest(100)
+			values := make([]float64, 0, 20000)
+			for i := 0; i < 20000; i++ {
+				v := next()
+				values = append(values, v)
+				digest.add(v)
+			}
+			sort.Float64s(values)
+
+			for _, q := range []float64{0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 0.999} {
+				est := digest.quantile(q)
+				tolerance := 0.01
+				if q < 0.05 || q > 0.95 {
+					tolerance = 0.002
+				}
+				require.InDelta(t, q, rank(values, est), tolerance,
+					"quantile %v: estimated %v, exact %v", q, est, exact(values, q))
+			}
+			require.Equal(t, values[0], digest.quantile(0))
+			require.Equal(t, values[len(values)-1], digest.quantile(1))
+			require.True(t, len(digest.centroids) < 200)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	a := newTDigest(100)
+	b := newTDigest(100)
+	values := make([]float64, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		v := r.Float64()
+		values = append(values, v)
+		if i%2 == 0 {
+			a.add(v)
+		} else {
+			b.add(v)
+		}
+	}
+	sort.Float64s(values)
+
+	a.merge(b)
+	require.Equal(t, float64(10000), a.count)
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		require.InDelta(t, q, rank(values, a.quantile(q)), 0.01)
+	}
+}
+
+func TestPush(t *testing.T) {
+	q := NewQuantile()
+	q.Quantiles = []float64{0.5, 0.999}
+	require.NoError(t, q.Init())
+
+	for i := 1; i <= 5; i++ {
+		q.Add(newMetric(map[string]interface{}{
+			"latency": int64(i),
+			"status":  "ok",
+		}))
+	}
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "response", map[string]interface{}{
+		"latency_count": int64(5),
+		"latency_min":   float64(1),
+		"latency_max":   float64(5),
+		"latency_p50":   float64(3),
+		"latency_p99.9": float64(5),
+	}, map[string]string{"host": "server01"})
+}
+
+func TestReset(t *testing.T) {
+	q := NewQuantile()
+	require.NoError(t, q.Init())
+	q.Add(newMetric(map[string]interface{}{"latency": 10.0}))
+	q.Reset()
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+	require.Len(t, acc.Metrics, 0)
+}
+
+func TestCumulative(t *testing.T) {
+	q := NewQuantile()
+	q.Cumulative = true
+	require.NoError(t, q.Init())
+
+	q.Add(newMetric(map[string]interface{}{"latency": 10.0}))
+	q.Reset()
+	q.Add(newMetric(map[string]interface{}{"latency": 20.0}))
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, int64(2), acc.Metrics[0].Fields["latency_count"])
+	require.Equal(t, 10.0, acc.Metrics[0].Fields["latency_min"])
+	require.Equal(t, 20.0, acc.Metrics[0].Fields["latency_max"])
+}
+
+func TestFieldSuffix(t *testing.T) {
+	require.Equal(t, "p50", fieldSuffix(0.5))
+	require.Equal(t, "p99", fieldSuffix(0.99))
+	require.Equal(t, "p99.9", fieldSuffix(0.999))
+	require.Equal(t, "p0", fieldSuffix(0))
+	require.Equal(t, "p100", fieldSuffix(1))
+}
+
+func TestInit(t *testing.T) {
+	q := NewQuantile()
+	q.Quantiles = []float64{1.5}
+	require.Error(t, q.Init())
+
+	q = NewQuantile()
+	q.Compression = 0
+	require.Error(t, q.Init())
+}
+
+func TestEmpty(t *testing.T) {
+	require.True(t, math.IsNaN(newTDigest(100).quantile(0.5)))
+}
@@ -0,0 +1,136 @@
+package quantile
+
+import (
+	"math"
+	"sort"
+)
+
+// centroid is a cluster of values of a t-digest.
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// tdigest is a merging t-digest, see "Computing Extremely Accurate Quantiles
+// Using t-Digests" by Ted Dunning and Otmar Ertl.  Values are buffered and
+// merged into the centroids once the buffer is full.  The compression bounds
+// the number of centroids, larger values are more accurate but use more
+// memory.
+type tdigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min         float64
+	max         float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+func (t *tdigest) add(value float64) {
+	t.addCentroid(centroid{mean: value, count: 1})
+}
+
+func (t *tdigest) addCentroid(c centroid) {
+	t.buffer = append(t.buffer, c)
+	t.count += c.count
+	if c.mean < t.min {
+		t.min = c.mean
+	}
+	if c.mean > t.max {
+		t.max = c.mean
+	}
+	if len(t.buffer) >= t.bufferSize() {
+		t.compress()
+	}
+}
+
+// merge adds the values of the digest o.
+func (t *tdigest) merge(o *tdigest) {
+	o.compress()
+	for _, c := range o.centroids {
+		t.addCentroid(c)
+	}
+}
+
+func (t *tdigest) bufferSize() int {
+	return int(5*t.compression) + 1
+}
+
+// compress merges the buffered values into the centroids.  Neighbouring
+// centroids are merged as long as the merged centroid spans at most one unit
+// of the scale function, which keeps the centroids at the tails small.
+func (t *tdigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	weight := 0.0
+	for _, c := range all[1:] {
+		q0 := weight / t.count
+		q2 := (weight + cur.count + c.count) / t.count
+		if t.scale(q2)-t.scale(q0) <= 1 {
+			cur.mean += (c.mean - cur.mean) * c.count / (cur.count + c.count)
+			cur.count += c.count
+			continue
+		}
+		merged = append(merged, cur)
+		weight += cur.count
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = t.buffer[:0]
+}
+
+// scale is the k1 scale function mapping a quantile to the number of
+// centroids below it.
+func (t *tdigest) scale(q float64) float64 {
+	if q > 1 {
+		q = 1
+	}
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// quantile returns the estimated value at quantile q, interpolating between
+// the centers of the centroids and the min and max values.
+func (t *tdigest) quantile(q float64) float64 {
+	t.compress()
+	if len(t.centroids) == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+
+	index := q * t.count
+	first := t.centroids[0]
+	if index < first.count/2 {
+		return t.min + (first.mean-t.min)*index/(first.count/2)
+	}
+
+	weight := first.count / 2
+	for i := 0; i < len(t.centroids)-1; i++ {
+		a, b := t.centroids[i], t.centroids[i+1]
+		delta := (a.count + b.count) / 2
+		if weight+delta > index {
+			return a.mean + (b.mean-a.mean)*(index-weight)/delta
+		}
+		weight += delta
+	}
+
+	last := t.centroids[len(t.centroids)-1]
+	return last.mean + (t.max-last.mean)*(index-weight)/(last.count/2)
+}
@@ -1,9 +1,11 @@
 package beanstalkd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/textproto"
+	"strings"
 	"sync"
 
 	"github.com/influxdata/telegraf"
@@ -20,6 +22,10 @@ const sampleConfig = `
   tubes = ["notifications"]
 `
 
+// errNotFound is returned for the tubes not existing, or deleted after being
+// listed as they had no more jobs and clients.
+var errNotFound = errors.New("NOT_FOUND")
+
 type Beanstalkd struct {
 	Server string   `toml:"server"`
 	Tubes  []string `toml:"tubes"`
@@ -62,7 +68,10 @@ func (b *Beanstalkd) Gather(acc telegraf.Accumulator) error {
 	for _, tube := range tubes {
 		wg.Add(1)
 		go func(tube string) {
-			b.gatherTubeStats(connection, tube, acc)
+			err := b.gatherTubeStats(connection, tube, acc)
+			if err != nil && err != errNotFound {
+				acc.AddError(fmt.Errorf("tube %s: %v", tube, err))
+			}
 			wg.Done()
 		}(tube)
 	}
@@ -169,7 +178,7 @@ func (b *Beanstalkd) gatherTubeStats(connection *textproto.Conn, tube string, ac
 }
 
 func runQuery(connection *textproto.Conn, cmd string, result interface{}) error {
-	requestId, err := connection.Cmd(cmd)
+	requestId, err := connection.Cmd("%s", cmd)
 	if err != nil {
 		return err
 	}
@@ -182,9 +191,16 @@ func runQuery(connection *textproto.Conn, cmd string, result interface{}) error
 		return err
 	}
 
+	// the errors are a single line, like NOT_FOUND or INTERNAL_ERROR
+	if status == errNotFound.Error() {
+		return errNotFound
+	}
+	if !strings.HasPrefix(status, "OK ") {
+		return fmt.Errorf("%s failed: %s", cmd, status)
+	}
 	size := 0
 	if _, err = fmt.Sscanf(status, "OK %d", &size); err != nil {
-		return err
+		return fmt.Errorf("invalid response to %s: %q", cmd, status)
 	}
 
 	body := make([]byte, size+2)
@@ -92,6 +92,29 @@ func TestBeanstalkd(t *testing.T) {
 	}
 }
 
+func TestBeanstalkdTubeErrors(t *testing.T) {
+	server, err := startTestServer(t)
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverAddress := server.Addr().String()
+	plugin := beanstalkd.Beanstalkd{
+		Server: serverAddress,
+		Tubes:  []string{"test", "unknown", "broken"},
+	}
+
+	// the tubes not found are skipped, the other errors reported and the
+	// stats of the other tubes kept
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.EqualError(t, acc.Errors[0], "tube broken: stats-tube broken failed: INTERNAL_ERROR")
+
+	acc.AssertContainsTaggedFields(t, "beanstalkd_overview", overviewFields, getOverviewTags(serverAddress))
+	acc.AssertContainsTaggedFields(t, "beanstalkd_tube", testTubeFields, getTubeTags(serverAddress, "test"))
+	require.Equal(t, 2, len(acc.Metrics))
+}
+
 func startTestServer(t *testing.T) (net.Listener, error) {
 	server, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
@@ -134,6 +157,8 @@ func startTestServer(t *testing.T) (net.Listener, error) {
 				sendSuccessResponse(statsTubeTestResponse)
 			case "stats-tube unknown":
 				tp.PrintfLine("NOT_FOUND")
+			case "stats-tube broken":
+				tp.PrintfLine("INTERNAL_ERROR")
 			default:
 				t.Log("Test server: unknown command")
 			}
@@ -2,6 +2,7 @@ package cloudwatch
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +35,7 @@ type (
 		Metrics     []*Metric         `toml:"metrics"`
 		CacheTTL    internal.Duration `toml:"cache_ttl"`
 		RateLimit   int               `toml:"ratelimit"`
+		FillGaps    string            `toml:"fill_gaps"`
 		client      cloudwatchClient
 		metricCache *MetricCache
 		windowStart time.Time
@@ -118,6 +120,11 @@ func (c *CloudWatch) SampleConfig() string {
   ## See http://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/cloudwatch_limits.html
   ratelimit = 200
 
+  ## Fill the periods of the collection window missing from the data points
+  ## returned by CloudWatch, either with the previous data point of the window
+  ## or with zero values.  One of "none", "previous" or "zero".
+  # fill_gaps = "none"
+
   ## Metrics to Pull (optional)
   ## Defaults to all Metrics in Namespace if nothing is provided
   ## Refreshes Namespace available metrics every 1h
@@ -137,6 +144,15 @@ func (c *CloudWatch) Description() string {
 	return "Pull Metric Statistics from Amazon CloudWatch"
 }
 
+func (c *CloudWatch) Init() error {
+	switch c.FillGaps {
+	case "", "none", "previous", "zero":
+	default:
+		return fmt.Errorf("invalid fill_gaps %q, must be one of none, previous or zero", c.FillGaps)
+	}
+	return nil
+}
+
 func SelectMetrics(c *CloudWatch) ([]*cloudwatch.Metric, error) {
 	var metrics []*cloudwatch.Metric
 
@@ -321,7 +337,12 @@ func (c *CloudWatch) gatherMetric(
 		return err
 	}
 
-	for _, point := range resp.Datapoints {
+	points := resp.Datapoints
+	if c.FillGaps == "previous" || c.FillGaps == "zero" {
+		points = c.fillGaps(points)
+	}
+
+	for _, point := range points {
 		tags := map[string]string{
 			"region": c.Region,
 			"unit":   snakeCase(*point.Unit),
@@ -356,6 +377,66 @@ func (c *CloudWatch) gatherMetric(
 	return nil
 }
 
+/*
+ * Fill the periods of the window missing from the data points of a metric
+ */
+func (c *CloudWatch) fillGaps(points []*cloudwatch.Datapoint) []*cloudwatch.Datapoint {
+	period := c.Period.Duration
+	if len(points) == 0 || period <= 0 {
+		return points
+	}
+
+	sorted := make([]*cloudwatch.Datapoint, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(*sorted[j].Timestamp)
+	})
+
+	// The periods are aligned on the data points returned by CloudWatch, the
+	// first one is the earliest period starting within the window.
+	start := *sorted[0].Timestamp
+	for !start.Add(-period).Before(c.windowStart) {
+		start = start.Add(-period)
+	}
+
+	filled := make([]*cloudwatch.Datapoint, 0, len(sorted))
+	var previous *cloudwatch.Datapoint
+	i := 0
+	for ts := start; ts.Before(c.windowEnd); ts = ts.Add(period) {
+		if i < len(sorted) && !sorted[i].Timestamp.After(ts) {
+			for i < len(sorted) && !sorted[i].Timestamp.After(ts) {
+				previous = sorted[i]
+				filled = append(filled, sorted[i])
+				i++
+			}
+			continue
+		}
+
+		switch c.FillGaps {
+		case "previous":
+			if previous == nil {
+				continue
+			}
+			point := *previous
+			point.Timestamp = aws.Time(ts)
+			filled = append(filled, &point)
+		case "zero":
+			filled = append(filled, &cloudwatch.Datapoint{
+				Timestamp:   aws.Time(ts),
+				Unit:        sorted[0].Unit,
+				Average:     aws.Float64(0),
+				Maximum:     aws.Float64(0),
+				Minimum:     aws.Float64(0),
+				SampleCount: aws.Float64(0),
+				Sum:         aws.Float64(0),
+			})
+		}
+	}
+	// data points past the window are emitted unchanged
+	filled = append(filled, sorted[i:]...)
+	return filled
+}
+
 /*
  * Formatting helpers
  */
@@ -9,6 +9,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockGatherCloudWatchClient struct{}
@@ -252,3 +253,101 @@ func TestUpdateWindow(t *testing.T) {
 	assert.EqualValues(t, c.windowEnd, now.Add(-c.Delay.Duration))
 	assert.EqualValues(t, c.windowStart, newStartTime)
 }
+
+type mockSparseCloudWatchClient struct {
+	datapoints []*cloudwatch.Datapoint
+}
+
+func (m *mockSparseCloudWatchClient) ListMetrics(params *cloudwatch.ListMetricsInput) (*cloudwatch.ListMetricsOutput, error) {
+	return &cloudwatch.ListMetricsOutput{}, nil
+}
+
+func (m *mockSparseCloudWatchClient) GetMetricStatistics(params *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	return &cloudwatch.GetMetricStatisticsOutput{
+		Label:      aws.String("RequestCount"),
+		Datapoints: m.datapoints,
+	}, nil
+}
+
+func sparseDatapoint(ts time.Time, sum float64) *cloudwatch.Datapoint {
+	return &cloudwatch.Datapoint{
+		Timestamp:   aws.Time(ts),
+		Minimum:     aws.Float64(sum),
+		Maximum:     aws.Float64(sum),
+		Average:     aws.Float64(sum),
+		Sum:         aws.Float64(sum),
+		SampleCount: aws.Float64(1),
+		Unit:        aws.String("Count"),
+	}
+}
+
+func TestFillGaps(t *testing.T) {
+	start := time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)
+	ts := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	// window of 6 periods with data points for the 2nd and 4th, returned out
+	// of order
+	datapoints := []*cloudwatch.Datapoint{
+		sparseDatapoint(ts(3), 30),
+		sparseDatapoint(ts(1), 10),
+	}
+
+	tests := []struct {
+		fill     string
+		expected map[time.Time]float64
+	}{
+		{
+			fill:     "none",
+			expected: map[time.Time]float64{ts(1): 10, ts(3): 30},
+		},
+		{
+			fill: "previous",
+			expected: map[time.Time]float64{
+				ts(1): 10, ts(2): 10, ts(3): 30, ts(4): 30, ts(5): 30,
+			},
+		},
+		{
+			fill: "zero",
+			expected: map[time.Time]float64{
+				ts(0): 0, ts(1): 10, ts(2): 0, ts(3): 30, ts(4): 0, ts(5): 0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fill, func(t *testing.T) {
+			c := &CloudWatch{
+				Region:      "us-east-1",
+				Namespace:   "AWS/ELB",
+				Period:      internal.Duration{Duration: time.Minute},
+				FillGaps:    tt.fill,
+				client:      &mockSparseCloudWatchClient{datapoints: datapoints},
+				windowStart: ts(0),
+				windowEnd:   ts(6),
+			}
+			require.NoError(t, c.Init())
+
+			var acc testutil.Accumulator
+			metric := &cloudwatch.Metric{
+				Namespace:  aws.String("AWS/ELB"),
+				MetricName: aws.String("RequestCount"),
+			}
+			require.NoError(t, c.gatherMetric(&acc, metric))
+
+			actual := make(map[time.Time]float64)
+			for _, m := range acc.Metrics {
+				require.Equal(t, "count", m.Tags["unit"])
+				actual[m.Time] = m.Fields["request_count_sum"].(float64)
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestFillGapsInvalid(t *testing.T) {
+	c := &CloudWatch{FillGaps: "linear"}
+	require.EqualError(t, c.Init(),
+		`invalid fill_gaps "linear", must be one of none, previous or zero`)
+}
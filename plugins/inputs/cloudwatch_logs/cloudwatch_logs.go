@@ -0,0 +1,306 @@
+package cloudwatch_logs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/internal/limiter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type CloudWatchLogs struct {
+	Region      string `toml:"region"`
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	Token       string `toml:"token"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	LogGroups        []string          `toml:"log_groups"`
+	LogGroupPrefixes []string          `toml:"log_group_prefixes"`
+	FilterPattern    string            `toml:"filter_pattern"`
+	InitialLookback  internal.Duration `toml:"initial_lookback"`
+	MaxConcurrency   int               `toml:"max_concurrency"`
+	RateLimit        int               `toml:"ratelimit"`
+
+	client cloudwatchLogsClient
+	parser parsers.Parser
+	// parserMu serializes the parser, shared by the groups gathered
+	// concurrently
+	parserMu sync.Mutex
+	// positions are the positions of the log groups, by name
+	positions map[string]*position
+	now       func() time.Time
+}
+
+// position is the timestamp, in milliseconds, of the last events gathered from
+// a log group.  The events of the group are requested from this timestamp on,
+// the events with the same timestamp already gathered are skipped.
+type position struct {
+	Timestamp int64
+	Seen      map[string]bool
+}
+
+type cloudwatchLogsClient interface {
+	FilterLogEvents(*cloudwatchlogs.FilterLogEventsInput) (*cloudwatchlogs.FilterLogEventsOutput, error)
+	DescribeLogGroups(*cloudwatchlogs.DescribeLogGroupsInput) (*cloudwatchlogs.DescribeLogGroupsOutput, error)
+}
+
+const sampleConfig = `
+  ## Amazon Region
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:8000"
+  # endpoint_url = ""
+
+  ## Names of the log groups to pull the events from.
+  log_groups = ["/aws/lambda/my-function"]
+
+  ## Prefixes of the names of the log groups to pull the events from, the
+  ## groups are listed on each gather.
+  # log_group_prefixes = ["/aws/lambda/"]
+
+  ## Only pull the events matching the CloudWatch Logs filter pattern, see
+  ## https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/FilterAndPatternSyntax.html
+  # filter_pattern = ""
+
+  ## How far back the events are pulled from on the first gather of a log
+  ## group, afterwards only the events since the last gather are pulled.
+  # initial_lookback = "0s"
+
+  ## Maximum number of log groups pulled concurrently.
+  # max_concurrency = 4
+
+  ## Maximum requests per second, shared by all the log groups.  The API
+  ## requests throttled by AWS are retried by the SDK.
+  # ratelimit = 5
+
+  ## Data format of the messages of the events.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+func (c *CloudWatchLogs) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CloudWatchLogs) Description() string {
+	return "Pull and parse log events from Amazon CloudWatch Logs"
+}
+
+func (c *CloudWatchLogs) SetParser(parser parsers.Parser) {
+	c.parser = parser
+}
+
+func (c *CloudWatchLogs) Init() error {
+	if len(c.LogGroups) == 0 && len(c.LogGroupPrefixes) == 0 {
+		return fmt.Errorf("no log_groups or log_group_prefixes configured")
+	}
+	if c.MaxConcurrency <= 0 {
+		return fmt.Errorf("invalid max_concurrency %d", c.MaxConcurrency)
+	}
+	if c.RateLimit <= 0 {
+		return fmt.Errorf("invalid ratelimit %d", c.RateLimit)
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+	c.positions = make(map[string]*position)
+	return nil
+}
+
+func (c *CloudWatchLogs) Gather(acc telegraf.Accumulator) error {
+	if c.client == nil {
+		c.initializeCloudWatchLogs()
+	}
+
+	lmtr := limiter.NewRateLimiter(c.RateLimit, time.Second)
+	defer lmtr.Stop()
+
+	groups, err := c.selectLogGroups(lmtr.C)
+	if err != nil {
+		return err
+	}
+
+	// the groups not selected anymore are forgotten
+	start := c.now().Add(-c.InitialLookback.Duration)
+	positions := make(map[string]*position, len(groups))
+	for _, group := range groups {
+		p, ok := c.positions[group]
+		if !ok {
+			p = &position{Timestamp: toMillis(start)}
+		}
+		positions[group] = p
+	}
+	c.positions = positions
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, c.MaxConcurrency)
+	for _, group := range groups {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(group string, p *position) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := c.gatherLogGroup(acc, lmtr.C, group, p); err != nil {
+				acc.AddError(fmt.Errorf("log group %s: %v", group, err))
+			}
+		}(group, positions[group])
+	}
+	wg.Wait()
+	return nil
+}
+
+// selectLogGroups returns the configured log groups and the ones matching the
+// prefixes, sorted by name.
+func (c *CloudWatchLogs) selectLogGroups(limit <-chan bool) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, group := range c.LogGroups {
+		selected[group] = true
+	}
+
+	for _, prefix := range c.LogGroupPrefixes {
+		var token *string
+		for more := true; more; {
+			<-limit
+			resp, err := c.client.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{
+				LogGroupNamePrefix: aws.String(prefix),
+				NextToken:          token,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("could not list the log groups of prefix %s: %v", prefix, err)
+			}
+			for _, group := range resp.LogGroups {
+				selected[aws.StringValue(group.LogGroupName)] = true
+			}
+			token = resp.NextToken
+			more = token != nil
+		}
+	}
+
+	groups := make([]string, 0, len(selected))
+	for group := range selected {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+// gatherLogGroup pulls the events of the log group since its position, and
+// moves the position to the last one.
+func (c *CloudWatchLogs) gatherLogGroup(acc telegraf.Accumulator, limit <-chan bool, group string, p *position) error {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(group),
+		StartTime:    aws.Int64(p.Timestamp),
+	}
+	if c.FilterPattern != "" {
+		input.FilterPattern = aws.String(c.FilterPattern)
+	}
+
+	for more := true; more; {
+		<-limit
+		resp, err := c.client.FilterLogEvents(input)
+		if err != nil {
+			return err
+		}
+		for _, event := range resp.Events {
+			c.gatherEvent(acc, group, p, event)
+		}
+		input.NextToken = resp.NextToken
+		more = input.NextToken != nil
+	}
+	return nil
+}
+
+func (c *CloudWatchLogs) gatherEvent(acc telegraf.Accumulator, group string, p *position, event *cloudwatchlogs.FilteredLogEvent) {
+	timestamp := aws.Int64Value(event.Timestamp)
+	id := aws.StringValue(event.EventId)
+	if timestamp < p.Timestamp || (timestamp == p.Timestamp && p.Seen[id]) {
+		return
+	}
+	if timestamp > p.Timestamp {
+		p.Timestamp = timestamp
+		p.Seen = make(map[string]bool)
+	}
+	if p.Seen == nil {
+		p.Seen = make(map[string]bool)
+	}
+	p.Seen[id] = true
+
+	c.parserMu.Lock()
+	metrics, err := c.parser.Parse([]byte(aws.StringValue(event.Message)))
+	c.parserMu.Unlock()
+	if err != nil {
+		acc.AddError(fmt.Errorf("could not parse event %s of log group %s: %v", id, group, err))
+		return
+	}
+
+	tm := time.Unix(0, timestamp*int64(time.Millisecond))
+	for _, m := range metrics {
+		tags := m.Tags()
+		tags["log_group"] = group
+		tags["log_stream"] = aws.StringValue(event.LogStreamName)
+		acc.AddFields(m.Name(), m.Fields(), tags, tm)
+	}
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func (c *CloudWatchLogs) initializeCloudWatchLogs() {
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      c.Region,
+		AccessKey:   c.AccessKey,
+		SecretKey:   c.SecretKey,
+		RoleARN:     c.RoleARN,
+		Profile:     c.Profile,
+		Filename:    c.Filename,
+		Token:       c.Token,
+		EndpointURL: c.EndpointURL,
+	}
+	configProvider := credentialConfig.Credentials()
+
+	c.client = cloudwatchlogs.New(configProvider)
+}
+
+func init() {
+	inputs.Add("cloudwatch_logs", func() telegraf.Input {
+		return &CloudWatchLogs{
+			MaxConcurrency: 4,
+			RateLimit:      5,
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package cloudwatch_logs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLogs serves the events of its log groups from the start time on, by
+// pages of pageSize events.
+type mockLogs struct {
+	sync.Mutex
+	groups   map[string][]*cloudwatchlogs.FilteredLogEvent
+	pageSize int
+	requests []*cloudwatchlogs.FilterLogEventsInput
+	err      error
+}
+
+func (m *mockLogs) add(group, stream string, timestamp int64, message string) {
+	m.Lock()
+	defer m.Unlock()
+	m.groups[group] = append(m.groups[group], &cloudwatchlogs.FilteredLogEvent{
+		EventId:       aws.String(fmt.Sprintf("%s-%d", group, len(m.groups[group]))),
+		LogStreamName: aws.String(stream),
+		Timestamp:     aws.Int64(timestamp),
+		Message:       aws.String(message),
+	})
+}
+
+func (m *mockLogs) FilterLogEvents(input *cloudwatchlogs.FilterLogEventsInput) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	copied := *input
+	m.requests = append(m.requests, &copied)
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var events []*cloudwatchlogs.FilteredLogEvent
+	for _, event := range m.groups[aws.StringValue(input.LogGroupName)] {
+		if aws.Int64Value(event.Timestamp) >= aws.Int64Value(input.StartTime) {
+			events = append(events, event)
+		}
+	}
+	offset := 0
+	if input.NextToken != nil {
+		offset, _ = strconv.Atoi(*input.NextToken)
+	}
+	out := &cloudwatchlogs.FilterLogEventsOutput{}
+	end := offset + m.pageSize
+	if end < len(events) {
+		out.NextToken = aws.String(strconv.Itoa(end))
+	} else {
+		end = len(events)
+	}
+	out.Events = events[offset:end]
+	return out, nil
+}
+
+func (m *mockLogs) DescribeLogGroups(input *cloudwatchlogs.DescribeLogGroupsInput) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	out := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for group := range m.groups {
+		if len(group) >= len(*input.LogGroupNamePrefix) && group[:len(*input.LogGroupNamePrefix)] == *input.LogGroupNamePrefix {
+			out.LogGroups = append(out.LogGroups, &cloudwatchlogs.LogGroup{LogGroupName: aws.String(group)})
+		}
+	}
+	return out, nil
+}
+
+func newTestCloudWatchLogs(t *testing.T, client *mockLogs) *CloudWatchLogs {
+	c := &CloudWatchLogs{
+		LogGroups:      []string{"app"},
+		MaxConcurrency: 2,
+		RateLimit:      1000,
+		client:         client,
+		now:            func() time.Time { return time.Unix(1500000000, 0) },
+	}
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	c.SetParser(parser)
+	require.NoError(t, c.Init())
+	return c
+}
+
+func newMockLogs() *mockLogs {
+	return &mockLogs{groups: make(map[string][]*cloudwatchlogs.FilteredLogEvent), pageSize: 2}
+}
+
+func values(acc *testutil.Accumulator) []int64 {
+	var values []int64
+	for _, m := range acc.Metrics {
+		values = append(values, m.Fields["value"].(int64))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
+
+func TestGatherIncremental(t *testing.T) {
+	client := newMockLogs()
+	c := newTestCloudWatchLogs(t, client)
+
+	// the events before the first gather are not pulled
+	client.add("app", "stream-a", 1499999999000, "requests value=0i")
+	client.add("app", "stream-a", 1500000001000, "requests value=1i")
+	client.add("app", "stream-a", 1500000002000, "requests value=2i")
+	client.add("app", "stream-b", 1500000003000, "requests value=3i")
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, []int64{1, 2, 3}, values(&acc))
+	acc.AssertContainsTaggedFields(t, "requests",
+		map[string]interface{}{"value": int64(3)},
+		map[string]string{"log_group": "app", "log_stream": "stream-b"})
+	require.Equal(t, time.Unix(1500000003, 0), acc.Metrics[2].Time)
+	// the three events are paginated
+	require.Len(t, client.requests, 2)
+	require.Equal(t, int64(1500000000000), aws.Int64Value(client.requests[0].StartTime))
+
+	// only the events since the last one are pulled, the events of the
+	// same millisecond are not duplicated
+	client.add("app", "stream-b", 1500000003000, "requests value=4i")
+	client.add("app", "stream-a", 1500000004000, "requests value=5i")
+	acc.ClearMetrics()
+	require.NoError(t, c.Gather(&acc))
+	require.Equal(t, []int64{4, 5}, values(&acc))
+	require.Equal(t, int64(1500000003000), aws.Int64Value(client.requests[2].StartTime))
+
+	acc.ClearMetrics()
+	require.NoError(t, c.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+}
+
+func TestGatherLogGroupPrefixes(t *testing.T) {
+	client := newMockLogs()
+	c := newTestCloudWatchLogs(t, client)
+	c.LogGroups = nil
+	c.LogGroupPrefixes = []string{"/aws/lambda/"}
+	c.FilterPattern = "ERROR"
+	c.InitialLookback.Duration = time.Hour
+
+	client.add("/aws/lambda/a", "stream", 1499999999000, "errors value=1i")
+	client.add("/aws/lambda/b", "stream", 1500000000000, "errors value=2i")
+	client.add("/aws/ecs/c", "stream", 1500000000000, "errors value=3i")
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Equal(t, []int64{1, 2}, values(&acc))
+	require.Len(t, client.requests, 2)
+	for _, request := range client.requests {
+		require.Equal(t, "ERROR", aws.StringValue(request.FilterPattern))
+		require.Equal(t, int64(1499996400000), aws.Int64Value(request.StartTime))
+	}
+}
+
+func TestGatherErrors(t *testing.T) {
+	client := newMockLogs()
+	c := newTestCloudWatchLogs(t, client)
+
+	client.add("app", "stream", 1500000001000, "invalid line protocol")
+	client.add("app", "stream", 1500000002000, "requests value=1i")
+
+	// the invalid messages are reported and skipped
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []int64{1}, values(&acc))
+
+	// a failed log group keeps its position
+	client.err = fmt.Errorf("ThrottlingException: Rate exceeded")
+	acc.ClearMetrics()
+	acc.Errors = nil
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+
+	client.err = nil
+	client.add("app", "stream", 1500000003000, "requests value=2i")
+	require.NoError(t, c.Gather(&acc))
+	require.Equal(t, []int64{2}, values(&acc))
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, c := range []*CloudWatchLogs{
+		{MaxConcurrency: 4, RateLimit: 5},
+		{LogGroups: []string{"app"}, RateLimit: 5},
+		{LogGroups: []string{"app"}, MaxConcurrency: 4},
+	} {
+		require.Error(t, c.Init())
+	}
+}
@@ -15,8 +15,9 @@ type DiskStats struct {
 	// Legacy support
 	Mountpoints []string
 
-	MountPoints []string
-	IgnoreFS    []string `toml:"ignore_fs"`
+	MountPoints        []string
+	IgnoreFS           []string `toml:"ignore_fs"`
+	ReportMountOptions bool     `toml:"report_mount_options"`
 }
 
 func (_ *DiskStats) Description() string {
@@ -30,6 +31,9 @@ var diskSampleConfig = `
 
   ## Ignore mount points by filesystem type.
   ignore_fs = ["tmpfs", "devtmpfs", "devfs", "overlay", "aufs", "squashfs"]
+
+  ## Add the mount options of each mount point as the "mount_opts" tag.
+  # report_mount_options = false
 `
 
 func (_ *DiskStats) SampleConfig() string {
@@ -59,6 +63,9 @@ func (s *DiskStats) Gather(acc telegraf.Accumulator) error {
 			"fstype": du.Fstype,
 			"mode":   mountOpts.Mode(),
 		}
+		if s.ReportMountOptions {
+			tags["mount_opts"] = mountOpts.String()
+		}
 		var used_percent float64
 		if du.Used+du.Free > 0 {
 			used_percent = float64(du.Used) /
@@ -92,6 +99,10 @@ func (opts MountOptions) Mode() string {
 	}
 }
 
+func (opts MountOptions) String() string {
+	return strings.Join(opts, ",")
+}
+
 func (opts MountOptions) exists(opt string) bool {
 	for _, o := range opts {
 		if o == opt {
@@ -372,3 +372,57 @@ func TestDiskStats(t *testing.T) {
 	err = (&DiskStats{ps: &mps, MountPoints: []string{"/", "/home"}}).Gather(&acc)
 	assert.Equal(t, 2*expectedAllDiskMetrics+7, acc.NFields())
 }
+
+func TestDiskReportMountOptions(t *testing.T) {
+	var mps system.MockPS
+	defer mps.AssertExpectations(t)
+	var acc testutil.Accumulator
+
+	duAll := []*disk.UsageStat{
+		{
+			Path:   "/",
+			Fstype: "ext4",
+			Total:  128,
+		},
+		{
+			Path:   "/home",
+			Fstype: "ext4",
+			Total:  256,
+		},
+	}
+	psAll := []*disk.PartitionStat{
+		{
+			Device:     "/dev/sda",
+			Mountpoint: "/",
+			Fstype:     "ext4",
+			Opts:       "ro,noatime,nodiratime",
+		},
+		{
+			Device:     "/dev/sdb",
+			Mountpoint: "/home",
+			Fstype:     "ext4",
+			Opts:       "rw,noatime,nodiratime,errors=remount-ro",
+		},
+	}
+
+	mps.On("DiskUsage", []string(nil), []string(nil)).Return(duAll, psAll, nil)
+
+	err := (&DiskStats{ps: &mps, ReportMountOptions: true}).Gather(&acc)
+	require.NoError(t, err)
+
+	require.Len(t, acc.Metrics, 2)
+	assert.Equal(t, map[string]string{
+		"path":       "/",
+		"fstype":     "ext4",
+		"device":     "sda",
+		"mode":       "ro",
+		"mount_opts": "ro,noatime,nodiratime",
+	}, acc.Metrics[0].Tags)
+	assert.Equal(t, map[string]string{
+		"path":       "/home",
+		"fstype":     "ext4",
+		"device":     "sdb",
+		"mode":       "rw",
+		"mount_opts": "rw,noatime,nodiratime,errors=remount-ro",
+	}, acc.Metrics[1].Tags)
+}
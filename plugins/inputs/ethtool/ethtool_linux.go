@@ -0,0 +1,231 @@
+// +build linux
+
+package ethtool
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Ethtool is a telegraf plugin to gather the statistics of the network
+// interfaces reported by ethtool -S.
+type Ethtool struct {
+	InterfaceInclude []string          `toml:"interface_include"`
+	InterfaceExclude []string          `toml:"interface_exclude"`
+	QueuePatterns    []string          `toml:"queue_patterns"`
+	Timeout          internal.Duration `toml:"timeout"`
+
+	ethtool    ethtool
+	interfaces func() ([]net.Interface, error)
+
+	filter   filter.Filter
+	patterns []*regexp.Regexp
+}
+
+type ethtool func(timeout time.Duration, iface string) (*bytes.Buffer, error)
+
+const measurement = "ethtool"
+
+var (
+	defaultTimeout = internal.Duration{Duration: 5 * time.Second}
+
+	// defaultQueuePatterns match the per-queue statistics of the common
+	// drivers, like rx_queue_0_packets (virtio, ixgbe), rx0_packets (mlx5)
+	// and rx-0.packets (i40e).
+	defaultQueuePatterns = []string{
+		`^(?P<direction>rx|tx)_queue_(?P<queue>\d+)_(?P<stat>.+)$`,
+		`^(?P<direction>rx|tx)(?P<queue>\d+)_(?P<stat>.+)$`,
+		`^(?P<direction>rx|tx)-(?P<queue>\d+)\.(?P<stat>.+)$`,
+	}
+)
+
+// Description returns a short description of the plugin
+func (e *Ethtool) Description() string {
+	return "Gather the statistics of the network interfaces with ethtool"
+}
+
+// SampleConfig returns sample configuration options.
+func (e *Ethtool) SampleConfig() string {
+	return `
+  ## Interfaces to gather, by default all but the loopback interfaces.
+  # interface_include = ["eth0"]
+  # interface_exclude = ["docker*"]
+
+  ## Regular expressions of the per-queue statistics.  The statistics
+  ## matching a pattern are added with the queue tag of the "queue" group and
+  ## a field named after the other named groups, joined with underscores:
+  ## rx_queue_0_packets is added as the rx_packets field of queue 0.  The
+  ## other statistics are added as is.
+  # queue_patterns = [
+  #   '^(?P<direction>rx|tx)_queue_(?P<queue>\d+)_(?P<stat>.+)$',
+  #   '^(?P<direction>rx|tx)(?P<queue>\d+)_(?P<stat>.+)$',
+  #   '^(?P<direction>rx|tx)-(?P<queue>\d+)\.(?P<stat>.+)$',
+  # ]
+
+  ## Timeout of the ethtool executions.
+  # timeout = "5s"
+`
+}
+
+// Init compiles the interface filter and the queue patterns.
+func (e *Ethtool) Init() error {
+	var err error
+	e.filter, err = filter.NewIncludeExcludeFilter(e.InterfaceInclude, e.InterfaceExclude)
+	if err != nil {
+		return err
+	}
+
+	if e.QueuePatterns == nil {
+		e.QueuePatterns = defaultQueuePatterns
+	}
+	for _, pattern := range e.QueuePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid queue pattern %q: %v", pattern, err)
+		}
+		if !hasGroup(re, "queue") {
+			return fmt.Errorf("queue pattern %q has no queue group", pattern)
+		}
+		e.patterns = append(e.patterns, re)
+	}
+	return nil
+}
+
+func hasGroup(re *regexp.Regexp, name string) bool {
+	for _, group := range re.SubexpNames() {
+		if group == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Gather adds the statistics of the interfaces, a metric for the interface
+// and one per queue.
+func (e *Ethtool) Gather(acc telegraf.Accumulator) error {
+	interfaces, err := e.interfaces()
+	if err != nil {
+		return err
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || !e.filter.Match(iface.Name) {
+			continue
+		}
+		out, err := e.ethtool(e.Timeout.Duration, iface.Name)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		e.gatherInterface(acc, iface.Name, out)
+	}
+	return nil
+}
+
+func (e *Ethtool) gatherInterface(acc telegraf.Accumulator, iface string, out *bytes.Buffer) {
+	fields := make(map[string]interface{})
+	queues := make(map[string]map[string]interface{})
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		// the statistics are listed as "     name: value" after the
+		// "NIC statistics:" header
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		queue, field, ok := e.matchQueue(key)
+		if !ok {
+			fields[key] = value
+			continue
+		}
+		if _, ok := queues[queue]; !ok {
+			queues[queue] = make(map[string]interface{})
+		}
+		queues[queue][field] = value
+	}
+
+	if len(fields) > 0 {
+		acc.AddFields(measurement, fields, map[string]string{"interface": iface})
+	}
+	for queue, fields := range queues {
+		tags := map[string]string{
+			"interface": iface,
+			"queue":     queue,
+		}
+		acc.AddFields(measurement, fields, tags)
+	}
+}
+
+// matchQueue returns the queue and the field name of a per-queue statistic,
+// from the first pattern matching the key.
+func (e *Ethtool) matchQueue(key string) (string, string, bool) {
+	for _, re := range e.patterns {
+		match := re.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+
+		var queue string
+		var names []string
+		for i, group := range re.SubexpNames() {
+			switch {
+			case group == "queue":
+				queue = match[i]
+			case group != "" && match[i] != "":
+				names = append(names, match[i])
+			}
+		}
+		if queue == "" || len(names) == 0 {
+			continue
+		}
+		return queue, strings.Join(names, "_"), true
+	}
+	return "", "", false
+}
+
+func runEthtool(timeout time.Duration, iface string) (*bytes.Buffer, error) {
+	ethtoolPath, err := exec.LookPath("ethtool")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(ethtoolPath, "-S", iface)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err = internal.RunTimeout(cmd, timeout)
+	if err != nil {
+		return &out, fmt.Errorf("error running ethtool -S %s: %s", iface, err)
+	}
+
+	return &out, nil
+}
+
+func init() {
+	inputs.Add("ethtool", func() telegraf.Input {
+		return &Ethtool{
+			ethtool:    runEthtool,
+			interfaces: net.Interfaces,
+			Timeout:    defaultTimeout,
+		}
+	})
+}
@@ -0,0 +1,152 @@
+// +build linux
+
+package ethtool
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// $ ethtool -S eth0
+const virtioStats = `NIC statistics:
+     rx_queue_0_packets: 1430678
+     rx_queue_0_bytes: 1880633520
+     rx_queue_1_packets: 976543
+     rx_queue_1_bytes: 1211864011
+     tx_queue_0_packets: 536478
+     tx_queue_0_bytes: 75503221
+     tx_queue_1_packets: 498210
+     tx_queue_1_bytes: 71012887
+`
+
+// $ ethtool -S ens1f0
+const mlx5Stats = `NIC statistics:
+     rx_packets: 2000
+     tx_packets: 1000
+     rx_csum_unnecessary: 1990
+     rx0_packets: 1200
+     rx0_bytes: 180000
+     rx1_packets: 800
+     rx1_bytes: 120000
+     tx0_packets: 700
+     tx1_packets: 300
+`
+
+func newEthtool(t *testing.T, e *Ethtool, stats map[string]string) *Ethtool {
+	e.Timeout = defaultTimeout
+	e.interfaces = func() ([]net.Interface, error) {
+		return []net.Interface{
+			{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+			{Name: "eth0", Flags: net.FlagUp},
+			{Name: "ens1f0", Flags: net.FlagUp},
+		}, nil
+	}
+	e.ethtool = func(timeout time.Duration, iface string) (*bytes.Buffer, error) {
+		out, ok := stats[iface]
+		if !ok {
+			return nil, fmt.Errorf("no stats for %s", iface)
+		}
+		return bytes.NewBufferString(out), nil
+	}
+	require.NoError(t, e.Init())
+	return e
+}
+
+func TestGatherQueues(t *testing.T) {
+	e := newEthtool(t, &Ethtool{}, map[string]string{
+		"eth0":   virtioStats,
+		"ens1f0": mlx5Stats,
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 5)
+
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{
+			"rx_packets": uint64(1430678),
+			"rx_bytes":   uint64(1880633520),
+			"tx_packets": uint64(536478),
+			"tx_bytes":   uint64(75503221),
+		},
+		map[string]string{"interface": "eth0", "queue": "0"})
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{
+			"rx_packets": uint64(976543),
+			"rx_bytes":   uint64(1211864011),
+			"tx_packets": uint64(498210),
+			"tx_bytes":   uint64(71012887),
+		},
+		map[string]string{"interface": "eth0", "queue": "1"})
+
+	// the statistics of the interface are kept as is
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{
+			"rx_packets":          uint64(2000),
+			"tx_packets":          uint64(1000),
+			"rx_csum_unnecessary": uint64(1990),
+		},
+		map[string]string{"interface": "ens1f0"})
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{
+			"rx_packets": uint64(1200),
+			"rx_bytes":   uint64(180000),
+			"tx_packets": uint64(700),
+		},
+		map[string]string{"interface": "ens1f0", "queue": "0"})
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{
+			"rx_packets": uint64(800),
+			"rx_bytes":   uint64(120000),
+			"tx_packets": uint64(300),
+		},
+		map[string]string{"interface": "ens1f0", "queue": "1"})
+}
+
+func TestGatherCustomPatterns(t *testing.T) {
+	// without patterns, all the statistics are added as is
+	e := newEthtool(t, &Ethtool{
+		InterfaceInclude: []string{"eth*"},
+		QueuePatterns:    []string{},
+	}, map[string]string{"eth0": virtioStats})
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	require.Len(t, acc.Metrics[0].Fields, 8)
+	require.Equal(t, uint64(1430678), acc.Metrics[0].Fields["rx_queue_0_packets"])
+
+	e = newEthtool(t, &Ethtool{
+		InterfaceInclude: []string{"eth*"},
+		QueuePatterns:    []string{`^(?P<direction>rx)_queue_(?P<queue>\d+)_(?P<stat>packets)$`},
+	}, map[string]string{"eth0": virtioStats})
+
+	acc.ClearMetrics()
+	require.NoError(t, e.Gather(&acc))
+	require.Len(t, acc.Metrics, 3)
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{"rx_packets": uint64(976543)},
+		map[string]string{"interface": "eth0", "queue": "1"})
+}
+
+func TestGatherError(t *testing.T) {
+	e := newEthtool(t, &Ethtool{}, map[string]string{"eth0": virtioStats})
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Len(t, acc.Metrics, 2)
+}
+
+func TestInitInvalidPatterns(t *testing.T) {
+	require.Error(t, (&Ethtool{QueuePatterns: []string{`rx_queue_(\d+`}}).Init())
+	require.Error(t, (&Ethtool{QueuePatterns: []string{`^rx_queue_(\d+)_(?P<stat>.+)$`}}).Init())
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+package ethtool
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Ethtool struct {
+}
+
+func (e *Ethtool) Description() string {
+	return "Gather the statistics of the network interfaces with ethtool"
+}
+
+func (e *Ethtool) SampleConfig() string { return "" }
+
+func (e *Ethtool) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("ethtool", func() telegraf.Input {
+		return &Ethtool{}
+	})
+}
@@ -11,10 +11,13 @@ import (
 )
 
 type File struct {
-	Files  []string `toml:"files"`
-	parser parsers.Parser
+	Files      []string `toml:"files"`
+	OffsetMode bool     `toml:"offset_mode"`
+	OffsetFile string   `toml:"offset_file"`
+	parser     parsers.Parser
 
 	filenames []string
+	offsets   map[string]*offset
 }
 
 const sampleConfig = `
@@ -31,6 +34,16 @@ const sampleConfig = `
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
   data_format = "influx"
+
+  ## Only parse the lines appended to the files since the last interval,
+  ## instead of the whole files.  A file truncated or replaced by another one,
+  ## like when rotated, is parsed again from its start.  Requires a line
+  ## oriented data format.
+  # offset_mode = false
+
+  ## File to persist the offsets to in offset_mode, so that the files are not
+  ## parsed again from their start when Telegraf restarts.
+  # offset_file = "/var/lib/telegraf/file_offsets.json"
 `
 
 // SampleConfig returns the default configuration of the Input
@@ -47,6 +60,9 @@ func (f *File) Gather(acc telegraf.Accumulator) error {
 	if err != nil {
 		return err
 	}
+	if f.OffsetMode {
+		return f.gatherAppended(acc)
+	}
 	for _, k := range f.filenames {
 		metrics, err := f.readMetric(k)
 		if err != nil {
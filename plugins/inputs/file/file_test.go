@@ -1,6 +1,7 @@
 package file
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -59,3 +60,109 @@ func TestGrokParser(t *testing.T) {
 	err = r.Gather(&acc)
 	assert.Equal(t, len(acc.Metrics), 2)
 }
+
+func newOffsetFile(t *testing.T, files ...string) *File {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	r := &File{
+		Files:      files,
+		OffsetMode: true,
+	}
+	r.SetParser(parser)
+	return r
+}
+
+func appendFile(t *testing.T, filename, data string) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+func gatherValues(t *testing.T, r *File) []float64 {
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	values := []float64{}
+	for _, m := range acc.Metrics {
+		values = append(values, m.Fields["value"].(float64))
+	}
+	return values
+}
+
+func TestOffsetModeAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "data.log")
+
+	appendFile(t, filename, "cpu value=1\ncpu value=2\n")
+	r := newOffsetFile(t, filename)
+	require.Equal(t, []float64{1, 2}, gatherValues(t, r))
+	require.Equal(t, []float64{}, gatherValues(t, r))
+
+	// an incomplete line is parsed once complete
+	appendFile(t, filename, "cpu value=3\ncpu val")
+	require.Equal(t, []float64{3}, gatherValues(t, r))
+	appendFile(t, filename, "ue=4\n")
+	require.Equal(t, []float64{4}, gatherValues(t, r))
+}
+
+func TestOffsetModeTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "data.log")
+
+	appendFile(t, filename, "cpu value=1\ncpu value=2\n")
+	r := newOffsetFile(t, filename)
+	require.Equal(t, []float64{1, 2}, gatherValues(t, r))
+
+	require.NoError(t, os.Truncate(filename, 0))
+	appendFile(t, filename, "cpu value=3\n")
+	require.Equal(t, []float64{3}, gatherValues(t, r))
+}
+
+func TestOffsetModeRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "data.log")
+
+	appendFile(t, filename, "cpu value=1\n")
+	r := newOffsetFile(t, filename)
+	require.Equal(t, []float64{1}, gatherValues(t, r))
+
+	// the new file is larger than the offset in the rotated one
+	require.NoError(t, os.Rename(filename, filename+".1"))
+	appendFile(t, filename, "cpu value=2\ncpu value=3\n")
+	require.Equal(t, []float64{2, 3}, gatherValues(t, r))
+	require.Equal(t, []float64{}, gatherValues(t, r))
+}
+
+func TestOffsetModePersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "data.log")
+	offsetFile := filepath.Join(dir, "offsets.json")
+
+	appendFile(t, filename, "cpu value=1\n")
+	r := newOffsetFile(t, filename)
+	r.OffsetFile = offsetFile
+	require.Equal(t, []float64{1}, gatherValues(t, r))
+
+	// a restarted input continues after the persisted offset
+	appendFile(t, filename, "cpu value=2\n")
+	r = newOffsetFile(t, filename)
+	r.OffsetFile = offsetFile
+	require.Equal(t, []float64{2}, gatherValues(t, r))
+
+	// and recognizes a file replaced while stopped from its first bytes
+	require.NoError(t, os.Remove(filename))
+	appendFile(t, filename, "mem value=3\nmem value=4\n")
+	r = newOffsetFile(t, filename)
+	r.OffsetFile = offsetFile
+	require.Equal(t, []float64{3, 4}, gatherValues(t, r))
+}
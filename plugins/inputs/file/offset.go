@@ -0,0 +1,196 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/telegraf"
+)
+
+// fingerprintSize is the number of bytes at the start of a file checksummed to
+// recognize it once replaced by another file, as after a restart the offsets
+// can not be compared to the os.FileInfo of the files.
+const fingerprintSize = 256
+
+// offset is the position up to which a file was parsed in offset_mode.
+type offset struct {
+	Offset      int64  `json:"offset"`
+	Fingerprint uint32 `json:"fingerprint"`
+
+	info os.FileInfo
+}
+
+// gatherAppended parses the complete lines appended to the files since the
+// last gather.
+func (f *File) gatherAppended(acc telegraf.Accumulator) error {
+	if f.offsets == nil {
+		f.offsets = f.loadOffsets()
+	}
+
+	current := make(map[string]*offset, len(f.filenames))
+	var gatherErr error
+	for _, filename := range f.filenames {
+		o := f.offsets[filename]
+		if o == nil {
+			o = &offset{}
+		}
+		current[filename] = o
+
+		data, err := readAppended(filename, o)
+		if err != nil {
+			gatherErr = fmt.Errorf("E! Error file: %v could not be read, %s", filename, err)
+			break
+		}
+		if len(data) == 0 {
+			continue
+		}
+		metrics, err := f.parser.Parse(data)
+		if err != nil {
+			acc.AddError(fmt.Errorf("E! Error parsing %v: %s", filename, err))
+			continue
+		}
+		for _, m := range metrics {
+			acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+		}
+	}
+
+	// the files not matched anymore are forgotten, unless not gathered
+	if gatherErr == nil {
+		f.offsets = current
+	} else {
+		for filename, o := range current {
+			f.offsets[filename] = o
+		}
+	}
+	if err := f.saveOffsets(); err != nil {
+		acc.AddError(err)
+	}
+	return gatherErr
+}
+
+// readAppended returns the complete lines of the file after the offset and
+// moves the offset after them.  The offset is reset when the file was
+// truncated or replaced.
+func readAppended(filename string, o *offset) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Offset > 0 {
+		fingerprint, err := fingerprint(file, o.Offset)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case info.Size() < o.Offset:
+			log.Printf("I! [inputs.file] %s was truncated, parsing it from its start", filename)
+			o.Offset = 0
+		case o.info != nil && !os.SameFile(o.info, info), fingerprint != o.Fingerprint:
+			log.Printf("I! [inputs.file] %s was replaced, parsing it from its start", filename)
+			o.Offset = 0
+		}
+	}
+	o.info = info
+
+	if _, err := file.Seek(o.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(file, info.Size()-o.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	// an incomplete last line is parsed once complete
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if end == 0 {
+		return nil, nil
+	}
+	data = data[:end]
+
+	o.Offset += int64(end)
+	if o.Fingerprint, err = fingerprint(file, o.Offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fingerprint returns the checksum of the fingerprintSize first bytes of the
+// file, or of its first size bytes if smaller.
+func fingerprint(file *os.File, size int64) (uint32, error) {
+	if size > fingerprintSize {
+		size = fingerprintSize
+	}
+	buf := make([]byte, size)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(buf[:n]), nil
+}
+
+// loadOffsets returns the offsets persisted in the offset file.  The files are
+// parsed from their start if it can not be read.
+func (f *File) loadOffsets() map[string]*offset {
+	offsets := make(map[string]*offset)
+	if f.OffsetFile == "" {
+		return offsets
+	}
+
+	data, err := ioutil.ReadFile(f.OffsetFile)
+	if os.IsNotExist(err) {
+		return offsets
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &offsets)
+	}
+	if err != nil {
+		log.Printf("W! [inputs.file] could not load the offsets from %s, parsing the files from their start: %v",
+			f.OffsetFile, err)
+		return make(map[string]*offset)
+	}
+	return offsets
+}
+
+// saveOffsets persists the offsets to the offset file, replacing it at once
+// so that it is never left incomplete.
+func (f *File) saveOffsets() error {
+	if f.OffsetFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(f.offsets)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.OffsetFile), filepath.Base(f.OffsetFile))
+	if err != nil {
+		return fmt.Errorf("could not save the offsets: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.OffsetFile)
+	}
+	if err != nil {
+		return fmt.Errorf("could not save the offsets: %v", err)
+	}
+	return nil
+}
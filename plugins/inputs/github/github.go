@@ -0,0 +1,250 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const defaultBaseURL = "https://api.github.com"
+
+type GitHub struct {
+	Repositories      []string          `toml:"repositories"`
+	AccessToken       string            `toml:"access_token"`
+	EnterpriseBaseURL string            `toml:"enterprise_base_url"`
+	HTTPTimeout       internal.Duration `toml:"http_timeout"`
+
+	client  *http.Client
+	baseURL string
+
+	mu    sync.Mutex
+	cache map[string]*cachedResponse
+
+	rateLimit     selfstat.Stat
+	rateRemaining selfstat.Stat
+	rateReset     selfstat.Stat
+}
+
+var sampleConfig = `
+  ## List of repositories to monitor, as owner/repository.
+  repositories = ["influxdata/telegraf"]
+
+  ## Github API access token.  Unauthenticated requests are limited to 60 per
+  ## hour.
+  # access_token = ""
+
+  ## Github API enterprise url.  Github Enterprise accounts must specify their
+  ## base url.
+  # enterprise_base_url = ""
+
+  ## Timeout for HTTP requests.
+  # http_timeout = "5s"
+`
+
+// cachedResponse is the last response of an endpoint, reused when the
+// endpoint answers it is not modified.
+type cachedResponse struct {
+	etag       string
+	repository repository
+}
+
+// repository is the response of the /repos/{owner}/{repo} endpoint.
+type repository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Language string `json:"language"`
+	License  *struct {
+		Name string `json:"name"`
+	} `json:"license"`
+	Stars       int64 `json:"stargazers_count"`
+	Subscribers int64 `json:"subscribers_count"`
+	Forks       int64 `json:"forks_count"`
+	OpenIssues  int64 `json:"open_issues_count"`
+	Networks    int64 `json:"network_count"`
+	Size        int64 `json:"size"`
+	Watchers    int64 `json:"watchers_count"`
+}
+
+func (g *GitHub) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GitHub) Description() string {
+	return "Gather repository information from GitHub hosted repositories."
+}
+
+func (g *GitHub) Init() error {
+	for _, repo := range g.Repositories {
+		if _, _, err := splitRepositoryName(repo); err != nil {
+			return err
+		}
+	}
+
+	g.baseURL = defaultBaseURL
+	if g.EnterpriseBaseURL != "" {
+		g.baseURL = g.EnterpriseBaseURL
+	}
+	g.baseURL = strings.TrimRight(g.baseURL, "/")
+
+	g.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+		Timeout: g.HTTPTimeout.Duration,
+	}
+	g.cache = make(map[string]*cachedResponse)
+
+	tags := map[string]string{"access_token": obfuscate(g.AccessToken)}
+	g.rateLimit = selfstat.Register("github", "ratelimit_limit", tags)
+	g.rateRemaining = selfstat.Register("github", "ratelimit_remaining", tags)
+	g.rateReset = selfstat.Register("github", "ratelimit_reset", tags)
+	return nil
+}
+
+func (g *GitHub) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, repo := range g.Repositories {
+		wg.Add(1)
+		go func(repo string) {
+			defer wg.Done()
+
+			r, err := g.getRepository(repo)
+			if err != nil {
+				acc.AddError(fmt.Errorf("%s: %v", repo, err))
+				return
+			}
+			acc.AddFields("github_repository", getFields(r), getTags(r))
+		}(repo)
+	}
+	wg.Wait()
+	return nil
+}
+
+// getRepository returns the repository, the one cached if not modified since
+// the last request.  The requests answered as not modified are not counted
+// by the rate limit.
+func (g *GitHub) getRepository(name string) (repository, error) {
+	owner, repo, _ := splitRepositoryName(name)
+	url := g.baseURL + "/repos/" + owner + "/" + repo
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return repository{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if g.AccessToken != "" {
+		req.Header.Set("Authorization", "token "+g.AccessToken)
+	}
+
+	g.mu.Lock()
+	cached := g.cache[url]
+	g.mu.Unlock()
+	if cached != nil {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return repository{}, err
+	}
+	defer resp.Body.Close()
+	g.updateRateLimit(resp.Header)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return cached.repository, nil
+	case resp.StatusCode != http.StatusOK:
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return repository{}, fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var r repository
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return repository{}, fmt.Errorf("invalid response of %s: %v", url, err)
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		g.mu.Lock()
+		g.cache[url] = &cachedResponse{etag: etag, repository: r}
+		g.mu.Unlock()
+	}
+	return r, nil
+}
+
+// updateRateLimit sets the rate limit stats from the headers of a response.
+func (g *GitHub) updateRateLimit(header http.Header) {
+	for name, stat := range map[string]selfstat.Stat{
+		"X-RateLimit-Limit":     g.rateLimit,
+		"X-RateLimit-Remaining": g.rateRemaining,
+		"X-RateLimit-Reset":     g.rateReset,
+	} {
+		if v, err := strconv.ParseInt(header.Get(name), 10, 64); err == nil {
+			stat.Set(v)
+		}
+	}
+}
+
+func splitRepositoryName(name string) (string, string, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository %q, expected owner/repository", name)
+	}
+	return parts[0], parts[1], nil
+}
+
+// obfuscate returns the token, for the tags, with only its last characters.
+func obfuscate(token string) string {
+	if token == "" {
+		return "Unauthenticated"
+	}
+	if len(token) <= 4 {
+		return "..."
+	}
+	return "..." + token[len(token)-4:]
+}
+
+func getTags(r repository) map[string]string {
+	license := "None"
+	if r.License != nil {
+		license = r.License.Name
+	}
+	return map[string]string{
+		"owner":    r.Owner.Login,
+		"name":     r.Name,
+		"language": r.Language,
+		"license":  license,
+	}
+}
+
+func getFields(r repository) map[string]interface{} {
+	return map[string]interface{}{
+		"stars":       r.Stars,
+		"subscribers": r.Subscribers,
+		"forks":       r.Forks,
+		"open_issues": r.OpenIssues,
+		"networks":    r.Networks,
+		"size":        r.Size,
+		"watchers":    r.Watchers,
+	}
+}
+
+func init() {
+	inputs.Add("github", func() telegraf.Input {
+		return &GitHub{
+			HTTPTimeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package github
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const etag = `"0123456789abcdef"`
+
+// apiServer serves the recorded repository, counting the requests not
+// answered as not modified against the rate limit.
+type apiServer struct {
+	sync.Mutex
+	remaining   int
+	notModified int
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if r.URL.Path != "/repos/influxdata/telegraf" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+	} else {
+		s.remaining--
+	}
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+	w.Header().Set("X-RateLimit-Reset", "1546300800")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, err := ioutil.ReadFile("testdata/repository.json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func newGitHub(t *testing.T, url string, repos ...string) *GitHub {
+	g := &GitHub{
+		Repositories:      repos,
+		AccessToken:       "0123456789",
+		EnterpriseBaseURL: url,
+	}
+	require.NoError(t, g.Init())
+	return g
+}
+
+func TestGatherNotModified(t *testing.T) {
+	api := &apiServer{remaining: 5000}
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	g := newGitHub(t, ts.URL, "influxdata/telegraf")
+	tags := map[string]string{
+		"owner":    "influxdata",
+		"name":     "telegraf",
+		"language": "Go",
+		"license":  "MIT License",
+	}
+	fields := map[string]interface{}{
+		"stars":       int64(6543),
+		"subscribers": int64(321),
+		"forks":       int64(2345),
+		"open_issues": int64(987),
+		"networks":    int64(2345),
+		"size":        int64(49010),
+		"watchers":    int64(6543),
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "github_repository", fields, tags)
+	require.Equal(t, int64(4999), g.rateRemaining.Get())
+
+	// the repository not modified is reported from the cache, without
+	// spending the rate limit
+	acc.ClearMetrics()
+	require.NoError(t, g.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "github_repository", fields, tags)
+	require.Equal(t, 1, api.notModified)
+	require.Equal(t, int64(4999), g.rateRemaining.Get())
+	require.Equal(t, int64(5000), g.rateLimit.Get())
+	require.Equal(t, int64(1546300800), g.rateReset.Get())
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(&apiServer{remaining: 5000})
+	defer ts.Close()
+
+	g := newGitHub(t, ts.URL, "influxdata/telegraf", "influxdata/missing")
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "influxdata/missing")
+	require.Len(t, acc.Metrics, 1)
+}
+
+func TestInitInvalidRepository(t *testing.T) {
+	for _, repo := range []string{"telegraf", "influxdata/", "influxdata/telegraf/plugins"} {
+		g := &GitHub{Repositories: []string{repo}}
+		require.Error(t, g.Init())
+	}
+}
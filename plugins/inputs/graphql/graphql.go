@@ -0,0 +1,308 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type GraphQL struct {
+	URLs          []string               `toml:"urls"`
+	Query         string                 `toml:"query"`
+	OperationName string                 `toml:"operation_name"`
+	Variables     map[string]interface{} `toml:"variables"`
+	ErrorMetric   bool                   `toml:"error_metric"`
+
+	Headers map[string]string `toml:"headers"`
+
+	// HTTP Basic Auth Credentials
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	tls.ClientConfig
+
+	Timeout internal.Duration `toml:"timeout"`
+
+	client    *http.Client
+	templates map[string]*template.Template
+	parser    parsers.Parser
+
+	// time of the previous gather, for the templates
+	previous time.Time
+}
+
+var sampleConfig = `
+  ## GraphQL endpoints to post the query to.
+  urls = ["http://localhost/graphql"]
+
+  ## The query, and the name of the operation to run when it has several.
+  query = """
+    query Stats($since: String!) {
+      stats(since: $since) { name value }
+    }
+  """
+  # operation_name = ""
+
+  ## The variables of the query.  String values are Go templates, with the
+  ## time of this gather as .Now and the time of the previous one as
+  ## .Previous, which is the zero time for the first gather.
+  # variables = {since = "{{ .Previous.Format \"2006-01-02T15:04:05Z07:00\" }}"}
+
+  ## Add a graphql_errors metric when the response holds errors, which are
+  ## always logged.
+  # error_metric = false
+
+  ## Optional HTTP headers
+  # headers = {"Authorization" = "Bearer token"}
+
+  ## Optional HTTP Basic Auth Credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Amount of time allowed to complete the HTTP request
+  # timeout = "5s"
+
+  ## Data format to parse the "data" object of the responses with, such as
+  ## "json" with its json_query and tag_keys options.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "json"
+`
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path"`
+	} `json:"errors"`
+}
+
+// templateData is the data of the variable templates.
+type templateData struct {
+	Now      time.Time
+	Previous time.Time
+}
+
+func (g *GraphQL) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GraphQL) Description() string {
+	return "Read metrics from the results of GraphQL queries"
+}
+
+func (g *GraphQL) SetParser(parser parsers.Parser) {
+	g.parser = parser
+}
+
+func (g *GraphQL) Init() error {
+	if g.Query == "" {
+		return errors.New("no query")
+	}
+
+	g.templates = make(map[string]*template.Template)
+	for name, v := range g.Variables {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		tmpl, err := template.New(name).Parse(s)
+		if err != nil {
+			return fmt.Errorf("variable %q: %v", name, err)
+		}
+		g.templates[name] = tmpl
+	}
+	return nil
+}
+
+func (g *GraphQL) Gather(acc telegraf.Accumulator) error {
+	if g.parser == nil {
+		return errors.New("Parser is not set")
+	}
+
+	if g.client == nil {
+		tlsCfg, err := g.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		g.client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsCfg,
+				Proxy:           http.ProxyFromEnvironment,
+			},
+			Timeout: g.Timeout.Duration,
+		}
+	}
+
+	now := time.Now()
+	body, err := g.requestBody(templateData{Now: now, Previous: g.previous})
+	if err != nil {
+		return err
+	}
+	g.previous = now
+
+	var wg sync.WaitGroup
+	for _, u := range g.URLs {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			if err := g.gatherURL(acc, url, body); err != nil {
+				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// requestBody returns the request with the templated variables.
+func (g *GraphQL) requestBody(data templateData) ([]byte, error) {
+	req := request{
+		Query:         g.Query,
+		OperationName: g.OperationName,
+	}
+	if len(g.Variables) > 0 {
+		req.Variables = make(map[string]interface{}, len(g.Variables))
+	}
+	for name, v := range g.Variables {
+		if tmpl, ok := g.templates[name]; ok {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("variable %q: %v", name, err)
+			}
+			v = buf.String()
+		}
+		req.Variables[name] = v
+	}
+	return json.Marshal(req)
+}
+
+func (g *GraphQL) gatherURL(acc telegraf.Accumulator, url string, body []byte) error {
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	for k, v := range g.Headers {
+		if strings.ToLower(k) == "host" {
+			request.Host = v
+		} else {
+			request.Header.Add(k, v)
+		}
+	}
+
+	if g.Username != "" || g.Password != "" {
+		request.SetBasicAuth(g.Username, g.Password)
+	}
+
+	resp, err := g.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// servers can answer errors with any status, but the body is not a
+	// GraphQL response on other errors
+	var result response
+	if err := json.Unmarshal(b, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Received status code %d (%s), expected %d (%s)",
+				resp.StatusCode,
+				http.StatusText(resp.StatusCode),
+				http.StatusOK,
+				http.StatusText(http.StatusOK))
+		}
+		return fmt.Errorf("invalid response: %v", err)
+	}
+
+	messages := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		message := e.Message
+		if len(e.Path) > 0 {
+			path := make([]string, len(e.Path))
+			for i, p := range e.Path {
+				path[i] = fmt.Sprint(p)
+			}
+			message = fmt.Sprintf("%s: %s", strings.Join(path, "."), message)
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) > 0 && g.ErrorMetric {
+		acc.AddFields("graphql_errors",
+			map[string]interface{}{
+				"count":   len(messages),
+				"message": messages[0],
+			},
+			map[string]string{"url": url})
+	}
+
+	// without data, the whole query failed
+	data := bytes.TrimSpace(result.Data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		if len(messages) > 0 {
+			return fmt.Errorf("query failed: %s", strings.Join(messages, "; "))
+		}
+		return fmt.Errorf("no data in the response, with status code %d", resp.StatusCode)
+	}
+	for _, message := range messages {
+		log.Printf("W! [inputs.graphql] [url=%s]: query error: %s", url, message)
+	}
+
+	metrics, err := g.parser.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	for _, metric := range metrics {
+		if !metric.HasTag("url") {
+			metric.AddTag("url", url)
+		}
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+	}
+
+	return nil
+}
+
+func init() {
+	inputs.Add("graphql", func() telegraf.Input {
+		return &GraphQL{
+			Timeout: internal.Duration{Duration: time.Second * 5},
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package graphql
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// newServer returns a GraphQL server answering the response, and the
+// channel of the requests it received.
+func newServer(t *testing.T, status int, response string) (*httptest.Server, chan request) {
+	requests := make(chan request, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req request
+		require.NoError(t, json.Unmarshal(body, &req))
+		requests <- req
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	return ts, requests
+}
+
+func newPlugin(t *testing.T, url string) *GraphQL {
+	g := &GraphQL{
+		URLs:  []string{url},
+		Query: "{ stats { name value } }",
+	}
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat: "json",
+		MetricName: "graphql",
+		JSONQuery:  "stats",
+		TagKeys:    []string{"name"},
+	})
+	require.NoError(t, err)
+	g.SetParser(parser)
+	return g
+}
+
+func TestGather(t *testing.T) {
+	ts, requests := newServer(t, http.StatusOK,
+		`{"data": {"stats": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]}}`)
+	defer ts.Close()
+
+	g := newPlugin(t, ts.URL)
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(g.Gather))
+
+	req := <-requests
+	require.Equal(t, "{ stats { name value } }", req.Query)
+	require.Empty(t, req.Variables)
+
+	acc.AssertContainsTaggedFields(t, "graphql",
+		map[string]interface{}{"value": 1.0},
+		map[string]string{"name": "a", "url": ts.URL})
+	acc.AssertContainsTaggedFields(t, "graphql",
+		map[string]interface{}{"value": 2.0},
+		map[string]string{"name": "b", "url": ts.URL})
+}
+
+func TestVariables(t *testing.T) {
+	ts, requests := newServer(t, http.StatusOK, `{"data": {"stats": [{"name": "a", "value": 1}]}}`)
+	defer ts.Close()
+
+	g := newPlugin(t, ts.URL)
+	require.NoError(t, toml.Unmarshal([]byte(`
+variables = {since = "{{ .Previous.Unix }}", until = "{{ .Now.Unix }}", first = 10}
+`), g))
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(g.Gather))
+	req := <-requests
+	require.Equal(t, float64(10), req.Variables["first"])
+	require.Equal(t, "-62135596800", req.Variables["since"])
+	until := req.Variables["until"]
+
+	require.NoError(t, acc.GatherError(g.Gather))
+	req = <-requests
+	require.Equal(t, until, req.Variables["since"])
+}
+
+func TestVariablesInvalidTemplate(t *testing.T) {
+	g := &GraphQL{
+		Query:     "{ stats }",
+		Variables: map[string]interface{}{"since": "{{ .Previous"},
+	}
+	require.Error(t, g.Init())
+}
+
+func TestPartialResponse(t *testing.T) {
+	ts, _ := newServer(t, http.StatusOK, `{
+		"data": {"stats": [{"name": "a", "value": 1}]},
+		"errors": [{"message": "stat b unavailable", "path": ["stats", 1]}]
+	}`)
+	defer ts.Close()
+
+	g := newPlugin(t, ts.URL)
+	g.ErrorMetric = true
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(g.Gather))
+
+	acc.AssertContainsTaggedFields(t, "graphql",
+		map[string]interface{}{"value": 1.0},
+		map[string]string{"name": "a", "url": ts.URL})
+	acc.AssertContainsTaggedFields(t, "graphql_errors",
+		map[string]interface{}{
+			"count":   1,
+			"message": "stats.1: stat b unavailable",
+		},
+		map[string]string{"url": ts.URL})
+}
+
+func TestErrorResponse(t *testing.T) {
+	ts, _ := newServer(t, http.StatusBadRequest,
+		`{"errors": [{"message": "Cannot query field \"stat\""}]}`)
+	defer ts.Close()
+
+	g := newPlugin(t, ts.URL)
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), `query failed: Cannot query field "stat"`)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestHTTPError(t *testing.T) {
+	ts, _ := newServer(t, http.StatusBadGateway, "bad gateway")
+	defer ts.Close()
+
+	g := newPlugin(t, ts.URL)
+	g.Timeout.Duration = time.Second
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "Received status code 502")
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -14,6 +15,8 @@ import (
 	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 type HTTP struct {
@@ -27,15 +30,29 @@ type HTTP struct {
 	Password string
 	tls.ClientConfig
 
+	// OAuth2 Client Credentials Grant
+	OAuth2 *OAuth2Config `toml:"oauth2"`
+
 	Timeout internal.Duration
 
 	client *http.Client
+	// tokenSource caches the OAuth2 access token across the gathers and
+	// fetches a new one before it expires
+	tokenSource oauth2.TokenSource
 
 	// The parser will automatically be set by Telegraf core code because
 	// this plugin implements the ParserInput interface (i.e. the SetParser method)
 	parser parsers.Parser
 }
 
+// OAuth2Config is the client of the OAuth2 client credentials grant.
+type OAuth2Config struct {
+	TokenURL     string   `toml:"token_url"`
+	ClientID     string   `toml:"client_id"`
+	ClientSecret string   `toml:"client_secret"`
+	Scopes       []string `toml:"scopes"`
+}
+
 var sampleConfig = `
   ## One or more URLs from which to read formatted metrics
   urls = [
@@ -67,6 +84,15 @@ var sampleConfig = `
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
   # data_format = "influx"
+
+  ## Optional OAuth2 Client Credentials Grant, the access token is fetched
+  ## from the token endpoint and sent as a bearer token.  It is reused until
+  ## it expires.
+  # [inputs.http.oauth2]
+  #   token_url = "https://identityprovider/oauth2/v1/token"
+  #   client_id = "clientid"
+  #   client_secret = "secret"
+  #   scopes = ["urn:opc:idm:__myscopes__"]
 `
 
 // SampleConfig returns the default configuration of the Input
@@ -98,6 +124,29 @@ func (h *HTTP) Gather(acc telegraf.Accumulator) error {
 			},
 			Timeout: h.Timeout.Duration,
 		}
+
+		if h.OAuth2 != nil {
+			if h.OAuth2.TokenURL == "" {
+				return errors.New("oauth2 token_url must be set")
+			}
+			config := clientcredentials.Config{
+				ClientID:     h.OAuth2.ClientID,
+				ClientSecret: h.OAuth2.ClientSecret,
+				TokenURL:     h.OAuth2.TokenURL,
+				Scopes:       h.OAuth2.Scopes,
+			}
+			ctx := context.WithValue(context.Background(), oauth2.HTTPClient, h.client)
+			h.tokenSource = config.TokenSource(ctx)
+		}
+	}
+
+	var token *oauth2.Token
+	if h.tokenSource != nil {
+		var err error
+		token, err = h.tokenSource.Token()
+		if err != nil {
+			return fmt.Errorf("fetching oauth2 token from %s: %v", h.OAuth2.TokenURL, err)
+		}
 	}
 
 	var wg sync.WaitGroup
@@ -105,7 +154,7 @@ func (h *HTTP) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(url string) {
 			defer wg.Done()
-			if err := h.gatherURL(acc, url); err != nil {
+			if err := h.gatherURL(acc, url, token); err != nil {
 				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
 			}
 		}(u)
@@ -125,12 +174,14 @@ func (h *HTTP) SetParser(parser parsers.Parser) {
 // Parameters:
 //     acc    : The telegraf Accumulator to use
 //     url    : endpoint to send request to
+//     token  : OAuth2 access token to send, if not nil
 //
 // Returns:
 //     error: Any error that may have occurred
 func (h *HTTP) gatherURL(
 	acc telegraf.Accumulator,
 	url string,
+	token *oauth2.Token,
 ) error {
 	request, err := http.NewRequest(h.Method, url, nil)
 	if err != nil {
@@ -149,6 +200,10 @@ func (h *HTTP) gatherURL(
 		request.SetBasicAuth(h.Username, h.Password)
 	}
 
+	if token != nil {
+		token.SetAuthHeader(request)
+	}
+
 	resp, err := h.client.Do(request)
 	if err != nil {
 		return err
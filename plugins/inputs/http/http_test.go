@@ -1,8 +1,10 @@
 package http_test
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	plugin "github.com/influxdata/telegraf/plugins/inputs/http"
@@ -144,6 +146,136 @@ func TestParserNotSet(t *testing.T) {
 	require.Error(t, acc.GatherError(plugin.Gather))
 }
 
+// tokenServer issues the access tokens token-1, token-2, ... valid for
+// expiresIn seconds.
+type tokenServer struct {
+	sync.Mutex
+	expiresIn int
+	issued    int
+	fail      bool
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.fail {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "invalid_client"}`))
+		return
+	}
+	if err := r.ParseForm(); err != nil ||
+		r.PostForm.Get("grant_type") != "client_credentials" ||
+		r.PostForm.Get("scope") != "metrics:read" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	user, password, _ := r.BasicAuth()
+	if user != "clientid" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.issued++
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d}`,
+		s.issued, s.expiresIn)
+}
+
+func newOAuth2Plugin(t *testing.T, tokenURL string, urls ...string) *plugin.HTTP {
+	plugin := &plugin.HTTP{
+		URLs: urls,
+		OAuth2: &plugin.OAuth2Config{
+			TokenURL:     tokenURL,
+			ClientID:     "clientid",
+			ClientSecret: "secret",
+			Scopes:       []string{"metrics:read"},
+		},
+	}
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat: "json",
+		MetricName: "metricName",
+	})
+	require.NoError(t, err)
+	plugin.SetParser(p)
+	return plugin
+}
+
+func TestOAuth2(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn int
+		issued    int
+		tokens    []string
+	}{
+		{
+			name:      "token reused across gathers",
+			expiresIn: 3600,
+			issued:    1,
+			tokens:    []string{"Bearer token-1", "Bearer token-1"},
+		},
+		{
+			// the tokens are refreshed shortly before they expire
+			name:      "token refreshed on expiry",
+			expiresIn: 1,
+			issued:    2,
+			tokens:    []string{"Bearer token-1", "Bearer token-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := &tokenServer{expiresIn: tt.expiresIn}
+			tokenServer := httptest.NewServer(tokens)
+			defer tokenServer.Close()
+
+			var mu sync.Mutex
+			var authorizations []string
+			fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				authorizations = append(authorizations, r.Header.Get("Authorization"))
+				mu.Unlock()
+				_, _ = w.Write([]byte(simpleJSON))
+			}))
+			defer fakeServer.Close()
+
+			plugin := newOAuth2Plugin(t, tokenServer.URL, fakeServer.URL+"/endpoint")
+			var acc testutil.Accumulator
+			require.NoError(t, acc.GatherError(plugin.Gather))
+			require.NoError(t, acc.GatherError(plugin.Gather))
+			require.Len(t, acc.Metrics, 2)
+			require.Equal(t, tt.tokens, authorizations)
+			require.Equal(t, tt.issued, tokens.issued)
+		})
+	}
+}
+
+func TestOAuth2TokenEndpointError(t *testing.T) {
+	tokens := &tokenServer{expiresIn: 3600, fail: true}
+	tokenServer := httptest.NewServer(tokens)
+	defer tokenServer.Close()
+
+	var requests int
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(simpleJSON))
+	}))
+	defer fakeServer.Close()
+
+	plugin := newOAuth2Plugin(t, tokenServer.URL, fakeServer.URL+"/endpoint")
+	var acc testutil.Accumulator
+	err := acc.GatherError(plugin.Gather)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "fetching oauth2 token from "+tokenServer.URL)
+	require.Empty(t, acc.Metrics)
+	require.Equal(t, 0, requests)
+
+	// the gather succeeds once the token endpoint recovers
+	tokens.Lock()
+	tokens.fail = false
+	tokens.Unlock()
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Len(t, acc.Metrics, 1)
+}
+
 const simpleJSON = `
 {
     "a": 1.2
@@ -4,6 +4,7 @@ import (
 	"compress/gzip"
 	"crypto/subtle"
 	"crypto/tls"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
@@ -25,6 +26,12 @@ const defaultMaxBodySize = 500 * 1024 * 1024
 
 type TimeFunc func() time.Time
 
+// Route is a path parsed with its own data format.
+type Route struct {
+	Path string            `toml:"path"`
+	Tags map[string]string `toml:"tags"`
+}
+
 type HTTPListenerV2 struct {
 	ServiceAddress string
 	Path           string
@@ -39,6 +46,8 @@ type HTTPListenerV2 struct {
 	BasicUsername string
 	BasicPassword string
 
+	Routes []Route `toml:"route"`
+
 	TimeFunc
 
 	wg sync.WaitGroup
@@ -46,7 +55,9 @@ type HTTPListenerV2 struct {
 	listener net.Listener
 
 	parsers.Parser
-	acc telegraf.Accumulator
+	routeParsers map[string]parsers.Parser
+	routes       map[string]Route
+	acc          telegraf.Accumulator
 }
 
 const sampleConfig = `
@@ -86,6 +97,19 @@ const sampleConfig = `
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
   data_format = "influx"
+
+  ## Routes parsing the requests to their path with their own data format,
+  ## instead of the path and data_format above.  Requests to other paths are
+  ## rejected.  Each route accepts the options of its data format and static
+  ## tags added to its metrics.
+  # [[inputs.http_listener_v2.route]]
+  #   path = "/json"
+  #   data_format = "json"
+  #   [inputs.http_listener_v2.route.tags]
+  #     source = "json"
+  # [[inputs.http_listener_v2.route]]
+  #   path = "/influx"
+  #   data_format = "influx"
 `
 
 func (h *HTTPListenerV2) SampleConfig() string {
@@ -104,6 +128,10 @@ func (h *HTTPListenerV2) SetParser(parser parsers.Parser) {
 	h.Parser = parser
 }
 
+func (h *HTTPListenerV2) SetRouteParsers(parsers map[string]parsers.Parser) {
+	h.routeParsers = parsers
+}
+
 // Start starts the http listener service.
 func (h *HTTPListenerV2) Start(acc telegraf.Accumulator) error {
 	if h.MaxBodySize.Size == 0 {
@@ -117,6 +145,17 @@ func (h *HTTPListenerV2) Start(acc telegraf.Accumulator) error {
 		h.WriteTimeout.Duration = time.Second * 10
 	}
 
+	h.routes = make(map[string]Route, len(h.Routes))
+	for _, route := range h.Routes {
+		if _, ok := h.routes[route.Path]; ok {
+			return fmt.Errorf("duplicate route %s", route.Path)
+		}
+		if h.routeParsers[route.Path] == nil {
+			return fmt.Errorf("no parser for route %s", route.Path)
+		}
+		h.routes[route.Path] = route
+	}
+
 	h.acc = acc
 
 	tlsConf, err := h.ServerConfig.TLSConfig()
@@ -164,14 +203,32 @@ func (h *HTTPListenerV2) Stop() {
 }
 
 func (h *HTTPListenerV2) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if len(h.routes) > 0 {
+		if route, ok := h.routes[req.URL.Path]; ok {
+			h.AuthenticateIfSet(func(res http.ResponseWriter, req *http.Request) {
+				h.serveWrite(res, req, h.routeParsers[route.Path], route.Tags)
+			}, res, req)
+		} else {
+			h.AuthenticateIfSet(http.NotFound, res, req)
+		}
+		return
+	}
+
 	if req.URL.Path == h.Path {
-		h.AuthenticateIfSet(h.serveWrite, res, req)
+		h.AuthenticateIfSet(func(res http.ResponseWriter, req *http.Request) {
+			h.serveWrite(res, req, h.Parser, nil)
+		}, res, req)
 	} else {
 		h.AuthenticateIfSet(http.NotFound, res, req)
 	}
 }
 
-func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request) {
+func (h *HTTPListenerV2) serveWrite(
+	res http.ResponseWriter,
+	req *http.Request,
+	parser parsers.Parser,
+	tags map[string]string,
+) {
 	// Check that the content length is not too large for us to handle.
 	if req.ContentLength > h.MaxBodySize.Size {
 		tooLarge(res)
@@ -211,13 +268,16 @@ func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	metrics, err := h.Parse(bytes)
+	metrics, err := parser.Parse(bytes)
 	if err != nil {
 		log.Println("D! " + err.Error())
 		badRequest(res)
 		return
 	}
 	for _, m := range metrics {
+		for k, v := range tags {
+			m.AddTag(k, v)
+		}
 		h.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
 	}
 	res.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,12 @@
+// +build !linux !cgo !journald
+
+package journald
+
+import (
+	"fmt"
+)
+
+func openJournal(path string) (journal, error) {
+	return nil, fmt.Errorf("telegraf was built without the journal, " +
+		"it must be built on linux with cgo and the journald build tag")
+}
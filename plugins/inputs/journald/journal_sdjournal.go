@@ -0,0 +1,40 @@
+// +build linux,cgo,journald
+
+package journald
+
+import (
+	"time"
+
+	"github.com/coreos/go-systemd/sdjournal"
+)
+
+// sdJournal reads the journal with the sd-journal API of libsystemd.
+type sdJournal struct {
+	*sdjournal.Journal
+}
+
+func openJournal(path string) (journal, error) {
+	var j *sdjournal.Journal
+	var err error
+	if path == "" {
+		j, err = sdjournal.NewJournal()
+	} else {
+		j, err = sdjournal.NewJournalFromDir(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sdJournal{Journal: j}, nil
+}
+
+func (j *sdJournal) GetEntry() (*entry, error) {
+	e, err := j.Journal.GetEntry()
+	if err != nil {
+		return nil, err
+	}
+	return &entry{
+		fields: e.Fields,
+		cursor: e.Cursor,
+		time:   time.Unix(0, int64(e.RealtimeTimestamp)*int64(time.Microsecond)),
+	}, nil
+}
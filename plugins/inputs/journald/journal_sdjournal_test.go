@@ -0,0 +1,38 @@
+// +build linux,cgo,journald
+
+package journald
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/journal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalJournal(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	if !journal.Enabled() {
+		t.Skip("Skipping test, the journal is not running")
+	}
+
+	plugin := &Journald{FromBeginning: true, Priority: "info"}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, journal.Send("telegraf journald test", journal.PriInfo,
+		map[string]string{"SYSLOG_IDENTIFIER": "telegraf-test"}))
+
+	// the entry is written by journald asynchronously
+	for i := 0; i < 50 && !acc.HasMeasurement("journald"); i++ {
+		require.NoError(t, plugin.Gather(&acc))
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.Empty(t, acc.Errors)
+	require.True(t, acc.HasMeasurement("journald"))
+}
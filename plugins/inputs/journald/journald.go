@@ -0,0 +1,333 @@
+package journald
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// waitTimeout bounds the time spent waiting for new entries, so the plugin
+// stops in time.
+const waitTimeout = 250 * time.Millisecond
+
+var priorities = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+var defaultTagKeys = []string{"_SYSTEMD_UNIT", "SYSLOG_IDENTIFIER", "PRIORITY"}
+
+const sampleConfig = `
+  ## Directory of the journal files to read, by default the journal of the
+  ## local system.
+  # path = "/var/log/journal"
+
+  ## Only read the entries of these units, and with at least this priority;
+  ## one of emerg, alert, crit, err, warning, notice, info or debug.
+  # units = ["sshd.service"]
+  # priority = "info"
+
+  ## Journal fields added as tags, and as fields in addition to the message.
+  ## The names are lower cased without the leading underscores, for example
+  ## _SYSTEMD_UNIT is added as the systemd_unit tag.
+  # tag_keys = ["_SYSTEMD_UNIT", "SYSLOG_IDENTIFIER", "PRIORITY"]
+  # field_keys = []
+
+  ## File storing the cursor of the last entry read, to resume reading from
+  ## it after a restart.
+  # cursor_file = "/var/lib/telegraf/journald.cursor"
+
+  ## Read the entries already in the journal when there is no cursor to
+  ## resume from, otherwise only the new entries are read.
+  # from_beginning = false
+
+  ## Read the new entries as soon as they are written, rather than on every
+  ## interval.
+  # follow = true
+`
+
+type Journald struct {
+	Path          string   `toml:"path"`
+	Units         []string `toml:"units"`
+	Priority      string   `toml:"priority"`
+	TagKeys       []string `toml:"tag_keys"`
+	FieldKeys     []string `toml:"field_keys"`
+	CursorFile    string   `toml:"cursor_file"`
+	FromBeginning bool     `toml:"from_beginning"`
+	Follow        bool     `toml:"follow"`
+
+	// open opens the journal files in the directory, or the local journal
+	open func(path string) (journal, error)
+
+	journal journal
+	// cursor is the cursor of the last entry read
+	cursor  string
+	matches []string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// entry is an entry of the journal, with its fields by name.
+type entry struct {
+	fields map[string]string
+	cursor string
+	time   time.Time
+}
+
+// journal is the subset of the sd-journal API the plugin is using.
+type journal interface {
+	AddMatch(match string) error
+	SeekHead() error
+	SeekTail() error
+	SeekCursor(cursor string) error
+	Next() (uint64, error)
+	Previous() (uint64, error)
+	GetEntry() (*entry, error)
+	Wait(timeout time.Duration) int
+	Close() error
+}
+
+func (j *Journald) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Journald) Description() string {
+	return "Read the entries of the systemd journal"
+}
+
+func (j *Journald) Init() error {
+	if j.open == nil {
+		j.open = openJournal
+	}
+	if j.TagKeys == nil {
+		j.TagKeys = defaultTagKeys
+	}
+
+	j.matches = nil
+	for _, unit := range j.Units {
+		j.matches = append(j.matches, "_SYSTEMD_UNIT="+unit)
+	}
+	if j.Priority != "" {
+		priority, ok := priorities[j.Priority]
+		if !ok {
+			return fmt.Errorf("invalid priority %q", j.Priority)
+		}
+		// matches of the same field are alternatives
+		for i := 0; i <= priority; i++ {
+			j.matches = append(j.matches, fmt.Sprintf("PRIORITY=%d", i))
+		}
+	}
+	return nil
+}
+
+func (j *Journald) Start(acc telegraf.Accumulator) error {
+	if j.CursorFile != "" {
+		cursor, err := ioutil.ReadFile(j.CursorFile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not read cursor: %v", err)
+		}
+		j.cursor = strings.TrimSpace(string(cursor))
+	}
+	if err := j.openJournal(); err != nil {
+		return err
+	}
+
+	if !j.Follow {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		j.follow(ctx, acc)
+	}()
+	return nil
+}
+
+func (j *Journald) Gather(acc telegraf.Accumulator) error {
+	if !j.Follow {
+		j.read(acc)
+	}
+	return nil
+}
+
+func (j *Journald) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+	if j.journal != nil {
+		j.journal.Close()
+		j.journal = nil
+	}
+}
+
+// openJournal opens the journal and seeks after the last entry read.
+func (j *Journald) openJournal() error {
+	journal, err := j.open(j.Path)
+	if err != nil {
+		return fmt.Errorf("could not open journal: %v", err)
+	}
+	for _, match := range j.matches {
+		if err := journal.AddMatch(match); err != nil {
+			journal.Close()
+			return fmt.Errorf("could not add match %s: %v", match, err)
+		}
+	}
+
+	switch {
+	case j.cursor != "":
+		// the entry of the cursor is skipped once read again
+		err = journal.SeekCursor(j.cursor)
+	case j.FromBeginning:
+		err = journal.SeekHead()
+	default:
+		// the tail is after the last entry, go back to it so the next
+		// entry is the first new one
+		err = journal.SeekTail()
+		if err == nil {
+			_, err = journal.Previous()
+		}
+	}
+	if err != nil {
+		journal.Close()
+		return fmt.Errorf("could not seek journal: %v", err)
+	}
+	j.journal = journal
+	return nil
+}
+
+// follow reads the entries as they are written until the context is done.
+func (j *Journald) follow(ctx context.Context, acc telegraf.Accumulator) {
+	for {
+		j.read(acc)
+		if j.journal == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		// rotated journal files are picked up by the journal itself
+		j.journal.Wait(waitTimeout)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+// read adds the new entries of the journal.  The journal is reopened from the
+// cursor after errors, in case its files were removed or corrupted.
+func (j *Journald) read(acc telegraf.Accumulator) {
+	if j.journal == nil {
+		if err := j.openJournal(); err != nil {
+			acc.AddError(err)
+			return
+		}
+	}
+
+	cursor := j.cursor
+	if err := j.readEntries(acc); err != nil {
+		acc.AddError(fmt.Errorf("could not read journal, reopening it: %v", err))
+		j.journal.Close()
+		j.journal = nil
+	}
+	if j.cursor != cursor {
+		if err := j.saveCursor(); err != nil {
+			acc.AddError(err)
+		}
+	}
+}
+
+func (j *Journald) readEntries(acc telegraf.Accumulator) error {
+	for {
+		n, err := j.journal.Next()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+
+		e, err := j.journal.GetEntry()
+		if err != nil {
+			return err
+		}
+		if e.cursor == j.cursor {
+			continue
+		}
+		j.cursor = e.cursor
+
+		tags := make(map[string]string)
+		for _, key := range j.TagKeys {
+			if v, ok := e.fields[key]; ok {
+				tags[keyName(key)] = v
+			}
+		}
+		fields := make(map[string]interface{})
+		if v, ok := e.fields["MESSAGE"]; ok {
+			fields["message"] = v
+		}
+		for _, key := range j.FieldKeys {
+			if v, ok := e.fields[key]; ok {
+				fields[keyName(key)] = v
+			}
+		}
+		acc.AddFields("journald", fields, tags, e.time)
+	}
+}
+
+// saveCursor writes the cursor to the cursor file, replacing it at once so it
+// is never left half written.
+func (j *Journald) saveCursor() error {
+	if j.CursorFile == "" {
+		return nil
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(j.CursorFile), filepath.Base(j.CursorFile))
+	if err != nil {
+		return fmt.Errorf("could not save cursor: %v", err)
+	}
+	_, err = tmp.WriteString(j.cursor + "\n")
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.CursorFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not save cursor: %v", err)
+	}
+	return nil
+}
+
+// keyName is the name of the tag or field of a journal field.
+func keyName(key string) string {
+	return strings.ToLower(strings.TrimLeft(key, "_"))
+}
+
+func init() {
+	inputs.Add("journald", func() telegraf.Input {
+		return &Journald{Follow: true}
+	})
+}
@@ -0,0 +1,285 @@
+package journald
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// mockJournal is a journal of entries with the cursors c0, c1 and so on.
+type mockJournal struct {
+	mu      sync.Mutex
+	entries []*entry
+	// pos is the index of the current entry
+	pos     int
+	matches []string
+	opens   int
+	closes  int
+	// failNext fails the next call of Next
+	failNext bool
+}
+
+func (m *mockJournal) addEntry(message string, fields map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := len(m.entries)
+	e := &entry{
+		fields: map[string]string{"MESSAGE": message},
+		cursor: fmt.Sprintf("c%d", n),
+		time:   time.Unix(int64(n), 0),
+	}
+	for k, v := range fields {
+		e.fields[k] = v
+	}
+	m.entries = append(m.entries, e)
+}
+
+func (m *mockJournal) open(path string) (journal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opens++
+	m.matches = nil
+	m.pos = -1
+	return m, nil
+}
+
+func (m *mockJournal) AddMatch(match string) error {
+	m.matches = append(m.matches, match)
+	return nil
+}
+
+func (m *mockJournal) SeekHead() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pos = -1
+	return nil
+}
+
+func (m *mockJournal) SeekTail() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pos = len(m.entries)
+	return nil
+}
+
+func (m *mockJournal) SeekCursor(cursor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.entries {
+		if e.cursor == cursor {
+			// the next entry is the one of the cursor
+			m.pos = i - 1
+			return nil
+		}
+	}
+	return errors.New("unknown cursor")
+}
+
+func (m *mockJournal) Next() (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failNext {
+		m.failNext = false
+		return 0, errors.New("journal file removed")
+	}
+	if m.pos+1 >= len(m.entries) {
+		return 0, nil
+	}
+	m.pos++
+	return 1, nil
+}
+
+func (m *mockJournal) Previous() (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pos <= 0 {
+		m.pos = -1
+		return 0, nil
+	}
+	m.pos--
+	return 1, nil
+}
+
+func (m *mockJournal) GetEntry() (*entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entries[m.pos], nil
+}
+
+func (m *mockJournal) Wait(timeout time.Duration) int {
+	time.Sleep(time.Millisecond)
+	return 0
+}
+
+func (m *mockJournal) Close() error {
+	m.closes++
+	return nil
+}
+
+func newTestJournald(t *testing.T, plugin *Journald) *mockJournal {
+	m := &mockJournal{}
+	plugin.open = m.open
+	require.NoError(t, plugin.Init())
+	return m
+}
+
+func messages(acc *testutil.Accumulator) []string {
+	acc.Lock()
+	defer acc.Unlock()
+	var messages []string
+	for _, m := range acc.Metrics {
+		messages = append(messages, m.Fields["message"].(string))
+	}
+	return messages
+}
+
+func TestGatherNewEntries(t *testing.T) {
+	plugin := &Journald{}
+	m := newTestJournald(t, plugin)
+	m.addEntry("before start", nil)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+
+	m.addEntry("accepted connection", map[string]string{
+		"_SYSTEMD_UNIT":     "sshd.service",
+		"SYSLOG_IDENTIFIER": "sshd",
+		"PRIORITY":          "6",
+		"_PID":              "42",
+	})
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, []*testutil.Metric{{
+		Measurement: "journald",
+		Tags: map[string]string{
+			"systemd_unit":      "sshd.service",
+			"syslog_identifier": "sshd",
+			"priority":          "6",
+		},
+		Fields: map[string]interface{}{"message": "accepted connection"},
+		Time:   time.Unix(1, 0),
+	}}, acc.Metrics)
+}
+
+func TestFieldKeysAndMatches(t *testing.T) {
+	plugin := &Journald{
+		FromBeginning: true,
+		Units:         []string{"sshd.service", "cron.service"},
+		Priority:      "err",
+		TagKeys:       []string{},
+		FieldKeys:     []string{"_PID", "MISSING"},
+	}
+	m := newTestJournald(t, plugin)
+	m.addEntry("error", map[string]string{"_PID": "42", "PRIORITY": "3"})
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+
+	require.Equal(t, []string{
+		"_SYSTEMD_UNIT=sshd.service",
+		"_SYSTEMD_UNIT=cron.service",
+		"PRIORITY=0",
+		"PRIORITY=1",
+		"PRIORITY=2",
+		"PRIORITY=3",
+	}, m.matches)
+	require.Equal(t, []*testutil.Metric{{
+		Measurement: "journald",
+		Tags:        map[string]string{},
+		Fields:      map[string]interface{}{"message": "error", "pid": "42"},
+		Time:        time.Unix(0, 0),
+	}}, acc.Metrics)
+}
+
+func TestCursorFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cursorFile := filepath.Join(dir, "cursor")
+
+	plugin := &Journald{FromBeginning: true, CursorFile: cursorFile}
+	m := newTestJournald(t, plugin)
+	m.addEntry("first", nil)
+	m.addEntry("second", nil)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+	plugin.Stop()
+	require.Equal(t, []string{"first", "second"}, messages(&acc))
+
+	cursor, err := ioutil.ReadFile(cursorFile)
+	require.NoError(t, err)
+	require.Equal(t, "c1\n", string(cursor))
+
+	// a restarted plugin resumes after the cursor, not from the beginning
+	m.addEntry("third", nil)
+	plugin = &Journald{FromBeginning: true, CursorFile: cursorFile, open: m.open}
+	require.NoError(t, plugin.Init())
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Start(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+	plugin.Stop()
+	require.Equal(t, []string{"third"}, messages(&acc))
+
+	cursor, err = ioutil.ReadFile(cursorFile)
+	require.NoError(t, err)
+	require.Equal(t, "c2\n", string(cursor))
+}
+
+func TestReopenAfterError(t *testing.T) {
+	plugin := &Journald{FromBeginning: true}
+	m := newTestJournald(t, plugin)
+	m.addEntry("first", nil)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.NoError(t, plugin.Gather(&acc))
+
+	m.addEntry("second", nil)
+	m.failNext = true
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, 1, m.closes)
+
+	// the journal is opened again and read from the cursor
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, 2, m.opens)
+	require.Equal(t, []string{"first", "second"}, messages(&acc))
+}
+
+func TestFollow(t *testing.T) {
+	plugin := &Journald{Follow: true}
+	m := newTestJournald(t, plugin)
+	m.addEntry("before start", nil)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	m.addEntry("first", nil)
+	m.addEntry("second", nil)
+	acc.Wait(2)
+	plugin.Stop()
+
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, []string{"first", "second"}, messages(&acc))
+	require.Equal(t, 1, m.closes)
+}
+
+func TestInvalidPriority(t *testing.T) {
+	plugin := &Journald{Priority: "verbose"}
+	require.EqualError(t, plugin.Init(), `invalid priority "verbose"`)
+}
@@ -0,0 +1,364 @@
+package kafka_consumer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxAvroDepth limits the nesting of decoded values, a recursive schema
+// could otherwise recurse forever.
+const maxAvroDepth = 64
+
+var errAvroTruncated = errors.New("invalid Avro data: truncated")
+
+// avroSchema is a parsed Avro schema, see
+// https://avro.apache.org/docs/current/spec.html
+type avroSchema struct {
+	// typ is the name of a primitive type or one of record, enum, array,
+	// map, union or fixed.
+	typ string
+
+	name     string
+	fields   []avroField
+	symbols  []string
+	items    *avroSchema
+	values   *avroSchema
+	branches []*avroSchema
+	size     int
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+var avroPrimitives = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"int":     true,
+	"long":    true,
+	"float":   true,
+	"double":  true,
+	"bytes":   true,
+	"string":  true,
+}
+
+func parseAvroSchema(buf []byte) (*avroSchema, error) {
+	var v interface{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %v", err)
+	}
+	p := &avroSchemaParser{names: make(map[string]*avroSchema)}
+	schema, err := p.parse(v, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %v", err)
+	}
+	return schema, nil
+}
+
+// avroSchemaParser keeps the named types of a schema, later parts of the
+// schema can refer to them by name.
+type avroSchemaParser struct {
+	names map[string]*avroSchema
+}
+
+func (p *avroSchemaParser) parse(v interface{}, namespace string) (*avroSchema, error) {
+	switch v := v.(type) {
+	case string:
+		if avroPrimitives[v] {
+			return &avroSchema{typ: v}, nil
+		}
+		if schema, ok := p.names[fullName(v, namespace)]; ok {
+			return schema, nil
+		}
+		if schema, ok := p.names[v]; ok {
+			return schema, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", v)
+	case []interface{}:
+		schema := &avroSchema{typ: "union"}
+		for _, branch := range v {
+			s, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			schema.branches = append(schema.branches, s)
+		}
+		return schema, nil
+	case map[string]interface{}:
+		return p.parseComplex(v, namespace)
+	}
+	return nil, fmt.Errorf("unexpected %v", v)
+}
+
+func (p *avroSchemaParser) parseComplex(v map[string]interface{}, namespace string) (*avroSchema, error) {
+	typ, ok := v["type"].(string)
+	if !ok {
+		// {"type": {...}} or {"type": [...]}
+		return p.parse(v["type"], namespace)
+	}
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+	case "array":
+		items, err := p.parse(v["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{typ: typ, items: items}, nil
+	case "map":
+		values, err := p.parse(v["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroSchema{typ: typ, values: values}, nil
+	default:
+		// primitive types with attributes such as logicalType, or a
+		// reference to a named type
+		return p.parse(typ, namespace)
+	}
+
+	name, _ := v["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("%s without name", typ)
+	}
+	if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+	name = fullName(name, namespace)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		namespace = name[:i]
+	}
+
+	schema := &avroSchema{typ: typ, name: name}
+	// registered before parsing the fields so they can refer to it
+	p.names[name] = schema
+
+	switch typ {
+	case "record", "error":
+		schema.typ = "record"
+		fields, ok := v["fields"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("record %s without fields", name)
+		}
+		for _, f := range fields {
+			f, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid field in record %s", name)
+			}
+			fieldName, _ := f["name"].(string)
+			if fieldName == "" {
+				return nil, fmt.Errorf("field without name in record %s", name)
+			}
+			fieldSchema, err := p.parse(f["type"], namespace)
+			if err != nil {
+				return nil, err
+			}
+			schema.fields = append(schema.fields, avroField{
+				name:   fieldName,
+				schema: fieldSchema,
+			})
+		}
+	case "enum":
+		symbols, ok := v["symbols"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("enum %s without symbols", name)
+		}
+		for _, s := range symbols {
+			symbol, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid symbol in enum %s", name)
+			}
+			schema.symbols = append(schema.symbols, symbol)
+		}
+	case "fixed":
+		size, ok := v["size"].(float64)
+		if !ok || size < 0 {
+			return nil, fmt.Errorf("fixed %s without size", name)
+		}
+		schema.size = int(size)
+	}
+	return schema, nil
+}
+
+func fullName(name, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// decodeAvro decodes the Avro binary encoded value of the schema.  Records
+// and maps are decoded to map[string]interface{}, enums to their symbol and
+// unions to the value of their branch.
+func decodeAvro(schema *avroSchema, buf []byte) (interface{}, error) {
+	d := avroDecoder{buf: buf}
+	return d.decode(schema, 0)
+}
+
+type avroDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *avroDecoder) decode(schema *avroSchema, depth int) (interface{}, error) {
+	if depth > maxAvroDepth {
+		return nil, errors.New("invalid Avro data: nested too deep")
+	}
+
+	switch schema.typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		return d.readLong()
+	case "float":
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case "double":
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes", "string":
+		return d.readString()
+	case "fixed":
+		b, err := d.read(schema.size)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "enum":
+		i, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(schema.symbols)) {
+			return nil, fmt.Errorf("invalid Avro data: enum index %d out of range", i)
+		}
+		return schema.symbols[i], nil
+	case "union":
+		i, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(schema.branches)) {
+			return nil, fmt.Errorf("invalid Avro data: union index %d out of range", i)
+		}
+		return d.decode(schema.branches[i], depth+1)
+	case "record":
+		record := make(map[string]interface{}, len(schema.fields))
+		for _, field := range schema.fields {
+			value, err := d.decode(field.schema, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			record[field.name] = value
+		}
+		return record, nil
+	case "array":
+		array := []interface{}{}
+		err := d.readBlocks(func() error {
+			value, err := d.decode(schema.items, depth+1)
+			if err != nil {
+				return err
+			}
+			array = append(array, value)
+			return nil
+		})
+		return array, err
+	case "map":
+		m := make(map[string]interface{})
+		err := d.readBlocks(func() error {
+			key, err := d.readString()
+			if err != nil {
+				return err
+			}
+			value, err := d.decode(schema.values, depth+1)
+			if err != nil {
+				return err
+			}
+			m[key] = value
+			return nil
+		})
+		return m, err
+	}
+	return nil, fmt.Errorf("unsupported Avro type %q", schema.typ)
+}
+
+func (d *avroDecoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, errAvroTruncated
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readLong reads a zig-zag encoded variable length integer.
+func (d *avroDecoder) readLong() (int64, error) {
+	v, n := binary.Varint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, errAvroTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *avroDecoder) readString() (string, error) {
+	n, err := d.readLong()
+	if err != nil {
+		return "", err
+	}
+	if n > int64(len(d.buf)) {
+		return "", errAvroTruncated
+	}
+	b, err := d.read(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readBlocks reads the blocks of an array or map, calling item for each of
+// their items.
+func (d *avroDecoder) readBlocks(item func() error) error {
+	for {
+		count, err := d.readLong()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// followed by the size of the block in bytes
+			count = -count
+			if _, err := d.readLong(); err != nil {
+				return err
+			}
+		}
+		// a block cannot have more items than there are bytes left
+		if count < 0 || count > int64(len(d.buf)-d.pos) {
+			return errAvroTruncated
+		}
+		for i := int64(0); i < count; i++ {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
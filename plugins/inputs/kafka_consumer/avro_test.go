@@ -0,0 +1,172 @@
+package kafka_consumer
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// avroEncoder builds Avro binary encoded test data.
+type avroEncoder struct {
+	buf []byte
+}
+
+func (e *avroEncoder) long(v int64) *avroEncoder {
+	b := make([]byte, binary.MaxVarintLen64)
+	e.buf = append(e.buf, b[:binary.PutVarint(b, v)]...)
+	return e
+}
+
+func (e *avroEncoder) str(s string) *avroEncoder {
+	e.long(int64(len(s)))
+	e.buf = append(e.buf, s...)
+	return e
+}
+
+func (e *avroEncoder) double(f float64) *avroEncoder {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, math.Float64bits(f))
+	e.buf = append(e.buf, b...)
+	return e
+}
+
+func (e *avroEncoder) float(f float32) *avroEncoder {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, math.Float32bits(f))
+	e.buf = append(e.buf, b...)
+	return e
+}
+
+func (e *avroEncoder) boolean(v bool) *avroEncoder {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+	return e
+}
+
+const testSchema = `{
+  "type": "record",
+  "name": "Measurement",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "value", "type": "double"},
+    {"name": "ratio", "type": "float"},
+    {"name": "count", "type": "long"},
+    {"name": "ok", "type": "boolean"},
+    {"name": "state", "type": {"type": "enum", "name": "State", "symbols": ["UP", "DOWN"]}},
+    {"name": "host", "type": ["null", "string"]},
+    {"name": "tags", "type": {"type": "map", "values": "string"}},
+    {"name": "samples", "type": {"type": "array", "items": "int"}},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "previous", "type": ["null", "State"]}
+  ]
+}`
+
+func TestDecodeAvro(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(testSchema))
+	require.NoError(t, err)
+
+	e := &avroEncoder{}
+	e.str("cpu").double(42.5).float(0.5).long(-3).boolean(true)
+	e.long(1)                             // state DOWN
+	e.long(1).str("server01")             // host
+	e.long(1).str("dc").str("eu").long(0) // tags
+	e.long(-2).long(2).long(1).long(2)    // samples block with its size
+	e.long(1).long(3).long(0)             // second samples block
+	e.long(1556813561098)                 // time
+	e.long(0)                             // previous null
+
+	value, err := decodeAvro(schema, e.buf)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"name":     "cpu",
+		"value":    42.5,
+		"ratio":    0.5,
+		"count":    int64(-3),
+		"ok":       true,
+		"state":    "DOWN",
+		"host":     "server01",
+		"tags":     map[string]interface{}{"dc": "eu"},
+		"samples":  []interface{}{int64(1), int64(2), int64(3)},
+		"time":     int64(1556813561098),
+		"previous": nil,
+	}, value)
+}
+
+func TestDecodeAvroRecursive(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(`{
+	  "type": "record",
+	  "name": "Node",
+	  "fields": [
+	    {"name": "value", "type": "int"},
+	    {"name": "next", "type": ["null", "Node"]}
+	  ]
+	}`))
+	require.NoError(t, err)
+
+	e := &avroEncoder{}
+	e.long(1).long(1).long(2).long(0)
+
+	value, err := decodeAvro(schema, e.buf)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"value": int64(1),
+		"next": map[string]interface{}{
+			"value": int64(2),
+			"next":  nil,
+		},
+	}, value)
+}
+
+func TestDecodeAvroTruncated(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(testSchema))
+	require.NoError(t, err)
+
+	e := &avroEncoder{}
+	e.str("cpu").double(42.5)
+
+	_, err = decodeAvro(schema, e.buf)
+	require.Error(t, err)
+}
+
+func TestDecodeAvroBlockCount(t *testing.T) {
+	schema, err := parseAvroSchema([]byte(`{"type": "array", "items": "long"}`))
+	require.NoError(t, err)
+
+	// a block claiming more items than there are bytes left
+	for _, count := range []int64{5, -5, math.MaxInt64, math.MinInt64} {
+		e := &avroEncoder{}
+		e.long(count)
+		if count < 0 {
+			e.long(4)
+		}
+		e.long(1).long(2).long(3).long(0)
+
+		_, err = decodeAvro(schema, e.buf)
+		require.Error(t, err, "count %d", count)
+	}
+
+	e := &avroEncoder{}
+	e.long(-3).long(3).long(1).long(2).long(3).long(0)
+	value, err := decodeAvro(schema, e.buf)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, value)
+}
+
+func TestParseAvroSchemaErrors(t *testing.T) {
+	for _, schema := range []string{
+		`not json`,
+		`"unknown"`,
+		`{"type": "record", "fields": []}`,
+		`{"type": "record", "name": "A", "fields": [{"name": "a", "type": "B"}]}`,
+		`{"type": "enum", "name": "E"}`,
+	} {
+		_, err := parseAvroSchema([]byte(schema))
+		require.Error(t, err, schema)
+	}
+}
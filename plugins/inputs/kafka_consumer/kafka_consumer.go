@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/Shopify/sarama"
 	cluster "github.com/bsm/sarama-cluster"
@@ -40,12 +41,16 @@ type Kafka struct {
 	Offset                 string   `toml:"offset"`
 	SASLUsername           string   `toml:"sasl_username"`
 	SASLPassword           string   `toml:"sasl_password"`
+	SchemaRegistryURL      string   `toml:"schema_registry_url"`
+	HeaderAsTags           []string `toml:"header_as_tags"`
+	HeaderDuplicates       string   `toml:"header_duplicates"`
 	tls.ClientConfig
 
-	cluster Consumer
-	parser  parsers.Parser
-	wg      *sync.WaitGroup
-	cancel  context.CancelFunc
+	cluster  Consumer
+	parser   parsers.Parser
+	registry *schemaRegistry
+	wg       *sync.WaitGroup
+	cancel   context.CancelFunc
 
 	// Unconfirmed messages
 	messages map[telegraf.TrackingID]*sarama.ConsumerMessage
@@ -89,6 +94,20 @@ var sampleConfig = `
   ## larger messages are dropped
   max_message_len = 1000000
 
+  ## URL of the Confluent Schema Registry.  When set, the messages are
+  ## expected in the Confluent wire format and their Avro value is decoded to
+  ## JSON with the schema from the registry before being parsed, use a JSON
+  ## data_format.
+  # schema_registry_url = "http://localhost:8081"
+
+  ## Record headers added as tags to the metrics of the messages, the headers
+  ## require a version of at least 0.11.0.0.  The header values that are not
+  ## valid UTF-8 are skipped.
+  # header_as_tags = []
+  ## How to tag the metrics when a header key is repeated in a record, either
+  ## "first" to keep the first value or "join" to join the values with commas.
+  # header_duplicates = "first"
+
   ## Maximum messages to read from the broker that have not been written by an
   ## output.  For best throughput set based on the number of metrics within
   ## each message and the size of the output's metric_batch_size.
@@ -183,6 +202,18 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 		}
 	}
 
+	if k.SchemaRegistryURL != "" {
+		k.registry = newSchemaRegistry(k.SchemaRegistryURL)
+	}
+
+	switch k.HeaderDuplicates {
+	case "":
+		k.HeaderDuplicates = "first"
+	case "first", "join":
+	default:
+		return fmt.Errorf("invalid header_duplicates %q, must be \"first\" or \"join\"", k.HeaderDuplicates)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	k.cancel = cancel
 
@@ -252,17 +283,71 @@ func (k *Kafka) onMessage(acc telegraf.TrackingAccumulator, msg *sarama.Consumer
 			len(msg.Value), k.MaxMessageLen)
 	}
 
-	metrics, err := k.parser.Parse(msg.Value)
+	value := msg.Value
+	if k.registry != nil {
+		var err error
+		value, err = k.registry.decode(msg.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	metrics, err := k.parser.Parse(value)
 	if err != nil {
 		return err
 	}
 
+	if len(k.HeaderAsTags) > 0 {
+		tags := k.headerTags(msg.Headers)
+		for _, m := range metrics {
+			for key, value := range tags {
+				m.AddTag(key, value)
+			}
+		}
+	}
+
 	id := acc.AddTrackingMetricGroup(metrics)
 	k.messages[id] = msg
 
 	return nil
 }
 
+// headerTags returns the tags of the headers in header_as_tags.
+func (k *Kafka) headerTags(headers []*sarama.RecordHeader) map[string]string {
+	tags := make(map[string]string)
+	for _, header := range headers {
+		if header == nil {
+			continue
+		}
+		key := string(header.Key)
+		if !k.isHeaderTag(key) {
+			continue
+		}
+		if !utf8.Valid(header.Value) {
+			log.Printf("W! [inputs.kafka_consumer] Skipping header %q, its value is not valid UTF-8", key)
+			continue
+		}
+
+		value, ok := tags[key]
+		switch {
+		case !ok:
+			tags[key] = string(header.Value)
+		case k.HeaderDuplicates == "join":
+			tags[key] = value + "," + string(header.Value)
+		}
+	}
+	return tags
+}
+
+func (k *Kafka) isHeaderTag(key string) bool {
+	for _, tag := range k.HeaderAsTags {
+		if tag == key {
+			return true
+		}
+	}
+	return false
+}
+
 func (k *Kafka) onDelivery(track telegraf.DeliveryInfo) {
 	msg, ok := k.messages[track.ID()]
 	if !ok {
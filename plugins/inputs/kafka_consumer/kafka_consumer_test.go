@@ -2,7 +2,11 @@ package kafka_consumer
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/Shopify/sarama"
@@ -165,6 +169,116 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+const testRegistrySchema = `{
+  "type": "record",
+  "name": "Load",
+  "fields": [
+    {"name": "host", "type": "string"},
+    {"name": "value", "type": "double"}
+  ]
+}`
+
+// newTestRegistry returns a Schema Registry serving testRegistrySchema with
+// the ID 1, after failing the first fail requests.
+func newTestRegistry(t *testing.T, fail int32) (*httptest.Server, *int32) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/schemas/ids/1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		err := json.NewEncoder(w).Encode(map[string]string{"schema": testRegistrySchema})
+		assert.NoError(t, err)
+	}))
+	return ts, &requests
+}
+
+// confluentMsg returns a message in the Confluent wire format with the
+// schema ID 1 and a value of testRegistrySchema.
+func confluentMsg(host string, value float64) *sarama.ConsumerMessage {
+	e := &avroEncoder{buf: []byte{0, 0, 0, 0, 1}}
+	e.str(host).double(value)
+	return &sarama.ConsumerMessage{Value: e.buf}
+}
+
+func newSchemaRegistryKafka(t *testing.T, url string) (*Kafka, *TestConsumer) {
+	k, consumer := newTestKafka()
+	k.registry = newSchemaRegistry(url)
+	var err error
+	k.parser, err = parsers.NewParser(&parsers.Config{
+		DataFormat: "json",
+		MetricName: "load",
+		TagKeys:    []string{"host"},
+	})
+	assert.NoError(t, err)
+	return k, consumer
+}
+
+func TestRunParserSchemaRegistry(t *testing.T) {
+	ts, requests := newTestRegistry(t, 0)
+	defer ts.Close()
+
+	k, consumer := newSchemaRegistryKafka(t, ts.URL)
+	acc := testutil.Accumulator{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go k.receiver(ctx, &acc)
+	consumer.Inject(confluentMsg("server01", 0.5))
+	consumer.Inject(confluentMsg("server02", 1.5))
+	acc.Wait(2)
+
+	acc.AssertContainsTaggedFields(t, "load",
+		map[string]interface{}{"value": 0.5},
+		map[string]string{"host": "server01"})
+	acc.AssertContainsTaggedFields(t, "load",
+		map[string]interface{}{"value": 1.5},
+		map[string]string{"host": "server02"})
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+// Test that messages are decoded once the Schema Registry is available again
+func TestRunParserSchemaRegistryUnavailable(t *testing.T) {
+	ts, _ := newTestRegistry(t, 1)
+	defer ts.Close()
+
+	k, consumer := newSchemaRegistryKafka(t, ts.URL)
+	acc := testutil.Accumulator{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go k.receiver(ctx, &acc)
+	consumer.Inject(confluentMsg("server01", 0.5))
+	acc.WaitError(1)
+	consumer.Inject(confluentMsg("server02", 1.5))
+	acc.Wait(1)
+
+	acc.AssertContainsTaggedFields(t, "load",
+		map[string]interface{}{"value": 1.5},
+		map[string]string{"host": "server02"})
+}
+
+func TestRunParserSchemaRegistryInvalidMsg(t *testing.T) {
+	ts, requests := newTestRegistry(t, 0)
+	defer ts.Close()
+
+	k, consumer := newSchemaRegistryKafka(t, ts.URL)
+	acc := testutil.Accumulator{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go k.receiver(ctx, &acc)
+	consumer.Inject(saramaMsg(testMsgJSON))
+	acc.WaitError(1)
+
+	assert.Equal(t, acc.NFields(), 0)
+	assert.Equal(t, int32(0), atomic.LoadInt32(requests))
+}
+
 func saramaMsg(val string) *sarama.ConsumerMessage {
 	return &sarama.ConsumerMessage{
 		Key:       nil,
@@ -173,3 +287,72 @@ func saramaMsg(val string) *sarama.ConsumerMessage {
 		Partition: 0,
 	}
 }
+
+func saramaMsgWithHeaders(val string, headers ...string) *sarama.ConsumerMessage {
+	msg := saramaMsg(val)
+	for i := 0; i+1 < len(headers); i += 2 {
+		msg.Headers = append(msg.Headers, &sarama.RecordHeader{
+			Key:   []byte(headers[i]),
+			Value: []byte(headers[i+1]),
+		})
+	}
+	return msg
+}
+
+// Test that the selected record headers are added as tags
+func TestHeaderAsTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		duplicates string
+		expected   map[string]string
+	}{
+		{
+			name: "keep first",
+			expected: map[string]string{
+				"host":   "server01",
+				"tenant": "acme",
+				"source": "edge",
+			},
+		},
+		{
+			name:       "join",
+			duplicates: "join",
+			expected: map[string]string{
+				"host":   "server01",
+				"tenant": "acme,globex",
+				"source": "edge",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, consumer := newTestKafka()
+			k.HeaderAsTags = []string{"tenant", "source", "binary"}
+			k.HeaderDuplicates = tt.duplicates
+			acc := testutil.Accumulator{}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			k.parser, _ = parsers.NewInfluxParser()
+			go k.receiver(ctx, &acc)
+			consumer.Inject(saramaMsgWithHeaders(testMsg,
+				"tenant", "acme",
+				"ignored", "value",
+				"source", "edge",
+				"tenant", "globex",
+				"binary", "\xff\xfe",
+			))
+			acc.Wait(1)
+
+			acc.AssertContainsTaggedFields(t, "cpu_load_short",
+				map[string]interface{}{"value": 23422.0}, tt.expected)
+		})
+	}
+}
+
+func TestHeaderDuplicatesInvalid(t *testing.T) {
+	k, _ := newTestKafka()
+	k.HeaderDuplicates = "last"
+	assert.Error(t, k.Start(&testutil.Accumulator{}))
+}
@@ -0,0 +1,108 @@
+package kafka_consumer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schemaRegistryTimeout is the timeout of the requests to the Schema
+// Registry.
+const schemaRegistryTimeout = 5 * time.Second
+
+// schemaRegistry decodes messages in the Confluent wire format, a zero magic
+// byte and the 4 bytes big endian ID of the schema in the Schema Registry
+// followed by the Avro encoded value.
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	schemas map[uint32]*avroSchema
+}
+
+func newSchemaRegistry(url string) *schemaRegistry {
+	return &schemaRegistry{
+		url:     strings.TrimSuffix(url, "/"),
+		client:  &http.Client{Timeout: schemaRegistryTimeout},
+		schemas: make(map[uint32]*avroSchema),
+	}
+}
+
+// decode returns the JSON encoding of the value of the message.
+func (r *schemaRegistry) decode(msg []byte) ([]byte, error) {
+	if len(msg) < 5 || msg[0] != 0 {
+		return nil, fmt.Errorf("message is not in the Confluent wire format")
+	}
+	id := binary.BigEndian.Uint32(msg[1:5])
+
+	schema, err := r.schema(id)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decodeAvro(schema, msg[5:])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode message with schema %d: %v", id, err)
+	}
+	return json.Marshal(value)
+}
+
+// schema returns the schema with the ID, fetched from the Schema Registry
+// the first time.  Failures are not cached, the schema is fetched again for
+// the next message.  The lock is not held during the request, so a slow
+// registry does not block messages with schemas that are already known.
+func (r *schemaRegistry) schema(id uint32) (*avroSchema, error) {
+	r.mu.Lock()
+	schema, ok := r.schemas[id]
+	r.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := r.fetch(id)
+	if err != nil {
+		return nil, fmt.Errorf("could not get schema %d from %s: %v", id, r.url, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// keep the schema of a concurrent fetch for the same ID, if any
+	if cached, ok := r.schemas[id]; ok {
+		return cached, nil
+	}
+	r.schemas[id] = schema
+	return schema, nil
+}
+
+func (r *schemaRegistry) fetch(id uint32) (*avroSchema, error) {
+	resp, err := r.client.Get(fmt.Sprintf("%s/schemas/ids/%d", r.url, id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d", resp.StatusCode)
+	}
+
+	var s struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, err
+	}
+	if s.SchemaType != "" && s.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("unsupported schema type %s", s.SchemaType)
+	}
+	return parseAvroSchema([]byte(s.Schema))
+}
@@ -0,0 +1,69 @@
+package kube_state
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/user"
+	"path/filepath"
+
+	"github.com/ericchiang/k8s"
+	"gopkg.in/yaml.v2"
+)
+
+// client is the subset of the Kubernetes client used to list and watch the
+// objects.
+type client interface {
+	List(ctx context.Context, namespace string, resp k8s.ResourceList, options ...k8s.Option) error
+	Watch(ctx context.Context, namespace string, r k8s.Resource, options ...k8s.Option) (watcher, error)
+}
+
+type watcher interface {
+	Next(r k8s.Resource) (string, error)
+	Close() error
+}
+
+type k8sClient struct {
+	*k8s.Client
+}
+
+func (c *k8sClient) Watch(ctx context.Context, namespace string, r k8s.Resource, options ...k8s.Option) (watcher, error) {
+	return c.Client.Watch(ctx, namespace, r, options...)
+}
+
+// newClient returns a client with the in-cluster configuration, or with the
+// kubeconfig file.
+func newClient(kubeconfig string) (client, error) {
+	if kubeconfig == "" {
+		c, err := k8s.NewInClusterClient()
+		if err == nil {
+			return &k8sClient{c}, nil
+		}
+		u, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("could not get current user: %v", err)
+		}
+		kubeconfig = filepath.Join(u.HomeDir, ".kube/config")
+	}
+
+	data, err := ioutil.ReadFile(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kubeconfig: %v", err)
+	}
+	var config k8s.Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("could not parse kubeconfig %s: %v", kubeconfig, err)
+	}
+	c, err := k8s.NewClient(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &k8sClient{c}, nil
+}
+
+// isForbidden reports if the error is the refusal of the API to give access
+// to the objects.
+func isForbidden(err error) bool {
+	apiErr, ok := err.(*k8s.APIError)
+	return ok && apiErr.Code == 403
+}
@@ -0,0 +1,373 @@
+package kube_state
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ericchiang/k8s"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// retryInterval is the time waited before listing the objects again after an
+// error in watch mode.
+const retryInterval = 5 * time.Second
+
+var sampleConfig = `
+  ## Path of the kubeconfig file.  By default the in-cluster configuration
+  ## is used when running in a pod, or ~/.kube/config otherwise.
+  # kubeconfig = ""
+
+  ## Namespaces of the objects counted, by default all namespaces.  Namespaces
+  ## Telegraf is not allowed to list the objects of are skipped.
+  # namespaces = ["default"]
+
+  ## Resources counted, among pods, deployments, nodes and jobs.
+  # resources = ["pods", "deployments", "nodes", "jobs"]
+
+  ## "poll" lists the objects on every interval, "watch" keeps them up to
+  ## date with the changes sent by the API and lists them again every
+  ## resync_interval.
+  # mode = "poll"
+  # resync_interval = "10m"
+
+  ## Timeout of the API requests listing the objects.
+  # timeout = "10s"
+`
+
+type KubeState struct {
+	KubeConfig     string            `toml:"kubeconfig"`
+	Namespaces     []string          `toml:"namespaces"`
+	Resources      []string          `toml:"resources"`
+	Mode           string            `toml:"mode"`
+	ResyncInterval internal.Duration `toml:"resync_interval"`
+	Timeout        internal.Duration `toml:"timeout"`
+
+	client client
+	stores []*store
+
+	mu sync.Mutex
+	// forbidden are the stores the API refused to list, warned about once
+	forbidden map[*store]bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// store holds the state of the objects of a resource in a namespace, all
+// namespaces if empty.
+type store struct {
+	resource  *resource
+	namespace string
+
+	mu sync.Mutex
+	// synced is set once the objects were listed
+	synced  bool
+	objects map[string]*state
+}
+
+func (s *store) replace(objects map[string]*state) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = objects
+	s.synced = true
+}
+
+func (s *store) update(eventType string, r k8s.Resource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch eventType {
+	case k8s.EventAdded, k8s.EventModified:
+		s.objects[objectKey(r)] = s.resource.state(r)
+	case k8s.EventDeleted:
+		delete(s.objects, objectKey(r))
+	}
+}
+
+func (s *store) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = nil
+	s.synced = false
+}
+
+func (s *store) states() ([]*state, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make([]*state, 0, len(s.objects))
+	for _, st := range s.objects {
+		states = append(states, st)
+	}
+	return states, s.synced
+}
+
+func (k *KubeState) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *KubeState) Description() string {
+	return "Count the pods, deployments, nodes and jobs of a Kubernetes cluster"
+}
+
+func (k *KubeState) Init() error {
+	switch k.Mode {
+	case "", "poll":
+		k.Mode = "poll"
+	case "watch":
+		if k.ResyncInterval.Duration <= 0 {
+			return fmt.Errorf("resync_interval must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid mode %q, must be poll or watch", k.Mode)
+	}
+
+	namespaces := k.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	k.stores = nil
+	for _, name := range k.Resources {
+		r, ok := resources[name]
+		if !ok {
+			return fmt.Errorf("invalid resource %q, must be pods, deployments, nodes or jobs", name)
+		}
+		if !r.namespaced {
+			k.stores = append(k.stores, &store{resource: r})
+			continue
+		}
+		for _, namespace := range namespaces {
+			k.stores = append(k.stores, &store{resource: r, namespace: namespace})
+		}
+	}
+	k.forbidden = make(map[*store]bool)
+	return nil
+}
+
+func (k *KubeState) Start(acc telegraf.Accumulator) error {
+	if k.client == nil {
+		c, err := newClient(k.KubeConfig)
+		if err != nil {
+			return err
+		}
+		k.client = c
+	}
+	if k.Mode != "watch" {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	k.cancel = cancel
+	for _, s := range k.stores {
+		k.wg.Add(1)
+		go func(s *store) {
+			defer k.wg.Done()
+			k.watch(ctx, s)
+		}(s)
+	}
+	return nil
+}
+
+func (k *KubeState) Stop() {
+	if k.cancel != nil {
+		k.cancel()
+	}
+	k.wg.Wait()
+}
+
+func (k *KubeState) Gather(acc telegraf.Accumulator) error {
+	if k.Mode == "poll" {
+		var wg sync.WaitGroup
+		for _, s := range k.stores {
+			wg.Add(1)
+			go func(s *store) {
+				defer wg.Done()
+				if _, err := k.list(context.Background(), s); err != nil && !k.checkForbidden(s, err) {
+					acc.AddError(err)
+				}
+			}(s)
+		}
+		wg.Wait()
+	}
+
+	byResource := make(map[*resource][]*state)
+	for _, s := range k.stores {
+		states, synced := s.states()
+		if synced {
+			byResource[s.resource] = append(byResource[s.resource], states...)
+		}
+	}
+	for r, states := range byResource {
+		addCounts(acc, r, states)
+	}
+	return nil
+}
+
+// list replaces the objects of the store by the listed ones, and returns the
+// resource version of the list.
+func (k *KubeState) list(ctx context.Context, s *store) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, k.Timeout.Duration)
+	defer cancel()
+
+	l := s.resource.newList()
+	if err := k.client.List(ctx, s.namespace, l); err != nil {
+		s.reset()
+		if isForbidden(err) {
+			// checked by the caller
+			return "", err
+		}
+		return "", fmt.Errorf("could not list %s%s: %v", s.resource.name, inNamespace(s), err)
+	}
+	objects := make(map[string]*state)
+	for _, item := range s.resource.items(l) {
+		objects[objectKey(item)] = s.resource.state(item)
+	}
+	s.replace(objects)
+	k.setAllowed(s)
+	return l.GetMetadata().GetResourceVersion(), nil
+}
+
+// watch keeps the objects of the store up to date until the context is done.
+func (k *KubeState) watch(ctx context.Context, s *store) {
+	for {
+		err := k.sync(ctx, s)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var retry time.Duration
+		if err != nil {
+			retry = retryInterval
+			if k.checkForbidden(s, err) {
+				retry = k.ResyncInterval.Duration
+			} else {
+				log.Printf("E! [inputs.kube_state] %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// sync lists the objects of the store and applies their changes until the
+// resync interval elapsed.
+func (k *KubeState) sync(ctx context.Context, s *store) error {
+	version, err := k.list(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, k.ResyncInterval.Duration)
+	defer cancel()
+	w, err := k.client.Watch(watchCtx, s.namespace, s.resource.newObject(),
+		k8s.ResourceVersion(version))
+	if err != nil {
+		return fmt.Errorf("could not watch %s%s: %v", s.resource.name, inNamespace(s), err)
+	}
+	defer w.Close()
+
+	for {
+		r := s.resource.newObject()
+		eventType, err := w.Next(r)
+		if err != nil {
+			if watchCtx.Err() != nil {
+				// time to resync
+				return nil
+			}
+			return fmt.Errorf("could not watch %s%s: %v", s.resource.name, inNamespace(s), err)
+		}
+		s.update(eventType, r)
+	}
+}
+
+// checkForbidden reports if the error is a refusal of the API to list the
+// objects of the store, and warns about it once.
+func (k *KubeState) checkForbidden(s *store, err error) bool {
+	if !isForbidden(err) {
+		return false
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.forbidden[s] {
+		log.Printf("W! [inputs.kube_state] Not allowed to list %s%s, skipping them: %v",
+			s.resource.name, inNamespace(s), err)
+		k.forbidden[s] = true
+	}
+	return true
+}
+
+func (k *KubeState) setAllowed(s *store) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.forbidden, s)
+}
+
+// addCounts adds the objects counted by namespace, and by phase for pods.
+func addCounts(acc telegraf.Accumulator, r *resource, states []*state) {
+	type group struct {
+		namespace, phase string
+	}
+	counts := make(map[group]map[string]interface{})
+	add := func(g group) map[string]interface{} {
+		fields, ok := counts[g]
+		if !ok {
+			fields = map[string]interface{}{"count": int64(0)}
+			counts[g] = fields
+		}
+		return fields
+	}
+
+	for _, st := range states {
+		g := group{namespace: st.namespace, phase: st.phase}
+		if r.name == "pods" {
+			// all the phases of the namespace are reported
+			for _, phase := range podPhases {
+				add(group{namespace: st.namespace, phase: phase})
+			}
+		}
+		fields := add(g)
+		fields["count"] = fields["count"].(int64) + 1
+		for k, v := range st.fields {
+			sum, _ := fields[k].(int64)
+			fields[k] = sum + v
+		}
+	}
+
+	for g, fields := range counts {
+		tags := map[string]string{}
+		if r.namespaced {
+			tags["namespace"] = g.namespace
+		}
+		if g.phase != "" {
+			tags["phase"] = g.phase
+		}
+		acc.AddGauge("kube_state_"+r.name, fields, tags)
+	}
+}
+
+func inNamespace(s *store) string {
+	if !s.resource.namespaced {
+		return ""
+	}
+	if s.namespace == "" {
+		return " in all namespaces"
+	}
+	return " in namespace " + s.namespace
+}
+
+func init() {
+	inputs.Add("kube_state", func() telegraf.Input {
+		return &KubeState{
+			Resources:      []string{"pods", "deployments", "nodes", "jobs"},
+			ResyncInterval: internal.Duration{Duration: 10 * time.Minute},
+			Timeout:        internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
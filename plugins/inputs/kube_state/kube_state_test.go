@@ -0,0 +1,359 @@
+package kube_state
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericchiang/k8s"
+	appsv1 "github.com/ericchiang/k8s/apis/apps/v1"
+	batchv1 "github.com/ericchiang/k8s/apis/batch/v1"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type event struct {
+	eventType string
+	object    k8s.Resource
+}
+
+// fakeClient lists objects from memory, and sends the events written to the
+// channel of the watched resource.
+type fakeClient struct {
+	mu        sync.Mutex
+	objects   []k8s.Resource
+	forbidden map[string]bool
+	lists     int
+	events    map[string]chan event
+}
+
+func newFakeClient(objects ...k8s.Resource) *fakeClient {
+	return &fakeClient{
+		objects:   objects,
+		forbidden: map[string]bool{},
+		events:    map[string]chan event{},
+	}
+}
+
+func (c *fakeClient) List(ctx context.Context, namespace string, resp k8s.ResourceList, options ...k8s.Option) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lists++
+	if c.forbidden[namespace] {
+		return &k8s.APIError{Code: 403}
+	}
+
+	list := reflect.ValueOf(resp).Elem()
+	items := list.FieldByName("Items")
+	for _, obj := range c.objects {
+		if namespace != "" && obj.GetMetadata().GetNamespace() != namespace {
+			continue
+		}
+		if reflect.TypeOf(obj) == items.Type().Elem() {
+			items.Set(reflect.Append(items, reflect.ValueOf(obj)))
+		}
+	}
+	list.FieldByName("Metadata").Set(reflect.ValueOf(&metav1.ListMeta{ResourceVersion: k8s.String("1")}))
+	return nil
+}
+
+func (c *fakeClient) Watch(ctx context.Context, namespace string, r k8s.Resource, options ...k8s.Option) (watcher, error) {
+	return &fakeWatcher{ctx: ctx, events: c.watch(reflect.TypeOf(r).Elem().Name())}, nil
+}
+
+func (c *fakeClient) watch(kind string) chan event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	events, ok := c.events[kind]
+	if !ok {
+		events = make(chan event)
+		c.events[kind] = events
+	}
+	return events
+}
+
+func (c *fakeClient) listCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lists
+}
+
+type fakeWatcher struct {
+	ctx    context.Context
+	events chan event
+}
+
+func (w *fakeWatcher) Next(r k8s.Resource) (string, error) {
+	select {
+	case <-w.ctx.Done():
+		return "", w.ctx.Err()
+	case e := <-w.events:
+		reflect.ValueOf(r).Elem().Set(reflect.ValueOf(e.object).Elem())
+		return e.eventType, nil
+	}
+}
+
+func (w *fakeWatcher) Close() error {
+	return nil
+}
+
+func meta(namespace, name string) *metav1.ObjectMeta {
+	return &metav1.ObjectMeta{Namespace: k8s.String(namespace), Name: k8s.String(name)}
+}
+
+func pod(namespace, name, phase string) *corev1.Pod {
+	return &corev1.Pod{
+		Metadata: meta(namespace, name),
+		Status:   &corev1.PodStatus{Phase: k8s.String(phase)},
+	}
+}
+
+func deployment(namespace, name string, desired, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		Metadata: meta(namespace, name),
+		Spec:     &appsv1.DeploymentSpec{Replicas: &desired},
+		Status: &appsv1.DeploymentStatus{
+			AvailableReplicas:   &available,
+			UnavailableReplicas: k8s.Int32(desired - available),
+			UpdatedReplicas:     &desired,
+		},
+	}
+}
+
+func node(name string, ready bool) *corev1.Node {
+	status := "False"
+	if ready {
+		status = "True"
+	}
+	return &corev1.Node{
+		Metadata: meta("", name),
+		Status: &corev1.NodeStatus{
+			Conditions: []*corev1.NodeCondition{
+				{Type: k8s.String("Ready"), Status: k8s.String(status)},
+			},
+		},
+	}
+}
+
+func job(namespace, name, condition string) *batchv1.Job {
+	j := &batchv1.Job{Metadata: meta(namespace, name), Status: &batchv1.JobStatus{}}
+	if condition != "" {
+		j.Status.Conditions = []*batchv1.JobCondition{
+			{Type: k8s.String(condition), Status: k8s.String("True")},
+		}
+	}
+	return j
+}
+
+func newKubeState(c client) *KubeState {
+	return &KubeState{
+		Resources:      []string{"pods", "deployments", "nodes", "jobs"},
+		ResyncInterval: internal.Duration{Duration: time.Minute},
+		Timeout:        internal.Duration{Duration: time.Second},
+		client:         c,
+	}
+}
+
+func podCounts(acc *testutil.Accumulator) map[string]int64 {
+	acc.Lock()
+	defer acc.Unlock()
+	counts := make(map[string]int64)
+	for _, m := range acc.Metrics {
+		if m.Measurement == "kube_state_pods" {
+			counts[m.Tags["namespace"]+"/"+m.Tags["phase"]] = m.Fields["count"].(int64)
+		}
+	}
+	return counts
+}
+
+func TestPoll(t *testing.T) {
+	c := newFakeClient(
+		pod("default", "a", "Running"),
+		pod("default", "b", "Running"),
+		pod("default", "c", "Pending"),
+		pod("kube-system", "d", "Failed"),
+		pod("kube-system", "e", ""),
+		deployment("default", "web", 3, 2),
+		deployment("default", "api", 2, 2),
+		node("node-1", true),
+		node("node-2", false),
+		job("default", "migrate", "Complete"),
+		job("default", "backup", ""),
+		job("kube-system", "cleanup", "Failed"),
+	)
+	k := newKubeState(c)
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, k.Start(&acc))
+	defer k.Stop()
+
+	require.NoError(t, acc.GatherError(k.Gather))
+	require.Equal(t, map[string]int64{
+		"default/Pending":       1,
+		"default/Running":       2,
+		"default/Succeeded":     0,
+		"default/Failed":        0,
+		"default/Unknown":       0,
+		"kube-system/Pending":   0,
+		"kube-system/Running":   0,
+		"kube-system/Succeeded": 0,
+		"kube-system/Failed":    1,
+		"kube-system/Unknown":   1,
+	}, podCounts(&acc))
+
+	acc.AssertContainsTaggedFields(t, "kube_state_deployments",
+		map[string]interface{}{
+			"count":                int64(2),
+			"replicas_desired":     int64(5),
+			"replicas_available":   int64(4),
+			"replicas_unavailable": int64(1),
+			"replicas_updated":     int64(5),
+		},
+		map[string]string{"namespace": "default"})
+	acc.AssertContainsTaggedFields(t, "kube_state_nodes",
+		map[string]interface{}{
+			"count":         int64(2),
+			"ready":         int64(1),
+			"not_ready":     int64(1),
+			"unschedulable": int64(0),
+		},
+		map[string]string{})
+	acc.AssertContainsTaggedFields(t, "kube_state_jobs",
+		map[string]interface{}{
+			"count":     int64(2),
+			"active":    int64(1),
+			"succeeded": int64(1),
+			"failed":    int64(0),
+		},
+		map[string]string{"namespace": "default"})
+	acc.AssertContainsTaggedFields(t, "kube_state_jobs",
+		map[string]interface{}{
+			"count":     int64(1),
+			"active":    int64(0),
+			"succeeded": int64(0),
+			"failed":    int64(1),
+		},
+		map[string]string{"namespace": "kube-system"})
+}
+
+func TestPollForbiddenNamespace(t *testing.T) {
+	c := newFakeClient(
+		pod("default", "a", "Running"),
+		pod("secret", "b", "Running"),
+	)
+	c.forbidden["secret"] = true
+	k := newKubeState(c)
+	k.Resources = []string{"pods"}
+	k.Namespaces = []string{"default", "secret"}
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, k.Start(&acc))
+	defer k.Stop()
+
+	// the forbidden namespace is skipped without error
+	require.NoError(t, acc.GatherError(k.Gather))
+	require.Empty(t, acc.Errors)
+	counts := podCounts(&acc)
+	require.Equal(t, int64(1), counts["default/Running"])
+	require.NotContains(t, counts, "secret/Running")
+	require.True(t, k.forbidden[k.stores[1]])
+
+	// and counted once allowed
+	c.mu.Lock()
+	c.forbidden["secret"] = false
+	c.mu.Unlock()
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(k.Gather))
+	require.Equal(t, int64(1), podCounts(&acc)["secret/Running"])
+	require.Empty(t, k.forbidden)
+}
+
+func TestWatch(t *testing.T) {
+	c := newFakeClient(
+		pod("default", "a", "Pending"),
+		pod("default", "b", "Running"),
+	)
+	k := newKubeState(c)
+	k.Resources = []string{"pods"}
+	k.Mode = "watch"
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, k.Start(&acc))
+	defer k.Stop()
+
+	events := c.watch("Pod")
+	events <- event{k8s.EventModified, pod("default", "a", "Running")}
+	events <- event{k8s.EventAdded, pod("default", "c", "Failed")}
+	events <- event{k8s.EventDeleted, pod("default", "b", "Running")}
+	// the events are applied once the next one is received
+	events <- event{k8s.EventAdded, pod("other", "d", "Running")}
+
+	require.NoError(t, acc.GatherError(k.Gather))
+	counts := podCounts(&acc)
+	require.Equal(t, int64(0), counts["default/Pending"])
+	require.Equal(t, int64(1), counts["default/Running"])
+	require.Equal(t, int64(1), counts["default/Failed"])
+	require.Equal(t, 1, c.listCount())
+}
+
+func TestWatchResync(t *testing.T) {
+	c := newFakeClient(pod("default", "a", "Running"))
+	k := newKubeState(c)
+	k.Resources = []string{"pods"}
+	k.Mode = "watch"
+	k.ResyncInterval.Duration = 10 * time.Millisecond
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, k.Start(&acc))
+	defer k.Stop()
+
+	c.mu.Lock()
+	c.objects = append(c.objects, pod("default", "b", "Running"))
+	c.mu.Unlock()
+
+	// the objects are listed again after the resync interval
+	for c.listCount() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, acc.GatherError(k.Gather))
+	require.Equal(t, int64(2), podCounts(&acc)["default/Running"])
+}
+
+func TestWatchForbidden(t *testing.T) {
+	c := newFakeClient(pod("secret", "a", "Running"))
+	c.forbidden["secret"] = true
+	k := newKubeState(c)
+	k.Resources = []string{"pods"}
+	k.Namespaces = []string{"secret"}
+	k.Mode = "watch"
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, k.Start(&acc))
+
+	for c.listCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, acc.GatherError(k.Gather))
+	require.Empty(t, acc.Metrics)
+
+	// the list is retried after the resync interval
+	k.Stop()
+	require.Equal(t, 1, c.listCount())
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, k := range []*KubeState{
+		{Mode: "stream"},
+		{Mode: "watch"},
+		{Resources: []string{"services"}},
+	} {
+		require.Error(t, k.Init(), fmt.Sprintf("%+v", k))
+	}
+}
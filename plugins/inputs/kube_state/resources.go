@@ -0,0 +1,158 @@
+package kube_state
+
+import (
+	"github.com/ericchiang/k8s"
+	appsv1 "github.com/ericchiang/k8s/apis/apps/v1"
+	batchv1 "github.com/ericchiang/k8s/apis/batch/v1"
+	corev1 "github.com/ericchiang/k8s/apis/core/v1"
+)
+
+var podPhases = []string{"Pending", "Running", "Succeeded", "Failed", "Unknown"}
+
+// resource describes how to list the objects of a resource and count them.
+type resource struct {
+	name       string
+	namespaced bool
+	newObject  func() k8s.Resource
+	newList    func() k8s.ResourceList
+	items      func(k8s.ResourceList) []k8s.Resource
+	// state returns what is counted of an object
+	state func(k8s.Resource) *state
+}
+
+// state is what is counted of an object, it is kept rather than the object
+// to bound the memory.
+type state struct {
+	namespace string
+	// phase of a pod, empty for the other resources
+	phase  string
+	fields map[string]int64
+}
+
+var resources = map[string]*resource{
+	"pods": {
+		name:       "pods",
+		namespaced: true,
+		newObject:  func() k8s.Resource { return &corev1.Pod{} },
+		newList:    func() k8s.ResourceList { return &corev1.PodList{} },
+		items: func(l k8s.ResourceList) []k8s.Resource {
+			var items []k8s.Resource
+			for _, item := range l.(*corev1.PodList).GetItems() {
+				items = append(items, item)
+			}
+			return items
+		},
+		state: func(r k8s.Resource) *state {
+			pod := r.(*corev1.Pod)
+			phase := pod.GetStatus().GetPhase()
+			if phase == "" {
+				phase = "Unknown"
+			}
+			return &state{
+				namespace: pod.GetMetadata().GetNamespace(),
+				phase:     phase,
+			}
+		},
+	},
+	"deployments": {
+		name:       "deployments",
+		namespaced: true,
+		newObject:  func() k8s.Resource { return &appsv1.Deployment{} },
+		newList:    func() k8s.ResourceList { return &appsv1.DeploymentList{} },
+		items: func(l k8s.ResourceList) []k8s.Resource {
+			var items []k8s.Resource
+			for _, item := range l.(*appsv1.DeploymentList).GetItems() {
+				items = append(items, item)
+			}
+			return items
+		},
+		state: func(r k8s.Resource) *state {
+			d := r.(*appsv1.Deployment)
+			status := d.GetStatus()
+			return &state{
+				namespace: d.GetMetadata().GetNamespace(),
+				fields: map[string]int64{
+					"replicas_desired":     int64(d.GetSpec().GetReplicas()),
+					"replicas_available":   int64(status.GetAvailableReplicas()),
+					"replicas_unavailable": int64(status.GetUnavailableReplicas()),
+					"replicas_updated":     int64(status.GetUpdatedReplicas()),
+				},
+			}
+		},
+	},
+	"nodes": {
+		name:      "nodes",
+		newObject: func() k8s.Resource { return &corev1.Node{} },
+		newList:   func() k8s.ResourceList { return &corev1.NodeList{} },
+		items: func(l k8s.ResourceList) []k8s.Resource {
+			var items []k8s.Resource
+			for _, item := range l.(*corev1.NodeList).GetItems() {
+				items = append(items, item)
+			}
+			return items
+		},
+		state: func(r k8s.Resource) *state {
+			node := r.(*corev1.Node)
+			var ready int64
+			for _, c := range node.GetStatus().GetConditions() {
+				if c.GetType() == "Ready" && c.GetStatus() == "True" {
+					ready = 1
+				}
+			}
+			var unschedulable int64
+			if node.GetSpec().GetUnschedulable() {
+				unschedulable = 1
+			}
+			return &state{
+				fields: map[string]int64{
+					"ready":         ready,
+					"not_ready":     1 - ready,
+					"unschedulable": unschedulable,
+				},
+			}
+		},
+	},
+	"jobs": {
+		name:       "jobs",
+		namespaced: true,
+		newObject:  func() k8s.Resource { return &batchv1.Job{} },
+		newList:    func() k8s.ResourceList { return &batchv1.JobList{} },
+		items: func(l k8s.ResourceList) []k8s.Resource {
+			var items []k8s.Resource
+			for _, item := range l.(*batchv1.JobList).GetItems() {
+				items = append(items, item)
+			}
+			return items
+		},
+		state: func(r k8s.Resource) *state {
+			job := r.(*batchv1.Job)
+			fields := map[string]int64{"active": 0, "succeeded": 0, "failed": 0}
+			switch {
+			case jobCondition(job, "Complete"):
+				fields["succeeded"] = 1
+			case jobCondition(job, "Failed"):
+				fields["failed"] = 1
+			default:
+				fields["active"] = 1
+			}
+			return &state{
+				namespace: job.GetMetadata().GetNamespace(),
+				fields:    fields,
+			}
+		},
+	},
+}
+
+func jobCondition(job *batchv1.Job, conditionType string) bool {
+	for _, c := range job.GetStatus().GetConditions() {
+		if c.GetType() == conditionType && c.GetStatus() == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+// objectKey identifies an object of a resource.
+func objectKey(r k8s.Resource) string {
+	return r.GetMetadata().GetNamespace() + "/" + r.GetMetadata().GetName()
+}
@@ -0,0 +1,441 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Maximum number of registers and of coils read by a request.
+const (
+	maxRegisters = 125
+	maxBits      = 2000
+)
+
+var sampleConfig = `
+  ## Name of the device, added as the name tag.
+  # name = "device"
+
+  ## Address of the device, "tcp://host:502" for a device on the network,
+  ## or "file:///dev/ttyUSB0" for a device on a serial line.
+  controller = "tcp://localhost:502"
+
+  ## Framing of the requests, "TCP" or "RTU".  By default TCP for the
+  ## network devices and RTU for the serial devices.
+  # transmission_mode = "TCP"
+
+  ## Settings of the serial line.
+  # baud_rate = 9600
+  # data_bits = 8
+  # parity = "N"
+  # stop_bits = 1
+
+  ## Slave id of the fields without one.
+  slave_id = 1
+
+  ## Timeout of a request.
+  # timeout = "1s"
+
+  ## Number of times a request is retried after a connection error or when
+  ## the slave is busy.  The connection is opened again before retrying
+  ## after an error.
+  # retries = 1
+
+  ## Fields read from the device, and added to a modbus metric per slave.
+  ##   register:   "coil", "discrete_input", "holding" or "input"
+  ##   address:    address of the coil or of the first register
+  ##   type:       "bool" for the coils and discrete inputs, "int16",
+  ##               "uint16", "int32", "uint32" or "float32" for registers
+  ##   byte_order: order in which the bytes of the value are received, A
+  ##               being the most significant.  "AB" or "BA" for 16 bits
+  ##               values, "ABCD", "DCBA", "BADC" or "CDAB" for 32 bits.
+  ##   scale:      factor applied to the value, giving a float field
+  [[inputs.modbus.field]]
+    name = "voltage"
+    register = "holding"
+    address = 0
+    type = "uint16"
+    scale = 0.1
+
+  [[inputs.modbus.field]]
+    name = "energy"
+    register = "input"
+    address = 10
+    type = "float32"
+    byte_order = "CDAB"
+`
+
+type Modbus struct {
+	Name             string            `toml:"name"`
+	Controller       string            `toml:"controller"`
+	TransmissionMode string            `toml:"transmission_mode"`
+	SlaveID          int               `toml:"slave_id"`
+	Timeout          internal.Duration `toml:"timeout"`
+	Retries          int               `toml:"retries"`
+	BaudRate         int               `toml:"baud_rate"`
+	DataBits         int               `toml:"data_bits"`
+	Parity           string            `toml:"parity"`
+	StopBits         int               `toml:"stop_bits"`
+	Fields           []Field           `toml:"field"`
+
+	requests []*request
+	framer   framer
+	conn     io.ReadWriteCloser
+}
+
+// serialSettings are the settings of the serial line.
+type serialSettings struct {
+	BaudRate int
+	DataBits int
+	Parity   string
+	StopBits int
+}
+
+// Field is a value read from the device.
+type Field struct {
+	Name      string  `toml:"name"`
+	SlaveID   int     `toml:"slave_id"`
+	Register  string  `toml:"register"`
+	Address   int     `toml:"address"`
+	Type      string  `toml:"type"`
+	ByteOrder string  `toml:"byte_order"`
+	Scale     float64 `toml:"scale"`
+}
+
+// request reads the coils or registers of fields of a slave.
+type request struct {
+	slaveID  byte
+	function byte
+	address  uint16
+	count    uint16
+	fields   []*Field
+}
+
+var functions = map[string]byte{
+	"coil":           readCoils,
+	"discrete_input": readDiscreteInputs,
+	"holding":        readHoldingRegisters,
+	"input":          readInputRegisters,
+}
+
+// sizes are the numbers of registers of the types.
+var sizes = map[string]int{
+	"int16":   1,
+	"uint16":  1,
+	"int32":   2,
+	"uint32":  2,
+	"float32": 2,
+}
+
+func (m *Modbus) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Modbus) Description() string {
+	return "Read coils and registers of Modbus devices"
+}
+
+func (m *Modbus) Init() error {
+	u, err := url.Parse(m.Controller)
+	if err != nil {
+		return fmt.Errorf("invalid controller: %v", err)
+	}
+	mode := m.TransmissionMode
+	switch u.Scheme {
+	case "tcp":
+		if mode == "" {
+			mode = "TCP"
+		}
+	case "file":
+		if mode == "" {
+			mode = "RTU"
+		}
+		if mode != "RTU" {
+			return fmt.Errorf("serial devices require the RTU transmission mode")
+		}
+	default:
+		return fmt.Errorf("invalid controller %q, must be tcp:// or file://", m.Controller)
+	}
+	switch mode {
+	case "TCP":
+		m.framer = &tcpFramer{}
+	case "RTU":
+		m.framer = &rtuFramer{}
+	default:
+		return fmt.Errorf("invalid transmission_mode %q, must be TCP or RTU", mode)
+	}
+
+	if len(m.Fields) == 0 {
+		return fmt.Errorf("no field to read")
+	}
+	names := make(map[string]bool)
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		if err := m.initField(f); err != nil {
+			return fmt.Errorf("field %q: %v", f.Name, err)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("field %q is defined twice", f.Name)
+		}
+		names[f.Name] = true
+	}
+	m.requests = groupRequests(m.Fields)
+	return nil
+}
+
+func (m *Modbus) initField(f *Field) error {
+	if f.Name == "" {
+		return fmt.Errorf("name must be set")
+	}
+	if f.SlaveID == 0 {
+		f.SlaveID = m.SlaveID
+	}
+	if f.SlaveID < 1 || f.SlaveID > 247 {
+		return fmt.Errorf("invalid slave_id %d", f.SlaveID)
+	}
+	function, ok := functions[f.Register]
+	if !ok {
+		return fmt.Errorf("invalid register %q", f.Register)
+	}
+
+	bits := function == readCoils || function == readDiscreteInputs
+	if f.Type == "" && bits {
+		f.Type = "bool"
+	}
+	if bits != (f.Type == "bool") {
+		return fmt.Errorf("type %q can not be read from %s registers", f.Type, f.Register)
+	}
+	size := 1
+	if !bits {
+		if size, ok = sizes[f.Type]; !ok {
+			return fmt.Errorf("invalid type %q", f.Type)
+		}
+		if f.ByteOrder == "" {
+			f.ByteOrder = "ABCD"[:2*size]
+		}
+		if !validByteOrder(f.ByteOrder, 2*size) {
+			return fmt.Errorf("invalid byte_order %q for type %s", f.ByteOrder, f.Type)
+		}
+	}
+	if f.Address < 0 || f.Address+size > 65536 {
+		return fmt.Errorf("invalid address %d", f.Address)
+	}
+	return nil
+}
+
+// validByteOrder reports if the order is a permutation of the first size
+// letters.
+func validByteOrder(order string, size int) bool {
+	if len(order) != size {
+		return false
+	}
+	seen := make(map[byte]bool)
+	for i := 0; i < len(order); i++ {
+		c := order[i]
+		if c < 'A' || int(c-'A') >= size || seen[c] {
+			return false
+		}
+		seen[c] = true
+	}
+	return true
+}
+
+// groupRequests returns requests reading the fields, the fields of
+// contiguous or overlapping registers are read by the same request.
+func groupRequests(fields []Field) []*request {
+	sorted := make([]*Field, 0, len(fields))
+	for i := range fields {
+		sorted = append(sorted, &fields[i])
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.SlaveID != b.SlaveID {
+			return a.SlaveID < b.SlaveID
+		}
+		if functions[a.Register] != functions[b.Register] {
+			return functions[a.Register] < functions[b.Register]
+		}
+		return a.Address < b.Address
+	})
+
+	var requests []*request
+	var r *request
+	for _, f := range sorted {
+		function := functions[f.Register]
+		max, size := maxRegisters, sizes[f.Type]
+		if f.Type == "bool" {
+			max, size = maxBits, 1
+		}
+		end := f.Address + size
+		if r != nil && r.slaveID == byte(f.SlaveID) && r.function == function &&
+			f.Address <= int(r.address)+int(r.count) && end-int(r.address) <= max {
+			if n := end - int(r.address); n > int(r.count) {
+				r.count = uint16(n)
+			}
+			r.fields = append(r.fields, f)
+			continue
+		}
+		r = &request{
+			slaveID:  byte(f.SlaveID),
+			function: function,
+			address:  uint16(f.Address),
+			count:    uint16(size),
+			fields:   []*Field{f},
+		}
+		requests = append(requests, r)
+	}
+	return requests
+}
+
+func (m *Modbus) Gather(acc telegraf.Accumulator) error {
+	// a metric per slave, with the fields read successfully
+	fields := make(map[byte]map[string]interface{})
+	var slaves []byte
+	for _, r := range m.requests {
+		if _, ok := fields[r.slaveID]; !ok {
+			fields[r.slaveID] = make(map[string]interface{})
+			slaves = append(slaves, r.slaveID)
+		}
+		data, err := m.read(r)
+		if err != nil {
+			acc.AddError(fmt.Errorf("could not read %d %s registers at %d of slave %d: %v",
+				r.count, r.fields[0].Register, r.address, r.slaveID, err))
+			continue
+		}
+		for _, f := range r.fields {
+			fields[r.slaveID][f.Name] = decode(f, data, int(r.address))
+		}
+	}
+
+	for _, slaveID := range slaves {
+		if len(fields[slaveID]) == 0 {
+			continue
+		}
+		tags := map[string]string{"slave_id": strconv.Itoa(int(slaveID))}
+		if m.Name != "" {
+			tags["name"] = m.Name
+		}
+		acc.AddFields("modbus", fields[slaveID], tags)
+	}
+	return nil
+}
+
+// read returns the data of the coils or registers of the request, it is
+// retried after connection errors and when the slave is busy.
+func (m *Modbus) read(r *request) ([]byte, error) {
+	pdu := readRequest(r.function, r.address, r.count)
+	var err error
+	for attempt := 0; attempt <= m.Retries; attempt++ {
+		if m.conn == nil {
+			if err = m.connect(); err != nil {
+				continue
+			}
+		}
+
+		var resp []byte
+		if d, ok := m.conn.(interface {
+			SetDeadline(time.Time) error
+		}); ok {
+			d.SetDeadline(time.Now().Add(m.Timeout.Duration))
+		}
+		resp, err = m.framer.roundTrip(m.conn, r.slaveID, pdu)
+		if err == nil {
+			var data []byte
+			data, err = readResponse(r.function, r.count, resp)
+			if e, ok := err.(*exception); ok && e.code != exceptionBusy {
+				return nil, err
+			}
+			if err == nil {
+				return data, nil
+			}
+			continue
+		}
+		// the rest of the response may still be received
+		m.disconnect()
+	}
+	return nil, err
+}
+
+func (m *Modbus) connect() error {
+	u, err := url.Parse(m.Controller)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "file" {
+		s := serialSettings{
+			BaudRate: m.BaudRate,
+			DataBits: m.DataBits,
+			Parity:   m.Parity,
+			StopBits: m.StopBits,
+		}
+		m.conn, err = openSerial(u.Path, s, m.Timeout.Duration)
+		return err
+	}
+	m.conn, err = net.DialTimeout("tcp", u.Host, m.Timeout.Duration)
+	return err
+}
+
+func (m *Modbus) disconnect() {
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+}
+
+// decode returns the value of the field in the data read from the address.
+func decode(f *Field, data []byte, address int) interface{} {
+	offset := f.Address - address
+	if f.Type == "bool" {
+		return data[offset/8]&(1<<uint(offset%8)) != 0
+	}
+
+	raw := data[2*offset : 2*offset+2*sizes[f.Type]]
+	b := make([]byte, len(raw))
+	for i := range b {
+		b[f.ByteOrder[i]-'A'] = raw[i]
+	}
+
+	var value float64
+	switch f.Type {
+	case "int16":
+		value = float64(int16(binary.BigEndian.Uint16(b)))
+	case "uint16":
+		value = float64(binary.BigEndian.Uint16(b))
+	case "int32":
+		value = float64(int32(binary.BigEndian.Uint32(b)))
+	case "uint32":
+		value = float64(binary.BigEndian.Uint32(b))
+	case "float32":
+		value = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	}
+	if f.Scale != 0 {
+		return value * f.Scale
+	}
+	if f.Type == "float32" {
+		return value
+	}
+	return int64(value)
+}
+
+func init() {
+	inputs.Add("modbus", func() telegraf.Input {
+		return &Modbus{
+			Timeout:  internal.Duration{Duration: time.Second},
+			Retries:  1,
+			BaudRate: 9600,
+			DataBits: 8,
+			Parity:   "N",
+			StopBits: 1,
+		}
+	})
+}
@@ -0,0 +1,382 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// server is a mock Modbus device answering the read requests of a slave.
+type server struct {
+	t        *testing.T
+	listener net.Listener
+	rtu      bool
+	slaveID  byte
+
+	mu        sync.Mutex
+	registers map[byte][]uint16
+	coils     []bool
+	requests  int
+	// dropNext closes the connection instead of answering the next request
+	dropNext bool
+	// busy is the number of requests answered with the busy exception
+	busy int
+}
+
+func newServer(t *testing.T, rtu bool) *server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &server{
+		t:        t,
+		listener: l,
+		rtu:      rtu,
+		slaveID:  1,
+		registers: map[byte][]uint16{
+			readHoldingRegisters: make([]uint16, 100),
+			readInputRegisters:   make([]uint16, 100),
+		},
+		coils: make([]bool, 100),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *server) url() string {
+	return "tcp://" + s.listener.Addr().String()
+}
+
+func (s *server) close() {
+	s.listener.Close()
+}
+
+func (s *server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *server) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var header []byte
+		var slaveID byte
+		var pdu []byte
+		if s.rtu {
+			frame := make([]byte, 8)
+			if _, err := io.ReadFull(conn, frame); err != nil {
+				return
+			}
+			crc := appendCRC(frame[:6:6])
+			if crc[6] != frame[6] || crc[7] != frame[7] {
+				s.t.Errorf("invalid CRC")
+				return
+			}
+			slaveID, pdu = frame[0], frame[1:6]
+		} else {
+			header = make([]byte, 7)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			pdu = make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+			if _, err := io.ReadFull(conn, pdu); err != nil {
+				return
+			}
+			slaveID = header[6]
+		}
+
+		resp, drop := s.respond(pdu)
+		if drop || slaveID != s.slaveID {
+			return
+		}
+		if s.rtu {
+			frame := append([]byte{slaveID}, resp...)
+			conn.Write(appendCRC(frame))
+		} else {
+			binary.BigEndian.PutUint16(header[4:], uint16(len(resp)+1))
+			conn.Write(append(header, resp...))
+		}
+	}
+}
+
+func (s *server) respond(pdu []byte) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.dropNext {
+		s.dropNext = false
+		return nil, true
+	}
+	function := pdu[0]
+	if s.busy > 0 {
+		s.busy--
+		return []byte{function | 0x80, exceptionBusy}, false
+	}
+
+	address := int(binary.BigEndian.Uint16(pdu[1:]))
+	count := int(binary.BigEndian.Uint16(pdu[3:]))
+	switch function {
+	case readCoils:
+		if address+count > len(s.coils) {
+			return []byte{function | 0x80, 2}, false
+		}
+		data := make([]byte, (count+7)/8)
+		for i := 0; i < count; i++ {
+			if s.coils[address+i] {
+				data[i/8] |= 1 << uint(i%8)
+			}
+		}
+		return append([]byte{function, byte(len(data))}, data...), false
+	case readHoldingRegisters, readInputRegisters:
+		registers := s.registers[function]
+		if address+count > len(registers) {
+			return []byte{function | 0x80, 2}, false
+		}
+		data := make([]byte, 2*count)
+		for i := 0; i < count; i++ {
+			binary.BigEndian.PutUint16(data[2*i:], registers[address+i])
+		}
+		return append([]byte{function, byte(len(data))}, data...), false
+	default:
+		return []byte{function | 0x80, 1}, false
+	}
+}
+
+func (s *server) set(function byte, address int, values ...uint16) {
+	s.update(func() {
+		copy(s.registers[function][address:], values)
+	})
+}
+
+func (s *server) update(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+func (s *server) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func newModbus(url string, fields ...Field) *Modbus {
+	m := &Modbus{
+		Controller: url,
+		SlaveID:    1,
+		Retries:    1,
+		Fields:     fields,
+	}
+	m.Timeout.Duration = time.Second
+	return m
+}
+
+func TestFloat32WordOrders(t *testing.T) {
+	s := newServer(t, false)
+	defer s.close()
+
+	// 123.456 is 0x42F6E979 as a float32
+	bits := math.Float32bits(123.456)
+	a, b := byte(bits>>24), byte(bits>>16)
+	c, d := byte(bits>>8), byte(bits)
+	word := func(hi, lo byte) uint16 { return uint16(hi)<<8 | uint16(lo) }
+	s.set(readHoldingRegisters, 0, word(a, b), word(c, d))
+	s.set(readHoldingRegisters, 2, word(c, d), word(a, b))
+	s.set(readHoldingRegisters, 4, word(b, a), word(d, c))
+	s.set(readHoldingRegisters, 6, word(d, c), word(b, a))
+
+	m := newModbus(s.url(),
+		Field{Name: "abcd", Register: "holding", Address: 0, Type: "float32", ByteOrder: "ABCD"},
+		Field{Name: "cdab", Register: "holding", Address: 2, Type: "float32", ByteOrder: "CDAB"},
+		Field{Name: "badc", Register: "holding", Address: 4, Type: "float32", ByteOrder: "BADC"},
+		Field{Name: "dcba", Register: "holding", Address: 6, Type: "float32", ByteOrder: "DCBA"},
+	)
+	require.NoError(t, m.Init())
+	// the contiguous registers are read at once
+	require.Len(t, m.requests, 1)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(m.Gather))
+	defer m.disconnect()
+	expected := float64(float32(123.456))
+	acc.AssertContainsTaggedFields(t, "modbus",
+		map[string]interface{}{
+			"abcd": expected,
+			"cdab": expected,
+			"badc": expected,
+			"dcba": expected,
+		},
+		map[string]string{"slave_id": "1"})
+}
+
+func TestTypes(t *testing.T) {
+	for _, rtu := range []bool{false, true} {
+		s := newServer(t, rtu)
+		s.set(readHoldingRegisters, 0, 0xfffe, 0x1234, 0xffff, 0xfffd)
+		s.set(readInputRegisters, 10, 0x0001, 0x0002, 2305)
+		s.update(func() {
+			s.coils[3] = true
+			s.coils[12] = true
+		})
+
+		m := newModbus(s.url(),
+			Field{Name: "int16", Register: "holding", Address: 0, Type: "int16"},
+			Field{Name: "uint16", Register: "holding", Address: 0, Type: "uint16"},
+			Field{Name: "swapped", Register: "holding", Address: 1, Type: "uint16", ByteOrder: "BA"},
+			Field{Name: "int32", Register: "holding", Address: 2, Type: "int32"},
+			Field{Name: "uint32", Register: "input", Address: 10, Type: "uint32"},
+			Field{Name: "scaled", Register: "input", Address: 12, Type: "uint16", Scale: 0.1},
+			Field{Name: "coil3", Register: "coil", Address: 3},
+			Field{Name: "coil4", Register: "coil", Address: 4},
+			Field{Name: "coil12", Register: "coil", Address: 12},
+		)
+		if rtu {
+			m.TransmissionMode = "RTU"
+		}
+		require.NoError(t, m.Init())
+		require.Len(t, m.requests, 4)
+
+		var acc testutil.Accumulator
+		require.NoError(t, acc.GatherError(m.Gather))
+		m.disconnect()
+		s.close()
+		acc.AssertContainsTaggedFields(t, "modbus",
+			map[string]interface{}{
+				"int16":   int64(-2),
+				"uint16":  int64(0xfffe),
+				"swapped": int64(0x3412),
+				"int32":   int64(-3),
+				"uint32":  int64(0x00010002),
+				"scaled":  230.5,
+				"coil3":   true,
+				"coil4":   false,
+				"coil12":  true,
+			},
+			map[string]string{"slave_id": "1"})
+	}
+}
+
+func TestPartialRead(t *testing.T) {
+	s := newServer(t, false)
+	defer s.close()
+	s.set(readHoldingRegisters, 0, 42)
+
+	m := newModbus(s.url(),
+		Field{Name: "valid", Register: "holding", Address: 0, Type: "uint16"},
+		Field{Name: "invalid", Register: "holding", Address: 200, Type: "uint16"},
+	)
+	m.Name = "meter"
+	require.NoError(t, m.Init())
+
+	// the fields read successfully are added
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	defer m.disconnect()
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "illegal data address")
+	acc.AssertContainsTaggedFields(t, "modbus",
+		map[string]interface{}{"valid": int64(42)},
+		map[string]string{"slave_id": "1", "name": "meter"})
+
+	// the exception is not retried
+	require.Equal(t, 2, s.requestCount())
+}
+
+func TestRetries(t *testing.T) {
+	s := newServer(t, false)
+	defer s.close()
+	s.set(readHoldingRegisters, 0, 42)
+
+	m := newModbus(s.url(),
+		Field{Name: "value", Register: "holding", Address: 0, Type: "uint16"},
+	)
+	require.NoError(t, m.Init())
+	defer m.disconnect()
+
+	// the connection is opened again after an error
+	s.update(func() { s.dropNext = true })
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(m.Gather))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsFields(t, "modbus", map[string]interface{}{"value": int64(42)})
+
+	// and when the slave is busy
+	s.update(func() { s.busy = 1 })
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(m.Gather))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsFields(t, "modbus", map[string]interface{}{"value": int64(42)})
+
+	// until the retries are exhausted
+	s.update(func() { s.busy = 2 })
+	acc.ClearMetrics()
+	require.NoError(t, m.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "slave device busy")
+	require.Empty(t, acc.Metrics)
+}
+
+func TestGroupRequests(t *testing.T) {
+	m := newModbus("tcp://localhost:502",
+		Field{Name: "a", Register: "holding", Address: 0, Type: "float32"},
+		Field{Name: "b", Register: "holding", Address: 2, Type: "uint16"},
+		Field{Name: "c", Register: "holding", Address: 1, Type: "uint16"},
+		Field{Name: "d", Register: "holding", Address: 10, Type: "uint16"},
+		Field{Name: "e", Register: "input", Address: 3, Type: "uint16"},
+		Field{Name: "f", Register: "holding", Address: 3, Type: "uint16", SlaveID: 2},
+		Field{Name: "g", Register: "holding", Address: 130, Type: "int32"},
+		Field{Name: "h", Register: "holding", Address: 11, Type: "int32"},
+	)
+	require.NoError(t, m.Init())
+
+	type group struct {
+		slaveID  byte
+		function byte
+		address  uint16
+		count    uint16
+		fields   int
+	}
+	var groups []group
+	for _, r := range m.requests {
+		groups = append(groups, group{r.slaveID, r.function, r.address, r.count, len(r.fields)})
+	}
+	require.Equal(t, []group{
+		{1, readHoldingRegisters, 0, 3, 3},
+		{1, readHoldingRegisters, 10, 3, 2},
+		{1, readHoldingRegisters, 130, 2, 1},
+		{1, readInputRegisters, 3, 1, 1},
+		{2, readHoldingRegisters, 3, 1, 1},
+	}, groups)
+}
+
+func TestInitErrors(t *testing.T) {
+	holding := Field{Name: "a", Register: "holding", Type: "uint16"}
+	for _, m := range []*Modbus{
+		newModbus("udp://localhost:502", holding),
+		newModbus("file:///dev/ttyUSB0", holding),
+		newModbus("tcp://localhost:502"),
+		newModbus("tcp://localhost:502", Field{Register: "holding", Type: "uint16"}),
+		newModbus("tcp://localhost:502", holding, holding),
+		newModbus("tcp://localhost:502", Field{Name: "a", Register: "holding", Type: "bool"}),
+		newModbus("tcp://localhost:502", Field{Name: "a", Register: "coil", Type: "uint16"}),
+		newModbus("tcp://localhost:502", Field{Name: "a", Register: "holding", Type: "float64"}),
+		newModbus("tcp://localhost:502", Field{Name: "a", Register: "holding", Type: "float32", ByteOrder: "AB"}),
+		newModbus("tcp://localhost:502", Field{Name: "a", Register: "holding", Type: "uint16", Address: 65536}),
+		newModbus("tcp://localhost:502", Field{Name: "a", Register: "holding", Type: "uint16", SlaveID: 300}),
+	} {
+		if m.Controller == "file:///dev/ttyUSB0" {
+			m.TransmissionMode = "TCP"
+		}
+		require.Error(t, m.Init(), "%+v", m)
+	}
+}
@@ -0,0 +1,86 @@
+// +build linux
+
+package modbus
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+}
+
+// openSerial opens the serial device in raw mode with the settings, reads
+// time out after the timeout.
+func openSerial(path string, s serialSettings, timeout time.Duration) (io.ReadWriteCloser, error) {
+	baud, ok := baudRates[s.BaudRate]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", s.BaudRate)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	t := unix.Termios{
+		Cflag: baud | unix.CREAD | unix.CLOCAL,
+	}
+	switch s.DataBits {
+	case 7:
+		t.Cflag |= unix.CS7
+	default:
+		t.Cflag |= unix.CS8
+	}
+	switch s.Parity {
+	case "E":
+		t.Cflag |= unix.PARENB
+	case "O":
+		t.Cflag |= unix.PARENB | unix.PARODD
+	}
+	if s.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+	// reads return once a byte is received, or after the timeout in tenths
+	// of seconds
+	deciseconds := timeout / (100 * time.Millisecond)
+	if deciseconds < 1 {
+		deciseconds = 1
+	} else if deciseconds > 255 {
+		deciseconds = 255
+	}
+	t.Cc[unix.VMIN] = 0
+	t.Cc[unix.VTIME] = uint8(deciseconds)
+
+	if err := unix.IoctlSetTermios(int(f.Fd()), unix.TCSETS, &t); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not configure %s: %v", path, err)
+	}
+	return &serialPort{f}, nil
+}
+
+// serialPort reports the reads returning nothing after the timeout as
+// errors.
+type serialPort struct {
+	*os.File
+}
+
+func (p *serialPort) Read(b []byte) (int, error) {
+	n, err := p.File.Read(b)
+	if n == 0 && (err == nil || err == io.EOF) {
+		return 0, fmt.Errorf("read timeout")
+	}
+	return n, err
+}
@@ -0,0 +1,13 @@
+// +build !linux
+
+package modbus
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+func openSerial(path string, s serialSettings, timeout time.Duration) (io.ReadWriteCloser, error) {
+	return nil, fmt.Errorf("serial devices are only supported on linux")
+}
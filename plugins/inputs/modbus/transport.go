@@ -0,0 +1,171 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Function codes of the requests.
+const (
+	readCoils            = 1
+	readDiscreteInputs   = 2
+	readHoldingRegisters = 3
+	readInputRegisters   = 4
+)
+
+// exceptionBusy is the exception code answered by a slave busy processing a
+// previous request.
+const exceptionBusy = 6
+
+// exception is the error answered by a slave to a request.
+type exception struct {
+	function byte
+	code     byte
+}
+
+func (e *exception) Error() string {
+	switch e.code {
+	case 1:
+		return "illegal function"
+	case 2:
+		return "illegal data address"
+	case 3:
+		return "illegal data value"
+	case 4:
+		return "slave device failure"
+	case exceptionBusy:
+		return "slave device busy"
+	default:
+		return fmt.Sprintf("exception %d", e.code)
+	}
+}
+
+// framer sends request PDUs to a slave over a connection and reads the
+// response PDUs, with the framing of a transmission mode.
+type framer interface {
+	roundTrip(conn io.ReadWriter, slaveID byte, pdu []byte) ([]byte, error)
+}
+
+// tcpFramer frames the PDUs with the MBAP header of Modbus TCP.
+type tcpFramer struct {
+	transaction uint16
+}
+
+func (f *tcpFramer) roundTrip(conn io.ReadWriter, slaveID byte, pdu []byte) ([]byte, error) {
+	f.transaction++
+	adu := make([]byte, 7+len(pdu))
+	binary.BigEndian.PutUint16(adu[0:], f.transaction)
+	binary.BigEndian.PutUint16(adu[2:], 0)
+	binary.BigEndian.PutUint16(adu[4:], uint16(len(pdu)+1))
+	adu[6] = slaveID
+	copy(adu[7:], pdu)
+	if _, err := conn.Write(adu); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[4:]))
+	switch {
+	case binary.BigEndian.Uint16(header[0:]) != f.transaction:
+		return nil, fmt.Errorf("response to transaction %d, expected %d",
+			binary.BigEndian.Uint16(header[0:]), f.transaction)
+	case binary.BigEndian.Uint16(header[2:]) != 0:
+		return nil, fmt.Errorf("invalid protocol identifier %d", binary.BigEndian.Uint16(header[2:]))
+	case length < 2 || length > 254:
+		return nil, fmt.Errorf("invalid length %d", length)
+	case header[6] != slaveID:
+		return nil, fmt.Errorf("response from slave %d, expected %d", header[6], slaveID)
+	}
+	resp := make([]byte, length-1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// rtuFramer frames the PDUs with the slave address and CRC of Modbus RTU.
+type rtuFramer struct{}
+
+func (f *rtuFramer) roundTrip(conn io.ReadWriter, slaveID byte, pdu []byte) ([]byte, error) {
+	adu := make([]byte, 0, len(pdu)+3)
+	adu = append(adu, slaveID)
+	adu = append(adu, pdu...)
+	adu = appendCRC(adu)
+	if _, err := conn.Write(adu); err != nil {
+		return nil, err
+	}
+
+	// the length of the response is known from its function code and byte
+	// count
+	resp := make([]byte, 3, 256)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	var remaining int
+	if resp[1]&0x80 != 0 {
+		remaining = 2
+	} else {
+		remaining = int(resp[2]) + 2
+	}
+	resp = resp[:3+remaining]
+	if _, err := io.ReadFull(conn, resp[3:]); err != nil {
+		return nil, err
+	}
+
+	if crc := appendCRC(resp[:len(resp)-2])[len(resp)-2:]; crc[0] != resp[len(resp)-2] || crc[1] != resp[len(resp)-1] {
+		return nil, fmt.Errorf("invalid CRC")
+	}
+	if resp[0] != slaveID {
+		return nil, fmt.Errorf("response from slave %d, expected %d", resp[0], slaveID)
+	}
+	return resp[1 : len(resp)-2], nil
+}
+
+// appendCRC appends the CRC-16 of the frame, least significant byte first.
+func appendCRC(frame []byte) []byte {
+	crc := uint16(0xffff)
+	for _, b := range frame {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return append(frame, byte(crc), byte(crc>>8))
+}
+
+// readRequest returns the PDU reading count coils or registers at address.
+func readRequest(function byte, address, count uint16) []byte {
+	pdu := make([]byte, 5)
+	pdu[0] = function
+	binary.BigEndian.PutUint16(pdu[1:], address)
+	binary.BigEndian.PutUint16(pdu[3:], count)
+	return pdu
+}
+
+// readResponse returns the data of the response PDU to a read request of
+// count coils or registers.
+func readResponse(function byte, count uint16, pdu []byte) ([]byte, error) {
+	if len(pdu) == 2 && pdu[0] == function|0x80 {
+		return nil, &exception{function: function, code: pdu[1]}
+	}
+	if len(pdu) < 2 || pdu[0] != function {
+		return nil, fmt.Errorf("invalid response to function %d", function)
+	}
+
+	expected := int(count) * 2
+	if function == readCoils || function == readDiscreteInputs {
+		expected = (int(count) + 7) / 8
+	}
+	if int(pdu[1]) != expected || len(pdu) != 2+expected {
+		return nil, fmt.Errorf("partial response of %d bytes, expected %d", len(pdu)-2, expected)
+	}
+	return pdu[2:], nil
+}
@@ -51,12 +51,11 @@ type MQTTConsumer struct {
 	ClientID          string `toml:"client_id"`
 	tls.ClientConfig
 
-	client     mqtt.Client
-	acc        telegraf.TrackingAccumulator
-	state      ConnectionState
-	subscribed bool
-	sem        semaphore
-	messages   map[telegraf.TrackingID]bool
+	client   mqtt.Client
+	acc      telegraf.TrackingAccumulator
+	state    ConnectionState
+	sem      semaphore
+	messages map[telegraf.TrackingID]bool
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -96,11 +95,13 @@ var sampleConfig = `
     "sensors/#",
   ]
 
-  # if true, messages that can't be delivered while the subscriber is offline
-  # will be delivered when it comes back (such as on service restart).
-  # NOTE: if true, client_id MUST be set
+  ## If true, messages that can't be delivered while the subscriber is offline
+  ## will be delivered when it comes back (such as on service restart).  The
+  ## broker only queues messages with a qos of 1 or 2.
+  ## NOTE: if true, client_id MUST be set
   persistent_session = false
-  # If empty, a random client ID will be generated.
+  ## If empty, a random client ID will be generated.  Must stay the same across
+  ## restarts for the broker to resume a persistent session.
   client_id = ""
 
   ## username and password to connect MQTT server.
@@ -144,6 +145,11 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 		return fmt.Errorf("qos value must be 0, 1, or 2: %d", m.QoS)
 	}
 
+	if m.PersistentSession && m.QoS == 0 {
+		log.Printf("W! [inputs.mqtt_consumer] Messages with a qos of 0 are " +
+			"not queued by the broker for persistent sessions, set qos to 1 or 2")
+	}
+
 	if m.ConnectionTimeout.Duration < 1*time.Second {
 		return fmt.Errorf("connection_timeout must be greater than 1s: %s", m.ConnectionTimeout.Duration)
 	}
@@ -175,22 +181,18 @@ func (m *MQTTConsumer) connect() error {
 	m.sem = make(semaphore, m.MaxUndeliveredMessages)
 	m.messages = make(map[telegraf.TrackingID]bool)
 
-	// Only subscribe on first connection when using persistent sessions.  On
-	// subsequent connections the subscriptions should be stored in the
-	// session, but the proper way to do this is to check the connection
-	// response to ensure a session was found.
-	if !m.PersistentSession || !m.subscribed {
-		topics := make(map[string]byte)
-		for _, topic := range m.Topics {
-			topics[topic] = byte(m.QoS)
-		}
-		subscribeToken := m.client.SubscribeMultiple(topics, m.recvMessage)
-		subscribeToken.Wait()
-		if subscribeToken.Error() != nil {
-			m.acc.AddError(fmt.Errorf("subscription error: topics: %s: %v",
-				strings.Join(m.Topics[:], ","), subscribeToken.Error()))
-		}
-		m.subscribed = true
+	// Subscribe on every connection, the subscriptions of a persistent
+	// session are lost if the broker did not keep the session.  Subscribing
+	// again to the same topics keeps the messages queued by the broker.
+	topics := make(map[string]byte)
+	for _, topic := range m.Topics {
+		topics[topic] = byte(m.QoS)
+	}
+	subscribeToken := m.client.SubscribeMultiple(topics, m.recvMessage)
+	subscribeToken.Wait()
+	if subscribeToken.Error() != nil {
+		m.acc.AddError(fmt.Errorf("subscription error: topics: %s: %v",
+			strings.Join(m.Topics[:], ","), subscribeToken.Error()))
 	}
 
 	return nil
@@ -213,7 +215,8 @@ func (m *MQTTConsumer) recvMessage(c mqtt.Client, msg mqtt.Message) {
 				continue
 			}
 			<-m.sem
-			// No ack, MQTT does not support durable handling
+			// No ack, the client library acknowledges messages as soon as
+			// they are received
 			delete(m.messages, track.ID())
 		case m.sem <- empty{}:
 			err := m.onMessage(m.acc, msg)
@@ -311,6 +314,11 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 	opts.SetAutoReconnect(false)
 	opts.SetKeepAlive(time.Second * 60)
 	opts.SetCleanSession(!m.PersistentSession)
+	if m.PersistentSession {
+		// The messages queued for the session are sent by the broker right
+		// after connecting, before the subscriptions are made again.
+		opts.SetDefaultPublishHandler(m.recvMessage)
+	}
 	opts.SetConnectionLostHandler(m.onConnectionLost)
 
 	return opts, nil
@@ -1,11 +1,17 @@
 package mqtt_consumer
 
 import (
+	"net"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -109,3 +115,269 @@ func (m *message) MessageID() uint16 {
 func (m *message) Payload() []byte {
 	return m.payload
 }
+
+// testBroker is a minimal MQTT broker keeping the sessions of its clients,
+// it queues the qos 1 messages of offline persistent sessions and sends them
+// again until they are acknowledged.
+type testBroker struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	sync.Mutex
+	sessions map[string]*testSession
+	conns    map[net.Conn]bool
+}
+
+type testSession struct {
+	conn       net.Conn
+	persistent bool
+	topics     map[string]byte
+	inflight   []*packets.PublishPacket
+	nextID     uint16
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	b := &testBroker{
+		listener: listener,
+		sessions: make(map[string]*testSession),
+		conns:    make(map[net.Conn]bool),
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			b.Lock()
+			b.conns[conn] = true
+			b.Unlock()
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				b.handle(conn)
+			}()
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) URL() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// Close stops the broker and closes the connections of its clients.
+func (b *testBroker) Close() {
+	b.listener.Close()
+	b.Lock()
+	for conn := range b.conns {
+		conn.Close()
+	}
+	b.Unlock()
+	b.wg.Wait()
+}
+
+// readPacket reads the next packet of a client, giving up after a few
+// seconds so a test cannot hang on a silent client.
+func readPacket(conn net.Conn) (packets.ControlPacket, error) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return packets.ReadPacket(conn)
+}
+
+func (b *testBroker) handle(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		b.Lock()
+		delete(b.conns, conn)
+		b.Unlock()
+	}()
+
+	packet, err := readPacket(conn)
+	if err != nil {
+		return
+	}
+	connect, ok := packet.(*packets.ConnectPacket)
+	if !ok {
+		return
+	}
+
+	b.Lock()
+	session, found := b.sessions[connect.ClientIdentifier]
+	if !found || connect.CleanSession {
+		session = &testSession{topics: make(map[string]byte)}
+		b.sessions[connect.ClientIdentifier] = session
+	}
+	session.conn = conn
+	session.persistent = !connect.CleanSession
+
+	connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+	connack.SessionPresent = found && !connect.CleanSession
+	connack.Write(conn)
+	for _, publish := range session.inflight {
+		publish.Dup = true
+		publish.Write(conn)
+	}
+	b.Unlock()
+
+	defer func() {
+		b.Lock()
+		if session.conn == conn {
+			session.conn = nil
+			// a new connection of the client may have replaced the session
+			if !session.persistent && b.sessions[connect.ClientIdentifier] == session {
+				delete(b.sessions, connect.ClientIdentifier)
+			}
+		}
+		b.Unlock()
+	}()
+
+	for {
+		packet, err := readPacket(conn)
+		if err != nil {
+			return
+		}
+
+		b.Lock()
+		switch p := packet.(type) {
+		case *packets.SubscribePacket:
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			for i, topic := range p.Topics {
+				qos := p.Qoss[i]
+				if qos > 1 {
+					qos = 1
+				}
+				session.topics[topic] = qos
+				suback.ReturnCodes = append(suback.ReturnCodes, qos)
+			}
+			suback.Write(conn)
+		case *packets.PubackPacket:
+			for i, publish := range session.inflight {
+				if publish.MessageID == p.MessageID {
+					session.inflight = append(session.inflight[:i], session.inflight[i+1:]...)
+					break
+				}
+			}
+		case *packets.PingreqPacket:
+			packets.NewControlPacket(packets.Pingresp).Write(conn)
+		case *packets.DisconnectPacket:
+			b.Unlock()
+			return
+		}
+		b.Unlock()
+	}
+}
+
+// publish sends the message to the sessions subscribed to the topic.
+func (b *testBroker) publish(topic string, payload string) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, session := range b.sessions {
+		qos, ok := session.topics[topic]
+		if !ok {
+			continue
+		}
+
+		publish := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+		publish.TopicName = topic
+		publish.Payload = []byte(payload)
+		publish.Qos = qos
+		if qos > 0 {
+			session.nextID++
+			publish.MessageID = session.nextID
+			session.inflight = append(session.inflight, publish)
+		}
+		if session.conn != nil {
+			publish.Write(session.conn)
+		}
+	}
+}
+
+// waitAcknowledged waits until the session of the client has no message
+// waiting for an acknowledgement.
+func (b *testBroker) waitAcknowledged(t *testing.T, clientID string) {
+	for i := 0; i < 100; i++ {
+		b.Lock()
+		session, ok := b.sessions[clientID]
+		acknowledged := !ok || len(session.inflight) == 0
+		b.Unlock()
+		if acknowledged {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("messages of %s not acknowledged", clientID)
+}
+
+func newBrokerMQTTConsumer(t *testing.T, b *testBroker, persistent bool) *MQTTConsumer {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+
+	m := &MQTTConsumer{
+		Servers:                []string{b.URL()},
+		Topics:                 []string{"telegraf/test"},
+		QoS:                    1,
+		PersistentSession:      persistent,
+		ClientID:               "telegraf-test",
+		ConnectionTimeout:      defaultConnectionTimeout,
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+	}
+	m.SetParser(parser)
+	return m
+}
+
+func TestPersistentSessionRedelivery(t *testing.T) {
+	b := newTestBroker(t)
+	defer b.Close()
+
+	var acc testutil.Accumulator
+	m := newBrokerMQTTConsumer(t, b, true)
+	require.NoError(t, m.Start(&acc))
+	require.Equal(t, Connected, m.state)
+
+	b.publish("telegraf/test", "cpu value=1 1000000000\n")
+	acc.Wait(1)
+	b.waitAcknowledged(t, "telegraf-test")
+	m.Stop()
+
+	// published while the consumer is offline
+	b.publish("telegraf/test", "cpu value=2 2000000000\n")
+
+	var restarted testutil.Accumulator
+	m = newBrokerMQTTConsumer(t, b, true)
+	require.NoError(t, m.Start(&restarted))
+	defer m.Stop()
+
+	restarted.Wait(1)
+	b.waitAcknowledged(t, "telegraf-test")
+	require.Len(t, restarted.Metrics, 1)
+	restarted.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"value": 2.0},
+		map[string]string{"topic": "telegraf/test"})
+}
+
+func TestCleanSessionDropsOfflineMessages(t *testing.T) {
+	b := newTestBroker(t)
+	defer b.Close()
+
+	var acc testutil.Accumulator
+	m := newBrokerMQTTConsumer(t, b, false)
+	require.NoError(t, m.Start(&acc))
+	m.Stop()
+
+	b.publish("telegraf/test", "cpu value=1 1000000000\n")
+
+	var restarted testutil.Accumulator
+	m = newBrokerMQTTConsumer(t, b, false)
+	require.NoError(t, m.Start(&restarted))
+	defer m.Stop()
+
+	b.publish("telegraf/test", "cpu value=2 2000000000\n")
+	restarted.Wait(1)
+	restarted.AssertContainsFields(t, "cpu", map[string]interface{}{"value": 2.0})
+}
@@ -0,0 +1,416 @@
+package natsjetstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	nats "github.com/nats-io/go-nats"
+)
+
+const (
+	defaultMaxUndeliveredMessages = 1000
+	defaultAckWait                = 30 * time.Second
+
+	// apiTimeout is the timeout of the requests to the JetStream API.
+	apiTimeout = 5 * time.Second
+)
+
+var deliverPolicies = map[string]bool{
+	"all":               true,
+	"last":              true,
+	"new":               true,
+	"by_start_sequence": true,
+	"by_start_time":     true,
+}
+
+type empty struct{}
+type semaphore chan empty
+
+type natsJetStream struct {
+	Servers       []string          `toml:"servers"`
+	Secure        bool              `toml:"secure"`
+	Stream        string            `toml:"stream"`
+	Durable       string            `toml:"durable"`
+	DeliverPolicy string            `toml:"deliver_policy"`
+	StartSequence uint64            `toml:"start_sequence"`
+	StartTime     string            `toml:"start_time"`
+	AckWait       internal.Duration `toml:"ack_wait"`
+
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
+	conn      *nats.Conn
+	sub       *nats.Subscription
+	startTime time.Time
+
+	parser parsers.Parser
+	// channel for all incoming JetStream messages
+	in chan *nats.Msg
+	// channel for the consumer to be created again after a reconnection
+	reconnected chan empty
+	acc         telegraf.TrackingAccumulator
+	wg          sync.WaitGroup
+	cancel      context.CancelFunc
+
+	// Unacknowledged messages
+	messages map[telegraf.TrackingID]*nats.Msg
+}
+
+var sampleConfig = `
+  ## urls of NATS servers
+  servers = ["nats://localhost:4222"]
+  ## Use Transport Layer Security
+  secure = false
+
+  ## JetStream stream to consume
+  stream = "telegraf"
+  ## Name of the durable consumer, the position in the stream is kept by the
+  ## server across restarts
+  durable = "telegraf"
+
+  ## Where the consumer starts in the stream when it is created, one of
+  ## "all", "last", "new", "by_start_sequence" or "by_start_time"
+  # deliver_policy = "all"
+  ## Stream sequence to start at with the "by_start_sequence" policy
+  # start_sequence = 1
+  ## Time to start at with the "by_start_time" policy, in RFC3339 format
+  # start_time = "2019-01-01T00:00:00Z"
+
+  ## Time to wait for a message to be acknowledged before it is delivered
+  ## again.  Messages are acknowledged once written by an output.
+  # ack_wait = "30s"
+
+  ## Maximum messages to read from the broker that have not been written by an
+  ## output.  For best throughput set based on the number of metrics within
+  ## each message and the size of the output's metric_batch_size.
+  ##
+  ## For example, if each message from the queue contains 10 metrics and the
+  ## output metric_batch_size is 1000, setting this to 100 will ensure that a
+  ## full batch is collected and the write is triggered immediately without
+  ## waiting until the next flush_interval.
+  # max_undelivered_messages = 1000
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+func (n *natsJetStream) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *natsJetStream) Description() string {
+	return "Read metrics from a NATS JetStream stream"
+}
+
+func (n *natsJetStream) SetParser(parser parsers.Parser) {
+	n.parser = parser
+}
+
+func (n *natsJetStream) Init() error {
+	if n.Stream == "" {
+		return fmt.Errorf("stream must be set")
+	}
+	if n.Durable == "" {
+		return fmt.Errorf("durable must be set")
+	}
+	if n.DeliverPolicy == "" {
+		n.DeliverPolicy = "all"
+	}
+	if !deliverPolicies[n.DeliverPolicy] {
+		return fmt.Errorf("invalid deliver_policy %q", n.DeliverPolicy)
+	}
+	if n.DeliverPolicy == "by_start_sequence" && n.StartSequence == 0 {
+		return fmt.Errorf("start_sequence must be set with deliver_policy %q", n.DeliverPolicy)
+	}
+	if n.DeliverPolicy == "by_start_time" {
+		t, err := time.Parse(time.RFC3339, n.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid start_time %q: %v", n.StartTime, err)
+		}
+		n.startTime = t
+	}
+	if n.AckWait.Duration == 0 {
+		n.AckWait.Duration = defaultAckWait
+	}
+	return nil
+}
+
+// deliverSubject is the subject the consumer pushes the messages to, it does
+// not change so the durable consumer can be created again with the same
+// configuration.
+func (n *natsJetStream) deliverSubject() string {
+	return fmt.Sprintf("_INBOX.telegraf.%s.%s", n.Stream, n.Durable)
+}
+
+// consumerConfig is the configuration of a JetStream consumer.
+type consumerConfig struct {
+	DurableName    string     `json:"durable_name"`
+	DeliverSubject string     `json:"deliver_subject"`
+	DeliverPolicy  string     `json:"deliver_policy"`
+	OptStartSeq    uint64     `json:"opt_start_seq,omitempty"`
+	OptStartTime   *time.Time `json:"opt_start_time,omitempty"`
+	AckPolicy      string     `json:"ack_policy"`
+	AckWait        int64      `json:"ack_wait"`
+	MaxAckPending  int        `json:"max_ack_pending,omitempty"`
+}
+
+type consumerCreateRequest struct {
+	Stream string         `json:"stream_name"`
+	Config consumerConfig `json:"config"`
+}
+
+type apiError struct {
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+}
+
+type apiResponse struct {
+	Error *apiError `json:"error"`
+}
+
+// request sends a request to the JetStream API and returns the error of the
+// response, if any.
+func (n *natsJetStream) request(subject string, data []byte) (*apiError, error) {
+	msg, err := n.conn.Request(subject, data, apiTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp apiResponse
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Error, nil
+}
+
+// createConsumer creates the durable consumer, or checks the existing one
+// has the same configuration.
+func (n *natsJetStream) createConsumer() error {
+	config := consumerConfig{
+		DurableName:    n.Durable,
+		DeliverSubject: n.deliverSubject(),
+		DeliverPolicy:  n.DeliverPolicy,
+		AckPolicy:      "explicit",
+		AckWait:        n.AckWait.Duration.Nanoseconds(),
+		MaxAckPending:  n.MaxUndeliveredMessages,
+	}
+	switch n.DeliverPolicy {
+	case "by_start_sequence":
+		config.OptStartSeq = n.StartSequence
+	case "by_start_time":
+		config.OptStartTime = &n.startTime
+	}
+
+	req, err := json.Marshal(&consumerCreateRequest{Stream: n.Stream, Config: config})
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("$JS.API.CONSUMER.DURABLE.CREATE.%s.%s", n.Stream, n.Durable)
+	apiErr, err := n.request(subject, req)
+	if err != nil {
+		return fmt.Errorf("could not create consumer %s on stream %s: %v", n.Durable, n.Stream, err)
+	}
+	if apiErr != nil {
+		return fmt.Errorf("could not create consumer %s on stream %s: %s (%d)",
+			n.Durable, n.Stream, apiErr.Description, apiErr.Code)
+	}
+	return nil
+}
+
+// ensureConsumer creates the consumer again only if the server reports it
+// does not exist, such as after it was deleted or the server lost it.
+func (n *natsJetStream) ensureConsumer() error {
+	subject := fmt.Sprintf("$JS.API.CONSUMER.INFO.%s.%s", n.Stream, n.Durable)
+	apiErr, err := n.request(subject, nil)
+	if err != nil {
+		return fmt.Errorf("could not get consumer %s on stream %s: %v", n.Durable, n.Stream, err)
+	}
+	if apiErr == nil {
+		return nil
+	}
+	if apiErr.Code != http.StatusNotFound {
+		return fmt.Errorf("could not get consumer %s on stream %s: %s (%d)",
+			n.Durable, n.Stream, apiErr.Description, apiErr.Code)
+	}
+
+	log.Printf("I! [inputs.nats_jetstream] Creating missing consumer %s on stream %s",
+		n.Durable, n.Stream)
+	return n.createConsumer()
+}
+
+// Start the JetStream consumer. Caller must call *natsJetStream.Stop() to
+// clean up.
+func (n *natsJetStream) Start(acc telegraf.Accumulator) error {
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	n.messages = make(map[telegraf.TrackingID]*nats.Msg)
+	n.in = make(chan *nats.Msg, 1000)
+	n.reconnected = make(chan empty, 1)
+
+	// set default NATS connection options
+	opts := nats.DefaultOptions
+
+	// override max reconnection tries
+	opts.MaxReconnect = -1
+
+	// override servers if any were specified
+	opts.Servers = n.Servers
+
+	opts.Secure = n.Secure
+
+	opts.ReconnectedCB = func(*nats.Conn) {
+		select {
+		case n.reconnected <- empty{}:
+		default:
+		}
+	}
+
+	var err error
+	n.conn, err = opts.Connect()
+	if err != nil {
+		return err
+	}
+
+	n.sub, err = n.conn.Subscribe(n.deliverSubject(), func(m *nats.Msg) {
+		n.in <- m
+	})
+	if err != nil {
+		n.conn.Close()
+		return err
+	}
+
+	if err := n.createConsumer(); err != nil {
+		n.conn.Close()
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+
+	// Start the message reader
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.receiver(ctx)
+	}()
+
+	log.Printf("I! [inputs.nats_jetstream] Started consuming stream %s with consumer %s from %s",
+		n.Stream, n.Durable, n.conn.ConnectedUrl())
+	return nil
+}
+
+// receiver() reads all incoming messages from JetStream, and parses them
+// into telegraf metrics.
+func (n *natsJetStream) receiver(ctx context.Context) {
+	sem := make(semaphore, n.MaxUndeliveredMessages)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case track := <-n.acc.Delivered():
+			<-sem
+			n.onDelivery(track)
+		case <-n.reconnected:
+			n.recreateConsumer()
+		case sem <- empty{}:
+			select {
+			case <-ctx.Done():
+				return
+			case track := <-n.acc.Delivered():
+				// Once for the delivered message, once to leave the case
+				<-sem
+				<-sem
+				n.onDelivery(track)
+			case <-n.reconnected:
+				<-sem
+				n.recreateConsumer()
+			case msg := <-n.in:
+				if err := n.onMessage(msg); err != nil {
+					n.acc.AddError(err)
+					<-sem
+				}
+			}
+		}
+	}
+}
+
+// onMessage parses the message, it is only acknowledged once its metrics are
+// delivered.  Messages that fail to parse are terminated, parsing them again
+// after ack_wait would fail the same way.
+func (n *natsJetStream) onMessage(msg *nats.Msg) error {
+	metrics, err := n.parser.Parse(msg.Data)
+	if err != nil {
+		if msg.Reply != "" {
+			if err := n.conn.Publish(msg.Reply, []byte("+TERM")); err != nil {
+				n.acc.AddError(fmt.Errorf("could not terminate message: %v", err))
+			}
+		}
+		return fmt.Errorf("stream: %s, error: %s", n.Stream, err.Error())
+	}
+
+	id := n.acc.AddTrackingMetricGroup(metrics)
+	n.messages[id] = msg
+	return nil
+}
+
+func (n *natsJetStream) onDelivery(track telegraf.DeliveryInfo) {
+	msg, ok := n.messages[track.ID()]
+	if !ok {
+		log.Printf("E! [inputs.nats_jetstream] Could not acknowledge message: %d", track.ID())
+		return
+	}
+	delete(n.messages, track.ID())
+
+	if !track.Delivered() || msg.Reply == "" {
+		return
+	}
+	if err := n.conn.Publish(msg.Reply, []byte("+ACK")); err != nil {
+		n.acc.AddError(fmt.Errorf("could not acknowledge message: %v", err))
+	}
+}
+
+// recreateConsumer creates the consumer again after a reconnection, in case
+// the server lost it.
+func (n *natsJetStream) recreateConsumer() {
+	if err := n.ensureConsumer(); err != nil {
+		n.acc.AddError(err)
+	}
+}
+
+func (n *natsJetStream) Stop() {
+	n.cancel()
+	n.wg.Wait()
+
+	if err := n.sub.Unsubscribe(); err != nil {
+		log.Printf("E! [inputs.nats_jetstream] Error unsubscribing from %s: %v",
+			n.sub.Subject, err)
+	}
+	n.conn.Close()
+}
+
+// Gather creates the consumer again if it was deleted.
+func (n *natsJetStream) Gather(acc telegraf.Accumulator) error {
+	return n.ensureConsumer()
+}
+
+func init() {
+	inputs.Add("nats_jetstream", func() telegraf.Input {
+		return &natsJetStream{
+			Servers:                []string{"nats://localhost:4222"},
+			DeliverPolicy:          "all",
+			AckWait:                internal.Duration{Duration: defaultAckWait},
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
+	})
+}
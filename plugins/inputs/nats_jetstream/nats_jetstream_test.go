@@ -0,0 +1,413 @@
+package natsjetstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	nats "github.com/nats-io/go-nats"
+	"github.com/stretchr/testify/require"
+)
+
+// trackingAccumulator reports the delivery of the metric groups when asked
+// to, the testutil.Accumulator never does.
+type trackingAccumulator struct {
+	testutil.Accumulator
+
+	mu        sync.Mutex
+	groups    []telegraf.TrackingID
+	delivered chan telegraf.DeliveryInfo
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d deliveryInfo) ID() telegraf.TrackingID { return d.id }
+func (d deliveryInfo) Delivered() bool         { return d.delivered }
+
+func newTrackingAccumulator() *trackingAccumulator {
+	return &trackingAccumulator{delivered: make(chan telegraf.DeliveryInfo)}
+}
+
+func (a *trackingAccumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return a
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	id := a.Accumulator.AddTrackingMetricGroup(group)
+	a.mu.Lock()
+	a.groups = append(a.groups, id)
+	a.mu.Unlock()
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+// deliverAll reports the delivery of all the metric groups added so far.
+func (a *trackingAccumulator) deliverAll(delivered bool) {
+	a.mu.Lock()
+	groups := a.groups
+	a.groups = nil
+	a.mu.Unlock()
+	for _, id := range groups {
+		a.delivered <- deliveryInfo{id: id, delivered: delivered}
+	}
+}
+
+// jetStreamURL is the JetStream enabled server of the nats-jetstream service
+// in docker-compose.yml.
+func jetStreamURL() string {
+	return "nats://" + testutil.GetLocalHost() + ":4223"
+}
+
+func request(t *testing.T, conn *nats.Conn, subject string, data []byte, resp interface{}) *apiError {
+	msg, err := conn.Request(subject, data, apiTimeout)
+	require.NoError(t, err)
+	var r apiResponse
+	require.NoError(t, json.Unmarshal(msg.Data, &r))
+	if resp != nil {
+		require.NoError(t, json.Unmarshal(msg.Data, resp))
+	}
+	return r.Error
+}
+
+// testStream is a stream created empty for a test, the subject of its
+// messages is its name.
+type testStream struct {
+	conn *nats.Conn
+	name string
+}
+
+func newTestStream(t *testing.T, name string, messages ...string) *testStream {
+	conn, err := nats.Connect(jetStreamURL())
+	require.NoError(t, err)
+	s := &testStream{conn: conn, name: name}
+
+	request(t, conn, "$JS.API.STREAM.DELETE."+name, nil, nil)
+	config, err := json.Marshal(map[string]interface{}{
+		"name":     name,
+		"subjects": []string{name},
+		"storage":  "memory",
+	})
+	require.NoError(t, err)
+	require.Nil(t, request(t, conn, "$JS.API.STREAM.CREATE."+name, config, nil))
+
+	for _, m := range messages {
+		require.Nil(t, request(t, conn, name, []byte(m), nil))
+	}
+	return s
+}
+
+func (s *testStream) close() {
+	s.conn.Close()
+}
+
+type consumerInfo struct {
+	Config        consumerConfig `json:"config"`
+	NumAckPending int            `json:"num_ack_pending"`
+	AckFloor      struct {
+		StreamSeq uint64 `json:"stream_seq"`
+	} `json:"ack_floor"`
+}
+
+func (s *testStream) consumerInfo(t *testing.T, durable string) consumerInfo {
+	var info consumerInfo
+	subject := fmt.Sprintf("$JS.API.CONSUMER.INFO.%s.%s", s.name, durable)
+	require.Nil(t, request(t, s.conn, subject, nil, &info))
+	return info
+}
+
+func (s *testStream) deleteConsumer(t *testing.T, durable string) {
+	subject := fmt.Sprintf("$JS.API.CONSUMER.DELETE.%s.%s", s.name, durable)
+	require.Nil(t, request(t, s.conn, subject, nil, nil))
+}
+
+// waitAcked waits for the messages of the stream up to the sequence seq to
+// be acknowledged, or terminated, and none left pending.
+func waitAcked(t *testing.T, s *testStream, durable string, seq uint64) {
+	var info consumerInfo
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		info = s.consumerInfo(t, durable)
+		if info.AckFloor.StreamSeq == seq && info.NumAckPending == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, seq, info.AckFloor.StreamSeq)
+	require.Equal(t, 0, info.NumAckPending)
+}
+
+func newTestJetStream(t *testing.T, stream string) *natsJetStream {
+	n := &natsJetStream{
+		Servers:                []string{jetStreamURL()},
+		Stream:                 stream,
+		Durable:                "test",
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+	}
+	require.NoError(t, n.Init())
+	n.parser, _ = parsers.NewInfluxParser()
+	return n
+}
+
+func TestAckAfterDelivery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	s := newTestStream(t, "telegraf_ack",
+		"cpu value=1 1000000000\n",
+		"cpu value=2 2000000000\n",
+	)
+	defer s.close()
+	n := newTestJetStream(t, s.name)
+	acc := newTrackingAccumulator()
+	require.NoError(t, n.Start(acc))
+	defer n.Stop()
+
+	acc.Wait(2)
+	info := s.consumerInfo(t, "test")
+	require.Equal(t, uint64(0), info.AckFloor.StreamSeq)
+	require.Equal(t, 2, info.NumAckPending)
+
+	acc.deliverAll(true)
+	waitAcked(t, s, "test", 2)
+
+	require.Equal(t, consumerConfig{
+		DurableName:    "test",
+		DeliverSubject: "_INBOX.telegraf.telegraf_ack.test",
+		DeliverPolicy:  "all",
+		AckPolicy:      "explicit",
+		AckWait:        defaultAckWait.Nanoseconds(),
+		MaxAckPending:  defaultMaxUndeliveredMessages,
+	}, s.consumerInfo(t, "test").Config)
+}
+
+// Test that messages are delivered again until written by an output
+func TestAtLeastOnce(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	s := newTestStream(t, "telegraf_at_least_once",
+		"cpu value=1 1000000000\n",
+		"cpu value=2 2000000000\n",
+	)
+	defer s.close()
+	n := newTestJetStream(t, s.name)
+	n.AckWait.Duration = 500 * time.Millisecond
+	acc := newTrackingAccumulator()
+	require.NoError(t, n.Start(acc))
+	defer n.Stop()
+
+	acc.Wait(2)
+	acc.deliverAll(false)
+
+	// delivered again after ack_wait
+	acc.Wait(4)
+	acc.deliverAll(true)
+	waitAcked(t, s, "test", 2)
+}
+
+// Test that messages failing to parse are terminated
+func TestParseErrorTerminated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	s := newTestStream(t, "telegraf_parse_error",
+		"cpu value=1 1000000000\n",
+		"cpu 2000000000\n",
+		"cpu value=3 3000000000\n",
+	)
+	defer s.close()
+	n := newTestJetStream(t, s.name)
+	n.AckWait.Duration = 500 * time.Millisecond
+	acc := newTrackingAccumulator()
+	require.NoError(t, n.Start(acc))
+	defer n.Stop()
+
+	acc.Wait(2)
+	acc.WaitError(1)
+	acc.deliverAll(true)
+	waitAcked(t, s, "test", 3)
+
+	// not delivered again after ack_wait
+	time.Sleep(time.Second)
+	acc.Lock()
+	defer acc.Unlock()
+	require.Len(t, acc.Errors, 1)
+	require.Len(t, acc.Metrics, 2)
+}
+
+func TestReplayFromStartSequence(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	s := newTestStream(t, "telegraf_start_sequence",
+		"cpu value=1 1000000000\n",
+		"cpu value=2 2000000000\n",
+		"cpu value=3 3000000000\n",
+	)
+	defer s.close()
+	n := newTestJetStream(t, s.name)
+	n.DeliverPolicy = "by_start_sequence"
+	n.StartSequence = 2
+	require.NoError(t, n.Init())
+	acc := newTrackingAccumulator()
+	require.NoError(t, n.Start(acc))
+	defer n.Stop()
+
+	acc.Wait(2)
+	acc.deliverAll(true)
+	waitAcked(t, s, "test", 3)
+
+	config := s.consumerInfo(t, "test").Config
+	require.Equal(t, "by_start_sequence", config.DeliverPolicy)
+	require.Equal(t, uint64(2), config.OptStartSeq)
+
+	var values []interface{}
+	for _, m := range acc.Metrics {
+		values = append(values, m.Fields["value"])
+	}
+	require.Equal(t, []interface{}{2.0, 3.0}, values)
+}
+
+func TestReplayFromStartTime(t *testing.T) {
+	n := &natsJetStream{
+		Stream:        "telegraf",
+		Durable:       "test",
+		DeliverPolicy: "by_start_time",
+		StartTime:     "2019-01-01T00:00:00Z",
+	}
+	require.NoError(t, n.Init())
+	require.Equal(t, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), n.startTime)
+}
+
+// Test that a deleted consumer is created again
+func TestConsumerRecreated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	s := newTestStream(t, "telegraf_recreated", "cpu value=1 1000000000\n")
+	defer s.close()
+	n := newTestJetStream(t, s.name)
+	acc := newTrackingAccumulator()
+	require.NoError(t, n.Start(acc))
+	defer n.Stop()
+
+	acc.Wait(1)
+	s.deleteConsumer(t, "test")
+
+	require.NoError(t, n.Gather(acc))
+	acc.Wait(2)
+
+	acc.deliverAll(true)
+	waitAcked(t, s, "test", 1)
+}
+
+// Test that the existing consumer is only checked on each interval
+func TestGatherConsumerExists(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	s := newTestStream(t, "telegraf_exists")
+	defer s.close()
+
+	var mu sync.Mutex
+	var subjects []string
+	_, err := s.conn.Subscribe("$JS.API.CONSUMER.>", func(msg *nats.Msg) {
+		mu.Lock()
+		subjects = append(subjects, msg.Subject)
+		mu.Unlock()
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.conn.Flush())
+
+	n := newTestJetStream(t, s.name)
+	require.NoError(t, n.Start(newTrackingAccumulator()))
+	defer n.Stop()
+	require.NoError(t, n.Gather(nil))
+	require.NoError(t, n.Gather(nil))
+
+	expected := []string{
+		"$JS.API.CONSUMER.DURABLE.CREATE.telegraf_exists.test",
+		"$JS.API.CONSUMER.INFO.telegraf_exists.test",
+		"$JS.API.CONSUMER.INFO.telegraf_exists.test",
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		count := len(subjects)
+		mu.Unlock()
+		if count >= len(expected) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, expected, subjects)
+}
+
+func TestStartConsumerError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	conn, err := nats.Connect(jetStreamURL())
+	require.NoError(t, err)
+	defer conn.Close()
+	request(t, conn, "$JS.API.STREAM.DELETE.telegraf_missing", nil, nil)
+
+	n := newTestJetStream(t, "telegraf_missing")
+	err = n.Start(newTrackingAccumulator())
+	require.EqualError(t, err,
+		"could not create consumer test on stream telegraf_missing: stream not found (404)")
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *natsJetStream
+	}{
+		{
+			name:   "missing stream",
+			plugin: &natsJetStream{Durable: "test"},
+		},
+		{
+			name:   "missing durable",
+			plugin: &natsJetStream{Stream: "telegraf"},
+		},
+		{
+			name:   "invalid deliver policy",
+			plugin: &natsJetStream{Stream: "telegraf", Durable: "test", DeliverPolicy: "first"},
+		},
+		{
+			name:   "missing start sequence",
+			plugin: &natsJetStream{Stream: "telegraf", Durable: "test", DeliverPolicy: "by_start_sequence"},
+		},
+		{
+			name:   "invalid start time",
+			plugin: &natsJetStream{Stream: "telegraf", Durable: "test", DeliverPolicy: "by_start_time", StartTime: "yesterday"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
@@ -2,7 +2,11 @@ package net
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/influxdata/telegraf"
@@ -16,8 +20,10 @@ type NetIOStats struct {
 	ps     system.PS
 
 	skipChecks          bool
+	sysClassNet         string
 	IgnoreProtocolStats bool
 	Interfaces          []string
+	ReportLinkState     bool
 }
 
 func (_ *NetIOStats) Description() string {
@@ -36,6 +42,11 @@ var netSampleConfig = `
   ##
   # ignore_protocol_stats = false
   ##
+  ## On linux systems telegraf can also report the link speed, operational
+  ## state and carrier of each interface, read from /sys/class/net.
+  ##
+  # report_link_state = false
+  ##
 `
 
 func (_ *NetIOStats) SampleConfig() string {
@@ -94,6 +105,9 @@ func (s *NetIOStats) Gather(acc telegraf.Accumulator) error {
 			"drop_in":      io.Dropin,
 			"drop_out":     io.Dropout,
 		}
+		if s.ReportLinkState {
+			s.addLinkState(io.Name, fields)
+		}
 		acc.AddCounter("net", fields, tags)
 	}
 
@@ -118,6 +132,50 @@ func (s *NetIOStats) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// addLinkState adds the link speed, operational state and carrier of the
+// interface to fields.  Attributes that cannot be read are skipped, this is
+// the case for the speed of most virtual interfaces and for the carrier of
+// interfaces that are administratively down.
+func (s *NetIOStats) addLinkState(name string, fields map[string]interface{}) {
+	if s.sysClassNet == "" {
+		s.sysClassNet = filepath.Join(hostSys(), "class", "net")
+	}
+	dir := filepath.Join(s.sysClassNet, name)
+
+	if speed, err := readSysInt(filepath.Join(dir, "speed")); err == nil && speed >= 0 {
+		fields["speed_mbps"] = speed
+	}
+	if state, err := readSysString(filepath.Join(dir, "operstate")); err == nil {
+		fields["up"] = state == "up"
+	}
+	if carrier, err := readSysInt(filepath.Join(dir, "carrier")); err == nil {
+		fields["carrier"] = carrier == 1
+	}
+}
+
+func hostSys() string {
+	if sys := os.Getenv("HOST_SYS"); sys != "" {
+		return sys
+	}
+	return "/sys"
+}
+
+func readSysString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readSysInt(path string) (int64, error) {
+	v, err := readSysString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
 func init() {
 	inputs.Add("net", func() telegraf.Input {
 		return &NetIOStats{ps: system.NewSystemPS()}
@@ -1,6 +1,9 @@
 package net
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"syscall"
 	"testing"
 
@@ -114,3 +117,69 @@ func TestNetStats(t *testing.T) {
 
 	acc.AssertDoesNotContainsTaggedFields(t, "netstat", fields3, make(map[string]string))
 }
+
+func writeSysFile(t *testing.T, dir, iface, name, value string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, iface), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, iface, name), []byte(value+"\n"), 0644))
+}
+
+func TestNetStatsLinkState(t *testing.T) {
+	var mps system.MockPS
+	defer mps.AssertExpectations(t)
+	var acc testutil.Accumulator
+
+	dir, err := ioutil.TempDir("", "sys_class_net")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeSysFile(t, dir, "eth0", "speed", "1000")
+	writeSysFile(t, dir, "eth0", "operstate", "up")
+	writeSysFile(t, dir, "eth0", "carrier", "1")
+	writeSysFile(t, dir, "veth0", "speed", "-1")
+	writeSysFile(t, dir, "veth0", "operstate", "down")
+
+	netio := []net.IOCountersStat{
+		{Name: "eth0"},
+		{Name: "veth0"},
+	}
+	mps.On("NetIO").Return(netio, nil)
+
+	n := &NetIOStats{
+		ps:                  &mps,
+		skipChecks:          true,
+		sysClassNet:         dir,
+		IgnoreProtocolStats: true,
+		ReportLinkState:     true,
+	}
+	err = n.Gather(&acc)
+	require.NoError(t, err)
+
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{
+			"bytes_sent":   uint64(0),
+			"bytes_recv":   uint64(0),
+			"packets_sent": uint64(0),
+			"packets_recv": uint64(0),
+			"err_in":       uint64(0),
+			"err_out":      uint64(0),
+			"drop_in":      uint64(0),
+			"drop_out":     uint64(0),
+			"speed_mbps":   int64(1000),
+			"up":           true,
+			"carrier":      true,
+		},
+		map[string]string{"interface": "eth0"})
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{
+			"bytes_sent":   uint64(0),
+			"bytes_recv":   uint64(0),
+			"packets_sent": uint64(0),
+			"packets_recv": uint64(0),
+			"err_in":       uint64(0),
+			"err_out":      uint64(0),
+			"drop_in":      uint64(0),
+			"drop_out":     uint64(0),
+			"up":           false,
+		},
+		map[string]string{"interface": "veth0"})
+}
@@ -2,6 +2,7 @@ package nvidia_smi
 
 import (
 	"bufio"
+	"encoding/xml"
 	"fmt"
 	"os"
 	"os/exec"
@@ -15,9 +16,10 @@ import (
 )
 
 var (
-	measurement = "nvidia_smi"
-	metrics     = "fan.speed,memory.total,memory.used,memory.free,pstate,temperature.gpu,name,uuid,compute_mode,utilization.gpu,utilization.memory,index,power.draw"
-	metricNames = [][]string{
+	measurement    = "nvidia_smi"
+	migMeasurement = "nvidia_smi_mig"
+	metrics        = "fan.speed,memory.total,memory.used,memory.free,pstate,temperature.gpu,name,uuid,compute_mode,utilization.gpu,utilization.memory,index,power.draw"
+	metricNames    = [][]string{
 		{"fan_speed", "integer"},
 		{"memory_total", "integer"},
 		{"memory_used", "integer"},
@@ -36,12 +38,37 @@ var (
 
 // NvidiaSMI holds the methods for this plugin
 type NvidiaSMI struct {
-	BinPath string
-	Timeout internal.Duration
+	BinPath   string
+	Timeout   internal.Duration
+	GatherMIG bool `toml:"gather_mig"`
 
 	metrics string
 }
 
+// smiLog is the part of the XML output of nvidia-smi -q -x about the MIG
+// devices.  The GPUs without MIG support or with MIG disabled have no
+// mig_device elements.
+type smiLog struct {
+	GPUs []struct {
+		UUID       string `xml:"uuid"`
+		MIGDevices []struct {
+			Index               string    `xml:"index"`
+			GPUInstanceID       string    `xml:"gpu_instance_id"`
+			ComputeInstanceID   string    `xml:"compute_instance_id"`
+			MultiprocessorCount string    `xml:"device_attributes>shared>multiprocessor_count"`
+			FBMemoryUsage       smiMemory `xml:"fb_memory_usage"`
+			BAR1MemoryUsage     smiMemory `xml:"bar1_memory_usage"`
+		} `xml:"mig_devices>mig_device"`
+	} `xml:"gpu"`
+}
+
+type smiMemory struct {
+	Total    string `xml:"total"`
+	Reserved string `xml:"reserved"`
+	Used     string `xml:"used"`
+	Free     string `xml:"free"`
+}
+
 // Description returns the description of the NvidiaSMI plugin
 func (smi *NvidiaSMI) Description() string {
 	return "Pulls statistics from nvidia GPUs attached to the host"
@@ -55,6 +82,10 @@ func (smi *NvidiaSMI) SampleConfig() string {
 
   ## Optional: timeout for GPU polling
   # timeout = "5s"
+
+  ## Optional: also gather the MIG (Multi-Instance GPU) devices of the GPUs
+  ## in MIG mode, with an additional query of nvidia-smi.
+  # gather_mig = false
 `
 }
 
@@ -75,6 +106,14 @@ func (smi *NvidiaSMI) Gather(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	if smi.GatherMIG {
+		data, err := smi.run("-q", "-x")
+		if err != nil {
+			return err
+		}
+		return gatherMIG(data, acc)
+	}
+
 	return nil
 }
 
@@ -90,7 +129,10 @@ func init() {
 
 func (smi *NvidiaSMI) pollSMI() (string, error) {
 	// Construct and execute metrics query
-	opts := []string{"--format=noheader,nounits,csv", fmt.Sprintf("--query-gpu=%s", smi.metrics)}
+	return smi.run("--format=noheader,nounits,csv", fmt.Sprintf("--query-gpu=%s", smi.metrics))
+}
+
+func (smi *NvidiaSMI) run(opts ...string) (string, error) {
 	ret, err := internal.CombinedOutputTimeout(exec.Command(smi.BinPath, opts...), smi.Timeout.Duration)
 	if err != nil {
 		return "", err
@@ -134,7 +176,8 @@ func parseLine(line string) (map[string]string, map[string]interface{}, error) {
 				continue
 			}
 
-			if strings.Contains(col, "[Not Supported]") {
+			// the GPUs in MIG mode report their utilization as N/A
+			if strings.Contains(col, "[Not Supported]") || strings.Contains(col, "[N/A]") {
 				continue
 			}
 
@@ -164,3 +207,45 @@ func parseLine(line string) (map[string]string, map[string]interface{}, error) {
 	// If the line is empty return an emptyline error
 	return tags, fields, fmt.Errorf("Different number of metrics returned (%d) than expeced (%d)", len(met), len(metricNames))
 }
+
+// gatherMIG adds a metric for each MIG device in the XML output of
+// nvidia-smi, with its memory usage and number of multiprocessors.
+func gatherMIG(ret string, acc telegraf.Accumulator) error {
+	var log smiLog
+	if err := xml.Unmarshal([]byte(ret), &log); err != nil {
+		return fmt.Errorf("Error parsing nvidia-smi XML output: %s", err)
+	}
+
+	for _, gpu := range log.GPUs {
+		for _, device := range gpu.MIGDevices {
+			tags := map[string]string{
+				"gpu_uuid":     gpu.UUID,
+				"mig_instance": device.Index,
+				"gi_id":        device.GPUInstanceID,
+				"ci_id":        device.ComputeInstanceID,
+			}
+			fields := make(map[string]interface{})
+			setMIGField(fields, "sm_count", device.MultiprocessorCount)
+			setMIGField(fields, "memory_total", device.FBMemoryUsage.Total)
+			setMIGField(fields, "memory_reserved", device.FBMemoryUsage.Reserved)
+			setMIGField(fields, "memory_used", device.FBMemoryUsage.Used)
+			setMIGField(fields, "memory_free", device.FBMemoryUsage.Free)
+			setMIGField(fields, "bar1_memory_total", device.BAR1MemoryUsage.Total)
+			setMIGField(fields, "bar1_memory_used", device.BAR1MemoryUsage.Used)
+			setMIGField(fields, "bar1_memory_free", device.BAR1MemoryUsage.Free)
+			if len(fields) > 0 {
+				acc.AddFields(migMeasurement, fields, tags)
+			}
+		}
+	}
+	return nil
+}
+
+// setMIGField sets the field to the integer of the value, like "4864 MiB",
+// unless the value is missing or N/A.
+func setMIGField(fields map[string]interface{}, name, value string) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), " MiB")
+	if out, err := strconv.ParseInt(value, 10, 64); err == nil {
+		fields[name] = out
+	}
+}
@@ -1,8 +1,10 @@
 package nvidia_smi
 
 import (
+	"io/ioutil"
 	"testing"
 
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -42,3 +44,62 @@ func TestParseLineNotSupported(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, nil, fields["fan_speed"])
 }
+
+func TestParseLineMIGMode(t *testing.T) {
+	line := "[N/A], 40536, 12, 40524, P0, 33, A100-SXM4-40GB, GPU-xxx, Default, [N/A], [N/A], 0, 52.5\n"
+	_, fields, err := parseLine(line)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"memory_total":    int64(40536),
+		"memory_used":     int64(12),
+		"memory_free":     int64(40524),
+		"temperature_gpu": int64(33),
+		"power_draw":      52.5,
+	}, fields)
+}
+
+func TestGatherMIG(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/mig.xml")
+	require.NoError(t, err)
+
+	// only the GPU in MIG mode has MIG devices
+	var acc testutil.Accumulator
+	require.NoError(t, gatherMIG(string(data), &acc))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "nvidia_smi_mig",
+		map[string]interface{}{
+			"sm_count":          int64(42),
+			"memory_total":      int64(19968),
+			"memory_reserved":   int64(0),
+			"memory_used":       int64(9),
+			"memory_free":       int64(19958),
+			"bar1_memory_total": int64(32767),
+			"bar1_memory_used":  int64(0),
+			"bar1_memory_free":  int64(32767),
+		},
+		map[string]string{
+			"gpu_uuid":     "GPU-7ddc9dd0-2c5b-1e0c-2d3e-3e2b8a0f1f41",
+			"mig_instance": "0",
+			"gi_id":        "1",
+			"ci_id":        "0",
+		})
+	// the N/A values are skipped
+	acc.AssertContainsTaggedFields(t, "nvidia_smi_mig",
+		map[string]interface{}{
+			"sm_count":          int64(14),
+			"memory_total":      int64(4864),
+			"memory_reserved":   int64(0),
+			"memory_used":       int64(3),
+			"memory_free":       int64(4860),
+			"bar1_memory_total": int64(8191),
+			"bar1_memory_free":  int64(8191),
+		},
+		map[string]string{
+			"gpu_uuid":     "GPU-7ddc9dd0-2c5b-1e0c-2d3e-3e2b8a0f1f41",
+			"mig_instance": "1",
+			"gi_id":        "5",
+			"ci_id":        "0",
+		})
+
+	require.Error(t, gatherMIG("invalid", &acc))
+}
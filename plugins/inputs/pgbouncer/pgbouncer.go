@@ -1,7 +1,6 @@
 package pgbouncer
 
 import (
-	"bytes"
 	"github.com/influxdata/telegraf/plugins/inputs/postgresql"
 
 	// register in driver.
@@ -16,8 +15,40 @@ type PgBouncer struct {
 	postgresql.Service
 }
 
-var ignoredColumns = map[string]bool{"user": true, "database": true, "pool_mode": true,
-	"avg_req": true, "avg_recv": true, "avg_sent": true, "avg_query": true,
+// command is a SHOW command of the admin console, its rows are gathered as
+// metrics of the measurement.  The columns of tags are tags of the metrics,
+// under the name of the tag, the ignored columns are skipped and the other
+// numeric columns are fields.  The columns depend on the version of PgBouncer,
+// and the ones missing are omitted.
+type command struct {
+	query       string
+	measurement string
+	tags        map[string]string
+	ignored     map[string]bool
+}
+
+var commands = []command{
+	{
+		query:       "SHOW STATS",
+		measurement: "pgbouncer",
+		tags:        map[string]string{"database": "db"},
+		ignored: map[string]bool{
+			"avg_req": true, "avg_recv": true, "avg_sent": true, "avg_query": true,
+		},
+	},
+	{
+		query:       "SHOW POOLS",
+		measurement: "pgbouncer_pools",
+		tags:        map[string]string{"database": "db", "user": "user", "pool_mode": "pool_mode"},
+	},
+	{
+		query:       "SHOW DATABASES",
+		measurement: "pgbouncer_databases",
+		tags: map[string]string{
+			"name": "db", "database": "pg_dbname", "force_user": "force_user", "pool_mode": "pool_mode",
+		},
+		ignored: map[string]bool{"host": true, "port": true},
+	},
 }
 
 var sampleConfig = `
@@ -41,126 +72,102 @@ func (p *PgBouncer) Description() string {
 }
 
 func (p *PgBouncer) Gather(acc telegraf.Accumulator) error {
-	var (
-		err     error
-		query   string
-		columns []string
-	)
-
-	query = `SHOW STATS`
-
-	rows, err := p.DB.Query(query)
+	tagAddress, err := p.SanitizedAddress()
 	if err != nil {
 		return err
 	}
 
-	defer rows.Close()
-
-	// grab the column information from the result
-	if columns, err = rows.Columns(); err != nil {
-		return err
-	}
-
-	for rows.Next() {
-		tags, columnMap, err := p.accRow(rows, acc, columns)
-
+	for _, cmd := range commands {
+		rows, err := p.DB.Query(cmd.query)
 		if err != nil {
 			return err
 		}
-
-		fields := make(map[string]interface{})
-		for col, val := range columnMap {
-			_, ignore := ignoredColumns[col]
-			if !ignore {
-				fields[col] = *val
-			}
+		err = gatherRows(acc, rows, cmd, tagAddress)
+		rows.Close()
+		if err != nil {
+			return err
 		}
-		acc.AddFields("pgbouncer", fields, tags)
 	}
+	return nil
+}
 
-	query = `SHOW POOLS`
+// rows are the rows of a result set, like sql.Rows.
+type rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
 
-	poolRows, err := p.DB.Query(query)
+// gatherRows adds a metric of the command per row, tagged with the server.
+func gatherRows(acc telegraf.Accumulator, rows rows, cmd command, tagAddress string) error {
+	// grab the column information from the result
+	columns, err := rows.Columns()
 	if err != nil {
 		return err
 	}
 
-	defer poolRows.Close()
-
-	// grab the column information from the result
-	if columns, err = poolRows.Columns(); err != nil {
-		return err
+	values := make([]interface{}, len(columns))
+	columnVars := make([]interface{}, len(columns))
+	for i := range values {
+		columnVars[i] = &values[i]
 	}
 
-	for poolRows.Next() {
-		tags, columnMap, err := p.accRow(poolRows, acc, columns)
-		if err != nil {
+	for rows.Next() {
+		if err := rows.Scan(columnVars...); err != nil {
 			return err
 		}
 
-		if s, ok := (*columnMap["user"]).(string); ok && s != "" {
-			tags["user"] = s
-		}
-
-		if s, ok := (*columnMap["pool_mode"]).(string); ok && s != "" {
-			tags["pool_mode"] = s
-		}
-
+		tags := map[string]string{"server": tagAddress}
 		fields := make(map[string]interface{})
-		for col, val := range columnMap {
-			_, ignore := ignoredColumns[col]
-			if !ignore {
-				fields[col] = *val
+		for i, col := range columns {
+			if tag, ok := cmd.tags[col]; ok {
+				if s := toString(values[i]); s != "" {
+					tags[tag] = s
+				}
+				continue
+			}
+			if cmd.ignored[col] {
+				continue
 			}
+			if v, ok := toField(values[i]); ok {
+				fields[col] = v
+			}
+		}
+		if _, ok := tags["db"]; !ok {
+			tags["db"] = "postgres"
 		}
-		acc.AddFields("pgbouncer_pools", fields, tags)
+		acc.AddFields(cmd.measurement, fields, tags)
 	}
-
-	return poolRows.Err()
-}
-
-type scanner interface {
-	Scan(dest ...interface{}) error
+	return rows.Err()
 }
 
-func (p *PgBouncer) accRow(row scanner, acc telegraf.Accumulator, columns []string) (map[string]string,
-	map[string]*interface{}, error) {
-	var columnVars []interface{}
-	var dbname bytes.Buffer
-
-	// this is where we'll store the column name with its *interface{}
-	columnMap := make(map[string]*interface{})
-
-	for _, column := range columns {
-		columnMap[column] = new(interface{})
-	}
-
-	// populate the array of interface{} with the pointers in the right order
-	for i := 0; i < len(columnMap); i++ {
-		columnVars = append(columnVars, columnMap[columns[i]])
-	}
-
-	// deconstruct array of variables and send to Scan
-	err := row.Scan(columnVars...)
-
-	if err != nil {
-		return nil, nil, err
-	}
-	if columnMap["database"] != nil {
-		// extract the database name from the column map
-		dbname.WriteString((*columnMap["database"]).(string))
-	} else {
-		dbname.WriteString("postgres")
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return ""
 	}
+}
 
-	var tagAddress string
-	tagAddress, err = p.SanitizedAddress()
-	if err != nil {
-		return nil, nil, err
+// toField returns the numeric value of a column, the NULL and text values
+// are skipped.
+func toField(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64, float64:
+		return v, true
+	case int32:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case bool:
+		return v, true
+	default:
+		return nil, false
 	}
-
-	// Return basic tags and the mapped columns
-	return map[string]string{"server": tagAddress, "db": dbname.String()}, columnMap, nil
 }
 
 func init() {
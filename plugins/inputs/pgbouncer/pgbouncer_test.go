@@ -64,3 +64,150 @@ func TestPgBouncerGeneratesMetrics(t *testing.T) {
 	assert.True(t, metricsCounted > 0)
 	assert.Equal(t, len(intMetrics)+len(int32Metrics), metricsCounted)
 }
+
+// resultSet is a recorded result set of a SHOW command.
+type resultSet struct {
+	columns []string
+	values  [][]interface{}
+	row     int
+}
+
+func (r *resultSet) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *resultSet) Next() bool {
+	r.row++
+	return r.row <= len(r.values)
+}
+
+func (r *resultSet) Scan(dest ...interface{}) error {
+	for i, v := range r.values[r.row-1] {
+		*dest[i].(*interface{}) = v
+	}
+	return nil
+}
+
+func (r *resultSet) Err() error {
+	return nil
+}
+
+func TestGatherStats(t *testing.T) {
+	// SHOW STATS of PgBouncer 1.7, and of 1.8 with the transaction columns
+	// and the averages renamed
+	v17 := &resultSet{
+		columns: []string{"database", "total_requests", "total_received", "total_sent",
+			"total_query_time", "avg_req", "avg_recv", "avg_sent", "avg_query"},
+		values: [][]interface{}{
+			{"app", int64(120), int64(4096), int64(8192), int64(5000), int64(2), int64(68), int64(136), int64(41)},
+		},
+	}
+	v18 := &resultSet{
+		columns: []string{"database", "total_xact_count", "total_query_count", "total_received",
+			"total_sent", "total_xact_time", "total_query_time", "total_wait_time", "avg_xact_count",
+			"avg_query_count", "avg_recv", "avg_sent", "avg_xact_time", "avg_query_time", "avg_wait_time"},
+		values: [][]interface{}{
+			{"app", int64(100), int64(120), int64(4096), int64(8192), int64(6000), int64(5000),
+				int64(10), int64(1), int64(2), int64(68), int64(136), int64(60), int64(41), int64(0)},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherRows(&acc, v17, commands[0], "host=localhost"))
+	tags := map[string]string{"server": "host=localhost", "db": "app"}
+	acc.AssertContainsTaggedFields(t, "pgbouncer", map[string]interface{}{
+		"total_requests":   int64(120),
+		"total_received":   int64(4096),
+		"total_sent":       int64(8192),
+		"total_query_time": int64(5000),
+	}, tags)
+
+	acc.ClearMetrics()
+	require.NoError(t, gatherRows(&acc, v18, commands[0], "host=localhost"))
+	acc.AssertContainsTaggedFields(t, "pgbouncer", map[string]interface{}{
+		"total_xact_count":  int64(100),
+		"total_query_count": int64(120),
+		"total_received":    int64(4096),
+		"total_sent":        int64(8192),
+		"total_xact_time":   int64(6000),
+		"total_query_time":  int64(5000),
+		"total_wait_time":   int64(10),
+		"avg_xact_count":    int64(1),
+		"avg_query_count":   int64(2),
+		"avg_xact_time":     int64(60),
+		"avg_query_time":    int64(41),
+		"avg_wait_time":     int64(0),
+	}, tags)
+}
+
+func TestGatherPools(t *testing.T) {
+	// SHOW POOLS of PgBouncer 1.7, without maxwait_us and pool_mode
+	pools := &resultSet{
+		columns: []string{"database", "user", "cl_active", "cl_waiting", "sv_active", "sv_idle",
+			"sv_used", "sv_tested", "sv_login", "maxwait"},
+		values: [][]interface{}{
+			{"app", "web", int64(10), int64(2), int64(5), int64(1), int64(0), int64(0), int64(0), int64(3)},
+			{"pgbouncer", "pgbouncer", int64(1), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0)},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherRows(&acc, pools, commands[1], "host=localhost"))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "pgbouncer_pools", map[string]interface{}{
+		"cl_active":  int64(10),
+		"cl_waiting": int64(2),
+		"sv_active":  int64(5),
+		"sv_idle":    int64(1),
+		"sv_used":    int64(0),
+		"sv_tested":  int64(0),
+		"sv_login":   int64(0),
+		"maxwait":    int64(3),
+	}, map[string]string{"server": "host=localhost", "db": "app", "user": "web"})
+}
+
+func TestGatherDatabases(t *testing.T) {
+	// SHOW DATABASES of PgBouncer 1.12, the fields and tags of NULL values
+	// are omitted
+	databases := &resultSet{
+		columns: []string{"name", "host", "port", "database", "force_user", "pool_size",
+			"reserve_pool", "pool_mode", "max_connections", "current_connections", "paused", "disabled"},
+		values: [][]interface{}{
+			{"app", "10.0.0.1", int64(5432), "app_production", nil, int64(20), int64(5), "transaction",
+				int64(100), int64(12), int64(0), int64(0)},
+			{"pgbouncer", nil, int64(6432), "pgbouncer", "pgbouncer", int64(2), int64(0), "statement",
+				int64(0), int64(1), int64(0), int64(0)},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherRows(&acc, databases, commands[2], "host=localhost"))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "pgbouncer_databases", map[string]interface{}{
+		"pool_size":           int64(20),
+		"reserve_pool":        int64(5),
+		"max_connections":     int64(100),
+		"current_connections": int64(12),
+		"paused":              int64(0),
+		"disabled":            int64(0),
+	}, map[string]string{
+		"server":    "host=localhost",
+		"db":        "app",
+		"pg_dbname": "app_production",
+		"pool_mode": "transaction",
+	})
+	acc.AssertContainsTaggedFields(t, "pgbouncer_databases", map[string]interface{}{
+		"pool_size":           int64(2),
+		"reserve_pool":        int64(0),
+		"max_connections":     int64(0),
+		"current_connections": int64(1),
+		"paused":              int64(0),
+		"disabled":            int64(0),
+	}, map[string]string{
+		"server":     "host=localhost",
+		"db":         "pgbouncer",
+		"pg_dbname":  "pgbouncer",
+		"force_user": "pgbouncer",
+		"pool_mode":  "statement",
+	})
+}
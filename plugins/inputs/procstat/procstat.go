@@ -55,7 +55,9 @@ var sampleConfig = `
   ## CGroup name or path
   # cgroup = "systemd/system.slice/nginx.service"
 
-  ## Windows service name
+  ## Windows service name, the process of the service and its children are
+  ## monitored.  The children of processes shared by several services, such
+  ## as svchost, are not.
   # win_service = ""
 
   ## override for process_name
@@ -295,6 +297,7 @@ func (p *Procstat) getPIDFinder() (PIDFinder, error) {
 func (p *Procstat) findPids(acc telegraf.Accumulator) ([]PID, map[string]string, error) {
 	var pids []PID
 	tags := make(map[string]string)
+	fields := make(map[string]interface{})
 	var err error
 
 	f, err := p.getPIDFinder()
@@ -321,8 +324,15 @@ func (p *Procstat) findPids(acc telegraf.Accumulator) ([]PID, map[string]string,
 		pids, err = p.cgroupPIDs()
 		tags = map[string]string{"cgroup": p.CGroup}
 	} else if p.WinService != "" {
-		pids, err = p.winServicePIDs()
+		var running bool
+		pids, running, err = p.winServicePIDs()
 		tags = map[string]string{"win_service": p.WinService}
+		if err == nil {
+			fields["running"] = 0
+			if running {
+				fields["running"] = 1
+			}
+		}
 	} else {
 		err = fmt.Errorf("Either exe, pid_file, user, pattern, systemd_unit, cgroup, or win_service must be specified")
 	}
@@ -333,7 +343,6 @@ func (p *Procstat) findPids(acc telegraf.Accumulator) ([]PID, map[string]string,
 	}
 
 	//adds a metric with info on the pgrep query
-	fields := make(map[string]interface{})
 	tags["pid_finder"] = p.PidFinder
 	fields["pid_count"] = len(pids)
 	acc.AddFields("procstat_lookup", fields, tags)
@@ -397,19 +406,6 @@ func (p *Procstat) cgroupPIDs() ([]PID, error) {
 	return pids, nil
 }
 
-func (p *Procstat) winServicePIDs() ([]PID, error) {
-	var pids []PID
-
-	pid, err := queryPidWithWinServiceName(p.WinService)
-	if err != nil {
-		return pids, err
-	}
-
-	pids = append(pids, PID(pid))
-
-	return pids, nil
-}
-
 func init() {
 	inputs.Add("procstat", func() telegraf.Input {
 		return &Procstat{}
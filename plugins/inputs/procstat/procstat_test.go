@@ -354,6 +354,77 @@ func TestGather_systemdUnitPIDs(t *testing.T) {
 	assert.Equal(t, "TestGather_systemdUnitPIDs", tags["systemd_unit"])
 }
 
+func mockWinService(t *testing.T, srv winService, children map[PID][]PID) {
+	queryWinService = func(name string) (winService, error) {
+		require.Equal(t, "TestGather_winServicePIDs", name)
+		return srv, nil
+	}
+	childPIDs = func(pid PID) ([]PID, error) {
+		return children[pid], nil
+	}
+}
+
+func restoreWinService() {
+	queryWinService = queryWinServiceStatus
+	childPIDs = processChildren
+}
+
+func TestGather_winServicePIDs(t *testing.T) {
+	mockWinService(t, winService{PID: 100, Running: true}, map[PID][]PID{
+		100: {101, 102},
+		102: {103},
+	})
+	defer restoreWinService()
+
+	p := Procstat{
+		createPIDFinder: pidFinder([]PID{}, nil),
+		WinService:      "TestGather_winServicePIDs",
+	}
+	var acc testutil.Accumulator
+	pids, tags, err := p.findPids(&acc)
+	require.NoError(t, err)
+	assert.Equal(t, []PID{100, 101, 102, 103}, pids)
+	assert.Equal(t, "TestGather_winServicePIDs", tags["win_service"])
+	acc.AssertContainsFields(t, "procstat_lookup", map[string]interface{}{
+		"pid_count": 4,
+		"running":   1,
+	})
+}
+
+func TestGather_winServiceSharedPIDs(t *testing.T) {
+	mockWinService(t, winService{PID: 100, Running: true, Shared: true}, map[PID][]PID{
+		100: {101},
+	})
+	defer restoreWinService()
+
+	p := Procstat{
+		createPIDFinder: pidFinder([]PID{}, nil),
+		WinService:      "TestGather_winServicePIDs",
+	}
+	var acc testutil.Accumulator
+	pids, _, err := p.findPids(&acc)
+	require.NoError(t, err)
+	assert.Equal(t, []PID{100}, pids)
+}
+
+func TestGather_winServiceStopped(t *testing.T) {
+	mockWinService(t, winService{}, nil)
+	defer restoreWinService()
+
+	p := Procstat{
+		createPIDFinder: pidFinder([]PID{}, nil),
+		WinService:      "TestGather_winServicePIDs",
+	}
+	var acc testutil.Accumulator
+	pids, _, err := p.findPids(&acc)
+	require.NoError(t, err)
+	assert.Empty(t, pids)
+	acc.AssertContainsFields(t, "procstat_lookup", map[string]interface{}{
+		"pid_count": 0,
+		"running":   0,
+	})
+}
+
 func TestGather_cgroupPIDs(t *testing.T) {
 	//no cgroups in windows
 	if runtime.GOOS == "windows" {
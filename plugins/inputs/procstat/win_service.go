@@ -0,0 +1,71 @@
+package procstat
+
+import (
+	"github.com/shirou/gopsutil/process"
+)
+
+// winService is the state of a Windows service from the service manager.
+type winService struct {
+	PID     PID
+	Running bool
+	// Shared is set when the hosting process, such as svchost, may host
+	// other services.
+	Shared bool
+}
+
+// queryWinService and childPIDs are so tests can mock out the service
+// manager and the process table.
+var (
+	queryWinService = queryWinServiceStatus
+	childPIDs       = processChildren
+)
+
+func processChildren(pid PID) ([]PID, error) {
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil, err
+	}
+	children, err := proc.Children()
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]PID, 0, len(children))
+	for _, child := range children {
+		pids = append(pids, PID(child.Pid))
+	}
+	return pids, nil
+}
+
+// winServicePIDs returns the process tree of the service and if it is
+// running.  The children of a shared hosting process are not included, they
+// may belong to the other services it hosts.
+func (p *Procstat) winServicePIDs() ([]PID, bool, error) {
+	srv, err := queryWinService(p.WinService)
+	if err != nil {
+		return nil, false, err
+	}
+	if !srv.Running {
+		return nil, false, nil
+	}
+
+	pids := []PID{srv.PID}
+	if srv.Shared {
+		return pids, true, nil
+	}
+
+	seen := map[PID]bool{srv.PID: true}
+	for i := 0; i < len(pids); i++ {
+		children, err := childPIDs(pids[i])
+		if err != nil {
+			// No problem; process may have ended after we found it
+			continue
+		}
+		for _, child := range children {
+			if !seen[child] {
+				seen[child] = true
+				pids = append(pids, child)
+			}
+		}
+	}
+	return pids, true, nil
+}
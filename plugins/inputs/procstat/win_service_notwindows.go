@@ -6,6 +6,6 @@ import (
 	"fmt"
 )
 
-func queryPidWithWinServiceName(winServiceName string) (uint32, error) {
-	return 0, fmt.Errorf("os not support win_service option")
+func queryWinServiceStatus(winServiceName string) (winService, error) {
+	return winService{}, fmt.Errorf("os not support win_service option")
 }
@@ -24,25 +24,30 @@ func getService(name string) (*mgr.Service, error) {
 	return srv, nil
 }
 
-func queryPidWithWinServiceName(winServiceName string) (uint32, error) {
+func queryWinServiceStatus(winServiceName string) (winService, error) {
 	srv, err := getService(winServiceName)
 	if err != nil {
-		return 0, err
+		return winService{}, err
 	}
+	defer srv.Close()
 
 	var p *windows.SERVICE_STATUS_PROCESS
 	var bytesNeeded uint32
 	var buf []byte
 
 	if err := windows.QueryServiceStatusEx(srv.Handle, windows.SC_STATUS_PROCESS_INFO, nil, 0, &bytesNeeded); err != windows.ERROR_INSUFFICIENT_BUFFER {
-		return 0, err
+		return winService{}, err
 	}
 
 	buf = make([]byte, bytesNeeded)
 	p = (*windows.SERVICE_STATUS_PROCESS)(unsafe.Pointer(&buf[0]))
 	if err := windows.QueryServiceStatusEx(srv.Handle, windows.SC_STATUS_PROCESS_INFO, &buf[0], uint32(len(buf)), &bytesNeeded); err != nil {
-		return 0, err
+		return winService{}, err
 	}
 
-	return p.ProcessId, nil
+	return winService{
+		PID:     PID(p.ProcessId),
+		Running: p.CurrentState != windows.SERVICE_STOPPED && p.ProcessId != 0,
+		Shared:  p.ServiceType&windows.SERVICE_WIN32_SHARE_PROCESS != 0,
+	}, nil
 }
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
@@ -77,33 +78,62 @@ func (p *Prometheus) start(ctx context.Context) error {
 	return nil
 }
 
-// An edge case exists if a pod goes offline at the same time a new pod is created
-// (without the scrape annotations). K8s may re-assign the old pod ip to the non-scrape
-// pod, causing errors in the logs. This is only true if the pod going offline is not
-// directed to do so by K8s.
+// podWatcher returns the events of the watched pods, it is implemented by
+// k8s.Watcher.
+type podWatcher interface {
+	Next(r k8s.Resource) (string, error)
+}
+
+// watchOptions returns the options restricting the watched pods to the ones
+// matching the configured selectors.
+func (p *Prometheus) watchOptions() []k8s.Option {
+	var options []k8s.Option
+	if p.KubernetesLabelSelector != "" {
+		options = append(options, k8s.QueryParam("labelSelector", p.KubernetesLabelSelector))
+	}
+	if p.KubernetesFieldSelector != "" {
+		options = append(options, k8s.QueryParam("fieldSelector", p.KubernetesFieldSelector))
+	}
+	return options
+}
+
 func (p *Prometheus) watch(ctx context.Context, client *k8s.Client) error {
-	pod := &corev1.Pod{}
-	watcher, err := client.Watch(ctx, "", &corev1.Pod{})
+	watcher, err := client.Watch(ctx, p.PodNamespace, &corev1.Pod{}, p.watchOptions()...)
 	if err != nil {
 		return err
 	}
 	defer watcher.Close()
 
+	return p.watchPods(ctx, watcher)
+}
+
+// An edge case exists if a pod goes offline at the same time a new pod is created
+// (without the scrape annotations). K8s may re-assign the old pod ip to the non-scrape
+// pod, causing errors in the logs. This is only true if the pod going offline is not
+// directed to do so by K8s.
+func (p *Prometheus) watchPods(ctx context.Context, watcher podWatcher) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			pod = &corev1.Pod{}
+			pod := &corev1.Pod{}
 			// An error here means we need to reconnect the watcher.
 			eventType, err := watcher.Next(pod)
 			if err != nil {
 				return err
 			}
 
+			if pod.GetMetadata().GetAnnotations()["prometheus.io/scrape"] != "true" {
+				continue
+			}
+
 			// If the pod is not "ready", there will be no ip associated with it.
-			if pod.GetMetadata().GetAnnotations()["prometheus.io/scrape"] != "true" ||
-				!podReady(pod.Status.GetContainerStatuses()) {
+			if !podReady(pod.Status.GetContainerStatuses()) {
+				// A pod becoming unready is not scraped until it is ready again.
+				if eventType == k8s.EventModified {
+					unregisterPod(pod, p)
+				}
 				continue
 			}
 
@@ -118,6 +148,8 @@ func (p *Prometheus) watch(ctx context.Context, client *k8s.Client) error {
 				} else {
 					registerPod(pod, p)
 				}
+			case k8s.EventDeleted:
+				unregisterPod(pod, p)
 			}
 		}
 	}
@@ -189,6 +221,10 @@ func getScrapeURL(pod *corev1.Pod) *string {
 	}
 	if port == "" {
 		port = "9102"
+	} else if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		log.Printf("W! [inputs.prometheus] ignoring pod %s in namespace %s: invalid prometheus.io/port %q",
+			pod.GetMetadata().GetName(), pod.GetMetadata().GetNamespace(), port)
+		return nil
 	}
 	if path == "" {
 		path = "/metrics"
@@ -1,10 +1,14 @@
 package prometheus
 
 import (
+	"context"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/ericchiang/k8s"
 	v1 "github.com/ericchiang/k8s/apis/core/v1"
 	metav1 "github.com/ericchiang/k8s/apis/meta/v1"
 )
@@ -94,6 +98,103 @@ func TestDeletePods(t *testing.T) {
 	assert.Equal(t, 0, len(prom.kubernetesPods))
 }
 
+func TestScrapeURLAnnotationsInvalidPort(t *testing.T) {
+	p := pod()
+	p.Metadata.Annotations = map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "metrics"}
+	url := getScrapeURL(p)
+	assert.Nil(t, url)
+}
+
+type podEvent struct {
+	eventType string
+	pod       *v1.Pod
+}
+
+// fakeWatcher returns its events then io.EOF, like a closed watch.
+type fakeWatcher struct {
+	events []podEvent
+}
+
+func (w *fakeWatcher) Next(r k8s.Resource) (string, error) {
+	if len(w.events) == 0 {
+		return "", io.EOF
+	}
+	event := w.events[0]
+	w.events = w.events[1:]
+	*r.(*v1.Pod) = *event.pod
+	return event.eventType, nil
+}
+
+func readyPod(name, ip string, annotations map[string]string) *v1.Pod {
+	p := pod()
+	p.Metadata.Name = str(name)
+	p.Status.PodIP = str(ip)
+	p.Metadata.Annotations = annotations
+	ready := true
+	p.Status.ContainerStatuses = []*v1.ContainerStatus{{Ready: &ready}}
+	return p
+}
+
+func TestWatchPods(t *testing.T) {
+	prom := &Prometheus{}
+
+	unready := readyPod("unready", "127.0.0.4", map[string]string{"prometheus.io/scrape": "true"})
+	notReady := false
+	unready.Status.ContainerStatuses[0].Ready = &notReady
+
+	watcher := &fakeWatcher{events: []podEvent{
+		{k8s.EventAdded, readyPod("default", "127.0.0.1", map[string]string{
+			"prometheus.io/scrape": "true",
+		})},
+		{k8s.EventAdded, readyPod("custom", "127.0.0.2", map[string]string{
+			"prometheus.io/scrape": "true",
+			"prometheus.io/port":   "8080",
+			"prometheus.io/path":   "/custom/metrics",
+		})},
+		{k8s.EventAdded, readyPod("noscrape", "127.0.0.3", map[string]string{
+			"prometheus.io/scrape": "false",
+		})},
+		{k8s.EventAdded, unready},
+		{k8s.EventAdded, readyPod("badport", "127.0.0.5", map[string]string{
+			"prometheus.io/scrape": "true",
+			"prometheus.io/port":   "http",
+		})},
+	}}
+	err := prom.watchPods(context.Background(), watcher)
+	require.Equal(t, io.EOF, err)
+
+	require.Len(t, prom.kubernetesPods, 2)
+	assert.Contains(t, prom.kubernetesPods, "http://127.0.0.1:9102/metrics")
+	assert.Contains(t, prom.kubernetesPods, "http://127.0.0.2:8080/custom/metrics")
+	assert.Equal(t, "custom", prom.kubernetesPods["http://127.0.0.2:8080/custom/metrics"].Tags["pod_name"])
+
+	// a pod becoming unready and a deleted pod are no longer scraped
+	custom := readyPod("custom", "127.0.0.2", map[string]string{
+		"prometheus.io/scrape": "true",
+		"prometheus.io/port":   "8080",
+		"prometheus.io/path":   "/custom/metrics",
+	})
+	custom.Status.ContainerStatuses[0].Ready = &notReady
+	watcher = &fakeWatcher{events: []podEvent{
+		{k8s.EventModified, custom},
+		{k8s.EventDeleted, readyPod("default", "127.0.0.1", map[string]string{
+			"prometheus.io/scrape": "true",
+		})},
+	}}
+	err = prom.watchPods(context.Background(), watcher)
+	require.Equal(t, io.EOF, err)
+	require.Len(t, prom.kubernetesPods, 0)
+}
+
+func TestWatchOptions(t *testing.T) {
+	prom := &Prometheus{}
+	assert.Len(t, prom.watchOptions(), 0)
+
+	prom.KubernetesLabelSelector = "app=web"
+	prom.KubernetesFieldSelector = "spec.nodeName=node1"
+	assert.Len(t, prom.watchOptions(), 2)
+}
+
 func pod() *v1.Pod {
 	p := &v1.Pod{Metadata: &metav1.ObjectMeta{}, Status: &v1.PodStatus{}}
 	p.Status.PodIP = str("127.0.0.1")
@@ -28,6 +28,10 @@ import (
 	"github.com/mesos/mesos-go/api/v1/lib/httpcli/httpagent"
 )
 
+// defaultMaxBodySize is the default maximum size of a response body, in
+// bytes, 500 MB.
+const defaultMaxBodySize = 500 * 1024 * 1024
+
 const acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3`
 
 type Prometheus struct {
@@ -50,12 +54,24 @@ type Prometheus struct {
 
 	ResponseTimeout internal.Duration `toml:"response_timeout"`
 
+	// Emit a prometheus_scrape metric with the health of each target
+	EmitScrapeMeta bool `toml:"emit_scrape_meta"`
+
+	// Maximum size of a response body, and of the bodies of some targets
+	MaxBodySize    internal.Size            `toml:"max_body_size"`
+	URLMaxBodySize map[string]internal.Size `toml:"url_max_body_size"`
+
 	tls.ClientConfig
 
 	client *http.Client
 
 	// Should we scrape Kubernetes services for prometheus annotations
-	MonitorPods    bool `toml:"monitor_kubernetes_pods"`
+	MonitorPods bool `toml:"monitor_kubernetes_pods"`
+	// Namespace and selectors restricting the scraped pods
+	PodNamespace            string `toml:"monitor_kubernetes_pods_namespace"`
+	KubernetesLabelSelector string `toml:"kubernetes_label_selector"`
+	KubernetesFieldSelector string `toml:"kubernetes_field_selector"`
+
 	lock           sync.Mutex
 	kubernetesPods map[string]URLAndAddress
 	cancel         context.CancelFunc
@@ -82,6 +98,13 @@ var sampleConfig = `
   ## - prometheus.io/path: If the metrics path is not /metrics, define it with this annotation.
   ## - prometheus.io/port: If port is not 9102 use this annotation
   # monitor_kubernetes_pods = true
+  ## Restrict the monitored pods to the ones of a namespace, by default the
+  ## pods of all the namespaces are monitored.
+  # monitor_kubernetes_pods_namespace = ""
+  ## Restrict the monitored pods with label and field selectors, in the
+  ## syntax of kubectl.
+  # kubernetes_label_selector = "env=prod,app notin (backup)"
+  # kubernetes_field_selector = "spec.nodeName=$HOSTNAME"
 
   ## The URL of the local mesos agent
   mesos_agent_url = "http://$NODE_PRIVATE_IP:5051"
@@ -100,12 +123,25 @@ var sampleConfig = `
   ## Specify timeout duration for slower prometheus clients (default is 3s)
   # response_timeout = "3s"
 
+  ## Emit a prometheus_scrape metric for each target, with the fields up,
+  ## duration_seconds and samples_scraped.
+  # emit_scrape_meta = false
+
+  ## Maximum size of a response body after decompression, the scrape of a
+  ## target with a larger body fails.  0 means to use the default of 500MB.
+  # max_body_size = "500MB"
+
   ## Optional TLS Config
   # tls_ca = /path/to/cafile
   # tls_cert = /path/to/certfile
   # tls_key = /path/to/keyfile
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
+
+  ## Maximum size of the response body of some targets, by URL as in the url
+  ## tag of their metrics.
+  # [inputs.prometheus.url_max_body_size]
+  #   "http://localhost:9100/metrics" = "1GB"
 `
 
 func (p *Prometheus) SampleConfig() string {
@@ -231,7 +267,13 @@ func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(serviceURL URLAndAddress) {
 			defer wg.Done()
-			acc.AddError(p.gatherURL(serviceURL, acc))
+			start := time.Now()
+			samples, err := p.gatherURL(serviceURL, acc)
+			acc.AddError(err)
+			if p.EmitScrapeMeta {
+				p.addScrapeMeta(serviceURL, err == nil, samples,
+					time.Since(start), acc)
+			}
 		}(URL)
 	}
 
@@ -257,7 +299,7 @@ func (p *Prometheus) createHTTPClient() (*http.Client, error) {
 	return client, nil
 }
 
-func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error {
+func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) (int, error) {
 	var req *http.Request
 	var err error
 	var uClient *http.Client
@@ -294,7 +336,7 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 	if p.BearerToken != "" {
 		token, err = ioutil.ReadFile(p.BearerToken)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		req.Header.Set("Authorization", "Bearer "+string(token))
 	}
@@ -306,34 +348,35 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 		resp, err = uClient.Do(req)
 	}
 	if err != nil {
-		return fmt.Errorf("error making HTTP request to %s: %s", u.URL, err)
+		return 0, fmt.Errorf("error making HTTP request to %s: %s", u.URL, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s returned HTTP status %s", u.URL, resp.Status)
+		return 0, fmt.Errorf("%s returned HTTP status %s", u.URL, resp.Status)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// the body is decompressed by the transport, so the limit applies to the
+	// decompressed size
+	maxBodySize := p.maxBodySize(u)
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
 	if err != nil {
-		return fmt.Errorf("error reading body: %s", err)
+		return 0, fmt.Errorf("error reading body: %s", err)
+	}
+	if int64(len(body)) > maxBodySize {
+		return 0, fmt.Errorf("%s returned a body larger than the maximum of %d bytes",
+			u.URL, maxBodySize)
 	}
 
 	metrics, err := Parse(body, resp.Header)
 	if err != nil {
-		return fmt.Errorf("error reading metrics for %s: %s",
+		return 0, fmt.Errorf("error reading metrics for %s: %s",
 			u.URL, err)
 	}
 
 	for _, metric := range metrics {
 		tags := metric.Tags()
-		// strip user and password from URL
-		u.OriginalURL.User = nil
-		tags["url"] = u.OriginalURL.String()
-		if u.Address != "" {
-			tags["address"] = u.Address
-		}
-		for k, v := range u.Tags {
+		for k, v := range targetTags(u) {
 			tags[k] = v
 		}
 
@@ -351,7 +394,53 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 		}
 	}
 
-	return nil
+	return len(metrics), nil
+}
+
+// maxBodySize returns the maximum size of the response body of the target.
+func (p *Prometheus) maxBodySize(u URLAndAddress) int64 {
+	size, ok := p.URLMaxBodySize[targetTags(u)["url"]]
+	if !ok {
+		size = p.MaxBodySize
+	}
+	if size.Size <= 0 {
+		return defaultMaxBodySize
+	}
+	return size.Size
+}
+
+// targetTags returns the tags identifying the scrape target.
+func targetTags(u URLAndAddress) map[string]string {
+	// strip user and password from URL
+	u.OriginalURL.User = nil
+	tags := map[string]string{"url": u.OriginalURL.String()}
+	if u.Address != "" {
+		tags["address"] = u.Address
+	}
+	for k, v := range u.Tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// addScrapeMeta adds the prometheus_scrape metric describing a scrape of the
+// target, similar to the up and scrape_* series added by Prometheus itself.
+func (p *Prometheus) addScrapeMeta(
+	u URLAndAddress,
+	up bool,
+	samples int,
+	duration time.Duration,
+	acc telegraf.Accumulator,
+) {
+	fields := map[string]interface{}{
+		"up":               0,
+		"duration_seconds": duration.Seconds(),
+		"samples_scraped":  samples,
+	}
+	if up {
+		fields["up"] = 1
+	}
+	acc.AddGauge("prometheus_scrape", fields, targetTags(u))
 }
 
 // Start will start the Kubernetes scraping if enabled in the configuration
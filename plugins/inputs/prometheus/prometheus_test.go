@@ -1,6 +1,8 @@
 package prometheus
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -105,6 +107,115 @@ func TestPrometheusGeneratesMetricsAlthoughFirstDNSFails(t *testing.T) {
 	assert.True(t, acc.HasTimestamp("test_metric", time.Unix(1490802350, 0)))
 }
 
+func TestPrometheusScrapeMeta(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		URLs:           []string{ts.URL},
+		EmitScrapeMeta: true,
+	}
+
+	var acc testutil.Accumulator
+
+	err := acc.GatherError(p.Gather)
+	require.NoError(t, err)
+
+	m, ok := acc.Get("prometheus_scrape")
+	require.True(t, ok)
+	assert.Equal(t, ts.URL+"/metrics", m.Tags["url"])
+	assert.Equal(t, 1, m.Fields["up"])
+	assert.Equal(t, 3, m.Fields["samples_scraped"])
+	assert.True(t, acc.HasFloatField("prometheus_scrape", "duration_seconds"))
+}
+
+func TestPrometheusScrapeMetaFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		URLs:           []string{ts.URL},
+		EmitScrapeMeta: true,
+	}
+
+	var acc testutil.Accumulator
+
+	err := acc.GatherError(p.Gather)
+	require.Error(t, err)
+
+	m, ok := acc.Get("prometheus_scrape")
+	require.True(t, ok)
+	assert.Equal(t, ts.URL+"/metrics", m.Tags["url"])
+	assert.Equal(t, 0, m.Fields["up"])
+	assert.Equal(t, 0, m.Fields["samples_scraped"])
+	assert.True(t, acc.HasFloatField("prometheus_scrape", "duration_seconds"))
+}
+
+func TestPrometheusMaxBodySize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		URLs:        []string{ts.URL},
+		MaxBodySize: internal.Size{Size: int64(len(sampleTextFormat))},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+	assert.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+
+	p.MaxBodySize.Size--
+	acc = testutil.Accumulator{}
+	err := acc.GatherError(p.Gather)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ts.URL+"/metrics returned a body larger than the maximum")
+	assert.Empty(t, acc.Metrics)
+
+	// the url limit overrides the default one
+	p.URLMaxBodySize = map[string]internal.Size{
+		ts.URL + "/metrics": {Size: int64(len(sampleTextFormat))},
+	}
+	acc = testutil.Accumulator{}
+	require.NoError(t, acc.GatherError(p.Gather))
+	assert.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+}
+
+func TestPrometheusMaxBodySizeGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(zw, "test_metric{label=\"%d\"} 1.0\n", i)
+	}
+	require.NoError(t, zw.Close())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	// the compressed body is under the limit, the decompressed one over it
+	p := &Prometheus{
+		URLs:        []string{ts.URL},
+		MaxBodySize: internal.Size{Size: 10000},
+	}
+	require.True(t, buf.Len() < 10000)
+	var acc testutil.Accumulator
+	err := acc.GatherError(p.Gather)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "returned a body larger than the maximum of 10000 bytes")
+
+	p.MaxBodySize.Size = 100000
+	acc = testutil.Accumulator{}
+	require.NoError(t, acc.GatherError(p.Gather))
+	assert.Len(t, acc.Metrics, 1000)
+}
+
 func TestPrometheusGathersMesosMetrics(t *testing.T) {
 	// The mock mesos server listens on 127.0.0.1
 	metricsUrl, _ := url.Parse("http://127.0.0.1:12345/metrics")
@@ -0,0 +1,318 @@
+package prometheus_pushgateway
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/prometheus"
+)
+
+// defaultMaxBodySize is the default maximum size of a request body, in bytes.
+const defaultMaxBodySize = 32 * 1024 * 1024
+
+// metricsPath is the prefix of the paths of the groups, followed by the job
+// and the other grouping labels: /metrics/job/<job>{/<label>/<value>}.
+const metricsPath = "/metrics/"
+
+type PrometheusPushgateway struct {
+	ServiceAddress string
+	ReadTimeout    internal.Duration
+	WriteTimeout   internal.Duration
+	MaxBodySize    internal.Size
+	Port           int
+
+	tlsint.ServerConfig
+
+	BasicUsername string
+	BasicPassword string
+
+	mu     sync.Mutex
+	groups map[string]*group
+
+	wg       sync.WaitGroup
+	listener net.Listener
+}
+
+// group holds the last pushed metrics of a group, by metric name.
+type group struct {
+	labels  map[string]string
+	metrics map[string][]telegraf.Metric
+}
+
+const sampleConfig = `
+  ## Address and port to receive the pushes on, set as the url of the
+  ## Pushgateway in the clients.
+  service_address = ":9091"
+
+  ## maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## maximum duration before timing out write of the response
+  # write_timeout = "10s"
+
+  ## Maximum allowed size of the request body in bytes.
+  # max_body_size = "32MB"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+
+  ## Optional username and password to accept for HTTP basic authentication.
+  ## You probably want to make sure you have TLS configured above for this.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+`
+
+func (p *PrometheusPushgateway) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusPushgateway) Description() string {
+	return "Receive metrics pushed with the API of the Prometheus Pushgateway"
+}
+
+// Gather adds the last pushed metrics of all the groups, tagged with the
+// grouping labels, like a Pushgateway serves them to each scrape.
+func (p *PrometheusPushgateway) Gather(acc telegraf.Accumulator) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, g := range p.groups {
+		for _, metrics := range g.metrics {
+			for _, m := range metrics {
+				tags := m.Tags()
+				for k, v := range g.labels {
+					tags[k] = v
+				}
+				switch m.Type() {
+				case telegraf.Counter:
+					acc.AddCounter(m.Name(), m.Fields(), tags, now)
+				case telegraf.Gauge:
+					acc.AddGauge(m.Name(), m.Fields(), tags, now)
+				case telegraf.Summary:
+					acc.AddSummary(m.Name(), m.Fields(), tags, now)
+				case telegraf.Histogram:
+					acc.AddHistogram(m.Name(), m.Fields(), tags, now)
+				default:
+					acc.AddFields(m.Name(), m.Fields(), tags, now)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Start starts the receiver.
+func (p *PrometheusPushgateway) Start(_ telegraf.Accumulator) error {
+	if p.MaxBodySize.Size == 0 {
+		p.MaxBodySize.Size = defaultMaxBodySize
+	}
+	if p.ReadTimeout.Duration < time.Second {
+		p.ReadTimeout.Duration = time.Second * 10
+	}
+	if p.WriteTimeout.Duration < time.Second {
+		p.WriteTimeout.Duration = time.Second * 10
+	}
+	p.groups = make(map[string]*group)
+
+	tlsConf, err := p.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         p.ServiceAddress,
+		Handler:      p,
+		ReadTimeout:  p.ReadTimeout.Duration,
+		WriteTimeout: p.WriteTimeout.Duration,
+		TLSConfig:    tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", p.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", p.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	p.listener = listener
+	p.Port = listener.Addr().(*net.TCPAddr).Port
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		server.Serve(p.listener)
+	}()
+
+	log.Printf("I! Started Prometheus Pushgateway receiver on %s\n", p.ServiceAddress)
+	return nil
+}
+
+// Stop cleans up all resources
+func (p *PrometheusPushgateway) Stop() {
+	p.listener.Close()
+	p.wg.Wait()
+
+	log.Println("I! Stopped Prometheus Pushgateway receiver on ", p.ServiceAddress)
+}
+
+func (p *PrometheusPushgateway) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.EscapedPath(), metricsPath) {
+		http.NotFound(res, req)
+		return
+	}
+	if !p.authenticated(req) {
+		http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	labels, err := groupingLabels(strings.TrimPrefix(req.URL.EscapedPath(), metricsPath))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := groupKey(labels)
+
+	if req.Method == http.MethodDelete {
+		p.mu.Lock()
+		delete(p.groups, key)
+		p.mu.Unlock()
+		res.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if req.Method != http.MethodPut && req.Method != http.MethodPost {
+		http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.ContentLength > p.MaxBodySize.Size {
+		http.Error(res, "Request body too large.", http.StatusRequestEntityTooLarge)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, p.MaxBodySize.Size))
+	if err != nil {
+		http.Error(res, "Request body too large.", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metrics, err := prometheus.Parse(body, req.Header)
+	if err != nil {
+		log.Printf("D! [inputs.prometheus_pushgateway] Rejected push from %s: %v",
+			req.RemoteAddr, err)
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pushed := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		pushed[m.Name()] = append(pushed[m.Name()], m)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	g, ok := p.groups[key]
+	if !ok || req.Method == http.MethodPut {
+		// PUT replaces all the metrics of the group
+		g = &group{labels: labels, metrics: make(map[string][]telegraf.Metric)}
+		p.groups[key] = g
+	}
+	// POST only replaces the metrics with the names pushed
+	for name, metrics := range pushed {
+		g.metrics[name] = metrics
+	}
+	res.WriteHeader(http.StatusOK)
+}
+
+// groupingLabels decodes the grouping labels of the escaped path, after the
+// metrics path.  The values of the labels suffixed with @base64 are encoded in
+// URL-safe base64, for the values holding a slash or empty.
+func groupingLabels(path string) (map[string]string, error) {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("odd number of grouping label path segments")
+	}
+
+	labels := make(map[string]string, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		name, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, err
+		}
+		value, err := url.PathUnescape(parts[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(name, "@base64") {
+			name = strings.TrimSuffix(name, "@base64")
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 value of label %q: %v", name, err)
+			}
+			value = string(decoded)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty grouping label name")
+		}
+		labels[name] = value
+	}
+	if labels["job"] == "" || !strings.HasPrefix(path, "job") {
+		return nil, fmt.Errorf("the path must start with a non-empty job label")
+	}
+	return labels, nil
+}
+
+// groupKey returns the key identifying the group of the labels.
+func groupKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key []byte
+	for _, name := range names {
+		key = append(key, name...)
+		key = append(key, 0)
+		key = append(key, labels[name]...)
+		key = append(key, 0)
+	}
+	return string(key)
+}
+
+func (p *PrometheusPushgateway) authenticated(req *http.Request) bool {
+	if p.BasicUsername == "" || p.BasicPassword == "" {
+		return true
+	}
+	username, password, ok := req.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(p.BasicUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(p.BasicPassword)) == 1
+}
+
+func init() {
+	inputs.Add("prometheus_pushgateway", func() telegraf.Input {
+		return &PrometheusPushgateway{
+			ServiceAddress: ":9091",
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package prometheus_pushgateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReceiver(t *testing.T) *PrometheusPushgateway {
+	p := &PrometheusPushgateway{
+		ServiceAddress: "localhost:0",
+	}
+	require.NoError(t, p.Start(&testutil.Accumulator{}))
+	return p
+}
+
+func push(t *testing.T, p *PrometheusPushgateway, method, path, body string) int {
+	return pushAuth(t, p, method, path, body, p.BasicUsername, p.BasicPassword)
+}
+
+func pushAuth(
+	t *testing.T,
+	p *PrometheusPushgateway,
+	method, path, body string,
+	username, password string,
+) int {
+	url := "http://localhost:" + strconv.Itoa(p.Port) + path
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func gather(t *testing.T, p *PrometheusPushgateway) *testutil.Accumulator {
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Gather(acc))
+	return acc
+}
+
+const batchMetrics = `# TYPE batch_duration_seconds gauge
+batch_duration_seconds 12.5
+# TYPE batch_records counter
+batch_records{table="users"} 1000
+batch_records{table="orders"} 50
+`
+
+func TestPushGroupingLabels(t *testing.T) {
+	p := newTestReceiver(t)
+	defer p.Stop()
+
+	require.Equal(t, http.StatusOK,
+		push(t, p, "PUT", "/metrics/job/backup/instance/db01", batchMetrics))
+
+	acc := gather(t, p)
+	require.Len(t, acc.Metrics, 3)
+	acc.AssertContainsTaggedFields(t, "batch_duration_seconds",
+		map[string]interface{}{"gauge": 12.5},
+		map[string]string{"job": "backup", "instance": "db01"})
+	acc.AssertContainsTaggedFields(t, "batch_records",
+		map[string]interface{}{"counter": 1000.0},
+		map[string]string{"job": "backup", "instance": "db01", "table": "users"})
+
+	// the last values are kept for each gather
+	acc = gather(t, p)
+	require.Len(t, acc.Metrics, 3)
+}
+
+func TestPushBase64Labels(t *testing.T) {
+	p := newTestReceiver(t)
+	defer p.Stop()
+
+	// "/var/tmp" and an empty value
+	require.Equal(t, http.StatusOK,
+		push(t, p, "POST", "/metrics/job/cleanup/path@base64/L3Zhci90bXA/dc@base64/=",
+			"# TYPE files_removed gauge\nfiles_removed 3\n"))
+
+	acc := gather(t, p)
+	acc.AssertContainsTaggedFields(t, "files_removed",
+		map[string]interface{}{"gauge": 3.0},
+		map[string]string{"job": "cleanup", "path": "/var/tmp", "dc": ""})
+}
+
+func TestPushPutReplacesGroup(t *testing.T) {
+	p := newTestReceiver(t)
+	defer p.Stop()
+
+	require.Equal(t, http.StatusOK,
+		push(t, p, "PUT", "/metrics/job/backup", batchMetrics))
+	require.Equal(t, http.StatusOK,
+		push(t, p, "PUT", "/metrics/job/backup",
+			"# TYPE batch_records counter\nbatch_records{table=\"users\"} 1200\n"))
+
+	// the metrics not pushed again are removed
+	acc := gather(t, p)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "batch_records",
+		map[string]interface{}{"counter": 1200.0},
+		map[string]string{"job": "backup", "table": "users"})
+}
+
+func TestPushPostMergesGroup(t *testing.T) {
+	p := newTestReceiver(t)
+	defer p.Stop()
+
+	require.Equal(t, http.StatusOK,
+		push(t, p, "PUT", "/metrics/job/backup", batchMetrics))
+	require.Equal(t, http.StatusOK,
+		push(t, p, "POST", "/metrics/job/backup",
+			"# TYPE batch_records counter\nbatch_records{table=\"users\"} 1200\n"))
+
+	// the metrics with the names pushed are replaced, the others kept
+	acc := gather(t, p)
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "batch_duration_seconds",
+		map[string]interface{}{"gauge": 12.5},
+		map[string]string{"job": "backup"})
+	acc.AssertContainsTaggedFields(t, "batch_records",
+		map[string]interface{}{"counter": 1200.0},
+		map[string]string{"job": "backup", "table": "users"})
+	acc.AssertDoesNotContainsTaggedFields(t, "batch_records",
+		map[string]interface{}{"counter": 50.0},
+		map[string]string{"job": "backup", "table": "orders"})
+}
+
+func TestPushSeparateGroups(t *testing.T) {
+	p := newTestReceiver(t)
+	defer p.Stop()
+
+	require.Equal(t, http.StatusOK,
+		push(t, p, "PUT", "/metrics/job/backup/instance/db01", batchMetrics))
+	require.Equal(t, http.StatusOK,
+		push(t, p, "PUT", "/metrics/job/backup/instance/db02",
+			"# TYPE batch_duration_seconds gauge\nbatch_duration_seconds 3\n"))
+
+	acc := gather(t, p)
+	require.Len(t, acc.Metrics, 4)
+
+	require.Equal(t, http.StatusAccepted,
+		push(t, p, "DELETE", "/metrics/job/backup/instance/db01", ""))
+	acc = gather(t, p)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "batch_duration_seconds",
+		map[string]interface{}{"gauge": 3.0},
+		map[string]string{"job": "backup", "instance": "db02"})
+}
+
+func TestPushInvalid(t *testing.T) {
+	p := newTestReceiver(t)
+	defer p.Stop()
+
+	require.Equal(t, http.StatusNotFound, push(t, p, "PUT", "/other/job/backup", batchMetrics))
+	require.Equal(t, http.StatusBadRequest, push(t, p, "PUT", "/metrics/instance/db01", batchMetrics))
+	require.Equal(t, http.StatusBadRequest, push(t, p, "PUT", "/metrics/job/backup/instance", batchMetrics))
+	require.Equal(t, http.StatusBadRequest, push(t, p, "PUT", "/metrics/job/backup", "not metrics{"))
+	require.Equal(t, http.StatusMethodNotAllowed, push(t, p, "GET", "/metrics/job/backup", ""))
+	require.Len(t, gather(t, p).Metrics, 0)
+}
+
+func TestPushBasicAuth(t *testing.T) {
+	p := &PrometheusPushgateway{
+		ServiceAddress: "localhost:0",
+		BasicUsername:  "user",
+		BasicPassword:  "secret",
+	}
+	require.NoError(t, p.Start(&testutil.Accumulator{}))
+	defer p.Stop()
+
+	require.Equal(t, http.StatusOK, push(t, p, "PUT", "/metrics/job/backup", batchMetrics))
+	require.Equal(t, http.StatusUnauthorized,
+		pushAuth(t, p, "PUT", "/metrics/job/backup", batchMetrics, "user", "wrong"))
+}
@@ -0,0 +1,263 @@
+package prometheus_remote_write
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// defaultMaxBodySize is the default maximum size of the compressed and of
+// the decompressed request body, in bytes.
+const defaultMaxBodySize = 32 * 1024 * 1024
+
+// nameLabel is the label holding the name of a time series.
+const nameLabel = "__name__"
+
+var errTooLarge = fmt.Errorf("decompressed request body too large")
+
+type PrometheusRemoteWrite struct {
+	ServiceAddress string
+	Path           string
+	ReadTimeout    internal.Duration
+	WriteTimeout   internal.Duration
+	MaxBodySize    internal.Size
+	Port           int
+
+	tlsint.ServerConfig
+
+	BasicUsername string
+	BasicPassword string
+
+	wg       sync.WaitGroup
+	listener net.Listener
+	acc      telegraf.Accumulator
+}
+
+const sampleConfig = `
+  ## Address and port to host the remote write receiver on
+  service_address = ":1234"
+
+  ## Path to listen to, set as the url of the remote_write section of
+  ## Prometheus.
+  # path = "/receive"
+
+  ## maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## maximum duration before timing out write of the response
+  # write_timeout = "10s"
+
+  ## Maximum allowed size of the request body in bytes, before and after
+  ## decompression.
+  # max_body_size = "32MB"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+
+  ## Optional username and password to accept for HTTP basic authentication.
+  ## You probably want to make sure you have TLS configured above for this.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+`
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Receive metrics sent by the remote write of Prometheus"
+}
+
+func (p *PrometheusRemoteWrite) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts the remote write receiver.
+func (p *PrometheusRemoteWrite) Start(acc telegraf.Accumulator) error {
+	if p.MaxBodySize.Size == 0 {
+		p.MaxBodySize.Size = defaultMaxBodySize
+	}
+	if p.ReadTimeout.Duration < time.Second {
+		p.ReadTimeout.Duration = time.Second * 10
+	}
+	if p.WriteTimeout.Duration < time.Second {
+		p.WriteTimeout.Duration = time.Second * 10
+	}
+
+	p.acc = acc
+
+	tlsConf, err := p.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         p.ServiceAddress,
+		Handler:      p,
+		ReadTimeout:  p.ReadTimeout.Duration,
+		WriteTimeout: p.WriteTimeout.Duration,
+		TLSConfig:    tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", p.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", p.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	p.listener = listener
+	p.Port = listener.Addr().(*net.TCPAddr).Port
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		server.Serve(p.listener)
+	}()
+
+	log.Printf("I! Started Prometheus remote write receiver on %s\n", p.ServiceAddress)
+	return nil
+}
+
+// Stop cleans up all resources
+func (p *PrometheusRemoteWrite) Stop() {
+	p.listener.Close()
+	p.wg.Wait()
+
+	log.Println("I! Stopped Prometheus remote write receiver on ", p.ServiceAddress)
+}
+
+func (p *PrometheusRemoteWrite) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != p.Path {
+		http.NotFound(res, req)
+		return
+	}
+	if !p.authenticated(req) {
+		http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.ContentLength > p.MaxBodySize.Size {
+		http.Error(res, "Request body too large.", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := http.MaxBytesReader(res, req.Body, p.MaxBodySize.Size)
+	compressed, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(res, "Request body too large.", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metrics, err := p.decode(compressed)
+	if err != nil {
+		log.Printf("D! [inputs.prometheus_remote_write] Rejected request from %s: %v",
+			req.RemoteAddr, err)
+		if err == errTooLarge {
+			http.Error(res, "Request body too large.", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	for _, m := range metrics {
+		p.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// decode decompresses and decodes a WriteRequest.  The name label of each
+// time series is the measurement, the other labels are tags and each sample
+// is a metric with a value field.  Stale markers and other NaN values are
+// skipped.
+func (p *PrometheusRemoteWrite) decode(compressed []byte) ([]telegraf.Metric, error) {
+	n, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy body: %v", err)
+	}
+	if int64(n) > p.MaxBodySize.Size {
+		return nil, errTooLarge
+	}
+	buf, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy body: %v", err)
+	}
+
+	series, err := decodeWriteRequest(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []telegraf.Metric
+	for _, ts := range series {
+		var name string
+		tags := make(map[string]string, len(ts.labels))
+		for _, l := range ts.labels {
+			if l.name == nameLabel {
+				name = l.value
+				continue
+			}
+			tags[l.name] = l.value
+		}
+		if name == "" {
+			return nil, fmt.Errorf("time series without %s label", nameLabel)
+		}
+
+		for _, s := range ts.samples {
+			if math.IsNaN(s.value) {
+				continue
+			}
+			m, err := metric.New(name, tags,
+				map[string]interface{}{"value": s.value},
+				time.Unix(0, s.timestamp*int64(time.Millisecond)))
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *PrometheusRemoteWrite) authenticated(req *http.Request) bool {
+	if p.BasicUsername == "" || p.BasicPassword == "" {
+		return true
+	}
+	username, password, ok := req.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(p.BasicUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(p.BasicPassword)) == 1
+}
+
+func init() {
+	inputs.Add("prometheus_remote_write", func() telegraf.Input {
+		return &PrometheusRemoteWrite{
+			ServiceAddress: ":1234",
+			Path:           "/receive",
+		}
+	})
+}
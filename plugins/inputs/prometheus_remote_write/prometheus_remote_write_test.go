@@ -0,0 +1,158 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReceiver() *PrometheusRemoteWrite {
+	return &PrometheusRemoteWrite{
+		ServiceAddress: "localhost:0",
+		Path:           "/receive",
+	}
+}
+
+func readWriteRequest(t *testing.T) []byte {
+	buf, err := ioutil.ReadFile("testdata/write_request.pb")
+	require.NoError(t, err)
+	return snappy.Encode(nil, buf)
+}
+
+func post(t *testing.T, p *PrometheusRemoteWrite, body []byte) *http.Response {
+	return postAuth(t, p, body, p.BasicUsername, p.BasicPassword)
+}
+
+func postAuth(
+	t *testing.T,
+	p *PrometheusRemoteWrite,
+	body []byte,
+	username, password string,
+) *http.Response {
+	url := "http://localhost:" + strconv.Itoa(p.Port) + "/receive"
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestWriteRequest(t *testing.T) {
+	p := newTestReceiver()
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	resp := post(t, p, readWriteRequest(t))
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	acc.Wait(3)
+	tags := map[string]string{
+		"code":     "200",
+		"instance": "localhost:9090",
+		"job":      "prometheus",
+	}
+	// The stale marker of the up series is skipped.
+	require.Equal(t, []*testutil.Metric{
+		{
+			Measurement: "http_requests_total",
+			Tags:        tags,
+			Fields:      map[string]interface{}{"value": 1027.0},
+			Time:        time.Unix(1530000000, 0),
+		},
+		{
+			Measurement: "http_requests_total",
+			Tags:        tags,
+			Fields:      map[string]interface{}{"value": 1029.0},
+			Time:        time.Unix(1530000015, 0),
+		},
+		{
+			Measurement: "up",
+			Tags:        map[string]string{"instance": "localhost:9090", "job": "prometheus"},
+			Fields:      map[string]interface{}{"value": 1.0},
+			Time:        time.Unix(1530000000, 0),
+		},
+	}, acc.Metrics)
+}
+
+func TestBasicAuth(t *testing.T) {
+	p := newTestReceiver()
+	p.BasicUsername = "prometheus"
+	p.BasicPassword = "secret"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	require.Equal(t, http.StatusNoContent, post(t, p, readWriteRequest(t)).StatusCode)
+
+	require.Equal(t, http.StatusUnauthorized,
+		postAuth(t, p, readWriteRequest(t), "prometheus", "wrong").StatusCode)
+	require.Equal(t, http.StatusUnauthorized,
+		postAuth(t, p, readWriteRequest(t), "", "").StatusCode)
+}
+
+func TestMalformed(t *testing.T) {
+	p := newTestReceiver()
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	// not snappy compressed
+	buf, err := ioutil.ReadFile("testdata/write_request.pb")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, post(t, p, buf).StatusCode)
+
+	// truncated protobuf
+	require.Equal(t, http.StatusBadRequest,
+		post(t, p, snappy.Encode(nil, buf[:len(buf)-10])).StatusCode)
+
+	// time series without a name
+	noName := []byte{0x0a, 0x0b, 0x0a, 0x09, 0x0a, 0x03, 'j', 'o', 'b', 0x12, 0x02, 'n', 'o'}
+	require.Equal(t, http.StatusBadRequest,
+		post(t, p, snappy.Encode(nil, noName)).StatusCode)
+
+	require.Len(t, acc.Metrics, 0)
+}
+
+func TestTooLarge(t *testing.T) {
+	p := newTestReceiver()
+	p.MaxBodySize = internal.Size{Size: 100}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	// The compressed request is small, but not once decompressed.
+	body := snappy.Encode(nil, make([]byte, 1000))
+	require.True(t, len(body) < 100)
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(t, p, body).StatusCode)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge,
+		post(t, p, make([]byte, 200)).StatusCode)
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	p := newTestReceiver()
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	resp, err := http.Get("http://localhost:" + strconv.Itoa(p.Port) + "/receive")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,233 @@
+package prometheus_remote_write
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// The messages of a remote write request, see prompb/remote.proto and
+// prompb/types.proto of Prometheus:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+//
+// Unknown fields, like the metadata of newer versions, are skipped.
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errInvalidProtobuf = errors.New("invalid protobuf message")
+
+// protoReader reads the fields of a protobuf message.
+type protoReader struct {
+	buf []byte
+}
+
+func (r *protoReader) done() bool {
+	return len(r.buf) == 0
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errInvalidProtobuf
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+// field reads the key of the next field, returning its number and wire
+// type.
+func (r *protoReader) field() (int, int, error) {
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(key >> 3), int(key & 0x7), nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.buf)) {
+		return nil, errInvalidProtobuf
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *protoReader) fixed64() (uint64, error) {
+	if len(r.buf) < 8 {
+		return 0, errInvalidProtobuf
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v, nil
+}
+
+// skip skips the value of a field of the wire type.
+func (r *protoReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		if len(r.buf) < 4 {
+			return errInvalidProtobuf
+		}
+		r.buf = r.buf[4:]
+	default:
+		return errInvalidProtobuf
+	}
+	return err
+}
+
+// decodeWriteRequest decodes the time series of a WriteRequest.
+func decodeWriteRequest(buf []byte) ([]timeSeries, error) {
+	var series []timeSeries
+	r := protoReader{buf: buf}
+	for !r.done() {
+		num, wireType, err := r.field()
+		if err != nil {
+			return nil, err
+		}
+		if num != 1 || wireType != wireBytes {
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		msg, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		ts, err := decodeTimeSeries(msg)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, ts)
+	}
+	return series, nil
+}
+
+func decodeTimeSeries(buf []byte) (timeSeries, error) {
+	var ts timeSeries
+	r := protoReader{buf: buf}
+	for !r.done() {
+		num, wireType, err := r.field()
+		if err != nil {
+			return ts, err
+		}
+		if (num != 1 && num != 2) || wireType != wireBytes {
+			if err := r.skip(wireType); err != nil {
+				return ts, err
+			}
+			continue
+		}
+		msg, err := r.bytes()
+		if err != nil {
+			return ts, err
+		}
+		if num == 1 {
+			l, err := decodeLabel(msg)
+			if err != nil {
+				return ts, err
+			}
+			ts.labels = append(ts.labels, l)
+		} else {
+			s, err := decodeSample(msg)
+			if err != nil {
+				return ts, err
+			}
+			ts.samples = append(ts.samples, s)
+		}
+	}
+	return ts, nil
+}
+
+func decodeLabel(buf []byte) (label, error) {
+	var l label
+	r := protoReader{buf: buf}
+	for !r.done() {
+		num, wireType, err := r.field()
+		if err != nil {
+			return l, err
+		}
+		if (num != 1 && num != 2) || wireType != wireBytes {
+			if err := r.skip(wireType); err != nil {
+				return l, err
+			}
+			continue
+		}
+		b, err := r.bytes()
+		if err != nil {
+			return l, err
+		}
+		if num == 1 {
+			l.name = string(b)
+		} else {
+			l.value = string(b)
+		}
+	}
+	return l, nil
+}
+
+func decodeSample(buf []byte) (sample, error) {
+	var s sample
+	r := protoReader{buf: buf}
+	for !r.done() {
+		num, wireType, err := r.field()
+		if err != nil {
+			return s, err
+		}
+		switch {
+		case num == 1 && wireType == wireFixed64:
+			v, err := r.fixed64()
+			if err != nil {
+				return s, err
+			}
+			s.value = math.Float64frombits(v)
+		case num == 2 && wireType == wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return s, err
+			}
+			s.timestamp = int64(v)
+		default:
+			if err := r.skip(wireType); err != nil {
+				return s, err
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,277 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type Proxmox struct {
+	BaseURL   string            `toml:"base_url"`
+	APIToken  string            `toml:"api_token"`
+	NodeNames []string          `toml:"node_names"`
+	Timeout   internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## URL of the API of one of the nodes of the cluster, the resources of all
+  ## the nodes are gathered from it.
+  base_url = "https://localhost:8006/api2/json"
+
+  ## API token, as USER@REALM!TOKENID=SECRET.  The token needs the
+  ## Sys.Audit privilege on the nodes and VM.Audit on the guests.
+  api_token = "telegraf@pve!monitoring=00000000-0000-0000-0000-000000000000"
+
+  ## Only gather the resources of these nodes, all the nodes of the cluster
+  ## if empty.
+  # node_names = []
+
+  ## Amount of time allowed to complete the HTTP requests.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification, for the self-signed
+  ## certificates of the default installation.
+  # insecure_skip_verify = false
+`
+
+// guestTypes are the types of the guests, by API path.
+var guestTypes = []string{"qemu", "lxc"}
+
+// node is an element of the /nodes response.
+type node struct {
+	Node    string  `json:"node"`
+	Status  string  `json:"status"`
+	CPU     float64 `json:"cpu"`
+	MaxCPU  int64   `json:"maxcpu"`
+	Mem     int64   `json:"mem"`
+	MaxMem  int64   `json:"maxmem"`
+	Disk    int64   `json:"disk"`
+	MaxDisk int64   `json:"maxdisk"`
+	Uptime  int64   `json:"uptime"`
+}
+
+// guest is an element of the /nodes/{node}/qemu and /nodes/{node}/lxc
+// responses.
+type guest struct {
+	VMID      vmID    `json:"vmid"`
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	CPU       float64 `json:"cpu"`
+	CPUs      float64 `json:"cpus"`
+	Mem       int64   `json:"mem"`
+	MaxMem    int64   `json:"maxmem"`
+	Disk      int64   `json:"disk"`
+	MaxDisk   int64   `json:"maxdisk"`
+	DiskRead  int64   `json:"diskread"`
+	DiskWrite int64   `json:"diskwrite"`
+	NetIn     int64   `json:"netin"`
+	NetOut    int64   `json:"netout"`
+	Uptime    int64   `json:"uptime"`
+}
+
+// vmID is the ID of a guest, a number for the VMs but a string for the
+// containers in some versions.
+type vmID string
+
+func (id *vmID) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	*id = vmID(s)
+	return nil
+}
+
+func (p *Proxmox) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Proxmox) Description() string {
+	return "Gather the resource usage of the nodes and guests of Proxmox VE"
+}
+
+func (p *Proxmox) Init() error {
+	if p.BaseURL == "" {
+		return errors.New("base_url must be set")
+	}
+	if p.APIToken == "" {
+		return errors.New("api_token must be set")
+	}
+	p.BaseURL = strings.TrimRight(p.BaseURL, "/")
+
+	tlsCfg, err := p.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	p.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: p.Timeout.Duration,
+	}
+	return nil
+}
+
+func (p *Proxmox) Gather(acc telegraf.Accumulator) error {
+	var nodes []node
+	if err := p.get("/nodes", &nodes); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		if !p.selected(n.Node) {
+			continue
+		}
+		gatherNode(acc, n)
+
+		// the API of the offline nodes times out
+		if n.Status != "online" {
+			log.Printf("W! [inputs.proxmox] skipping the guests of node %s: %s", n.Node, n.Status)
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			p.gatherGuests(acc, name)
+		}(n.Node)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (p *Proxmox) selected(name string) bool {
+	if len(p.NodeNames) == 0 {
+		return true
+	}
+	for _, n := range p.NodeNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherNode gathers the resources of the node, only the uptime for the nodes
+// offline.
+func gatherNode(acc telegraf.Accumulator, n node) {
+	tags := map[string]string{
+		"node":   n.Node,
+		"status": n.Status,
+	}
+	if n.Status != "online" {
+		acc.AddFields("proxmox_node", map[string]interface{}{"uptime": n.Uptime}, tags)
+		return
+	}
+
+	fields := map[string]interface{}{
+		"cpu_usage":  n.CPU * 100,
+		"cpus":       n.MaxCPU,
+		"mem_used":   n.Mem,
+		"mem_total":  n.MaxMem,
+		"disk_used":  n.Disk,
+		"disk_total": n.MaxDisk,
+		"uptime":     n.Uptime,
+	}
+	acc.AddFields("proxmox_node", fields, tags)
+}
+
+// gatherGuests gathers the VMs and containers of the node.  The nodes not
+// answering, like the ones unreachable from the node of the API, are skipped
+// with a warning.
+func (p *Proxmox) gatherGuests(acc telegraf.Accumulator, nodeName string) {
+	for _, guestType := range guestTypes {
+		var guests []guest
+		path := "/nodes/" + url.PathEscape(nodeName) + "/" + guestType
+		if err := p.get(path, &guests); err != nil {
+			log.Printf("W! [inputs.proxmox] skipping the %s guests of node %s: %v", guestType, nodeName, err)
+			continue
+		}
+
+		for _, g := range guests {
+			fields := map[string]interface{}{
+				"cpu_usage":  g.CPU * 100,
+				"cpus":       g.CPUs,
+				"mem_used":   g.Mem,
+				"mem_total":  g.MaxMem,
+				"disk_used":  g.Disk,
+				"disk_total": g.MaxDisk,
+				"disk_read":  g.DiskRead,
+				"disk_write": g.DiskWrite,
+				"net_in":     g.NetIn,
+				"net_out":    g.NetOut,
+				"uptime":     g.Uptime,
+			}
+			tags := map[string]string{
+				"node":   nodeName,
+				"vmid":   string(g.VMID),
+				"type":   guestType,
+				"status": g.Status,
+			}
+			if g.Name != "" {
+				tags["name"] = g.Name
+			}
+			acc.AddFields("proxmox_guest", fields, tags)
+		}
+	}
+}
+
+// get decodes the data of the response of the API.
+func (p *Proxmox) get(path string, data interface{}) error {
+	req, err := http.NewRequest("GET", p.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "PVEAPIToken="+p.APIToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// the reason of the errors is in the status line
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned %s: %s", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	result := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid response of %s: %v", path, err)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("proxmox", func() telegraf.Input {
+		return &Proxmox{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
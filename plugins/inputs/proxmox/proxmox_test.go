@@ -0,0 +1,166 @@
+package proxmox
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const token = "telegraf@pve!monitoring=secret"
+
+// newServer returns an API serving the recorded responses of node pve1, node
+// pve2 being unreachable.
+func newServer(t *testing.T) *httptest.Server {
+	responses := map[string]string{
+		"/api2/json/nodes":           "testdata/nodes.json",
+		"/api2/json/nodes/pve1/qemu": "testdata/qemu.json",
+		"/api2/json/nodes/pve1/lxc":  "testdata/lxc.json",
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "PVEAPIToken="+token {
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+		file, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(595)
+			return
+		}
+		body, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+}
+
+func newProxmox(t *testing.T, url string) *Proxmox {
+	p := &Proxmox{
+		BaseURL:  url + "/api2/json/",
+		APIToken: token,
+	}
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestGather(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	// the guests of the unreachable and offline nodes are skipped
+	var acc testutil.Accumulator
+	require.NoError(t, newProxmox(t, ts.URL).Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("proxmox_node",
+			map[string]string{"node": "pve1", "status": "online"},
+			map[string]interface{}{
+				"cpu_usage":  12.5,
+				"cpus":       int64(8),
+				"mem_used":   int64(6442450944),
+				"mem_total":  int64(33554432000),
+				"disk_used":  int64(10737418240),
+				"disk_total": int64(100000000000),
+				"uptime":     int64(86400),
+			}, time.Unix(0, 0)),
+		testutil.MustMetric("proxmox_node",
+			map[string]string{"node": "pve2", "status": "online"},
+			map[string]interface{}{
+				"cpu_usage":  50.0,
+				"cpus":       int64(4),
+				"mem_used":   int64(1073741824),
+				"mem_total":  int64(8589934592),
+				"disk_used":  int64(5368709120),
+				"disk_total": int64(50000000000),
+				"uptime":     int64(3600),
+			}, time.Unix(0, 0)),
+		testutil.MustMetric("proxmox_node",
+			map[string]string{"node": "pve3", "status": "offline"},
+			map[string]interface{}{"uptime": int64(0)}, time.Unix(0, 0)),
+		testutil.MustMetric("proxmox_guest",
+			map[string]string{"node": "pve1", "vmid": "100", "type": "qemu", "name": "web", "status": "running"},
+			map[string]interface{}{
+				"cpu_usage":  25.0,
+				"cpus":       2.0,
+				"mem_used":   int64(1073741824),
+				"mem_total":  int64(2147483648),
+				"disk_used":  int64(0),
+				"disk_total": int64(34359738368),
+				"disk_read":  int64(1048576),
+				"disk_write": int64(2097152),
+				"net_in":     int64(123456),
+				"net_out":    int64(654321),
+				"uptime":     int64(7200),
+			}, time.Unix(0, 0)),
+		testutil.MustMetric("proxmox_guest",
+			map[string]string{"node": "pve1", "vmid": "101", "type": "qemu", "name": "db", "status": "stopped"},
+			map[string]interface{}{
+				"cpu_usage":  0.0,
+				"cpus":       4.0,
+				"mem_used":   int64(0),
+				"mem_total":  int64(8589934592),
+				"disk_used":  int64(0),
+				"disk_total": int64(68719476736),
+				"disk_read":  int64(0),
+				"disk_write": int64(0),
+				"net_in":     int64(0),
+				"net_out":    int64(0),
+				"uptime":     int64(0),
+			}, time.Unix(0, 0)),
+		testutil.MustMetric("proxmox_guest",
+			map[string]string{"node": "pve1", "vmid": "200", "type": "lxc", "name": "dns", "status": "running"},
+			map[string]interface{}{
+				"cpu_usage":  1.0,
+				"cpus":       1.0,
+				"mem_used":   int64(134217728),
+				"mem_total":  int64(536870912),
+				"disk_used":  int64(1073741824),
+				"disk_total": int64(8589934592),
+				"disk_read":  int64(4096),
+				"disk_write": int64(8192),
+				"net_in":     int64(1000),
+				"net_out":    int64(2000),
+				"uptime":     int64(600),
+			}, time.Unix(0, 0)),
+	}
+	require.Len(t, acc.Metrics, len(expected))
+	for _, m := range expected {
+		acc.AssertContainsTaggedFields(t, m.Name(), m.Fields(), m.Tags())
+	}
+}
+
+func TestGatherNodeNames(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	p := newProxmox(t, ts.URL)
+	p.NodeNames = []string{"pve2"}
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "pve2", acc.Metrics[0].Tags["node"])
+}
+
+func TestGatherUnauthorized(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	p := newProxmox(t, ts.URL)
+	p.APIToken = "telegraf@pve!monitoring=invalid"
+	var acc testutil.Accumulator
+	err := p.Gather(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "401 Unauthorized")
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&Proxmox{APIToken: token}).Init())
+	require.Error(t, (&Proxmox{BaseURL: "https://localhost:8006/api2/json"}).Init())
+}
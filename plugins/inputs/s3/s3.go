@@ -0,0 +1,357 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/influxdata/telegraf"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// maxMissed is the number of gathers in a row an object may be missing from
+// the listing before it is forgotten.  Listings are eventually consistent,
+// so an object may be left out of one listing and be back in the next.
+const maxMissed = 3
+
+const defaultMaxConcurrentDownloads = 4
+
+type S3 struct {
+	Region      string `toml:"region"`
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	Token       string `toml:"token"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	ForcePathStyle         bool   `toml:"force_path_style"`
+	Bucket                 string `toml:"bucket"`
+	Prefix                 string `toml:"prefix"`
+	DeleteAfterRead        bool   `toml:"delete_after_read"`
+	ProcessedTag           string `toml:"processed_tag"`
+	MaxConcurrentDownloads int    `toml:"max_concurrent_downloads"`
+
+	client s3Client
+	parser parsers.Parser
+	// parseMu serializes the use of the parser by the downloads
+	parseMu sync.Mutex
+
+	mu sync.Mutex
+	// objects are the objects read so far, by key
+	objects map[string]*object
+}
+
+type object struct {
+	etag   string
+	missed int
+}
+
+type s3Client interface {
+	ListObjectsV2Pages(*s3.ListObjectsV2Input, func(*s3.ListObjectsV2Output, bool) bool) error
+	GetObject(*s3.GetObjectInput) (*s3.GetObjectOutput, error)
+	DeleteObject(*s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error)
+	GetObjectTagging(*s3.GetObjectTaggingInput) (*s3.GetObjectTaggingOutput, error)
+	PutObjectTagging(*s3.PutObjectTaggingInput) (*s3.PutObjectTaggingOutput, error)
+}
+
+const sampleConfig = `
+  ## Amazon Region
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:9000"
+  # endpoint_url = ""
+
+  ## Address the bucket in the path of the URL rather than in the host name,
+  ## needed by most S3 compatible services.
+  # force_path_style = false
+
+  ## Bucket and prefix of the objects to read.
+  bucket = "my-bucket"
+  # prefix = "metrics/"
+
+  ## Objects are read once, new objects and objects with a new ETag are read
+  ## on the next interval.  These are remembered while Telegraf is running,
+  ## the objects read can also be deleted, or tagged with processed_tag set to
+  ## true and skipped when Telegraf is restarted.
+  # delete_after_read = false
+  # processed_tag = "telegraf-processed"
+
+  ## Maximum number of objects downloaded at the same time.
+  # max_concurrent_downloads = 4
+
+  ## Data format of the objects, gzip compressed objects are decompressed.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+func (s *S3) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *S3) Description() string {
+	return "Read metrics from new objects in an Amazon S3 bucket"
+}
+
+func (s *S3) SetParser(parser parsers.Parser) {
+	s.parser = parser
+}
+
+func (s *S3) Init() error {
+	if s.Bucket == "" {
+		return fmt.Errorf("bucket must be set")
+	}
+	if s.MaxConcurrentDownloads <= 0 {
+		s.MaxConcurrentDownloads = defaultMaxConcurrentDownloads
+	}
+	s.objects = make(map[string]*object)
+	return nil
+}
+
+func (s *S3) initializeS3() {
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      s.Region,
+		AccessKey:   s.AccessKey,
+		SecretKey:   s.SecretKey,
+		RoleARN:     s.RoleARN,
+		Profile:     s.Profile,
+		Filename:    s.Filename,
+		Token:       s.Token,
+		EndpointURL: s.EndpointURL,
+	}
+	configProvider := credentialConfig.Credentials()
+
+	s.client = s3.New(configProvider, &aws.Config{
+		S3ForcePathStyle: aws.Bool(s.ForcePathStyle),
+	})
+}
+
+func (s *S3) Gather(acc telegraf.Accumulator) error {
+	if s.client == nil {
+		s.initializeS3()
+	}
+
+	objs, err := s.newObjects()
+	if err != nil {
+		return fmt.Errorf("could not list objects in %s: %v", s.Bucket, err)
+	}
+
+	objC := make(chan *s3.Object)
+	var wg sync.WaitGroup
+	for i := 0; i < s.MaxConcurrentDownloads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range objC {
+				if err := s.readObject(acc, obj); err != nil {
+					acc.AddError(fmt.Errorf("object %s in %s: %v",
+						aws.StringValue(obj.Key), s.Bucket, err))
+				}
+			}
+		}()
+	}
+	for _, obj := range objs {
+		objC <- obj
+	}
+	close(objC)
+	wg.Wait()
+	return nil
+}
+
+// newObjects lists the bucket and returns the objects that were not read
+// yet, or have changed since.
+func (s *S3) newObjects() ([]*s3.Object, error) {
+	listed := make(map[string]bool)
+	var objs []*s3.Object
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(s.Prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			// skip the placeholders of folders, and duplicates
+			if strings.HasSuffix(key, "/") || listed[key] {
+				continue
+			}
+			listed[key] = true
+
+			s.mu.Lock()
+			seen, ok := s.objects[key]
+			s.mu.Unlock()
+			if !ok || seen.etag != aws.StringValue(obj.ETag) {
+				objs = append(objs, obj)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, seen := range s.objects {
+		if listed[key] {
+			seen.missed = 0
+			continue
+		}
+		seen.missed++
+		if seen.missed >= maxMissed {
+			delete(s.objects, key)
+		}
+	}
+	return objs, nil
+}
+
+func (s *S3) readObject(acc telegraf.Accumulator, obj *s3.Object) error {
+	key := aws.StringValue(obj.Key)
+	var tags []*s3.Tag
+	if s.ProcessedTag != "" {
+		out, err := s.client.GetObjectTagging(&s3.GetObjectTaggingInput{
+			Bucket: aws.String(s.Bucket),
+			Key:    aws.String(key),
+		})
+		if isNotFound(err) {
+			s.setRead(key, aws.StringValue(obj.ETag))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		tags = out.TagSet
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) == s.ProcessedTag {
+				// read before Telegraf was restarted
+				s.setRead(key, aws.StringValue(obj.ETag))
+				return nil
+			}
+		}
+	}
+
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		// deleted since it was listed
+		s.setRead(key, aws.StringValue(obj.ETag))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	body, err := readBody(out.Body)
+	out.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	// parse errors are not retried, the object is only read again if it
+	// changes
+	s.setRead(key, aws.StringValue(out.ETag))
+
+	s.parseMu.Lock()
+	metrics, err := s.parser.Parse(body)
+	s.parseMu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+
+	if s.DeleteAfterRead {
+		_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(s.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("could not delete: %v", err)
+		}
+	} else if s.ProcessedTag != "" {
+		tags = append(tags, &s3.Tag{
+			Key:   aws.String(s.ProcessedTag),
+			Value: aws.String("true"),
+		})
+		_, err := s.client.PutObjectTagging(&s3.PutObjectTaggingInput{
+			Bucket:  aws.String(s.Bucket),
+			Key:     aws.String(key),
+			Tagging: &s3.Tagging{TagSet: tags},
+		})
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("could not tag as processed: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *S3) setRead(key, etag string) {
+	s.mu.Lock()
+	s.objects[key] = &object{etag: etag}
+	s.mu.Unlock()
+}
+
+// readBody reads the body of an object, decompressing it if it starts with
+// the gzip magic bytes.
+func readBody(r io.Reader) ([]byte, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		return body, nil
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	return ioutil.ReadAll(gr)
+}
+
+func isNotFound(err error) bool {
+	if err, ok := err.(awserr.Error); ok {
+		return err.Code() == s3.ErrCodeNoSuchKey
+	}
+	return false
+}
+
+func init() {
+	inputs.Add("s3", func() telegraf.Input {
+		return &S3{
+			MaxConcurrentDownloads: defaultMaxConcurrentDownloads,
+		}
+	})
+}
@@ -0,0 +1,275 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockObject struct {
+	body []byte
+	etag string
+	tags []*s3.Tag
+}
+
+// mockS3 is a bucket listed two objects per page.
+type mockS3 struct {
+	sync.Mutex
+	objects map[string]*mockObject
+	// duplicate is listed a second time on the last page
+	duplicate string
+	gets      []string
+}
+
+func newMockS3() *mockS3 {
+	return &mockS3{objects: make(map[string]*mockObject)}
+}
+
+func (m *mockS3) put(key string, body []byte) {
+	m.Lock()
+	defer m.Unlock()
+	etag := "1"
+	if obj, ok := m.objects[key]; ok {
+		etag = obj.etag + "1"
+	}
+	m.objects[key] = &mockObject{body: body, etag: etag}
+}
+
+func (m *mockS3) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	m.Lock()
+	var keys []string
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var contents []*s3.Object
+	for _, key := range keys {
+		contents = append(contents, &s3.Object{
+			Key:  aws.String(key),
+			ETag: aws.String(m.objects[key].etag),
+		})
+	}
+	if obj, ok := m.objects[m.duplicate]; ok {
+		contents = append(contents, &s3.Object{
+			Key:  aws.String(m.duplicate),
+			ETag: aws.String(obj.etag),
+		})
+	}
+	m.Unlock()
+
+	for i := 0; i < len(contents); i += 2 {
+		end := i + 2
+		if end > len(contents) {
+			end = len(contents)
+		}
+		if !fn(&s3.ListObjectsV2Output{Contents: contents[i:end]}, end == len(contents)) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockS3) object(key *string) (*mockObject, error) {
+	obj, ok := m.objects[aws.StringValue(key)]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)
+	}
+	return obj, nil
+}
+
+func (m *mockS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	obj, err := m.object(input.Key)
+	if err != nil {
+		return nil, err
+	}
+	m.gets = append(m.gets, aws.StringValue(input.Key))
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewReader(obj.body)),
+		ETag: aws.String(obj.etag),
+	}, nil
+}
+
+func (m *mockS3) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.objects, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *mockS3) GetObjectTagging(input *s3.GetObjectTaggingInput) (*s3.GetObjectTaggingOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	obj, err := m.object(input.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.GetObjectTaggingOutput{TagSet: obj.tags}, nil
+}
+
+func (m *mockS3) PutObjectTagging(input *s3.PutObjectTaggingInput) (*s3.PutObjectTaggingOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	obj, err := m.object(input.Key)
+	if err != nil {
+		return nil, err
+	}
+	obj.tags = input.Tagging.TagSet
+	return &s3.PutObjectTaggingOutput{}, nil
+}
+
+func (m *mockS3) Gets() []string {
+	m.Lock()
+	defer m.Unlock()
+	gets := append([]string(nil), m.gets...)
+	m.gets = nil
+	sort.Strings(gets)
+	return gets
+}
+
+func newTestS3(t *testing.T, client *mockS3) *S3 {
+	plugin := &S3{
+		Bucket:                 "bucket",
+		MaxConcurrentDownloads: 2,
+		client:                 client,
+	}
+	require.NoError(t, plugin.Init())
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	plugin.SetParser(parser)
+	return plugin
+}
+
+func gzipped(t *testing.T, body string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestGatherOnlyNewObjects(t *testing.T) {
+	client := newMockS3()
+	for i := 0; i < 5; i++ {
+		client.put(fmt.Sprintf("metrics/%d", i), []byte(fmt.Sprintf("cpu value=%d 0\n", i)))
+	}
+	client.put("metrics/", nil)
+	client.duplicate = "metrics/4"
+	plugin := newTestS3(t, client)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, []string{"metrics/0", "metrics/1", "metrics/2", "metrics/3", "metrics/4"}, client.Gets())
+	require.Len(t, acc.Metrics, 5)
+
+	// nothing changed
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, client.Gets())
+	require.Empty(t, acc.Metrics)
+
+	// a new object and an overwritten one
+	client.put("metrics/5", []byte("cpu value=5 0\n"))
+	client.put("metrics/0", []byte("cpu value=10 0\n"))
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, []string{"metrics/0", "metrics/5"}, client.Gets())
+	var values []float64
+	for _, m := range acc.Metrics {
+		values = append(values, m.Fields["value"].(float64))
+	}
+	require.ElementsMatch(t, []float64{5, 10}, values)
+}
+
+func TestGatherGzip(t *testing.T) {
+	client := newMockS3()
+	client.put("metrics.gz", gzipped(t, "cpu value=42 0\n"))
+	client.put("invalid.gz", []byte{0x1f, 0x8b, 0x00})
+	plugin := newTestS3(t, client)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "object invalid.gz in bucket")
+	acc.AssertContainsFields(t, "cpu", map[string]interface{}{"value": float64(42)})
+}
+
+func TestGatherDeleteAfterRead(t *testing.T) {
+	client := newMockS3()
+	client.put("a", []byte("cpu value=1 0\n"))
+	client.put("b", []byte("cpu value=2 0\n"))
+	plugin := newTestS3(t, client)
+	plugin.DeleteAfterRead = true
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+	require.Empty(t, client.objects)
+}
+
+func TestGatherProcessedTag(t *testing.T) {
+	client := newMockS3()
+	client.put("a", []byte("cpu value=1 0\n"))
+	client.objects["a"].tags = []*s3.Tag{{Key: aws.String("owner"), Value: aws.String("me")}}
+	plugin := newTestS3(t, client)
+	plugin.ProcessedTag = "telegraf-processed"
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, []*s3.Tag{
+		{Key: aws.String("owner"), Value: aws.String("me")},
+		{Key: aws.String("telegraf-processed"), Value: aws.String("true")},
+	}, client.objects["a"].tags)
+
+	// a restarted plugin skips the tagged object
+	client.Gets()
+	plugin = newTestS3(t, client)
+	plugin.ProcessedTag = "telegraf-processed"
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+	require.Empty(t, client.Gets())
+}
+
+func TestForgetMissingObjects(t *testing.T) {
+	client := newMockS3()
+	client.put("a", []byte("cpu value=1 0\n"))
+	plugin := newTestS3(t, client)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	obj := client.objects["a"]
+	delete(client.objects, "a")
+
+	// left out of a listing, the object is still known
+	for i := 0; i < maxMissed-1; i++ {
+		require.NoError(t, plugin.Gather(&acc))
+	}
+	client.objects["a"] = obj
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, []string{"a"}, client.Gets())
+	require.Len(t, acc.Metrics, 1)
+
+	delete(client.objects, "a")
+	for i := 0; i < maxMissed; i++ {
+		require.NoError(t, plugin.Gather(&acc))
+	}
+	require.Empty(t, plugin.objects)
+}
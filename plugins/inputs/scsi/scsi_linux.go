@@ -0,0 +1,193 @@
+// +build linux
+
+package scsi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// SCSI is a telegraf plugin to gather the error counters of the Fibre Channel
+// host ports and of the SAS phys from sysfs.
+type SCSI struct {
+	SysPath string   `toml:"sys_path"`
+	Hosts   []string `toml:"hosts"`
+}
+
+const defaultSysPath = "/sys"
+
+// The counters of the fc_host statistics directory, in hexadecimal.
+var fcHostStatistics = []string{
+	"link_failure_count",
+	"loss_of_sync_count",
+	"loss_of_signal_count",
+	"invalid_crc_count",
+	"invalid_tx_word_count",
+	"prim_seq_protocol_err_count",
+	"nos_count",
+	"lip_count",
+	"error_frames",
+	"dumped_frames",
+	"fcp_packet_aborts",
+	"tx_frames",
+	"rx_frames",
+	"seconds_since_last_reset",
+}
+
+// The error counters of the sas_phy directories, in decimal.
+var sasPhyCounters = []string{
+	"invalid_dword_count",
+	"loss_of_dword_sync_count",
+	"phy_reset_problem_count",
+	"running_disparity_error_count",
+}
+
+var sampleConfig = `
+  ## Path of the sysfs mount, by default /sys or the HOST_SYS environment
+  ## variable if set.
+  # sys_path = "/sys"
+
+  ## SCSI hosts to gather, by default all of them.
+  # hosts = ["host0", "host1"]
+`
+
+// Description returns a short description of the plugin
+func (s *SCSI) Description() string {
+	return "Gather the error counters of the Fibre Channel ports and SAS phys"
+}
+
+// SampleConfig returns sample configuration options.
+func (s *SCSI) SampleConfig() string {
+	return sampleConfig
+}
+
+// Gather adds a metric per Fibre Channel host port and per SAS phy, the
+// systems without those add nothing.
+func (s *SCSI) Gather(acc telegraf.Accumulator) error {
+	sysPath := s.SysPath
+	if sysPath == "" {
+		sysPath = defaultSysPath
+		if sys := os.Getenv("HOST_SYS"); sys != "" {
+			sysPath = sys
+		}
+	}
+
+	hosts, err := filepath.Glob(filepath.Join(sysPath, "class", "fc_host", "host*"))
+	if err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if s.includeHost(filepath.Base(host)) {
+			gatherFCHost(acc, sysPath, host)
+		}
+	}
+
+	phys, err := filepath.Glob(filepath.Join(sysPath, "class", "sas_phy", "phy-*"))
+	if err != nil {
+		return err
+	}
+	for _, phy := range phys {
+		if s.includeHost(phyHost(filepath.Base(phy))) {
+			gatherSASPhy(acc, phy)
+		}
+	}
+	return nil
+}
+
+func (s *SCSI) includeHost(host string) bool {
+	if len(s.Hosts) == 0 {
+		return true
+	}
+	for _, h := range s.Hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+func gatherFCHost(acc telegraf.Accumulator, sysPath, host string) {
+	name := filepath.Base(host)
+	tags := map[string]string{"host": name}
+	if port := readAttribute(filepath.Join(host, "port_name")); port != "" {
+		tags["port_name"] = port
+	}
+	if state := readAttribute(filepath.Join(host, "port_state")); state != "" {
+		tags["port_state"] = state
+	}
+	if driver := readAttribute(filepath.Join(sysPath, "class", "scsi_host", name, "proc_name")); driver != "" {
+		tags["driver"] = driver
+	}
+
+	fields := make(map[string]interface{})
+	for _, stat := range fcHostStatistics {
+		if v, ok := readCounter(filepath.Join(host, "statistics", stat)); ok {
+			fields[stat] = v
+		}
+	}
+	if len(fields) > 0 {
+		acc.AddFields("scsi_fc_host", fields, tags)
+	}
+}
+
+func gatherSASPhy(acc telegraf.Accumulator, phy string) {
+	name := filepath.Base(phy)
+	tags := map[string]string{
+		"host": phyHost(name),
+		"phy":  name,
+	}
+	if address := readAttribute(filepath.Join(phy, "sas_address")); address != "" {
+		tags["sas_address"] = address
+	}
+
+	fields := make(map[string]interface{})
+	for _, counter := range sasPhyCounters {
+		if v, ok := readCounter(filepath.Join(phy, counter)); ok {
+			fields[counter] = v
+		}
+	}
+	if len(fields) > 0 {
+		acc.AddFields("scsi_sas_phy", fields, tags)
+	}
+}
+
+// phyHost returns the SCSI host of a SAS phy named phy-H:N, or phy-H:E:N for
+// the phys of the expanders.
+func phyHost(phy string) string {
+	return "host" + strings.SplitN(strings.TrimPrefix(phy, "phy-"), ":", 2)[0]
+}
+
+func readAttribute(path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readCounter returns the counter of the file, in decimal or in hexadecimal
+// with a 0x prefix.  The counters not supported by the driver, reported as
+// all ones in hexadecimal, are skipped.
+func readCounter(path string) (uint64, bool) {
+	value := readAttribute(path)
+	if value == "" || value == "0xffffffffffffffff" {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(value, 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func init() {
+	inputs.Add("scsi", func() telegraf.Input {
+		return &SCSI{}
+	})
+}
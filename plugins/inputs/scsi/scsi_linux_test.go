@@ -0,0 +1,126 @@
+// +build linux
+
+package scsi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestGatherFCHosts(t *testing.T) {
+	s := &SCSI{SysPath: "testdata/fc"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	// the counters not supported, like nos_count, are skipped
+	acc.AssertContainsTaggedFields(t, "scsi_fc_host",
+		map[string]interface{}{
+			"link_failure_count":          uint64(3),
+			"loss_of_sync_count":          uint64(26),
+			"loss_of_signal_count":        uint64(0),
+			"invalid_crc_count":           uint64(7),
+			"invalid_tx_word_count":       uint64(47),
+			"prim_seq_protocol_err_count": uint64(0),
+			"lip_count":                   uint64(2),
+			"error_frames":                uint64(0),
+			"dumped_frames":               uint64(0),
+			"fcp_packet_aborts":           uint64(0),
+			"tx_frames":                   uint64(1789481),
+			"rx_frames":                   uint64(2785040),
+			"seconds_since_last_reset":    uint64(86400),
+		},
+		map[string]string{
+			"host":       "host1",
+			"port_name":  "0x21000024ff4a1b2c",
+			"port_state": "Online",
+			"driver":     "qla2xxx",
+		})
+	acc.AssertContainsTaggedFields(t, "scsi_fc_host",
+		map[string]interface{}{
+			"link_failure_count":   uint64(1),
+			"loss_of_sync_count":   uint64(4),
+			"loss_of_signal_count": uint64(4),
+			"invalid_crc_count":    uint64(0),
+		},
+		map[string]string{
+			"host":       "host2",
+			"port_name":  "0x21000024ff4a1b2d",
+			"port_state": "Linkdown",
+			"driver":     "qla2xxx",
+		})
+
+	s.Hosts = []string{"host2"}
+	acc.ClearMetrics()
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "host2", acc.Metrics[0].Tags["host"])
+}
+
+// The SAS phys are created at runtime, their names having colons.
+func TestGatherSASPhys(t *testing.T) {
+	sys, err := ioutil.TempDir("", "scsi")
+	require.NoError(t, err)
+	defer os.RemoveAll(sys)
+
+	phys := map[string]map[string]string{
+		"phy-0:0": {
+			"sas_address":                   "0x500605b0012345f0",
+			"invalid_dword_count":           "57",
+			"loss_of_dword_sync_count":      "12",
+			"phy_reset_problem_count":       "0",
+			"running_disparity_error_count": "0",
+		},
+		"phy-0:4:12": {
+			"sas_address":              "0x5003048001abcdef",
+			"invalid_dword_count":      "0",
+			"loss_of_dword_sync_count": "3",
+		},
+		"phy-1:0": {
+			"invalid_dword_count": "1",
+		},
+	}
+	for phy, attributes := range phys {
+		dir := filepath.Join(sys, "class", "sas_phy", phy)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		for name, value := range attributes {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644))
+		}
+	}
+
+	s := &SCSI{SysPath: sys, Hosts: []string{"host0"}}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "scsi_sas_phy",
+		map[string]interface{}{
+			"invalid_dword_count":           uint64(57),
+			"loss_of_dword_sync_count":      uint64(12),
+			"phy_reset_problem_count":       uint64(0),
+			"running_disparity_error_count": uint64(0),
+		},
+		map[string]string{"host": "host0", "phy": "phy-0:0", "sas_address": "0x500605b0012345f0"})
+	acc.AssertContainsTaggedFields(t, "scsi_sas_phy",
+		map[string]interface{}{
+			"invalid_dword_count":      uint64(0),
+			"loss_of_dword_sync_count": uint64(3),
+		},
+		map[string]string{"host": "host0", "phy": "phy-0:4:12", "sas_address": "0x5003048001abcdef"})
+}
+
+func TestGatherNoHosts(t *testing.T) {
+	for _, sys := range []string{"testdata/none", "testdata/missing"} {
+		s := &SCSI{SysPath: sys}
+		var acc testutil.Accumulator
+		require.NoError(t, s.Gather(&acc))
+		require.Empty(t, acc.Metrics)
+	}
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+package scsi
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type SCSI struct {
+}
+
+func (s *SCSI) Description() string {
+	return "Gather the error counters of the Fibre Channel ports and SAS phys"
+}
+
+func (s *SCSI) SampleConfig() string { return "" }
+
+func (s *SCSI) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("scsi", func() telegraf.Input {
+		return &SCSI{}
+	})
+}
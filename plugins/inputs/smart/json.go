@@ -0,0 +1,233 @@
+package smart
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// deviceOpenFailed is the bit of the smartctl exit status set when the device
+// could not be opened or did not answer to the identification.
+const deviceOpenFailed = 1 << 1
+
+// smartctlOutput is the part of the output of `smartctl --json` read by the
+// plugin.
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	WWN          *struct {
+		NAA uint64 `json:"naa"`
+		OUI uint64 `json:"oui"`
+		ID  uint64 `json:"id"`
+	} `json:"wwn"`
+	UserCapacity struct {
+		Bytes int64 `json:"bytes"`
+	} `json:"user_capacity"`
+	NVMeTotalCapacity int64 `json:"nvme_total_capacity"`
+	SmartSupport      *struct {
+		Enabled bool `json:"enabled"`
+	} `json:"smart_support"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+
+	ATASmartAttributes struct {
+		Table []struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			Value      int64  `json:"value"`
+			Worst      int64  `json:"worst"`
+			Thresh     int64  `json:"thresh"`
+			WhenFailed string `json:"when_failed"`
+			Flags      struct {
+				String string `json:"string"`
+			} `json:"flags"`
+			Raw struct {
+				Value  int64  `json:"value"`
+				String string `json:"string"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	PowerCycleCount *int64 `json:"power_cycle_count"`
+	Temperature     *struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+
+	NVMeSmartHealthInformationLog map[string]json.RawMessage `json:"nvme_smart_health_information_log"`
+}
+
+// nvmeFields are the entries of the NVMe SMART health log added to the
+// fields of smart_device.
+var nvmeFields = []string{
+	"available_spare",
+	"available_spare_threshold",
+	"controller_busy_time",
+	"critical_warning",
+	"data_units_read",
+	"data_units_written",
+	"host_reads",
+	"host_writes",
+	"media_errors",
+	"num_err_log_entries",
+	"percentage_used",
+	"unsafe_shutdowns",
+}
+
+// whenFailed maps the when_failed of the attributes to the fail tag, as
+// printed by smartctl --format=brief.
+var whenFailed = map[string]string{
+	"":            "-",
+	"now":         "NOW",
+	"past":        "Past",
+	"in_the_past": "Past",
+}
+
+func gatherDiskJSON(acc telegraf.Accumulator, usesudo, attributes bool, smartctl, nocheck, device string, wg *sync.WaitGroup) {
+	defer wg.Done()
+	args := []string{"--json", "--info", "--health", "--attributes", "--tolerance=verypermissive", "-n", nocheck}
+	args = append(args, strings.Split(device, " ")...)
+	cmd := sudo(usesudo, smartctl, args...)
+	out, e := internal.CombinedOutputTimeout(cmd, time.Second*5)
+
+	// Ignore all exit statuses except if it is a command line parse error
+	exitStatus, er := exitStatus(e)
+	if er != nil {
+		acc.AddError(fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), e, string(out)))
+		return
+	}
+
+	var data smartctlOutput
+	if err := json.Unmarshal(out, &data); err != nil {
+		acc.AddError(fmt.Errorf("failed to parse the output of %s: %s", strings.Join(cmd.Args, " "), err))
+		return
+	}
+
+	device_node := strings.Split(device, " ")[0]
+	if data.Smartctl.ExitStatus&deviceOpenFailed != 0 {
+		messages := []string{}
+		for _, message := range data.Smartctl.Messages {
+			messages = append(messages, message.String)
+		}
+		log.Printf("W! [inputs.smart] Skipping device %s: %s", device_node, strings.Join(messages, "; "))
+		return
+	}
+
+	device_tags := map[string]string{}
+	device_tags["device"] = path.Base(device_node)
+	if data.ModelName != "" {
+		device_tags["model"] = data.ModelName
+	}
+	if data.SerialNumber != "" {
+		device_tags["serial_no"] = data.SerialNumber
+	}
+	if data.WWN != nil {
+		device_tags["wwn"] = fmt.Sprintf("%x%06x%09x", data.WWN.NAA, data.WWN.OUI, data.WWN.ID)
+	}
+	if data.UserCapacity.Bytes > 0 {
+		device_tags["capacity"] = strconv.FormatInt(data.UserCapacity.Bytes, 10)
+	} else if data.NVMeTotalCapacity > 0 {
+		device_tags["capacity"] = strconv.FormatInt(data.NVMeTotalCapacity, 10)
+	}
+	if data.SmartSupport != nil {
+		if data.SmartSupport.Enabled {
+			device_tags["enabled"] = "Enabled"
+		} else {
+			device_tags["enabled"] = "Disabled"
+		}
+	}
+
+	device_fields := make(map[string]interface{})
+	device_fields["exit_status"] = exitStatus
+	if data.SmartStatus != nil {
+		device_fields["health_ok"] = data.SmartStatus.Passed
+	}
+	if data.Temperature != nil {
+		device_fields["temp_c"] = data.Temperature.Current
+	}
+	if data.PowerOnTime != nil {
+		device_fields["power_on_hours"] = data.PowerOnTime.Hours
+	}
+	if data.PowerCycleCount != nil {
+		device_fields["power_cycle_count"] = *data.PowerCycleCount
+	}
+	for _, field := range nvmeFields {
+		value, ok := data.NVMeSmartHealthInformationLog[field]
+		if !ok {
+			continue
+		}
+		// the counters are parsed from their text to keep the large values
+		// exact
+		if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			device_fields[field] = i
+		}
+	}
+
+	for _, attr := range data.ATASmartAttributes.Table {
+		id := strconv.Itoa(attr.ID)
+
+		// The raw value is parsed from its text like in the text output, as
+		// the temperatures hold the minimum and maximum in the upper bytes.
+		raw := attr.Raw.Value
+		if f := strings.Fields(attr.Raw.String); len(f) > 0 {
+			if val, err := parseRawValue(f[0]); err == nil {
+				raw = val
+			}
+		}
+
+		if attributes {
+			tags := map[string]string{}
+			fields := make(map[string]interface{})
+
+			tags["device"] = path.Base(device_node)
+			if serial, ok := device_tags["serial_no"]; ok {
+				tags["serial_no"] = serial
+			}
+			if wwn, ok := device_tags["wwn"]; ok {
+				tags["wwn"] = wwn
+			}
+			tags["id"] = id
+			tags["name"] = attr.Name
+			tags["flags"] = strings.TrimSpace(attr.Flags.String)
+			if fail, ok := whenFailed[attr.WhenFailed]; ok {
+				tags["fail"] = fail
+			} else {
+				tags["fail"] = attr.WhenFailed
+			}
+
+			fields["exit_status"] = exitStatus
+			fields["value"] = attr.Value
+			fields["worst"] = attr.Worst
+			fields["threshold"] = attr.Thresh
+			fields["raw_value"] = raw
+
+			acc.AddFields("smart_attribute", fields, tags)
+		}
+
+		// If the attribute matches on the one in deviceFieldIds
+		// save the raw value to a field.
+		if field, ok := deviceFieldIds[id]; ok {
+			device_fields[field] = raw
+		}
+	}
+	acc.AddFields("smart_device", device_fields, device_tags)
+}
@@ -55,6 +55,7 @@ type Smart struct {
 	Excludes   []string
 	Devices    []string
 	UseSudo    bool
+	UseJSON    bool `toml:"use_json"`
 }
 
 var sampleConfig = `
@@ -80,6 +81,11 @@ var sampleConfig = `
   ##
   # attributes = false
   #
+  ## Parse the JSON output of smartctl (smartctl --json), available since
+  ## smartmontools 7.0, instead of its text output.  This also gathers the
+  ## power on hours and the NVMe health information.
+  # use_json = false
+  #
   ## Optionally specify devices to exclude from reporting.
   # excludes = [ "/dev/pass6" ]
   #
@@ -134,11 +140,13 @@ func (m *Smart) scan() ([]string, error) {
 		return []string{}, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
 	}
 
+	// The device type found by the scan, like "/dev/sda -d sat", is kept as
+	// some devices can not be read without it.
 	devices := []string{}
 	for _, line := range strings.Split(string(out), "\n") {
-		dev := strings.Split(line, " ")
-		if len(dev) > 1 && !excludedDev(m.Excludes, strings.TrimSpace(dev[0])) {
-			devices = append(devices, strings.TrimSpace(dev[0]))
+		dev := strings.Fields(strings.Split(line, "#")[0])
+		if len(dev) > 0 && !excludedDev(m.Excludes, dev[0]) {
+			devices = append(devices, strings.Join(dev, " "))
 		}
 	}
 	return devices, nil
@@ -163,7 +171,11 @@ func (m *Smart) getAttributes(acc telegraf.Accumulator, devices []string) {
 	wg.Add(len(devices))
 
 	for _, device := range devices {
-		go gatherDisk(acc, m.UseSudo, m.Attributes, m.Path, m.Nocheck, device, &wg)
+		if m.UseJSON {
+			go gatherDiskJSON(acc, m.UseSudo, m.Attributes, m.Path, m.Nocheck, device, &wg)
+		} else {
+			go gatherDisk(acc, m.UseSudo, m.Attributes, m.Path, m.Nocheck, device, &wg)
+		}
 	}
 
 	wg.Wait()
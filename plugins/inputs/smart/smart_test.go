@@ -59,6 +59,113 @@ ID# ATTRIBUTE_NAME          FLAGS    VALUE WORST THRESH FAIL RAW_VALUE
                             |||____ S speed/performance
                             ||_____ O updated online
                             |______ P prefailure warning
+`
+	mockSATAJSONData = `{
+  "json_format_version": [1, 0],
+  "smartctl": {
+    "version": [7, 0],
+    "svn_revision": "4883",
+    "platform_info": "x86_64-linux-4.19.0",
+    "build_info": "(local build)",
+    "argv": ["smartctl", "--json", "--info", "--health", "--attributes", "--tolerance=verypermissive", "-n", "standby", "/dev/sda", "-d", "sat"],
+    "exit_status": 0
+  },
+  "device": {"name": "/dev/sda", "info_name": "/dev/sda [SAT]", "type": "sat", "protocol": "ATA"},
+  "model_family": "Samsung based SSDs",
+  "model_name": "Samsung SSD 850 EVO 250GB",
+  "serial_number": "S2R6NX0H512345A",
+  "wwn": {"naa": 5, "oui": 9528, "id": 61799438706},
+  "firmware_version": "EMT02B6Q",
+  "user_capacity": {"blocks": 488397168, "bytes": 250059350016},
+  "logical_block_size": 512,
+  "rotation_rate": 0,
+  "smart_support": {"available": true, "enabled": true},
+  "smart_status": {"passed": true},
+  "ata_smart_attributes": {
+    "revision": 1,
+    "table": [
+      {"id": 5, "name": "Reallocated_Sector_Ct", "value": 100, "worst": 100, "thresh": 10, "when_failed": "",
+       "flags": {"value": 51, "string": "PO--CK ", "prefailure": true, "updated_online": true, "performance": false, "error_rate": false, "event_count": true, "auto_keep": true},
+       "raw": {"value": 0, "string": "0"}},
+      {"id": 9, "name": "Power_On_Hours", "value": 94, "worst": 94, "thresh": 0, "when_failed": "",
+       "flags": {"value": 50, "string": "-O--CK ", "prefailure": false, "updated_online": true, "performance": false, "error_rate": false, "event_count": true, "auto_keep": true},
+       "raw": {"value": 27130, "string": "27130"}},
+      {"id": 190, "name": "Airflow_Temperature_Cel", "value": 67, "worst": 49, "thresh": 0, "when_failed": "past",
+       "flags": {"value": 50, "string": "-O--CK ", "prefailure": false, "updated_online": true, "performance": false, "error_rate": false, "event_count": true, "auto_keep": true},
+       "raw": {"value": 33, "string": "33"}},
+      {"id": 194, "name": "Temperature_Celsius", "value": 67, "worst": 49, "thresh": 0, "when_failed": "",
+       "flags": {"value": 34, "string": "-O---K ", "prefailure": false, "updated_online": true, "performance": false, "error_rate": false, "event_count": false, "auto_keep": true},
+       "raw": {"value": 219047903265, "string": "33 (Min/Max 18/51)"}},
+      {"id": 199, "name": "UDMA_CRC_Error_Count", "value": 100, "worst": 100, "thresh": 0, "when_failed": "",
+       "flags": {"value": 62, "string": "-OSRCK ", "prefailure": false, "updated_online": true, "performance": true, "error_rate": true, "event_count": true, "auto_keep": true},
+       "raw": {"value": 2, "string": "2"}}
+    ]
+  },
+  "power_on_time": {"hours": 27130},
+  "power_cycle_count": 512,
+  "temperature": {"current": 33}
+}
+`
+	mockNVMeJSONData = `{
+  "json_format_version": [1, 0],
+  "smartctl": {
+    "version": [7, 0],
+    "svn_revision": "4883",
+    "platform_info": "x86_64-linux-4.19.0",
+    "build_info": "(local build)",
+    "argv": ["smartctl", "--json", "--info", "--health", "--attributes", "--tolerance=verypermissive", "-n", "standby", "/dev/nvme0", "-d", "nvme"],
+    "exit_status": 0
+  },
+  "device": {"name": "/dev/nvme0", "info_name": "/dev/nvme0", "type": "nvme", "protocol": "NVMe"},
+  "model_name": "Samsung SSD 970 EVO Plus 500GB",
+  "serial_number": "S4EVNF0M123456X",
+  "firmware_version": "2B2QEXM7",
+  "nvme_pci_vendor": {"id": 5197, "subsystem_id": 5197},
+  "nvme_ieee_oui_identifier": 9528,
+  "nvme_total_capacity": 500107862016,
+  "nvme_unallocated_capacity": 0,
+  "nvme_controller_id": 4,
+  "nvme_number_of_namespaces": 1,
+  "smart_status": {"passed": true, "nvme": {"value": 0}},
+  "nvme_smart_health_information_log": {
+    "critical_warning": 0,
+    "temperature": 38,
+    "available_spare": 100,
+    "available_spare_threshold": 10,
+    "percentage_used": 1,
+    "data_units_read": 12345678,
+    "data_units_written": 23456789012345,
+    "host_reads": 234567890,
+    "host_writes": 345678901,
+    "controller_busy_time": 1234,
+    "power_cycles": 321,
+    "power_on_hours": 4567,
+    "unsafe_shutdowns": 45,
+    "media_errors": 0,
+    "num_err_log_entries": 12,
+    "warning_temp_time": 0,
+    "critical_comp_time": 0,
+    "temperature_sensors": [38, 41]
+  },
+  "temperature": {"current": 38},
+  "power_cycle_count": 321,
+  "power_on_time": {"hours": 4567}
+}
+`
+	mockUnreadableJSONData = `{
+  "json_format_version": [1, 0],
+  "smartctl": {
+    "version": [7, 0],
+    "svn_revision": "4883",
+    "platform_info": "x86_64-linux-4.19.0",
+    "build_info": "(local build)",
+    "argv": ["smartctl", "--json", "--info", "--health", "--attributes", "--tolerance=verypermissive", "-n", "standby", "/dev/sdb"],
+    "messages": [
+      {"string": "Smartctl open device: /dev/sdb failed: No such device", "severity": "error"}
+    ],
+    "exit_status": 2
+  }
+}
 `
 )
 
@@ -379,6 +486,119 @@ func TestGatherNoAttributes(t *testing.T) {
 
 }
 
+func TestGatherJSON(t *testing.T) {
+	s := &Smart{
+		Path:       "smartctl",
+		Attributes: true,
+		UseJSON:    true,
+		Devices:    []string{"/dev/sda -d sat", "/dev/nvme0 -d nvme", "/dev/sdb"},
+	}
+	// overwriting exec commands with mock commands
+	execCommand = fakeExecCommand
+	var acc testutil.Accumulator
+
+	err := s.Gather(&acc)
+
+	require.NoError(t, err)
+	require.Empty(t, acc.Errors)
+	for _, m := range acc.Metrics {
+		assert.NotEqual(t, "sdb", m.Tags["device"], "Unreadable device shouldn't be reported")
+	}
+
+	acc.AssertContainsTaggedFields(t, "smart_device",
+		map[string]interface{}{
+			"exit_status":       int(0),
+			"health_ok":         true,
+			"temp_c":            int64(33),
+			"power_on_hours":    int64(27130),
+			"power_cycle_count": int64(512),
+			"udma_crc_errors":   int64(2),
+		},
+		map[string]string{
+			"device":    "sda",
+			"model":     "Samsung SSD 850 EVO 250GB",
+			"serial_no": "S2R6NX0H512345A",
+			"wwn":       "5002538e63889972",
+			"enabled":   "Enabled",
+			"capacity":  "250059350016",
+		})
+
+	acc.AssertContainsTaggedFields(t, "smart_device",
+		map[string]interface{}{
+			"exit_status":               int(0),
+			"health_ok":                 true,
+			"temp_c":                    int64(38),
+			"power_on_hours":            int64(4567),
+			"power_cycle_count":         int64(321),
+			"available_spare":           int64(100),
+			"available_spare_threshold": int64(10),
+			"controller_busy_time":      int64(1234),
+			"critical_warning":          int64(0),
+			"data_units_read":           int64(12345678),
+			"data_units_written":        int64(23456789012345),
+			"host_reads":                int64(234567890),
+			"host_writes":               int64(345678901),
+			"media_errors":              int64(0),
+			"num_err_log_entries":       int64(12),
+			"percentage_used":           int64(1),
+			"unsafe_shutdowns":          int64(45),
+		},
+		map[string]string{
+			"device":    "nvme0",
+			"model":     "Samsung SSD 970 EVO Plus 500GB",
+			"serial_no": "S4EVNF0M123456X",
+			"capacity":  "500107862016",
+		})
+
+	acc.AssertContainsTaggedFields(t, "smart_attribute",
+		map[string]interface{}{
+			"exit_status": int(0),
+			"value":       int64(67),
+			"worst":       int64(49),
+			"threshold":   int64(0),
+			"raw_value":   int64(33),
+		},
+		map[string]string{
+			"device":    "sda",
+			"serial_no": "S2R6NX0H512345A",
+			"wwn":       "5002538e63889972",
+			"id":        "194",
+			"name":      "Temperature_Celsius",
+			"flags":     "-O---K",
+			"fail":      "-",
+		})
+
+	acc.AssertContainsTaggedFields(t, "smart_attribute",
+		map[string]interface{}{
+			"exit_status": int(0),
+			"value":       int64(67),
+			"worst":       int64(49),
+			"threshold":   int64(0),
+			"raw_value":   int64(33),
+		},
+		map[string]string{
+			"device":    "sda",
+			"serial_no": "S2R6NX0H512345A",
+			"wwn":       "5002538e63889972",
+			"id":        "190",
+			"name":      "Airflow_Temperature_Cel",
+			"flags":     "-O--CK",
+			"fail":      "Past",
+		})
+}
+
+func TestScanDeviceType(t *testing.T) {
+	s := &Smart{
+		Path: "smartctl",
+	}
+	// overwriting exec commands with mock commands
+	execCommand = fakeExecCommand
+
+	devices, err := s.scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/ada0 -d atacam"}, devices)
+}
+
 func TestExcludedDev(t *testing.T) {
 	assert.Equal(t, true, excludedDev([]string{"/dev/pass6"}, "/dev/pass6 -d atacam"), "Should be excluded.")
 	assert.Equal(t, false, excludedDev([]string{}, "/dev/pass6 -d atacam"), "Shouldn't be excluded.")
@@ -418,6 +638,17 @@ func TestHelperProcess(t *testing.T) {
 		if arg1 == "--info" {
 			fmt.Fprint(os.Stdout, mockInfoAttributeData)
 		}
+		if arg1 == "--json" {
+			switch args[len(args)-1] {
+			case "sat":
+				fmt.Fprint(os.Stdout, mockSATAJSONData)
+			case "nvme":
+				fmt.Fprint(os.Stdout, mockNVMeJSONData)
+			default:
+				fmt.Fprint(os.Stdout, mockUnreadableJSONData)
+				os.Exit(2)
+			}
+		}
 	} else {
 		fmt.Fprint(os.Stdout, "command not found")
 		os.Exit(1)
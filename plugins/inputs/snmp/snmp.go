@@ -14,6 +14,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
 	"github.com/influxdata/telegraf/plugins/inputs"
 
 	"github.com/soniah/gosnmp"
@@ -609,104 +610,28 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		return gs, nil
 	}
 
-	agent := s.Agents[idx]
-
-	gs := gosnmpWrapper{&gosnmp.GoSNMP{}}
-	s.connectionCache[idx] = gs
-
-	host, portStr, err := net.SplitHostPort(agent)
-	if err != nil {
-		if err, ok := err.(*net.AddrError); !ok || err.Err != "missing port in address" {
-			return nil, Errorf(err, "parsing host")
-		}
-		host = agent
-		portStr = "161"
-	}
-	gs.Target = host
-
-	port, err := strconv.ParseUint(portStr, 10, 16)
+	client, err := snmp.NewClient(s.Agents[idx], snmp.ClientConfig{
+		Timeout:        s.Timeout,
+		Retries:        s.Retries,
+		Version:        s.Version,
+		Community:      s.Community,
+		MaxRepetitions: s.MaxRepetitions,
+		ContextName:    s.ContextName,
+		SecLevel:       s.SecLevel,
+		SecName:        s.SecName,
+		AuthProtocol:   s.AuthProtocol,
+		AuthPassword:   s.AuthPassword,
+		PrivProtocol:   s.PrivProtocol,
+		PrivPassword:   s.PrivPassword,
+		EngineID:       s.EngineID,
+		EngineBoots:    s.EngineBoots,
+		EngineTime:     s.EngineTime,
+	})
 	if err != nil {
-		return nil, Errorf(err, "parsing port")
-	}
-	gs.Port = uint16(port)
-
-	gs.Timeout = s.Timeout.Duration
-
-	gs.Retries = s.Retries
-
-	switch s.Version {
-	case 3:
-		gs.Version = gosnmp.Version3
-	case 2, 0:
-		gs.Version = gosnmp.Version2c
-	case 1:
-		gs.Version = gosnmp.Version1
-	default:
-		return nil, fmt.Errorf("invalid version")
-	}
-
-	if s.Version < 3 {
-		if s.Community == "" {
-			gs.Community = "public"
-		} else {
-			gs.Community = s.Community
-		}
-	}
-
-	gs.MaxRepetitions = s.MaxRepetitions
-
-	if s.Version == 3 {
-		gs.ContextName = s.ContextName
-
-		sp := &gosnmp.UsmSecurityParameters{}
-		gs.SecurityParameters = sp
-		gs.SecurityModel = gosnmp.UserSecurityModel
-
-		switch strings.ToLower(s.SecLevel) {
-		case "noauthnopriv", "":
-			gs.MsgFlags = gosnmp.NoAuthNoPriv
-		case "authnopriv":
-			gs.MsgFlags = gosnmp.AuthNoPriv
-		case "authpriv":
-			gs.MsgFlags = gosnmp.AuthPriv
-		default:
-			return nil, fmt.Errorf("invalid secLevel")
-		}
-
-		sp.UserName = s.SecName
-
-		switch strings.ToLower(s.AuthProtocol) {
-		case "md5":
-			sp.AuthenticationProtocol = gosnmp.MD5
-		case "sha":
-			sp.AuthenticationProtocol = gosnmp.SHA
-		case "":
-			sp.AuthenticationProtocol = gosnmp.NoAuth
-		default:
-			return nil, fmt.Errorf("invalid authProtocol")
-		}
-
-		sp.AuthenticationPassphrase = s.AuthPassword
-
-		switch strings.ToLower(s.PrivProtocol) {
-		case "des":
-			sp.PrivacyProtocol = gosnmp.DES
-		case "aes":
-			sp.PrivacyProtocol = gosnmp.AES
-		case "":
-			sp.PrivacyProtocol = gosnmp.NoPriv
-		default:
-			return nil, fmt.Errorf("invalid privProtocol")
-		}
-
-		sp.PrivacyPassphrase = s.PrivPassword
-
-		sp.AuthoritativeEngineID = s.EngineID
-
-		sp.AuthoritativeEngineBoots = s.EngineBoots
-
-		sp.AuthoritativeEngineTime = s.EngineTime
+		return nil, err
 	}
+	gs := gosnmpWrapper{client}
+	s.connectionCache[idx] = gs
 
 	if err := gs.Connect(); err != nil {
 		return nil, Errorf(err, "setting up connection")
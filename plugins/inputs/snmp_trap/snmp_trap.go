@@ -0,0 +1,568 @@
+package snmp_trap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	// snmpTrapOID is the varbind of the OID of the SNMPv2 traps.
+	snmpTrapOID = ".1.3.6.1.6.3.1.1.4.1.0"
+	// snmpTraps is the prefix of the OIDs of the generic traps.
+	snmpTraps = ".1.3.6.1.6.3.1.1.5"
+	// usmStatsUnknownEngineIDs is the counter reported to the SNMPv3
+	// senders discovering the engine ID of the listener.
+	usmStatsUnknownEngineIDs = ".1.3.6.1.6.3.15.1.1.4.0"
+)
+
+var authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":     gosnmp.DES,
+	"AES":     gosnmp.AES,
+	"AES192":  gosnmp.AES192,
+	"AES256":  gosnmp.AES256,
+	"AES192C": gosnmp.AES192C,
+	"AES256C": gosnmp.AES256C,
+}
+
+// execCommand is so tests can mock out exec.Command usage.
+var execCommand = exec.Command
+
+// V3User are the credentials of a SNMPv3 user.
+type V3User struct {
+	EngineID     string `toml:"engine_id"`
+	Username     string `toml:"username"`
+	AuthProtocol string `toml:"auth_protocol"`
+	AuthPassword string `toml:"auth_password"`
+	PrivProtocol string `toml:"priv_protocol"`
+	PrivPassword string `toml:"priv_password"`
+}
+
+// usmUser is a SNMPv3 user, its traps are decoded with its params.
+type usmUser struct {
+	engineID string
+	username string
+	level    gosnmp.SnmpV3MsgFlags
+	params   *gosnmp.GoSNMP
+}
+
+type mibEntry struct {
+	mibName string
+	oidText string
+}
+
+type SnmpTrap struct {
+	ServiceAddress string            `toml:"service_address"`
+	Timeout        internal.Duration `toml:"timeout"`
+	EngineID       string            `toml:"engine_id"`
+	V3Users        []V3User          `toml:"v3_user"`
+
+	acc      telegraf.Accumulator
+	conn     *net.UDPConn
+	wg       sync.WaitGroup
+	users    []*usmUser
+	engineID string
+	started  time.Time
+	// unknownEngineIDs counts the discoveries of the engine ID
+	unknownEngineIDs uint32
+
+	timeFunc   func() time.Time
+	lookupFunc func(oid string) (mibEntry, error)
+
+	cacheLock sync.Mutex
+	cache     map[string]mibEntry
+}
+
+var sampleConfig = `
+  ## Address to listen on for traps and informs, only UDP is supported.
+  # service_address = "udp://:162"
+
+  ## Timeout running the snmptranslate command translating the OIDs.
+  # timeout = "5s"
+
+  ## Authoritative engine ID of the listener, in hex, reported to the senders
+  ## of SNMPv3 informs discovering it.  The SNMPv3 informs are not received
+  ## when unset.
+  # engine_id = "80001f8880e9bd0c1d12667a5100000000"
+
+  ## SNMPv3 users, add one table per user.  The SNMPv3 traps of the other
+  ## users, or failing the authentication of the user, are dropped.
+  # [[inputs.snmp_trap.v3_user]]
+  #   ## Authoritative engine ID of the user, in hex: the engine of the sender
+  #   ## for traps, and of the listener for informs.  Any engine when unset.
+  #   # engine_id = "8000000001020304"
+  #   username = "telegraf"
+  #   ## Authentication protocol, one of "MD5", "SHA", "SHA224", "SHA256",
+  #   ## "SHA384" or "SHA512".  The traps are not authenticated when unset.
+  #   # auth_protocol = "SHA"
+  #   # auth_password = "authpass"
+  #   ## Privacy protocol, one of "DES", "AES", "AES192", "AES256", "AES192C"
+  #   ## or "AES256C", requires an authentication protocol.  The traps are
+  #   ## not encrypted when unset.
+  #   # priv_protocol = "AES"
+  #   # priv_password = "privpass"
+`
+
+func (s *SnmpTrap) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SnmpTrap) Description() string {
+	return "Receive SNMP traps and informs"
+}
+
+func (s *SnmpTrap) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (s *SnmpTrap) Init() error {
+	if s.EngineID != "" {
+		id, err := parseEngineID(s.EngineID)
+		if err != nil {
+			return err
+		}
+		s.engineID = id
+	}
+
+	s.users = nil
+	for _, u := range s.V3Users {
+		user, err := newUser(u)
+		if err != nil {
+			return fmt.Errorf("v3_user %q: %v", u.Username, err)
+		}
+		for _, other := range s.users {
+			if other.username == user.username && other.engineID == user.engineID {
+				return fmt.Errorf("v3_user %q: duplicate user of the engine", u.Username)
+			}
+		}
+		s.users = append(s.users, user)
+	}
+
+	if s.lookupFunc == nil {
+		s.lookupFunc = s.snmptranslate
+	}
+	if s.timeFunc == nil {
+		s.timeFunc = time.Now
+	}
+	return nil
+}
+
+func newUser(u V3User) (*usmUser, error) {
+	if u.Username == "" {
+		return nil, fmt.Errorf("username must be set")
+	}
+
+	sp := &gosnmp.UsmSecurityParameters{
+		UserName:               u.Username,
+		AuthenticationProtocol: gosnmp.NoAuth,
+		PrivacyProtocol:        gosnmp.NoPriv,
+	}
+	level := gosnmp.NoAuthNoPriv
+	if u.AuthProtocol != "" {
+		var ok bool
+		if sp.AuthenticationProtocol, ok = authProtocols[strings.ToUpper(u.AuthProtocol)]; !ok {
+			return nil, fmt.Errorf("invalid auth_protocol %q", u.AuthProtocol)
+		}
+		if u.AuthPassword == "" {
+			return nil, fmt.Errorf("auth_password must be set")
+		}
+		sp.AuthenticationPassphrase = u.AuthPassword
+		level = gosnmp.AuthNoPriv
+	}
+	if u.PrivProtocol != "" {
+		if level == gosnmp.NoAuthNoPriv {
+			return nil, fmt.Errorf("priv_protocol requires an auth_protocol")
+		}
+		var ok bool
+		if sp.PrivacyProtocol, ok = privProtocols[strings.ToUpper(u.PrivProtocol)]; !ok {
+			return nil, fmt.Errorf("invalid priv_protocol %q", u.PrivProtocol)
+		}
+		if u.PrivPassword == "" {
+			return nil, fmt.Errorf("priv_password must be set")
+		}
+		sp.PrivacyPassphrase = u.PrivPassword
+		level = gosnmp.AuthPriv
+	}
+
+	var engineID string
+	if u.EngineID != "" {
+		var err error
+		if engineID, err = parseEngineID(u.EngineID); err != nil {
+			return nil, err
+		}
+		sp.AuthoritativeEngineID = engineID
+	}
+
+	return &usmUser{
+		engineID: engineID,
+		username: u.Username,
+		level:    level,
+		params: &gosnmp.GoSNMP{
+			Version:            gosnmp.Version3,
+			SecurityModel:      gosnmp.UserSecurityModel,
+			MsgFlags:           level,
+			SecurityParameters: sp,
+		},
+	}, nil
+}
+
+// parseEngineID decodes an engine ID in hex, between 5 and 32 bytes long.
+func parseEngineID(s string) (string, error) {
+	id, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid engine_id %q: %v", s, err)
+	}
+	if len(id) < 5 || len(id) > 32 {
+		return "", fmt.Errorf("invalid engine_id %q: must be 5 to 32 bytes long", s)
+	}
+	return string(id), nil
+}
+
+func (s *SnmpTrap) Start(acc telegraf.Accumulator) error {
+	spl := strings.SplitN(s.ServiceAddress, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid service address: %s", s.ServiceAddress)
+	}
+	switch spl[0] {
+	case "udp", "udp4", "udp6":
+	default:
+		return fmt.Errorf("unsupported protocol '%s' in '%s'", spl[0], s.ServiceAddress)
+	}
+
+	addr, err := net.ResolveUDPAddr(spl[0], spl[1])
+	if err != nil {
+		return err
+	}
+	s.conn, err = net.ListenUDP(spl[0], addr)
+	if err != nil {
+		return err
+	}
+
+	s.acc = acc
+	s.started = time.Now()
+	s.cache = make(map[string]mibEntry)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.listen()
+	}()
+	return nil
+}
+
+func (s *SnmpTrap) Stop() {
+	s.conn.Close()
+	s.wg.Wait()
+}
+
+func (s *SnmpTrap) listen() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				s.acc.AddError(err)
+			}
+			return
+		}
+		s.receive(buf[:n], addr)
+	}
+}
+
+// receive decodes and adds the metric of a trap, and acknowledges the
+// informs.
+func (s *SnmpTrap) receive(msg []byte, addr *net.UDPAddr) {
+	version, err := messageVersion(msg)
+	if err != nil {
+		log.Printf("D! [inputs.snmp_trap] Dropped message from %s: %v", addr.IP, err)
+		return
+	}
+
+	var packet *gosnmp.SnmpPacket
+	var user *usmUser
+	if version == gosnmp.Version3 {
+		packet, user, err = s.unmarshalV3(msg, addr)
+	} else {
+		packet, err = (&gosnmp.GoSNMP{Version: version}).UnmarshalTrap(msg, false)
+	}
+	if err != nil {
+		log.Printf("D! [inputs.snmp_trap] Dropped message from %s: %v", addr.IP, err)
+		return
+	}
+	if packet == nil {
+		return
+	}
+
+	switch packet.PDUType {
+	case gosnmp.Trap, gosnmp.SNMPv2Trap, gosnmp.InformRequest:
+	default:
+		log.Printf("D! [inputs.snmp_trap] Dropped %s from %s", packet.PDUType, addr.IP)
+		return
+	}
+
+	s.addTrap(packet, addr)
+
+	if packet.PDUType == gosnmp.InformRequest {
+		if err := s.acknowledge(packet, user, addr); err != nil {
+			s.acc.AddError(fmt.Errorf("acknowledging inform from %s: %v", addr.IP, err))
+		}
+	}
+}
+
+// messageVersion reads the version of a SNMP message, the first integer of
+// its sequence.
+func messageVersion(msg []byte) (gosnmp.SnmpVersion, error) {
+	if len(msg) < 2 || msg[0] != byte(gosnmp.Sequence) {
+		return 0, fmt.Errorf("invalid SNMP message")
+	}
+	i := 2
+	if msg[1]&0x80 != 0 {
+		i += int(msg[1] & 0x7f)
+	}
+	if len(msg) < i+3 || msg[i] != byte(gosnmp.Integer) || msg[i+1] != 1 {
+		return 0, fmt.Errorf("invalid SNMP message")
+	}
+	switch version := gosnmp.SnmpVersion(msg[i+2]); version {
+	case gosnmp.Version1, gosnmp.Version2c, gosnmp.Version3:
+		return version, nil
+	default:
+		return 0, fmt.Errorf("unsupported SNMP version %d", msg[i+2])
+	}
+}
+
+// unmarshalV3 decodes a SNMPv3 message with the credentials of its user.  The
+// messages of unknown users, failing the authentication, or of a lower
+// security level than their user are errors.  The discoveries of the engine
+// ID of the listener are answered and result in no packet.
+func (s *SnmpTrap) unmarshalV3(msg []byte, addr *net.UDPAddr) (*gosnmp.SnmpPacket, *usmUser, error) {
+	err := fmt.Errorf("no SNMPv3 user authenticated the message")
+	for _, u := range s.users {
+		// the message is modified when decoded
+		packet, uerr := u.params.UnmarshalTrap(append([]byte(nil), msg...), true)
+		if uerr != nil {
+			continue
+		}
+
+		sp := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if sp.UserName != u.username {
+			continue
+		}
+		if u.engineID != "" && sp.AuthoritativeEngineID != u.engineID {
+			continue
+		}
+		if packet.MsgFlags&gosnmp.AuthPriv != u.level {
+			err = fmt.Errorf("security level of the message lower than the SNMPv3 user %q", u.username)
+			continue
+		}
+		return packet, u, nil
+	}
+
+	discovery := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{},
+	}
+	packet, derr := discovery.UnmarshalTrap(append([]byte(nil), msg...), true)
+	if derr == nil && packet.MsgFlags&gosnmp.AuthPriv == gosnmp.NoAuthNoPriv {
+		sp := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if sp.AuthoritativeEngineID == "" && packet.MsgFlags&gosnmp.Reportable != 0 {
+			if s.engineID == "" {
+				return nil, nil, fmt.Errorf("engine ID discovery, engine_id is not set")
+			}
+			return nil, nil, s.reportEngineID(packet, addr)
+		}
+	}
+
+	return nil, nil, err
+}
+
+// reportEngineID answers the discovery of the engine ID of the listener by a
+// sender of informs.
+func (s *SnmpTrap) reportEngineID(discovery *gosnmp.SnmpPacket, addr *net.UDPAddr) error {
+	s.unknownEngineIDs++
+	report := &gosnmp.SnmpPacket{
+		Version:       gosnmp.Version3,
+		MsgFlags:      gosnmp.NoAuthNoPriv,
+		SecurityModel: gosnmp.UserSecurityModel,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			AuthoritativeEngineID:    s.engineID,
+			AuthoritativeEngineBoots: 1,
+			AuthoritativeEngineTime:  uint32(time.Since(s.started).Seconds()),
+		},
+		ContextEngineID: s.engineID,
+		PDUType:         gosnmp.Report,
+		MsgID:           discovery.MsgID,
+		RequestID:       discovery.RequestID,
+		MsgMaxSize:      discovery.MsgMaxSize,
+		Variables: []gosnmp.SnmpPDU{{
+			Name:  usmStatsUnknownEngineIDs,
+			Type:  gosnmp.Counter32,
+			Value: s.unknownEngineIDs,
+		}},
+	}
+	return s.send(report, addr)
+}
+
+// acknowledge sends the response of an inform, with the same variables.
+func (s *SnmpTrap) acknowledge(inform *gosnmp.SnmpPacket, user *usmUser, addr *net.UDPAddr) error {
+	response := *inform
+	response.PDUType = gosnmp.GetResponse
+	response.Error = gosnmp.NoError
+	response.ErrorIndex = 0
+	if response.Version == gosnmp.Version3 {
+		response.MsgFlags &^= gosnmp.Reportable
+		// the salt of the encryption is renewed for each message
+		if err := user.params.SecurityParameters.InitPacket(&response); err != nil {
+			return err
+		}
+	}
+	return s.send(&response, addr)
+}
+
+func (s *SnmpTrap) send(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) error {
+	b, err := packet.MarshalMsg()
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.WriteToUDP(b, addr)
+	return err
+}
+
+func (s *SnmpTrap) addTrap(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	tags := map[string]string{
+		"source": addr.IP.String(),
+	}
+	fields := make(map[string]interface{})
+
+	switch packet.Version {
+	case gosnmp.Version1:
+		tags["version"] = "1"
+		tags["community"] = packet.Community
+		tags["agent_address"] = packet.AgentAddress
+		// the OID of the SNMPv1 traps as converted to SNMPv2, RFC 3584
+		oid := snmpTraps + "." + strconv.Itoa(packet.GenericTrap+1)
+		if packet.GenericTrap == 6 {
+			oid = packet.Enterprise + ".0." + strconv.Itoa(packet.SpecificTrap)
+		}
+		s.setTrapOID(tags, oid)
+		fields["sysUpTimeInstance"] = packet.Timestamp
+	case gosnmp.Version2c:
+		tags["version"] = "2c"
+		tags["community"] = packet.Community
+	case gosnmp.Version3:
+		tags["version"] = "3"
+		sp := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		tags["engine_id"] = hex.EncodeToString([]byte(sp.AuthoritativeEngineID))
+		tags["username"] = sp.UserName
+		if packet.ContextName != "" {
+			tags["context_name"] = packet.ContextName
+		}
+	}
+
+	for _, v := range packet.Variables {
+		var value interface{}
+		switch v.Type {
+		case gosnmp.ObjectIdentifier:
+			oid, ok := v.Value.(string)
+			if !ok {
+				continue
+			}
+			if v.Name == snmpTrapOID {
+				s.setTrapOID(tags, oid)
+				continue
+			}
+			value = s.lookup(oid).oidText
+		case gosnmp.Null, gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+			continue
+		default:
+			value = v.Value
+		}
+		fields[s.lookup(v.Name).oidText] = value
+	}
+
+	s.acc.AddFields("snmp_trap", fields, tags, s.timeFunc())
+}
+
+func (s *SnmpTrap) setTrapOID(tags map[string]string, oid string) {
+	e := s.lookup(oid)
+	tags["oid"] = oid
+	tags["name"] = e.oidText
+	if e.mibName != "" {
+		tags["mib"] = e.mibName
+	}
+}
+
+// lookup translates an OID, the OIDs which cannot be translated are left
+// numeric.
+func (s *SnmpTrap) lookup(oid string) mibEntry {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	if e, ok := s.cache[oid]; ok {
+		return e
+	}
+	e, err := s.lookupFunc(oid)
+	if err != nil {
+		s.acc.AddError(fmt.Errorf("translating OID %s: %v", oid, err))
+		e = mibEntry{oidText: oid}
+	}
+	s.cache[oid] = e
+	return e
+}
+
+func (s *SnmpTrap) snmptranslate(oid string) (mibEntry, error) {
+	var out bytes.Buffer
+	cmd := execCommand("snmptranslate", "-Td", "-Ob", "-m", "all", oid)
+	cmd.Stdout = &out
+	err := internal.RunTimeout(cmd, s.Timeout.Duration)
+	if err, ok := err.(*exec.Error); ok && err.Err == exec.ErrNotFound {
+		// the OIDs are left numeric without snmptranslate
+		return mibEntry{oidText: oid}, nil
+	}
+	if err != nil {
+		return mibEntry{}, err
+	}
+
+	scanner := bufio.NewScanner(&out)
+	if !scanner.Scan() {
+		return mibEntry{oidText: oid}, scanner.Err()
+	}
+	text := scanner.Text()
+	i := strings.Index(text, "::")
+	if i == -1 {
+		// not found in the MIBs
+		return mibEntry{oidText: oid}, nil
+	}
+	return mibEntry{mibName: text[:i], oidText: text[i+2:]}, nil
+}
+
+func init() {
+	inputs.Add("snmp_trap", func() telegraf.Input {
+		return &SnmpTrap{
+			ServiceAddress: "udp://:162",
+			Timeout:        internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
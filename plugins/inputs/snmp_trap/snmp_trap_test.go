@@ -0,0 +1,414 @@
+package snmp_trap
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1500000000, 0)
+
+var mibs = map[string]mibEntry{
+	".1.3.6.1.2.1.1.3.0":     {"DISMAN-EVENT-MIB", "sysUpTimeInstance"},
+	".1.3.6.1.6.3.1.1.5.4":   {"IF-MIB", "linkUp"},
+	".1.3.6.1.2.1.2.2.1.1.2": {"IF-MIB", "ifIndex.2"},
+	".1.3.6.1.2.1.2.2.1.8.2": {"IF-MIB", "ifOperStatus.2"},
+}
+
+func lookup(oid string) (mibEntry, error) {
+	if e, ok := mibs[oid]; ok {
+		return e, nil
+	}
+	return mibEntry{oidText: oid}, nil
+}
+
+// linkUp is the trap sent by the tests, of the interface index.
+func linkUp(index int) gosnmp.SnmpTrap {
+	return gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1000)},
+			{Name: snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.4"},
+			{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: index},
+		},
+	}
+}
+
+// listen starts the plugin on a random port of the loopback.
+func listen(t *testing.T, s *SnmpTrap) (*testutil.Accumulator, uint16) {
+	s.ServiceAddress = "udp://127.0.0.1:0"
+	s.lookupFunc = lookup
+	s.timeFunc = func() time.Time { return now }
+	require.NoError(t, s.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	return acc, uint16(s.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func sender(t *testing.T, port uint16, version gosnmp.SnmpVersion) *gosnmp.GoSNMP {
+	g := &gosnmp.GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      port,
+		Version:   version,
+		Community: "public",
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	require.NoError(t, g.Connect())
+	return g
+}
+
+// v3Sender is the sender of the SNMPv3 traps of the user, its own
+// authoritative engine unless an inform.
+func v3Sender(t *testing.T, port uint16, flags gosnmp.SnmpV3MsgFlags, sp *gosnmp.UsmSecurityParameters, inform bool) *gosnmp.GoSNMP {
+	if !inform && sp.AuthoritativeEngineID == "" {
+		sp.AuthoritativeEngineID = "\x80\x00\x00\x00\x01\x02\x03\x05"
+	}
+	sp.AuthoritativeEngineBoots = 1
+	sp.AuthoritativeEngineTime = 100
+	g := &gosnmp.GoSNMP{
+		Target:             "127.0.0.1",
+		Port:               port,
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           flags,
+		SecurityParameters: sp,
+		Timeout:            time.Second,
+		Retries:            1,
+	}
+	require.NoError(t, g.Connect())
+	return g
+}
+
+func TestReceiveTrap(t *testing.T) {
+	s := &SnmpTrap{}
+	acc, port := listen(t, s)
+	defer s.Stop()
+
+	g := sender(t, port, gosnmp.Version2c)
+	defer g.Conn.Close()
+	_, err := g.SendTrap(linkUp(2))
+	require.NoError(t, err)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{
+			"sysUpTimeInstance": uint32(1000),
+			"ifIndex.2":         2,
+		},
+		map[string]string{
+			"source":    "127.0.0.1",
+			"version":   "2c",
+			"community": "public",
+			"oid":       ".1.3.6.1.6.3.1.1.5.4",
+			"name":      "linkUp",
+			"mib":       "IF-MIB",
+		},
+	)
+	assert.True(t, acc.HasTimestamp("snmp_trap", now))
+}
+
+func TestReceiveTrapV1(t *testing.T) {
+	s := &SnmpTrap{}
+	acc, port := listen(t, s)
+	defer s.Stop()
+
+	g := sender(t, port, gosnmp.Version1)
+	defer g.Conn.Close()
+	_, err := g.SendTrap(gosnmp.SnmpTrap{
+		Enterprise:   ".1.3.6.1.4.1.8072.2.3",
+		AgentAddress: "192.168.1.1",
+		GenericTrap:  6,
+		SpecificTrap: 1,
+		Timestamp:    300,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.2.2.1.8.2", Type: gosnmp.Integer, Value: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{
+			"sysUpTimeInstance": uint(300),
+			"ifOperStatus.2":    1,
+		},
+		map[string]string{
+			"source":        "127.0.0.1",
+			"version":       "1",
+			"community":     "public",
+			"agent_address": "192.168.1.1",
+			"oid":           ".1.3.6.1.4.1.8072.2.3.0.1",
+			"name":          ".1.3.6.1.4.1.8072.2.3.0.1",
+		},
+	)
+}
+
+func TestInformAcknowledged(t *testing.T) {
+	s := &SnmpTrap{}
+	acc, port := listen(t, s)
+	defer s.Stop()
+
+	g := sender(t, port, gosnmp.Version2c)
+	defer g.Conn.Close()
+	inform := linkUp(2)
+	inform.IsInform = true
+	response, err := g.SendTrap(inform)
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.GetResponse, response.PDUType)
+	assert.Len(t, response.Variables, 3)
+
+	acc.Wait(1)
+	assert.Equal(t, "2c", acc.TagValue("snmp_trap", "version"))
+}
+
+var v3Users = []V3User{
+	{Username: "authsha", AuthProtocol: "SHA", AuthPassword: "authpass1"},
+	{Username: "authpriv", AuthProtocol: "SHA256", AuthPassword: "authpass2", PrivProtocol: "AES", PrivPassword: "privpass2"},
+	{Username: "md5des", AuthProtocol: "md5", AuthPassword: "authpass3", PrivProtocol: "des", PrivPassword: "privpass3"},
+	{Username: "noauth"},
+	{Username: "engine", EngineID: "8000000001020304", AuthProtocol: "SHA", AuthPassword: "authpass4"},
+}
+
+func TestV3Authentication(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    gosnmp.SnmpV3MsgFlags
+		sp       *gosnmp.UsmSecurityParameters
+		received bool
+	}{
+		{
+			name:     "auth no priv",
+			flags:    gosnmp.AuthNoPriv,
+			sp:       &gosnmp.UsmSecurityParameters{UserName: "authsha", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "authpass1"},
+			received: true,
+		},
+		{
+			name:  "auth priv",
+			flags: gosnmp.AuthPriv,
+			sp: &gosnmp.UsmSecurityParameters{UserName: "authpriv", AuthenticationProtocol: gosnmp.SHA256, AuthenticationPassphrase: "authpass2",
+				PrivacyProtocol: gosnmp.AES, PrivacyPassphrase: "privpass2"},
+			received: true,
+		},
+		{
+			name:  "auth priv des",
+			flags: gosnmp.AuthPriv,
+			sp: &gosnmp.UsmSecurityParameters{UserName: "md5des", AuthenticationProtocol: gosnmp.MD5, AuthenticationPassphrase: "authpass3",
+				PrivacyProtocol: gosnmp.DES, PrivacyPassphrase: "privpass3"},
+			received: true,
+		},
+		{
+			name:     "no auth",
+			flags:    gosnmp.NoAuthNoPriv,
+			sp:       &gosnmp.UsmSecurityParameters{UserName: "noauth"},
+			received: true,
+		},
+		{
+			name:  "engine of the user",
+			flags: gosnmp.AuthNoPriv,
+			sp: &gosnmp.UsmSecurityParameters{UserName: "engine", AuthoritativeEngineID: "\x80\x00\x00\x00\x01\x02\x03\x04",
+				AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "authpass4"},
+			received: true,
+		},
+		{
+			name:  "wrong auth password",
+			flags: gosnmp.AuthNoPriv,
+			sp:    &gosnmp.UsmSecurityParameters{UserName: "authsha", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "wrongpass"},
+		},
+		{
+			name:  "wrong auth protocol",
+			flags: gosnmp.AuthNoPriv,
+			sp:    &gosnmp.UsmSecurityParameters{UserName: "authsha", AuthenticationProtocol: gosnmp.MD5, AuthenticationPassphrase: "authpass1"},
+		},
+		{
+			name:  "wrong priv password",
+			flags: gosnmp.AuthPriv,
+			sp: &gosnmp.UsmSecurityParameters{UserName: "authpriv", AuthenticationProtocol: gosnmp.SHA256, AuthenticationPassphrase: "authpass2",
+				PrivacyProtocol: gosnmp.AES, PrivacyPassphrase: "wrongpass"},
+		},
+		{
+			name:  "unknown user",
+			flags: gosnmp.AuthNoPriv,
+			sp:    &gosnmp.UsmSecurityParameters{UserName: "unknown", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "authpass1"},
+		},
+		{
+			name:  "other engine",
+			flags: gosnmp.AuthNoPriv,
+			sp:    &gosnmp.UsmSecurityParameters{UserName: "engine", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "authpass4"},
+		},
+		{
+			name:  "no auth of an auth user",
+			flags: gosnmp.NoAuthNoPriv,
+			sp:    &gosnmp.UsmSecurityParameters{UserName: "authsha"},
+		},
+		{
+			name:  "no priv of a priv user",
+			flags: gosnmp.AuthNoPriv,
+			sp:    &gosnmp.UsmSecurityParameters{UserName: "authpriv", AuthenticationProtocol: gosnmp.SHA256, AuthenticationPassphrase: "authpass2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SnmpTrap{V3Users: v3Users}
+			acc, port := listen(t, s)
+			defer s.Stop()
+
+			g := v3Sender(t, port, tt.flags, tt.sp, false)
+			defer g.Conn.Close()
+			_, err := g.SendTrap(linkUp(2))
+			require.NoError(t, err)
+
+			// the traps are received in order, the trap of the test is
+			// processed once the next one is received
+			next := v3Sender(t, port, gosnmp.AuthNoPriv, &gosnmp.UsmSecurityParameters{
+				UserName: "authsha", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "authpass1"}, false)
+			defer next.Conn.Close()
+			_, err = next.SendTrap(linkUp(3))
+			require.NoError(t, err)
+
+			if !tt.received {
+				acc.Wait(1)
+				require.Len(t, acc.Metrics, 1)
+				assert.Equal(t, 3, acc.Metrics[0].Fields["ifIndex.2"])
+				return
+			}
+
+			acc.Wait(2)
+			require.Len(t, acc.Metrics, 2)
+			m := acc.Metrics[0]
+			assert.Equal(t, 2, m.Fields["ifIndex.2"])
+			assert.Equal(t, uint32(1000), m.Fields["sysUpTimeInstance"])
+			assert.Equal(t, map[string]string{
+				"source":    "127.0.0.1",
+				"version":   "3",
+				"engine_id": hex.EncodeToString([]byte(tt.sp.AuthoritativeEngineID)),
+				"username":  tt.sp.UserName,
+				"oid":       ".1.3.6.1.6.3.1.1.5.4",
+				"name":      "linkUp",
+				"mib":       "IF-MIB",
+			}, m.Tags)
+		})
+	}
+}
+
+func TestV3Inform(t *testing.T) {
+	s := &SnmpTrap{EngineID: "80001f8880e9bd0c1d12667a5100000000", V3Users: v3Users}
+	acc, port := listen(t, s)
+	defer s.Stop()
+
+	// the sender discovers the engine of the listener
+	g := v3Sender(t, port, gosnmp.AuthPriv, &gosnmp.UsmSecurityParameters{
+		UserName: "authpriv", AuthenticationProtocol: gosnmp.SHA256, AuthenticationPassphrase: "authpass2",
+		PrivacyProtocol: gosnmp.AES, PrivacyPassphrase: "privpass2"}, true)
+	defer g.Conn.Close()
+	inform := linkUp(2)
+	inform.IsInform = true
+	response, err := g.SendTrap(inform)
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.GetResponse, response.PDUType)
+
+	acc.Wait(1)
+	assert.Equal(t, "80001f8880e9bd0c1d12667a5100000000", acc.TagValue("snmp_trap", "engine_id"))
+	assert.Equal(t, "authpriv", acc.TagValue("snmp_trap", "username"))
+}
+
+func TestV3InformWithoutEngineID(t *testing.T) {
+	s := &SnmpTrap{V3Users: v3Users}
+	acc, port := listen(t, s)
+	defer s.Stop()
+
+	g := v3Sender(t, port, gosnmp.AuthNoPriv, &gosnmp.UsmSecurityParameters{
+		UserName: "authsha", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "authpass1"}, true)
+	g.Timeout = 100 * time.Millisecond
+	g.Retries = 0
+	defer g.Conn.Close()
+	inform := linkUp(2)
+	inform.IsInform = true
+	_, err := g.SendTrap(inform)
+	require.Error(t, err)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *SnmpTrap
+		err  string
+	}{
+		{
+			name: "invalid engine ID",
+			s:    &SnmpTrap{EngineID: "80zz"},
+			err:  `invalid engine_id "80zz": encoding/hex: invalid byte: U+007A 'z'`,
+		},
+		{
+			name: "short engine ID",
+			s:    &SnmpTrap{EngineID: "0x80000000"},
+			err:  `invalid engine_id "0x80000000": must be 5 to 32 bytes long`,
+		},
+		{
+			name: "no username",
+			s:    &SnmpTrap{V3Users: []V3User{{AuthProtocol: "SHA"}}},
+			err:  `v3_user "": username must be set`,
+		},
+		{
+			name: "invalid auth protocol",
+			s:    &SnmpTrap{V3Users: []V3User{{Username: "u", AuthProtocol: "SHA1", AuthPassword: "pass"}}},
+			err:  `v3_user "u": invalid auth_protocol "SHA1"`,
+		},
+		{
+			name: "no auth password",
+			s:    &SnmpTrap{V3Users: []V3User{{Username: "u", AuthProtocol: "SHA"}}},
+			err:  `v3_user "u": auth_password must be set`,
+		},
+		{
+			name: "priv without auth",
+			s:    &SnmpTrap{V3Users: []V3User{{Username: "u", PrivProtocol: "AES", PrivPassword: "pass"}}},
+			err:  `v3_user "u": priv_protocol requires an auth_protocol`,
+		},
+		{
+			name: "invalid priv protocol",
+			s:    &SnmpTrap{V3Users: []V3User{{Username: "u", AuthProtocol: "SHA", AuthPassword: "pass", PrivProtocol: "3DES", PrivPassword: "pass"}}},
+			err:  `v3_user "u": invalid priv_protocol "3DES"`,
+		},
+		{
+			name: "no priv password",
+			s:    &SnmpTrap{V3Users: []V3User{{Username: "u", AuthProtocol: "SHA", AuthPassword: "pass", PrivProtocol: "AES"}}},
+			err:  `v3_user "u": priv_password must be set`,
+		},
+		{
+			name: "duplicate user",
+			s:    &SnmpTrap{V3Users: []V3User{{Username: "u"}, {Username: "u", AuthProtocol: "SHA", AuthPassword: "pass"}}},
+			err:  `v3_user "u": duplicate user of the engine`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.s.Init()
+			require.Error(t, err)
+			assert.Equal(t, tt.err, err.Error())
+		})
+	}
+}
+
+func TestMessageVersion(t *testing.T) {
+	for _, msg := range [][]byte{
+		nil,
+		{0x30},
+		{0x04, 0x03, 0x02, 0x01, 0x01},
+		{0x30, 0x03, 0x02, 0x01, 0x02},
+		{0x30, 0x81},
+	} {
+		_, err := messageVersion(msg)
+		assert.Error(t, err, "%x", msg)
+	}
+
+	version, err := messageVersion([]byte{0x30, 0x81, 0x80, 0x02, 0x01, 0x03})
+	require.NoError(t, err)
+	assert.Equal(t, gosnmp.Version3, version)
+}
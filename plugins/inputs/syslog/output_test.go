@@ -0,0 +1,61 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	syslogout "github.com/influxdata/telegraf/plugins/outputs/syslog"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// TestOutputRoundTrip checks that the metrics of the receiver written by the
+// syslog output are received as is.
+func TestOutputRoundTrip(t *testing.T) {
+	tags := map[string]string{
+		"severity": "warning",
+		"facility": "system daemons",
+		"hostname": "web01",
+		"appname":  "nginx",
+	}
+	fields := map[string]interface{}{
+		"version":                uint16(1),
+		"severity_code":          4,
+		"facility_code":          3,
+		"timestamp":              time.Date(2019, 1, 2, 3, 4, 5, 6000, time.UTC).UnixNano(),
+		"procid":                 "1234",
+		"msgid":                  "access",
+		"message":                `GET /search?q="a b"`,
+		"exampleSDID@32473_iut":  "3",
+		"exampleSDID@32473_note": `quoted "value" with \ and ]`,
+		"origin@32473":           true,
+	}
+
+	for _, framing := range []Framing{OctetCounting, NonTransparent} {
+		t.Run(framing.String(), func(t *testing.T) {
+			receiver := newTCPSyslogReceiver("tcp://"+address, nil, 0, false, framing)
+			acc := &testutil.Accumulator{}
+			require.NoError(t, receiver.Start(acc))
+			defer receiver.Stop()
+
+			output := outputs.Outputs["syslog"]().(*syslogout.Syslog)
+			output.Address = "tcp://127.0.0.1" + address
+			output.Framing = framing.String()
+			output.SDIDs = []string{"exampleSDID@32473", "origin@32473"}
+			require.NoError(t, output.Init())
+			require.NoError(t, output.Connect())
+			defer output.Close()
+
+			m := testutil.MustMetric("syslog", tags, fields, time.Unix(0, 0))
+			require.NoError(t, output.Write([]telegraf.Metric{m}))
+
+			acc.Wait(1)
+			require.Empty(t, acc.Errors)
+			require.Equal(t, tags, acc.Metrics[0].Tags)
+			require.Equal(t, fields, acc.Metrics[0].Fields)
+		})
+	}
+}
@@ -16,15 +16,26 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
-type SystemStats struct{}
+type SystemStats struct {
+	MonitorUnits []string `toml:"monitor_units"`
+
+	systemctl string
+}
 
 func (_ *SystemStats) Description() string {
 	return "Read metrics about system load & uptime"
 }
 
-func (_ *SystemStats) SampleConfig() string { return "" }
+var sampleConfig = `
+  ## List of systemd units to report the state of using systemctl.
+  # monitor_units = ["sshd.service"]
+`
+
+func (_ *SystemStats) SampleConfig() string {
+	return sampleConfig
+}
 
-func (_ *SystemStats) Gather(acc telegraf.Accumulator) error {
+func (s *SystemStats) Gather(acc telegraf.Accumulator) error {
 	loadavg, err := load.Avg()
 	if err != nil && !strings.Contains(err.Error(), "not implemented") {
 		return err
@@ -59,6 +70,10 @@ func (_ *SystemStats) Gather(acc telegraf.Accumulator) error {
 		"uptime_format": format_uptime(hostinfo.Uptime),
 	}, nil, now)
 
+	if len(s.MonitorUnits) > 0 {
+		s.gatherUnits(acc, now)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,101 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+var (
+	execCommand = exec.Command // execCommand is used to mock commands in tests.
+)
+
+const systemctlTimeout = 5 * time.Second
+
+// unitState holds the states of a systemd unit as reported by systemctl.
+type unitState struct {
+	LoadState   string
+	ActiveState string
+	SubState    string
+}
+
+// gatherUnits emits a system_units metric for each of the monitored units.
+func (s *SystemStats) gatherUnits(acc telegraf.Accumulator, now time.Time) {
+	if s.systemctl == "" {
+		path, err := exec.LookPath("systemctl")
+		if err != nil {
+			acc.AddError(errors.New("systemctl not found: verify that systemd is installed and that systemctl is in your PATH"))
+			return
+		}
+		s.systemctl = path
+	}
+
+	for _, unit := range s.MonitorUnits {
+		state, err := s.showUnit(unit)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+
+		active := 0
+		if state.ActiveState == "active" {
+			active = 1
+		}
+
+		tags := map[string]string{
+			"unit":         unit,
+			"load_state":   state.LoadState,
+			"active_state": state.ActiveState,
+			"sub_state":    state.SubState,
+		}
+		fields := map[string]interface{}{
+			"active": active,
+		}
+		acc.AddGauge("system_units", fields, tags, now)
+	}
+}
+
+func (s *SystemStats) showUnit(unit string) (*unitState, error) {
+	cmd := execCommand(s.systemctl, "show", "--property=ActiveState,SubState,LoadState", unit)
+	out, err := internal.CombinedOutputTimeout(cmd, systemctlTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return parseUnitState(out)
+}
+
+// parseUnitState parses the output of "systemctl show" for a single unit.
+// Units that do not exist are not an error for systemctl, they are reported
+// with a LoadState of "not-found".
+func parseUnitState(out []byte) (*unitState, error) {
+	state := &unitState{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "LoadState":
+			state.LoadState = parts[1]
+		case "ActiveState":
+			state.ActiveState = parts[1]
+		case "SubState":
+			state.SubState = parts[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if state.LoadState == "" || state.ActiveState == "" {
+		return nil, fmt.Errorf("unexpected systemctl output: %q", string(out))
+	}
+	return state, nil
+}
@@ -0,0 +1,124 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnitState(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected *unitState
+		err      bool
+	}{
+		{
+			name:   "active unit",
+			output: "ActiveState=active\nSubState=running\nLoadState=loaded\n",
+			expected: &unitState{
+				LoadState:   "loaded",
+				ActiveState: "active",
+				SubState:    "running",
+			},
+		},
+		{
+			name:   "unknown unit",
+			output: "ActiveState=inactive\nSubState=dead\nLoadState=not-found\n",
+			expected: &unitState{
+				LoadState:   "not-found",
+				ActiveState: "inactive",
+				SubState:    "dead",
+			},
+		},
+		{
+			name:   "garbage",
+			output: "Failed to connect to bus: No such file or directory\n",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := parseUnitState([]byte(tt.output))
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, state)
+		})
+	}
+}
+
+func TestGatherUnits(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	s := &SystemStats{
+		MonitorUnits: []string{"sshd.service", "missing.service"},
+		systemctl:    "systemctl",
+	}
+
+	var acc testutil.Accumulator
+	s.gatherUnits(&acc, time.Now())
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "system_units",
+		map[string]interface{}{"active": 1},
+		map[string]string{
+			"unit":         "sshd.service",
+			"load_state":   "loaded",
+			"active_state": "active",
+			"sub_state":    "running",
+		})
+	acc.AssertContainsTaggedFields(t, "system_units",
+		map[string]interface{}{"active": 0},
+		map[string]string{
+			"unit":         "missing.service",
+			"load_state":   "not-found",
+			"active_state": "inactive",
+			"sub_state":    "dead",
+		})
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command
+// For example, if you run:
+// GO_WANT_HELPER_PROCESS=1 go test -test.run=TestHelperProcess -- systemctl show --property=ActiveState,SubState,LoadState sshd.service
+// it returns the state of an active unit.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	cmd, args := args[3], args[4:]
+
+	if cmd != "systemctl" || len(args) != 3 || args[0] != "show" {
+		fmt.Fprint(os.Stdout, "command not found")
+		os.Exit(1)
+	}
+
+	switch args[2] {
+	case "sshd.service":
+		fmt.Fprint(os.Stdout, "ActiveState=active\nSubState=running\nLoadState=loaded\n")
+	default:
+		fmt.Fprint(os.Stdout, "ActiveState=inactive\nSubState=dead\nLoadState=not-found\n")
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,222 @@
+// +build linux
+
+package systemd_units
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// SystemdUnits is a telegraf plugin to gather systemd unit status
+type SystemdUnits struct {
+	Timeout          internal.Duration `toml:"timeout"`
+	UnitType         string            `toml:"unittype"`
+	TrackTransitions bool              `toml:"track_transitions"`
+	MaxTrackedUnits  int               `toml:"max_tracked_units"`
+
+	systemctl systemctl
+
+	// states are the active states of the units of the last gather
+	states map[string]string
+	full   bool
+}
+
+type systemctl func(timeout time.Duration, unitType string) (*bytes.Buffer, error)
+
+const measurement = "systemd_units"
+
+// The codes of the load, active and sub states, as listed by
+// "systemctl --state=help".
+var loadMap = map[string]int{
+	"loaded":      0,
+	"stub":        1,
+	"not-found":   2,
+	"bad-setting": 3,
+	"error":       4,
+	"merged":      5,
+	"masked":      6,
+}
+
+var activeMap = map[string]int{
+	"active":       0,
+	"reloading":    1,
+	"inactive":     2,
+	"failed":       3,
+	"activating":   4,
+	"deactivating": 5,
+}
+
+var subMap = map[string]int{
+	// service_state_table
+	"dead":          0,
+	"start-pre":     1,
+	"start":         2,
+	"exited":        3,
+	"running":       4,
+	"start-post":    5,
+	"reload":        6,
+	"stop":          7,
+	"stop-watchdog": 8,
+	"stop-sigterm":  9,
+	"stop-sigkill":  10,
+	"stop-post":     11,
+	"final-sigterm": 12,
+	"final-sigkill": 13,
+	"failed":        14,
+	"auto-restart":  15,
+
+	// other unit types
+	"waiting":    16,
+	"listening":  17,
+	"mounted":    18,
+	"plugged":    19,
+	"elapsed":    20,
+	"abandoned":  21,
+	"tentative":  22,
+	"mounting":   23,
+	"unmounting": 24,
+}
+
+var (
+	defaultTimeout         = internal.Duration{Duration: time.Second}
+	defaultUnitType        = "service"
+	defaultMaxTrackedUnits = 1000
+)
+
+// Description returns a short description of the plugin
+func (s *SystemdUnits) Description() string {
+	return "Gather systemd units state"
+}
+
+// SampleConfig returns sample configuration options.
+func (s *SystemdUnits) SampleConfig() string {
+	return `
+  ## Set timeout for systemctl execution
+  # timeout = "1s"
+  #
+  ## Filter for a specific unit type, default is "service", other possible
+  ## values are "socket", "target", "device", "mount", "automount", "swap",
+  ## "timer", "path", "slice" and "scope":
+  # unittype = "service"
+
+  ## Add a systemd_units_transition metric when the active state of a unit
+  ## changed since the last gather, like from "active" to "failed".
+  # track_transitions = false
+
+  ## Maximum number of units whose state is tracked for the transitions, the
+  ## units above are not tracked.
+  # max_tracked_units = 1000
+`
+}
+
+// Gather parses systemctl outputs and adds counters to the Accumulator
+func (s *SystemdUnits) Gather(acc telegraf.Accumulator) error {
+	out, err := s.systemctl(s.Timeout.Duration, s.UnitType)
+	if err != nil {
+		return err
+	}
+
+	states := make(map[string]string)
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		data := strings.Fields(line)
+		if len(data) < 4 {
+			acc.AddError(fmt.Errorf("Error parsing line (expected at least 4 fields): %s", line))
+			continue
+		}
+		name, load, active, sub := data[0], data[1], data[2], data[3]
+		tags := map[string]string{
+			"name":   name,
+			"load":   load,
+			"active": active,
+			"sub":    sub,
+		}
+
+		fields := make(map[string]interface{})
+		if code, ok := loadMap[load]; ok {
+			fields["load_code"] = code
+		}
+		if code, ok := activeMap[active]; ok {
+			fields["active_code"] = code
+		}
+		if code, ok := subMap[sub]; ok {
+			fields["sub_code"] = code
+		}
+		acc.AddFields(measurement, fields, tags)
+
+		if s.TrackTransitions {
+			s.track(acc, states, name, active)
+		}
+	}
+
+	if s.TrackTransitions {
+		// the units gone are forgotten
+		s.states = states
+	}
+	return nil
+}
+
+// track adds the transition of the unit since the last gather, the units not
+// seen before have none.
+func (s *SystemdUnits) track(acc telegraf.Accumulator, states map[string]string, name, active string) {
+	previous, ok := s.states[name]
+	if !ok && len(states) >= s.MaxTrackedUnits {
+		if !s.full {
+			log.Printf("W! [inputs.systemd_units] tracking the transitions of %d units at most", s.MaxTrackedUnits)
+			s.full = true
+		}
+		return
+	}
+	states[name] = active
+
+	if ok && previous != active {
+		tags := map[string]string{
+			"name":       name,
+			"from_state": previous,
+			"to_state":   active,
+		}
+		acc.AddFields(measurement+"_transition", map[string]interface{}{"transition": 1}, tags)
+	}
+}
+
+func runSystemctl(timeout time.Duration, unitType string) (*bytes.Buffer, error) {
+	// is systemctl available ?
+	systemctlPath, err := exec.LookPath("systemctl")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(systemctlPath, "list-units", "--all", "--plain",
+		fmt.Sprintf("--type=%s", unitType), "--no-legend")
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err = internal.RunTimeout(cmd, timeout)
+	if err != nil {
+		return &out, fmt.Errorf("error running systemctl list-units --all --plain --type=%s --no-legend: %s", unitType, err)
+	}
+
+	return &out, nil
+}
+
+func init() {
+	inputs.Add("systemd_units", func() telegraf.Input {
+		return &SystemdUnits{
+			systemctl:       runSystemctl,
+			Timeout:         defaultTimeout,
+			UnitType:        defaultUnitType,
+			MaxTrackedUnits: defaultMaxTrackedUnits,
+		}
+	})
+}
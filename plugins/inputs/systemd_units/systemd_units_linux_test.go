@@ -0,0 +1,102 @@
+// +build linux
+
+package systemd_units
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// $ systemctl list-units --all --plain --type=service --no-legend
+const listUnits = `auditd.service loaded active running Security Auditing Service
+dbus.service loaded active running D-Bus System Message Bus
+sshd.service loaded active running OpenSSH server daemon
+nfs.service not-found inactive dead nfs.service
+`
+
+const listUnitsFailed = `auditd.service loaded active running Security Auditing Service
+dbus.service loaded active running D-Bus System Message Bus
+sshd.service loaded failed failed OpenSSH server daemon
+nfs.service not-found inactive dead nfs.service
+`
+
+func newSystemdUnits(outputs ...string) *SystemdUnits {
+	return &SystemdUnits{
+		MaxTrackedUnits: defaultMaxTrackedUnits,
+		systemctl: func(timeout time.Duration, unitType string) (*bytes.Buffer, error) {
+			out := outputs[0]
+			if len(outputs) > 1 {
+				outputs = outputs[1:]
+			}
+			return bytes.NewBufferString(out), nil
+		},
+	}
+}
+
+func TestSystemdUnits(t *testing.T) {
+	s := newSystemdUnits(listUnits)
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+	acc.AssertContainsTaggedFields(t, "systemd_units",
+		map[string]interface{}{"load_code": 0, "active_code": 0, "sub_code": 4},
+		map[string]string{"name": "sshd.service", "load": "loaded", "active": "active", "sub": "running"})
+	acc.AssertContainsTaggedFields(t, "systemd_units",
+		map[string]interface{}{"load_code": 2, "active_code": 2, "sub_code": 0},
+		map[string]string{"name": "nfs.service", "load": "not-found", "active": "inactive", "sub": "dead"})
+}
+
+func TestSystemdUnitsTransition(t *testing.T) {
+	s := newSystemdUnits(listUnits, listUnitsFailed)
+	s.TrackTransitions = true
+
+	// the units seen for the first time have no transition
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.False(t, acc.HasMeasurement("systemd_units_transition"))
+
+	acc.ClearMetrics()
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Metrics, 5)
+	acc.AssertContainsTaggedFields(t, "systemd_units_transition",
+		map[string]interface{}{"transition": 1},
+		map[string]string{"name": "sshd.service", "from_state": "active", "to_state": "failed"})
+	acc.AssertContainsTaggedFields(t, "systemd_units",
+		map[string]interface{}{"load_code": 0, "active_code": 3, "sub_code": 14},
+		map[string]string{"name": "sshd.service", "load": "loaded", "active": "failed", "sub": "failed"})
+
+	// the state unchanged has no other transition
+	acc.ClearMetrics()
+	require.NoError(t, s.Gather(&acc))
+	require.False(t, acc.HasMeasurement("systemd_units_transition"))
+}
+
+func TestSystemdUnitsMaxTracked(t *testing.T) {
+	s := newSystemdUnits(listUnits, listUnitsFailed)
+	s.TrackTransitions = true
+	s.MaxTrackedUnits = 2
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, s.states, 2)
+
+	// sshd.service is not tracked
+	acc.ClearMetrics()
+	require.NoError(t, s.Gather(&acc))
+	require.False(t, acc.HasMeasurement("systemd_units_transition"))
+}
+
+func TestSystemdUnitsParseError(t *testing.T) {
+	s := newSystemdUnits("invalid.service loaded\n")
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Empty(t, acc.Metrics)
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+package systemd_units
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type SystemdUnits struct {
+}
+
+func (s *SystemdUnits) Description() string {
+	return "Gather systemd units state"
+}
+
+func (s *SystemdUnits) SampleConfig() string { return "" }
+
+func (s *SystemdUnits) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("systemd_units", func() telegraf.Input {
+		return &SystemdUnits{}
+	})
+}
@@ -0,0 +1,275 @@
+package tcp_query
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+var sampleConfig = `
+  ## Servers to query, each with its own connection.
+  servers = ["localhost:4000"]
+
+  ## Timeout of the connection, and of the commands not setting their own.
+  # timeout = "5s"
+
+  ## Keep the connection open between the gathers, the commands run once
+  ## per connection are not sent again until it is reconnected.
+  # reuse_connection = false
+
+  ## Delimiters appended to the requests and ending the responses, of the
+  ## commands not setting their own.
+  # request_delimiter = "\n"
+  # response_delimiter = "\n"
+
+  ## Maximum size of a response.
+  # max_response_size = "1MB"
+
+  ## Data format to parse the responses with, the response delimiter is
+  ## removed from the responses.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+
+  ## Commands sent in order over the connection, each sending its request
+  ## and reading the response up to the response delimiter.  A command
+  ## without request only reads a response, such as the greeting of the
+  ## server.
+  [[inputs.tcp_query.command]]
+    request = "stats"
+    ## Delimiters and timeout of the command.
+    # request_delimiter = "\n"
+    # response_delimiter = "END\n"
+    # timeout = "5s"
+    ## Name of the metrics parsed from the response.
+    # measurement = ""
+    ## Send the command only once per connection, such as a login.
+    # once = false
+    ## Do not parse the response.
+    # discard_response = false
+`
+
+type TCPQuery struct {
+	Servers           []string          `toml:"servers"`
+	Timeout           internal.Duration `toml:"timeout"`
+	ReuseConnection   bool              `toml:"reuse_connection"`
+	RequestDelimiter  string            `toml:"request_delimiter"`
+	ResponseDelimiter string            `toml:"response_delimiter"`
+	MaxResponseSize   internal.Size     `toml:"max_response_size"`
+	Commands          []Command         `toml:"command"`
+
+	parser parsers.Parser
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// Command is a request and its response.
+type Command struct {
+	Request           string            `toml:"request"`
+	RequestDelimiter  string            `toml:"request_delimiter"`
+	ResponseDelimiter string            `toml:"response_delimiter"`
+	Timeout           internal.Duration `toml:"timeout"`
+	Measurement       string            `toml:"measurement"`
+	Once              bool              `toml:"once"`
+	DiscardResponse   bool              `toml:"discard_response"`
+}
+
+// session is a connection to a server.
+type session struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// fresh until the commands sent once per connection are sent
+	fresh bool
+}
+
+func (t *TCPQuery) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *TCPQuery) Description() string {
+	return "Send commands over TCP and parse the responses"
+}
+
+func (t *TCPQuery) SetParser(parser parsers.Parser) {
+	t.parser = parser
+}
+
+// Init sets the delimiters and timeouts of the commands not setting their
+// own.
+func (t *TCPQuery) Init() error {
+	if len(t.Commands) == 0 {
+		return errors.New("no command")
+	}
+	for i := range t.Commands {
+		cmd := &t.Commands[i]
+		if cmd.RequestDelimiter == "" {
+			cmd.RequestDelimiter = t.RequestDelimiter
+		}
+		if cmd.ResponseDelimiter == "" {
+			cmd.ResponseDelimiter = t.ResponseDelimiter
+		}
+		if cmd.ResponseDelimiter == "" {
+			return fmt.Errorf("command %q: no response delimiter", cmd.Request)
+		}
+		if cmd.Timeout.Duration == 0 {
+			cmd.Timeout = t.Timeout
+		}
+	}
+	t.sessions = make(map[string]*session)
+	return nil
+}
+
+func (t *TCPQuery) Gather(acc telegraf.Accumulator) error {
+	if t.parser == nil {
+		return errors.New("Parser is not set")
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range t.Servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			if err := t.gatherServer(acc, server); err != nil {
+				acc.AddError(fmt.Errorf("[server=%s]: %s", server, err))
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// gatherServer runs the commands over the connection to the server.  The
+// connection is closed on errors, and after the commands unless reused.
+func (t *TCPQuery) gatherServer(acc telegraf.Accumulator, server string) error {
+	s, err := t.session(server)
+	if err != nil {
+		return err
+	}
+
+	for i := range t.Commands {
+		cmd := &t.Commands[i]
+		if cmd.Once && !s.fresh {
+			continue
+		}
+
+		response, err := s.query(cmd, t.MaxResponseSize.Size)
+		if err != nil {
+			t.closeSession(server)
+			return fmt.Errorf("command %q: %v", cmd.Request, err)
+		}
+		if cmd.DiscardResponse {
+			continue
+		}
+
+		metrics, err := t.parser.Parse(response)
+		if err != nil {
+			acc.AddError(fmt.Errorf("[server=%s]: command %q: %v", server, cmd.Request, err))
+			continue
+		}
+		for _, metric := range metrics {
+			if cmd.Measurement != "" {
+				metric.SetName(cmd.Measurement)
+			}
+			if !metric.HasTag("server") {
+				metric.AddTag("server", server)
+			}
+			acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+		}
+	}
+	s.fresh = false
+
+	if !t.ReuseConnection {
+		t.closeSession(server)
+	}
+	return nil
+}
+
+// session returns the open connection to the server, or connects to it.
+func (t *TCPQuery) session(server string) (*session, error) {
+	t.mu.Lock()
+	s, ok := t.sessions[server]
+	t.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	conn, err := net.DialTimeout("tcp", server, t.Timeout.Duration)
+	if err != nil {
+		return nil, err
+	}
+	s = &session{conn: conn, reader: bufio.NewReader(conn), fresh: true}
+
+	t.mu.Lock()
+	t.sessions[server] = s
+	t.mu.Unlock()
+	return s, nil
+}
+
+func (t *TCPQuery) closeSession(server string) {
+	t.mu.Lock()
+	s, ok := t.sessions[server]
+	delete(t.sessions, server)
+	t.mu.Unlock()
+	if ok {
+		s.conn.Close()
+	}
+}
+
+// query sends the request of the command and returns its response, without
+// the response delimiter.
+func (s *session) query(cmd *Command, maxSize int64) ([]byte, error) {
+	if cmd.Timeout.Duration > 0 {
+		s.conn.SetDeadline(time.Now().Add(cmd.Timeout.Duration))
+	}
+	if cmd.Request != "" {
+		if _, err := io.WriteString(s.conn, cmd.Request+cmd.RequestDelimiter); err != nil {
+			return nil, err
+		}
+	}
+	return readResponse(s.reader, []byte(cmd.ResponseDelimiter), maxSize)
+}
+
+// readResponse reads up to the delimiter, over as many reads as the response
+// is split in, and returns the response without the delimiter.  The bytes
+// after the delimiter are left in the reader.
+func readResponse(r *bufio.Reader, delimiter []byte, maxSize int64) ([]byte, error) {
+	last := delimiter[len(delimiter)-1]
+	var response []byte
+	for {
+		chunk, err := r.ReadSlice(last)
+		response = append(response, chunk...)
+		if err == nil && bytes.HasSuffix(response, delimiter) {
+			return response[:len(response)-len(delimiter)], nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		if maxSize > 0 && int64(len(response)) > maxSize {
+			return nil, fmt.Errorf("response larger than %d bytes", maxSize)
+		}
+	}
+}
+
+func init() {
+	inputs.Add("tcp_query", func() telegraf.Input {
+		return &TCPQuery{
+			Timeout:           internal.Duration{Duration: 5 * time.Second},
+			RequestDelimiter:  "\n",
+			ResponseDelimiter: "\n",
+			MaxResponseSize:   internal.Size{Size: 1024 * 1024},
+		}
+	})
+}
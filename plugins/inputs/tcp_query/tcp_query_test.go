@@ -0,0 +1,237 @@
+package tcp_query
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// server is a mock server greeting the clients, then answering the login
+// and stats requests.  The stats response is written in several parts.
+type server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu          sync.Mutex
+	connections int
+	conns       []net.Conn
+	requests    []string
+}
+
+func newServer(t *testing.T) *server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &server{listener: l}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+func (s *server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.connections++
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+
+	conn.Write([]byte("WELCOME\r\n"))
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		request := strings.TrimSpace(line)
+		s.mu.Lock()
+		s.requests = append(s.requests, request)
+		s.mu.Unlock()
+
+		switch request {
+		case "login user secret":
+			conn.Write([]byte("OK\r\n"))
+		case "stats":
+			for _, part := range []string{
+				"queue,name=jobs depth=3i 1600000000000000000\n",
+				"queue,name=mail depth=1",
+				"5i 1600000000000000000\nEN",
+				"D\r\n",
+			} {
+				conn.Write([]byte(part))
+				time.Sleep(10 * time.Millisecond)
+			}
+		case "sleep":
+			time.Sleep(time.Second)
+		default:
+			conn.Write([]byte("ERROR unknown command\r\n"))
+		}
+	}
+}
+
+// close closes the listener and the connections left open by the client.
+func (s *server) close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *server) stats() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections, append([]string(nil), s.requests...)
+}
+
+func newTestTCPQuery(t *testing.T, address string) *TCPQuery {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+
+	q := &TCPQuery{
+		Servers:           []string{address},
+		Timeout:           internal.Duration{Duration: time.Second},
+		RequestDelimiter:  "\n",
+		ResponseDelimiter: "\r\n",
+		Commands: []Command{
+			{Once: true, DiscardResponse: true},
+			{Request: "login user secret", Once: true, DiscardResponse: true},
+			{Request: "stats", ResponseDelimiter: "END\r\n"},
+		},
+	}
+	q.SetParser(parser)
+	require.NoError(t, q.Init())
+	return q
+}
+
+func TestGatherSession(t *testing.T) {
+	s := newServer(t)
+	defer s.close()
+	address := s.listener.Addr().String()
+
+	q := newTestTCPQuery(t, address)
+	q.Commands[2].Measurement = "broker_queue"
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(q.Gather))
+
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "broker_queue",
+		map[string]interface{}{"depth": int64(3)},
+		map[string]string{"name": "jobs", "server": address})
+	acc.AssertContainsTaggedFields(t, "broker_queue",
+		map[string]interface{}{"depth": int64(15)},
+		map[string]string{"name": "mail", "server": address})
+	require.True(t, acc.HasTimestamp("broker_queue", time.Unix(0, 1600000000000000000)))
+
+	connections, requests := s.stats()
+	require.Equal(t, 1, connections)
+	require.Equal(t, []string{"login user secret", "stats"}, requests)
+	require.Len(t, q.sessions, 0)
+}
+
+func TestGatherReuseConnection(t *testing.T) {
+	s := newServer(t)
+	defer s.close()
+
+	q := newTestTCPQuery(t, s.listener.Addr().String())
+	q.ReuseConnection = true
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(q.Gather))
+	require.NoError(t, acc.GatherError(q.Gather))
+	require.Len(t, acc.Metrics, 4)
+
+	// the greeting and login are only read and sent on the new connection
+	connections, requests := s.stats()
+	require.Equal(t, 1, connections)
+	require.Equal(t, []string{"login user secret", "stats", "stats"}, requests)
+}
+
+func TestGatherTimeoutReconnects(t *testing.T) {
+	s := newServer(t)
+	defer s.close()
+
+	q := newTestTCPQuery(t, s.listener.Addr().String())
+	q.ReuseConnection = true
+	q.Commands[2] = Command{Request: "sleep", RequestDelimiter: "\n", ResponseDelimiter: "\r\n", Timeout: internal.Duration{Duration: 50 * time.Millisecond}}
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(q.Gather))
+	require.Len(t, q.sessions, 0)
+
+	q.Commands[2] = Command{Request: "stats", RequestDelimiter: "\n", ResponseDelimiter: "END\r\n", Timeout: q.Timeout}
+	acc = testutil.Accumulator{}
+	require.NoError(t, acc.GatherError(q.Gather))
+	require.Len(t, acc.Metrics, 2)
+
+	connections, _ := s.stats()
+	require.Equal(t, 2, connections)
+}
+
+func TestGatherParseError(t *testing.T) {
+	s := newServer(t)
+	defer s.close()
+
+	q := newTestTCPQuery(t, s.listener.Addr().String())
+	q.Commands = append([]Command{q.Commands[0], {Request: "version", RequestDelimiter: "\n", ResponseDelimiter: "\r\n", Timeout: q.Timeout}}, q.Commands[1:]...)
+
+	// the session goes on after the response not parsed
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(q.Gather))
+	require.Len(t, acc.Metrics, 2)
+}
+
+func TestGatherMaxResponseSize(t *testing.T) {
+	s := newServer(t)
+	defer s.close()
+
+	q := newTestTCPQuery(t, s.listener.Addr().String())
+	q.MaxResponseSize = internal.Size{Size: 16}
+
+	var acc testutil.Accumulator
+	require.Error(t, acc.GatherError(q.Gather))
+	require.Len(t, acc.Metrics, 0)
+}
+
+func TestReadResponse(t *testing.T) {
+	r := bufio.NewReaderSize(strings.NewReader("a=1\r\nb=2\nEND\r\nnext\r\n"), 16)
+
+	response, err := readResponse(r, []byte("END\r\n"), 0)
+	require.NoError(t, err)
+	require.Equal(t, "a=1\r\nb=2\n", string(response))
+
+	response, err = readResponse(r, []byte("\r\n"), 0)
+	require.NoError(t, err)
+	require.Equal(t, "next", string(response))
+
+	_, err = readResponse(r, []byte("\r\n"), 0)
+	require.Error(t, err)
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&TCPQuery{}).Init())
+	require.Error(t, (&TCPQuery{Commands: []Command{{Request: "stats"}}}).Init())
+}
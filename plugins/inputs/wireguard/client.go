@@ -0,0 +1,95 @@
+// +build !freebsd cgo
+
+package wireguard
+
+import (
+	"fmt"
+	"os"
+
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// wgctrlClient reads the WireGuard interfaces with wgctrl, from the kernel
+// module and from the userspace implementations.
+type wgctrlClient struct {
+	client *wgctrl.Client
+}
+
+func newClient() (client, error) {
+	c, err := wgctrl.New()
+	if err != nil {
+		return nil, err
+	}
+	return &wgctrlClient{client: c}, nil
+}
+
+func (c *wgctrlClient) Devices() ([]*device, error) {
+	devices, err := c.client.Devices()
+	if err != nil {
+		return nil, deviceError("", err)
+	}
+	result := make([]*device, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, newDevice(d))
+	}
+	return result, nil
+}
+
+func (c *wgctrlClient) Device(name string) (*device, error) {
+	d, err := c.client.Device(name)
+	if err != nil {
+		return nil, deviceError(name, err)
+	}
+	return newDevice(d), nil
+}
+
+func deviceError(name string, err error) error {
+	what := "reading devices"
+	if name != "" {
+		what = fmt.Sprintf("reading device %q", name)
+	}
+	switch cause := cause(err); {
+	case os.IsPermission(cause):
+		return fmt.Errorf("%s: permission denied, the CAP_NET_ADMIN capability is required", what)
+	case os.IsNotExist(cause):
+		return fmt.Errorf("%s: not a WireGuard interface", what)
+	default:
+		return fmt.Errorf("%s: %v", what, err)
+	}
+}
+
+// cause returns the innermost error wrapped by err, like the errno of a
+// netlink error.
+func cause(err error) error {
+	for {
+		wrapper, ok := err.(interface {
+			Unwrap() error
+		})
+		if !ok || wrapper.Unwrap() == nil {
+			return err
+		}
+		err = wrapper.Unwrap()
+	}
+}
+
+func newDevice(d *wgtypes.Device) *device {
+	result := &device{
+		Name:         d.Name,
+		ListenPort:   d.ListenPort,
+		FirewallMark: d.FirewallMark,
+		Peers:        make([]*peer, 0, len(d.Peers)),
+	}
+	for _, p := range d.Peers {
+		key := p.PublicKey
+		result.Peers = append(result.Peers, &peer{
+			PublicKey:           key[:],
+			PersistentKeepalive: p.PersistentKeepaliveInterval,
+			LastHandshake:       p.LastHandshakeTime,
+			RxBytes:             p.ReceiveBytes,
+			TxBytes:             p.TransmitBytes,
+			AllowedIPs:          len(p.AllowedIPs),
+		})
+	}
+	return result
+}
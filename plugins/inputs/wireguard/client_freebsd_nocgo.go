@@ -0,0 +1,9 @@
+// +build freebsd,!cgo
+
+package wireguard
+
+import "errors"
+
+func newClient() (client, error) {
+	return nil, errors.New("wireguard on FreeBSD requires telegraf built with cgo")
+}
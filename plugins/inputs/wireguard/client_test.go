@@ -0,0 +1,80 @@
+// +build !freebsd cgo
+
+package wireguard
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func TestNewDevice(t *testing.T) {
+	var key1, key2 wgtypes.Key
+	copy(key1[:], "0123456789abcdef0123456789abcdef")
+	copy(key2[:], "fedcba9876543210fedcba9876543210")
+
+	d := newDevice(&wgtypes.Device{
+		Name:         "wg0",
+		Type:         wgtypes.LinuxKernel,
+		ListenPort:   51820,
+		FirewallMark: 42,
+		Peers: []wgtypes.Peer{
+			{
+				PublicKey:                   key1,
+				PersistentKeepaliveInterval: 25 * time.Second,
+				LastHandshakeTime:           time.Unix(1500000000, 0),
+				ReceiveBytes:                1000,
+				TransmitBytes:               2000,
+				AllowedIPs: []net.IPNet{
+					{IP: net.ParseIP("10.0.0.2"), Mask: net.CIDRMask(32, 32)},
+					{IP: net.ParseIP("10.1.0.0"), Mask: net.CIDRMask(16, 32)},
+				},
+			},
+			{
+				PublicKey: key2,
+			},
+		},
+	})
+
+	require.Equal(t, &device{
+		Name:         "wg0",
+		ListenPort:   51820,
+		FirewallMark: 42,
+		Peers: []*peer{
+			{
+				PublicKey:           []byte("0123456789abcdef0123456789abcdef"),
+				PersistentKeepalive: 25 * time.Second,
+				LastHandshake:       time.Unix(1500000000, 0),
+				RxBytes:             1000,
+				TxBytes:             2000,
+				AllowedIPs:          2,
+			},
+			{
+				PublicKey: []byte("fedcba9876543210fedcba9876543210"),
+			},
+		},
+	}, d)
+}
+
+// wrapError wraps an error like the netlink errors do.
+type wrapError struct {
+	err error
+}
+
+func (e *wrapError) Error() string { return "netlink receive: " + e.err.Error() }
+func (e *wrapError) Unwrap() error { return e.err }
+
+func TestDeviceError(t *testing.T) {
+	require.EqualError(t, deviceError("wg0", &wrapError{syscall.EPERM}),
+		`reading device "wg0": permission denied, the CAP_NET_ADMIN capability is required`)
+	require.EqualError(t, deviceError("eth0", os.ErrNotExist),
+		`reading device "eth0": not a WireGuard interface`)
+	require.EqualError(t, deviceError("", errors.New("connection refused")),
+		`reading devices: connection refused`)
+}
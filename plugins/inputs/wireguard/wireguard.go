@@ -0,0 +1,137 @@
+package wireguard
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// device is the state of a WireGuard interface.
+type device struct {
+	Name         string
+	ListenPort   int
+	FirewallMark int
+	Peers        []*peer
+}
+
+type peer struct {
+	PublicKey           []byte
+	PersistentKeepalive time.Duration
+	LastHandshake       time.Time
+	RxBytes             int64
+	TxBytes             int64
+	AllowedIPs          int
+}
+
+// client reads the state of the WireGuard interfaces.
+type client interface {
+	// Devices returns all the WireGuard interfaces.
+	Devices() ([]*device, error)
+	// Device returns the named WireGuard interface.
+	Device(name string) (*device, error)
+}
+
+type Wireguard struct {
+	Devices       []string `toml:"devices"`
+	HashPublicKey bool     `toml:"hash_public_key"`
+
+	client client
+	now    func() time.Time
+}
+
+var sampleConfig = `
+  ## The WireGuard interfaces to read, all of them if empty.
+  # devices = ["wg0"]
+
+  ## Tag the peers with a hash of their public key instead of the key.
+  # hash_public_key = false
+`
+
+func (w *Wireguard) Description() string {
+	return "Collect the statistics of the WireGuard interfaces and peers"
+}
+
+func (w *Wireguard) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Wireguard) Gather(acc telegraf.Accumulator) error {
+	if w.client == nil {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		w.client = c
+	}
+
+	if len(w.Devices) == 0 {
+		devices, err := w.client.Devices()
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			w.gatherDevice(acc, d)
+		}
+		return nil
+	}
+
+	for _, name := range w.Devices {
+		d, err := w.client.Device(name)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		w.gatherDevice(acc, d)
+	}
+	return nil
+}
+
+func (w *Wireguard) gatherDevice(acc telegraf.Accumulator, d *device) {
+	now := w.now()
+	acc.AddFields("wireguard_device",
+		map[string]interface{}{
+			"listen_port":   d.ListenPort,
+			"firewall_mark": d.FirewallMark,
+			"peers":         len(d.Peers),
+		},
+		map[string]string{"device": d.Name},
+		now)
+
+	for _, p := range d.Peers {
+		fields := map[string]interface{}{
+			"rx_bytes":             p.RxBytes,
+			"tx_bytes":             p.TxBytes,
+			"persistent_keepalive": int64(p.PersistentKeepalive / time.Second),
+			"allowed_ips":          p.AllowedIPs,
+		}
+		// the time is zero until the first handshake
+		if !p.LastHandshake.IsZero() {
+			fields["last_handshake_seconds_ago"] = int64(now.Sub(p.LastHandshake) / time.Second)
+		}
+		tags := map[string]string{
+			"device":     d.Name,
+			"public_key": w.publicKey(p.PublicKey),
+		}
+		acc.AddFields("wireguard_peer", fields, tags, now)
+	}
+}
+
+// publicKey returns the public key in base64, as shown by wg(8), or the first
+// 16 hexadecimal digits of its SHA-256 hash.
+func (w *Wireguard) publicKey(key []byte) string {
+	if w.HashPublicKey {
+		sum := sha256.Sum256(key)
+		return hex.EncodeToString(sum[:8])
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func init() {
+	inputs.Add("wireguard", func() telegraf.Input {
+		return &Wireguard{now: time.Now}
+	})
+}
@@ -0,0 +1,156 @@
+package wireguard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type mockClient struct {
+	devices []*device
+	err     error
+}
+
+func (c *mockClient) Devices() ([]*device, error) {
+	return c.devices, c.err
+}
+
+func (c *mockClient) Device(name string) (*device, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	for _, d := range c.devices {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return nil, errors.New("reading device \"" + name + "\": not a WireGuard interface")
+}
+
+var now = time.Unix(1500000000, 0)
+
+func testDevices() []*device {
+	return []*device{
+		{
+			Name:         "wg0",
+			ListenPort:   51820,
+			FirewallMark: 0,
+			Peers: []*peer{
+				{
+					PublicKey:           []byte("0123456789abcdef0123456789abcdef"),
+					PersistentKeepalive: 25 * time.Second,
+					LastHandshake:       now.Add(-90 * time.Second),
+					RxBytes:             1000,
+					TxBytes:             2000,
+					AllowedIPs:          2,
+				},
+				{
+					PublicKey: []byte("fedcba9876543210fedcba9876543210"),
+				},
+			},
+		},
+		{
+			Name:       "wg1",
+			ListenPort: 51821,
+		},
+	}
+}
+
+func TestGather(t *testing.T) {
+	w := &Wireguard{
+		client: &mockClient{devices: testDevices()},
+		now:    func() time.Time { return now },
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(w.Gather))
+
+	acc.AssertContainsTaggedFields(t, "wireguard_device",
+		map[string]interface{}{
+			"listen_port":   51820,
+			"firewall_mark": 0,
+			"peers":         2,
+		},
+		map[string]string{"device": "wg0"})
+	acc.AssertContainsTaggedFields(t, "wireguard_device",
+		map[string]interface{}{
+			"listen_port":   51821,
+			"firewall_mark": 0,
+			"peers":         0,
+		},
+		map[string]string{"device": "wg1"})
+	acc.AssertContainsTaggedFields(t, "wireguard_peer",
+		map[string]interface{}{
+			"rx_bytes":                   int64(1000),
+			"tx_bytes":                   int64(2000),
+			"persistent_keepalive":       int64(25),
+			"allowed_ips":                2,
+			"last_handshake_seconds_ago": int64(90),
+		},
+		map[string]string{
+			"device":     "wg0",
+			"public_key": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		})
+	// no handshake yet
+	acc.AssertContainsTaggedFields(t, "wireguard_peer",
+		map[string]interface{}{
+			"rx_bytes":             int64(0),
+			"tx_bytes":             int64(0),
+			"persistent_keepalive": int64(0),
+			"allowed_ips":          0,
+		},
+		map[string]string{
+			"device":     "wg0",
+			"public_key": "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=",
+		})
+	require.Len(t, acc.Metrics, 4)
+}
+
+func TestGatherDevices(t *testing.T) {
+	w := &Wireguard{
+		Devices: []string{"wg1", "eth0"},
+		client:  &mockClient{devices: testDevices()},
+		now:     func() time.Time { return now },
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, w.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), `"eth0"`)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "wg1", acc.Metrics[0].Tags["device"])
+}
+
+func TestGatherPermissionError(t *testing.T) {
+	err := errors.New(`reading device "wg0": permission denied, the CAP_NET_ADMIN capability is required`)
+	w := &Wireguard{
+		client: &mockClient{err: err},
+		now:    func() time.Time { return now },
+	}
+
+	var acc testutil.Accumulator
+	require.Equal(t, err, w.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+}
+
+func TestHashPublicKey(t *testing.T) {
+	w := &Wireguard{
+		HashPublicKey: true,
+		client:        &mockClient{devices: testDevices()},
+		now:           func() time.Time { return now },
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(w.Gather))
+	for _, m := range acc.Metrics {
+		if m.Measurement != "wireguard_peer" {
+			continue
+		}
+		require.Len(t, m.Tags["public_key"], 16)
+		require.NotContains(t, m.Tags["public_key"], "=")
+	}
+}
@@ -2,13 +2,17 @@ package zfs
 
 type Sysctl func(metric string) ([]string, error)
 type Zpool func() ([]string, error)
+type ZfsGet func(properties []string, types []string) ([]string, error)
 
 type Zfs struct {
-	KstatPath    string
-	KstatMetrics []string
-	PoolMetrics  bool
-	sysctl       Sysctl
-	zpool        Zpool
+	KstatPath         string
+	KstatMetrics      []string
+	PoolMetrics       bool
+	DatasetProperties []string `toml:"dataset_properties"`
+	DatasetSnapshots  bool     `toml:"dataset_snapshots"`
+	sysctl            Sysctl
+	zpool             Zpool
+	zfsGet            ZfsGet
 }
 
 var sampleConfig = `
@@ -24,6 +28,12 @@ var sampleConfig = `
   #   "dmu_tx", "fm", "vdev_mirror_stats", "zfetchstats", "zil"]
   ## By default, don't gather zpool stats
   # poolMetrics = false
+
+  ## Properties of the filesystems and volumes to gather with "zfs get",
+  ## none by default.
+  # dataset_properties = ["used", "available", "compressratio"]
+  ## Gather the properties of the snapshots too.
+  # dataset_snapshots = false
 `
 
 func (z *Zfs) SampleConfig() string {
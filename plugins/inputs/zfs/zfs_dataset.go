@@ -0,0 +1,96 @@
+// +build linux freebsd
+
+package zfs
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// gatherDatasetStats adds the properties of the datasets, one zfs_dataset
+// metric per dataset.  The numeric properties are added as numbers, the
+// others as strings, and the properties not applying to the dataset, like
+// volsize for a filesystem, are skipped.
+func (z *Zfs) gatherDatasetStats(acc telegraf.Accumulator) error {
+	types := []string{"filesystem", "volume"}
+	if z.DatasetSnapshots {
+		types = append(types, "snapshot")
+	}
+
+	lines, err := z.zfsGet(z.DatasetProperties, types)
+	if err != nil {
+		return err
+	}
+
+	// the datasets are added in the order of zfs get, a line per property
+	var names []string
+	datasets := make(map[string]map[string]interface{})
+	for _, line := range lines {
+		col := strings.Split(line, "\t")
+		if len(col) < 3 {
+			continue
+		}
+		name, property, value := col[0], col[1], col[2]
+
+		fields, ok := datasets[name]
+		if !ok {
+			fields = make(map[string]interface{})
+			datasets[name] = fields
+			names = append(names, name)
+		}
+		if value == "-" || value == "" {
+			continue
+		}
+		fields[property] = parseProperty(value)
+	}
+
+	for _, name := range names {
+		if len(datasets[name]) == 0 {
+			continue
+		}
+		tags := map[string]string{
+			"dataset": name,
+			"pool":    strings.SplitN(strings.SplitN(name, "@", 2)[0], "/", 2)[0],
+		}
+		acc.AddFields("zfs_dataset", datasets[name], tags)
+	}
+	return nil
+}
+
+// parseProperty returns the value of a property, the ratios not parsable
+// having a trailing x.
+func parseProperty(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+		return v
+	}
+	return value
+}
+
+func run(command string, args ...string) ([]string, error) {
+	cmd := exec.Command(command, args...)
+	var outbuf, errbuf bytes.Buffer
+	cmd.Stdout = &outbuf
+	cmd.Stderr = &errbuf
+	err := cmd.Run()
+
+	stdout := strings.TrimSpace(outbuf.String())
+	stderr := strings.TrimSpace(errbuf.String())
+
+	if _, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%s error: %s", command, stderr)
+	}
+	return strings.Split(stdout, "\n"), nil
+}
+
+func zfsGet(properties []string, types []string) ([]string, error) {
+	return run("zfs", []string{"get", "-H", "-p", "-o", "name,property,value",
+		"-t", strings.Join(types, ","), strings.Join(properties, ",")}...)
+}
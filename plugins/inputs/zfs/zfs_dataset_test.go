@@ -0,0 +1,89 @@
+// +build linux freebsd
+
+package zfs
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// $ zfs get -H -p -o name,property,value -t filesystem,volume used,available,compressratio,compression,volsize
+var zfsGetOutput = []string{
+	"tank	used	1126164848640",
+	"tank	available	7807367127040",
+	"tank	compressratio	1.83",
+	"tank	compression	lz4",
+	"tank	volsize	-",
+	"tank/home	used	626958278656",
+	"tank/home	available	7807367127040",
+	"tank/home	compressratio	1.28x",
+	"tank/home	compression	off",
+	"tank/home	volsize	-",
+	"tank/vm	used	34359738368",
+	"tank/vm	available	7807367127040",
+	"tank/vm	compressratio	1.00",
+	"tank/vm	compression	lz4",
+	"tank/vm	volsize	32212254720",
+}
+
+var zfsGetSnapshotOutput = []string{
+	"tank/home@daily	used	1048576",
+	"tank/home@daily	available	-",
+}
+
+func TestZfsDatasetMetrics(t *testing.T) {
+	var gotTypes []string
+	z := &Zfs{
+		DatasetProperties: []string{"used", "available", "compressratio", "compression", "volsize"},
+		zfsGet: func(properties []string, types []string) ([]string, error) {
+			gotTypes = types
+			return zfsGetOutput, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, z.gatherDatasetStats(&acc))
+	require.Equal(t, []string{"filesystem", "volume"}, gotTypes)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "zfs_dataset", map[string]interface{}{
+		"used":          int64(1126164848640),
+		"available":     int64(7807367127040),
+		"compressratio": 1.83,
+		"compression":   "lz4",
+	}, map[string]string{"dataset": "tank", "pool": "tank"})
+	acc.AssertContainsTaggedFields(t, "zfs_dataset", map[string]interface{}{
+		"used":          int64(626958278656),
+		"available":     int64(7807367127040),
+		"compressratio": 1.28,
+		"compression":   "off",
+	}, map[string]string{"dataset": "tank/home", "pool": "tank"})
+	acc.AssertContainsTaggedFields(t, "zfs_dataset", map[string]interface{}{
+		"used":          int64(34359738368),
+		"available":     int64(7807367127040),
+		"compressratio": 1.0,
+		"compression":   "lz4",
+		"volsize":       int64(32212254720),
+	}, map[string]string{"dataset": "tank/vm", "pool": "tank"})
+}
+
+func TestZfsDatasetSnapshots(t *testing.T) {
+	var gotTypes []string
+	z := &Zfs{
+		DatasetProperties: []string{"used", "available"},
+		DatasetSnapshots:  true,
+		zfsGet: func(properties []string, types []string) ([]string, error) {
+			gotTypes = types
+			return zfsGetSnapshotOutput, nil
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, z.gatherDatasetStats(&acc))
+	require.Equal(t, []string{"filesystem", "volume", "snapshot"}, gotTypes)
+	acc.AssertContainsTaggedFields(t, "zfs_dataset", map[string]interface{}{
+		"used": int64(1048576),
+	}, map[string]string{"dataset": "tank/home@daily", "pool": "tank"})
+}
@@ -3,9 +3,7 @@
 package zfs
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 
@@ -114,23 +112,11 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 	acc.AddFields("zfs", fields, tags)
-	return nil
-}
-
-func run(command string, args ...string) ([]string, error) {
-	cmd := exec.Command(command, args...)
-	var outbuf, errbuf bytes.Buffer
-	cmd.Stdout = &outbuf
-	cmd.Stderr = &errbuf
-	err := cmd.Run()
 
-	stdout := strings.TrimSpace(outbuf.String())
-	stderr := strings.TrimSpace(errbuf.String())
-
-	if _, ok := err.(*exec.ExitError); ok {
-		return nil, fmt.Errorf("%s error: %s", command, stderr)
+	if len(z.DatasetProperties) > 0 {
+		return z.gatherDatasetStats(acc)
 	}
-	return strings.Split(stdout, "\n"), nil
+	return nil
 }
 
 func zpool() ([]string, error) {
@@ -146,6 +132,7 @@ func init() {
 		return &Zfs{
 			sysctl: sysctl,
 			zpool:  zpool,
+			zfsGet: zfsGet,
 		}
 	})
 }
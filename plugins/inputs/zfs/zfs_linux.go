@@ -128,11 +128,17 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 	acc.AddFields("zfs", fields, tags)
+
+	if len(z.DatasetProperties) > 0 {
+		return z.gatherDatasetStats(acc)
+	}
 	return nil
 }
 
 func init() {
 	inputs.Add("zfs", func() telegraf.Input {
-		return &Zfs{}
+		return &Zfs{
+			zfsGet: zfsGet,
+		}
 	})
 }
@@ -0,0 +1,443 @@
+package clickhouse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## URL of the HTTP interface of ClickHouse.
+  url = "http://127.0.0.1:8123"
+
+  ## Database holding the tables, one table is used for each measurement.
+  # database = "telegraf"
+
+  ## Credentials of the ClickHouse user.
+  # username = "default"
+  # password = ""
+
+  ## Timeout for the HTTP requests.
+  # timeout = "5s"
+
+  ## If true, missing tables are created with the columns of the first
+  ## metrics written to them.
+  # table_create = true
+
+  ## Engine of the created tables, they are ordered by tags and time.
+  # table_engine = "MergeTree()"
+
+  ## Time to live of the rows of the created tables, for example "30 DAY".
+  # table_ttl = ""
+
+  ## Tags and fields without a column in the table are added as new columns
+  ## by default.  If true, they are skipped instead.
+  # skip_unknown_fields = false
+
+  ## If true, the rows are inserted using asynchronous inserts, which are
+  ## buffered and flushed by the server.  Writes still wait for the flush so
+  ## failed inserts are retried.
+  # async_insert = false
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	defaultURL      = "http://127.0.0.1:8123"
+	defaultDatabase = "telegraf"
+	defaultEngine   = "MergeTree()"
+	defaultTimeout  = 5 * time.Second
+
+	// timestampColumn is the column holding the time of the metrics.
+	timestampColumn = "timestamp"
+	timestampType   = "DateTime64(9, 'UTC')"
+	timestampLayout = "2006-01-02 15:04:05.999999999"
+)
+
+type ClickHouse struct {
+	URL               string            `toml:"url"`
+	Database          string            `toml:"database"`
+	Username          string            `toml:"username"`
+	Password          string            `toml:"password"`
+	Timeout           internal.Duration `toml:"timeout"`
+	TableCreate       bool              `toml:"table_create"`
+	TableEngine       string            `toml:"table_engine"`
+	TableTTL          string            `toml:"table_ttl"`
+	SkipUnknownFields bool              `toml:"skip_unknown_fields"`
+	AsyncInsert       bool              `toml:"async_insert"`
+	tls.ClientConfig
+
+	client *http.Client
+
+	// tables holds the columns of the known tables.
+	tables map[string]map[string]bool
+}
+
+// column is a column required to insert a batch of metrics.
+type column struct {
+	name string
+	typ  string
+	tag  bool
+}
+
+func (c *ClickHouse) Description() string {
+	return "Write metrics into ClickHouse tables using the HTTP interface"
+}
+
+func (c *ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *ClickHouse) Connect() error {
+	if c.URL == "" {
+		return fmt.Errorf("url must be set")
+	}
+	if c.Database == "" {
+		return fmt.Errorf("database must be set")
+	}
+	if c.TableEngine == "" {
+		c.TableEngine = defaultEngine
+	}
+	if c.Timeout.Duration == 0 {
+		c.Timeout.Duration = defaultTimeout
+	}
+
+	tlsCfg, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	c.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: c.Timeout.Duration,
+	}
+	c.tables = make(map[string]map[string]bool)
+	return nil
+}
+
+func (c *ClickHouse) Close() error {
+	return nil
+}
+
+func (c *ClickHouse) Write(metrics []telegraf.Metric) error {
+	byTable := make(map[string][]telegraf.Metric)
+	var tables []string
+	for _, metric := range metrics {
+		if _, ok := byTable[metric.Name()]; !ok {
+			tables = append(tables, metric.Name())
+		}
+		byTable[metric.Name()] = append(byTable[metric.Name()], metric)
+	}
+
+	for _, table := range tables {
+		if err := c.writeTable(table, byTable[table]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTable inserts the metrics into the table, first creating the table
+// or adding missing columns if needed.
+func (c *ClickHouse) writeTable(table string, metrics []telegraf.Metric) error {
+	columns := batchColumns(metrics)
+
+	existing, err := c.tableColumns(table)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		if !c.TableCreate {
+			return fmt.Errorf("table %s does not exist", c.qualified(table))
+		}
+		if err := c.exec(c.createTableSQL(table, columns), nil); err != nil {
+			return err
+		}
+		existing = make(map[string]bool, len(columns))
+		for _, col := range columns {
+			existing[col.name] = true
+		}
+		c.tables[table] = existing
+	}
+
+	known := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		if existing[col.name] {
+			known[col.name] = true
+			continue
+		}
+		if c.SkipUnknownFields {
+			log.Printf("D! [outputs.clickhouse] Skipping %s, there is no column "+
+				"for it in %s", col.name, c.qualified(table))
+			continue
+		}
+		if err := c.exec(c.addColumnSQL(table, col), nil); err != nil {
+			return err
+		}
+		existing[col.name] = true
+		known[col.name] = true
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, metric := range metrics {
+		if err := enc.Encode(row(metric, known)); err != nil {
+			return err
+		}
+	}
+	return c.exec(c.insertSQL(table), &body)
+}
+
+// batchColumns returns the columns of the metrics, the timestamp followed
+// by the tags and the fields in alphabetical order.  A field with the name
+// of a tag is ignored.
+func batchColumns(metrics []telegraf.Metric) []column {
+	tags := make(map[string]bool)
+	fields := make(map[string]string)
+	var tagNames, fieldNames []string
+	for _, metric := range metrics {
+		for _, tag := range metric.TagList() {
+			if !tags[tag.Key] && tag.Key != timestampColumn {
+				tags[tag.Key] = true
+				tagNames = append(tagNames, tag.Key)
+			}
+		}
+		for _, field := range metric.FieldList() {
+			if _, ok := fields[field.Key]; !ok && field.Key != timestampColumn {
+				fields[field.Key] = columnType(field.Value)
+				fieldNames = append(fieldNames, field.Key)
+			}
+		}
+	}
+	sort.Strings(tagNames)
+	sort.Strings(fieldNames)
+
+	columns := []column{{name: timestampColumn, typ: timestampType}}
+	for _, k := range tagNames {
+		columns = append(columns, column{name: k, typ: "LowCardinality(String)", tag: true})
+	}
+	for _, k := range fieldNames {
+		if !tags[k] {
+			columns = append(columns, column{name: k, typ: "Nullable(" + fields[k] + ")"})
+		}
+	}
+	return columns
+}
+
+func columnType(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return "Int64"
+	case uint64:
+		return "UInt64"
+	case bool:
+		return "UInt8"
+	case string:
+		return "String"
+	default:
+		return "Float64"
+	}
+}
+
+// row returns the JSONEachRow row of the metric, limited to the known
+// columns.  Tags take precedence over fields with the same name.
+func row(metric telegraf.Metric, known map[string]bool) map[string]interface{} {
+	r := make(map[string]interface{}, len(known))
+	for _, field := range metric.FieldList() {
+		if !known[field.Key] {
+			continue
+		}
+		if b, ok := field.Value.(bool); ok {
+			// booleans are stored as UInt8
+			if b {
+				r[field.Key] = 1
+			} else {
+				r[field.Key] = 0
+			}
+			continue
+		}
+		r[field.Key] = field.Value
+	}
+	for _, tag := range metric.TagList() {
+		if known[tag.Key] {
+			r[tag.Key] = tag.Value
+		}
+	}
+	r[timestampColumn] = metric.Time().UTC().Format(timestampLayout)
+	return r
+}
+
+func (c *ClickHouse) createTableSQL(table string, columns []column) string {
+	defs := make([]string, 0, len(columns))
+	var order []string
+	for _, col := range columns {
+		defs = append(defs, quoteIdentifier(col.name)+" "+col.typ)
+		if col.tag {
+			order = append(order, quoteIdentifier(col.name))
+		}
+	}
+	order = append(order, quoteIdentifier(timestampColumn))
+
+	sql := "CREATE TABLE IF NOT EXISTS " + c.qualified(table) + " (\n  " +
+		strings.Join(defs, ",\n  ") + "\n) ENGINE = " + c.TableEngine +
+		" ORDER BY (" + strings.Join(order, ", ") + ")"
+	if c.TableTTL != "" {
+		sql += " TTL toDateTime(" + quoteIdentifier(timestampColumn) + ") + INTERVAL " + c.TableTTL
+	}
+	return sql
+}
+
+func (c *ClickHouse) addColumnSQL(table string, col column) string {
+	return "ALTER TABLE " + c.qualified(table) + " ADD COLUMN IF NOT EXISTS " +
+		quoteIdentifier(col.name) + " " + col.typ
+}
+
+func (c *ClickHouse) insertSQL(table string) string {
+	return "INSERT INTO " + c.qualified(table) + " FORMAT JSONEachRow"
+}
+
+func (c *ClickHouse) qualified(table string) string {
+	return quoteIdentifier(c.Database) + "." + quoteIdentifier(table)
+}
+
+// quoteIdentifier quotes a database, table or column name with backticks.
+func quoteIdentifier(name string) string {
+	name = strings.Replace(name, `\`, `\\`, -1)
+	return "`" + strings.Replace(name, "`", "\\`", -1) + "`"
+}
+
+// quoteString quotes a string literal.
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
+
+// tableColumns returns the columns of the table, or nil if it does not
+// exist.  The columns are cached once known.
+func (c *ClickHouse) tableColumns(table string) (map[string]bool, error) {
+	if columns, ok := c.tables[table]; ok {
+		return columns, nil
+	}
+
+	sql := "SELECT name FROM system.columns WHERE database = " +
+		quoteString(c.Database) + " AND table = " + quoteString(table) +
+		" FORMAT TabSeparated"
+	var out bytes.Buffer
+	if err := c.query(sql, nil, &out); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, nil
+	}
+	columns := make(map[string]bool, len(lines))
+	for _, name := range lines {
+		columns[unescapeTSV(name)] = true
+	}
+	c.tables[table] = columns
+	return columns, nil
+}
+
+// unescapeTSV undoes the escaping of a value in the TabSeparated format.
+func unescapeTSV(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case '0':
+			b.WriteByte(0)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func (c *ClickHouse) exec(sql string, body io.Reader) error {
+	return c.query(sql, body, ioutil.Discard)
+}
+
+// query runs the statement, rows to insert are read from the body.
+func (c *ClickHouse) query(sql string, body io.Reader, out io.Writer) error {
+	params := url.Values{}
+	params.Set("query", sql)
+	if body != nil && c.AsyncInsert {
+		params.Set("async_insert", "1")
+		params.Set("wait_for_async_insert", "1")
+	}
+	if body == nil {
+		body = http.NoBody
+	}
+
+	u := strings.TrimSuffix(c.URL, "/") + "/?" + params.Encode()
+	req, err := http.NewRequest(http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	if c.Username != "" {
+		req.Header.Set("X-ClickHouse-User", c.Username)
+	}
+	if c.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", c.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("when writing to [%s] received status code %d: %s",
+			c.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+func init() {
+	outputs.Add("clickhouse", func() telegraf.Output {
+		return &ClickHouse{
+			URL:         defaultURL,
+			Database:    defaultDatabase,
+			TableCreate: true,
+			TableEngine: defaultEngine,
+			Timeout:     internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package clickhouse
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	query  string
+	params map[string]string
+	body   string
+}
+
+// server is a mock of the ClickHouse HTTP interface recording the
+// statements it receives.
+type server struct {
+	sync.Mutex
+	*httptest.Server
+	requests []request
+	columns  map[string]string
+	fail     bool
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{columns: make(map[string]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		req := request{
+			query:  r.URL.Query().Get("query"),
+			params: make(map[string]string),
+			body:   string(body),
+		}
+		for k := range r.URL.Query() {
+			req.params[k] = r.URL.Query().Get(k)
+		}
+		req.params["user"] = r.Header.Get("X-ClickHouse-User")
+		req.params["key"] = r.Header.Get("X-ClickHouse-Key")
+		s.requests = append(s.requests, req)
+
+		if s.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Code: 241. DB::Exception: Memory limit exceeded"))
+			return
+		}
+		if table, ok := s.columns[req.query]; ok {
+			w.Write([]byte(table))
+		}
+	}))
+	return s
+}
+
+func newMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("cpu", tags, fields, time.Unix(1500000000, 5))
+	return m
+}
+
+func newClickHouse(url string) *ClickHouse {
+	return &ClickHouse{
+		URL:         url,
+		Database:    "telegraf",
+		TableCreate: true,
+		TableEngine: defaultEngine,
+	}
+}
+
+const describeCPU = "SELECT name FROM system.columns WHERE database = 'telegraf' " +
+	"AND table = 'cpu' FORMAT TabSeparated"
+
+func TestWriteCreatesTable(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	c := newClickHouse(s.URL)
+	c.TableTTL = "30 DAY"
+	c.Username = "writer"
+	c.Password = "secret"
+	require.NoError(t, c.Connect())
+
+	err := c.Write([]telegraf.Metric{
+		newMetric(map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3)}),
+		newMetric(map[string]string{"host": "b"},
+			map[string]interface{}{"usage": 1.0, "ok": true}),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, s.requests, 3)
+	require.Equal(t, describeCPU, s.requests[0].query)
+	require.Equal(t, "CREATE TABLE IF NOT EXISTS `telegraf`.`cpu` (\n"+
+		"  `timestamp` DateTime64(9, 'UTC'),\n"+
+		"  `cpu` LowCardinality(String),\n"+
+		"  `host` LowCardinality(String),\n"+
+		"  `count` Nullable(Int64),\n"+
+		"  `ok` Nullable(UInt8),\n"+
+		"  `usage` Nullable(Float64)\n"+
+		") ENGINE = MergeTree() ORDER BY (`cpu`, `host`, `timestamp`) "+
+		"TTL toDateTime(`timestamp`) + INTERVAL 30 DAY", s.requests[1].query)
+
+	insert := s.requests[2]
+	require.Equal(t, "INSERT INTO `telegraf`.`cpu` FORMAT JSONEachRow", insert.query)
+	require.Equal(t, "writer", insert.params["user"])
+	require.Equal(t, "secret", insert.params["key"])
+	require.Equal(t,
+		`{"count":3,"cpu":"cpu0","host":"a","timestamp":"2017-07-14 02:40:00.000000005","usage":42.5}`+"\n"+
+			`{"host":"b","ok":1,"timestamp":"2017-07-14 02:40:00.000000005","usage":1}`+"\n",
+		insert.body)
+
+	// The columns of the table are cached.
+	require.NoError(t, c.Write([]telegraf.Metric{
+		newMetric(map[string]string{"host": "a"}, map[string]interface{}{"usage": 2.0}),
+	}))
+	require.Len(t, s.requests, 4)
+}
+
+func TestWriteAddsColumns(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+	s.columns[describeCPU] = "timestamp\nhost\nusage\n"
+
+	c := newClickHouse(s.URL)
+	require.NoError(t, c.Connect())
+
+	err := c.Write([]telegraf.Metric{
+		newMetric(map[string]string{"host": "a", "region": "eu"},
+			map[string]interface{}{"usage": 42.5, "idle": 57.5}),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, s.requests, 4)
+	require.Equal(t, "ALTER TABLE `telegraf`.`cpu` ADD COLUMN IF NOT EXISTS "+
+		"`region` LowCardinality(String)", s.requests[1].query)
+	require.Equal(t, "ALTER TABLE `telegraf`.`cpu` ADD COLUMN IF NOT EXISTS "+
+		"`idle` Nullable(Float64)", s.requests[2].query)
+	require.Equal(t,
+		`{"host":"a","idle":57.5,"region":"eu","timestamp":"2017-07-14 02:40:00.000000005","usage":42.5}`+"\n",
+		s.requests[3].body)
+}
+
+func TestWriteSkipUnknownFields(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+	s.columns[describeCPU] = "timestamp\nhost\nusage\n"
+
+	c := newClickHouse(s.URL)
+	c.SkipUnknownFields = true
+	require.NoError(t, c.Connect())
+
+	err := c.Write([]telegraf.Metric{
+		newMetric(map[string]string{"host": "a", "region": "eu"},
+			map[string]interface{}{"usage": 42.5, "idle": 57.5}),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, s.requests, 2)
+	require.Equal(t,
+		`{"host":"a","timestamp":"2017-07-14 02:40:00.000000005","usage":42.5}`+"\n",
+		s.requests[1].body)
+}
+
+func TestWriteMissingTable(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	c := newClickHouse(s.URL)
+	c.TableCreate = false
+	require.NoError(t, c.Connect())
+
+	err := c.Write([]telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"usage": 42.5}),
+	})
+	require.EqualError(t, err, "table `telegraf`.`cpu` does not exist")
+}
+
+func TestWriteAsyncInsert(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+	s.columns[describeCPU] = "timestamp\nusage\n"
+
+	c := newClickHouse(s.URL)
+	c.AsyncInsert = true
+	require.NoError(t, c.Connect())
+
+	require.NoError(t, c.Write([]telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"usage": 42.5}),
+	}))
+	require.Len(t, s.requests, 2)
+	require.Empty(t, s.requests[0].params["async_insert"])
+	require.Equal(t, "1", s.requests[1].params["async_insert"])
+	require.Equal(t, "1", s.requests[1].params["wait_for_async_insert"])
+}
+
+func TestWriteError(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+	s.fail = true
+
+	c := newClickHouse(s.URL)
+	require.NoError(t, c.Connect())
+
+	err := c.Write([]telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"usage": 42.5}),
+	})
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "Memory limit exceeded"))
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	require.Equal(t, "`cpu`", quoteIdentifier("cpu"))
+	require.Equal(t, "`a\\`b`", quoteIdentifier("a`b"))
+	require.Equal(t, `'it\'s'`, quoteString("it's"))
+	require.Equal(t, "a\tb", unescapeTSV(`a\tb`))
+}
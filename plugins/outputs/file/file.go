@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -20,7 +24,9 @@ type File struct {
 }
 
 var sampleConfig = `
-  ## Files to write to, "stdout" is a specially handled file.
+  ## Files to write to, "stdout" is a specially handled file.  With a batch
+  ## only data format, such as "parquet", each write creates a new file named
+  ## after the file with the time of the write before its extension.
   files = ["stdout", "/tmp/metrics.out"]
 
   ## Data format to output.
@@ -39,6 +45,11 @@ func (f *File) Connect() error {
 		f.Files = []string{"stdout"}
 	}
 
+	// the batches are written to new files, they cannot be appended
+	if _, ok := f.serializer.(serializers.BatchSerializer); ok {
+		return nil
+	}
+
 	for _, file := range f.Files {
 		if file == "stdout" {
 			f.writers = append(f.writers, os.Stdout)
@@ -83,6 +94,10 @@ func (f *File) Description() string {
 }
 
 func (f *File) Write(metrics []telegraf.Metric) error {
+	if _, ok := f.serializer.(serializers.BatchSerializer); ok {
+		return f.writeBatch(metrics)
+	}
+
 	var writeErr error = nil
 	for _, metric := range metrics {
 		b, err := f.serializer.Serialize(metric)
@@ -100,6 +115,42 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 	return writeErr
 }
 
+// writeBatch writes the metrics to a new file for each of the files.
+func (f *File) writeBatch(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	b, err := f.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %s", err)
+	}
+
+	suffix := "-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	var writeErr error
+	for _, file := range f.Files {
+		if file == "stdout" {
+			os.Stdout.Write(b)
+			continue
+		}
+
+		ext := filepath.Ext(file)
+		name := strings.TrimSuffix(file, ext) + suffix + ext
+		of, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+		if err != nil {
+			writeErr = fmt.Errorf("E! failed to create file: %s", err)
+			continue
+		}
+		_, err = of.Write(b)
+		if cerr := of.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			writeErr = fmt.Errorf("E! failed to write file %s: %s", name, err)
+		}
+	}
+	return writeErr
+}
+
 func init() {
 	outputs.Add("file", func() telegraf.Output {
 		return &File{}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -180,6 +181,44 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileBatchSerializer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := serializers.NewParquetSerializer(0, 0, "")
+	assert.NoError(t, err)
+	f := File{
+		Files:      []string{filepath.Join(dir, "metrics.parquet")},
+		serializer: s,
+	}
+
+	err = f.Connect()
+	assert.NoError(t, err)
+
+	metrics := testutil.MockMetrics()
+	expected, err := s.SerializeBatch(metrics)
+	assert.NoError(t, err)
+
+	// each write creates a new file
+	for i := 0; i < 2; i++ {
+		err = f.Write(metrics)
+		assert.NoError(t, err)
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "metrics-*.parquet"))
+	assert.NoError(t, err)
+	assert.Len(t, names, 2)
+	for _, name := range names {
+		buf, err := ioutil.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, buf)
+	}
+
+	err = f.Close()
+	assert.NoError(t, err)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
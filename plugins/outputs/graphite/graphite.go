@@ -5,7 +5,6 @@ import (
 	"errors"
 	"io"
 	"log"
-	"math/rand"
 	"net"
 	"time"
 
@@ -13,6 +12,13 @@ import (
 	tlsint "github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// the backoff of a failed server doubles with each consecutive failure
+	minBackoff = time.Second
+	maxBackoff = time.Minute
 )
 
 type Graphite struct {
@@ -22,14 +28,33 @@ type Graphite struct {
 	Prefix   string
 	Template string
 	Timeout  int
-	conns    []net.Conn
+	PoolSize int
 	tlsint.ClientConfig
+
+	servers   []*server
+	next      int
+	tlsConfig *tls.Config
+}
+
+// server is a graphite server with its pool of connections.  A server failing
+// to connect or to write is skipped until its backoff elapses.
+type server struct {
+	address  string
+	conns    []net.Conn
+	next     int
+	failures int
+	retryAt  time.Time
+
+	up           selfstat.Stat
+	failureCount selfstat.Stat
 }
 
 var sampleConfig = `
   ## TCP endpoint for your graphite instance.
   ## If multiple endpoints are configured, output will be load balanced.
-  ## Only one of the endpoints will be written to with each iteration.
+  ## Only one of the endpoints will be written to with each iteration, the
+  ## next endpoints are written to if it fails.  A failed endpoint is skipped
+  ## for a backoff from 1s doubling up to 1m on each consecutive failure.
   servers = ["localhost:2003"]
   ## Prefix metrics name
   prefix = ""
@@ -43,6 +68,10 @@ var sampleConfig = `
   ## timeout in seconds for the write connection to graphite
   timeout = 2
 
+  ## Number of connections kept open to each endpoint, the connections are
+  ## written to in turn and a broken connection is replaced.
+  # pool_size = 1
+
   ## Optional TLS Config
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
@@ -56,6 +85,9 @@ func (g *Graphite) Connect() error {
 	if g.Timeout <= 0 {
 		g.Timeout = 2
 	}
+	if g.PoolSize <= 0 {
+		g.PoolSize = 1
+	}
 	if len(g.Servers) == 0 {
 		g.Servers = append(g.Servers, "localhost:2003")
 	}
@@ -65,33 +97,45 @@ func (g *Graphite) Connect() error {
 	if err != nil {
 		return err
 	}
+	g.tlsConfig = tlsConfig
 
-	// Get Connections
-	var conns []net.Conn
-	for _, server := range g.Servers {
-		// Dialer with timeout
-		d := net.Dialer{Timeout: time.Duration(g.Timeout) * time.Second}
-
-		// Get secure connection if tls config is set
-		var conn net.Conn
-		if tlsConfig != nil {
-			conn, err = tls.DialWithDialer(&d, "tcp", server, tlsConfig)
-		} else {
-			conn, err = d.Dial("tcp", server)
+	// Get Connections, the servers down are retried on write
+	g.servers = make([]*server, 0, len(g.Servers))
+	for _, address := range g.Servers {
+		tags := map[string]string{"server": address}
+		s := &server{
+			address:      address,
+			conns:        make([]net.Conn, g.PoolSize),
+			up:           selfstat.Register("graphite", "up", tags),
+			failureCount: selfstat.Register("graphite", "failures", tags),
 		}
-
-		if err == nil {
-			conns = append(conns, conn)
+		s.up.Set(1)
+		for i := range s.conns {
+			if s.conns[i], err = g.dial(address); err != nil {
+				s.fail(err)
+				break
+			}
 		}
+		g.servers = append(g.servers, s)
 	}
-	g.conns = conns
 	return nil
 }
 
+func (g *Graphite) dial(address string) (net.Conn, error) {
+	// Dialer with timeout
+	d := net.Dialer{Timeout: time.Duration(g.Timeout) * time.Second}
+
+	// Get secure connection if tls config is set
+	if g.tlsConfig != nil {
+		return tls.DialWithDialer(&d, "tcp", address, g.tlsConfig)
+	}
+	return d.Dial("tcp", address)
+}
+
 func (g *Graphite) Close() error {
 	// Closing all connections
-	for _, conn := range g.conns {
-		conn.Close()
+	for _, s := range g.servers {
+		s.close()
 	}
 	return nil
 }
@@ -109,14 +153,16 @@ func (g *Graphite) Description() string {
 // We can detect that by finding an eof
 // if not for this, we can happily write and flush without getting errors (in Go) but getting RST tcp packets back (!)
 // props to Tv via the authors of carbon-relay-ng` for this trick.
-func checkEOF(conn net.Conn) {
+//
+// checkEOF returns false if the connection was closed.
+func checkEOF(conn net.Conn) bool {
 	b := make([]byte, 1024)
 	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
 	num, err := conn.Read(b)
 	if err == io.EOF {
 		log.Printf("E! Conn %s is closed. closing conn explicitly", conn)
 		conn.Close()
-		return
+		return false
 	}
 	// just in case i misunderstand something or the remote behaves badly
 	if num != 0 {
@@ -126,11 +172,14 @@ func checkEOF(conn net.Conn) {
 	if e, ok := err.(net.Error); !(ok && e.Timeout()) {
 		log.Printf("E! conn %s checkEOF .conn.Read returned err != EOF, which is unexpected.  closing conn. error: %s\n", conn, err)
 		conn.Close()
+		return false
 	}
+	return true
 }
 
-// Choose a random server in the cluster to write to until a successful write
-// occurs, logging each unsuccessful. If all servers fail, return error.
+// Write to the servers in turn, skipping the servers down, until a
+// successful write occurs, logging each unsuccessful. If all servers fail,
+// return error so the metrics are written again on the next flush.
 func (g *Graphite) Write(metrics []telegraf.Metric) error {
 	// Prepare data
 	var batch []byte
@@ -147,45 +196,93 @@ func (g *Graphite) Write(metrics []telegraf.Metric) error {
 		batch = append(batch, buf...)
 	}
 
-	err = g.send(batch)
-
-	// try to reconnect and retry to send
-	if err != nil {
-		log.Println("E! Graphite: Reconnecting and retrying: ")
-		g.Connect()
-		err = g.send(batch)
-	}
-
-	return err
+	return g.send(batch)
 }
 
 func (g *Graphite) send(batch []byte) error {
-	// This will get set to nil if a successful write occurs
-	err := errors.New("Could not write to any Graphite server in cluster\n")
-
-	// Send data to a random server
-	p := rand.Perm(len(g.conns))
-	for _, n := range p {
-		if g.Timeout > 0 {
-			g.conns[n].SetWriteDeadline(time.Now().Add(time.Duration(g.Timeout) * time.Second))
+	now := time.Now()
+	for i := range g.servers {
+		n := (g.next + i) % len(g.servers)
+		s := g.servers[n]
+		if now.Before(s.retryAt) {
+			continue
 		}
-		checkEOF(g.conns[n])
-		if _, e := g.conns[n].Write(batch); e != nil {
-			// Error
-			log.Println("E! Graphite Error: " + e.Error())
-			// Close explicitly
-			g.conns[n].Close()
+		if err := g.sendTo(s, batch); err != nil {
+			s.fail(err)
 			// Let's try the next one
-		} else {
-			// Success
-			err = nil
-			break
+			continue
+		}
+
+		// Success, the next write goes to the next server
+		if s.failures > 0 {
+			log.Printf("I! [outputs.graphite] Server %s is back up", s.address)
+			s.failures = 0
+			s.up.Set(1)
 		}
+		g.next = (n + 1) % len(g.servers)
+		return nil
 	}
 
+	return errors.New("Could not write to any Graphite server in cluster\n")
+}
+
+// sendTo writes the batch to the next connection of the server, trying the
+// next ones on failure and replacing the broken connections.  It fails if no
+// new connection can be opened.
+func (g *Graphite) sendTo(s *server, batch []byte) error {
+	var err error
+	for i := 0; i <= len(s.conns); i++ {
+		n := s.next
+		s.next = (s.next + 1) % len(s.conns)
+
+		if s.conns[n] != nil && !checkEOF(s.conns[n]) {
+			s.conns[n] = nil
+		}
+		if s.conns[n] == nil {
+			if s.conns[n], err = g.dial(s.address); err != nil {
+				return err
+			}
+		}
+
+		conn := s.conns[n]
+		conn.SetWriteDeadline(time.Now().Add(time.Duration(g.Timeout) * time.Second))
+		if _, err = conn.Write(batch); err == nil {
+			return nil
+		}
+		log.Printf("E! [outputs.graphite] Error writing to %s: %s", s.address, err)
+		// Close explicitly
+		conn.Close()
+		s.conns[n] = nil
+	}
 	return err
 }
 
+// fail closes the connections of the server and skips it for its backoff.
+func (s *server) fail(err error) {
+	backoff := minBackoff
+	for i := 0; i < s.failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	s.failures++
+	s.retryAt = time.Now().Add(backoff)
+	s.up.Set(0)
+	s.failureCount.Incr(1)
+	s.close()
+	log.Printf("E! [outputs.graphite] Server %s is down, retrying in %s: %s", s.address, backoff, err)
+}
+
+func (s *server) close() {
+	for i, conn := range s.conns {
+		if conn != nil {
+			conn.Close()
+			s.conns[i] = nil
+		}
+	}
+}
+
 func init() {
 	outputs.Add("graphite", func() telegraf.Output {
 		return &Graphite{}
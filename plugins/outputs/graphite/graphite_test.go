@@ -217,3 +217,154 @@ func TCPServer2WithTags(t *testing.T, wg *sync.WaitGroup) {
 		tcpServer.Close()
 	}()
 }
+
+// lineServer accepts connections and sends the lines received on them.
+type lineServer struct {
+	listener net.Listener
+	lines    chan string
+	conns    chan net.Conn
+}
+
+func newLineServer(t *testing.T) *lineServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &lineServer{
+		listener: listener,
+		lines:    make(chan string, 100),
+		conns:    make(chan net.Conn, 100),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.conns <- conn
+			go func() {
+				tp := textproto.NewReader(bufio.NewReader(conn))
+				for {
+					line, err := tp.ReadLine()
+					if err != nil {
+						return
+					}
+					s.lines <- line
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *lineServer) readLine(t *testing.T) string {
+	select {
+	case line := <-s.lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+		return ""
+	}
+}
+
+// downAddress returns an address nothing listens on.
+func downAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func poolMetrics(value float64) []telegraf.Metric {
+	m, _ := metric.New(
+		"mymeasurement",
+		map[string]string{"host": "192.168.0.1"},
+		map[string]interface{}{"value": value},
+		time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
+	)
+	return []telegraf.Metric{m}
+}
+
+func TestGraphiteFailover(t *testing.T) {
+	up := newLineServer(t)
+	defer up.listener.Close()
+
+	g := Graphite{
+		Servers: []string{downAddress(t), up.listener.Addr().String()},
+		Prefix:  "my.prefix",
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	// the server down is skipped during its backoff
+	down := g.servers[0]
+	require.Equal(t, 1, down.failures)
+	require.True(t, down.retryAt.After(time.Now()))
+	require.NoError(t, g.Write(poolMetrics(1)))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 1 1289430000", up.readLine(t))
+	require.NoError(t, g.Write(poolMetrics(2)))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 2 1289430000", up.readLine(t))
+
+	// once the backoff elapsed the server is retried, its backoff doubles
+	down.retryAt = time.Now()
+	start := time.Now()
+	require.NoError(t, g.Write(poolMetrics(3)))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 3 1289430000", up.readLine(t))
+	require.Equal(t, 2, down.failures)
+	require.True(t, down.retryAt.Sub(start) >= 2*time.Second)
+	require.Equal(t, int64(0), down.up.Get())
+	require.Equal(t, int64(1), g.servers[1].up.Get())
+}
+
+func TestGraphiteRoundRobin(t *testing.T) {
+	servers := []*lineServer{newLineServer(t), newLineServer(t)}
+	g := Graphite{Prefix: "my.prefix"}
+	for _, s := range servers {
+		defer s.listener.Close()
+		g.Servers = append(g.Servers, s.listener.Addr().String())
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	require.NoError(t, g.Write(poolMetrics(1)))
+	require.NoError(t, g.Write(poolMetrics(2)))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 1 1289430000", servers[0].readLine(t))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 2 1289430000", servers[1].readLine(t))
+
+	// a server going down is failed over, the metrics are written to the
+	// other server
+	servers[0].listener.Close()
+	conn := <-servers[0].conns
+	conn.Close()
+	require.NoError(t, g.Write(poolMetrics(3)))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 3 1289430000", servers[1].readLine(t))
+	require.Equal(t, 1, g.servers[0].failures)
+
+	// the metrics are not written if all the servers are down
+	servers[1].listener.Close()
+	conn = <-servers[1].conns
+	conn.Close()
+	require.Error(t, g.Write(poolMetrics(4)))
+}
+
+func TestGraphitePool(t *testing.T) {
+	s := newLineServer(t)
+	defer s.listener.Close()
+
+	g := Graphite{
+		Servers:  []string{s.listener.Addr().String()},
+		Prefix:   "my.prefix",
+		PoolSize: 2,
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+	first, second := <-s.conns, <-s.conns
+
+	// the connections are written to in turn, a broken one is replaced
+	require.NoError(t, g.Write(poolMetrics(1)))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 1 1289430000", s.readLine(t))
+	first.Close()
+	second.Close()
+	require.NoError(t, g.Write(poolMetrics(2)))
+	require.Equal(t, "my.prefix.192_168_0_1.mymeasurement 2 1289430000", s.readLine(t))
+	require.Equal(t, 0, g.servers[0].failures)
+}
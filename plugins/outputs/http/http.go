@@ -3,9 +3,10 @@ package http
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -57,9 +58,15 @@ var sampleConfig = `
   #   # Should be set manually to "application/json" for json data_format
   #   Content-Type = "text/plain; charset=utf-8"
 
-  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## HTTP Content-Encoding for write request body, can be set to "gzip" or
+  ## "zstd" to compress body or "identity" to apply no encoding.  If the server
+  ## answers a compressed request with 415 Unsupported Media Type, the body is
+  ## sent again and from then on without encoding.
   # content_encoding = "identity"
+
+  ## Compression level of the content encoding, from 1 to 9 for gzip and from
+  ## 1 to 22 for zstd.  The default level of the encoding is used when 0.
+  # compression_level = 0
 `
 
 const (
@@ -79,11 +86,13 @@ type HTTP struct {
 	ClientSecret    string            `toml:"client_secret"`
 	TokenURL        string            `toml:"token_url"`
 	Scopes          []string          `toml:"scopes"`
-	ContentEncoding string            `toml:"content_encoding"`
+	ContentEncoding  string            `toml:"content_encoding"`
+	CompressionLevel int               `toml:"compression_level"`
 	tls.ClientConfig
 
 	client     *http.Client
 	serializer serializers.Serializer
+	encoder    internal.ContentEncoder
 }
 
 func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
@@ -131,6 +140,12 @@ func (h *HTTP) Connect() error {
 		h.Timeout.Duration = defaultClientTimeout
 	}
 
+	encoder, err := internal.NewContentEncoder(h.ContentEncoding, h.CompressionLevel)
+	if err != nil {
+		return err
+	}
+	h.encoder = encoder
+
 	ctx := context.Background()
 	client, err := h.createClient(ctx)
 	if err != nil {
@@ -168,17 +183,28 @@ func (h *HTTP) Write(metrics []telegraf.Metric) error {
 }
 
 func (h *HTTP) write(reqBody []byte) error {
-	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
+	encoder := h.encoder
+	err := h.send(reqBody, encoder)
+	if err == errUnsupportedEncoding {
+		log.Printf("W! [outputs.http] %s does not support the %s content encoding, sending the metrics without encoding",
+			h.URL, encoder.Encoding())
+		h.encoder, _ = internal.NewContentEncoder("identity", 0)
+		err = h.send(reqBody, h.encoder)
+	}
+	return err
+}
 
-	var err error
-	if h.ContentEncoding == "gzip" {
-		reqBodyBuffer, err = internal.CompressWithGzip(reqBodyBuffer)
-		if err != nil {
-			return err
-		}
+// errUnsupportedEncoding is returned by send when the server does not accept
+// the content encoding of the body.
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+func (h *HTTP) send(reqBody []byte, encoder internal.ContentEncoder) error {
+	body, err := encoder.Encode(reqBody)
+	if err != nil {
+		return err
 	}
 
-	req, err := http.NewRequest(h.Method, h.URL, reqBodyBuffer)
+	req, err := http.NewRequest(h.Method, h.URL, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -189,8 +215,8 @@ func (h *HTTP) write(reqBody []byte) error {
 
 	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
 	req.Header.Set("Content-Type", defaultContentType)
-	if h.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
+	if encoding := encoder.Encoding(); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
 	}
 	for k, v := range h.Headers {
 		req.Header.Set(k, v)
@@ -203,6 +229,9 @@ func (h *HTTP) write(reqBody []byte) error {
 	defer resp.Body.Close()
 	_, err = ioutil.ReadAll(resp.Body)
 
+	if resp.StatusCode == http.StatusUnsupportedMediaType && encoder.Encoding() != "" {
+		return errUnsupportedEncoding
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return fmt.Errorf("when writing to [%s] received status code: %d", h.URL, resp.StatusCode)
 	}
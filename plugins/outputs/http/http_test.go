@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/require"
 )
 
@@ -258,6 +259,23 @@ func TestContentEncodingGzip(t *testing.T) {
 			},
 			expected: "gzip",
 		},
+		{
+			name: "zstd content_encoding",
+			plugin: &HTTP{
+				URL:             u.String(),
+				ContentEncoding: "zstd",
+			},
+			expected: "zstd",
+		},
+		{
+			name: "zstd compression_level",
+			plugin: &HTTP{
+				URL:              u.String(),
+				ContentEncoding:  "zstd",
+				CompressionLevel: 19,
+			},
+			expected: "zstd",
+		},
 	}
 
 	for _, tt := range tests {
@@ -267,9 +285,15 @@ func TestContentEncodingGzip(t *testing.T) {
 
 				body := r.Body
 				var err error
-				if r.Header.Get("Content-Encoding") == "gzip" {
+				switch r.Header.Get("Content-Encoding") {
+				case "gzip":
 					body, err = gzip.NewReader(r.Body)
 					require.NoError(t, err)
+				case "zstd":
+					decoder, err := zstd.NewReader(r.Body)
+					require.NoError(t, err)
+					defer decoder.Close()
+					body = decoder.IOReadCloser()
 				}
 
 				payload, err := ioutil.ReadAll(body)
@@ -290,6 +314,84 @@ func TestContentEncodingGzip(t *testing.T) {
 	}
 }
 
+func TestContentEncodingZstdPayload(t *testing.T) {
+	var received []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		received, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:             ts.URL,
+		ContentEncoding: "zstd",
+	}
+	serializer := influx.NewSerializer()
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	metrics := make([]telegraf.Metric, 0, 100)
+	for i := 0; i < 100; i++ {
+		metrics = append(metrics, getMetric())
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected, err := serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	// the body is a zstd frame smaller than the payload
+	require.Equal(t, []byte{0x28, 0xb5, 0x2f, 0xfd}, received[:4])
+	require.True(t, len(received) < len(expected))
+
+	decoder, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer decoder.Close()
+	payload, err := decoder.DecodeAll(received, nil)
+	require.NoError(t, err)
+	require.Equal(t, expected, payload)
+}
+
+func TestContentEncodingUnsupported(t *testing.T) {
+	var encodings []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		payload, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(payload), "cpu value=42")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:             ts.URL,
+		ContentEncoding: "zstd",
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	require.NoError(t, plugin.Connect())
+
+	// the body is sent again without encoding, and so are the next ones
+	require.NoError(t, plugin.Write([]telegraf.Metric{getMetric()}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{getMetric()}))
+	require.Equal(t, []string{"zstd", "", ""}, encodings)
+}
+
+func TestInvalidContentEncoding(t *testing.T) {
+	for _, plugin := range []*HTTP{
+		{URL: "http://localhost", ContentEncoding: "br"},
+		{URL: "http://localhost", ContentEncoding: "gzip", CompressionLevel: 10},
+		{URL: "http://localhost", ContentEncoding: "zstd", CompressionLevel: 23},
+		{URL: "http://localhost", CompressionLevel: 1},
+	} {
+		require.Error(t, plugin.Connect())
+	}
+}
+
 func TestBasicAuth(t *testing.T) {
 	ts := httptest.NewServer(http.NotFoundHandler())
 	defer ts.Close()
@@ -16,6 +16,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
@@ -24,6 +25,7 @@ type APIErrorType int
 const (
 	_ APIErrorType = iota
 	DatabaseNotFound
+	PartialWrite
 )
 
 const (
@@ -34,6 +36,11 @@ const (
 	errStringPartialWrite          = "partial write"
 	errStringPointsBeyondRP        = "points beyond retention policy"
 	errStringUnableToParse         = "unable to parse"
+	errStringFieldTypeConflict     = "field type conflict"
+
+	// maxPartialWriteRetries is the maximum number of times the points not
+	// rejected by a partial write are sent again.
+	maxPartialWriteRetries = 5
 )
 
 var (
@@ -262,8 +269,47 @@ func (c *httpClient) CreateDatabase(ctx context.Context) error {
 	}
 }
 
-// Write sends the metrics to InfluxDB
+// Write sends the metrics to InfluxDB.  When some points are rejected by the
+// server, they are set aside and the other points are sent again: InfluxDB
+// may not have written them, and writing a point twice has no effect.  The
+// rejected points are returned in a models.PermanentError.
 func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error {
+	var rejected []telegraf.Metric
+	for retry := 0; len(metrics) > 0; retry++ {
+		err := c.write(ctx, metrics)
+		apiError, ok := err.(*APIError)
+		if !ok || apiError.Type != PartialWrite {
+			if err == nil {
+				break
+			}
+			return err
+		}
+
+		var more []telegraf.Metric
+		if retry < maxPartialWriteRetries {
+			more, metrics = c.splitRejected(metrics, apiError.Description)
+		}
+		if len(more) == 0 {
+			log.Printf("E! [outputs.influxdb]: when writing to [%s]: received error %v; discarding points",
+				c.URL(), apiError.Description)
+			break
+		}
+
+		log.Printf("E! [outputs.influxdb]: when writing to [%s]: received error %v; %d points rejected",
+			c.URL(), apiError.Description, len(more))
+		rejected = append(rejected, more...)
+	}
+
+	if len(rejected) > 0 {
+		return &models.PermanentError{
+			Metrics: rejected,
+			Err:     fmt.Errorf("%d points rejected by [%s]", len(rejected), c.URL()),
+		}
+	}
+	return nil
+}
+
+func (c *httpClient) write(ctx context.Context, metrics []telegraf.Metric) error {
 	var err error
 
 	reader := influx.NewReader(metrics, c.serializer)
@@ -316,20 +362,19 @@ func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error
 	}
 
 	// Other partial write errors, such as "field type conflict", are not
-	// correctable at this point and so the point is dropped instead of
+	// correctable for the rejected points, which are dropped instead of
 	// retrying.
-	if strings.Contains(desc, errStringPartialWrite) {
-		log.Printf("E! [outputs.influxdb]: when writing to [%s]: received error %v; discarding points",
-			c.URL(), desc)
-		return nil
-	}
-
-	// This error indicates a bug in either Telegraf line protocol
-	// serialization, retries would not be successful.
-	if strings.Contains(desc, errStringUnableToParse) {
-		log.Printf("E! [outputs.influxdb]: when writing to [%s]: received error %v; discarding points",
-			c.URL(), desc)
-		return nil
+	//
+	// Parse errors indicate a bug in either Telegraf line protocol
+	// serialization, retries would not be successful either.
+	if strings.Contains(desc, errStringPartialWrite) ||
+		strings.Contains(desc, errStringUnableToParse) {
+		return &APIError{
+			StatusCode:  resp.StatusCode,
+			Title:       resp.Status,
+			Description: desc,
+			Type:        PartialWrite,
+		}
 	}
 
 	return &APIError{
@@ -19,8 +19,11 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/outputs/influxdb"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -658,3 +661,104 @@ func TestHTTP_UnixSocket(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTP_WritePartial(t *testing.T) {
+	tests := []struct {
+		name     string
+		errors   []string
+		rejected string
+		requests []string
+	}{
+		{
+			name: "field type conflict",
+			errors: []string{
+				`partial write: field type conflict: input field "value" on measurement "cpu" is type integer, already exists as type float dropped=1`,
+			},
+			rejected: "cpu,host=b value=2i 0\n",
+			requests: []string{
+				"cpu,host=a value=1 0\ncpu,host=b value=2i 0\nmem value=3i 0\n",
+				"cpu,host=a value=1 0\nmem value=3i 0\n",
+			},
+		},
+		{
+			name: "several field type conflicts",
+			errors: []string{
+				`partial write: field type conflict: input field "value" on measurement "cpu" is type integer, already exists as type float dropped=2`,
+				`partial write: field type conflict: input field "value" on measurement "mem" is type integer, already exists as type boolean dropped=1`,
+			},
+			rejected: "cpu,host=b value=2i 0\nmem value=3i 0\n",
+			requests: []string{
+				"cpu,host=a value=1 0\ncpu,host=b value=2i 0\nmem value=3i 0\n",
+				"cpu,host=a value=1 0\nmem value=3i 0\n",
+				"cpu,host=a value=1 0\n",
+			},
+		},
+		{
+			name: "unable to parse",
+			errors: []string{
+				"unable to parse 'cpu,host=a value=1 0': bad timestamp\nunable to parse 'mem value=3i 0': bad timestamp",
+			},
+			rejected: "cpu,host=a value=1 0\nmem value=3i 0\n",
+			requests: []string{
+				"cpu,host=a value=1 0\ncpu,host=b value=2i 0\nmem value=3i 0\n",
+				"cpu,host=b value=2i 0\n",
+			},
+		},
+		{
+			name: "unidentified points are discarded",
+			errors: []string{
+				`partial write: max-values-per-tag limit exceeded (100001/100000): measurement="cpu" tag="host" value="b" dropped=1`,
+			},
+			requests: []string{
+				"cpu,host=a value=1 0\ncpu,host=b value=2i 0\nmem value=3i 0\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				requests = append(requests, string(body))
+				if len(requests) > len(tt.errors) {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"error": %q}`, tt.errors[len(requests)-1])
+			}))
+			defer ts.Close()
+
+			u, err := url.Parse(ts.URL)
+			require.NoError(t, err)
+
+			client, err := influxdb.NewHTTPClient(&influxdb.HTTPConfig{
+				URL: u,
+			})
+			require.NoError(t, err)
+
+			metrics := []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{"host": "a"},
+					map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+				testutil.MustMetric("cpu", map[string]string{"host": "b"},
+					map[string]interface{}{"value": int64(2)}, time.Unix(0, 0)),
+				testutil.MustMetric("mem", map[string]string{},
+					map[string]interface{}{"value": int64(3)}, time.Unix(0, 0)),
+			}
+			err = client.Write(context.Background(), metrics)
+			require.Equal(t, tt.requests, requests)
+			if tt.rejected == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			perr, ok := err.(*models.PermanentError)
+			require.True(t, ok, "expected a permanent error, got %v", err)
+			rejected, err := influx.NewSerializer().SerializeBatch(perr.Metrics)
+			require.NoError(t, err)
+			require.Equal(t, tt.rejected, string(rejected))
+		})
+	}
+}
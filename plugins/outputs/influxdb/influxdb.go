@@ -11,6 +11,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
@@ -47,6 +48,7 @@ type InfluxDB struct {
 	ContentEncoding      string            `toml:"content_encoding"`
 	SkipDatabaseCreation bool              `toml:"skip_database_creation"`
 	InfluxUintSupport    bool              `toml:"influx_uint_support"`
+	InfluxUintOverflow   string            `toml:"influx_uint_overflow"`
 	tls.ClientConfig
 
 	Precision string // precision deprecated in 1.0; value is ignored
@@ -120,6 +122,11 @@ var sampleConfig = `
   ## integer values.  Enabling this option will result in field type errors if
   ## existing data has been written.
   # influx_uint_support = false
+
+  ## Without unsigned integer support, values larger than the maximum signed
+  ## integer are written as the maximum signed integer with "clamp", or as
+  ## their two's complement value with "wrap".
+  # influx_uint_overflow = "clamp"
 `
 
 func (i *InfluxDB) Connect() error {
@@ -139,6 +146,14 @@ func (i *InfluxDB) Connect() error {
 	if i.InfluxUintSupport {
 		i.serializer.SetFieldTypeSupport(influx.UintSupport)
 	}
+	switch i.InfluxUintOverflow {
+	case "", "clamp":
+	case "wrap":
+		i.serializer.SetUintConversion(influx.UintWrap)
+	default:
+		return fmt.Errorf("invalid influx_uint_overflow %q, must be clamp or wrap",
+			i.InfluxUintOverflow)
+	}
 
 	for _, u := range urls {
 		parts, err := url.Parse(u)
@@ -202,6 +217,9 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 		if err == nil {
 			return nil
 		}
+		if _, ok := err.(*models.PermanentError); ok {
+			return err
+		}
 
 		switch apiError := err.(type) {
 		case *APIError:
@@ -0,0 +1,98 @@
+package influxdb
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// fieldTypeConflictRE matches the point rejected by a field type conflict, of
+// which InfluxDB only reports the first one.
+var fieldTypeConflictRE = regexp.MustCompile(
+	`input field "(.*?)" on measurement "(.*?)" is type (\w+)`)
+
+// splitRejected returns the metrics rejected by the server according to the
+// description of its error, and the other metrics.  No metrics are rejected
+// if they can not be identified.
+func (c *httpClient) splitRejected(metrics []telegraf.Metric, desc string) (rejected, accepted []telegraf.Metric) {
+	var isRejected func(telegraf.Metric) bool
+	switch {
+	case strings.Contains(desc, errStringFieldTypeConflict):
+		parts := fieldTypeConflictRE.FindStringSubmatch(desc)
+		if parts == nil {
+			return nil, metrics
+		}
+		isRejected = func(m telegraf.Metric) bool {
+			if m.Name() != parts[2] {
+				return false
+			}
+			v, ok := m.GetField(parts[1])
+			return ok && isFieldType(v, parts[3])
+		}
+	case strings.Contains(desc, errStringUnableToParse):
+		lines := unparsableLines(desc)
+		if len(lines) == 0 {
+			return nil, metrics
+		}
+		isRejected = func(m telegraf.Metric) bool {
+			octets, err := c.serializer.Serialize(m)
+			if err != nil {
+				return false
+			}
+			for _, line := range bytes.Split(bytes.TrimSuffix(octets, []byte("\n")), []byte("\n")) {
+				if lines[string(line)] {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return nil, metrics
+	}
+
+	for _, m := range metrics {
+		if isRejected(m) {
+			rejected = append(rejected, m)
+		} else {
+			accepted = append(accepted, m)
+		}
+	}
+	return rejected, accepted
+}
+
+// unparsableLines returns the lines of the parse errors, formatted as
+// "unable to parse '<line>': <reason>" one per line.
+func unparsableLines(desc string) map[string]bool {
+	prefix := errStringUnableToParse + " '"
+	lines := make(map[string]bool)
+	for _, e := range strings.Split(desc, "\n") {
+		start := strings.Index(e, prefix)
+		end := strings.LastIndex(e, "': ")
+		if start < 0 || end < start+len(prefix) {
+			continue
+		}
+		lines[e[start+len(prefix):end]] = true
+	}
+	return lines
+}
+
+// isFieldType returns true if the field value is written with the InfluxDB
+// type.  Unsigned integers are written as integers without uint support.
+func isFieldType(v interface{}, typ string) bool {
+	switch v.(type) {
+	case float64:
+		return typ == "float"
+	case int64:
+		return typ == "integer"
+	case uint64:
+		return typ == "integer" || typ == "unsigned"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	default:
+		return false
+	}
+}
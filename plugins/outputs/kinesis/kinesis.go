@@ -1,7 +1,11 @@
 package kinesis
 
 import (
+	"bytes"
+	"fmt"
 	"log"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -25,14 +29,20 @@ type (
 		Token       string `toml:"token"`
 		EndpointURL string `toml:"endpoint_url"`
 
-		StreamName         string     `toml:"streamname"`
-		PartitionKey       string     `toml:"partitionkey"`
-		RandomPartitionKey bool       `toml:"use_random_partitionkey"`
-		Partition          *Partition `toml:"partition"`
-		Debug              bool       `toml:"debug"`
-		svc                *kinesis.Kinesis
+		StreamName           string     `toml:"streamname"`
+		PartitionKey         string     `toml:"partitionkey"`
+		RandomPartitionKey   bool       `toml:"use_random_partitionkey"`
+		PartitionKeyTemplate string     `toml:"partition_key_template"`
+		Partition            *Partition `toml:"partition"`
+		Debug                bool       `toml:"debug"`
+		svc                  kinesisClient
 
-		serializer serializers.Serializer
+		serializer  serializers.Serializer
+		keyTemplate *template.Template
+	}
+
+	kinesisClient interface {
+		PutRecords(*kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error)
 	}
 
 	Partition struct {
@@ -40,6 +50,20 @@ type (
 		Key     string `toml:"key"`
 		Default string `toml:"default"`
 	}
+
+	// keyData is the data of the partition key template.
+	keyData struct {
+		Name string
+		Tags map[string]string
+	}
+)
+
+const (
+	// Limits of a PutRecords request
+	maxRecordsPerRequest = 500
+	maxRequestSize       = 5 * 1024 * 1024
+	// Limit of the data and partition key of a record
+	maxRecordSize = 1024 * 1024
 )
 
 var sampleConfig = `
@@ -96,7 +120,11 @@ var sampleConfig = `
   #    method = "tag"
   #    key = "host"
   #    default = "mykey"
-
+  #
+  ## Use a Go template rendered for each metric with its .Name and .Tags, so
+  ## the related metrics are written to the same shard.  It takes precedence
+  ## over the partition methods, a random key is used when it renders empty.
+  # partition_key_template = '{{ .Tags.region }}/{{ .Tags.host }}'
 
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
@@ -116,6 +144,18 @@ func (k *KinesisOutput) Description() string {
 	return "Configuration for the AWS Kinesis output."
 }
 
+func (k *KinesisOutput) Init() error {
+	if k.PartitionKeyTemplate == "" {
+		return nil
+	}
+	var err error
+	k.keyTemplate, err = template.New("partition_key").Option("missingkey=zero").Parse(k.PartitionKeyTemplate)
+	if err != nil {
+		return fmt.Errorf("could not parse partition_key_template: %v", err)
+	}
+	return nil
+}
+
 func (k *KinesisOutput) Connect() error {
 	if k.Partition == nil {
 		log.Print("E! kinesis : Deprecated paritionkey configuration in use, please consider using outputs.kinesis.partition")
@@ -179,6 +219,18 @@ func writekinesis(k *KinesisOutput, r []*kinesis.PutRecordsRequestEntry) time.Du
 }
 
 func (k *KinesisOutput) getPartitionKey(metric telegraf.Metric) string {
+	if k.keyTemplate != nil {
+		var buf bytes.Buffer
+		err := k.keyTemplate.Execute(&buf, keyData{Name: metric.Name(), Tags: metric.Tags()})
+		if err != nil {
+			log.Printf("E! kinesis : Unable to render the partition key template: %v", err)
+		}
+		if key := strings.TrimSpace(buf.String()); err == nil && key != "" {
+			return key
+		}
+		u := uuid.NewV4()
+		return u.String()
+	}
 	if k.Partition != nil {
 		switch k.Partition.Method {
 		case "static":
@@ -208,17 +260,14 @@ func (k *KinesisOutput) getPartitionKey(metric telegraf.Metric) string {
 }
 
 func (k *KinesisOutput) Write(metrics []telegraf.Metric) error {
-	var sz uint32
-
 	if len(metrics) == 0 {
 		return nil
 	}
 
 	r := []*kinesis.PutRecordsRequestEntry{}
+	var size int
 
 	for _, metric := range metrics {
-		sz++
-
 		values, err := k.serializer.Serialize(metric)
 		if err != nil {
 			return err
@@ -226,25 +275,31 @@ func (k *KinesisOutput) Write(metrics []telegraf.Metric) error {
 
 		partitionKey := k.getPartitionKey(metric)
 
-		d := kinesis.PutRecordsRequestEntry{
-			Data:         values,
-			PartitionKey: aws.String(partitionKey),
+		recordSize := len(values) + len(partitionKey)
+		if recordSize > maxRecordSize {
+			log.Printf("E! kinesis: Dropping a record of %d bytes, larger than the %d bytes limit", recordSize, maxRecordSize)
+			continue
 		}
 
-		r = append(r, &d)
-
-		if sz == 500 {
-			// Max Messages Per PutRecordRequest is 500
+		// Max Messages Per PutRecordRequest is 500, for at most 5MB
+		if len(r) == maxRecordsPerRequest || size+recordSize > maxRequestSize {
 			elapsed := writekinesis(k, r)
-			log.Printf("E! Wrote a %+v point batch to Kinesis in %+v.\n", sz, elapsed)
-			sz = 0
+			log.Printf("E! Wrote a %+v point batch to Kinesis in %+v.\n", len(r), elapsed)
+			size = 0
 			r = nil
 		}
 
+		d := kinesis.PutRecordsRequestEntry{
+			Data:         values,
+			PartitionKey: aws.String(partitionKey),
+		}
+
+		r = append(r, &d)
+		size += recordSize
 	}
-	if sz > 0 {
+	if len(r) > 0 {
 		elapsed := writekinesis(k, r)
-		log.Printf("E! Wrote a %+v point batch to Kinesis in %+v.\n", sz, elapsed)
+		log.Printf("E! Wrote a %+v point batch to Kinesis in %+v.\n", len(r), elapsed)
 	}
 
 	return nil
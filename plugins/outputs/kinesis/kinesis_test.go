@@ -1,11 +1,17 @@
 package kinesis
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPartitionKey(t *testing.T) {
@@ -83,3 +89,63 @@ func TestPartitionKey(t *testing.T) {
 	assert.Nil(err, "Issue parsing UUID")
 	assert.Equal(byte(4), u.Version(), "PartitionKey should be UUIDv4")
 }
+
+func TestPartitionKeyTemplate(t *testing.T) {
+	k := KinesisOutput{
+		PartitionKeyTemplate: "{{ .Tags.region }}/{{ .Tags.host }}",
+		// the template takes precedence
+		Partition: &Partition{Method: "static", Key: "-"},
+	}
+	require.NoError(t, k.Init())
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{"region": "eu-west-1", "host": "server01"},
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	require.Equal(t, "eu-west-1/server01", k.getPartitionKey(m))
+
+	k = KinesisOutput{PartitionKeyTemplate: "{{ .Tags.host }}"}
+	require.NoError(t, k.Init())
+
+	// a random key is used when the template renders empty
+	m = testutil.MustMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	u, err := uuid.FromString(k.getPartitionKey(m))
+	require.NoError(t, err)
+	require.Equal(t, byte(4), u.Version())
+
+	k = KinesisOutput{PartitionKeyTemplate: "{{ .Tags.host"}
+	require.Error(t, k.Init())
+}
+
+// mockKinesis records the sizes of the PutRecords requests.
+type mockKinesis struct {
+	requests []int
+}
+
+func (m *mockKinesis) PutRecords(input *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+	m.requests = append(m.requests, len(input.Records))
+	return &kinesis.PutRecordsOutput{}, nil
+}
+
+func TestWriteBatchLimits(t *testing.T) {
+	svc := &mockKinesis{}
+	k := KinesisOutput{PartitionKey: "-", svc: svc, serializer: influx.NewSerializer()}
+
+	metrics := make([]telegraf.Metric, 0, 1200)
+	for i := 0; i < 1200; i++ {
+		metrics = append(metrics, testutil.TestMetric(i))
+	}
+	require.NoError(t, k.Write(metrics))
+	require.Equal(t, []int{500, 500, 200}, svc.requests)
+
+	// 6 records of ~900KB exceed the 5MB of a request, the records larger
+	// than 1MB are dropped
+	svc.requests = nil
+	metrics = nil
+	for _, size := range []int{900, 900, 2000, 900, 900, 900, 900} {
+		metrics = append(metrics, testutil.MustMetric("blob", map[string]string{},
+			map[string]interface{}{"data": strings.Repeat("x", size*1024)}, time.Unix(0, 0)))
+	}
+	require.NoError(t, k.Write(metrics))
+	require.Equal(t, []int{5, 1}, svc.requests)
+}
@@ -0,0 +1,292 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+var sampleConfig = `
+  ## The domain of Loki
+  domain = "http://127.0.0.1:3100"
+
+  ## Endpoint to push the logs to
+  # endpoint = "/loki/api/v1/push"
+
+  ## Timeout for HTTP message
+  # timeout = "5s"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Additional HTTP headers, for example the tenant of a multi-tenant Loki
+  # [outputs.loki.headers]
+  #   X-Scope-OrgID = "telegraf"
+
+  ## Compress the body of the push requests with gzip
+  # gzip_request = false
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Data format of the log lines.
+  ## Each data format has it's own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  # data_format = "influx"
+`
+
+const (
+	defaultEndpoint      = "/loki/api/v1/push"
+	defaultClientTimeout = 5 * time.Second
+
+	// nameLabel is the stream label holding the metric name.
+	nameLabel = "__name"
+)
+
+type Loki struct {
+	Domain      string            `toml:"domain"`
+	Endpoint    string            `toml:"endpoint"`
+	Timeout     internal.Duration `toml:"timeout"`
+	Username    string            `toml:"username"`
+	Password    string            `toml:"password"`
+	Headers     map[string]string `toml:"headers"`
+	GzipRequest bool              `toml:"gzip_request"`
+	tls.ClientConfig
+
+	url        string
+	client     *http.Client
+	serializer serializers.Serializer
+}
+
+// pushRequest is the body of a push request.
+type pushRequest struct {
+	Streams []*stream `json:"streams"`
+}
+
+// stream holds the log lines with the same labels, the values are pairs of
+// a timestamp in nanoseconds and a log line.
+type stream struct {
+	Labels map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+
+	key   string
+	times []time.Time
+}
+
+func (s *stream) Len() int { return len(s.Values) }
+func (s *stream) Less(i, j int) bool {
+	return s.times[i].Before(s.times[j])
+}
+func (s *stream) Swap(i, j int) {
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+	s.times[i], s.times[j] = s.times[j], s.times[i]
+}
+
+func (l *Loki) SetSerializer(serializer serializers.Serializer) {
+	l.serializer = serializer
+}
+
+func (l *Loki) Description() string {
+	return "Send metrics as log lines to Loki"
+}
+
+func (l *Loki) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Loki) Connect() error {
+	if l.Domain == "" {
+		return fmt.Errorf("domain must be set")
+	}
+	if l.Endpoint == "" {
+		l.Endpoint = defaultEndpoint
+	}
+	l.url = strings.TrimSuffix(l.Domain, "/") + l.Endpoint
+
+	if l.Timeout.Duration == 0 {
+		l.Timeout.Duration = defaultClientTimeout
+	}
+
+	tlsCfg, err := l.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	l.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: l.Timeout.Duration,
+	}
+	return nil
+}
+
+func (l *Loki) Close() error {
+	return nil
+}
+
+func (l *Loki) Write(metrics []telegraf.Metric) error {
+	streams, err := l.streams(metrics)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(&pushRequest{Streams: streams})
+	if err != nil {
+		return err
+	}
+	return l.push(body)
+}
+
+// streams groups the metrics by labels, with the log lines of each stream
+// in time order as required by Loki.
+func (l *Loki) streams(metrics []telegraf.Metric) ([]*stream, error) {
+	byKey := make(map[string]*stream)
+	var streams []*stream
+	for _, metric := range metrics {
+		line, err := l.serializer.Serialize(metric)
+		if err != nil {
+			return nil, err
+		}
+
+		labels := streamLabels(metric)
+		key := labelsKey(labels)
+		s, ok := byKey[key]
+		if !ok {
+			s = &stream{Labels: labels, key: key}
+			byKey[key] = s
+			streams = append(streams, s)
+		}
+		s.Values = append(s.Values, [2]string{
+			strconv.FormatInt(metric.Time().UnixNano(), 10),
+			strings.TrimRight(string(line), "\n"),
+		})
+		s.times = append(s.times, metric.Time())
+	}
+
+	for _, s := range streams {
+		sort.Stable(s)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].key < streams[j].key
+	})
+	return streams, nil
+}
+
+// streamLabels returns the labels of the stream of the metric, its tags and
+// name, with the names sanitized to the characters allowed by Loki.
+func streamLabels(metric telegraf.Metric) map[string]string {
+	labels := make(map[string]string, len(metric.TagList())+1)
+	for _, tag := range metric.TagList() {
+		labels[sanitize(tag.Key)] = tag.Value
+	}
+	labels[nameLabel] = metric.Name()
+	return labels
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func sanitize(name string) string {
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case r >= '0' && r <= '9':
+		default:
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func (l *Loki) push(body []byte) error {
+	var reqBody io.Reader = bytes.NewBuffer(body)
+
+	var err error
+	if l.GzipRequest {
+		reqBody, err = internal.CompressWithGzip(reqBody)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, l.url, reqBody)
+	if err != nil {
+		return err
+	}
+
+	if l.Username != "" || l.Password != "" {
+		req.SetBasicAuth(l.Username, l.Password)
+	}
+
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	req.Header.Set("Content-Type", "application/json")
+	if l.GzipRequest {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range l.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("when writing to [%s] received status code %d: %s",
+			l.url, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("loki", func() telegraf.Output {
+		return &Loki{
+			Endpoint: defaultEndpoint,
+			Timeout:  internal.Duration{Duration: defaultClientTimeout},
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package loki
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(
+	t *testing.T,
+	tags map[string]string,
+	value float64,
+	sec int64,
+) telegraf.Metric {
+	m, err := metric.New("cpu", tags,
+		map[string]interface{}{"value": value}, time.Unix(sec, 0))
+	require.NoError(t, err)
+	return m
+}
+
+type pushed struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]string        `json:"values"`
+	} `json:"streams"`
+}
+
+// newServer returns a Loki server decoding the push requests into req.
+func newServer(t *testing.T, req *pushed, header *http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		*header = r.Header
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+		require.NoError(t, json.NewDecoder(body).Decode(req))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func newLoki(url string) *Loki {
+	l := &Loki{
+		Domain:   url,
+		Endpoint: defaultEndpoint,
+	}
+	l.SetSerializer(influx.NewSerializer())
+	return l
+}
+
+func TestWrite(t *testing.T) {
+	var req pushed
+	var header http.Header
+	ts := newServer(t, &req, &header)
+	defer ts.Close()
+
+	l := newLoki(ts.URL)
+	l.Username = "user"
+	l.Password = "pass"
+	l.Headers = map[string]string{"X-Scope-OrgID": "telegraf"}
+	require.NoError(t, l.Connect())
+
+	err := l.Write([]telegraf.Metric{
+		newMetric(t, map[string]string{"host": "b"}, 1, 2),
+		newMetric(t, map[string]string{"host": "a"}, 2, 3),
+		newMetric(t, map[string]string{"host": "b"}, 3, 1),
+		newMetric(t, map[string]string{"host": "b"}, 4, 1),
+	})
+	require.NoError(t, err)
+
+	user, pass, ok := (&http.Request{Header: header}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "pass", pass)
+	require.Equal(t, "telegraf", header.Get("X-Scope-OrgID"))
+
+	require.Len(t, req.Streams, 2)
+	require.Equal(t, map[string]string{"host": "a", "__name": "cpu"}, req.Streams[0].Stream)
+	require.Equal(t, [][]string{
+		{"3000000000", "cpu,host=a value=2 3000000000"},
+	}, req.Streams[0].Values)
+
+	require.Equal(t, map[string]string{"host": "b", "__name": "cpu"}, req.Streams[1].Stream)
+	require.Equal(t, [][]string{
+		{"1000000000", "cpu,host=b value=3 1000000000"},
+		{"1000000000", "cpu,host=b value=4 1000000000"},
+		{"2000000000", "cpu,host=b value=1 2000000000"},
+	}, req.Streams[1].Values)
+}
+
+func TestWriteGzip(t *testing.T) {
+	var req pushed
+	var header http.Header
+	ts := newServer(t, &req, &header)
+	defer ts.Close()
+
+	l := newLoki(ts.URL)
+	l.GzipRequest = true
+	require.NoError(t, l.Connect())
+
+	err := l.Write([]telegraf.Metric{newMetric(t, nil, 1, 1)})
+	require.NoError(t, err)
+
+	require.Equal(t, "gzip", header.Get("Content-Encoding"))
+	require.Len(t, req.Streams, 1)
+	require.Equal(t, map[string]string{"__name": "cpu"}, req.Streams[0].Stream)
+}
+
+func TestSanitizeLabels(t *testing.T) {
+	m := newMetric(t, map[string]string{"host.name": "a", "0zone": "b"}, 1, 1)
+	require.Equal(t, map[string]string{
+		"host_name": "a",
+		"_0zone":    "b",
+		"__name":    "cpu",
+	}, streamLabels(m))
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry out of order", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	l := newLoki(ts.URL)
+	require.NoError(t, l.Connect())
+
+	err := l.Write([]telegraf.Metric{newMetric(t, nil, 1, 1)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "entry out of order")
+}
+
+func TestConnectMissingDomain(t *testing.T) {
+	l := newLoki("")
+	require.Error(t, l.Connect())
+}
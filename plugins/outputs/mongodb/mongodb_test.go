@@ -0,0 +1,207 @@
+package mongodb
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// mockClient records the inserts and commands instead of sending them.
+type mockClient struct {
+	collections []string
+	inserts     map[string][][]interface{}
+	commands    []bson.D
+	failInsert  bool
+	closed      bool
+}
+
+func (c *mockClient) Insert(collection string, docs []interface{}) error {
+	if c.failInsert {
+		return errors.New("connection reset")
+	}
+	c.inserts[collection] = append(c.inserts[collection], docs)
+	return nil
+}
+
+func (c *mockClient) CollectionNames() ([]string, error) {
+	return c.collections, nil
+}
+
+func (c *mockClient) Run(cmd bson.D) error {
+	c.commands = append(c.commands, cmd)
+	return nil
+}
+
+func (c *mockClient) Close() {
+	c.closed = true
+}
+
+func newTestMongoDB(t *testing.T, m *MongoDB, c *mockClient) *MongoDB {
+	m.DSN = "mongodb://localhost:27017"
+	m.Database = "telegraf"
+	if m.TimeSeriesGranularity == "" {
+		m.TimeSeriesGranularity = "seconds"
+	}
+	c.inserts = make(map[string][][]interface{})
+	m.dial = func(*MongoDB) (client, error) { return c, nil }
+	require.NoError(t, m.Init())
+	require.NoError(t, m.Connect())
+	return m
+}
+
+var now = time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "web01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 98.5, "usage_user": 1.5},
+			now),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "web01", "cpu": "cpu1"},
+			map[string]interface{}{"usage_idle": 97.0, "usage_user": 3.0},
+			now),
+		testutil.MustMetric("disk",
+			map[string]string{"host": "web01", "path": "/"},
+			map[string]interface{}{
+				"free":     uint64(1 << 40),
+				"inodes":   uint64(math.MaxUint64),
+				"readonly": false,
+				"fstype":   "ext4",
+				"used.pct": int64(42),
+			},
+			now),
+	}
+}
+
+func TestWriteCollectionPerMeasurement(t *testing.T) {
+	c := &mockClient{}
+	m := newTestMongoDB(t, &MongoDB{}, c)
+
+	require.NoError(t, m.Write(testMetrics()))
+
+	// the documents of a collection are inserted with a single request
+	require.Len(t, c.inserts["cpu"], 1)
+	require.Equal(t, []interface{}{
+		bson.M{
+			"timestamp":  now,
+			"tags":       bson.M{"host": "web01", "cpu": "cpu0"},
+			"usage_idle": 98.5,
+			"usage_user": 1.5,
+		},
+		bson.M{
+			"timestamp":  now,
+			"tags":       bson.M{"host": "web01", "cpu": "cpu1"},
+			"usage_idle": 97.0,
+			"usage_user": 3.0,
+		},
+	}, c.inserts["cpu"][0])
+
+	require.Len(t, c.inserts["disk"], 1)
+	require.Equal(t, []interface{}{
+		bson.M{
+			"timestamp": now,
+			"tags":      bson.M{"host": "web01", "path": "/"},
+			"free":      int64(1 << 40),
+			"inodes":    float64(math.MaxUint64),
+			"readonly":  false,
+			"fstype":    "ext4",
+			"used_pct":  int64(42),
+		},
+	}, c.inserts["disk"][0])
+}
+
+func TestWriteFixedCollection(t *testing.T) {
+	c := &mockClient{}
+	m := newTestMongoDB(t, &MongoDB{Collection: "metrics"}, c)
+
+	require.NoError(t, m.Write(testMetrics()))
+	require.Len(t, c.inserts, 1)
+	require.Len(t, c.inserts["metrics"], 1)
+
+	docs := c.inserts["metrics"][0]
+	require.Len(t, docs, 3)
+	require.Equal(t, "cpu", docs[0].(bson.M)["name"])
+	require.Equal(t, "disk", docs[2].(bson.M)["name"])
+}
+
+func TestWriteCreateTimeSeries(t *testing.T) {
+	c := &mockClient{collections: []string{"cpu"}}
+	m := newTestMongoDB(t, &MongoDB{
+		CreateTimeSeries:      true,
+		TimeSeriesGranularity: "minutes",
+		TTL:                   internal.Duration{Duration: 24 * time.Hour},
+	}, c)
+
+	require.NoError(t, m.Write(testMetrics()))
+	require.NoError(t, m.Write(testMetrics()))
+
+	// only the missing collection is created, once
+	require.Equal(t, []bson.D{{
+		{Name: "create", Value: "disk"},
+		{Name: "timeseries", Value: bson.D{
+			{Name: "timeField", Value: "timestamp"},
+			{Name: "metaField", Value: "tags"},
+			{Name: "granularity", Value: "minutes"},
+		}},
+		{Name: "expireAfterSeconds", Value: int64(86400)},
+	}}, c.commands)
+	require.Len(t, c.inserts["disk"], 2)
+}
+
+func TestWriteErrorReconnects(t *testing.T) {
+	c := &mockClient{failInsert: true}
+	m := newTestMongoDB(t, &MongoDB{}, c)
+
+	require.Error(t, m.Write(testMetrics()))
+	require.True(t, c.closed)
+
+	c.failInsert = false
+	require.NoError(t, m.Write(testMetrics()))
+	require.Len(t, c.inserts["cpu"], 1)
+}
+
+func TestWriteConcern(t *testing.T) {
+	for _, tt := range []struct {
+		writeConcern string
+		w            int
+		wmode        string
+	}{
+		{"", 1, ""},
+		{"1", 1, ""},
+		{"3", 3, ""},
+		{"majority", 0, "majority"},
+	} {
+		m := &MongoDB{
+			WriteConcern:        tt.writeConcern,
+			WriteConcernJournal: true,
+			WriteConcernTimeout: internal.Duration{Duration: 5 * time.Second},
+		}
+		safe, err := m.safe()
+		require.NoError(t, err)
+		require.Equal(t, tt.w, safe.W)
+		require.Equal(t, tt.wmode, safe.WMode)
+		require.True(t, safe.J)
+		require.Equal(t, 5000, safe.WTimeout)
+	}
+
+	safe, err := (&MongoDB{WriteConcern: "0"}).safe()
+	require.NoError(t, err)
+	require.Nil(t, safe)
+
+	_, err = (&MongoDB{WriteConcern: "all"}).safe()
+	require.Error(t, err)
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&MongoDB{Database: "telegraf", TimeSeriesGranularity: "seconds"}).Init())
+	require.Error(t, (&MongoDB{DSN: "mongodb://localhost", Database: "telegraf", TimeSeriesGranularity: "days"}).Init())
+}
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,7 +39,8 @@ type Sample struct {
 	// Histograms and Summaries need a count and a sum
 	Count uint64
 	Sum   float64
-	// Expiration is the deadline that this Sample is valid until.
+	// Expiration is the deadline that this Sample is valid until, the zero
+	// time if it does not expire.
 	Expiration time.Time
 }
 
@@ -53,6 +55,14 @@ type MetricFamily struct {
 	LabelSet map[string]int
 }
 
+// metricExpiration is the expiration interval of the metrics with a name
+// matching the filter.
+type metricExpiration struct {
+	pattern  string
+	filter   filter.Filter
+	interval time.Duration
+}
+
 type PrometheusClient struct {
 	Listen             string
 	TLSCert            string            `toml:"tls_cert"`
@@ -61,6 +71,7 @@ type PrometheusClient struct {
 	BasicPassword      string            `toml:"basic_password"`
 	IPRange            []string          `toml:"ip_range"`
 	ExpirationInterval internal.Duration `toml:"expiration_interval"`
+	MetricExpiration   map[string]string `toml:"metric_expiration"`
 	Path               string            `toml:"path"`
 	CollectorsExclude  []string          `toml:"collectors_exclude"`
 	StringAsLabel      bool              `toml:"string_as_label"`
@@ -70,6 +81,8 @@ type PrometheusClient struct {
 	sync.Mutex
 	// fam is the non-expired MetricFamily by Prometheus metric name.
 	fam map[string]*MetricFamily
+	// expirations are the compiled MetricExpiration, longest pattern first.
+	expirations []metricExpiration
 	// now returns the current time.
 	now func() time.Time
 }
@@ -92,6 +105,13 @@ var sampleConfig = `
   ## Expiration interval for each metric. 0 == no expiration
   # expiration_interval = "60s"
 
+  ## Expiration intervals overriding expiration_interval for the metrics with
+  ## a Prometheus name matching a glob pattern.  If several patterns match,
+  ## the longest one is used.
+  # [outputs.prometheus_client.metric_expiration]
+  #   "http_request_*" = "10s"
+  #   "build_info" = "24h"
+
   ## Collectors to enable, valid entries are "gocollector" and "process".
   ## If unset, both are enabled.
   # collectors_exclude = ["gocollector", "process"]
@@ -145,6 +165,10 @@ func (p *PrometheusClient) auth(h http.Handler) http.Handler {
 }
 
 func (p *PrometheusClient) Connect() error {
+	if err := p.compileExpirations(); err != nil {
+		return err
+	}
+
 	defaultCollectors := map[string]bool{
 		"gocollector": true,
 		"process":     true,
@@ -221,12 +245,61 @@ func (p *PrometheusClient) Describe(ch chan<- *prometheus.Desc) {
 	prometheus.NewGauge(prometheus.GaugeOpts{Name: "Dummy", Help: "Dummy"}).Describe(ch)
 }
 
+// compileExpirations compiles the MetricExpiration patterns, unless already
+// done.
+func (p *PrometheusClient) compileExpirations() error {
+	if p.expirations != nil || len(p.MetricExpiration) == 0 {
+		return nil
+	}
+
+	expirations := make([]metricExpiration, 0, len(p.MetricExpiration))
+	for pattern, value := range p.MetricExpiration {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid expiration of %q: %v", pattern, err)
+		}
+		f, err := filter.Compile([]string{pattern})
+		if err != nil {
+			return fmt.Errorf("invalid metric_expiration pattern %q: %v", pattern, err)
+		}
+		expirations = append(expirations, metricExpiration{
+			pattern:  pattern,
+			filter:   f,
+			interval: interval,
+		})
+	}
+	sort.Slice(expirations, func(i, j int) bool {
+		if len(expirations[i].pattern) != len(expirations[j].pattern) {
+			return len(expirations[i].pattern) > len(expirations[j].pattern)
+		}
+		return expirations[i].pattern < expirations[j].pattern
+	})
+	p.expirations = expirations
+	return nil
+}
+
+// expiration returns the expiration deadline of a Sample of the metric
+// written at now, or the zero time if it does not expire.
+func (p *PrometheusClient) expiration(name string, now time.Time) time.Time {
+	interval := p.ExpirationInterval.Duration
+	for _, e := range p.expirations {
+		if e.filter.Match(name) {
+			interval = e.interval
+			break
+		}
+	}
+	if interval == 0 {
+		return time.Time{}
+	}
+	return now.Add(interval)
+}
+
 // Expire removes Samples that have expired.
 func (p *PrometheusClient) Expire() {
 	now := p.now()
 	for name, family := range p.fam {
 		for key, sample := range family.Samples {
-			if p.ExpirationInterval.Duration != 0 && now.After(sample.Expiration) {
+			if !sample.Expiration.IsZero() && now.After(sample.Expiration) {
 				for k := range sample.Labels {
 					family.LabelSet[k]--
 				}
@@ -346,6 +419,10 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 	p.Lock()
 	defer p.Unlock()
 
+	if err := p.compileExpirations(); err != nil {
+		return err
+	}
+
 	now := p.now()
 
 	for _, point := range metrics {
@@ -404,9 +481,9 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 				SummaryValue: summaryvalue,
 				Count:        count,
 				Sum:          sum,
-				Expiration:   now.Add(p.ExpirationInterval.Duration),
 			}
 			mname = sanitize(point.Name())
+			sample.Expiration = p.expiration(mname, now)
 
 			p.addMetricFamily(point, sample, mname, sampleID)
 
@@ -445,9 +522,9 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 				HistogramValue: histogramvalue,
 				Count:          count,
 				Sum:            sum,
-				Expiration:     now.Add(p.ExpirationInterval.Duration),
 			}
 			mname = sanitize(point.Name())
+			sample.Expiration = p.expiration(mname, now)
 
 			p.addMetricFamily(point, sample, mname, sampleID)
 
@@ -466,12 +543,6 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 					continue
 				}
 
-				sample := &Sample{
-					Labels:     labels,
-					Value:      value,
-					Expiration: now.Add(p.ExpirationInterval.Duration),
-				}
-
 				// Special handling of value field; supports passthrough from
 				// the prometheus input.
 				var mname string
@@ -493,6 +564,12 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 					}
 				}
 
+				sample := &Sample{
+					Labels:     labels,
+					Value:      value,
+					Expiration: p.expiration(mname, now),
+				}
+
 				p.addMetricFamily(point, sample, mname, sampleID)
 
 			}
@@ -603,6 +603,51 @@ func TestExpire_TagsWithDecrement(t *testing.T) {
 	require.Equal(t, map[string]int{"host": 0}, fam.LabelSet)
 }
 
+func TestExpire_MetricExpiration(t *testing.T) {
+	client := NewClient()
+	client.MetricExpiration = map[string]string{
+		"foo*":      "10s",
+		"foo_build": "0s",
+	}
+
+	setUnixTime(client, 0)
+	for _, name := range []string{"foo", "foo_build", "bar"} {
+		m, err := metric.New(
+			name,
+			make(map[string]string),
+			map[string]interface{}{"value": 1.0},
+			time.Now())
+		require.NoError(t, err)
+		err = client.Write([]telegraf.Metric{m})
+		require.NoError(t, err)
+	}
+
+	setUnixTime(client, 11)
+	client.Expire()
+	require.Equal(t, 2, len(client.fam))
+	require.Contains(t, client.fam, "foo_build")
+	require.Contains(t, client.fam, "bar")
+
+	setUnixTime(client, 61)
+	client.Expire()
+	require.Equal(t, 1, len(client.fam))
+	require.Contains(t, client.fam, "foo_build")
+}
+
+func TestMetricExpirationInvalid(t *testing.T) {
+	client := NewClient()
+	client.MetricExpiration = map[string]string{"foo": "soon"}
+
+	m, err := metric.New(
+		"foo",
+		make(map[string]string),
+		map[string]interface{}{"value": 1.0},
+		time.Now())
+	require.NoError(t, err)
+	err = client.Write([]telegraf.Metric{m})
+	require.Error(t, err)
+}
+
 var pTesting *PrometheusClient
 
 func TestPrometheusWritePointEmptyTag(t *testing.T) {
@@ -0,0 +1,268 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## URL of the remote write endpoint, for example the push API of Cortex,
+  ## Mimir or the receive component of Thanos.
+  url = "http://127.0.0.1:9090/api/v1/write"
+
+  ## Timeout for HTTP message
+  # timeout = "5s"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Additional HTTP headers, for example the tenant of Cortex or Mimir
+  # [outputs.prometheus_remote_write.headers]
+  #   X-Scope-OrgID = "telegraf"
+
+  ## Maximum number of attempts of a request failing with a connection error
+  ## or a 5xx status code, and the wait time before the first retry.
+  # max_attempts = 1
+  # retry_backoff = "1s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	defaultClientTimeout = 5 * time.Second
+	defaultRetryBackoff  = time.Second
+
+	// remoteWriteVersion is the version of the remote write protocol.
+	remoteWriteVersion = "0.1.0"
+
+	// nameLabel is the label holding the name of a time series.
+	nameLabel = "__name__"
+)
+
+var invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type PrometheusRemoteWrite struct {
+	URL          string            `toml:"url"`
+	Timeout      internal.Duration `toml:"timeout"`
+	Username     string            `toml:"username"`
+	Password     string            `toml:"password"`
+	Headers      map[string]string `toml:"headers"`
+	MaxAttempts  int               `toml:"max_attempts"`
+	RetryBackoff internal.Duration `toml:"retry_backoff"`
+	tls.ClientConfig
+
+	client *http.Client
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Send metrics to a Prometheus remote write endpoint"
+}
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	if p.URL == "" {
+		return fmt.Errorf("url must be set")
+	}
+	if p.Timeout.Duration == 0 {
+		p.Timeout.Duration = defaultClientTimeout
+	}
+	if p.RetryBackoff.Duration == 0 {
+		p.RetryBackoff.Duration = defaultRetryBackoff
+	}
+
+	tlsCfg, err := p.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: tlsCfg,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+	p.client = &http.Client{
+		Transport: internal.NewRetryTransport(transport, p.MaxAttempts,
+			p.RetryBackoff.Duration, map[string]string{"output": "prometheus_remote_write"}),
+		Timeout: p.Timeout.Duration,
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	series := timeSeriesOf(metrics)
+	if len(series) == 0 {
+		return nil
+	}
+	return p.send(snappy.Encode(nil, encodeWriteRequest(series)))
+}
+
+// timeSeriesOf groups the numeric fields of the metrics in time series.  A
+// time series is named after the measurement and field, or the measurement
+// only for a field named value, and labelled with the tags.  The labels and
+// the samples of each time series are sorted as required by the receivers.
+func timeSeriesOf(metrics []telegraf.Metric) []*timeSeries {
+	byKey := make(map[string]*timeSeries)
+	var series []*timeSeries
+	for _, metric := range metrics {
+		for _, field := range metric.FieldList() {
+			value, ok := sampleValue(field.Value)
+			if !ok {
+				continue
+			}
+
+			name := metric.Name()
+			if field.Key != "value" {
+				name += "_" + field.Key
+			}
+			labels := make([]label, 0, len(metric.TagList())+1)
+			labels = append(labels, label{name: nameLabel, value: sanitize(name)})
+			for _, tag := range metric.TagList() {
+				labels = append(labels, label{name: sanitize(tag.Key), value: tag.Value})
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+
+			key := labelsKey(labels)
+			ts, ok := byKey[key]
+			if !ok {
+				ts = &timeSeries{labels: labels}
+				byKey[key] = ts
+				series = append(series, ts)
+			}
+			ts.samples = append(ts.samples, sample{
+				value:     value,
+				timestamp: metric.Time().UnixNano() / int64(time.Millisecond),
+			})
+		}
+	}
+
+	for _, ts := range series {
+		samples := ts.samples
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].timestamp < samples[j].timestamp
+		})
+	}
+	return series
+}
+
+func sampleValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// sanitize replaces the characters not allowed in the metric and label names
+// of Prometheus by underscores.
+func sanitize(name string) string {
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return invalidNameCharRE.ReplaceAllString(name, "_")
+}
+
+func labelsKey(labels []label) string {
+	var b bytes.Buffer
+	for _, l := range labels {
+		b.WriteString(l.name)
+		b.WriteByte(0)
+		b.WriteString(l.value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func (p *PrometheusRemoteWrite) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if p.Username != "" || p.Password != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	if p.MaxAttempts > 1 {
+		// samples sent again are ignored by the receiver, so the POST
+		// requests can be retried
+		key, err := idempotencyKey()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Idempotency-Key", key)
+	}
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("when writing to [%s] received status code %d: %s",
+			p.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func idempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return &PrometheusRemoteWrite{
+			Timeout:      internal.Duration{Duration: defaultClientTimeout},
+			RetryBackoff: internal.Duration{Duration: defaultRetryBackoff},
+		}
+	})
+}
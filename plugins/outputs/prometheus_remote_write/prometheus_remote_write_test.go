@@ -0,0 +1,148 @@
+package prometheus_remote_write
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	receiver "github.com/influxdata/telegraf/plugins/inputs/prometheus_remote_write"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	sec int64,
+) telegraf.Metric {
+	m, _ := metric.New(name, tags, fields, time.Unix(sec, 0))
+	return m
+}
+
+func TestTimeSeries(t *testing.T) {
+	series := timeSeriesOf([]telegraf.Metric{
+		newMetric("cpu", map[string]string{"host": "a", "cpu-id": "0"},
+			map[string]interface{}{"usage_idle": 90.0, "state": "ok"}, 2),
+		newMetric("cpu", map[string]string{"host": "a", "cpu-id": "0"},
+			map[string]interface{}{"usage_idle": 80.0}, 1),
+		newMetric("up", nil, map[string]interface{}{"value": true}, 1),
+	})
+
+	require.Equal(t, []*timeSeries{
+		{
+			labels: []label{
+				{name: "__name__", value: "cpu_usage_idle"},
+				{name: "cpu_id", value: "0"},
+				{name: "host", value: "a"},
+			},
+			samples: []sample{
+				{value: 80, timestamp: 1000},
+				{value: 90, timestamp: 2000},
+			},
+		},
+		{
+			labels:  []label{{name: "__name__", value: "up"}},
+			samples: []sample{{value: 1, timestamp: 1000}},
+		},
+	}, series)
+}
+
+func TestSanitize(t *testing.T) {
+	require.Equal(t, "disk_used_percent", sanitize("disk.used-percent"))
+	require.Equal(t, "_1m_load", sanitize("1m_load"))
+}
+
+func TestRoundTrip(t *testing.T) {
+	input := &receiver.PrometheusRemoteWrite{
+		ServiceAddress: "localhost:0",
+		Path:           "/receive",
+		BasicUsername:  "telegraf",
+		BasicPassword:  "secret",
+	}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, input.Start(acc))
+	defer input.Stop()
+
+	output := &PrometheusRemoteWrite{
+		URL:      "http://localhost:" + strconv.Itoa(input.Port) + "/receive",
+		Username: "telegraf",
+		Password: "secret",
+	}
+	require.NoError(t, output.Connect())
+
+	err := output.Write([]telegraf.Metric{
+		newMetric("mem", map[string]string{"host": "a"},
+			map[string]interface{}{"used": int64(1024), "free": uint64(2048)}, 10),
+		newMetric("mem", map[string]string{"host": "b"},
+			map[string]interface{}{"used": int64(512)}, 20),
+	})
+	require.NoError(t, err)
+
+	acc.Wait(3)
+	require.Len(t, acc.Metrics, 3)
+	acc.AssertContainsTaggedFields(t, "mem_used",
+		map[string]interface{}{"value": 1024.0}, map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "mem_free",
+		map[string]interface{}{"value": 2048.0}, map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "mem_used",
+		map[string]interface{}{"value": 512.0}, map[string]string{"host": "b"})
+	require.True(t, acc.HasTimestamp("mem_free", time.Unix(10, 0)))
+}
+
+func TestHeaders(t *testing.T) {
+	var header http.Header
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		compressed, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err = snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	output := &PrometheusRemoteWrite{
+		URL:         ts.URL,
+		Headers:     map[string]string{"X-Scope-OrgID": "telegraf"},
+		MaxAttempts: 3,
+	}
+	require.NoError(t, output.Connect())
+	require.NoError(t, output.Write([]telegraf.Metric{
+		newMetric("up", nil, map[string]interface{}{"value": 1.0}, 1),
+	}))
+
+	require.Equal(t, "snappy", header.Get("Content-Encoding"))
+	require.Equal(t, "application/x-protobuf", header.Get("Content-Type"))
+	require.Equal(t, "0.1.0", header.Get("X-Prometheus-Remote-Write-Version"))
+	require.Equal(t, "telegraf", header.Get("X-Scope-OrgID"))
+	require.NotEmpty(t, header.Get("Idempotency-Key"))
+
+	// WriteRequest{timeseries: [{labels: [{__name__, up}], samples: [{1, 1000}]}]}
+	require.Equal(t, []byte{
+		0x0a, 0x1e,
+		0x0a, 0x0e, 0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_', 0x12, 0x02, 'u', 'p',
+		0x12, 0x0c, 0x09, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0x10, 0xe8, 0x07,
+	}, body)
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	output := &PrometheusRemoteWrite{URL: ts.URL}
+	require.NoError(t, output.Connect())
+	err := output.Write([]telegraf.Metric{
+		newMetric("up", nil, map[string]interface{}{"value": 1.0}, 1),
+	})
+	require.EqualError(t, err, "when writing to ["+ts.URL+"] received status code 400: out of order sample")
+}
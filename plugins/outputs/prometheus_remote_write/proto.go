@@ -0,0 +1,97 @@
+package prometheus_remote_write
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The messages of a remote write request, see prompb/remote.proto and
+// prompb/types.proto of Prometheus:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// protoWriter appends protobuf fields to a buffer.
+type protoWriter struct {
+	buf []byte
+}
+
+func (w *protoWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf = append(w.buf, b[:n]...)
+}
+
+func (w *protoWriter) key(num int, wireType int) {
+	w.varint(uint64(num)<<3 | uint64(wireType))
+}
+
+func (w *protoWriter) bytes(num int, b []byte) {
+	w.key(num, wireBytes)
+	w.varint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *protoWriter) string(num int, s string) {
+	w.key(num, wireBytes)
+	w.varint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *protoWriter) double(num int, v float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	w.key(num, wireFixed64)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *protoWriter) int64(num int, v int64) {
+	w.key(num, wireVarint)
+	w.varint(uint64(v))
+}
+
+// encodeWriteRequest encodes the time series as a WriteRequest.
+func encodeWriteRequest(series []*timeSeries) []byte {
+	var req, ts, msg protoWriter
+	for _, s := range series {
+		ts.buf = ts.buf[:0]
+		for _, l := range s.labels {
+			msg.buf = msg.buf[:0]
+			msg.string(1, l.name)
+			msg.string(2, l.value)
+			ts.bytes(1, msg.buf)
+		}
+		for _, smp := range s.samples {
+			msg.buf = msg.buf[:0]
+			msg.double(1, smp.value)
+			msg.int64(2, smp.timestamp)
+			ts.bytes(2, msg.buf)
+		}
+		req.bytes(1, ts.buf)
+	}
+	return req.buf
+}
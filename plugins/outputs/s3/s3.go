@@ -0,0 +1,383 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/satori/go.uuid"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const (
+	defaultKey = `telegraf/{{ .Time.Format "2006-01-02" }}/{{ .Tags.host }}/{{ .ID }}`
+
+	// minPartSize is the minimum size of the parts of a multipart upload,
+	// except for the last one.
+	minPartSize = 5 * 1024 * 1024
+
+	defaultMultipartSize = 16 * 1024 * 1024
+)
+
+type S3 struct {
+	Region      string `toml:"region"`
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	Token       string `toml:"token"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	ForcePathStyle       bool          `toml:"force_path_style"`
+	Bucket               string        `toml:"bucket"`
+	Key                  string        `toml:"key"`
+	ContentEncoding      string        `toml:"content_encoding"`
+	MultipartSize        internal.Size `toml:"multipart_size"`
+	ServerSideEncryption string        `toml:"server_side_encryption"`
+	SSEKMSKeyID          string        `toml:"sse_kms_key_id"`
+
+	client     s3Client
+	serializer serializers.Serializer
+	key        *template.Template
+	// newID returns the unique ID of a new object
+	newID func() string
+}
+
+type s3Client interface {
+	PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(*s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(*s3.UploadPartInput) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(*s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(*s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// keyData is the data of the key template.
+type keyData struct {
+	Name string
+	Tags map[string]string
+	Time time.Time
+	ID   string
+}
+
+var sampleConfig = `
+  ## Amazon Region
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:9000"
+  # endpoint_url = ""
+
+  ## Address the bucket in the path of the URL rather than in the host name,
+  ## needed by most S3 compatible services.
+  # force_path_style = false
+
+  ## Bucket the objects are written to.
+  bucket = "my-bucket"
+
+  ## Go template of the keys of the objects, rendered for each metric with
+  ## its .Name, .Tags and .Time in UTC.  The metrics of a write with the same
+  ## key are written to the same object, .ID is unique for each object and
+  ## must be part of the key.
+  # key = '''telegraf/{{ .Time.Format "2006-01-02" }}/{{ .Tags.host }}/{{ .ID }}'''
+
+  ## Compress the objects, either "identity" or "gzip".
+  # content_encoding = "identity"
+
+  ## Objects larger than this size are uploaded in parts of this size, it
+  ## must be at least 5MB.
+  # multipart_size = "16MB"
+
+  ## Server-side encryption of the objects, either "AES256" or "aws:kms"
+  ## with the optional ID of the KMS key.
+  # server_side_encryption = ""
+  # sse_kms_key_id = ""
+
+  ## The number of metrics per object and the time between objects are set
+  ## with metric_batch_size and flush_interval.
+  # metric_batch_size = 10000
+  # flush_interval = "1m"
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+func (s *S3) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *S3) Description() string {
+	return "Write metrics as objects to an Amazon S3 bucket"
+}
+
+func (s *S3) SetSerializer(serializer serializers.Serializer) {
+	s.serializer = serializer
+}
+
+func (s *S3) Init() error {
+	if s.Bucket == "" {
+		return fmt.Errorf("bucket must be set")
+	}
+
+	if s.Key == "" {
+		s.Key = defaultKey
+	}
+	var err error
+	s.key, err = template.New("key").Option("missingkey=zero").Parse(s.Key)
+	if err != nil {
+		return fmt.Errorf("could not parse key: %v", err)
+	}
+	// the key must change with the ID so objects are never overwritten
+	var keys [2]string
+	for i := range keys {
+		keys[i], err = s.renderKey(keyData{ID: fmt.Sprintf("id%d", i)})
+		if err != nil {
+			return err
+		}
+	}
+	if keys[0] == keys[1] {
+		return fmt.Errorf("key must contain the object {{ .ID }}")
+	}
+
+	switch s.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content_encoding %q", s.ContentEncoding)
+	}
+
+	if s.MultipartSize.Size == 0 {
+		s.MultipartSize.Size = defaultMultipartSize
+	}
+	if s.MultipartSize.Size < minPartSize {
+		return fmt.Errorf("multipart_size must be at least 5MB")
+	}
+
+	switch s.ServerSideEncryption {
+	case "", s3.ServerSideEncryptionAes256, s3.ServerSideEncryptionAwsKms:
+	default:
+		return fmt.Errorf("invalid server_side_encryption %q", s.ServerSideEncryption)
+	}
+	if s.SSEKMSKeyID != "" && s.ServerSideEncryption != s3.ServerSideEncryptionAwsKms {
+		return fmt.Errorf("sse_kms_key_id requires server_side_encryption aws:kms")
+	}
+
+	if s.newID == nil {
+		s.newID = func() string { return uuid.NewV4().String() }
+	}
+	return nil
+}
+
+func (s *S3) Connect() error {
+	if s.client != nil {
+		return nil
+	}
+
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      s.Region,
+		AccessKey:   s.AccessKey,
+		SecretKey:   s.SecretKey,
+		RoleARN:     s.RoleARN,
+		Profile:     s.Profile,
+		Filename:    s.Filename,
+		Token:       s.Token,
+		EndpointURL: s.EndpointURL,
+	}
+	configProvider := credentialConfig.Credentials()
+
+	s.client = s3.New(configProvider, &aws.Config{
+		S3ForcePathStyle: aws.Bool(s.ForcePathStyle),
+	})
+	return nil
+}
+
+func (s *S3) Close() error {
+	return nil
+}
+
+func (s *S3) renderKey(data keyData) (string, error) {
+	var buf bytes.Buffer
+	if err := s.key.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render key: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// Write writes the metrics to one object per key.  If an upload fails the
+// objects already written are written again with new keys on the next write.
+func (s *S3) Write(metrics []telegraf.Metric) error {
+	// the metrics are grouped by their key rendered with a placeholder ID,
+	// replaced by a new ID for each object
+	placeholder := s.newID()
+	var keys []string
+	groups := make(map[string][]telegraf.Metric)
+	for _, metric := range metrics {
+		key, err := s.renderKey(keyData{
+			Name: metric.Name(),
+			Tags: metric.Tags(),
+			Time: metric.Time().UTC(),
+			ID:   placeholder,
+		})
+		if err != nil {
+			return err
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], metric)
+	}
+
+	for _, key := range keys {
+		body, err := s.serializer.SerializeBatch(groups[key])
+		if err != nil {
+			return err
+		}
+		if s.ContentEncoding == "gzip" {
+			body, err = compress(body)
+			if err != nil {
+				return err
+			}
+		}
+
+		key = strings.Replace(key, placeholder, s.newID(), -1)
+		if err := s.upload(key, body); err != nil {
+			return fmt.Errorf("could not write object %s to %s: %v", key, s.Bucket, err)
+		}
+	}
+	return nil
+}
+
+func (s *S3) contentEncoding() *string {
+	if s.ContentEncoding == "gzip" {
+		return aws.String("gzip")
+	}
+	return nil
+}
+
+func (s *S3) sse() (*string, *string) {
+	var sse, kmsKeyID *string
+	if s.ServerSideEncryption != "" {
+		sse = aws.String(s.ServerSideEncryption)
+	}
+	if s.SSEKMSKeyID != "" {
+		kmsKeyID = aws.String(s.SSEKMSKeyID)
+	}
+	return sse, kmsKeyID
+}
+
+func (s *S3) upload(key string, body []byte) error {
+	sse, kmsKeyID := s.sse()
+	if int64(len(body)) <= s.MultipartSize.Size {
+		_, err := s.client.PutObject(&s3.PutObjectInput{
+			Bucket:               aws.String(s.Bucket),
+			Key:                  aws.String(key),
+			Body:                 bytes.NewReader(body),
+			ContentEncoding:      s.contentEncoding(),
+			ServerSideEncryption: sse,
+			SSEKMSKeyId:          kmsKeyID,
+		})
+		return err
+	}
+
+	upload, err := s.client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(s.Bucket),
+		Key:                  aws.String(key),
+		ContentEncoding:      s.contentEncoding(),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+	})
+	if err != nil {
+		return err
+	}
+
+	var parts []*s3.CompletedPart
+	for n := int64(1); len(body) > 0; n++ {
+		size := int(s.MultipartSize.Size)
+		if size > len(body) {
+			size = len(body)
+		}
+		part, err := s.client.UploadPart(&s3.UploadPartInput{
+			Bucket:     aws.String(s.Bucket),
+			Key:        aws.String(key),
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int64(n),
+			Body:       bytes.NewReader(body[:size]),
+		})
+		if err != nil {
+			s.abort(key, upload.UploadId)
+			return err
+		}
+		parts = append(parts, &s3.CompletedPart{
+			ETag:       part.ETag,
+			PartNumber: aws.Int64(n),
+		})
+		body = body[size:]
+	}
+
+	_, err = s.client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.Bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		s.abort(key, upload.UploadId)
+	}
+	return err
+}
+
+// abort aborts a failed multipart upload so its parts are not kept.
+func (s *S3) abort(key string, uploadID *string) {
+	s.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.Bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+}
+
+func compress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func init() {
+	outputs.Add("s3", func() telegraf.Output {
+		return &S3{}
+	})
+}
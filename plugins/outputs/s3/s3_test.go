@@ -0,0 +1,238 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/plugins/serializers/parquet"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockUpload struct {
+	key   string
+	parts map[int64][]byte
+}
+
+type mockS3 struct {
+	objects map[string][]byte
+	puts    []*s3.PutObjectInput
+	creates []*s3.CreateMultipartUploadInput
+	uploads map[string]*mockUpload
+	aborted []string
+	// failPart fails the upload of the part with this number
+	failPart int64
+}
+
+func newMockS3() *mockS3 {
+	return &mockS3{
+		objects: make(map[string][]byte),
+		uploads: make(map[string]*mockUpload),
+	}
+}
+
+func (m *mockS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.puts = append(m.puts, input)
+	m.objects[aws.StringValue(input.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	id := fmt.Sprintf("upload%d", len(m.creates))
+	m.creates = append(m.creates, input)
+	m.uploads[id] = &mockUpload{key: aws.StringValue(input.Key), parts: make(map[int64][]byte)}
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(id)}, nil
+}
+
+func (m *mockS3) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	if aws.Int64Value(input.PartNumber) == m.failPart {
+		return nil, fmt.Errorf("upload failed")
+	}
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	upload := m.uploads[aws.StringValue(input.UploadId)]
+	upload.parts[aws.Int64Value(input.PartNumber)] = body
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag%d", aws.Int64Value(input.PartNumber)))}, nil
+}
+
+func (m *mockS3) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	upload := m.uploads[aws.StringValue(input.UploadId)]
+	var body []byte
+	for i, part := range input.MultipartUpload.Parts {
+		n := aws.Int64Value(part.PartNumber)
+		if n != int64(i+1) || aws.StringValue(part.ETag) != fmt.Sprintf("etag%d", n) {
+			return nil, fmt.Errorf("invalid part %d", n)
+		}
+		body = append(body, upload.parts[n]...)
+	}
+	m.objects[upload.key] = body
+	delete(m.uploads, aws.StringValue(input.UploadId))
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	m.aborted = append(m.aborted, aws.StringValue(input.Key))
+	delete(m.uploads, aws.StringValue(input.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func newTestS3(t *testing.T, plugin *S3) *mockS3 {
+	client := newMockS3()
+	plugin.Bucket = "bucket"
+	plugin.client = client
+	n := 0
+	plugin.newID = func() string {
+		n++
+		return fmt.Sprintf("id%d", n)
+	}
+	require.NoError(t, plugin.Init())
+	plugin.SetSerializer(influx.NewSerializer())
+	require.NoError(t, plugin.Connect())
+	return client
+}
+
+func testMetric(host string, value int64, ts time.Time) telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{"host": host},
+		map[string]interface{}{"value": value},
+		ts)
+}
+
+func TestWriteKeys(t *testing.T) {
+	plugin := &S3{}
+	client := newTestS3(t, plugin)
+
+	day1 := time.Date(2019, 3, 1, 23, 0, 0, 0, time.UTC)
+	day2 := time.Date(2019, 3, 2, 1, 0, 0, 0, time.FixedZone("", 3*3600))
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testMetric("a", 1, day1),
+		testMetric("b", 2, day1),
+		testMetric("a", 3, day1),
+		testMetric("a", 4, day2),
+	}))
+
+	// day2 is still on March 1st in UTC, the IDs follow the placeholder of
+	// the write
+	require.Equal(t, map[string][]byte{
+		"telegraf/2019-03-01/a/id2": []byte(
+			"cpu,host=a value=1i 1551481200000000000\n" +
+				"cpu,host=a value=3i 1551481200000000000\n" +
+				"cpu,host=a value=4i 1551477600000000000\n"),
+		"telegraf/2019-03-01/b/id3": []byte("cpu,host=b value=2i 1551481200000000000\n"),
+	}, client.objects)
+
+	// the same metrics with new keys
+	require.NoError(t, plugin.Write([]telegraf.Metric{testMetric("b", 2, day1)}))
+	require.Contains(t, client.objects, "telegraf/2019-03-01/b/id5")
+	require.Len(t, client.objects, 3)
+}
+
+func TestWriteKeyTemplate(t *testing.T) {
+	plugin := &S3{Key: `{{ .Name }}/{{ .Time.Format "2006/01/02/15" }}/{{ .Tags.missing }}{{ .ID }}.influx`}
+	client := newTestS3(t, plugin)
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testMetric("a", 1, time.Date(2019, 3, 1, 23, 0, 0, 0, time.UTC)),
+	}))
+	require.Contains(t, client.objects, "cpu/2019/03/01/23/id2.influx")
+}
+
+func TestWriteGzip(t *testing.T) {
+	plugin := &S3{
+		ContentEncoding:      "gzip",
+		ServerSideEncryption: "aws:kms",
+		SSEKMSKeyID:          "key",
+	}
+	client := newTestS3(t, plugin)
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{testMetric("a", 1, time.Unix(0, 0))}))
+	require.Len(t, client.puts, 1)
+	require.Equal(t, "gzip", aws.StringValue(client.puts[0].ContentEncoding))
+	require.Equal(t, "aws:kms", aws.StringValue(client.puts[0].ServerSideEncryption))
+	require.Equal(t, "key", aws.StringValue(client.puts[0].SSEKMSKeyId))
+
+	r, err := gzip.NewReader(bytes.NewReader(client.objects["telegraf/1970-01-01/a/id2"]))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "cpu,host=a value=1i 0\n", string(body))
+}
+
+func TestWriteParquet(t *testing.T) {
+	plugin := &S3{Key: `{{ .Tags.host }}/{{ .ID }}.parquet`}
+	client := newTestS3(t, plugin)
+	serializer := &parquet.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+
+	a := []telegraf.Metric{testMetric("a", 1, time.Unix(0, 0)), testMetric("a", 3, time.Unix(1, 0))}
+	b := []telegraf.Metric{testMetric("b", 2, time.Unix(0, 0))}
+	require.NoError(t, plugin.Write([]telegraf.Metric{a[0], b[0], a[1]}))
+
+	// a Parquet file per object
+	fileA, err := serializer.SerializeBatch(a)
+	require.NoError(t, err)
+	fileB, err := serializer.SerializeBatch(b)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{
+		"a/id2.parquet": fileA,
+		"b/id3.parquet": fileB,
+	}, client.objects)
+}
+
+func TestWriteMultipart(t *testing.T) {
+	plugin := &S3{ServerSideEncryption: "AES256"}
+	client := newTestS3(t, plugin)
+	plugin.MultipartSize.Size = 40
+
+	var metrics []telegraf.Metric
+	var expected string
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, testMetric("a", int64(i), time.Unix(0, 0)))
+		expected += fmt.Sprintf("cpu,host=a value=%di 0\n", i)
+	}
+	require.NoError(t, plugin.Write(metrics[:1]))
+	require.Empty(t, client.creates)
+
+	require.NoError(t, plugin.Write(metrics))
+	require.Len(t, client.creates, 1)
+	require.Equal(t, "AES256", aws.StringValue(client.creates[0].ServerSideEncryption))
+	require.Equal(t, expected, string(client.objects["telegraf/1970-01-01/a/id4"]))
+	require.Empty(t, client.uploads)
+
+	// failed uploads are aborted
+	client.failPart = 2
+	err := plugin.Write(metrics)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "could not write object telegraf/1970-01-01/a/id6 to bucket"))
+	require.Equal(t, []string{"telegraf/1970-01-01/a/id6"}, client.aborted)
+	require.Empty(t, client.uploads)
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, plugin := range []*S3{
+		{},
+		{Bucket: "bucket", Key: "telegraf/{{ .Tags.host }}"},
+		{Bucket: "bucket", Key: "{{ .Unknown"},
+		{Bucket: "bucket", ContentEncoding: "zstd"},
+		{Bucket: "bucket", ServerSideEncryption: "none"},
+		{Bucket: "bucket", SSEKMSKeyID: "key"},
+	} {
+		require.Error(t, plugin.Init())
+	}
+}
@@ -0,0 +1,219 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialect is the SQL syntax of a database.
+type dialect struct {
+	// quote quotes an identifier
+	quote func(string) string
+	// placeholder is the placeholder of the i-th parameter, from 1
+	placeholder func(i int) string
+	// types are the types of the timestamp and tag columns, and of the fields
+	// by Go type
+	types map[string]string
+	// unsignedAsString passes unsigned integers as strings, for databases
+	// without 64 bits unsigned integers
+	unsignedAsString bool
+	// upsert returns the statement inserting a row, or updating the row with
+	// the same conflict columns
+	upsert func(d *dialect, table string, columns, conflict []string) string
+}
+
+var dialects = map[string]*dialect{
+	"pgx": {
+		quote: func(s string) string {
+			return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+		},
+		placeholder: func(i int) string {
+			return "$" + strconv.Itoa(i)
+		},
+		types: map[string]string{
+			"timestamp": "TIMESTAMP",
+			"tag":       "TEXT",
+			"int64":     "BIGINT",
+			"uint64":    "NUMERIC(20,0)",
+			"float64":   "DOUBLE PRECISION",
+			"string":    "TEXT",
+			"bool":      "BOOLEAN",
+		},
+		unsignedAsString: true,
+		upsert: func(d *dialect, table string, columns, conflict []string) string {
+			query := insert(d, "INSERT INTO ", table, columns) +
+				" ON CONFLICT (" + d.quoteAll(conflict) + ")"
+			updated := others(columns, conflict)
+			if len(updated) == 0 {
+				return query + " DO NOTHING"
+			}
+			set := make([]string, len(updated))
+			for i, c := range updated {
+				set[i] = d.quote(c) + "=EXCLUDED." + d.quote(c)
+			}
+			return query + " DO UPDATE SET " + strings.Join(set, ",")
+		},
+	},
+	"mysql": {
+		quote: func(s string) string {
+			return "`" + strings.Replace(s, "`", "``", -1) + "`"
+		},
+		placeholder: func(int) string {
+			return "?"
+		},
+		types: map[string]string{
+			// the tags are part of the keys, which cannot be TEXT columns
+			"timestamp": "DATETIME(6)",
+			"tag":       "VARCHAR(255)",
+			"int64":     "BIGINT",
+			"uint64":    "BIGINT UNSIGNED",
+			"float64":   "DOUBLE",
+			"string":    "TEXT",
+			"bool":      "BOOLEAN",
+		},
+		upsert: func(d *dialect, table string, columns, conflict []string) string {
+			// the conflict columns are those of the duplicated key
+			updated := others(columns, conflict)
+			if len(updated) == 0 {
+				return insert(d, "INSERT IGNORE INTO ", table, columns)
+			}
+			set := make([]string, len(updated))
+			for i, c := range updated {
+				set[i] = d.quote(c) + "=VALUES(" + d.quote(c) + ")"
+			}
+			return insert(d, "INSERT INTO ", table, columns) +
+				" ON DUPLICATE KEY UPDATE " + strings.Join(set, ",")
+		},
+	},
+	"sqlserver": {
+		quote: func(s string) string {
+			return "[" + strings.Replace(s, "]", "]]", -1) + "]"
+		},
+		placeholder: func(i int) string {
+			return "@p" + strconv.Itoa(i)
+		},
+		types: map[string]string{
+			"timestamp": "DATETIME2",
+			"tag":       "NVARCHAR(255)",
+			"int64":     "BIGINT",
+			"uint64":    "DECIMAL(20,0)",
+			"float64":   "FLOAT",
+			"string":    "NVARCHAR(MAX)",
+			"bool":      "BIT",
+		},
+		unsignedAsString: true,
+		upsert: func(d *dialect, table string, columns, conflict []string) string {
+			placeholders := make([]string, len(columns))
+			source := make([]string, len(columns))
+			for i, c := range columns {
+				placeholders[i] = d.placeholder(i + 1)
+				source[i] = "s." + d.quote(c)
+			}
+			on := make([]string, len(conflict))
+			for i, c := range conflict {
+				on[i] = "t." + d.quote(c) + "=s." + d.quote(c)
+			}
+			query := "MERGE INTO " + d.quote(table) + " AS t" +
+				" USING (VALUES (" + strings.Join(placeholders, ",") + "))" +
+				" AS s (" + d.quoteAll(columns) + ")" +
+				" ON " + strings.Join(on, " AND ")
+			if updated := others(columns, conflict); len(updated) > 0 {
+				set := make([]string, len(updated))
+				for i, c := range updated {
+					set[i] = d.quote(c) + "=s." + d.quote(c)
+				}
+				query += " WHEN MATCHED THEN UPDATE SET " + strings.Join(set, ",")
+			}
+			return query + " WHEN NOT MATCHED THEN INSERT (" + d.quoteAll(columns) + ")" +
+				" VALUES (" + strings.Join(source, ",") + ");"
+		},
+	},
+}
+
+func (d *dialect) quoteAll(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = d.quote(c)
+	}
+	return strings.Join(quoted, ",")
+}
+
+// insertStatement returns the statement inserting a row in the table, or
+// upserting it if conflict columns are set.
+func (d *dialect) insertStatement(table string, columns, conflict []string) string {
+	if len(conflict) == 0 {
+		return insert(d, "INSERT INTO ", table, columns)
+	}
+	return d.upsert(d, table, columns, conflict)
+}
+
+// createStatement returns the statement creating the table from the template,
+// the conflict columns are the primary key.
+func (d *dialect) createStatement(template, table string, columns, types, conflict []string) string {
+	definitions := make([]string, len(columns))
+	for i, c := range columns {
+		definitions[i] = d.quote(c) + " " + types[i]
+	}
+	if len(conflict) > 0 {
+		definitions = append(definitions, "PRIMARY KEY ("+d.quoteAll(conflict)+")")
+	}
+	return strings.NewReplacer(
+		"{TABLE}", d.quote(table),
+		"{COLUMNS}", strings.Join(definitions, ","),
+	).Replace(template)
+}
+
+// convert converts a field value to a parameter of a statement.
+func (d *dialect) convert(v interface{}) interface{} {
+	if v, ok := v.(uint64); ok && d.unsignedAsString {
+		return strconv.FormatUint(v, 10)
+	}
+	return v
+}
+
+// columnType returns the type of the column of a field value.
+func (d *dialect) columnType(v interface{}) (string, error) {
+	switch v.(type) {
+	case int64:
+		return d.types["int64"], nil
+	case uint64:
+		return d.types["uint64"], nil
+	case float64:
+		return d.types["float64"], nil
+	case string:
+		return d.types["string"], nil
+	case bool:
+		return d.types["bool"], nil
+	}
+	return "", fmt.Errorf("unsupported field type %T", v)
+}
+
+func insert(d *dialect, verb, table string, columns []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = d.placeholder(i + 1)
+	}
+	return verb + d.quote(table) + "(" + d.quoteAll(columns) + ")" +
+		" VALUES (" + strings.Join(placeholders, ",") + ")"
+}
+
+// others returns the columns that are not conflict columns.
+func others(columns, conflict []string) []string {
+	var others []string
+	for _, c := range columns {
+		if !contains(conflict, c) {
+			others = append(others, c)
+		}
+	}
+	return others
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatements(t *testing.T) {
+	columns := []string{"timestamp", "host", "value"}
+	tests := []struct {
+		driver   string
+		insert   string
+		upsert   string
+		ignore   string
+		create   string
+		unsigned interface{}
+	}{
+		{
+			driver: "pgx",
+			insert: `INSERT INTO "cpu"("timestamp","host","value") VALUES ($1,$2,$3)`,
+			upsert: `INSERT INTO "cpu"("timestamp","host","value") VALUES ($1,$2,$3)` +
+				` ON CONFLICT ("timestamp","host") DO UPDATE SET "value"=EXCLUDED."value"`,
+			ignore: `INSERT INTO "cpu"("timestamp","host","value") VALUES ($1,$2,$3)` +
+				` ON CONFLICT ("timestamp","host","value") DO NOTHING`,
+			create: `CREATE TABLE "cpu"("timestamp" TIMESTAMP,"host" TEXT,"value" NUMERIC(20,0),` +
+				`PRIMARY KEY ("timestamp","host"))`,
+			unsigned: "18446744073709551615",
+		},
+		{
+			driver: "mysql",
+			insert: "INSERT INTO `cpu`(`timestamp`,`host`,`value`) VALUES (?,?,?)",
+			upsert: "INSERT INTO `cpu`(`timestamp`,`host`,`value`) VALUES (?,?,?)" +
+				" ON DUPLICATE KEY UPDATE `value`=VALUES(`value`)",
+			ignore: "INSERT IGNORE INTO `cpu`(`timestamp`,`host`,`value`) VALUES (?,?,?)",
+			create: "CREATE TABLE `cpu`(`timestamp` DATETIME(6),`host` VARCHAR(255),`value` BIGINT UNSIGNED," +
+				"PRIMARY KEY (`timestamp`,`host`))",
+			unsigned: uint64(18446744073709551615),
+		},
+		{
+			driver: "sqlserver",
+			insert: `INSERT INTO [cpu]([timestamp],[host],[value]) VALUES (@p1,@p2,@p3)`,
+			upsert: `MERGE INTO [cpu] AS t USING (VALUES (@p1,@p2,@p3)) AS s ([timestamp],[host],[value])` +
+				` ON t.[timestamp]=s.[timestamp] AND t.[host]=s.[host]` +
+				` WHEN MATCHED THEN UPDATE SET [value]=s.[value]` +
+				` WHEN NOT MATCHED THEN INSERT ([timestamp],[host],[value]) VALUES (s.[timestamp],s.[host],s.[value]);`,
+			ignore: `MERGE INTO [cpu] AS t USING (VALUES (@p1,@p2,@p3)) AS s ([timestamp],[host],[value])` +
+				` ON t.[timestamp]=s.[timestamp] AND t.[host]=s.[host] AND t.[value]=s.[value]` +
+				` WHEN NOT MATCHED THEN INSERT ([timestamp],[host],[value]) VALUES (s.[timestamp],s.[host],s.[value]);`,
+			create: `CREATE TABLE [cpu]([timestamp] DATETIME2,[host] NVARCHAR(255),[value] DECIMAL(20,0),` +
+				`PRIMARY KEY ([timestamp],[host]))`,
+			unsigned: "18446744073709551615",
+		},
+	}
+
+	for _, tt := range tests {
+		d := dialects[tt.driver]
+		require.Equal(t, tt.insert, d.insertStatement("cpu", columns, nil), tt.driver)
+		require.Equal(t, tt.upsert, d.insertStatement("cpu", columns, columns[:2]), tt.driver)
+		require.Equal(t, tt.ignore, d.insertStatement("cpu", columns, columns), tt.driver)
+
+		valueType, err := d.columnType(uint64(1))
+		require.NoError(t, err)
+		types := []string{d.types["timestamp"], d.types["tag"], valueType}
+		require.Equal(t, tt.create, d.createStatement(defaultTableTemplate, "cpu", columns, types, columns[:2]), tt.driver)
+		require.Equal(t, tt.unsigned, d.convert(uint64(18446744073709551615)), tt.driver)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	require.Equal(t, `"a""b"`, dialects["pgx"].quote(`a"b`))
+	require.Equal(t, "`a``b`", dialects["mysql"].quote("a`b"))
+	require.Equal(t, "[a]]b]", dialects["sqlserver"].quote("a]b"))
+}
+
+// fakeDB records the statements executed through the fake driver.
+type fakeDB struct {
+	mu sync.Mutex
+	// tables are the names of the existing tables
+	tables map[string]bool
+	// execs are the statements executed, with their arguments
+	execs     []string
+	args      [][]driver.Value
+	commits   int
+	rollbacks int
+	failExec  bool
+}
+
+func (db *fakeDB) exec(query string, args []driver.Value) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.failExec {
+		return errors.New("connection lost")
+	}
+	if strings.HasPrefix(query, "SELECT 1 FROM ") {
+		if !db.tables[strings.Fields(query)[3]] {
+			return errors.New("no such table")
+		}
+		return nil
+	}
+	if strings.HasPrefix(query, "CREATE TABLE ") {
+		db.tables[strings.SplitN(query[len("CREATE TABLE "):], "(", 2)[0]] = true
+	}
+	db.execs = append(db.execs, query)
+	db.args = append(db.args, args)
+	return nil
+}
+
+type fakeDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+var fake = &fakeDriver{dbs: make(map[string]*fakeDB)}
+
+func init() {
+	gosql.Register("fakesql", fake)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &fakeConn{db: d.dbs[name]}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{db: c.db}, nil }
+
+// CheckNamedValue passes the values as they are, like the drivers
+// supporting more types than database/sql.
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.db.exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+func newTestSQL(t *testing.T, plugin *SQL) *fakeDB {
+	plugin.DataSourceName = t.Name()
+	require.NoError(t, plugin.Init())
+
+	db := &fakeDB{tables: make(map[string]bool)}
+	fake.mu.Lock()
+	fake.dbs[t.Name()] = db
+	fake.mu.Unlock()
+
+	var err error
+	plugin.db, err = gosql.Open("fakesql", t.Name())
+	require.NoError(t, err)
+	plugin.tables = make(map[string]bool)
+	return db
+}
+
+func testMetric(host string, value uint64, ts int64) telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{"host": host},
+		map[string]interface{}{"value": value, "ok": true},
+		time.Unix(ts, 0))
+}
+
+func TestWriteBatches(t *testing.T) {
+	plugin := &SQL{Driver: "pgx", ConflictColumns: []string{"timestamp", "host"}, BatchSize: 2}
+	db := newTestSQL(t, plugin)
+	defer plugin.Close()
+
+	mem := testutil.MustMetric("mem",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"used": int64(10)},
+		time.Unix(0, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testMetric("a", 1, 0),
+		mem,
+		testMetric("b", 2, 0),
+		testMetric("a", 3, 10),
+	}))
+
+	insertCPU := `INSERT INTO "cpu"("timestamp","host","ok","value") VALUES ($1,$2,$3,$4)` +
+		` ON CONFLICT ("timestamp","host") DO UPDATE SET "ok"=EXCLUDED."ok","value"=EXCLUDED."value"`
+	insertMem := `INSERT INTO "mem"("timestamp","host","used") VALUES ($1,$2,$3)` +
+		` ON CONFLICT ("timestamp","host") DO UPDATE SET "used"=EXCLUDED."used"`
+	require.Equal(t, []string{
+		`CREATE TABLE "cpu"("timestamp" TIMESTAMP,"host" TEXT,"ok" BOOLEAN,"value" NUMERIC(20,0),` +
+			`PRIMARY KEY ("timestamp","host"))`,
+		insertCPU,
+		insertCPU,
+		insertCPU,
+		`CREATE TABLE "mem"("timestamp" TIMESTAMP,"host" TEXT,"used" BIGINT,PRIMARY KEY ("timestamp","host"))`,
+		insertMem,
+	}, db.execs)
+	require.Equal(t, []driver.Value{time.Unix(10, 0).UTC(), "a", true, "3"}, db.args[3])
+	require.Equal(t, []driver.Value{time.Unix(0, 0).UTC(), "a", int64(10)}, db.args[5])
+	// two transactions for the three rows of cpu
+	require.Equal(t, 3, db.commits)
+
+	// tables are only created once, and not if they already exist
+	db.execs = nil
+	require.NoError(t, plugin.Write([]telegraf.Metric{testMetric("a", 1, 0)}))
+	require.Equal(t, []string{insertCPU}, db.execs)
+
+	other := &SQL{Driver: "pgx", DataSourceName: t.Name(), ConflictColumns: []string{"timestamp", "host"}}
+	require.NoError(t, other.Init())
+	other.db = plugin.db
+	other.tables = make(map[string]bool)
+	db.execs = nil
+	require.NoError(t, other.Write([]telegraf.Metric{testMetric("a", 1, 0)}))
+	require.Equal(t, []string{insertCPU}, db.execs)
+}
+
+func TestWriteWithoutConflictColumns(t *testing.T) {
+	plugin := &SQL{Driver: "mysql", TableTemplate: "CREATE TABLE IF NOT EXISTS {TABLE} ({COLUMNS}) ENGINE=InnoDB"}
+	db := newTestSQL(t, plugin)
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{testMetric("a", 18446744073709551615, 0)}))
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS `cpu` (`timestamp` DATETIME(6),`host` VARCHAR(255),`ok` BOOLEAN,`value` BIGINT UNSIGNED) ENGINE=InnoDB",
+		"INSERT INTO `cpu`(`timestamp`,`host`,`ok`,`value`) VALUES (?,?,?,?)",
+	}, db.execs)
+	require.Equal(t, []driver.Value{time.Unix(0, 0).UTC(), "a", true, uint64(18446744073709551615)}, db.args[1])
+}
+
+func TestWriteMissingConflictColumn(t *testing.T) {
+	plugin := &SQL{Driver: "pgx", ConflictColumns: []string{"timestamp", "region"}}
+	db := newTestSQL(t, plugin)
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{testMetric("a", 1, 0)}))
+	require.Empty(t, db.execs)
+}
+
+func TestWriteError(t *testing.T) {
+	plugin := &SQL{Driver: "sqlserver"}
+	db := newTestSQL(t, plugin)
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{testMetric("a", 1, 0)}))
+	require.True(t, plugin.tables["cpu"])
+
+	db.failExec = true
+	err := plugin.Write([]telegraf.Metric{testMetric("a", 1, 0)})
+	require.EqualError(t, err, "could not write to table cpu: connection lost")
+	require.Equal(t, 1, db.rollbacks)
+	require.False(t, plugin.tables["cpu"])
+}
+
+func TestInitErrors(t *testing.T) {
+	require.Error(t, (&SQL{Driver: "sqlite", DataSourceName: "file"}).Init())
+	require.Error(t, (&SQL{Driver: "pgx"}).Init())
+}
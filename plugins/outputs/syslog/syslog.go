@@ -0,0 +1,303 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	framingOctetCounting  = "octet-counting"
+	framingNonTransparent = "non-transparent"
+)
+
+// Syslog is a syslog output, writing the metrics as RFC5424 or RFC3164
+// messages with the transports expected by the syslog input.
+type Syslog struct {
+	Address             string             `toml:"address"`
+	KeepAlivePeriod     *internal.Duration `toml:"keep_alive_period"`
+	Format              string             `toml:"format"`
+	Framing             string             `toml:"framing"`
+	Trailer             string             `toml:"trailer"`
+	Separator           string             `toml:"sdparam_separator"`
+	SDIDs               []string           `toml:"sdids"`
+	DefaultSDID         string             `toml:"default_sdid"`
+	DefaultSeverityCode uint8              `toml:"default_severity_code"`
+	DefaultFacilityCode uint8              `toml:"default_facility_code"`
+	DefaultAppname      string             `toml:"default_appname"`
+	MessageTemplate     string             `toml:"message_template"`
+	tlsint.ClientConfig
+
+	net.Conn
+	mapper   *mapper
+	isStream bool
+	trailer  []byte
+}
+
+var sampleConfig = `
+  ## URL to connect to
+  ## ex: address = "tcp://127.0.0.1:6514"
+  ## ex: address = "udp://127.0.0.1:514"
+  ## TLS is used for the tcp addresses when the TLS options are set.
+  address = "tcp://127.0.0.1:6514"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Period between keep alive probes.
+  ## Only applies to TCP sockets.
+  ## 0 disables keep alive probes.
+  ## Defaults to the OS configuration.
+  # keep_alive_period = "5m"
+
+  ## The format of the messages, "rfc5424" or "rfc3164".  The RFC3164
+  ## messages have no structured data.
+  # format = "rfc5424"
+
+  ## The framing technique with which messages are transported, must be one
+  ## of "octet-counting" (RFC5425#section-4.3.1, RFC6587#section-3.4.1) or
+  ## "non-transparent" (RFC6587#section-3.4.2).  Only applies to stream
+  ## sockets, the datagrams hold a message each.
+  # framing = "octet-counting"
+
+  ## The trailer of the messages in case of non-transparent framing, "LF"
+  ## or "NUL".
+  # trailer = "LF"
+
+  ## SD-PARAMs settings
+  ## Syslog messages can contain key/value pairs within zero or more
+  ## structured data sections.  For each unrecognized metric tag/field an
+  ## SD-PARAM is created.
+  ##
+  ## Example:
+  ##   [[outputs.syslog]]
+  ##     sdparam_separator = "_"
+  ##     default_sdid = "default@32473"
+  ##     sdids = ["foo@123", "bar@456"]
+  ##
+  ##   input => xyzzy,x=y foo@123_value=42,bar@456_value2=84,something_else=1
+  ##   output (structured data only) => [bar@456 value2="84"][default@32473 something_else="1" x="y"][foo@123 value="42"]
+
+  ## SD-PARAMs separator between the sdid and tag/field key (default = "_")
+  # sdparam_separator = "_"
+
+  ## Default sdid used for tags/fields that don't contain a prefix defined in
+  ## the explicit sdids setting below.  If no default is specified, no SD-PARAMs
+  ## are created for those tags/fields.
+  # default_sdid = "default@32473"
+
+  ## List of explicit prefixes to extract from tag/field keys and use as the
+  ## SDID, if they match (see above example for more details):
+  # sdids = ["foo@123", "bar@456"]
+
+  ## The severity and facility of the messages are those of the
+  ## severity_code and facility_code fields, or else of the severity and
+  ## facility tags, like "warning" and "daemon", or else these defaults.
+  # default_severity_code = 5
+  # default_facility_code = 1
+
+  ## The appname of the messages is the appname tag, or else this default.
+  # default_appname = "Telegraf"
+
+  ## The message body is the message field, or else the output of this
+  ## template if set.  The template is a Go template with the Name, Tags and
+  ## Fields of the metric.
+  # message_template = "{{.Name}} value={{.Fields.value}}"
+`
+
+func (s *Syslog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Syslog) Description() string {
+	return "Configuration for Syslog server to send metrics to"
+}
+
+// Init checks the format and framing and compiles the message template.
+func (s *Syslog) Init() error {
+	spl := strings.SplitN(s.Address, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid address: %s", s.Address)
+	}
+	switch spl[0] {
+	case "tcp", "tcp4", "tcp6":
+		s.isStream = true
+	case "udp", "udp4", "udp6":
+		s.isStream = false
+	default:
+		return fmt.Errorf("unknown protocol '%s' in '%s'", spl[0], s.Address)
+	}
+
+	s.Format = strings.ToLower(s.Format)
+	switch s.Format {
+	case "":
+		s.Format = formatRFC5424
+	case formatRFC5424, formatRFC3164:
+	default:
+		return fmt.Errorf("invalid format %q", s.Format)
+	}
+
+	s.Framing = strings.ToLower(s.Framing)
+	switch s.Framing {
+	case "":
+		s.Framing = framingOctetCounting
+	case framingOctetCounting, framingNonTransparent:
+	default:
+		return fmt.Errorf("invalid framing %q", s.Framing)
+	}
+
+	switch strings.ToUpper(s.Trailer) {
+	case "", "LF":
+		s.trailer = []byte{'\n'}
+	case "NUL":
+		s.trailer = []byte{0}
+	default:
+		return fmt.Errorf("invalid trailer %q", s.Trailer)
+	}
+
+	if s.DefaultSeverityCode > 7 {
+		return fmt.Errorf("invalid default_severity_code %d", s.DefaultSeverityCode)
+	}
+	if s.DefaultFacilityCode > 23 {
+		return fmt.Errorf("invalid default_facility_code %d", s.DefaultFacilityCode)
+	}
+
+	s.mapper.Format = s.Format
+	s.mapper.Separator = s.Separator
+	s.mapper.SDIDs = s.SDIDs
+	s.mapper.DefaultSDID = s.DefaultSDID
+	s.mapper.DefaultSeverityCode = s.DefaultSeverityCode
+	s.mapper.DefaultFacilityCode = s.DefaultFacilityCode
+	s.mapper.DefaultAppname = s.DefaultAppname
+	if s.MessageTemplate != "" {
+		tmpl, err := template.New("message").Option("missingkey=zero").Parse(s.MessageTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid message_template: %v", err)
+		}
+		s.mapper.Message = tmpl
+	}
+	return nil
+}
+
+func (s *Syslog) Connect() error {
+	spl := strings.SplitN(s.Address, "://", 2)
+
+	tlsCfg, err := s.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	var c net.Conn
+	if tlsCfg == nil || !s.isStream {
+		c, err = net.Dial(spl[0], spl[1])
+	} else {
+		c, err = tls.Dial(spl[0], spl[1], tlsCfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.setKeepAlive(c); err != nil {
+		log.Printf("W! [outputs.syslog] unable to configure keep alive (%s): %s", s.Address, err)
+	}
+
+	s.Conn = c
+	return nil
+}
+
+func (s *Syslog) setKeepAlive(c net.Conn) error {
+	if s.KeepAlivePeriod == nil {
+		return nil
+	}
+	tcpc, ok := c.(*net.TCPConn)
+	if !ok {
+		return fmt.Errorf("cannot set keep alive on a %s socket", strings.SplitN(s.Address, "://", 2)[0])
+	}
+	if s.KeepAlivePeriod.Duration == 0 {
+		return tcpc.SetKeepAlive(false)
+	}
+	if err := tcpc.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return tcpc.SetKeepAlivePeriod(s.KeepAlivePeriod.Duration)
+}
+
+// Write writes the metrics as syslog messages, a message per metric.  The
+// metrics whose message can't be formatted are dropped.
+func (s *Syslog) Write(metrics []telegraf.Metric) error {
+	if s.Conn == nil {
+		// previous write failed with permanent error and socket was closed.
+		if err := s.Connect(); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range metrics {
+		msg, err := s.mapper.Map(m)
+		if err != nil {
+			log.Printf("E! [outputs.syslog] could not format metric %s: %v", m.Name(), err)
+			continue
+		}
+		if _, err := s.Conn.Write(s.frame(msg)); err != nil {
+			if err, ok := err.(net.Error); !ok || !err.Temporary() {
+				// permanent error. close the connection
+				s.Close()
+				return fmt.Errorf("closing connection: %v", err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// frame returns the message framed for the stream sockets.
+func (s *Syslog) frame(msg []byte) []byte {
+	if !s.isStream {
+		return msg
+	}
+	if s.Framing == framingNonTransparent {
+		return append(msg, s.trailer...)
+	}
+	return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+}
+
+// Close closes the connection. Noop if already closed.
+func (s *Syslog) Close() error {
+	if s.Conn == nil {
+		return nil
+	}
+	err := s.Conn.Close()
+	s.Conn = nil
+	return err
+}
+
+func newSyslog() *Syslog {
+	return &Syslog{
+		Format:              formatRFC5424,
+		Framing:             framingOctetCounting,
+		Trailer:             "LF",
+		Separator:           "_",
+		DefaultSeverityCode: 5, // notice
+		DefaultFacilityCode: 1, // user-level
+		DefaultAppname:      "Telegraf",
+		mapper:              newMapper(),
+	}
+}
+
+func init() {
+	outputs.Add("syslog", func() telegraf.Output { return newSyslog() })
+}
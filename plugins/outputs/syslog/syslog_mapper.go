@@ -0,0 +1,384 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	formatRFC5424 = "rfc5424"
+	formatRFC3164 = "rfc3164"
+
+	nilValue = "-"
+
+	// rfc5424Time is RFC3339 with at most 6 digits of fractional seconds.
+	rfc5424Time = "2006-01-02T15:04:05.999999Z07:00"
+)
+
+// The severities by their short level, as tagged by the syslog input.
+var severities = map[string]uint8{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// The facilities by their keyword, and by their level as tagged by the
+// syslog input.
+var facilities = map[string]uint8{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"ntp":      12,
+	"security": 13,
+	"console":  14,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+
+	"kernel messages":                          0,
+	"user-level messages":                      1,
+	"mail system":                              2,
+	"system daemons":                           3,
+	"security/authorization messages":          4,
+	"messages generated internally by syslogd": 5,
+	"line printer subsystem":                   6,
+	"network news subsystem":                   7,
+	"UUCP subsystem":                           8,
+	"clock daemon":                             9,
+	"FTP daemon":                               11,
+	"NTP subsystem":                            12,
+	"log audit":                                13,
+	"log alert":                                14,
+	"local use 0 (local0)":                     16,
+	"local use 1 (local1)":                     17,
+	"local use 2 (local2)":                     18,
+	"local use 3 (local3)":                     19,
+	"local use 4 (local4)":                     20,
+	"local use 5 (local5)":                     21,
+	"local use 6 (local6)":                     22,
+	"local use 7 (local7)":                     23,
+}
+
+// The tags and fields of the header of the messages, as added by the syslog
+// input, not added to the structured data.
+var (
+	headerTags   = []string{"severity", "facility", "hostname", "appname"}
+	headerFields = []string{"version", "severity_code", "facility_code", "timestamp", "procid", "msgid", "message"}
+)
+
+// mapper formats the metrics as syslog messages.
+type mapper struct {
+	Format              string
+	Separator           string
+	SDIDs               []string
+	DefaultSDID         string
+	DefaultSeverityCode uint8
+	DefaultFacilityCode uint8
+	DefaultAppname      string
+	Message             *template.Template
+
+	hostname string
+}
+
+// messageData is the data of the message template.
+type messageData struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+}
+
+func newMapper() *mapper {
+	hostname, _ := os.Hostname()
+	return &mapper{hostname: hostname}
+}
+
+// Map returns the syslog message of the metric, without framing.
+func (sm *mapper) Map(m telegraf.Metric) ([]byte, error) {
+	msg, err := sm.message(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	pri := int(sm.facility(m))*8 + int(sm.severity(m))
+	timestamp := m.Time()
+	if v, ok := m.GetField("timestamp"); ok {
+		if ns, ok := v.(int64); ok {
+			timestamp = time.Unix(0, ns)
+		}
+	}
+
+	if sm.Format == formatRFC3164 {
+		// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+		fmt.Fprintf(&buf, "<%d>%s %s %s", pri, timestamp.Format(time.Stamp),
+			headerValue(sm.hostnameOf(m), 255), headerValue(sm.appname(m), 32))
+		if procid := sm.stringField(m, "procid"); procid != "" {
+			fmt.Fprintf(&buf, "[%s]", headerValue(procid, 128))
+		}
+		buf.WriteString(": ")
+		buf.WriteString(msg)
+		return buf.Bytes(), nil
+	}
+
+	// <PRI>VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP
+	// STRUCTURED-DATA [SP MSG]
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %s %s ", pri,
+		timestamp.UTC().Format(rfc5424Time),
+		headerValue(sm.hostnameOf(m), 255),
+		headerValue(sm.appname(m), 48),
+		headerValue(sm.stringField(m, "procid"), 128),
+		headerValue(sm.msgid(m), 32))
+	sm.writeStructuredData(&buf, m)
+	if msg != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(msg)
+	}
+	return buf.Bytes(), nil
+}
+
+func (sm *mapper) message(m telegraf.Metric) (string, error) {
+	if sm.Message == nil {
+		return sm.stringField(m, "message"), nil
+	}
+	var buf bytes.Buffer
+	err := sm.Message.Execute(&buf, messageData{Name: m.Name(), Tags: m.Tags(), Fields: m.Fields()})
+	if err != nil {
+		return "", fmt.Errorf("executing message template: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// severity returns the severity of the severity_code field, or else of the
+// severity tag.
+func (sm *mapper) severity(m telegraf.Metric) uint8 {
+	if code, ok := codeField(m, "severity_code"); ok && code < 8 {
+		return code
+	}
+	if tag, ok := m.GetTag("severity"); ok {
+		if code, ok := severities[tag]; ok {
+			return code
+		}
+	}
+	return sm.DefaultSeverityCode
+}
+
+// facility returns the facility of the facility_code field, or else of the
+// facility tag.
+func (sm *mapper) facility(m telegraf.Metric) uint8 {
+	if code, ok := codeField(m, "facility_code"); ok && code < 24 {
+		return code
+	}
+	if tag, ok := m.GetTag("facility"); ok {
+		if code, ok := facilities[tag]; ok {
+			return code
+		}
+	}
+	return sm.DefaultFacilityCode
+}
+
+func codeField(m telegraf.Metric, key string) (uint8, bool) {
+	v, ok := m.GetField(key)
+	if !ok {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case int64:
+		return uint8(v), v >= 0 && v < 256
+	case uint64:
+		return uint8(v), v < 256
+	}
+	return 0, false
+}
+
+func (sm *mapper) hostnameOf(m telegraf.Metric) string {
+	for _, key := range []string{"hostname", "source", "host"} {
+		if tag, ok := m.GetTag(key); ok {
+			return tag
+		}
+	}
+	return sm.hostname
+}
+
+func (sm *mapper) appname(m telegraf.Metric) string {
+	if tag, ok := m.GetTag("appname"); ok {
+		return tag
+	}
+	return sm.DefaultAppname
+}
+
+func (sm *mapper) msgid(m telegraf.Metric) string {
+	if msgid := sm.stringField(m, "msgid"); msgid != "" {
+		return msgid
+	}
+	return m.Name()
+}
+
+func (sm *mapper) stringField(m telegraf.Metric, key string) string {
+	v, ok := m.GetField(key)
+	if !ok {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// writeStructuredData writes the SD-ELEMENTs of the metric.  The tags and
+// fields named after an SD-ID of SDIDs and the separator are the SD-PARAMs of
+// its element, the others the SD-PARAMs of the element of DefaultSDID if set.
+// The boolean true fields named after an SD-ID are its elements without
+// parameters, as added by the syslog input.
+func (sm *mapper) writeStructuredData(buf *bytes.Buffer, m telegraf.Metric) {
+	elements := make(map[string]map[string]string)
+	add := func(key, value string) {
+		for _, sdid := range sm.SDIDs {
+			if key == sdid {
+				if _, ok := elements[sdid]; !ok {
+					elements[sdid] = make(map[string]string)
+				}
+				return
+			}
+			if name := strings.TrimPrefix(key, sdid+sm.Separator); name != key && name != "" {
+				if _, ok := elements[sdid]; !ok {
+					elements[sdid] = make(map[string]string)
+				}
+				elements[sdid][name] = value
+				return
+			}
+		}
+		if sm.DefaultSDID != "" {
+			if _, ok := elements[sm.DefaultSDID]; !ok {
+				elements[sm.DefaultSDID] = make(map[string]string)
+			}
+			elements[sm.DefaultSDID][key] = value
+		}
+	}
+
+	for _, tag := range m.TagList() {
+		if !contains(headerTags, tag.Key) {
+			add(tag.Key, tag.Value)
+		}
+	}
+	for _, field := range m.FieldList() {
+		if contains(headerFields, field.Key) {
+			continue
+		}
+		if b, ok := field.Value.(bool); ok && b && contains(sm.SDIDs, field.Key) {
+			add(field.Key, "")
+			continue
+		}
+		add(field.Key, formatValue(field.Value))
+	}
+
+	if len(elements) == 0 {
+		buf.WriteString(nilValue)
+		return
+	}
+
+	sdids := make([]string, 0, len(elements))
+	for sdid := range elements {
+		sdids = append(sdids, sdid)
+	}
+	sort.Strings(sdids)
+	for _, sdid := range sdids {
+		buf.WriteByte('[')
+		buf.WriteString(sdName(sdid))
+		names := make([]string, 0, len(elements[sdid]))
+		for name := range elements[sdid] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(buf, ` %s="%s"`, sdName(name), escapeParamValue(elements[sdid][name]))
+		}
+		buf.WriteByte(']')
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// headerValue returns the header field, made of printable US-ASCII characters
+// and at most max long, or the NILVALUE if empty.
+func headerValue(s string, max int) string {
+	s = printable(s, nil)
+	if s == "" {
+		return nilValue
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+// sdName returns the SD-ID or PARAM-NAME, made of printable US-ASCII
+// characters but '=', ']' and '"' and at most 32 characters long.
+func sdName(s string) string {
+	s = printable(s, func(c byte) bool { return c == '=' || c == ']' || c == '"' })
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+func printable(s string, exclude func(byte) bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c > 32 && c < 127 && (exclude == nil || !exclude(c)) {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// escapeParamValue escapes the '"', '\' and ']' characters of a PARAM-VALUE.
+func escapeParamValue(s string) string {
+	return paramValueEscaper.Replace(s)
+}
+
+var paramValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
@@ -0,0 +1,121 @@
+package syslog
+
+import (
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newTestMapper() *mapper {
+	return &mapper{
+		Format:              formatRFC5424,
+		Separator:           "_",
+		DefaultSeverityCode: 5,
+		DefaultFacilityCode: 1,
+		DefaultAppname:      "Telegraf",
+		hostname:            "localhost",
+	}
+}
+
+func TestMapHeader(t *testing.T) {
+	sm := newTestMapper()
+	m := testutil.MustMetric("syslog",
+		map[string]string{
+			"severity": "warning",
+			"facility": "daemon",
+			"hostname": "web01",
+			"appname":  "nginx",
+		},
+		map[string]interface{}{
+			"version":   uint64(1),
+			"timestamp": time.Date(2019, 1, 2, 3, 4, 5, 6000, time.UTC).UnixNano(),
+			"procid":    "1234",
+			"msgid":     "access",
+			"message":   "GET / 200",
+		},
+		time.Unix(0, 0))
+
+	msg, err := sm.Map(m)
+	require.NoError(t, err)
+	require.Equal(t, "<28>1 2019-01-02T03:04:05.000006Z web01 nginx 1234 access - GET / 200", string(msg))
+
+	// the code fields take precedence over the tags
+	m.AddField("severity_code", int64(3))
+	m.AddField("facility_code", int64(16))
+	msg, err = sm.Map(m)
+	require.NoError(t, err)
+	require.Equal(t, "<131>1 2019-01-02T03:04:05.000006Z web01 nginx 1234 access - GET / 200", string(msg))
+}
+
+func TestMapDefaults(t *testing.T) {
+	sm := newTestMapper()
+	m := testutil.MustMetric("cpu",
+		map[string]string{"severity": "unknown"},
+		map[string]interface{}{"usage_idle": 98.5},
+		time.Unix(1546300800, 0))
+
+	msg, err := sm.Map(m)
+	require.NoError(t, err)
+	require.Equal(t, "<13>1 2019-01-01T00:00:00Z localhost Telegraf - cpu -", string(msg))
+}
+
+func TestMapStructuredData(t *testing.T) {
+	sm := newTestMapper()
+	sm.SDIDs = []string{"foo@123", "bar@456"}
+	sm.DefaultSDID = "default@32473"
+	m := testutil.MustMetric("xyzzy",
+		map[string]string{"x": "y", "hostname": "web01"},
+		map[string]interface{}{
+			"foo@123_value":  int64(42),
+			"bar@456_value2": `a "quoted" \ value]`,
+			"bar@456":        true,
+			"something_else": 1.5,
+			"message":        "hello",
+		},
+		time.Unix(1546300800, 0))
+
+	msg, err := sm.Map(m)
+	require.NoError(t, err)
+	require.Equal(t, `<13>1 2019-01-01T00:00:00Z web01 Telegraf - xyzzy `+
+		`[bar@456 value2="a \"quoted\" \\ value\]"]`+
+		`[default@32473 something_else="1.5" x="y"]`+
+		`[foo@123 value="42"] hello`, string(msg))
+
+	// without default SD-ID, the other tags and fields are not added
+	sm.DefaultSDID = ""
+	msg, err = sm.Map(m)
+	require.NoError(t, err)
+	require.Equal(t, `<13>1 2019-01-01T00:00:00Z web01 Telegraf - xyzzy `+
+		`[bar@456 value2="a \"quoted\" \\ value\]"]`+
+		`[foo@123 value="42"] hello`, string(msg))
+}
+
+func TestMapSanitizeHeader(t *testing.T) {
+	sm := newTestMapper()
+	m := testutil.MustMetric("a metric",
+		map[string]string{"hostname": "web 01", "appname": "a very long application name exceeding the limit"},
+		map[string]interface{}{"msgid": ""},
+		time.Unix(1546300800, 0))
+
+	msg, err := sm.Map(m)
+	require.NoError(t, err)
+	require.Equal(t, "<13>1 2019-01-01T00:00:00Z web01 averylongapplicationnameexceedingthelimit - ametric -", string(msg))
+}
+
+func TestMapRFC3164(t *testing.T) {
+	sm := newTestMapper()
+	sm.Format = formatRFC3164
+	sm.Message = template.Must(template.New("message").Parse("{{.Name}} idle={{.Fields.usage_idle}} cpu={{.Tags.cpu}}"))
+	m := testutil.MustMetric("cpu",
+		map[string]string{"cpu": "cpu0", "host": "web01", "severity": "err"},
+		map[string]interface{}{"usage_idle": 98.5, "procid": int64(42)},
+		time.Date(2019, 1, 2, 3, 4, 5, 0, time.Local))
+
+	msg, err := sm.Map(m)
+	require.NoError(t, err)
+	require.Equal(t, "<11>Jan  2 03:04:05 web01 Telegraf[42]: cpu idle=98.5 cpu=cpu0", string(msg))
+}
@@ -0,0 +1,131 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"hostname": "web01"},
+			map[string]interface{}{"message": "first"},
+			time.Unix(1546300800, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{"hostname": "web01"},
+			map[string]interface{}{"message": "second\nline"},
+			time.Unix(1546300800, 0)),
+	}
+}
+
+func newTestSyslog(t *testing.T, s *Syslog) *Syslog {
+	s.mapper.hostname = "localhost"
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	return s
+}
+
+func TestWriteOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := newSyslog()
+	s.Address = "tcp://" + listener.Addr().String()
+	newTestSyslog(t, s)
+	defer s.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{
+		"<13>1 2019-01-01T00:00:00Z web01 Telegraf - cpu - first",
+		"<13>1 2019-01-01T00:00:00Z web01 Telegraf - cpu - second\nline",
+	} {
+		length, err := reader.ReadString(' ')
+		require.NoError(t, err)
+		n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		require.NoError(t, err)
+		msg := make([]byte, n)
+		_, err = io.ReadFull(reader, msg)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(msg))
+	}
+}
+
+func TestWriteNonTransparent(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := newSyslog()
+	s.Address = "tcp://" + listener.Addr().String()
+	s.Framing = framingNonTransparent
+	s.Trailer = "NUL"
+	newTestSyslog(t, s)
+	defer s.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{
+		"<13>1 2019-01-01T00:00:00Z web01 Telegraf - cpu - first\x00",
+		"<13>1 2019-01-01T00:00:00Z web01 Telegraf - cpu - second\nline\x00",
+	} {
+		msg, err := reader.ReadString(0)
+		require.NoError(t, err)
+		require.Equal(t, expected, msg)
+	}
+}
+
+func TestWriteUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s := newSyslog()
+	s.Address = "udp://" + conn.LocalAddr().String()
+	newTestSyslog(t, s)
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMetrics()[:1]))
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, "<13>1 2019-01-01T00:00:00Z web01 Telegraf - cpu - first", string(buf[:n]))
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, s := range []*Syslog{
+		{Address: "127.0.0.1:6514"},
+		{Address: "unix:///tmp/syslog.sock"},
+		{Address: "tcp://127.0.0.1:6514", Format: "rfc3339"},
+		{Address: "tcp://127.0.0.1:6514", Framing: "lf"},
+		{Address: "tcp://127.0.0.1:6514", Trailer: "CRLF"},
+		{Address: "tcp://127.0.0.1:6514", DefaultSeverityCode: 8},
+		{Address: "tcp://127.0.0.1:6514", MessageTemplate: "{{.Name"},
+	} {
+		s.mapper = newMapper()
+		require.Error(t, s.Init(), s.Address)
+	}
+}
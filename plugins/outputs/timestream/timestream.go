@@ -0,0 +1,442 @@
+package timestream
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/timestreamwrite"
+
+	"github.com/influxdata/telegraf"
+	internalaws "github.com/influxdata/telegraf/internal/config/aws"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	// maxRecordsPerRequest is the maximum number of records of a WriteRecords
+	// request.
+	maxRecordsPerRequest = 100
+
+	mappingModeMultiTable  = "multi-table"
+	mappingModeSingleTable = "single-table"
+)
+
+type Timestream struct {
+	Region      string `toml:"region"`
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	Token       string `toml:"token"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	DatabaseName string `toml:"database_name"`
+
+	MappingMode                     string `toml:"mapping_mode"`
+	SingleTableName                 string `toml:"single_table_name"`
+	SingleTableMeasurementDimension string `toml:"single_table_measurement_dimension"`
+
+	UseMultiMeasureRecords bool   `toml:"use_multi_measure_records"`
+	MultiMeasureName       string `toml:"multi_measure_name"`
+
+	CreateDatabaseIfNotExists  bool  `toml:"create_database_if_not_exists"`
+	CreateTableIfNotExists     bool  `toml:"create_table_if_not_exists"`
+	MagneticStoreRetentionDays int64 `toml:"create_table_magnetic_store_retention_period_in_days"`
+	MemoryStoreRetentionHours  int64 `toml:"create_table_memory_store_retention_period_in_hours"`
+
+	client timestreamClient
+	// version is the version of the last records written, the records of a
+	// write have a higher version so that they replace the records written
+	// before with the same dimensions, time and measure name.
+	version int64
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+}
+
+type timestreamClient interface {
+	WriteRecords(*timestreamwrite.WriteRecordsInput) (*timestreamwrite.WriteRecordsOutput, error)
+	CreateDatabase(*timestreamwrite.CreateDatabaseInput) (*timestreamwrite.CreateDatabaseOutput, error)
+	CreateTable(*timestreamwrite.CreateTableInput) (*timestreamwrite.CreateTableOutput, error)
+}
+
+var sampleConfig = `
+  ## Amazon Region
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:8000"
+  # endpoint_url = ""
+
+  ## Timestream database the metrics are written to.
+  database_name = "telegraf"
+
+  ## How the metrics are mapped to tables:
+  ##   "multi-table":  each measurement is written to the table of its name.
+  ##   "single-table": all the metrics are written to single_table_name, with
+  ##                   their measurement name in the dimension
+  ##                   single_table_measurement_dimension.
+  # mapping_mode = "multi-table"
+  # single_table_name = "telegraf"
+  # single_table_measurement_dimension = "namespace"
+
+  ## Write each metric as one multi-measure record holding all its fields,
+  ## named multi_measure_name or else after its measurement, instead of one
+  ## record per field named after the field.
+  # use_multi_measure_records = false
+  # multi_measure_name = ""
+
+  ## Create the database and the tables not found when writing.  The tables
+  ## are created with these retention periods.
+  # create_database_if_not_exists = false
+  # create_table_if_not_exists = true
+  # create_table_magnetic_store_retention_period_in_days = 365
+  # create_table_memory_store_retention_period_in_hours = 24
+`
+
+func (t *Timestream) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Timestream) Description() string {
+	return "Write metrics to Amazon Timestream"
+}
+
+func (t *Timestream) Init() error {
+	if t.DatabaseName == "" {
+		return fmt.Errorf("database_name must be set")
+	}
+	switch t.MappingMode {
+	case "":
+		t.MappingMode = mappingModeMultiTable
+	case mappingModeMultiTable:
+	case mappingModeSingleTable:
+		if t.SingleTableName == "" || t.SingleTableMeasurementDimension == "" {
+			return fmt.Errorf("single_table_name and single_table_measurement_dimension must be set in single-table mapping_mode")
+		}
+	default:
+		return fmt.Errorf("invalid mapping_mode %q", t.MappingMode)
+	}
+	if t.CreateTableIfNotExists && (t.MagneticStoreRetentionDays < 1 || t.MemoryStoreRetentionHours < 1) {
+		return fmt.Errorf("the retention periods of the created tables must be positive")
+	}
+	if t.now == nil {
+		t.now = time.Now
+	}
+	return nil
+}
+
+func (t *Timestream) Connect() error {
+	if t.client != nil {
+		return nil
+	}
+
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      t.Region,
+		AccessKey:   t.AccessKey,
+		SecretKey:   t.SecretKey,
+		RoleARN:     t.RoleARN,
+		Profile:     t.Profile,
+		Filename:    t.Filename,
+		Token:       t.Token,
+		EndpointURL: t.EndpointURL,
+	}
+	t.client = timestreamwrite.New(credentialConfig.Credentials())
+	return nil
+}
+
+func (t *Timestream) Close() error {
+	return nil
+}
+
+// request is a WriteRecords request with the metric of each record.
+type request struct {
+	input   *timestreamwrite.WriteRecordsInput
+	metrics []telegraf.Metric
+}
+
+// Write writes the records of the metrics to their tables, in requests of at
+// most 100 records.  The records rejected by Timestream are not written
+// again, the metrics of the requests that failed are.
+func (t *Timestream) Write(metrics []telegraf.Metric) error {
+	version := t.nextVersion()
+
+	var tables []string
+	requests := make(map[string][]*request)
+	for _, metric := range metrics {
+		table := t.table(metric)
+		records := t.records(metric, version)
+		if len(records) == 0 {
+			continue
+		}
+
+		if _, ok := requests[table]; !ok {
+			tables = append(tables, table)
+		}
+		for _, record := range records {
+			reqs := requests[table]
+			if len(reqs) == 0 || len(reqs[len(reqs)-1].input.Records) == maxRecordsPerRequest {
+				reqs = append(reqs, &request{
+					input: &timestreamwrite.WriteRecordsInput{
+						DatabaseName: aws.String(t.DatabaseName),
+						TableName:    aws.String(table),
+					},
+				})
+				requests[table] = reqs
+			}
+			req := reqs[len(reqs)-1]
+			req.input.Records = append(req.input.Records, record)
+			req.metrics = append(req.metrics, metric)
+		}
+	}
+
+	var writeErr error
+	var rejected []telegraf.Metric
+	for _, table := range tables {
+		for _, req := range requests[table] {
+			metrics, err := t.writeRequest(req)
+			if err != nil {
+				writeErr = err
+				continue
+			}
+			rejected = append(rejected, metrics...)
+		}
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if len(rejected) > 0 {
+		return &models.PermanentError{
+			Metrics: rejected,
+			Err:     fmt.Errorf("records of %d metrics rejected by Timestream", len(rejected)),
+		}
+	}
+	return nil
+}
+
+// nextVersion returns the version of the records of a write, the current
+// time in milliseconds or higher than the previous version.
+func (t *Timestream) nextVersion() int64 {
+	version := t.now().UnixNano() / int64(time.Millisecond)
+	if version <= t.version {
+		version = t.version + 1
+	}
+	t.version = version
+	return version
+}
+
+// writeRequest writes the records of the request, creating the table if not
+// found, and returns the metrics of the records rejected.
+func (t *Timestream) writeRequest(req *request) ([]telegraf.Metric, error) {
+	_, err := t.client.WriteRecords(req.input)
+	if isErrorCode(err, timestreamwrite.ErrCodeResourceNotFoundException) && t.CreateTableIfNotExists {
+		if err := t.createTable(aws.StringValue(req.input.TableName)); err != nil {
+			return nil, err
+		}
+		_, err = t.client.WriteRecords(req.input)
+	}
+	if err == nil {
+		return nil, nil
+	}
+
+	rejectedErr, ok := err.(*timestreamwrite.RejectedRecordsException)
+	if !ok {
+		return nil, fmt.Errorf("could not write to table %s: %v", aws.StringValue(req.input.TableName), err)
+	}
+
+	// the other records of the request were written
+	var rejected []telegraf.Metric
+	seen := make(map[telegraf.Metric]bool)
+	for _, record := range rejectedErr.RejectedRecords {
+		index := int(aws.Int64Value(record.RecordIndex))
+		if index < 0 || index >= len(req.metrics) {
+			continue
+		}
+		metric := req.metrics[index]
+		log.Printf("W! [outputs.timestream] Record of %s rejected by table %s: %s",
+			metric.Name(), aws.StringValue(req.input.TableName), aws.StringValue(record.Reason))
+		if !seen[metric] {
+			seen[metric] = true
+			rejected = append(rejected, metric)
+		}
+	}
+	return rejected, nil
+}
+
+// createTable creates the table, and the database if not found and
+// create_database_if_not_exists.
+func (t *Timestream) createTable(table string) error {
+	input := &timestreamwrite.CreateTableInput{
+		DatabaseName: aws.String(t.DatabaseName),
+		TableName:    aws.String(table),
+		RetentionProperties: &timestreamwrite.RetentionProperties{
+			MagneticStoreRetentionPeriodInDays: aws.Int64(t.MagneticStoreRetentionDays),
+			MemoryStoreRetentionPeriodInHours:  aws.Int64(t.MemoryStoreRetentionHours),
+		},
+	}
+	_, err := t.client.CreateTable(input)
+	if isErrorCode(err, timestreamwrite.ErrCodeResourceNotFoundException) && t.CreateDatabaseIfNotExists {
+		log.Printf("I! [outputs.timestream] Creating database %s", t.DatabaseName)
+		_, err = t.client.CreateDatabase(&timestreamwrite.CreateDatabaseInput{
+			DatabaseName: aws.String(t.DatabaseName),
+		})
+		if err != nil && !isErrorCode(err, timestreamwrite.ErrCodeConflictException) {
+			return fmt.Errorf("could not create database %s: %v", t.DatabaseName, err)
+		}
+		_, err = t.client.CreateTable(input)
+	}
+	switch {
+	case err == nil:
+		log.Printf("I! [outputs.timestream] Created table %s", table)
+	case isErrorCode(err, timestreamwrite.ErrCodeConflictException):
+		// the table was created concurrently
+	default:
+		return fmt.Errorf("could not create table %s: %v", table, err)
+	}
+	return nil
+}
+
+func isErrorCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
+
+// table returns the name of the table of the metric.
+func (t *Timestream) table(metric telegraf.Metric) string {
+	if t.MappingMode == mappingModeSingleTable {
+		return t.SingleTableName
+	}
+	return metric.Name()
+}
+
+// records returns the records of the metric, one per field or a single
+// multi-measure record.
+func (t *Timestream) records(metric telegraf.Metric, version int64) []*timestreamwrite.Record {
+	dimensions := make([]*timestreamwrite.Dimension, 0, len(metric.TagList())+1)
+	for _, tag := range metric.TagList() {
+		if tag.Value == "" {
+			continue
+		}
+		dimensions = append(dimensions, &timestreamwrite.Dimension{
+			Name:  aws.String(tag.Key),
+			Value: aws.String(tag.Value),
+		})
+	}
+	if t.MappingMode == mappingModeSingleTable {
+		dimensions = append(dimensions, &timestreamwrite.Dimension{
+			Name:  aws.String(t.SingleTableMeasurementDimension),
+			Value: aws.String(metric.Name()),
+		})
+	}
+	timestamp := aws.String(strconv.FormatInt(metric.Time().UnixNano(), 10))
+
+	var values []*timestreamwrite.MeasureValue
+	for _, field := range metric.FieldList() {
+		value, valueType, ok := measureValue(field.Value)
+		if !ok {
+			log.Printf("D! [outputs.timestream] Skipping field %s of %s, its value %v can not be written",
+				field.Key, metric.Name(), field.Value)
+			continue
+		}
+		values = append(values, &timestreamwrite.MeasureValue{
+			Name:  aws.String(field.Key),
+			Value: aws.String(value),
+			Type:  aws.String(valueType),
+		})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	if t.UseMultiMeasureRecords {
+		name := t.MultiMeasureName
+		if name == "" {
+			name = metric.Name()
+		}
+		return []*timestreamwrite.Record{{
+			Dimensions:       dimensions,
+			MeasureName:      aws.String(name),
+			MeasureValueType: aws.String(timestreamwrite.MeasureValueTypeMulti),
+			MeasureValues:    values,
+			Time:             timestamp,
+			TimeUnit:         aws.String(timestreamwrite.TimeUnitNanoseconds),
+			Version:          aws.Int64(version),
+		}}
+	}
+
+	records := make([]*timestreamwrite.Record, 0, len(values))
+	for _, value := range values {
+		records = append(records, &timestreamwrite.Record{
+			Dimensions:       dimensions,
+			MeasureName:      value.Name,
+			MeasureValue:     value.Value,
+			MeasureValueType: value.Type,
+			Time:             timestamp,
+			TimeUnit:         aws.String(timestreamwrite.TimeUnitNanoseconds),
+			Version:          aws.Int64(version),
+		})
+	}
+	return records
+}
+
+// measureValue returns the value and the type of the measure of the field
+// value, or false if it can not be written: unsigned integers larger than
+// the BIGINT and empty strings.
+func measureValue(value interface{}) (string, string, bool) {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", "", false
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), timestreamwrite.MeasureValueTypeDouble, true
+	case int64:
+		return strconv.FormatInt(v, 10), timestreamwrite.MeasureValueTypeBigint, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return "", "", false
+		}
+		return strconv.FormatUint(v, 10), timestreamwrite.MeasureValueTypeBigint, true
+	case bool:
+		return strconv.FormatBool(v), timestreamwrite.MeasureValueTypeBoolean, true
+	case string:
+		if v == "" {
+			return "", "", false
+		}
+		return v, timestreamwrite.MeasureValueTypeVarchar, true
+	}
+	return "", "", false
+}
+
+func init() {
+	outputs.Add("timestream", func() telegraf.Output {
+		return &Timestream{
+			MappingMode:                     mappingModeMultiTable,
+			SingleTableMeasurementDimension: "namespace",
+			CreateTableIfNotExists:          true,
+			MagneticStoreRetentionDays:      365,
+			MemoryStoreRetentionHours:       24,
+		}
+	})
+}
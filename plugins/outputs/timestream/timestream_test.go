@@ -0,0 +1,279 @@
+package timestream
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/timestreamwrite"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTimestream struct {
+	writes    []*timestreamwrite.WriteRecordsInput
+	databases []string
+	tables    map[string]*timestreamwrite.CreateTableInput
+
+	// databaseExists and the tables decide the ResourceNotFoundException
+	databaseExists bool
+	// reject rejects the records with these indexes of the next write
+	reject []int64
+}
+
+func newMockTimestream() *mockTimestream {
+	return &mockTimestream{
+		databaseExists: true,
+		tables:         make(map[string]*timestreamwrite.CreateTableInput),
+	}
+}
+
+func (m *mockTimestream) WriteRecords(input *timestreamwrite.WriteRecordsInput) (*timestreamwrite.WriteRecordsOutput, error) {
+	if len(input.Records) > maxRecordsPerRequest {
+		return nil, awserr.New(timestreamwrite.ErrCodeValidationException, "too many records", nil)
+	}
+	if _, ok := m.tables[aws.StringValue(input.TableName)]; !ok {
+		return nil, awserr.New(timestreamwrite.ErrCodeResourceNotFoundException, "table not found", nil)
+	}
+	m.writes = append(m.writes, input)
+	if len(m.reject) > 0 {
+		err := &timestreamwrite.RejectedRecordsException{}
+		for _, index := range m.reject {
+			err.RejectedRecords = append(err.RejectedRecords, &timestreamwrite.RejectedRecord{
+				RecordIndex: aws.Int64(index),
+				Reason:      aws.String("The record timestamp is outside the time range"),
+			})
+		}
+		m.reject = nil
+		return nil, err
+	}
+	return &timestreamwrite.WriteRecordsOutput{}, nil
+}
+
+func (m *mockTimestream) CreateDatabase(input *timestreamwrite.CreateDatabaseInput) (*timestreamwrite.CreateDatabaseOutput, error) {
+	m.databases = append(m.databases, aws.StringValue(input.DatabaseName))
+	m.databaseExists = true
+	return &timestreamwrite.CreateDatabaseOutput{}, nil
+}
+
+func (m *mockTimestream) CreateTable(input *timestreamwrite.CreateTableInput) (*timestreamwrite.CreateTableOutput, error) {
+	if !m.databaseExists {
+		return nil, awserr.New(timestreamwrite.ErrCodeResourceNotFoundException, "database not found", nil)
+	}
+	if _, ok := m.tables[aws.StringValue(input.TableName)]; ok {
+		return nil, awserr.New(timestreamwrite.ErrCodeConflictException, "table exists", nil)
+	}
+	m.tables[aws.StringValue(input.TableName)] = input
+	return &timestreamwrite.CreateTableOutput{}, nil
+}
+
+func newTestTimestream(client *mockTimestream) *Timestream {
+	t := &Timestream{
+		DatabaseName:               "telegraf",
+		CreateTableIfNotExists:     true,
+		MagneticStoreRetentionDays: 365,
+		MemoryStoreRetentionHours:  24,
+		client:                     client,
+		now:                        func() time.Time { return time.Unix(1600000000, 0) },
+	}
+	if err := t.Init(); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func newMetric(name string, fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric(name,
+		map[string]string{"host": "server01", "region": "eu-west-1"},
+		fields,
+		time.Unix(1500000000, 123),
+	)
+}
+
+func dimensions(kv ...string) []*timestreamwrite.Dimension {
+	var dims []*timestreamwrite.Dimension
+	for i := 0; i < len(kv); i += 2 {
+		dims = append(dims, &timestreamwrite.Dimension{Name: aws.String(kv[i]), Value: aws.String(kv[i+1])})
+	}
+	return dims
+}
+
+func TestWriteSingleMeasure(t *testing.T) {
+	client := newMockTimestream()
+	client.tables["cpu"] = nil
+	ts := newTestTimestream(client)
+
+	require.NoError(t, ts.Write([]telegraf.Metric{
+		newMetric("cpu", map[string]interface{}{
+			"usage_idle":  98.5,
+			"processes":   int64(12),
+			"threads":     uint64(42),
+			"overflow":    uint64(math.MaxUint64),
+			"online":      true,
+			"state":       "running",
+			"empty_state": "",
+		}),
+	}))
+
+	require.Len(t, client.writes, 1)
+	input := client.writes[0]
+	require.Equal(t, "telegraf", aws.StringValue(input.DatabaseName))
+	require.Equal(t, "cpu", aws.StringValue(input.TableName))
+
+	expected := map[string][2]string{
+		"usage_idle": {"98.5", "DOUBLE"},
+		"processes":  {"12", "BIGINT"},
+		"threads":    {"42", "BIGINT"},
+		"online":     {"true", "BOOLEAN"},
+		"state":      {"running", "VARCHAR"},
+	}
+	require.Len(t, input.Records, len(expected))
+	for _, record := range input.Records {
+		value, ok := expected[aws.StringValue(record.MeasureName)]
+		require.True(t, ok, aws.StringValue(record.MeasureName))
+		require.Equal(t, value[0], aws.StringValue(record.MeasureValue))
+		require.Equal(t, value[1], aws.StringValue(record.MeasureValueType))
+		require.Equal(t, dimensions("host", "server01", "region", "eu-west-1"), record.Dimensions)
+		require.Equal(t, "1500000000000000123", aws.StringValue(record.Time))
+		require.Equal(t, "NANOSECONDS", aws.StringValue(record.TimeUnit))
+		require.Equal(t, int64(1600000000000), aws.Int64Value(record.Version))
+	}
+}
+
+func TestWriteMultiMeasure(t *testing.T) {
+	client := newMockTimestream()
+	client.tables["telegraf"] = nil
+	ts := newTestTimestream(client)
+	ts.MappingMode = mappingModeSingleTable
+	ts.SingleTableName = "telegraf"
+	ts.SingleTableMeasurementDimension = "namespace"
+	ts.UseMultiMeasureRecords = true
+	require.NoError(t, ts.Init())
+
+	require.NoError(t, ts.Write([]telegraf.Metric{
+		newMetric("cpu", map[string]interface{}{"usage_idle": 98.5}),
+		newMetric("mem", map[string]interface{}{"used": int64(1024), "active": true}),
+	}))
+
+	require.Len(t, client.writes, 1)
+	records := client.writes[0].Records
+	require.Len(t, records, 2)
+
+	require.Equal(t, &timestreamwrite.Record{
+		Dimensions:       dimensions("host", "server01", "region", "eu-west-1", "namespace", "mem"),
+		MeasureName:      aws.String("mem"),
+		MeasureValueType: aws.String("MULTI"),
+		MeasureValues: []*timestreamwrite.MeasureValue{
+			{Name: aws.String("active"), Value: aws.String("true"), Type: aws.String("BOOLEAN")},
+			{Name: aws.String("used"), Value: aws.String("1024"), Type: aws.String("BIGINT")},
+		},
+		Time:     aws.String("1500000000000000123"),
+		TimeUnit: aws.String("NANOSECONDS"),
+		Version:  aws.Int64(1600000000000),
+	}, sortMeasureValues(records[1]))
+
+	// the multi-measure records can be named after a fixed name
+	ts.MultiMeasureName = "metrics"
+	require.NoError(t, ts.Write([]telegraf.Metric{
+		newMetric("cpu", map[string]interface{}{"usage_idle": 98.5}),
+	}))
+	require.Equal(t, "metrics", aws.StringValue(client.writes[1].Records[0].MeasureName))
+}
+
+func sortMeasureValues(record *timestreamwrite.Record) *timestreamwrite.Record {
+	values := record.MeasureValues
+	for i := 1; i < len(values); i++ {
+		for j := i; j > 0 && aws.StringValue(values[j].Name) < aws.StringValue(values[j-1].Name); j-- {
+			values[j], values[j-1] = values[j-1], values[j]
+		}
+	}
+	return record
+}
+
+func TestWriteBatches(t *testing.T) {
+	client := newMockTimestream()
+	client.tables["cpu"] = nil
+	client.tables["mem"] = nil
+	ts := newTestTimestream(client)
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 150; i++ {
+		metrics = append(metrics, newMetric("cpu", map[string]interface{}{"a": float64(i), "b": float64(i)}))
+	}
+	metrics = append(metrics, newMetric("mem", map[string]interface{}{"used": int64(1)}))
+	require.NoError(t, ts.Write(metrics))
+
+	var sizes []int
+	for _, input := range client.writes {
+		sizes = append(sizes, len(input.Records))
+	}
+	require.Equal(t, []int{100, 100, 100, 1}, sizes)
+}
+
+func TestWriteVersionsIncrease(t *testing.T) {
+	client := newMockTimestream()
+	client.tables["cpu"] = nil
+	ts := newTestTimestream(client)
+
+	// the writes in the same millisecond still replace the previous records
+	m := newMetric("cpu", map[string]interface{}{"usage_idle": 98.5})
+	require.NoError(t, ts.Write([]telegraf.Metric{m}))
+	require.NoError(t, ts.Write([]telegraf.Metric{m}))
+	require.Equal(t, int64(1600000000000), aws.Int64Value(client.writes[0].Records[0].Version))
+	require.Equal(t, int64(1600000000001), aws.Int64Value(client.writes[1].Records[0].Version))
+}
+
+func TestWriteCreatesTable(t *testing.T) {
+	client := newMockTimestream()
+	client.databaseExists = false
+	ts := newTestTimestream(client)
+
+	m := newMetric("cpu", map[string]interface{}{"usage_idle": 98.5})
+	require.Error(t, ts.Write([]telegraf.Metric{m}))
+	require.Empty(t, client.tables)
+
+	ts.CreateDatabaseIfNotExists = true
+	require.NoError(t, ts.Write([]telegraf.Metric{m}))
+	require.Equal(t, []string{"telegraf"}, client.databases)
+	require.Equal(t, &timestreamwrite.RetentionProperties{
+		MagneticStoreRetentionPeriodInDays: aws.Int64(365),
+		MemoryStoreRetentionPeriodInHours:  aws.Int64(24),
+	}, client.tables["cpu"].RetentionProperties)
+	require.Len(t, client.writes, 1)
+
+	ts.CreateTableIfNotExists = false
+	err := ts.Write([]telegraf.Metric{newMetric("mem", map[string]interface{}{"used": int64(1)})})
+	require.Error(t, err)
+	require.Len(t, client.tables, 1)
+}
+
+func TestWriteRejectedRecords(t *testing.T) {
+	client := newMockTimestream()
+	client.tables["cpu"] = nil
+	ts := newTestTimestream(client)
+
+	m1 := newMetric("cpu", map[string]interface{}{"usage_idle": 98.5})
+	m2 := newMetric("cpu", map[string]interface{}{"usage_idle": 97.5, "usage_user": 1.5})
+	client.reject = []int64{1, 2}
+
+	err := ts.Write([]telegraf.Metric{m1, m2})
+	permanent, ok := err.(*models.PermanentError)
+	require.True(t, ok, fmt.Sprintf("%v", err))
+	require.Equal(t, []telegraf.Metric{m2}, permanent.Metrics)
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, ts := range []*Timestream{
+		{},
+		{DatabaseName: "telegraf", MappingMode: "per-field"},
+		{DatabaseName: "telegraf", MappingMode: mappingModeSingleTable},
+		{DatabaseName: "telegraf", CreateTableIfNotExists: true},
+	} {
+		require.Error(t, ts.Init())
+	}
+}
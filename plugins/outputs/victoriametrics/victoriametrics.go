@@ -0,0 +1,319 @@
+package victoriametrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## URL of VictoriaMetrics, of the single node server or of vminsert for a
+  ## cluster.  The samples are sent to the /api/v1/import/prometheus endpoint.
+  url = "http://127.0.0.1:8428"
+
+  ## Tenant of a VictoriaMetrics cluster, as "AccountID" or
+  ## "AccountID:ProjectID".  The samples are then sent to
+  ## /insert/<tenant>/prometheus/api/v1/import/prometheus.
+  # tenant = ""
+
+  ## Labels added by VictoriaMetrics to all the samples sent.
+  # [outputs.victoriametrics.extra_labels]
+  #   source = "telegraf"
+
+  ## Maximum number of samples per request, the samples of a write are split
+  ## in several requests above it.  0 sends all of them in one request.
+  # max_samples_per_request = 10000
+
+  ## Compress the requests, one of "gzip", "zstd" or "identity".
+  # content_encoding = "gzip"
+
+  ## Timeout for HTTP message
+  # timeout = "5s"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Additional HTTP headers, for example the authorization of vmauth
+  # [outputs.victoriametrics.headers]
+  #   Authorization = "Bearer token"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	defaultClientTimeout        = 5 * time.Second
+	defaultMaxSamplesPerRequest = 10000
+
+	importPath = "/api/v1/import/prometheus"
+)
+
+var invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type VictoriaMetrics struct {
+	URL                  string            `toml:"url"`
+	Tenant               string            `toml:"tenant"`
+	ExtraLabels          map[string]string `toml:"extra_labels"`
+	MaxSamplesPerRequest int               `toml:"max_samples_per_request"`
+	ContentEncoding      string            `toml:"content_encoding"`
+	Timeout              internal.Duration `toml:"timeout"`
+	Username             string            `toml:"username"`
+	Password             string            `toml:"password"`
+	Headers              map[string]string `toml:"headers"`
+	tls.ClientConfig
+
+	importURL string
+	encoder   internal.ContentEncoder
+	client    *http.Client
+}
+
+func (v *VictoriaMetrics) Description() string {
+	return "Send metrics to VictoriaMetrics with its Prometheus import API"
+}
+
+func (v *VictoriaMetrics) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *VictoriaMetrics) Connect() error {
+	if v.URL == "" {
+		return fmt.Errorf("url must be set")
+	}
+	if v.Timeout.Duration == 0 {
+		v.Timeout.Duration = defaultClientTimeout
+	}
+
+	importURL, err := buildImportURL(v.URL, v.Tenant, v.ExtraLabels)
+	if err != nil {
+		return err
+	}
+	v.importURL = importURL
+
+	v.encoder, err = internal.NewContentEncoder(v.ContentEncoding, 0)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg, err := v.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	v.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: v.Timeout.Duration,
+	}
+	return nil
+}
+
+// buildImportURL returns the URL of the import endpoint of the base URL, under
+// the path prefix of the tenant if set.  The extra labels are passed to
+// VictoriaMetrics as extra_label query arguments.
+func buildImportURL(base, tenant string, extraLabels map[string]string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %v", base, err)
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+	if tenant != "" {
+		if err := checkTenant(tenant); err != nil {
+			return "", err
+		}
+		path += "/insert/" + tenant + "/prometheus"
+	}
+	u.Path = path + importPath
+
+	if len(extraLabels) > 0 {
+		query := u.Query()
+		for k, val := range extraLabels {
+			query.Add("extra_label", sanitize(k)+"="+val)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+// checkTenant checks the tenant is "AccountID" or "AccountID:ProjectID", both
+// being 32 bits unsigned integers.
+func checkTenant(tenant string) error {
+	ids := strings.SplitN(tenant, ":", 2)
+	for _, id := range ids {
+		if _, err := strconv.ParseUint(id, 10, 32); err != nil {
+			return fmt.Errorf("invalid tenant %q, must be AccountID or AccountID:ProjectID", tenant)
+		}
+	}
+	return nil
+}
+
+func (v *VictoriaMetrics) Close() error {
+	return nil
+}
+
+func (v *VictoriaMetrics) Write(metrics []telegraf.Metric) error {
+	var buf bytes.Buffer
+	samples := 0
+	for _, metric := range metrics {
+		for _, field := range metric.FieldList() {
+			value, ok := sampleValue(field.Value)
+			if !ok {
+				continue
+			}
+			writeSample(&buf, metric, field.Key, value)
+			samples++
+
+			if v.MaxSamplesPerRequest > 0 && samples >= v.MaxSamplesPerRequest {
+				if err := v.send(buf.Bytes()); err != nil {
+					return err
+				}
+				buf.Reset()
+				samples = 0
+			}
+		}
+	}
+	if samples == 0 {
+		return nil
+	}
+	return v.send(buf.Bytes())
+}
+
+// writeSample writes the sample of the field in the Prometheus text format,
+// with its timestamp in milliseconds.  The sample is named after the
+// measurement and the field, or the measurement only for a field named value,
+// and labelled with the tags, sorted by name.
+func writeSample(buf *bytes.Buffer, metric telegraf.Metric, field string, value float64) {
+	name := metric.Name()
+	if field != "value" {
+		name += "_" + field
+	}
+	buf.WriteString(sanitize(name))
+
+	tags := metric.TagList()
+	if len(tags) > 0 {
+		labels := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			labels = append(labels, sanitize(tag.Key)+`="`+labelValueEscaper.Replace(tag.Value)+`"`)
+		}
+		sort.Strings(labels)
+		buf.WriteByte('{')
+		buf.WriteString(strings.Join(labels, ","))
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(formatValue(value))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(metric.Time().UnixNano()/int64(time.Millisecond), 10))
+	buf.WriteByte('\n')
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func sampleValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// sanitize replaces the characters not allowed in the metric and label names
+// by underscores.
+func sanitize(name string) string {
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return invalidNameCharRE.ReplaceAllString(name, "_")
+}
+
+func (v *VictoriaMetrics) send(reqBody []byte) error {
+	body, err := v.encoder.Encode(reqBody)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, v.importURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if v.Username != "" || v.Password != "" {
+		req.SetBasicAuth(v.Username, v.Password)
+	}
+
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	req.Header.Set("Content-Type", "text/plain")
+	if encoding := v.encoder.Encoding(); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	for k, val := range v.Headers {
+		req.Header.Set(k, val)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("when writing to [%s] received status code %d: %s",
+			v.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("victoriametrics", func() telegraf.Output {
+		return &VictoriaMetrics{
+			MaxSamplesPerRequest: defaultMaxSamplesPerRequest,
+			ContentEncoding:      "gzip",
+			Timeout:              internal.Duration{Duration: defaultClientTimeout},
+		}
+	})
+}
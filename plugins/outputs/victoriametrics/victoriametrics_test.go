@@ -0,0 +1,148 @@
+package victoriametrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSamples(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a", "cpu-id": "0"},
+			map[string]interface{}{"usage_idle": 90.5, "state": "ok"}, time.Unix(1, 0)),
+		testutil.MustMetric("disk.io", map[string]string{"path": `C:\ "data"`},
+			map[string]interface{}{"reads": uint64(42)}, time.Unix(2, 500000000)),
+		testutil.MustMetric("disk.io", map[string]string{"path": `C:\ "data"`},
+			map[string]interface{}{"rate": math.Inf(1)}, time.Unix(2, 500000000)),
+		testutil.MustMetric("up", nil, map[string]interface{}{"value": true}, time.Unix(3, 0)),
+	}
+
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		for _, field := range m.FieldList() {
+			if value, ok := sampleValue(field.Value); ok {
+				writeSample(&buf, m, field.Key, value)
+			}
+		}
+	}
+	require.Equal(t, `cpu_usage_idle{cpu_id="0",host="a"} 90.5 1000
+disk_io_reads{path="C:\\ \"data\""} 42 2500
+disk_io_rate{path="C:\\ \"data\""} +Inf 2500
+up 1 3000
+`, buf.String())
+}
+
+func TestImportURL(t *testing.T) {
+	tests := []struct {
+		url         string
+		tenant      string
+		extraLabels map[string]string
+		expected    string
+	}{
+		{
+			url:      "http://localhost:8428",
+			expected: "http://localhost:8428/api/v1/import/prometheus",
+		},
+		{
+			url:      "http://localhost:8480/",
+			tenant:   "42",
+			expected: "http://localhost:8480/insert/42/prometheus/api/v1/import/prometheus",
+		},
+		{
+			url:      "https://vmauth/victoria",
+			tenant:   "42:7",
+			expected: "https://vmauth/victoria/insert/42:7/prometheus/api/v1/import/prometheus",
+		},
+		{
+			url:         "http://localhost:8428",
+			extraLabels: map[string]string{"source.name": "telegraf"},
+			expected:    "http://localhost:8428/api/v1/import/prometheus?extra_label=source_name%3Dtelegraf",
+		},
+	}
+	for _, tt := range tests {
+		actual, err := buildImportURL(tt.url, tt.tenant, tt.extraLabels)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, actual)
+	}
+
+	for _, tenant := range []string{"a", "42:b", "42:", "4294967296", "1:2:3"} {
+		_, err := buildImportURL("http://localhost:8480", tenant, nil)
+		require.Error(t, err, tenant)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	output := &VictoriaMetrics{
+		URL:                  ts.URL,
+		Tenant:               "1:2",
+		MaxSamplesPerRequest: 2,
+		ContentEncoding:      "gzip",
+		Username:             "telegraf",
+		Password:             "secret",
+	}
+	require.NoError(t, output.Connect())
+	require.NoError(t, output.Write([]telegraf.Metric{
+		testutil.MustMetric("mem", map[string]string{"host": "a"},
+			map[string]interface{}{"used": int64(1024)}, time.Unix(10, 0)),
+		testutil.MustMetric("mem", map[string]string{"host": "a"},
+			map[string]interface{}{"free": int64(2048)}, time.Unix(10, 0)),
+		testutil.MustMetric("mem", map[string]string{"host": "b"},
+			map[string]interface{}{"used": int64(512)}, time.Unix(10, 0)),
+	}))
+
+	// the samples are split by max_samples_per_request
+	require.Equal(t, []string{
+		"mem_used{host=\"a\"} 1024 10000\nmem_free{host=\"a\"} 2048 10000\n",
+		"mem_used{host=\"b\"} 512 10000\n",
+	}, bodies)
+	for _, r := range requests {
+		require.Equal(t, "/insert/1:2/prometheus/api/v1/import/prometheus", r.URL.Path)
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "telegraf", username)
+		require.Equal(t, "secret", password)
+	}
+
+	// the writes without numeric fields send nothing
+	require.NoError(t, output.Write([]telegraf.Metric{
+		testutil.MustMetric("log", nil, map[string]interface{}{"message": "hello"}, time.Unix(10, 0)),
+	}))
+	require.Len(t, requests, 2)
+}
+
+func TestWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "cannot parse line", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	output := &VictoriaMetrics{URL: ts.URL}
+	require.NoError(t, output.Connect())
+	err := output.Write([]telegraf.Metric{
+		testutil.MustMetric("up", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+	})
+	require.EqualError(t, err, "when writing to ["+ts.URL+"] received status code 400: cannot parse line")
+}
@@ -0,0 +1,288 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## URL to send the requests to
+  url = "http://127.0.0.1:8080/hook"
+
+  ## HTTP method, one of: "POST" or "PUT"
+  # method = "POST"
+
+  ## Timeout for HTTP message
+  # timeout = "5s"
+
+  ## Go template of the body of the requests, rendered for each metric with
+  ## one request per metric.  The template has access to the .Name, .Tags,
+  ## .Fields and .Time of the metric, the json function encodes a value to
+  ## JSON.
+  template = '''
+  {"title": {{json .Name}}, "host": {{json .Tags.host}}, "value": {{json .Fields.value}}}
+  '''
+
+  ## Go template of the body of the requests, rendered once for all the
+  ## metrics of a write, instead of template.  The metrics are in .Metrics.
+  # batch_template = '''
+  # [{{range $i, $m := .Metrics}}{{if $i}},{{end}}{"name": {{json $m.Name}}}{{end}}]
+  # '''
+
+  ## Content-Type of the requests
+  # content_type = "application/json"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Additional HTTP headers
+  # [outputs.webhook.headers]
+  #   X-Source = "telegraf"
+
+  ## Maximum number of attempts of a request failing with a connection error
+  ## or a 5xx status code, and the wait time before the first retry.
+  # max_attempts = 1
+  # retry_backoff = "1s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	defaultClientTimeout = 5 * time.Second
+	defaultContentType   = "application/json"
+	defaultMethod        = http.MethodPost
+	defaultRetryBackoff  = time.Second
+)
+
+// templateFuncs are the functions available in the templates.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+}
+
+type Webhook struct {
+	URL           string            `toml:"url"`
+	Method        string            `toml:"method"`
+	Timeout       internal.Duration `toml:"timeout"`
+	Template      string            `toml:"template"`
+	BatchTemplate string            `toml:"batch_template"`
+	ContentType   string            `toml:"content_type"`
+	Username      string            `toml:"username"`
+	Password      string            `toml:"password"`
+	Headers       map[string]string `toml:"headers"`
+	MaxAttempts   int               `toml:"max_attempts"`
+	RetryBackoff  internal.Duration `toml:"retry_backoff"`
+	tls.ClientConfig
+
+	template      *template.Template
+	batchTemplate *template.Template
+	client        *http.Client
+}
+
+// metricData is the data of a metric in the templates.
+type metricData struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+	Time   time.Time
+}
+
+// batchData is the data of the batch template.
+type batchData struct {
+	Metrics []metricData
+}
+
+func newMetricData(metric telegraf.Metric) metricData {
+	return metricData{
+		Name:   metric.Name(),
+		Tags:   metric.Tags(),
+		Fields: metric.Fields(),
+		Time:   metric.Time(),
+	}
+}
+
+func (w *Webhook) Description() string {
+	return "Send metrics in a templated body to a HTTP endpoint"
+}
+
+func (w *Webhook) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Webhook) Init() error {
+	if w.URL == "" {
+		return fmt.Errorf("url must be set")
+	}
+
+	if w.Method == "" {
+		w.Method = defaultMethod
+	}
+	w.Method = strings.ToUpper(w.Method)
+	if w.Method != http.MethodPost && w.Method != http.MethodPut {
+		return fmt.Errorf("invalid method [%s] %s", w.URL, w.Method)
+	}
+
+	if (w.Template == "") == (w.BatchTemplate == "") {
+		return fmt.Errorf("exactly one of template or batch_template must be set")
+	}
+
+	var err error
+	if w.Template != "" {
+		w.template, err = parseTemplate("template", w.Template)
+	} else {
+		w.batchTemplate, err = parseTemplate("batch_template", w.BatchTemplate)
+	}
+	return err
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Parse(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return t, nil
+}
+
+func (w *Webhook) Connect() error {
+	if w.Timeout.Duration == 0 {
+		w.Timeout.Duration = defaultClientTimeout
+	}
+	if w.RetryBackoff.Duration == 0 {
+		w.RetryBackoff.Duration = defaultRetryBackoff
+	}
+
+	tlsCfg, err := w.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: tlsCfg,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+	w.client = &http.Client{
+		Transport: internal.NewRetryTransport(transport, w.MaxAttempts,
+			w.RetryBackoff.Duration, map[string]string{"output": "webhook"}),
+		Timeout: w.Timeout.Duration,
+	}
+	return nil
+}
+
+func (w *Webhook) Close() error {
+	return nil
+}
+
+func (w *Webhook) Write(metrics []telegraf.Metric) error {
+	if w.batchTemplate != nil {
+		data := batchData{Metrics: make([]metricData, 0, len(metrics))}
+		for _, metric := range metrics {
+			data.Metrics = append(data.Metrics, newMetricData(metric))
+		}
+		body, err := render(w.batchTemplate, data)
+		if err != nil {
+			return err
+		}
+		return w.send(body)
+	}
+
+	for _, metric := range metrics {
+		body, err := render(w.template, newMetricData(metric))
+		if err != nil {
+			return err
+		}
+		if err := w.send(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func render(t *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("could not render %s: %v", t.Name(), err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (w *Webhook) send(body []byte) error {
+	req, err := http.NewRequest(w.Method, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if w.Username != "" || w.Password != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	contentType := w.ContentType
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	req.Header.Set("Content-Type", contentType)
+	if w.MaxAttempts > 1 {
+		// lets the retries of POST requests be recognized by the receiver
+		key, err := idempotencyKey()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Idempotency-Key", key)
+	}
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("when writing to [%s] received status code: %d", w.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+func idempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func init() {
+	outputs.Add("webhook", func() telegraf.Output {
+		return &Webhook{
+			Method:       defaultMethod,
+			Timeout:      internal.Duration{Duration: defaultClientTimeout},
+			ContentType:  defaultContentType,
+			MaxAttempts:  1,
+			RetryBackoff: internal.Duration{Duration: defaultRetryBackoff},
+		}
+	})
+}
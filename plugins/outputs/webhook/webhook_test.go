@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func getMetrics(t *testing.T) []telegraf.Metric {
+	m1, err := metric.New("cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(1, 0))
+	require.NoError(t, err)
+	m2, err := metric.New("mem",
+		map[string]string{"host": "server02"},
+		map[string]interface{}{"value": int64(7)},
+		time.Unix(2, 0))
+	require.NoError(t, err)
+	return []telegraf.Metric{m1, m2}
+}
+
+type request struct {
+	method      string
+	contentType string
+	idempotency string
+	body        string
+}
+
+// newServer returns a server recording the requests, responding with the
+// status codes in turn and then 204.
+func newServer(t *testing.T, statuses ...int) (*httptest.Server, func() []request) {
+	var mu sync.Mutex
+	var requests []request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		requests = append(requests, request{
+			method:      r.Method,
+			contentType: r.Header.Get("Content-Type"),
+			idempotency: r.Header.Get("Idempotency-Key"),
+			body:        string(body),
+		})
+		status := http.StatusNoContent
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	return ts, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request(nil), requests...)
+	}
+}
+
+func newWebhook(url string) *Webhook {
+	return &Webhook{
+		URL:         url,
+		Method:      defaultMethod,
+		ContentType: defaultContentType,
+	}
+}
+
+func TestBatchTemplate(t *testing.T) {
+	ts, requests := newServer(t)
+	defer ts.Close()
+
+	w := newWebhook(ts.URL)
+	w.BatchTemplate = `
+{"alerts": [
+  {{- range $i, $m := .Metrics}}{{if $i}},{{end}}
+  {"name": {{json $m.Name}}, "host": {{json $m.Tags.host}}, "value": {{json $m.Fields.value}}, "ts": {{$m.Time.Unix}}}
+  {{- end}}
+]}
+`
+	require.NoError(t, w.Init())
+	require.NoError(t, w.Connect())
+	require.NoError(t, w.Write(getMetrics(t)))
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	require.Equal(t, http.MethodPost, reqs[0].method)
+	require.Equal(t, "application/json", reqs[0].contentType)
+
+	var body interface{}
+	require.NoError(t, json.Unmarshal([]byte(reqs[0].body), &body))
+	require.Equal(t, map[string]interface{}{
+		"alerts": []interface{}{
+			map[string]interface{}{"name": "cpu", "host": "server01", "value": 42.0, "ts": 1.0},
+			map[string]interface{}{"name": "mem", "host": "server02", "value": 7.0, "ts": 2.0},
+		},
+	}, body)
+}
+
+func TestTemplatePerMetric(t *testing.T) {
+	ts, requests := newServer(t)
+	defer ts.Close()
+
+	w := newWebhook(ts.URL)
+	w.Method = "put"
+	w.ContentType = "text/plain"
+	w.Template = `{{.Name}} on {{.Tags.host}} is {{.Fields.value}}`
+	require.NoError(t, w.Init())
+	require.NoError(t, w.Connect())
+	require.NoError(t, w.Write(getMetrics(t)))
+
+	reqs := requests()
+	require.Len(t, reqs, 2)
+	require.Equal(t, request{
+		method:      http.MethodPut,
+		contentType: "text/plain",
+		body:        "cpu on server01 is 42",
+	}, reqs[0])
+	require.Equal(t, "mem on server02 is 7", reqs[1].body)
+}
+
+func TestRetry(t *testing.T) {
+	ts, requests := newServer(t, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	w := newWebhook(ts.URL)
+	w.Template = `{{json .Name}}`
+	w.MaxAttempts = 2
+	w.RetryBackoff.Duration = time.Millisecond
+	require.NoError(t, w.Init())
+	require.NoError(t, w.Connect())
+	require.NoError(t, w.Write(getMetrics(t)[:1]))
+
+	reqs := requests()
+	require.Len(t, reqs, 2)
+	require.NotEmpty(t, reqs[0].idempotency)
+	require.Equal(t, reqs[0], reqs[1])
+}
+
+func TestWriteError(t *testing.T) {
+	ts, _ := newServer(t, http.StatusBadRequest)
+	defer ts.Close()
+
+	w := newWebhook(ts.URL)
+	w.Template = `{{json .Name}}`
+	require.NoError(t, w.Init())
+	require.NoError(t, w.Connect())
+	require.Error(t, w.Write(getMetrics(t)))
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Webhook
+	}{
+		{
+			name:   "missing url",
+			plugin: &Webhook{Template: "{{.Name}}"},
+		},
+		{
+			name:   "invalid method",
+			plugin: &Webhook{URL: "http://localhost", Method: "GET", Template: "{{.Name}}"},
+		},
+		{
+			name:   "missing template",
+			plugin: &Webhook{URL: "http://localhost"},
+		},
+		{
+			name:   "both templates",
+			plugin: &Webhook{URL: "http://localhost", Template: "{{.Name}}", BatchTemplate: "{{.Metrics}}"},
+		},
+		{
+			name:   "invalid template",
+			plugin: &Webhook{URL: "http://localhost", Template: "{{.Name"},
+		},
+		{
+			name:   "undefined function",
+			plugin: &Webhook{URL: "http://localhost", BatchTemplate: "{{yaml .Metrics}}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
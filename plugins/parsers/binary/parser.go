@@ -0,0 +1,296 @@
+package binary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Field is a field of the records, decoded from Length bytes at Offset.
+type Field struct {
+	Name   string `toml:"name"`
+	Offset int    `toml:"offset"`
+	Length int    `toml:"length"`
+	// Type is one of int, uint, float, bool and bitfield
+	Type string `toml:"type"`
+	// Endianness overrides the endianness of the parser
+	Endianness string `toml:"endianness"`
+	// Scale multiplies the numbers, converted to floats, if not zero
+	Scale float64 `toml:"scale"`
+	// BitOffset and Bits are the bits of the bitfield and bool fields,
+	// counted from the least significant bit of the bytes
+	BitOffset int `toml:"bit_offset"`
+	Bits      int `toml:"bits"`
+
+	order binary.ByteOrder
+}
+
+// Parser decodes records of a fixed binary layout, each parsed as a metric.
+// The bytes of a partial record at the end of a buffer are kept and
+// completed by the next buffer.
+type Parser struct {
+	MetricName string
+	// Endianness is big or little, big by default
+	Endianness string
+	// RecordLength is the length of the records, by default up to the end of
+	// the last field
+	RecordLength int
+	Fields       []Field
+	// TimestampField is the field holding the timestamp of the records, a
+	// unix timestamp in TimestampFormat
+	TimestampField  string
+	TimestampFormat string
+	DefaultTags     map[string]string
+
+	TimeFunc func() time.Time
+
+	timestamp *Field
+	// mu protects the bytes of the partial record
+	mu      sync.Mutex
+	pending []byte
+}
+
+var byteOrders = map[string]binary.ByteOrder{
+	"big":    binary.BigEndian,
+	"little": binary.LittleEndian,
+}
+
+// Init checks the layout of the records.
+func (p *Parser) Init() error {
+	if p.Endianness == "" {
+		p.Endianness = "big"
+	}
+	order, ok := byteOrders[p.Endianness]
+	if !ok {
+		return fmt.Errorf("invalid endianness %q", p.Endianness)
+	}
+	if len(p.Fields) == 0 {
+		return fmt.Errorf("no fields configured")
+	}
+
+	end := 0
+	names := make(map[string]bool)
+	for i := range p.Fields {
+		f := &p.Fields[i]
+		if f.Name == "" {
+			return fmt.Errorf("field at offset %d without name", f.Offset)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("duplicate field %s", f.Name)
+		}
+		names[f.Name] = true
+		if err := f.compile(order); err != nil {
+			return fmt.Errorf("field %s: %v", f.Name, err)
+		}
+		if f.Offset+f.Length > end {
+			end = f.Offset + f.Length
+		}
+		if f.Name == p.TimestampField {
+			p.timestamp = f
+		}
+	}
+
+	if p.RecordLength == 0 {
+		p.RecordLength = end
+	}
+	if p.RecordLength < end {
+		return fmt.Errorf("record length %d shorter than the fields, ending at %d", p.RecordLength, end)
+	}
+
+	if p.TimestampField != "" {
+		if p.timestamp == nil {
+			return fmt.Errorf("unknown timestamp field %s", p.TimestampField)
+		}
+		switch p.timestamp.Type {
+		case "int", "uint", "float":
+		default:
+			return fmt.Errorf("timestamp field %s must be a number", p.TimestampField)
+		}
+	}
+	switch p.TimestampFormat {
+	case "":
+		p.TimestampFormat = "unix"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid timestamp format %q", p.TimestampFormat)
+	}
+
+	if p.TimeFunc == nil {
+		p.TimeFunc = time.Now
+	}
+	return nil
+}
+
+func (f *Field) compile(order binary.ByteOrder) error {
+	if f.Offset < 0 {
+		return fmt.Errorf("invalid offset %d", f.Offset)
+	}
+	f.order = order
+	if f.Endianness != "" {
+		var ok bool
+		if f.order, ok = byteOrders[f.Endianness]; !ok {
+			return fmt.Errorf("invalid endianness %q", f.Endianness)
+		}
+	}
+
+	switch f.Type {
+	case "int", "uint", "bool", "bitfield":
+		if f.Length < 1 || f.Length > 8 {
+			return fmt.Errorf("invalid length %d, must be 1 to 8 bytes", f.Length)
+		}
+	case "float":
+		if f.Length != 4 && f.Length != 8 {
+			return fmt.Errorf("invalid length %d, must be 4 or 8 bytes", f.Length)
+		}
+	default:
+		return fmt.Errorf("invalid type %q", f.Type)
+	}
+
+	if f.Type == "bitfield" || (f.Type == "bool" && f.Bits > 0) {
+		if f.Bits < 1 || f.BitOffset < 0 || f.BitOffset+f.Bits > 8*f.Length {
+			return fmt.Errorf("invalid bits %d at bit offset %d of %d bytes", f.Bits, f.BitOffset, f.Length)
+		}
+	} else if f.Bits != 0 || f.BitOffset != 0 {
+		return fmt.Errorf("bits only apply to the bitfield and bool types")
+	}
+	if f.Scale != 0 && f.Type == "bool" {
+		return fmt.Errorf("scale does not apply to the bool type")
+	}
+	return nil
+}
+
+// Parse decodes the complete records of the buffer, preceded by the bytes
+// left over by the previous buffer.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	p.mu.Lock()
+	data := buf
+	if len(p.pending) > 0 {
+		data = append(p.pending, buf...)
+	}
+	n := len(data) / p.RecordLength
+	p.pending = append([]byte(nil), data[n*p.RecordLength:]...)
+	p.mu.Unlock()
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		m, err := p.parseRecord(data[i*p.RecordLength:(i+1)*p.RecordLength], now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseRecord(record []byte, now time.Time) (telegraf.Metric, error) {
+	tags := make(map[string]string, len(p.DefaultTags))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+
+	tm := now
+	fields := make(map[string]interface{}, len(p.Fields))
+	for i := range p.Fields {
+		f := &p.Fields[i]
+		v := f.decode(record)
+		if f == p.timestamp {
+			tm = p.parseTimestamp(v)
+			continue
+		}
+		fields[f.Name] = v
+	}
+	return metric.New(p.MetricName, tags, fields, tm)
+}
+
+// decode returns the value of the field in the record, an int64, uint64,
+// float64 or bool.
+func (f *Field) decode(record []byte) interface{} {
+	b := record[f.Offset : f.Offset+f.Length]
+	var raw uint64
+	if f.order == binary.BigEndian {
+		for _, c := range b {
+			raw = raw<<8 | uint64(c)
+		}
+	} else {
+		for i := len(b) - 1; i >= 0; i-- {
+			raw = raw<<8 | uint64(b[i])
+		}
+	}
+
+	var v interface{}
+	switch f.Type {
+	case "int":
+		// sign extended from the length of the field
+		shift := uint(64 - 8*f.Length)
+		v = int64(raw<<shift) >> shift
+	case "uint":
+		v = raw
+	case "float":
+		if f.Length == 4 {
+			v = float64(math.Float32frombits(uint32(raw)))
+		} else {
+			v = math.Float64frombits(raw)
+		}
+	case "bitfield":
+		v = f.bits(raw)
+	case "bool":
+		if f.Bits > 0 {
+			raw = f.bits(raw)
+		}
+		return raw != 0
+	}
+
+	if f.Scale == 0 {
+		return v
+	}
+	switch n := v.(type) {
+	case int64:
+		return float64(n) * f.Scale
+	case uint64:
+		return float64(n) * f.Scale
+	default:
+		return n.(float64) * f.Scale
+	}
+}
+
+func (f *Field) bits(raw uint64) uint64 {
+	return raw >> uint(f.BitOffset) & (1<<uint(f.Bits) - 1)
+}
+
+func (p *Parser) parseTimestamp(v interface{}) time.Time {
+	unit := map[string]float64{
+		"unix":    float64(time.Second),
+		"unix_ms": float64(time.Millisecond),
+		"unix_us": float64(time.Microsecond),
+		"unix_ns": 1,
+	}[p.TimestampFormat]
+	switch n := v.(type) {
+	case int64:
+		return time.Unix(0, n*int64(unit))
+	case uint64:
+		return time.Unix(0, int64(n)*int64(unit))
+	default:
+		return time.Unix(0, int64(n.(float64)*unit))
+	}
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("expected 1 metric, got %d", len(metrics))
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,157 @@
+package binary
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1500000000, 0)
+
+func newParser(t *testing.T, p *Parser) *Parser {
+	p.MetricName = "telemetry"
+	p.TimeFunc = func() time.Time { return now }
+	require.NoError(t, p.Init())
+	return p
+}
+
+// sensorFields is the layout of the records written by sensorRecord.
+var sensorFields = []Field{
+	{Name: "device", Offset: 0, Length: 2, Type: "uint"},
+	{Name: "temperature", Offset: 2, Length: 2, Type: "int", Endianness: "little", Scale: 0.1},
+	{Name: "pressure", Offset: 4, Length: 4, Type: "float"},
+	{Name: "running", Offset: 8, Length: 1, Type: "bool"},
+	{Name: "mode", Offset: 9, Length: 1, Type: "bitfield", BitOffset: 4, Bits: 3},
+	{Name: "alarm", Offset: 9, Length: 1, Type: "bool", BitOffset: 7, Bits: 1},
+	{Name: "time", Offset: 10, Length: 4, Type: "uint"},
+}
+
+func sensorRecord(device uint16, temperature int16, pressure float32, running bool, flags byte, timestamp uint32) []byte {
+	record := make([]byte, 16)
+	binary.BigEndian.PutUint16(record[0:], device)
+	binary.LittleEndian.PutUint16(record[2:], uint16(temperature))
+	binary.BigEndian.PutUint32(record[4:], math.Float32bits(pressure))
+	if running {
+		record[8] = 1
+	}
+	record[9] = flags
+	binary.BigEndian.PutUint32(record[10:], timestamp)
+	return record
+}
+
+func sensorMetric(fields map[string]interface{}, tm time.Time) telegraf.Metric {
+	return testutil.MustMetric("telemetry", map[string]string{"site": "plant"}, fields, tm)
+}
+
+func TestParseRecords(t *testing.T) {
+	p := newParser(t, &Parser{
+		RecordLength:   16,
+		Fields:         sensorFields,
+		TimestampField: "time",
+		DefaultTags:    map[string]string{"site": "plant"},
+	})
+
+	var buf []byte
+	buf = append(buf, sensorRecord(258, -253, 1.5, true, 0xB0, 1500000001)...)
+	buf = append(buf, sensorRecord(7, 215, -0.25, false, 0x40, 1500000002)...)
+	metrics, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		sensorMetric(map[string]interface{}{
+			"device":      uint64(258),
+			"temperature": -253 * 0.1,
+			"pressure":    1.5,
+			"running":     true,
+			"mode":        uint64(3),
+			"alarm":       true,
+		}, time.Unix(1500000001, 0)),
+		sensorMetric(map[string]interface{}{
+			"device":      uint64(7),
+			"temperature": 215 * 0.1,
+			"pressure":    -0.25,
+			"running":     false,
+			"mode":        uint64(4),
+			"alarm":       false,
+		}, time.Unix(1500000002, 0)),
+	}, metrics)
+}
+
+func TestParsePartialRecord(t *testing.T) {
+	p := newParser(t, &Parser{
+		Endianness: "little",
+		Fields: []Field{
+			{Name: "counter", Offset: 0, Length: 4, Type: "int"},
+			{Name: "ratio", Offset: 4, Length: 8, Type: "float", Endianness: "big"},
+		},
+	})
+
+	record := func(counter int32, ratio float64) []byte {
+		b := make([]byte, 12)
+		binary.LittleEndian.PutUint32(b, uint32(counter))
+		binary.BigEndian.PutUint64(b[4:], math.Float64bits(ratio))
+		return b
+	}
+	buf := append(record(-1, 0.5), record(2, 0.75)...)
+
+	// the partial record at the end is completed by the next buffer
+	metrics, err := p.Parse(buf[:17])
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"counter": int64(-1), "ratio": 0.5}, metrics[0].Fields())
+	require.Equal(t, now, metrics[0].Time())
+
+	metrics, err = p.Parse(buf[17:20])
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+
+	metrics, err = p.Parse(buf[20:])
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"counter": int64(2), "ratio": 0.75}, metrics[0].Fields())
+}
+
+func TestParseTimestampFormat(t *testing.T) {
+	p := newParser(t, &Parser{
+		Fields: []Field{
+			{Name: "time", Offset: 0, Length: 8, Type: "int"},
+			{Name: "value", Offset: 8, Length: 1, Type: "uint"},
+		},
+		TimestampField:  "time",
+		TimestampFormat: "unix_ms",
+	})
+
+	buf := make([]byte, 9)
+	binary.BigEndian.PutUint64(buf, 1500000000123)
+	buf[8] = 42
+	m, err := p.ParseLine(string(buf))
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1500000000, 123000000), m.Time())
+	require.Equal(t, map[string]interface{}{"value": uint64(42)}, m.Fields())
+}
+
+func TestInitInvalid(t *testing.T) {
+	value := Field{Name: "value", Offset: 0, Length: 2, Type: "uint"}
+	for _, p := range []*Parser{
+		{},
+		{Fields: []Field{value}, Endianness: "middle"},
+		{Fields: []Field{value, value}},
+		{Fields: []Field{{Offset: 0, Length: 2, Type: "uint"}}},
+		{Fields: []Field{{Name: "value", Offset: 0, Length: 2, Type: "string"}}},
+		{Fields: []Field{{Name: "value", Offset: 0, Length: 9, Type: "int"}}},
+		{Fields: []Field{{Name: "value", Offset: 0, Length: 2, Type: "float"}}},
+		{Fields: []Field{{Name: "value", Offset: 0, Length: 1, Type: "bitfield", BitOffset: 4, Bits: 5}}},
+		{Fields: []Field{{Name: "value", Offset: 0, Length: 1, Type: "uint", Bits: 1}}},
+		{Fields: []Field{{Name: "value", Offset: 0, Length: 1, Type: "bool", Scale: 2}}},
+		{Fields: []Field{value}, RecordLength: 1},
+		{Fields: []Field{value}, TimestampField: "time"},
+		{Fields: []Field{value}, TimestampFormat: "rfc3339"},
+	} {
+		require.Error(t, p.Init())
+	}
+}
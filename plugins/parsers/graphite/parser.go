@@ -111,15 +111,31 @@ func (p *GraphiteParser) ParseLine(line string) (telegraf.Metric, error) {
 		return nil, fmt.Errorf("received %q which doesn't have required fields", line)
 	}
 
+	// split the tags of the tagged format from the name
+	name := fields[0]
+	var explicitTags map[string]string
+	if strings.Contains(name, ";") {
+		var err error
+		name, explicitTags, err = parseTaggedName(name)
+		if err != nil {
+			return nil, fmt.Errorf("received %q with %s", line, err)
+		}
+	}
+
 	// decode the name and tags
-	measurement, tags, field, err := p.templateEngine.Apply(fields[0])
+	measurement, tags, field, err := p.templateEngine.Apply(name)
 	if err != nil {
 		return nil, err
 	}
 
 	// Could not extract measurement, use the raw value
 	if measurement == "" {
-		measurement = fields[0]
+		measurement = name
+	}
+
+	// Tags of the tagged format win over the tags of the template
+	for k, v := range explicitTags {
+		tags[k] = v
 	}
 
 	// Parse value.
@@ -169,6 +185,53 @@ func (p *GraphiteParser) ParseLine(line string) (telegraf.Metric, error) {
 	return metric.New(measurement, tags, fieldValues, timestamp)
 }
 
+// parseTaggedName splits a name in the Graphite tagged format,
+// name;tag1=value1;tag2=value2, into the name and its tags.  A backslash
+// escapes a ';', '=' or '\' in the tags.
+func parseTaggedName(s string) (string, map[string]string, error) {
+	parts := splitEscaped(s, ';')
+	tags := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		kv := splitEscaped(part, '=')
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return "", nil, fmt.Errorf("invalid tag %q", part)
+		}
+		tags[unescape(kv[0])] = unescape(kv[1])
+	}
+	return parts[0], tags, nil
+}
+
+// splitEscaped splits s around the separators not escaped by a backslash,
+// the escapes are kept.
+func splitEscaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
 // ApplyTemplate extracts the template fields from the given line and
 // returns the measurement name and tags.
 func (p *GraphiteParser) ApplyTemplate(line string) (string, map[string]string, string, error) {
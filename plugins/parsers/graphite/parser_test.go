@@ -619,6 +619,75 @@ func TestParseDefaultTemplateTagsOverridGlobal(t *testing.T) {
 	require.Equal(t, "1c", value)
 }
 
+func TestParseTaggedLine(t *testing.T) {
+	p, err := NewGraphiteParser("", nil, map[string]string{"region": "us-east"})
+	require.NoError(t, err)
+
+	m, err := p.ParseLine(`cpu.load;host=server01;dc=eu\;1 11 1435077219`)
+	require.NoError(t, err)
+	require.Equal(t, "cpu.load", m.Name())
+	require.Equal(t, map[string]string{
+		"host":   "server01",
+		"dc":     "eu;1",
+		"region": "us-east",
+	}, m.Tags())
+	require.Equal(t, map[string]interface{}{"value": float64(11)}, m.Fields())
+	require.Equal(t, time.Unix(1435077219, 0), m.Time())
+}
+
+func TestParseTaggedLineTemplate(t *testing.T) {
+	p, err := NewGraphiteParser("",
+		[]string{"servers.* .host.measurement* zone=1c"},
+		map[string]string{"host": "should not set"})
+	require.NoError(t, err)
+
+	m, err := p.ParseLine("servers.localhost.cpu_load;host=server01;env=prod 11 1435077219")
+	require.NoError(t, err)
+	require.Equal(t, "cpu_load", m.Name())
+	require.Equal(t, map[string]string{
+		"host": "server01",
+		"env":  "prod",
+		"zone": "1c",
+	}, m.Tags())
+}
+
+func TestParseTaggedLineInvalidTag(t *testing.T) {
+	p, err := NewGraphiteParser("", nil, nil)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		"cpu;host 11 1435077219",
+		"cpu;=server01 11 1435077219",
+		"cpu;host= 11 1435077219",
+		"cpu;host=a=b 11 1435077219",
+	} {
+		_, err := p.ParseLine(line)
+		require.Error(t, err, line)
+	}
+}
+
+func TestParseMixedTaggedAndUntagged(t *testing.T) {
+	p, err := NewGraphiteParser("_",
+		[]string{"servers.* .host.measurement*"}, nil)
+	require.NoError(t, err)
+
+	metrics, err := p.Parse([]byte(
+		"servers.localhost.cpu_load 11 1435077219\n" +
+			"servers.localhost.cpu_load;host=server01 12 1435077220\n" +
+			"mem.used;host=server02 13 1435077221\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	require.Equal(t, "cpu_load", metrics[0].Name())
+	require.Equal(t, map[string]string{"host": "localhost"}, metrics[0].Tags())
+
+	require.Equal(t, "cpu_load", metrics[1].Name())
+	require.Equal(t, map[string]string{"host": "server01"}, metrics[1].Tags())
+
+	require.Equal(t, "mem_used", metrics[2].Name())
+	require.Equal(t, map[string]string{"host": "server02"}, metrics[2].Tags())
+}
+
 func TestParseTemplateWhitespace(t *testing.T) {
 	p, err := NewGraphiteParser("",
 		[]string{"servers.localhost        .host.measurement*           zone=1c"},
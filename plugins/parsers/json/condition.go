@@ -0,0 +1,166 @@
+package json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// condition is a comparison of the value at a path of an object with a
+// literal value, in the form:
+//
+//	<path> <operator> <value>
+//
+// The path is a list of object keys or array indices separated by dots, like
+// the simple GJSON paths, a dot within a key is escaped with a backslash.  The
+// operator is one of ==, !=, <, <=, > or >=.  The value is either a double
+// quoted string, a number, true or false.  Strings and booleans can only be
+// compared for equality.
+type condition struct {
+	path     []string
+	operator string
+	value    interface{}
+}
+
+func parseCondition(expr string) (*condition, error) {
+	i, op := findOperator(expr)
+	if op == "" {
+		return nil, fmt.Errorf("invalid condition %q: missing operator", expr)
+	}
+
+	path := strings.TrimSpace(expr[:i])
+	if path == "" {
+		return nil, fmt.Errorf("invalid condition %q: missing path", expr)
+	}
+	c := &condition{
+		path:     splitPath(path),
+		operator: op,
+	}
+
+	literal := strings.TrimSpace(expr[i+len(op):])
+	switch {
+	case literal == "true":
+		c.value = true
+	case literal == "false":
+		c.value = false
+	case strings.HasPrefix(literal, `"`):
+		str, err := strconv.Unquote(literal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q: invalid string %s", expr, literal)
+		}
+		c.value = str
+	default:
+		num, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q: invalid value %s", expr, literal)
+		}
+		c.value = num
+	}
+
+	if _, ok := c.value.(float64); !ok && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("invalid condition %q: %s can only compare numbers", expr, op)
+	}
+	return c, nil
+}
+
+// findOperator returns the first comparison operator of expr and its
+// position.
+func findOperator(expr string) (int, string) {
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '=', '!', '<', '>':
+		default:
+			continue
+		}
+		if i+1 < len(expr) && expr[i+1] == '=' {
+			return i, expr[i : i+2]
+		}
+		if expr[i] == '<' || expr[i] == '>' {
+			return i, expr[i : i+1]
+		}
+	}
+	return -1, ""
+}
+
+// splitPath splits the path on the dots not escaped by a backslash.
+func splitPath(path string) []string {
+	var keys []string
+	var key []byte
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			key = append(key, path[i])
+		case path[i] == '.':
+			keys = append(keys, string(key))
+			key = key[:0]
+		default:
+			key = append(key, path[i])
+		}
+	}
+	return append(keys, string(key))
+}
+
+// lookup returns the value at the path of the decoded JSON value.
+func (c *condition) lookup(value interface{}) (interface{}, bool) {
+	for _, key := range c.path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// match reports if the condition holds for the decoded JSON object, a missing
+// value never matches.
+func (c *condition) match(obj map[string]interface{}) bool {
+	result, ok := c.lookup(obj)
+	if !ok {
+		return false
+	}
+
+	num, isNumber := c.value.(float64)
+	if !isNumber {
+		var equal bool
+		switch r := result.(type) {
+		case string, bool:
+			equal = r == c.value
+		}
+		if c.operator == "==" {
+			return equal
+		}
+		return !equal
+	}
+
+	n, ok := result.(float64)
+	if !ok {
+		return c.operator == "!="
+	}
+	switch c.operator {
+	case "==":
+		return n == num
+	case "!=":
+		return n != num
+	case "<":
+		return n < num
+	case "<=":
+		return n <= num
+	case ">":
+		return n > num
+	case ">=":
+		return n >= num
+	}
+	return false
+}
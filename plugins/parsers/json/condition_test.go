@@ -0,0 +1,63 @@
+package json
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionMatch(t *testing.T) {
+	var obj map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"unit": "celsius", "value": 21.5, "ok": true, "sensor": {"id": 3},
+		"readings": [{"value": 1}], "dotted.key": "x", "empty": null
+	}`), &obj)
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{`unit == "celsius"`, true},
+		{`unit != "celsius"`, false},
+		{`unit == "fahrenheit"`, false},
+		{`value > 20`, true},
+		{`value >= 21.5`, true},
+		{`value < 21.5`, false},
+		{`value <= 21.5`, true},
+		{`value == 21.5`, true},
+		{`sensor.id == 3`, true},
+		{`ok == true`, true},
+		{`ok == false`, false},
+		{`unit == 1`, false},
+		{`unit != 1`, true},
+		{`missing == "x"`, false},
+		{`missing != "x"`, false},
+		{`readings.0.value == 1`, true},
+		{`readings.1.value == 1`, false},
+		{`dotted\.key == "x"`, true},
+		{`sensor == "x"`, false},
+		{`sensor != true`, true},
+		{`empty == "x"`, false},
+	}
+
+	for _, tt := range tests {
+		c, err := parseCondition(tt.expr)
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.match, c.match(obj), tt.expr)
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	for _, expr := range []string{
+		`unit`,
+		`== "celsius"`,
+		`unit == celsius`,
+		`unit < "celsius"`,
+		`unit == "celsius`,
+	} {
+		_, err := parseCondition(expr)
+		require.Error(t, err, expr)
+	}
+}
@@ -29,20 +29,88 @@ type JSONParser struct {
 	JSONQuery      string
 	JSONTimeKey    string
 	JSONTimeFormat string
-	DefaultTags    map[string]string
+	// JSONArrayIndexTags are the names of the tags set to the index of the
+	// element within a JSON array, one for each level of nested arrays.
+	JSONArrayIndexTags []string
+	// JSONCondition skips the objects for which the condition does not hold,
+	// it is parsed by Compile.
+	JSONCondition string
+	DefaultTags   map[string]string
+
+	condition *condition
+}
+
+// Compile parses the JSONCondition, it must be called before the parser is
+// used if JSONCondition is set.
+func (p *JSONParser) Compile() error {
+	p.condition = nil
+	if p.JSONCondition == "" {
+		return nil
+	}
+	condition, err := parseCondition(p.JSONCondition)
+	if err != nil {
+		return err
+	}
+	p.condition = condition
+	return nil
 }
 
 func (p *JSONParser) parseArray(buf []byte) ([]telegraf.Metric, error) {
-	metrics := make([]telegraf.Metric, 0)
+	if len(p.JSONArrayIndexTags) == 0 {
+		metrics := make([]telegraf.Metric, 0)
+
+		var jsonOut []map[string]interface{}
+		err := json.Unmarshal(buf, &jsonOut)
+		if err != nil {
+			err = fmt.Errorf("unable to parse out as JSON Array, %s", err)
+			return nil, err
+		}
+		for _, item := range jsonOut {
+			metrics, err = p.parseObject(metrics, item, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return metrics, nil
+	}
 
-	var jsonOut []map[string]interface{}
+	var jsonOut []interface{}
 	err := json.Unmarshal(buf, &jsonOut)
 	if err != nil {
 		err = fmt.Errorf("unable to parse out as JSON Array, %s", err)
 		return nil, err
 	}
-	for _, item := range jsonOut {
-		metrics, err = p.parseObject(metrics, item)
+	return p.parseArrayElements(make([]telegraf.Metric, 0), jsonOut, nil)
+}
+
+// parseArrayElements parses each object of the array into a metric, nested
+// arrays are parsed recursively when index tags are set.  indexTags holds the index tags of the
+// enclosing arrays.
+func (p *JSONParser) parseArrayElements(
+	metrics []telegraf.Metric,
+	jsonOut []interface{},
+	indexTags map[string]string,
+) ([]telegraf.Metric, error) {
+	level := len(indexTags)
+	var err error
+	for i, item := range jsonOut {
+		tags := indexTags
+		if level < len(p.JSONArrayIndexTags) {
+			tags = make(map[string]string, level+1)
+			for k, v := range indexTags {
+				tags[k] = v
+			}
+			tags[p.JSONArrayIndexTags[level]] = strconv.Itoa(i)
+		}
+
+		switch item := item.(type) {
+		case map[string]interface{}:
+			metrics, err = p.parseObject(metrics, item, tags)
+		case []interface{}:
+			metrics, err = p.parseArrayElements(metrics, item, tags)
+		default:
+			err = fmt.Errorf("unable to parse out as JSON Array, got element of type %T", item)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -93,11 +161,18 @@ func parseUnixTimestamp(jsonValue interface{}, format string) (time.Time, error)
 	}
 }
 
-func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]interface{}) ([]telegraf.Metric, error) {
+func (p *JSONParser) parseObject(metrics []telegraf.Metric, jsonOut map[string]interface{}, indexTags map[string]string) ([]telegraf.Metric, error) {
+	if p.condition != nil && !p.condition.match(jsonOut) {
+		return metrics, nil
+	}
+
 	tags := make(map[string]string)
 	for k, v := range p.DefaultTags {
 		tags[k] = v
 	}
+	for k, v := range indexTags {
+		tags[k] = v
+	}
 
 	f := JSONFlattener{}
 	err := f.FullFlattenJSON("", jsonOut, true, true)
@@ -208,6 +283,10 @@ func (p *JSONParser) switchFieldToTag(tags map[string]string, fields map[string]
 }
 
 func (p *JSONParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if p.JSONCondition != "" && p.condition == nil {
+		return nil, errors.New("json_condition is set but the parser is not compiled")
+	}
+
 	if p.JSONQuery != "" {
 		result := gjson.GetBytes(buf, p.JSONQuery)
 		buf = []byte(result.Raw)
@@ -231,7 +310,7 @@ func (p *JSONParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 			err = fmt.Errorf("unable to parse out as JSON, %s", err)
 			return nil, err
 		}
-		return p.parseObject(metrics, jsonOut)
+		return p.parseObject(metrics, jsonOut, nil)
 	}
 	return p.parseArray(buf)
 }
@@ -751,3 +751,118 @@ func TestTimeKeyDelete(t *testing.T) {
 
 	testutil.RequireMetricsEqual(t, expected, metrics)
 }
+
+func TestArrayIndexTags(t *testing.T) {
+	data := `[
+		{"sensor": "inlet", "temperature": 21.5, "timestamp": 1541183052},
+		{"sensor": "outlet", "temperature": 24.0, "timestamp": 1541183053}
+	]`
+
+	parser := JSONParser{
+		MetricName:         "json",
+		TagKeys:            []string{"sensor"},
+		JSONTimeKey:        "timestamp",
+		JSONTimeFormat:     "unix",
+		JSONArrayIndexTags: []string{"index"},
+	}
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("json",
+			map[string]string{"index": "0", "sensor": "inlet"},
+			map[string]interface{}{"temperature": 21.5},
+			time.Unix(1541183052, 0)),
+		testutil.MustMetric("json",
+			map[string]string{"index": "1", "sensor": "outlet"},
+			map[string]interface{}{"temperature": 24.0},
+			time.Unix(1541183053, 0)),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestArrayIndexTagsNested(t *testing.T) {
+	data := `{
+		"rack": [
+			[{"temperature": 21.5, "t": 1}, {"temperature": 22.0, "t": 2}],
+			[{"temperature": 24.0, "t": 3}]
+		]
+	}`
+
+	parser := JSONParser{
+		MetricName:         "json",
+		JSONQuery:          "rack",
+		JSONTimeKey:        "t",
+		JSONTimeFormat:     "unix",
+		JSONArrayIndexTags: []string{"shelf", "slot"},
+	}
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("json",
+			map[string]string{"shelf": "0", "slot": "0"},
+			map[string]interface{}{"temperature": 21.5},
+			time.Unix(1, 0)),
+		testutil.MustMetric("json",
+			map[string]string{"shelf": "0", "slot": "1"},
+			map[string]interface{}{"temperature": 22.0},
+			time.Unix(2, 0)),
+		testutil.MustMetric("json",
+			map[string]string{"shelf": "1", "slot": "0"},
+			map[string]interface{}{"temperature": 24.0},
+			time.Unix(3, 0)),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestCondition(t *testing.T) {
+	data := `[
+		{"sensor": "inlet", "unit": "celsius", "value": 21.5, "timestamp": 1541183052},
+		{"sensor": "inlet", "unit": "fahrenheit", "value": 70.7, "timestamp": 1541183052},
+		{"sensor": "outlet", "value": 24.0, "timestamp": 1541183053}
+	]`
+
+	parser := JSONParser{
+		MetricName:     "json",
+		TagKeys:        []string{"sensor"},
+		JSONTimeKey:    "timestamp",
+		JSONTimeFormat: "unix",
+		JSONCondition:  `unit == "celsius"`,
+	}
+	require.NoError(t, parser.Compile())
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("json",
+			map[string]string{"sensor": "inlet"},
+			map[string]interface{}{"value": 21.5},
+			time.Unix(1541183052, 0)),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestConditionInvalid(t *testing.T) {
+	parser := JSONParser{
+		MetricName:    "json",
+		JSONCondition: "unit",
+	}
+	require.EqualError(t, parser.Compile(), `invalid condition "unit": missing operator`)
+
+	_, err := parser.Parse([]byte(`{"value": 42}`))
+	require.Error(t, err)
+}
+
+func TestNestedArrayWithoutIndexTags(t *testing.T) {
+	parser := JSONParser{
+		MetricName: "json",
+	}
+
+	_, err := parser.Parse([]byte(`[[{"temperature": 21.5}], [{"temperature": 24.0}]]`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to parse out as JSON Array")
+}
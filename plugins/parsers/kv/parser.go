@@ -0,0 +1,265 @@
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var (
+	ErrNoMetric = fmt.Errorf("no metric in line")
+)
+
+// Parser decodes lines of key/value pairs into metrics, a metric per line.
+// The pairs are separated by PairSeparator and their key and value by
+// KeyValueSeparator, the values quoted with double or single quotes may
+// contain both separators.
+type Parser struct {
+	MetricName string
+	// KeyValueSeparator separates the keys from their values, "=" by default
+	KeyValueSeparator string
+	// PairSeparator separates the pairs, " " by default.  A space separator
+	// matches any run of spaces and tabs.
+	PairSeparator string
+	// TagKeys are the keys added as tags
+	TagKeys []string
+	// IntKeys and StringKeys are the keys added as integers and strings, the
+	// type of the others is determined from their values
+	IntKeys     []string
+	StringKeys  []string
+	DefaultTags map[string]string
+
+	Now func() time.Time
+
+	tagKeys    map[string]bool
+	intKeys    map[string]bool
+	stringKeys map[string]bool
+}
+
+// Init checks the separators of the parser.
+func (p *Parser) Init() error {
+	if p.KeyValueSeparator == "" {
+		p.KeyValueSeparator = "="
+	}
+	if p.PairSeparator == "" {
+		p.PairSeparator = " "
+	}
+	if p.KeyValueSeparator == p.PairSeparator {
+		return fmt.Errorf("key/value and pair separators must differ")
+	}
+	if strings.ContainsAny(p.KeyValueSeparator+p.PairSeparator, `"'\`) {
+		return fmt.Errorf("separators must not contain quotes or backslashes")
+	}
+	if p.Now == nil {
+		p.Now = time.Now
+	}
+
+	p.tagKeys = toSet(p.TagKeys)
+	p.intKeys = toSet(p.IntKeys)
+	p.stringKeys = toSet(p.StringKeys)
+	return nil
+}
+
+func toSet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+// Parse converts a slice of bytes of key/value lines to metrics.
+func (p *Parser) Parse(b []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		m, err := p.parseLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// ParseLine converts a single line of key/value pairs to a metric.
+func (p *Parser) ParseLine(s string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+// SetDefaultTags adds tags to the metrics outputs of Parse and ParseLine.
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// parseLine returns the metric of the pairs of the line, or nil if the line
+// has none.  The words without a separator and the empty values are skipped.
+func (p *Parser) parseLine(line string) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+
+	s := line
+	for {
+		s = p.trimPairSeparators(s)
+		if s == "" {
+			break
+		}
+
+		var key, value string
+		var err error
+		key, s, err = p.scanKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("%v in line %q", err, line)
+		}
+		if key == "" {
+			continue
+		}
+		value, s, err = p.scanValue(s)
+		if err != nil {
+			return nil, fmt.Errorf("%v in line %q", err, line)
+		}
+		if value == "" {
+			continue
+		}
+
+		switch {
+		case p.tagKeys[key]:
+			tags[key] = value
+		case p.intKeys[key]:
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value %q of key %q is not an integer", value, key)
+			}
+			fields[key] = v
+		case p.stringKeys[key]:
+			fields[key] = value
+		default:
+			fields[key] = convert(value)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return metric.New(p.MetricName, tags, fields, p.Now())
+}
+
+// trimPairSeparators removes the pair separators and, when the separator is
+// not a space, the spaces at the start of s.
+func (p *Parser) trimPairSeparators(s string) string {
+	for {
+		trimmed := strings.TrimLeft(s, " \t")
+		trimmed = strings.TrimPrefix(trimmed, p.PairSeparator)
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+// scanKey returns the key at the start of s and the rest of s after the
+// key/value separator.  The key is empty for a word without a separator,
+// the rest of s is then after the word.
+func (p *Parser) scanKey(s string) (string, string, error) {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], p.KeyValueSeparator):
+			return strings.TrimSpace(s[:i]), s[i+len(p.KeyValueSeparator):], nil
+		case p.isPairSeparator(s[i:]):
+			return "", s[i:], nil
+		case s[i] == '"' || s[i] == '\'':
+			return "", "", fmt.Errorf("unexpected quote in key")
+		}
+	}
+	return "", "", nil
+}
+
+// scanValue returns the value at the start of s, unquoted, and the rest of s
+// after the value.
+func (p *Parser) scanValue(s string) (string, string, error) {
+	s = strings.TrimLeft(s, " \t")
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		value, rest, err := unquote(s)
+		if err != nil {
+			return "", "", err
+		}
+		if strings.TrimSpace(rest) != "" && !p.isPairSeparator(rest) &&
+			!p.isPairSeparator(strings.TrimLeft(rest, " \t")) {
+			return "", "", fmt.Errorf("missing pair separator after quoted value")
+		}
+		return value, rest, nil
+	}
+
+	for i := 0; i < len(s); i++ {
+		if p.isPairSeparator(s[i:]) {
+			return strings.TrimSpace(s[:i]), s[i:], nil
+		}
+	}
+	return strings.TrimSpace(s), "", nil
+}
+
+func (p *Parser) isPairSeparator(s string) bool {
+	if p.PairSeparator == " " {
+		return s != "" && (s[0] == ' ' || s[0] == '\t')
+	}
+	return strings.HasPrefix(s, p.PairSeparator)
+}
+
+// unquote returns the value quoted at the start of s and the rest of s after
+// the closing quote.  A backslash escapes the quote and the backslash, the
+// other backslashes are kept.
+func unquote(s string) (string, string, error) {
+	quote := s[0]
+	var value bytes.Buffer
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == quote || s[i+1] == '\\'):
+			value.WriteByte(s[i+1])
+			i++
+		case c == quote:
+			return value.String(), s[i+1:], nil
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted value")
+}
+
+// convert returns the value as an integer, a float or a boolean if it is
+// one, like the logfmt parser.
+func convert(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseBool(value); err == nil {
+		return v
+	}
+	return value
+}
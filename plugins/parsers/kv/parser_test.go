@@ -0,0 +1,146 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newParser(t *testing.T, p *Parser) *Parser {
+	p.MetricName = "kv"
+	p.Now = func() time.Time { return time.Unix(0, 0) }
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		parser   *Parser
+		input    string
+		expected []telegraf.Metric
+	}{
+		{
+			name:   "logfmt like",
+			parser: &Parser{},
+			input:  `method=GET status=200 duration=0.25 cached=true msg="not found"` + "\n",
+			expected: []telegraf.Metric{
+				testutil.MustMetric("kv",
+					map[string]string{},
+					map[string]interface{}{
+						"method":   "GET",
+						"status":   int64(200),
+						"duration": 0.25,
+						"cached":   true,
+						"msg":      "not found",
+					},
+					time.Unix(0, 0)),
+			},
+		},
+		{
+			name:   "colon separated",
+			parser: &Parser{KeyValueSeparator: ":"},
+			input:  `level:warn time:"12:30:05" count:3 path:'/var/log/a b.log'`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("kv",
+					map[string]string{},
+					map[string]interface{}{
+						"level": "warn",
+						"time":  "12:30:05",
+						"count": int64(3),
+						"path":  "/var/log/a b.log",
+					},
+					time.Unix(0, 0)),
+			},
+		},
+		{
+			name:   "semicolon delimited",
+			parser: &Parser{KeyValueSeparator: ":", PairSeparator: ";"},
+			input: `host: web01; url: "/search?q=a;b"; bytes: 1653;;` + "\n" +
+				`host: web02; msg: "say \"hi\"; bye"`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("kv",
+					map[string]string{},
+					map[string]interface{}{
+						"host":  "web01",
+						"url":   "/search?q=a;b",
+						"bytes": int64(1653),
+					},
+					time.Unix(0, 0)),
+				testutil.MustMetric("kv",
+					map[string]string{},
+					map[string]interface{}{
+						"host": "web02",
+						"msg":  `say "hi"; bye`,
+					},
+					time.Unix(0, 0)),
+			},
+		},
+		{
+			name: "type hints",
+			parser: &Parser{
+				PairSeparator: ",",
+				TagKeys:       []string{"host"},
+				IntKeys:       []string{"code"},
+				StringKeys:    []string{"version"},
+			},
+			input: `host=web01, code=404, version=1.10, bare, empty=`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("kv",
+					map[string]string{"host": "web01"},
+					map[string]interface{}{
+						"code":    int64(404),
+						"version": "1.10",
+					},
+					time.Unix(0, 0)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := newParser(t, tt.parser).Parse([]byte(tt.input))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, tt.expected, metrics)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser *Parser
+		input  string
+	}{
+		{"unterminated quote", &Parser{}, `msg="not found status=404`},
+		{"value after quote", &Parser{PairSeparator: ";"}, `msg="a"b; status=404`},
+		{"not an integer", &Parser{IntKeys: []string{"status"}}, `status=ok`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newParser(t, tt.parser).Parse([]byte(tt.input))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseLineDefaultTags(t *testing.T) {
+	p := newParser(t, &Parser{DefaultTags: map[string]string{"source": "app", "host": "default"}})
+	p.TagKeys = []string{"host"}
+	require.NoError(t, p.Init())
+
+	m, err := p.ParseLine(`host=web01 status=200`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"source": "app", "host": "web01"}, m.Tags())
+
+	_, err = p.ParseLine(`no pairs here`)
+	require.Equal(t, ErrNoMetric, err)
+}
+
+func TestInitInvalidSeparators(t *testing.T) {
+	require.Error(t, (&Parser{KeyValueSeparator: ";", PairSeparator: ";"}).Init())
+	require.Error(t, (&Parser{PairSeparator: `"`}).Init())
+}
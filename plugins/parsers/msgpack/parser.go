@@ -0,0 +1,440 @@
+package msgpack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// maxDepth is the maximum nesting of the skipped arrays and maps.
+const maxDepth = 32
+
+// maxMetricSize is the maximum size of a metric read by a Decoder.
+const maxMetricSize = 16 * 1024 * 1024
+
+// ErrNeedMore is returned by ParseNext when the buffer ends within a metric.
+var ErrNeedMore = errors.New("need more data")
+
+// Parser decodes the metrics encoded as MessagePack maps by the msgpack
+// serializer.  A buffer can hold several metrics one after another.
+type Parser struct {
+	DefaultTags map[string]string
+}
+
+func NewParser(defaultTags map[string]string) *Parser {
+	return &Parser{DefaultTags: defaultTags}
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for pos := 0; pos < len(buf); {
+		m, n, err := p.ParseNext(buf[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("metric %d at byte %d: %v", len(metrics), pos, err)
+		}
+		metrics = append(metrics, m)
+		pos += n
+	}
+	return metrics, nil
+}
+
+// ParseNext decodes the first metric of the buffer and returns the number of
+// bytes it used.  ErrNeedMore is returned when the buffer ends before the
+// metric, so that it can be called again once more data is read.
+func (p *Parser) ParseNext(buf []byte) (telegraf.Metric, int, error) {
+	d := &decoder{buf: buf}
+	m, err := p.decodeMetric(d)
+	if err == errShort {
+		return nil, 0, ErrNeedMore
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return m, d.pos, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("expected 1 metric, got %d", len(metrics))
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) decodeMetric(d *decoder) (telegraf.Metric, error) {
+	n, err := d.readMapHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	var name string
+	var tm time.Time
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "name":
+			name, err = d.readString()
+		case "time":
+			tm, err = d.readTime()
+		case "tags":
+			err = d.readStringMap(func(k string) error {
+				v, err := d.readString()
+				tags[k] = v
+				return err
+			})
+		case "fields":
+			err = d.readStringMap(func(k string) error {
+				v, err := d.readValue()
+				if v != nil {
+					fields[k] = v
+				}
+				return err
+			})
+		default:
+			_, err = d.readValue()
+		}
+		if err != nil && err != errShort {
+			err = fmt.Errorf("%s: %v", key, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("no name")
+	}
+	if tm.IsZero() {
+		return nil, fmt.Errorf("no time")
+	}
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+	return metric.New(name, tags, fields, tm)
+}
+
+// errShort is returned when the data ends within a value, and is kept
+// unwrapped up to ParseNext.
+var errShort = errors.New("unexpected end of data")
+
+type decoder struct {
+	buf   []byte
+	pos   int
+	depth int
+}
+
+func (d *decoder) readN(n int) ([]byte, error) {
+	if n < 0 || len(d.buf)-d.pos < n {
+		return nil, errShort
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) readByte() (byte, error) {
+	b, err := d.readN(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// readUint reads a big endian unsigned integer of n bytes.
+func (d *decoder) readUint(n int) (uint64, error) {
+	b, err := d.readN(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *decoder) readMapHeader() (int, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	switch {
+	case c&0xf0 == 0x80:
+		return int(c & 0x0f), nil
+	case c == 0xde:
+		n, err = d.readUint(2)
+	case c == 0xdf:
+		n, err = d.readUint(4)
+	default:
+		return 0, fmt.Errorf("expected a map, got 0x%02x", c)
+	}
+	return int(n), err
+}
+
+func (d *decoder) readStringMap(value func(key string) error) error {
+	n, err := d.readMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return err
+		}
+		err = value(key)
+		if err != nil && err != errShort {
+			err = fmt.Errorf("%s: %v", key, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) readString() (string, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return "", err
+	}
+	s, ok, err := d.readStringOf(c)
+	if err == nil && !ok {
+		err = fmt.Errorf("expected a string, got 0x%02x", c)
+	}
+	return s, err
+}
+
+// readStringOf reads the string of format c, ok is false for the other
+// formats.
+func (d *decoder) readStringOf(c byte) (s string, ok bool, err error) {
+	var n uint64
+	switch {
+	case c&0xe0 == 0xa0:
+		n = uint64(c & 0x1f)
+	case c == 0xd9:
+		n, err = d.readUint(1)
+	case c == 0xda:
+		n, err = d.readUint(2)
+	case c == 0xdb:
+		n, err = d.readUint(4)
+	default:
+		return "", false, nil
+	}
+	if err != nil {
+		return "", true, err
+	}
+	b, err := d.readN(int(n))
+	return string(b), true, err
+}
+
+func (d *decoder) readTime() (time.Time, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var n int
+	switch c {
+	case 0xd6:
+		n = 4
+	case 0xd7:
+		n = 8
+	case 0xc7:
+		size, err := d.readByte()
+		if err != nil {
+			return time.Time{}, err
+		}
+		n = int(size)
+	default:
+		return time.Time{}, fmt.Errorf("expected a timestamp, got 0x%02x", c)
+	}
+	ext, err := d.readByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ext != 0xff {
+		return time.Time{}, fmt.Errorf("expected a timestamp, got extension %d", int8(ext))
+	}
+
+	switch n {
+	case 4:
+		sec, err := d.readUint(4)
+		return time.Unix(int64(sec), 0), err
+	case 8:
+		v, err := d.readUint(8)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), err
+	case 12:
+		nsec, err := d.readUint(4)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, err := d.readUint(8)
+		return time.Unix(int64(sec), int64(nsec)), err
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp of %d bytes", n)
+	}
+}
+
+// readValue reads a field value.  nil is returned for nil, and the values
+// telegraf can not hold like arrays are skipped.
+func (d *decoder) readValue() (interface{}, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if s, ok, err := d.readStringOf(c); ok {
+		return s, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return nil, d.skip(2 * int(c&0x0f))
+	case c&0xf0 == 0x90:
+		return nil, d.skip(int(c & 0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.readUint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+		v, err := d.readUint(n)
+		// sign extension
+		shift := uint(64 - 8*n)
+		return int64(v<<shift) >> shift, err
+	case 0xca:
+		v, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.readUint(8)
+		return math.Float64frombits(v), err
+	case 0xc4, 0xc5, 0xc6:
+		// binary data is kept as a string
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.readN(int(n))
+		return string(b), err
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return nil, d.skip(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return nil, d.skip(2 * int(n))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		_, err := d.readN(1 + 1<<(c-0xd4))
+		return nil, err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		_, err = d.readN(1 + int(n))
+		return nil, err
+	default:
+		return nil, fmt.Errorf("invalid format 0x%02x", c)
+	}
+}
+
+// skip skips n values.
+func (d *decoder) skip(n int) error {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxDepth {
+		return fmt.Errorf("values nested too deeply")
+	}
+	for i := 0; i < n; i++ {
+		if _, err := d.readValue(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decoder reads the metrics from a stream of concatenated maps, like a
+// connection.
+type Decoder struct {
+	parser *Parser
+	r      io.Reader
+	buf    []byte
+	pos    int
+}
+
+func NewDecoder(r io.Reader, parser *Parser) *Decoder {
+	return &Decoder{parser: parser, r: r}
+}
+
+// Decode returns the next metric of the stream, reading more data as needed.
+// io.EOF is returned at the end of the stream, and io.ErrUnexpectedEOF when
+// it ends within a metric.
+func (d *Decoder) Decode() (telegraf.Metric, error) {
+	for {
+		if d.pos < len(d.buf) {
+			m, n, err := d.parser.ParseNext(d.buf[d.pos:])
+			if err == nil {
+				d.pos += n
+				return m, nil
+			}
+			if err != ErrNeedMore {
+				return nil, err
+			}
+			if len(d.buf)-d.pos >= maxMetricSize {
+				return nil, fmt.Errorf("metric larger than %d bytes", maxMetricSize)
+			}
+		}
+
+		// keep the start of the partial metric and read more data after it
+		n := copy(d.buf, d.buf[d.pos:])
+		d.buf, d.pos = d.buf[:n], 0
+		if cap(d.buf)-n < 4096 {
+			buf := make([]byte, n, 2*cap(d.buf)+4096)
+			copy(buf, d.buf)
+			d.buf = buf
+		}
+		read, err := d.r.Read(d.buf[n:cap(d.buf)])
+		d.buf = d.buf[:n+read]
+		if read > 0 || err == nil {
+			continue
+		}
+		if err == io.EOF && len(d.buf) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,243 @@
+package msgpack
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/msgpack"
+)
+
+func MustMetric(v telegraf.Metric, err error) telegraf.Metric {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestRoundTrip(t *testing.T) {
+	metrics := []telegraf.Metric{
+		MustMetric(metric.New(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{
+				"int":      int64(-1 << 40),
+				"small":    int64(3),
+				"uint":     uint64(1 << 63),
+				"float":    91.5,
+				"bool":     false,
+				"string":   "idle",
+				"empty":    "",
+				"long_str": string(make([]byte, 300)),
+			},
+			time.Unix(1500000000, 123),
+		)),
+		MustMetric(metric.New(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": uint64(0)},
+			time.Unix(-5, 0),
+		)),
+	}
+
+	s := serializer.NewSerializer()
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	parser := NewParser(nil)
+	parsed, err := parser.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, parsed, len(metrics))
+	for i, m := range metrics {
+		require.Equal(t, m.Name(), parsed[i].Name())
+		require.Equal(t, m.Tags(), parsed[i].Tags())
+		require.Equal(t, m.Fields(), parsed[i].Fields())
+		require.True(t, m.Time().Equal(parsed[i].Time()))
+	}
+
+	again, err := s.SerializeBatch(parsed)
+	require.NoError(t, err)
+	require.Equal(t, buf, again)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	m := MustMetric(metric.New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"value": int64(1)}, time.Unix(0, 0)))
+	buf, err := serializer.NewSerializer().Serialize(m)
+	require.NoError(t, err)
+
+	parser := NewParser(map[string]string{"host": "default", "dc": "east"})
+	parsed, err := parser.ParseLine(string(buf))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"host": "a", "dc": "east"}, parsed.Tags())
+}
+
+func TestParseOtherFormats(t *testing.T) {
+	buf := []byte{
+		0x84,
+		0xa4, 'n', 'a', 'm', 'e', 0xd9, 0x03, 'c', 'p', 'u',
+		0xa4, 't', 'i', 'm', 'e', 0xd6, 0xff, 0x00, 0x00, 0x00, 0x01,
+		// unknown keys are ignored
+		0xa5, 'e', 'x', 't', 'r', 'a', 0x92, 0x01, 0x81, 0xa1, 'k', 0xc0,
+		0xa6, 'f', 'i', 'e', 'l', 'd', 's', 0x85,
+		0xa1, 'f', 0xca, 0x3f, 0xc0, 0x00, 0x00,
+		0xa1, 'b', 0xc4, 0x02, 'h', 'i',
+		0xa1, 'n', 0xc0,
+		0xa1, 'a', 0x91, 0x01,
+		0xa1, 'i', 0xd1, 0xff, 0x38,
+	}
+
+	parser := NewParser(nil)
+	m, err := parser.ParseLine(string(buf))
+	require.NoError(t, err)
+	require.Equal(t, "cpu", m.Name())
+	require.Equal(t, time.Unix(1, 0), m.Time())
+	require.Equal(t, map[string]interface{}{
+		"f": 1.5,
+		"b": "hi",
+		"i": int64(-200),
+	}, m.Fields())
+}
+
+func TestParseErrors(t *testing.T) {
+	m := MustMetric(metric.New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"value": int64(1)}, time.Unix(0, 0)))
+	buf, err := serializer.NewSerializer().Serialize(m)
+	require.NoError(t, err)
+
+	parser := NewParser(nil)
+	for i := 1; i < len(buf); i++ {
+		_, err := parser.Parse(buf[:i])
+		require.Error(t, err, "truncated at %d bytes", i)
+	}
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{
+			name: "not a map",
+			buf:  []byte{0x01},
+		},
+		{
+			name: "no name",
+			buf:  []byte{0x81, 0xa4, 't', 'i', 'm', 'e', 0xd6, 0xff, 0x00, 0x00, 0x00, 0x01},
+		},
+		{
+			name: "no time",
+			buf:  []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a'},
+		},
+		{
+			name: "nested too deeply",
+			buf: append([]byte{0x81, 0xa1, 'x'},
+				[]byte("\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91"+
+					"\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x91\x01")...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.Parse(tt.buf)
+			require.Error(t, err)
+		})
+	}
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		MustMetric(metric.New("cpu", map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5, "count": uint64(2)}, time.Unix(1, 0))),
+		MustMetric(metric.New("mem", map[string]string{},
+			map[string]interface{}{"used": int64(100), "ok": true}, time.Unix(2, 0))),
+		MustMetric(metric.New("disk", map[string]string{"path": "/"},
+			map[string]interface{}{"state": "ro"}, time.Unix(3, 5))),
+	}
+}
+
+func TestParseNext(t *testing.T) {
+	metrics := testMetrics()
+	buf, err := serializer.NewSerializer().SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	parser := NewParser(nil)
+	for _, expected := range metrics {
+		m, n, err := parser.ParseNext(buf)
+		require.NoError(t, err)
+		require.Equal(t, expected.Name(), m.Name())
+		require.Equal(t, expected.Fields(), m.Fields())
+		buf = buf[n:]
+	}
+	require.Empty(t, buf)
+}
+
+func TestParseNextTruncated(t *testing.T) {
+	m := testMetrics()[0]
+	buf, err := serializer.NewSerializer().Serialize(m)
+	require.NoError(t, err)
+
+	parser := NewParser(nil)
+	for i := 0; i < len(buf); i++ {
+		_, n, err := parser.ParseNext(buf[:i])
+		require.Equal(t, ErrNeedMore, err, "truncated at %d bytes", i)
+		require.Equal(t, 0, n)
+	}
+
+	// an invalid metric is an error, even if truncated
+	_, _, err = parser.ParseNext([]byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0x01})
+	require.Error(t, err)
+	require.NotEqual(t, ErrNeedMore, err)
+}
+
+func TestDecoder(t *testing.T) {
+	metrics := testMetrics()
+	buf, err := serializer.NewSerializer().SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	// a byte at a time, so that every metric is first read partially
+	d := NewDecoder(iotest.OneByteReader(bytes.NewReader(buf)), NewParser(nil))
+	for _, expected := range metrics {
+		m, err := d.Decode()
+		require.NoError(t, err)
+		require.Equal(t, expected.Name(), m.Name())
+		require.Equal(t, expected.Tags(), m.Tags())
+		require.Equal(t, expected.Fields(), m.Fields())
+		require.True(t, expected.Time().Equal(m.Time()))
+	}
+	_, err = d.Decode()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDecoderLarge(t *testing.T) {
+	var metrics []telegraf.Metric
+	for i := 0; i < 1000; i++ {
+		metrics = append(metrics, testMetrics()...)
+	}
+	buf, err := serializer.NewSerializer().SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	d := NewDecoder(bytes.NewReader(buf), NewParser(nil))
+	for range metrics {
+		_, err := d.Decode()
+		require.NoError(t, err)
+	}
+	_, err = d.Decode()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	buf, err := serializer.NewSerializer().SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	d := NewDecoder(bytes.NewReader(buf[:len(buf)-1]), NewParser(nil))
+	for i := 0; i < 2; i++ {
+		_, err := d.Decode()
+		require.NoError(t, err)
+	}
+	_, err = d.Decode()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+}
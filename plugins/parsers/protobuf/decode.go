@@ -0,0 +1,252 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// the wire types of the protobuf encoding
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// maxDepth is the maximum nesting of the decoded messages.
+const maxDepth = 64
+
+var errShort = errors.New("unexpected end of message")
+
+// message is a decoded message, holding the values of its fields by number in
+// the order they were encoded.  The values are int64, uint64, float64, bool,
+// string or *message.
+type message struct {
+	typ    *messageType
+	values map[int32][]interface{}
+}
+
+func newMessage(typ *messageType) *message {
+	return &message{typ: typ, values: make(map[int32][]interface{})}
+}
+
+// last returns the value of a non-repeated field, the last one encoded.
+func (m *message) last(f *fieldType) (interface{}, bool) {
+	values := m.values[f.number]
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[len(values)-1], true
+}
+
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+// decodeMessage decodes the buffer into the message.  The fields unknown to
+// its type are skipped.
+func decodeMessage(buf []byte, m *message, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("messages nested more than %d levels", maxDepth)
+	}
+
+	d := &decoder{buf: buf}
+	for d.pos < len(d.buf) {
+		key, err := d.varint()
+		if err != nil {
+			return err
+		}
+		number, wireType := int32(key>>3), int(key&7)
+
+		f := m.typ.byNumber[number]
+		if f == nil {
+			if err := d.skip(wireType); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the repeated scalars can be packed in a single length-delimited
+		// value, whatever the packed option of the field
+		if wireType == wireBytes && f.wireType() != wireBytes && f.repeated {
+			data, err := d.bytes()
+			if err != nil {
+				return fmt.Errorf("field %s: %v", f.name, err)
+			}
+			packed := &decoder{buf: data}
+			for packed.pos < len(packed.buf) {
+				v, err := packed.scalar(f)
+				if err != nil {
+					return fmt.Errorf("field %s: %v", f.name, err)
+				}
+				m.values[number] = append(m.values[number], v)
+			}
+			continue
+		}
+		if wireType != f.wireType() {
+			return fmt.Errorf("field %s: wire type %d does not match its type %s",
+				f.name, wireType, f.kind)
+		}
+
+		if !f.isMessage() {
+			v, err := d.scalar(f)
+			if err != nil {
+				return fmt.Errorf("field %s: %v", f.name, err)
+			}
+			m.values[number] = append(m.values[number], v)
+			continue
+		}
+
+		data, err := d.bytes()
+		if err != nil {
+			return fmt.Errorf("field %s: %v", f.name, err)
+		}
+		// the values of a non-repeated message field are merged
+		var sub *message
+		if v, ok := m.last(f); ok && !f.repeated {
+			sub = v.(*message)
+		} else {
+			sub = newMessage(f.message)
+			m.values[number] = append(m.values[number], sub)
+		}
+		if err := decodeMessage(data, sub, depth+1); err != nil {
+			return fmt.Errorf("field %s: %v", f.name, err)
+		}
+	}
+	return nil
+}
+
+// scalar decodes a value of the scalar field, or of the string and bytes
+// fields.
+func (d *decoder) scalar(f *fieldType) (interface{}, error) {
+	switch f.wireType() {
+	case wireFixed64:
+		v, err := d.fixed64()
+		if err != nil {
+			return nil, err
+		}
+		switch f.kind {
+		case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+			return math.Float64frombits(v), nil
+		case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+			return int64(v), nil
+		default:
+			return v, nil
+		}
+	case wireFixed32:
+		v, err := d.fixed32()
+		if err != nil {
+			return nil, err
+		}
+		switch f.kind {
+		case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+			return float64(math.Float32frombits(v)), nil
+		case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+			return int64(int32(v)), nil
+		default:
+			return uint64(v), nil
+		}
+	case wireBytes:
+		data, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+
+	v, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_INT32:
+		return int64(int32(v)), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32:
+		return uint64(uint32(v)), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT64:
+		return v, nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT32:
+		return int64(int32(uint32(v)>>1) ^ -int32(v&1)), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT64:
+		return int64(v>>1) ^ -int64(v&1), nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return v != 0, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		// the values unknown to the descriptor set are kept as numbers
+		if name, ok := f.enum[int32(v)]; ok {
+			return name, nil
+		}
+		return int64(int32(v)), nil
+	default:
+		return int64(v), nil
+	}
+}
+
+func (d *decoder) varint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if d.pos >= len(d.buf) {
+			return 0, errShort
+		}
+		b := d.buf[d.pos]
+		d.pos++
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("varint overflows 64 bits")
+}
+
+func (d *decoder) fixed64() (uint64, error) {
+	if len(d.buf)-d.pos < 8 {
+		return 0, errShort
+	}
+	v := binary.LittleEndian.Uint64(d.buf[d.pos:])
+	d.pos += 8
+	return v, nil
+}
+
+func (d *decoder) fixed32() (uint32, error) {
+	if len(d.buf)-d.pos < 4 {
+		return 0, errShort
+	}
+	v := binary.LittleEndian.Uint32(d.buf[d.pos:])
+	d.pos += 4
+	return v, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, errShort
+	}
+	data := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return data, nil
+}
+
+func (d *decoder) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case wireVarint:
+		_, err = d.varint()
+	case wireFixed64:
+		_, err = d.fixed64()
+	case wireFixed32:
+		_, err = d.fixed32()
+	case wireBytes:
+		_, err = d.bytes()
+	default:
+		err = fmt.Errorf("unsupported wire type %d", wireType)
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package protobuf
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// timestampType is the well-known type of the timestamps.
+const timestampType = ".google.protobuf.Timestamp"
+
+// messageType is a message of the descriptor set, with its fields indexed by
+// number for decoding.
+type messageType struct {
+	name     string
+	proto3   bool
+	mapEntry bool
+
+	// fields are in declaration order, to name the fields of the metrics
+	// in a stable order
+	fields   []*fieldType
+	byNumber map[int32]*fieldType
+	byName   map[string]*fieldType
+}
+
+type fieldType struct {
+	name     string
+	number   int32
+	kind     descriptor.FieldDescriptorProto_Type
+	repeated bool
+
+	// message is the type of the message fields
+	message *messageType
+	// enum are the names of the values of the enum fields
+	enum map[int32]string
+}
+
+// newMessageTypes returns the message types of the descriptor set by their
+// fully-qualified name, like ".package.Message".
+func newMessageTypes(set *descriptor.FileDescriptorSet) (map[string]*messageType, error) {
+	messages := make(map[string]*messageType)
+	enums := make(map[string]map[int32]string)
+	descriptors := make(map[*messageType]*descriptor.DescriptorProto)
+
+	var addEnums func(scope string, types []*descriptor.EnumDescriptorProto)
+	addEnums = func(scope string, types []*descriptor.EnumDescriptorProto) {
+		for _, e := range types {
+			values := make(map[int32]string)
+			for _, v := range e.GetValue() {
+				values[v.GetNumber()] = v.GetName()
+			}
+			enums[scope+"."+e.GetName()] = values
+		}
+	}
+	var addMessages func(scope string, proto3 bool, types []*descriptor.DescriptorProto)
+	addMessages = func(scope string, proto3 bool, types []*descriptor.DescriptorProto) {
+		for _, d := range types {
+			name := scope + "." + d.GetName()
+			m := &messageType{
+				name:     name,
+				proto3:   proto3,
+				mapEntry: d.GetOptions().GetMapEntry(),
+				byNumber: make(map[int32]*fieldType),
+				byName:   make(map[string]*fieldType),
+			}
+			messages[name] = m
+			descriptors[m] = d
+			addEnums(name, d.GetEnumType())
+			addMessages(name, proto3, d.GetNestedType())
+		}
+	}
+	for _, file := range set.GetFile() {
+		scope := ""
+		if file.GetPackage() != "" {
+			scope = "." + file.GetPackage()
+		}
+		addEnums(scope, file.GetEnumType())
+		addMessages(scope, file.GetSyntax() == "proto3", file.GetMessageType())
+	}
+
+	// the fields are resolved once all the types are known, as they can
+	// refer to the types declared after them or in other files
+	for m, d := range descriptors {
+		for _, fd := range d.GetField() {
+			f := &fieldType{
+				name:     fd.GetName(),
+				number:   fd.GetNumber(),
+				kind:     fd.GetType(),
+				repeated: fd.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED,
+			}
+			switch f.kind {
+			case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+				if f.message = messages[fd.GetTypeName()]; f.message == nil {
+					return nil, fmt.Errorf("field %s of %s has unknown type %s", f.name, m.name, fd.GetTypeName())
+				}
+			case descriptor.FieldDescriptorProto_TYPE_ENUM:
+				if f.enum = enums[fd.GetTypeName()]; f.enum == nil {
+					return nil, fmt.Errorf("field %s of %s has unknown type %s", f.name, m.name, fd.GetTypeName())
+				}
+			case descriptor.FieldDescriptorProto_TYPE_GROUP:
+				return nil, fmt.Errorf("field %s of %s is a group, groups are not supported", f.name, m.name)
+			}
+			m.fields = append(m.fields, f)
+			m.byNumber[f.number] = f
+			m.byName[f.name] = f
+		}
+	}
+	return messages, nil
+}
+
+// isMessage returns true for the fields holding messages.
+func (f *fieldType) isMessage() bool {
+	return f.kind == descriptor.FieldDescriptorProto_TYPE_MESSAGE
+}
+
+// zero returns the value of the scalar field when not encoded.
+func (f *fieldType) zero() interface{} {
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE, descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return float64(0)
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return uint64(0)
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return ""
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if name, ok := f.enum[0]; ok {
+			return name
+		}
+		return int64(0)
+	default:
+		return int64(0)
+	}
+}
+
+// wireType returns the wire type of the values of the field when not packed.
+func (f *fieldType) wireType() int {
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE, descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return wireFixed64
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return wireFixed32
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		return wireBytes
+	default:
+		return wireVarint
+	}
+}
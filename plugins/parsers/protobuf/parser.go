@@ -0,0 +1,433 @@
+package protobuf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Parser decodes binary protobuf messages of a type of a compiled descriptor
+// set, as written by `protoc --include_imports --descriptor_set_out`.
+type Parser struct {
+	// DescriptorSet is the file of the FileDescriptorSet
+	DescriptorSet string
+	// MessageType is the fully-qualified name of the decoded messages
+	MessageType string
+	MetricName  string
+	// MetricPath is a repeated message field of which each element is
+	// parsed as a metric, tagged with its index as IndexTag
+	MetricPath string
+	IndexTag   string
+	// TagPaths and FieldPaths are the fields of the tags and fields, all the
+	// fields of the metric messages when FieldPaths is empty
+	TagPaths        []string
+	FieldPaths      []string
+	TimestampPath   string
+	TimestampFormat string
+	DefaultTags     map[string]string
+
+	TimeFunc func() time.Time
+
+	root       *messageType
+	metricPath []*fieldType
+	tagPaths   []*fieldPath
+	fieldPaths []*fieldPath
+	timestamp  *fieldPath
+	// skip are the paths of the tags and timestamp, left out of the fields
+	// of the metric messages
+	skip map[string]bool
+}
+
+// fieldPath is a field of the messages, named after its path in the metrics.
+type fieldPath struct {
+	name string
+	path string
+	// inElement is true for the paths within the elements of the metric
+	// path, relative to them
+	inElement bool
+	fields    []*fieldType
+	owner     *messageType
+}
+
+// Init loads the descriptor set and checks the paths against the message
+// type.
+func (p *Parser) Init() error {
+	data, err := ioutil.ReadFile(p.DescriptorSet)
+	if err != nil {
+		return fmt.Errorf("could not read descriptor set: %v", err)
+	}
+	set := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return fmt.Errorf("could not decode descriptor set %s: %v", p.DescriptorSet, err)
+	}
+	messages, err := newMessageTypes(set)
+	if err != nil {
+		return fmt.Errorf("invalid descriptor set %s: %v", p.DescriptorSet, err)
+	}
+
+	p.root = messages["."+strings.TrimPrefix(p.MessageType, ".")]
+	if p.root == nil {
+		return fmt.Errorf("unknown message type %q in descriptor set %s", p.MessageType, p.DescriptorSet)
+	}
+
+	element := p.root
+	p.metricPath = nil
+	if p.MetricPath != "" {
+		path, err := resolve(p.root, p.MetricPath)
+		if err != nil {
+			return fmt.Errorf("metric path: %v", err)
+		}
+		last := path.fields[len(path.fields)-1]
+		if !last.isMessage() || !last.repeated {
+			return fmt.Errorf("metric path %q is not a repeated message field", p.MetricPath)
+		}
+		p.metricPath = path.fields
+		element = last.message
+	}
+	if p.IndexTag == "" {
+		p.IndexTag = "index"
+	}
+
+	p.skip = make(map[string]bool)
+	p.tagPaths, p.fieldPaths = nil, nil
+	for _, s := range p.TagPaths {
+		path, err := p.compile(s, element)
+		if err != nil {
+			return fmt.Errorf("tag path: %v", err)
+		}
+		p.tagPaths = append(p.tagPaths, path)
+		p.skipPath(path)
+	}
+	for _, s := range p.FieldPaths {
+		path, err := p.compile(s, element)
+		if err != nil {
+			return fmt.Errorf("field path: %v", err)
+		}
+		p.fieldPaths = append(p.fieldPaths, path)
+	}
+
+	p.timestamp = nil
+	if p.TimestampPath != "" {
+		path, err := p.compile(p.TimestampPath, element)
+		if err != nil {
+			return fmt.Errorf("timestamp path: %v", err)
+		}
+		last := path.fields[len(path.fields)-1]
+		if last.repeated || !isTimestamp(last) {
+			return fmt.Errorf("timestamp path %q is not an integer or %s field", p.TimestampPath, timestampType[1:])
+		}
+		p.timestamp = path
+		p.skipPath(path)
+	}
+	switch p.TimestampFormat {
+	case "":
+		p.TimestampFormat = "unix"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid timestamp format %q", p.TimestampFormat)
+	}
+
+	if p.TimeFunc == nil {
+		p.TimeFunc = time.Now
+	}
+	return nil
+}
+
+// compile resolves a path, relative to the elements of the metric path when
+// within it.
+func (p *Parser) compile(s string, element *messageType) (*fieldPath, error) {
+	if p.MetricPath != "" && strings.HasPrefix(s, p.MetricPath+".") {
+		path, err := resolve(element, strings.TrimPrefix(s, p.MetricPath+"."))
+		if err != nil {
+			return nil, err
+		}
+		path.inElement = true
+		return path, nil
+	}
+	return resolve(p.root, s)
+}
+
+// skipPath leaves the path out of the fields of the metric messages, the
+// elements of the metric path if any.
+func (p *Parser) skipPath(path *fieldPath) {
+	if path.inElement || len(p.metricPath) == 0 {
+		p.skip[path.path] = true
+	}
+}
+
+// resolve returns the fields of a dotted path of the message type.  Only its
+// last field can be repeated, as the paths select a single message.
+func resolve(typ *messageType, s string) (*fieldPath, error) {
+	path := &fieldPath{name: strings.Replace(s, ".", "_", -1), path: s}
+	for i, name := range strings.Split(s, ".") {
+		if i > 0 {
+			prev := path.fields[i-1]
+			if !prev.isMessage() {
+				return nil, fmt.Errorf("%q: field %s is not a message", s, prev.name)
+			}
+			if prev.repeated {
+				return nil, fmt.Errorf("%q: field %s is repeated", s, prev.name)
+			}
+			typ = prev.message
+		}
+		f := typ.byName[name]
+		if f == nil {
+			return nil, fmt.Errorf("%q: unknown field %s of %s", s, name, typ.name)
+		}
+		path.fields = append(path.fields, f)
+		path.owner = typ
+	}
+	return path, nil
+}
+
+func isTimestamp(f *fieldType) bool {
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		return f.message.name == timestampType
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return true
+	}
+	return false
+}
+
+// Parse decodes a message, parsed as one metric or as one metric per element
+// of the metric path.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	root := newMessage(p.root)
+	if err := decodeMessage(buf, root, 0); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %v", p.root.name[1:], err)
+	}
+
+	elements := []interface{}{root}
+	if len(p.metricPath) > 0 {
+		elements = nil
+		m := root
+		for _, f := range p.metricPath[:len(p.metricPath)-1] {
+			v, ok := m.last(f)
+			if !ok {
+				m = nil
+				break
+			}
+			m = v.(*message)
+		}
+		if m != nil {
+			elements = m.values[p.metricPath[len(p.metricPath)-1].number]
+		}
+	}
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0, len(elements))
+	for i, element := range elements {
+		m, err := p.parseElement(root, element.(*message), i, now)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseElement(root, element *message, index int, now time.Time) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	if len(p.metricPath) > 0 {
+		tags[p.IndexTag] = strconv.Itoa(index)
+	}
+	for _, path := range p.tagPaths {
+		values := make(map[string]interface{})
+		path.collect(values, root, element, false)
+		for k, v := range values {
+			tags[k] = fmt.Sprint(v)
+		}
+	}
+
+	fields := make(map[string]interface{})
+	if len(p.fieldPaths) == 0 {
+		flattenMessage(fields, "", "", element, p.skip)
+	}
+	for _, path := range p.fieldPaths {
+		path.collect(fields, root, element, true)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	tm := now
+	if p.timestamp != nil {
+		if v, ok := p.timestamp.value(root, element); ok {
+			tm = p.parseTimestamp(v)
+		}
+	}
+	return metric.New(p.MetricName, tags, fields, tm)
+}
+
+// value returns the value of the non-repeated path.
+func (path *fieldPath) value(root, element *message) (interface{}, bool) {
+	m := root
+	if path.inElement {
+		m = element
+	}
+	for _, f := range path.fields {
+		v, ok := m.last(f)
+		if !ok {
+			return nil, false
+		}
+		if f.isMessage() {
+			m = v.(*message)
+		} else {
+			return v, true
+		}
+	}
+	return m, true
+}
+
+// collect adds the values of the path to out.  The absent scalar fields of
+// proto3 messages are set to their zero value if zero is true, as proto3 does
+// not encode them.
+func (path *fieldPath) collect(out map[string]interface{}, root, element *message, zero bool) {
+	m := root
+	if path.inElement {
+		m = element
+	}
+	fields := path.fields
+	for _, f := range fields[:len(fields)-1] {
+		v, ok := m.last(f)
+		if !ok {
+			m = nil
+			break
+		}
+		m = v.(*message)
+	}
+
+	last := fields[len(fields)-1]
+	if m == nil || len(m.values[last.number]) == 0 {
+		if zero && path.owner.proto3 && !last.repeated && !last.isMessage() {
+			out[path.name] = last.zero()
+		}
+		return
+	}
+	flattenValues(out, path.name, path.path, last, m.values[last.number], nil)
+}
+
+// flattenMessage adds the fields of the message to out, except the ones of the
+// skipped paths.
+func flattenMessage(out map[string]interface{}, prefix, path string, m *message, skip map[string]bool) {
+	for _, f := range m.typ.fields {
+		values := m.values[f.number]
+		if len(values) == 0 {
+			continue
+		}
+		fpath := join(path, f.name, ".")
+		if skip[fpath] {
+			continue
+		}
+		flattenValues(out, join(prefix, f.name, "_"), fpath, f, values, skip)
+	}
+}
+
+// flattenValues adds the values of the field to out, named after the field.
+// The nested messages are flattened, their fields prefixed by the name of the
+// field, and the elements of the repeated fields are suffixed by their index,
+// or their key for the maps.
+func flattenValues(out map[string]interface{}, name, path string, f *fieldType, values []interface{}, skip map[string]bool) {
+	if !f.repeated {
+		flattenValue(out, name, path, values[len(values)-1], skip)
+		return
+	}
+	if f.message != nil && f.message.mapEntry {
+		key, value := f.message.byNumber[1], f.message.byNumber[2]
+		for _, v := range values {
+			entry := v.(*message)
+			k, ok := entry.last(key)
+			if !ok {
+				k = key.zero()
+			}
+			if v, ok := entry.last(value); ok {
+				flattenValue(out, name+"_"+fmt.Sprint(k), path, v, skip)
+			}
+		}
+		return
+	}
+	for i, v := range values {
+		flattenValue(out, name+"_"+strconv.Itoa(i), path, v, skip)
+	}
+}
+
+func flattenValue(out map[string]interface{}, name, path string, v interface{}, skip map[string]bool) {
+	if m, ok := v.(*message); ok {
+		flattenMessage(out, name, path, m, skip)
+		return
+	}
+	out[name] = v
+}
+
+func join(prefix, name, sep string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + sep + name
+}
+
+func (p *Parser) parseTimestamp(v interface{}) time.Time {
+	var n int64
+	switch v := v.(type) {
+	case *message:
+		var seconds, nanos int64
+		if f := v.typ.byNumber[1]; f != nil {
+			if s, ok := v.last(f); ok {
+				seconds, _ = s.(int64)
+			}
+		}
+		if f := v.typ.byNumber[2]; f != nil {
+			if ns, ok := v.last(f); ok {
+				nanos, _ = ns.(int64)
+			}
+		}
+		return time.Unix(seconds, nanos)
+	case int64:
+		n = v
+	case uint64:
+		n = int64(v)
+	}
+
+	switch p.TimestampFormat {
+	case "unix_ms":
+		return time.Unix(0, n*int64(time.Millisecond))
+	case "unix_us":
+		return time.Unix(0, n*int64(time.Microsecond))
+	case "unix_ns":
+		return time.Unix(0, n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("expected 1 metric, got %d", len(metrics))
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,357 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func field(name string, number int32, kind descriptor.FieldDescriptorProto_Type, typeName string) *descriptor.FieldDescriptorProto {
+	f := &descriptor.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   kind.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func repeated(f *descriptor.FieldDescriptorProto) *descriptor.FieldDescriptorProto {
+	f.Label = descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+// descriptorSet returns the descriptor set of:
+//
+//	syntax = "proto3";
+//	package example;
+//
+//	enum Status { UNKNOWN = 0; OK = 1; FAILED = 2; }
+//	message Location { string region = 1; int32 rack = 2; }
+//	message Sample {
+//	  string sensor = 1; double value = 2; int64 time_ms = 3; bool ok = 4;
+//	}
+//	message Report {
+//	  string host = 1;
+//	  Location location = 2;
+//	  repeated Sample samples = 3;
+//	  repeated double loads = 4;
+//	  google.protobuf.Timestamp time = 5;
+//	  Status status = 6;
+//	  sint64 offset = 7;
+//	  map<string, int64> counters = 8;
+//	}
+func descriptorSet() *descriptor.FileDescriptorSet {
+	timestamp := &descriptor.FileDescriptorProto{
+		Name:    proto.String("google/protobuf/timestamp.proto"),
+		Package: proto.String("google.protobuf"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: proto.String("Timestamp"),
+			Field: []*descriptor.FieldDescriptorProto{
+				field("seconds", 1, descriptor.FieldDescriptorProto_TYPE_INT64, ""),
+				field("nanos", 2, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+			},
+		}},
+	}
+	example := &descriptor.FileDescriptorProto{
+		Name:       proto.String("example.proto"),
+		Package:    proto.String("example"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name: proto.String("Status"),
+			Value: []*descriptor.EnumValueDescriptorProto{
+				{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("OK"), Number: proto.Int32(1)},
+				{Name: proto.String("FAILED"), Number: proto.Int32(2)},
+			},
+		}},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Report"),
+				Field: []*descriptor.FieldDescriptorProto{
+					field("host", 1, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					field("location", 2, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".example.Location"),
+					repeated(field("samples", 3, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".example.Sample")),
+					repeated(field("loads", 4, descriptor.FieldDescriptorProto_TYPE_DOUBLE, "")),
+					field("time", 5, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					field("status", 6, descriptor.FieldDescriptorProto_TYPE_ENUM, ".example.Status"),
+					field("offset", 7, descriptor.FieldDescriptorProto_TYPE_SINT64, ""),
+					repeated(field("counters", 8, descriptor.FieldDescriptorProto_TYPE_MESSAGE, ".example.Report.CountersEntry")),
+				},
+				NestedType: []*descriptor.DescriptorProto{{
+					Name: proto.String("CountersEntry"),
+					Field: []*descriptor.FieldDescriptorProto{
+						field("key", 1, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+						field("value", 2, descriptor.FieldDescriptorProto_TYPE_INT64, ""),
+					},
+					Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+			},
+			{
+				Name: proto.String("Location"),
+				Field: []*descriptor.FieldDescriptorProto{
+					field("region", 1, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					field("rack", 2, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+				},
+			},
+			{
+				Name: proto.String("Sample"),
+				Field: []*descriptor.FieldDescriptorProto{
+					field("sensor", 1, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					field("value", 2, descriptor.FieldDescriptorProto_TYPE_DOUBLE, ""),
+					field("time_ms", 3, descriptor.FieldDescriptorProto_TYPE_INT64, ""),
+					field("ok", 4, descriptor.FieldDescriptorProto_TYPE_BOOL, ""),
+				},
+			},
+		},
+	}
+	return &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{example, timestamp}}
+}
+
+func writeDescriptorSet(t *testing.T, set *descriptor.FileDescriptorSet) string {
+	data, err := proto.Marshal(set)
+	require.NoError(t, err)
+	file, err := ioutil.TempFile("", "descriptor_set")
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.Write(data)
+	require.NoError(t, err)
+	return file.Name()
+}
+
+// encoder writes the protobuf encoding of the test messages.
+type encoder []byte
+
+func (e encoder) key(number, wireType int) encoder {
+	return e.varint(uint64(number<<3 | wireType))
+}
+
+func (e encoder) varint(v uint64) encoder {
+	for v >= 0x80 {
+		e = append(e, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(e, byte(v))
+}
+
+func (e encoder) bytes(number int, data []byte) encoder {
+	return append(e.key(number, wireBytes).varint(uint64(len(data))), data...)
+}
+
+func (e encoder) double(number int, v float64) encoder {
+	e = e.key(number, wireFixed64)
+	return append(e, make([]byte, 8)...).putFixed64(math.Float64bits(v))
+}
+
+func (e encoder) putFixed64(v uint64) encoder {
+	binary.LittleEndian.PutUint64(e[len(e)-8:], v)
+	return e
+}
+
+func sample(sensor string, value float64, timeMS int64, ok bool) []byte {
+	e := encoder{}.bytes(1, []byte(sensor)).double(2, value).key(3, wireVarint).varint(uint64(timeMS))
+	if ok {
+		e = e.key(4, wireVarint).varint(1)
+	}
+	return e
+}
+
+func report() []byte {
+	// the negative int32 are sign-extended to 64 bits
+	rack := int64(-3)
+	location := encoder{}.bytes(1, []byte("eu-west")).key(2, wireVarint).varint(uint64(rack))
+	loads := make([]byte, 16)
+	binary.LittleEndian.PutUint64(loads, math.Float64bits(0.5))
+	binary.LittleEndian.PutUint64(loads[8:], math.Float64bits(1.5))
+	timestamp := encoder{}.key(1, wireVarint).varint(1500000000).key(2, wireVarint).varint(42)
+	counterA := encoder{}.bytes(1, []byte("a")).key(2, wireVarint).varint(1)
+	counterB := encoder{}.bytes(1, []byte("b")).key(2, wireVarint).varint(2)
+
+	return encoder{}.
+		bytes(1, []byte("server01")).
+		bytes(2, location).
+		bytes(3, sample("temperature", 21.5, 1500000001000, true)).
+		bytes(3, sample("humidity", 40, 1500000002000, false)).
+		bytes(4, loads).
+		bytes(5, timestamp).
+		key(6, wireVarint).varint(1).
+		key(7, wireVarint).varint(9). // zigzag -5
+		bytes(8, counterA).
+		bytes(8, counterB).
+		// the fields unknown to the descriptor set are skipped
+		key(99, wireVarint).varint(7).
+		bytes(100, []byte("unknown"))
+}
+
+func newTestParser(t *testing.T, p *Parser) *Parser {
+	file := writeDescriptorSet(t, descriptorSet())
+	defer os.Remove(file)
+
+	p.DescriptorSet = file
+	p.MetricName = "report"
+	p.TimeFunc = func() time.Time { return time.Unix(1600000000, 0) }
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestParseAllFields(t *testing.T) {
+	p := newTestParser(t, &Parser{
+		MessageType:   "example.Report",
+		TagPaths:      []string{"host", "location.region"},
+		TimestampPath: "time",
+		DefaultTags:   map[string]string{"source": "test"},
+	})
+
+	metrics, err := p.Parse(report())
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("report",
+			map[string]string{"source": "test", "host": "server01", "location_region": "eu-west"},
+			map[string]interface{}{
+				"location_rack":     int64(-3),
+				"samples_0_sensor":  "temperature",
+				"samples_0_value":   21.5,
+				"samples_0_time_ms": int64(1500000001000),
+				"samples_0_ok":      true,
+				"samples_1_sensor":  "humidity",
+				"samples_1_value":   40.0,
+				"samples_1_time_ms": int64(1500000002000),
+				"loads_0":           0.5,
+				"loads_1":           1.5,
+				"status":            "OK",
+				"offset":            int64(-5),
+				"counters_a":        int64(1),
+				"counters_b":        int64(2),
+			},
+			time.Unix(1500000000, 42),
+		),
+	}, metrics)
+}
+
+func TestParseMetricPath(t *testing.T) {
+	p := newTestParser(t, &Parser{
+		MessageType:     "example.Report",
+		MetricPath:      "samples",
+		TagPaths:        []string{"host", "samples.sensor"},
+		FieldPaths:      []string{"samples.value", "samples.ok", "status", "location"},
+		TimestampPath:   "samples.time_ms",
+		TimestampFormat: "unix_ms",
+	})
+
+	metrics, err := p.Parse(report())
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("report",
+			map[string]string{"host": "server01", "sensor": "temperature", "index": "0"},
+			map[string]interface{}{
+				"value":           21.5,
+				"ok":              true,
+				"status":          "OK",
+				"location_region": "eu-west",
+				"location_rack":   int64(-3),
+			},
+			time.Unix(1500000001, 0),
+		),
+		testutil.MustMetric("report",
+			map[string]string{"host": "server01", "sensor": "humidity", "index": "1"},
+			map[string]interface{}{
+				"value": 40.0,
+				// the proto3 defaults are not encoded
+				"ok":              false,
+				"status":          "OK",
+				"location_region": "eu-west",
+				"location_rack":   int64(-3),
+			},
+			time.Unix(1500000002, 0),
+		),
+	}, metrics)
+
+	// the metric path of the message without elements has no metrics
+	metrics, err = p.Parse(encoder{}.bytes(1, []byte("server01")))
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+}
+
+func TestParseMetricPathAllFields(t *testing.T) {
+	p := newTestParser(t, &Parser{
+		MessageType: "example.Report",
+		MetricPath:  "samples",
+		IndexTag:    "sample",
+		TagPaths:    []string{"samples.sensor"},
+	})
+
+	metrics, err := p.Parse(report())
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]string{"sensor": "humidity", "sample": "1"}, metrics[1].Tags())
+	require.Equal(t, map[string]interface{}{
+		"value":   40.0,
+		"time_ms": int64(1500000002000),
+	}, metrics[1].Fields())
+	require.Equal(t, time.Unix(1600000000, 0), metrics[1].Time())
+}
+
+func TestParseMergesMessages(t *testing.T) {
+	p := newTestParser(t, &Parser{MessageType: ".example.Location"})
+
+	// the non-repeated fields keep their last value
+	buf := encoder{}.bytes(1, []byte("eu-west")).bytes(1, []byte("us-east")).key(2, wireVarint).varint(4)
+	m, err := p.ParseLine(string(buf))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"region": "us-east", "rack": int64(4)}, m.Fields())
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := newTestParser(t, &Parser{MessageType: "example.Report"})
+
+	buf := report()
+	_, err := p.Parse(buf[:len(buf)-3])
+	require.Error(t, err)
+
+	// host is a string, not a varint
+	_, err = p.Parse(encoder{}.key(1, wireVarint).varint(1))
+	require.Error(t, err)
+
+	_, err = p.Parse(encoder{}.key(9, 3))
+	require.Error(t, err)
+}
+
+func TestInitInvalid(t *testing.T) {
+	file := writeDescriptorSet(t, descriptorSet())
+	defer os.Remove(file)
+
+	for _, p := range []*Parser{
+		{DescriptorSet: "/nonexistent", MessageType: "example.Report"},
+		{DescriptorSet: file, MessageType: "example.Unknown"},
+		{DescriptorSet: file, MessageType: "example.Report", TagPaths: []string{"location.zone"}},
+		{DescriptorSet: file, MessageType: "example.Report", FieldPaths: []string{"samples.value"}},
+		{DescriptorSet: file, MessageType: "example.Report", FieldPaths: []string{"host.length"}},
+		{DescriptorSet: file, MessageType: "example.Report", MetricPath: "location"},
+		{DescriptorSet: file, MessageType: "example.Report", TimestampPath: "host"},
+		{DescriptorSet: file, MessageType: "example.Report", TimestampPath: "time", TimestampFormat: "rfc3339"},
+	} {
+		require.Error(t, p.Init())
+	}
+
+	// the types must be in the descriptor set
+	set := descriptorSet()
+	set.File = set.File[:1]
+	missing := writeDescriptorSet(t, set)
+	defer os.Remove(missing)
+	p := &Parser{DescriptorSet: missing, MessageType: "example.Report"}
+	require.Error(t, p.Init())
+}
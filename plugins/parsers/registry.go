@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/binary"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
@@ -12,10 +13,14 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/grok"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
+	"github.com/influxdata/telegraf/plugins/parsers/kv"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	"github.com/influxdata/telegraf/plugins/parsers/msgpack"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
+	"github.com/influxdata/telegraf/plugins/parsers/xpath"
 )
 
 type ParserFunc func() (Parser, error)
@@ -34,6 +39,13 @@ type ParserFuncInput interface {
 	SetParserFunc(fn ParserFunc)
 }
 
+// RouteParserInput is an interface for input plugins that parse the data of
+// each of their routes with its own data format.
+type RouteParserInput interface {
+	// SetRouteParsers sets the parsers of the routes by path.
+	SetRouteParsers(parsers map[string]Parser)
+}
+
 // Parser is an interface defining functions that a parser plugin must satisfy.
 type Parser interface {
 	// Parse takes a byte buffer separated by newlines
@@ -85,6 +97,12 @@ type Config struct {
 	// time format
 	JSONTimeFormat string `toml:"json_time_format"`
 
+	// tag names for the index of the elements of JSON arrays, one per level
+	JSONArrayIndexTags []string `toml:"json_array_index_tags"`
+
+	// only parse JSON objects for which this condition holds
+	JSONCondition string `toml:"json_condition"`
+
 	// Authentication file for collectd
 	CollectdAuthFile string `toml:"collectd_auth_file"`
 	// One of none (default), sign, or encrypt
@@ -137,6 +155,33 @@ type Config struct {
 	CSVTimestampColumn   string   `toml:"csv_timestamp_column"`
 	CSVTimestampFormat   string   `toml:"csv_timestamp_format"`
 	CSVTrimSpace         bool     `toml:"csv_trim_space"`
+
+	// protobuf configuration
+	ProtobufDescriptorSet   string   `toml:"protobuf_descriptor_set"`
+	ProtobufMessageType     string   `toml:"protobuf_message_type"`
+	ProtobufMetricPath      string   `toml:"protobuf_metric_path"`
+	ProtobufIndexTag        string   `toml:"protobuf_index_tag"`
+	ProtobufTagPaths        []string `toml:"protobuf_tag_paths"`
+	ProtobufFieldPaths      []string `toml:"protobuf_field_paths"`
+	ProtobufTimestampPath   string   `toml:"protobuf_timestamp_path"`
+	ProtobufTimestampFormat string   `toml:"protobuf_timestamp_format"`
+
+	// binary configuration
+	BinaryEndianness      string         `toml:"binary_endianness"`
+	BinaryRecordLength    int            `toml:"binary_record_length"`
+	BinaryFields          []binary.Field `toml:"binary_field"`
+	BinaryTimestampField  string         `toml:"binary_timestamp_field"`
+	BinaryTimestampFormat string         `toml:"binary_timestamp_format"`
+
+	// kv configuration
+	KVSeparator     string   `toml:"kv_separator"`
+	KVPairSeparator string   `toml:"kv_pair_separator"`
+	KVTagKeys       []string `toml:"kv_tag_keys"`
+	KVIntKeys       []string `toml:"kv_int_keys"`
+	KVStringKeys    []string `toml:"kv_string_keys"`
+
+	// xpath configuration, of the xml, xpath_json and xpath_cbor formats
+	XPathConfigs []xpath.Config `toml:"xpath"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -145,13 +190,15 @@ func NewParser(config *Config) (Parser, error) {
 	var parser Parser
 	switch config.DataFormat {
 	case "json":
-		parser = newJSONParser(config.MetricName,
+		parser, err = newJSONParser(config.MetricName,
 			config.TagKeys,
 			config.JSONNameKey,
 			config.JSONStringFields,
 			config.JSONQuery,
 			config.JSONTimeKey,
 			config.JSONTimeFormat,
+			config.JSONArrayIndexTags,
+			config.JSONCondition,
 			config.DefaultTags)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
@@ -203,6 +250,20 @@ func NewParser(config *Config) (Parser, error) {
 			config.DefaultTags)
 	case "logfmt":
 		parser, err = NewLogFmtParser(config.MetricName, config.DefaultTags)
+	case "msgpack":
+		parser, err = NewMsgpackParser(config.DefaultTags)
+	case "protobuf":
+		parser, err = NewProtobufParser(config)
+	case "binary":
+		parser, err = NewBinaryParser(config)
+	case "kv":
+		parser, err = NewKVParser(config)
+	case "xml":
+		parser, err = NewXPathParser("xml", config)
+	case "xpath_json":
+		parser, err = NewXPathParser("json", config)
+	case "xpath_cbor":
+		parser, err = NewXPathParser("cbor", config)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -275,19 +336,24 @@ func newJSONParser(
 	jsonQuery string,
 	timeKey string,
 	timeFormat string,
+	arrayIndexTags []string,
+	condition string,
 	defaultTags map[string]string,
-) Parser {
+) (Parser, error) {
 	parser := &json.JSONParser{
-		MetricName:     metricName,
-		TagKeys:        tagKeys,
-		StringFields:   stringFields,
-		JSONNameKey:    jsonNameKey,
-		JSONQuery:      jsonQuery,
-		JSONTimeKey:    timeKey,
-		JSONTimeFormat: timeFormat,
-		DefaultTags:    defaultTags,
+		MetricName:         metricName,
+		TagKeys:            tagKeys,
+		StringFields:       stringFields,
+		JSONNameKey:        jsonNameKey,
+		JSONQuery:          jsonQuery,
+		JSONTimeKey:        timeKey,
+		JSONTimeFormat:     timeFormat,
+		JSONArrayIndexTags: arrayIndexTags,
+		JSONCondition:      condition,
+		DefaultTags:        defaultTags,
 	}
-	return parser
+	err := parser.Compile()
+	return parser, err
 }
 
 //Deprecated: Use NewParser to get a JSONParser object
@@ -390,6 +456,82 @@ func NewLogFmtParser(metricName string, defaultTags map[string]string) (Parser,
 	return logfmt.NewParser(metricName, defaultTags), nil
 }
 
+// NewMsgpackParser returns a parser of the metrics written by the msgpack
+// serializer.
+func NewMsgpackParser(defaultTags map[string]string) (Parser, error) {
+	return msgpack.NewParser(defaultTags), nil
+}
+
+// NewProtobufParser returns a parser of the protobuf messages of a type of
+// the descriptor set, loaded at once.
+func NewProtobufParser(config *Config) (Parser, error) {
+	parser := &protobuf.Parser{
+		DescriptorSet:   config.ProtobufDescriptorSet,
+		MessageType:     config.ProtobufMessageType,
+		MetricName:      config.MetricName,
+		MetricPath:      config.ProtobufMetricPath,
+		IndexTag:        config.ProtobufIndexTag,
+		TagPaths:        config.ProtobufTagPaths,
+		FieldPaths:      config.ProtobufFieldPaths,
+		TimestampPath:   config.ProtobufTimestampPath,
+		TimestampFormat: config.ProtobufTimestampFormat,
+		DefaultTags:     config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// NewBinaryParser returns a parser of the records of the fixed binary layout.
+func NewBinaryParser(config *Config) (Parser, error) {
+	parser := &binary.Parser{
+		MetricName:      config.MetricName,
+		Endianness:      config.BinaryEndianness,
+		RecordLength:    config.BinaryRecordLength,
+		Fields:          config.BinaryFields,
+		TimestampField:  config.BinaryTimestampField,
+		TimestampFormat: config.BinaryTimestampFormat,
+		DefaultTags:     config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// NewKVParser returns a parser of the lines of key/value pairs.
+func NewKVParser(config *Config) (Parser, error) {
+	parser := &kv.Parser{
+		MetricName:        config.MetricName,
+		KeyValueSeparator: config.KVSeparator,
+		PairSeparator:     config.KVPairSeparator,
+		TagKeys:           config.KVTagKeys,
+		IntKeys:           config.KVIntKeys,
+		StringKeys:        config.KVStringKeys,
+		DefaultTags:       config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// NewXPathParser returns a parser of the documents of the format with XPath
+// queries.
+func NewXPathParser(format string, config *Config) (Parser, error) {
+	parser := &xpath.Parser{
+		Format:      format,
+		Configs:     config.XPathConfigs,
+		MetricName:  config.MetricName,
+		DefaultTags: config.DefaultTags,
+	}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
 func NewWavefrontParser(defaultTags map[string]string) (Parser, error) {
 	return wavefront.NewWavefrontParser(defaultTags), nil
 }
@@ -0,0 +1,254 @@
+package xpath
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The major types of the CBOR data items.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// cborIndefinite is the additional information of the indefinite length
+// items, ended by cborBreak.
+const (
+	cborIndefinite = 31
+	cborBreak      = 0xff
+)
+
+// parseCBOR reads a CBOR document, in the same node model as the JSON
+// documents.
+func parseCBOR(buf []byte) (*node, error) {
+	d := &cborDecoder{buf: buf}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(buf) {
+		return nil, errors.New("unexpected data after the document")
+	}
+	return newDocument(v)
+}
+
+// cborDecoder decodes a data item as an object, an array, the text of a
+// scalar, or nil for null and undefined.  The numbers are written in
+// decimal, the byte strings in base64 as encoding/json does, and the tags
+// are left out for their content.
+type cborDecoder struct {
+	buf []byte
+	off int
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.off) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.buf[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// head reads the initial byte of a data item and its argument.
+func (d *cborDecoder) head() (major, info byte, arg uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		b, err := d.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, c := range b {
+			arg = arg<<8 | uint64(c)
+		}
+	case info == cborIndefinite:
+		switch major {
+		case cborBytes, cborText, cborArray, cborMap:
+		default:
+			return 0, 0, 0, fmt.Errorf("invalid indefinite length of major type %d", major)
+		}
+	default:
+		return 0, 0, 0, fmt.Errorf("reserved additional information %d", info)
+	}
+	return major, info, arg, nil
+}
+
+// atBreak consumes the break ending an indefinite length item.
+func (d *cborDecoder) atBreak() (bool, error) {
+	if d.off >= len(d.buf) {
+		return false, io.ErrUnexpectedEOF
+	}
+	if d.buf[d.off] != cborBreak {
+		return false, nil
+	}
+	d.off++
+	return true, nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("nested deeper than %d levels", maxDepth)
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		return strconv.FormatUint(arg, 10), nil
+	case cborNegInt:
+		if arg == math.MaxUint64 {
+			return "-18446744073709551616", nil
+		}
+		return "-" + strconv.FormatUint(arg+1, 10), nil
+	case cborBytes, cborText:
+		b, err := d.str(major, info, arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborBytes {
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+		return string(b), nil
+	case cborArray:
+		array := []interface{}{}
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite {
+				if end, err := d.atBreak(); err != nil || end {
+					return array, err
+				}
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		}
+		return array, nil
+	case cborMap:
+		obj := object{}
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite {
+				if end, err := d.atBreak(); err != nil || end {
+					return obj, err
+				}
+			}
+			key, err := d.key()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: key, value: v})
+		}
+		return obj, nil
+	case cborTag:
+		return d.value(depth + 1)
+	}
+	return d.simple(info, arg)
+}
+
+// str reads a byte or text string, of definite length or made of the
+// definite length chunks of an indefinite length one.
+func (d *cborDecoder) str(major, info byte, arg uint64) ([]byte, error) {
+	if info != cborIndefinite {
+		return d.read(arg)
+	}
+	var s []byte
+	for {
+		if end, err := d.atBreak(); err != nil || end {
+			return s, err
+		}
+		m, i, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || i == cborIndefinite {
+			return nil, errors.New("invalid chunk of indefinite length string")
+		}
+		chunk, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+}
+
+// key reads the key of a map member, a text string or an integer.
+func (d *cborDecoder) key() (string, error) {
+	if d.off >= len(d.buf) {
+		return "", io.ErrUnexpectedEOF
+	}
+	switch d.buf[d.off] >> 5 {
+	case cborUint, cborNegInt, cborText:
+	default:
+		return "", fmt.Errorf("unsupported map key of major type %d", d.buf[d.off]>>5)
+	}
+	v, err := d.value(0)
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+func (d *cborDecoder) simple(info byte, arg uint64) (interface{}, error) {
+	switch info {
+	case 20:
+		return "false", nil
+	case 21:
+		return "true", nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return strconv.FormatFloat(float16(uint16(arg)), 'f', -1, 32), nil
+	case 26:
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(arg))), 'f', -1, 32), nil
+	case 27:
+		return strconv.FormatFloat(math.Float64frombits(arg), 'f', -1, 64), nil
+	}
+	return nil, fmt.Errorf("unsupported simple value %d", arg)
+}
+
+// float16 converts a half-precision float.
+func float16(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant != 0 {
+			return math.NaN()
+		}
+		v = math.Inf(1)
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		v = -v
+	}
+	return v
+}
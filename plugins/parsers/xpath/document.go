@@ -0,0 +1,236 @@
+package xpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// maxDepth is the maximum nesting of the arrays and maps of the JSON and
+// CBOR documents.
+const maxDepth = 128
+
+// object is a JSON object or CBOR map, its members kept in document order.
+type object []member
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+// parseXML reads an XML document, its elements and attributes named with
+// the namespace prefix they are written with, the queries match them with
+// the same prefix.  The text made only of whitespace, between the elements,
+// is left out.
+func parseXML(buf []byte) (*node, error) {
+	root := newRoot()
+	curr := root
+	d := xml.NewDecoder(bytes.NewReader(buf))
+	for {
+		// the raw tokens keep the prefixes instead of the namespaces
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			curr = curr.addElement(t.Name.Space, t.Name.Local)
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+					continue
+				}
+				curr.addAttribute(a.Name.Space, a.Name.Local, a.Value)
+			}
+		case xml.EndElement:
+			if curr == root || t.Name.Space != curr.prefix || t.Name.Local != curr.name {
+				return nil, fmt.Errorf("unexpected end element </%s>", qualifiedName(t.Name))
+			}
+			curr = curr.parent
+		case xml.CharData:
+			if curr == root || len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			if last := len(curr.children) - 1; last >= 0 && curr.children[last].typ == xpath.TextNode {
+				curr.children[last].text += string(t)
+				continue
+			}
+			curr.addText(string(t))
+		}
+	}
+	if curr != root {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if len(root.children) == 0 {
+		return nil, errors.New("no root element")
+	}
+	return root, nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// parseJSON reads a JSON document, in the same node model as the CBOR
+// documents.
+func parseJSON(buf []byte) (*node, error) {
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.UseNumber()
+	v, err := decodeJSON(d, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the document")
+	}
+	return newDocument(v)
+}
+
+// decodeJSON decodes a value as an object, an array, the text of a scalar,
+// or nil for null.
+func decodeJSON(d *json.Decoder, depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("nested deeper than %d levels", maxDepth)
+	}
+	tok, err := d.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			obj := object{}
+			for d.More() {
+				key, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := decodeJSON(d, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, member{key: key.(string), value: v})
+			}
+			_, err := d.Token()
+			return obj, err
+		}
+		array := []interface{}{}
+		for d.More() {
+			v, err := decodeJSON(d, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		}
+		_, err := d.Token()
+		return array, err
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case string:
+		return t, nil
+	}
+	return nil, nil
+}
+
+// newDocument maps a decoded JSON or CBOR value to the node model, the
+// members of a top-level object are the root elements.
+func newDocument(v interface{}) (*node, error) {
+	root := newRoot()
+	switch v := v.(type) {
+	case object:
+		if err := addMembers(root, v); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		if err := addValue(root, "", v); err != nil {
+			return nil, err
+		}
+	case string:
+		root.addText(v)
+	}
+	return root, nil
+}
+
+// addMembers adds the members of an object to the node.  The members named
+// "@name" are the attributes of the node and "#text" its text, the same
+// documents written in XML and JSON are then navigated the same way.  All
+// the other members are the children of the node named after their key.
+func addMembers(n *node, obj object) error {
+	for _, m := range obj {
+		switch {
+		case strings.HasPrefix(m.key, "@"):
+			switch v := m.value.(type) {
+			case string:
+				prefix, name := splitName(m.key[1:])
+				n.addAttribute(prefix, name, v)
+			case nil:
+			default:
+				return fmt.Errorf("attribute %q is not a scalar value", m.key)
+			}
+		case m.key == "#text":
+			switch v := m.value.(type) {
+			case string:
+				if v != "" {
+					n.addText(v)
+				}
+			case nil:
+			default:
+				return errors.New("#text is not a scalar value")
+			}
+		default:
+			if err := addValue(n, m.key, m.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addValue adds a value to the node as the element name, with the prefix
+// before its first colon as in XML.  Each element of an array is an element
+// named after the array, as the repeated elements of XML, and an array within
+// an array is an element holding its own elements.
+func addValue(n *node, name string, v interface{}) error {
+	prefix, local := splitName(name)
+	switch v := v.(type) {
+	case object:
+		return addMembers(n.addElement(prefix, local), v)
+	case []interface{}:
+		for _, item := range v {
+			parent := n
+			if _, ok := item.([]interface{}); ok {
+				parent = n.addElement(prefix, local)
+			}
+			if err := addValue(parent, name, item); err != nil {
+				return err
+			}
+		}
+	case string:
+		e := n.addElement(prefix, local)
+		if v != "" {
+			e.addText(v)
+		}
+	default:
+		n.addElement(prefix, local)
+	}
+	return nil
+}
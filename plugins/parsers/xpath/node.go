@@ -0,0 +1,192 @@
+package xpath
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/antchfx/xpath"
+)
+
+// node is a node of the documents of all the formats, navigated the same
+// way by the queries: the root of a document, an element, or the text of an
+// element.
+type node struct {
+	typ      xpath.NodeType
+	prefix   string
+	name     string
+	text     string
+	attrs    []attribute
+	parent   *node
+	children []*node
+	// index is the position of the node in the children of its parent
+	index int
+}
+
+type attribute struct {
+	prefix string
+	name   string
+	value  string
+}
+
+// splitName splits the namespace prefix of a name, as in XML.
+func splitName(name string) (prefix, local string) {
+	if i := strings.Index(name, ":"); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func newRoot() *node {
+	return &node{typ: xpath.RootNode}
+}
+
+func (n *node) addChild(child *node) *node {
+	child.parent = n
+	child.index = len(n.children)
+	n.children = append(n.children, child)
+	return child
+}
+
+func (n *node) addElement(prefix, name string) *node {
+	return n.addChild(&node{typ: xpath.ElementNode, prefix: prefix, name: name})
+}
+
+func (n *node) addText(text string) {
+	n.addChild(&node{typ: xpath.TextNode, text: text})
+}
+
+func (n *node) addAttribute(prefix, name, value string) {
+	n.attrs = append(n.attrs, attribute{prefix: prefix, name: name, value: value})
+}
+
+// value is the string-value of the node, the concatenation of the text of
+// its descendants.
+func (n *node) value() string {
+	if n.typ == xpath.TextNode {
+		return n.text
+	}
+	var buf bytes.Buffer
+	var write func(*node)
+	write = func(n *node) {
+		if n.typ == xpath.TextNode {
+			buf.WriteString(n.text)
+		}
+		for _, child := range n.children {
+			write(child)
+		}
+	}
+	write(n)
+	return buf.String()
+}
+
+// navigator is the xpath.NodeNavigator over the nodes, on an attribute of
+// the current node when attr is not -1.
+type navigator struct {
+	root *node
+	curr *node
+	attr int
+}
+
+func newNavigator(root *node) *navigator {
+	return &navigator{root: root, curr: root, attr: -1}
+}
+
+func (n *navigator) NodeType() xpath.NodeType {
+	if n.attr != -1 {
+		return xpath.AttributeNode
+	}
+	return n.curr.typ
+}
+
+func (n *navigator) LocalName() string {
+	if n.attr != -1 {
+		return n.curr.attrs[n.attr].name
+	}
+	return n.curr.name
+}
+
+func (n *navigator) Prefix() string {
+	if n.attr != -1 {
+		return n.curr.attrs[n.attr].prefix
+	}
+	return n.curr.prefix
+}
+
+func (n *navigator) Value() string {
+	if n.attr != -1 {
+		return n.curr.attrs[n.attr].value
+	}
+	return n.curr.value()
+}
+
+func (n *navigator) Copy() xpath.NodeNavigator {
+	c := *n
+	return &c
+}
+
+func (n *navigator) MoveToRoot() {
+	n.curr = n.root
+	n.attr = -1
+}
+
+func (n *navigator) MoveToParent() bool {
+	if n.attr != -1 {
+		n.attr = -1
+		return true
+	}
+	if n.curr.parent == nil {
+		return false
+	}
+	n.curr = n.curr.parent
+	return true
+}
+
+func (n *navigator) MoveToNextAttribute() bool {
+	if n.attr >= len(n.curr.attrs)-1 {
+		return false
+	}
+	n.attr++
+	return true
+}
+
+func (n *navigator) MoveToChild() bool {
+	if n.attr != -1 || len(n.curr.children) == 0 {
+		return false
+	}
+	n.curr = n.curr.children[0]
+	return true
+}
+
+func (n *navigator) MoveToFirst() bool {
+	if n.attr != -1 || n.curr.parent == nil || n.curr.index == 0 {
+		return false
+	}
+	n.curr = n.curr.parent.children[0]
+	return true
+}
+
+func (n *navigator) MoveToNext() bool {
+	if n.attr != -1 || n.curr.parent == nil || n.curr.index == len(n.curr.parent.children)-1 {
+		return false
+	}
+	n.curr = n.curr.parent.children[n.curr.index+1]
+	return true
+}
+
+func (n *navigator) MoveToPrevious() bool {
+	if n.attr != -1 || n.curr.parent == nil || n.curr.index == 0 {
+		return false
+	}
+	n.curr = n.curr.parent.children[n.curr.index-1]
+	return true
+}
+
+func (n *navigator) MoveTo(other xpath.NodeNavigator) bool {
+	o, ok := other.(*navigator)
+	if !ok || o.root != n.root {
+		return false
+	}
+	n.curr = o.curr
+	n.attr = o.attr
+	return true
+}
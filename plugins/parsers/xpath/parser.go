@@ -0,0 +1,357 @@
+package xpath
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antchfx/xpath"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Config are the queries of the metrics selected in the documents, relative
+// to the selected nodes.
+type Config struct {
+	MetricSelection string            `toml:"metric_selection"`
+	MetricName      string            `toml:"metric_name"`
+	Timestamp       string            `toml:"timestamp"`
+	TimestampFormat string            `toml:"timestamp_format"`
+	Tags            map[string]string `toml:"tags"`
+	Fields          map[string]string `toml:"fields"`
+	FieldsInt       map[string]string `toml:"fields_int"`
+	// FieldSelection selects the nodes parsed as fields, named after
+	// FieldName and valued with FieldValue relative to each of them
+	FieldSelection string `toml:"field_selection"`
+	FieldName      string `toml:"field_name"`
+	FieldValue     string `toml:"field_value"`
+}
+
+// Parser parses the metrics of XML, JSON or CBOR documents with XPath
+// queries.  The documents of all the formats are navigated as the same node
+// model, so the same queries parse the same documents in any of them.
+type Parser struct {
+	// Format of the documents, "xml", "json" or "cbor"
+	Format      string
+	Configs     []Config
+	MetricName  string
+	DefaultTags map[string]string
+
+	TimeFunc func() time.Time
+
+	decode func([]byte) (*node, error)
+	// mu serializes the evaluation of the queries, they keep their state
+	// while evaluated
+	mu      sync.Mutex
+	queries []*queries
+}
+
+// queries are the compiled queries of a Config.
+type queries struct {
+	selection       *xpath.Expr
+	name            *xpath.Expr
+	timestamp       *xpath.Expr
+	timestampFormat string
+	tags            []namedQuery
+	fields          []namedQuery
+	fieldsInt       []namedQuery
+	fieldSelection  *xpath.Expr
+	fieldName       *xpath.Expr
+	fieldValue      *xpath.Expr
+}
+
+type namedQuery struct {
+	name string
+	expr *xpath.Expr
+}
+
+var decoders = map[string]func([]byte) (*node, error){
+	"xml":  parseXML,
+	"json": parseJSON,
+	"cbor": parseCBOR,
+}
+
+// Init compiles the queries.
+func (p *Parser) Init() error {
+	var ok bool
+	if p.decode, ok = decoders[p.Format]; !ok {
+		return fmt.Errorf("invalid document format %q", p.Format)
+	}
+	if len(p.Configs) == 0 {
+		return fmt.Errorf("no xpath queries")
+	}
+
+	p.queries = nil
+	for _, c := range p.Configs {
+		q, err := compileConfig(c)
+		if err != nil {
+			return err
+		}
+		p.queries = append(p.queries, q)
+	}
+
+	if p.TimeFunc == nil {
+		p.TimeFunc = time.Now
+	}
+	return nil
+}
+
+func compileConfig(c Config) (*queries, error) {
+	var q queries
+	var err error
+	if c.MetricSelection == "" {
+		c.MetricSelection = "/"
+	}
+	if q.selection, err = compile(c.MetricSelection); err != nil {
+		return nil, err
+	}
+	if q.name, err = compile(c.MetricName); err != nil {
+		return nil, err
+	}
+	if q.timestamp, err = compile(c.Timestamp); err != nil {
+		return nil, err
+	}
+	q.timestampFormat = c.TimestampFormat
+	if q.timestampFormat == "" {
+		q.timestampFormat = time.RFC3339
+	}
+
+	if q.tags, err = compileNamed(c.Tags); err != nil {
+		return nil, err
+	}
+	if q.fields, err = compileNamed(c.Fields); err != nil {
+		return nil, err
+	}
+	if q.fieldsInt, err = compileNamed(c.FieldsInt); err != nil {
+		return nil, err
+	}
+
+	if c.FieldSelection != "" {
+		if c.FieldName == "" {
+			c.FieldName = "name()"
+		}
+		if c.FieldValue == "" {
+			c.FieldValue = "."
+		}
+		if q.fieldSelection, err = compile(c.FieldSelection); err != nil {
+			return nil, err
+		}
+		if q.fieldName, err = compile(c.FieldName); err != nil {
+			return nil, err
+		}
+		if q.fieldValue, err = compile(c.FieldValue); err != nil {
+			return nil, err
+		}
+	}
+	return &q, nil
+}
+
+// compile compiles a query, no query is nil.
+func compile(s string) (*xpath.Expr, error) {
+	if s == "" {
+		return nil, nil
+	}
+	expr, err := xpath.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %v", s, err)
+	}
+	return expr, nil
+}
+
+// compileNamed compiles the queries of the tags or fields, sorted by name.
+func compileNamed(m map[string]string) ([]namedQuery, error) {
+	named := make([]namedQuery, 0, len(m))
+	for name, s := range m {
+		expr, err := compile(s)
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			named = append(named, namedQuery{name: name, expr: expr})
+		}
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].name < named[j].name })
+	return named, nil
+}
+
+// Parse decodes a document, parsed as one metric per node selected by the
+// metric selection of each of the configurations.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	root, err := p.decode(buf)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s document: %v", p.Format, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.TimeFunc()
+	var metrics []telegraf.Metric
+	for _, q := range p.queries {
+		nodes := q.selection.Select(newNavigator(root))
+		for nodes.MoveNext() {
+			m, err := p.parseNode(q, nodes.Current().Copy(), now)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// parseNode parses the metric of a selected node, nil if it has no field.
+func (p *Parser) parseNode(q *queries, n xpath.NodeNavigator, now time.Time) (telegraf.Metric, error) {
+	name := p.MetricName
+	if q.name != nil {
+		if v, ok := evaluate(q.name, n); ok && toString(v) != "" {
+			name = toString(v)
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, t := range q.tags {
+		if v, ok := evaluate(t.expr, n); ok && toString(v) != "" {
+			tags[t.name] = toString(v)
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for _, f := range q.fields {
+		if v, ok := evaluate(f.expr, n); ok {
+			fields[f.name] = v
+		}
+	}
+	for _, f := range q.fieldsInt {
+		v, ok := evaluate(f.expr, n)
+		if !ok {
+			continue
+		}
+		i, err := toInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", f.name, err)
+		}
+		fields[f.name] = i
+	}
+	if q.fieldSelection != nil {
+		nodes := q.fieldSelection.Select(n.Copy())
+		for nodes.MoveNext() {
+			field := nodes.Current().Copy()
+			v, ok := evaluate(q.fieldName, field)
+			if !ok || toString(v) == "" {
+				continue
+			}
+			if value, ok := evaluate(q.fieldValue, field); ok {
+				fields[toString(v)] = value
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	tm := now
+	if q.timestamp != nil {
+		if v, ok := evaluate(q.timestamp, n); ok {
+			var err error
+			if tm, err = parseTimestamp(v, q.timestampFormat); err != nil {
+				return nil, fmt.Errorf("timestamp: %v", err)
+			}
+		}
+	}
+	return metric.New(name, tags, fields, tm)
+}
+
+// evaluate returns the result of the query on the node, a string, a float
+// or a bool.  The node sets result in the value of their first node, and
+// nothing when empty, as the numbers not a number.
+func evaluate(expr *xpath.Expr, n xpath.NodeNavigator) (interface{}, bool) {
+	switch v := expr.Evaluate(n.Copy()).(type) {
+	case *xpath.NodeIterator:
+		if !v.MoveNext() {
+			return nil, false
+		}
+		return v.Current().Value(), true
+	case float64:
+		if math.IsNaN(v) {
+			return nil, false
+		}
+		return v, true
+	case string, bool:
+		return v, true
+	}
+	return nil, false
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return v.(string)
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return strconv.ParseInt(strings.TrimSpace(v.(string)), 10, 64)
+}
+
+var units = map[string]int64{
+	"unix":    int64(time.Second),
+	"unix_ms": int64(time.Millisecond),
+	"unix_us": int64(time.Microsecond),
+	"unix_ns": 1,
+}
+
+// parseTimestamp parses an epoch in one of the units, or a time in the Go
+// layout of the format.
+func parseTimestamp(v interface{}, format string) (time.Time, error) {
+	unit, ok := units[format]
+	if !ok {
+		return time.Parse(format, strings.TrimSpace(toString(v)))
+	}
+
+	s := strings.TrimSpace(toString(v))
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, n*unit), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s timestamp %q", format, s)
+	}
+	return time.Unix(0, int64(f*float64(unit))), nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("expected 1 metric, got %d", len(metrics))
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
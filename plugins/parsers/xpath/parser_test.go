@@ -0,0 +1,393 @@
+package xpath
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1500000000, 0)
+
+func newParser(t *testing.T, format string, configs ...Config) *Parser {
+	p := &Parser{
+		Format:      format,
+		Configs:     configs,
+		MetricName:  "file",
+		DefaultTags: map[string]string{"site": "lab"},
+		TimeFunc:    func() time.Time { return now },
+	}
+	require.NoError(t, p.Init())
+	return p
+}
+
+// cborEncode writes the values of the tests, the objects keep the order of
+// their members.
+func cborEncode(v interface{}) []byte {
+	var buf bytes.Buffer
+	head := func(major byte, n uint64) {
+		switch {
+		case n < 24:
+			buf.WriteByte(major<<5 | byte(n))
+		case n <= math.MaxUint8:
+			buf.Write([]byte{major<<5 | 24, byte(n)})
+		case n <= math.MaxUint16:
+			buf.WriteByte(major<<5 | 25)
+			binary.Write(&buf, binary.BigEndian, uint16(n))
+		case n <= math.MaxUint32:
+			buf.WriteByte(major<<5 | 26)
+			binary.Write(&buf, binary.BigEndian, uint32(n))
+		default:
+			buf.WriteByte(major<<5 | 27)
+			binary.Write(&buf, binary.BigEndian, n)
+		}
+	}
+	var encode func(v interface{})
+	encode = func(v interface{}) {
+		switch v := v.(type) {
+		case int:
+			if v < 0 {
+				head(cborNegInt, uint64(-1-v))
+			} else {
+				head(cborUint, uint64(v))
+			}
+		case float64:
+			buf.WriteByte(cborSimple<<5 | 27)
+			binary.Write(&buf, binary.BigEndian, math.Float64bits(v))
+		case bool:
+			if v {
+				buf.WriteByte(cborSimple<<5 | 21)
+			} else {
+				buf.WriteByte(cborSimple<<5 | 20)
+			}
+		case nil:
+			buf.WriteByte(cborSimple<<5 | 22)
+		case string:
+			head(cborText, uint64(len(v)))
+			buf.WriteString(v)
+		case []byte:
+			head(cborBytes, uint64(len(v)))
+			buf.Write(v)
+		case []interface{}:
+			head(cborArray, uint64(len(v)))
+			for _, item := range v {
+				encode(item)
+			}
+		case object:
+			head(cborMap, uint64(len(v)))
+			for _, m := range v {
+				encode(m.key)
+				encode(m.value)
+			}
+		}
+	}
+	encode(v)
+	return buf.Bytes()
+}
+
+// The same report, in each of the formats.
+const reportXML = `<?xml version="1.0" encoding="UTF-8"?>
+<report host="server01">
+  <time>1500000001</time>
+  <cpu name="cpu0">
+    <usage_idle>98.5</usage_idle>
+    <usage_user>1.5</usage_user>
+    <cores>4</cores>
+    <online>true</online>
+  </cpu>
+  <cpu name="cpu1">
+    <usage_idle>97</usage_idle>
+    <usage_user>3</usage_user>
+    <cores>2</cores>
+    <online>false</online>
+  </cpu>
+</report>
+`
+
+const reportJSON = `{
+  "report": {
+    "@host": "server01",
+    "time": 1500000001,
+    "cpu": [
+      {"@name": "cpu0", "usage_idle": 98.5, "usage_user": 1.5, "cores": 4, "online": true},
+      {"@name": "cpu1", "usage_idle": 97, "usage_user": 3, "cores": 2, "online": false}
+    ]
+  }
+}`
+
+var reportCBOR = cborEncode(object{
+	{"report", object{
+		{"@host", "server01"},
+		{"time", 1500000001},
+		{"cpu", []interface{}{
+			object{{"@name", "cpu0"}, {"usage_idle", 98.5}, {"usage_user", 1.5}, {"cores", 4}, {"online", true}},
+			object{{"@name", "cpu1"}, {"usage_idle", 97.0}, {"usage_user", 3.0}, {"cores", 2}, {"online", false}},
+		}},
+	}},
+})
+
+var reportConfigs = []Config{
+	{
+		MetricSelection: "/report/cpu",
+		MetricName:      "'cpu'",
+		Timestamp:       "../time",
+		TimestampFormat: "unix",
+		Tags: map[string]string{
+			"host": "../@host",
+			"cpu":  "@name",
+		},
+		Fields: map[string]string{
+			"usage_idle": "number(usage_idle)",
+			"usage_user": "number(usage_user)",
+			"online":     "online = 'true'",
+		},
+		FieldsInt: map[string]string{
+			"cores": "cores",
+		},
+	},
+	{
+		MetricSelection: "/report",
+		MetricName:      "name()",
+		FieldSelection:  "cpu/usage_idle",
+		FieldName:       "concat('idle_', ../@name)",
+		FieldValue:      "number(.)",
+	},
+}
+
+var reportMetrics = []telegraf.Metric{
+	testutil.MustMetric("cpu",
+		map[string]string{"site": "lab", "host": "server01", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 98.5, "usage_user": 1.5, "online": true, "cores": int64(4)},
+		time.Unix(1500000001, 0)),
+	testutil.MustMetric("cpu",
+		map[string]string{"site": "lab", "host": "server01", "cpu": "cpu1"},
+		map[string]interface{}{"usage_idle": 97.0, "usage_user": 3.0, "online": false, "cores": int64(2)},
+		time.Unix(1500000001, 0)),
+	testutil.MustMetric("report",
+		map[string]string{"site": "lab"},
+		map[string]interface{}{"idle_cpu0": 98.5, "idle_cpu1": 97.0},
+		now),
+}
+
+// Test that the same queries parse the same metrics in all the formats
+func TestParseFormats(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		doc    []byte
+	}{
+		{"xml", []byte(reportXML)},
+		{"json", []byte(reportJSON)},
+		{"cbor", reportCBOR},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			p := newParser(t, tt.format, reportConfigs...)
+			metrics, err := p.Parse(tt.doc)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, reportMetrics, metrics)
+		})
+	}
+}
+
+func TestParseXML(t *testing.T) {
+	p := newParser(t, "xml", Config{
+		MetricSelection: "//m:sensor",
+		Fields: map[string]string{
+			"label": "text()",
+			"note":  "string(note)",
+			"count": "count(*)",
+		},
+	})
+
+	// the elements are matched with their prefix, the text only made of
+	// whitespace is left out
+	metrics, err := p.Parse([]byte(`
+<m:sensors xmlns:m="urn:sensors">
+  <m:sensor id="a">first <note><![CDATA[<raw>]]></note></m:sensor>
+</m:sensors>`))
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("file",
+			map[string]string{"site": "lab"},
+			map[string]interface{}{"label": "first ", "note": "<raw>", "count": 1.0},
+			now),
+	}, metrics)
+}
+
+func TestParseJSON(t *testing.T) {
+	p := newParser(t, "json",
+		Config{
+			MetricSelection: "/*",
+			Tags:            map[string]string{"id": "@id"},
+			Fields: map[string]string{
+				"value": "number(values[2])",
+				"count": "count(values)",
+				"rows":  "count(matrix)",
+				"cell":  "number(matrix[2]/matrix[1])",
+				"text":  "string(text())",
+				"empty": "boolean(missing)",
+			},
+		})
+
+	// a top-level array, of which the elements are unnamed
+	metrics, err := p.Parse([]byte(`[
+  {"@id": "a", "#text": "label", "values": [1, 2, 3], "matrix": [[1, 2], [3, 4]], "missing": null},
+  {"@id": "b", "values": []}
+]`))
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("file",
+			map[string]string{"site": "lab", "id": "a"},
+			map[string]interface{}{"value": 2.0, "count": 3.0, "rows": 2.0, "cell": 3.0, "text": "label", "empty": true},
+			now),
+		testutil.MustMetric("file",
+			map[string]string{"site": "lab", "id": "b"},
+			map[string]interface{}{"value": 0.0, "count": 0.0, "rows": 0.0, "cell": 0.0, "text": "", "empty": false},
+			now),
+	}, metrics)
+}
+
+func TestParseCBOR(t *testing.T) {
+	p := newParser(t, "cbor", Config{
+		FieldSelection: "/*",
+	})
+
+	doc := []byte{0xbf} // indefinite length map
+	for _, item := range [][]byte{
+		// unsigned, negative and big integers
+		{0x61, 'a'}, {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0x61, 'b'}, {0x38, 0x63},
+		{0x61, 'c'}, {0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		// half, single and double precision floats
+		{0x61, 'd'}, {0xf9, 0x3e, 0x00},
+		{0x61, 'e'}, {0xfa, 0x47, 0xc3, 0x50, 0x00},
+		{0x61, 'f'}, {0xfb, 0xc0, 0x10, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66},
+		// an epoch tagged as a time, and a byte string
+		{0x61, 'g'}, {0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0},
+		{0x61, 'h'}, {0x43, 0x01, 0x02, 0x03},
+		// indefinite length text string, and an integer key
+		{0x61, 'i'}, {0x7f, 0x65, 's', 't', 'r', 'e', 'a', 0x64, 'm', 'i', 'n', 'g', 0xff},
+		{0x18, 0x2a}, {0xf5},
+		// null and undefined are empty
+		{0x61, 'j'}, {0xf6},
+		{0x61, 'k'}, {0xf7},
+	} {
+		doc = append(doc, item...)
+	}
+	doc = append(doc, 0xff)
+
+	metrics, err := p.Parse(doc)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("file",
+			map[string]string{"site": "lab"},
+			map[string]interface{}{
+				"a":  "18446744073709551615",
+				"b":  "-100",
+				"c":  "-18446744073709551616",
+				"d":  "1.5",
+				"e":  "100000",
+				"f":  "-4.1",
+				"g":  "1363896240",
+				"h":  "AQID",
+				"i":  "streaming",
+				"42": "true",
+				"j":  "",
+				"k":  "",
+			},
+			now),
+	}, metrics)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for _, tt := range []struct {
+		format   string
+		value    string
+		expected time.Time
+	}{
+		{"", "2017-07-14T02:40:01Z", time.Unix(1500000001, 0)},
+		{"2006-01-02 15:04:05", "2017-07-14 02:40:01", time.Unix(1500000001, 0)},
+		{"unix", "1500000001.5", time.Unix(1500000001, 500000000)},
+		{"unix_ms", "1500000001500", time.Unix(1500000001, 500000000)},
+		{"unix_us", "1500000001500000", time.Unix(1500000001, 500000000)},
+		{"unix_ns", "1500000001500000001", time.Unix(1500000001, 500000001)},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			p := newParser(t, "json", Config{
+				Timestamp:       "time",
+				TimestampFormat: tt.format,
+				Fields:          map[string]string{"value": "number(value)"},
+			})
+			metrics, err := p.Parse([]byte(`{"time": "` + tt.value + `", "value": 1}`))
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.True(t, tt.expected.Equal(metrics[0].Time()), metrics[0].Time())
+		})
+	}
+}
+
+// Test that the nodes without fields are not parsed as metrics
+func TestParseNoFields(t *testing.T) {
+	p := newParser(t, "json", Config{
+		MetricSelection: "/cpu",
+		Fields:          map[string]string{"usage_idle": "usage_idle"},
+	})
+
+	metrics, err := p.Parse([]byte(`{"cpu": [{"usage_idle": "98"}, {"usage_user": "2"}]}`))
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("file",
+			map[string]string{"site": "lab"},
+			map[string]interface{}{"usage_idle": "98"},
+			now),
+	}, metrics)
+}
+
+func TestParseErrors(t *testing.T) {
+	config := Config{
+		FieldsInt: map[string]string{"cores": "cores"},
+		Timestamp: "time",
+	}
+	for _, tt := range []struct {
+		name   string
+		format string
+		doc    string
+	}{
+		{"invalid xml", "xml", `<report><cores>4</report>`},
+		{"empty xml", "xml", ``},
+		{"invalid json", "json", `{"cores": 4`},
+		{"json after the document", "json", `{"cores": 4} {"cores": 2}`},
+		{"json attribute object", "json", `{"@cores": {"count": 4}}`},
+		{"truncated cbor", "cbor", "\xa1\x65cores"},
+		{"cbor after the document", "cbor", "\xa1\x65cores\x04\x04"},
+		{"cbor map key", "cbor", "\xa1\xf5\x04"},
+		{"invalid integer", "json", `{"cores": "four"}`},
+		{"invalid timestamp", "json", `{"cores": 4, "time": "yesterday"}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newParser(t, tt.format, config)
+			_, err := p.Parse([]byte(tt.doc))
+			require.Error(t, err)
+		})
+	}
+
+	// nested deeper than the maximum depth
+	p := newParser(t, "json", config)
+	_, err := p.Parse(bytes.Repeat([]byte("["), maxDepth+2))
+	require.Error(t, err)
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, p := range []*Parser{
+		{Format: "yaml", Configs: []Config{{}}},
+		{Format: "xml"},
+		{Format: "xml", Configs: []Config{{MetricSelection: "//["}}},
+		{Format: "xml", Configs: []Config{{Fields: map[string]string{"value": "number("}}}},
+	} {
+		require.Error(t, p.Init())
+	}
+}
@@ -1,9 +1,18 @@
 package all
 
 import (
+	_ "github.com/influxdata/telegraf/plugins/processors/batch"
+	_ "github.com/influxdata/telegraf/plugins/processors/clone"
 	_ "github.com/influxdata/telegraf/plugins/processors/converter"
 	_ "github.com/influxdata/telegraf/plugins/processors/dcos_metadata"
+	_ "github.com/influxdata/telegraf/plugins/processors/deadband"
+	_ "github.com/influxdata/telegraf/plugins/processors/dedup"
 	_ "github.com/influxdata/telegraf/plugins/processors/enum"
+	_ "github.com/influxdata/telegraf/plugins/processors/filter"
+	_ "github.com/influxdata/telegraf/plugins/processors/geoip"
+	_ "github.com/influxdata/telegraf/plugins/processors/ifname"
+	_ "github.com/influxdata/telegraf/plugins/processors/json_transform"
+	_ "github.com/influxdata/telegraf/plugins/processors/lookup"
 	_ "github.com/influxdata/telegraf/plugins/processors/lowercase"
 	_ "github.com/influxdata/telegraf/plugins/processors/nginx_vts_filter"
 	_ "github.com/influxdata/telegraf/plugins/processors/override"
@@ -11,6 +20,13 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/processors/printer"
 	_ "github.com/influxdata/telegraf/plugins/processors/regex"
 	_ "github.com/influxdata/telegraf/plugins/processors/rename"
+	_ "github.com/influxdata/telegraf/plugins/processors/reverse_dns"
+	_ "github.com/influxdata/telegraf/plugins/processors/sample"
+	_ "github.com/influxdata/telegraf/plugins/processors/scale"
+	_ "github.com/influxdata/telegraf/plugins/processors/split"
 	_ "github.com/influxdata/telegraf/plugins/processors/strings"
+	_ "github.com/influxdata/telegraf/plugins/processors/tag_limit"
+	_ "github.com/influxdata/telegraf/plugins/processors/timestamp"
 	_ "github.com/influxdata/telegraf/plugins/processors/topk"
+	_ "github.com/influxdata/telegraf/plugins/processors/unit"
 )
@@ -0,0 +1,99 @@
+package batch
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Name of the tag holding the batch id.
+  # batch_tag = "batch_id"
+
+  ## Number of batches, the batch ids range from 0 to batches - 1.
+  batches = 4
+
+  ## How metrics are assigned to batches:
+  ##   round_robin - in turn, per Telegraf process
+  ##   hash_by_tag - by a hash of the tags, stable across restarts
+  # mode = "round_robin"
+
+  ## Tags hashed in hash_by_tag mode.  By default the measurement name and
+  ## all tags are hashed, so each series always lands in the same batch.
+  # hash_tags = []
+`
+
+type Batch struct {
+	BatchTag string   `toml:"batch_tag"`
+	Batches  int      `toml:"batches"`
+	Mode     string   `toml:"mode"`
+	HashTags []string `toml:"hash_tags"`
+
+	next int
+}
+
+func (b *Batch) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *Batch) Description() string {
+	return "Add a batch id tag to metrics to partition them for downstream outputs."
+}
+
+func (b *Batch) Init() error {
+	if b.Batches < 1 {
+		return fmt.Errorf("batches must be at least 1")
+	}
+	if b.BatchTag == "" {
+		return fmt.Errorf("batch_tag must be set")
+	}
+	switch b.Mode {
+	case "":
+		b.Mode = "round_robin"
+	case "round_robin", "hash_by_tag":
+	default:
+		return fmt.Errorf("invalid mode %q", b.Mode)
+	}
+	return nil
+}
+
+func (b *Batch) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		var id int
+		if b.Mode == "hash_by_tag" {
+			id = int(b.hash(metric) % uint64(b.Batches))
+		} else {
+			id = b.next
+			b.next = (b.next + 1) % b.Batches
+		}
+		metric.AddTag(b.BatchTag, strconv.Itoa(id))
+	}
+	return in
+}
+
+// hash returns the hash of the hash_tags of the metric, or of its series if
+// no hash_tags are set.  Missing tags hash as empty values.
+func (b *Batch) hash(metric telegraf.Metric) uint64 {
+	if len(b.HashTags) == 0 {
+		return metric.HashID()
+	}
+	h := fnv.New64a()
+	for _, key := range b.HashTags {
+		value, _ := metric.GetTag(key)
+		h.Write([]byte(value))
+		h.Write([]byte("\n"))
+	}
+	return h.Sum64()
+}
+
+func init() {
+	processors.Add("batch", func() telegraf.Processor {
+		return &Batch{
+			BatchTag: "batch_id",
+			Mode:     "round_robin",
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package batch
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(tags map[string]string) telegraf.Metric {
+	m, _ := metric.New("cpu", tags,
+		map[string]interface{}{"usage": 42.0},
+		time.Unix(1500000000, 0),
+	)
+	return m
+}
+
+func newBatch(mode string, batches int) *Batch {
+	return &Batch{
+		BatchTag: "batch_id",
+		Batches:  batches,
+		Mode:     mode,
+	}
+}
+
+func batchID(t *testing.T, m telegraf.Metric) string {
+	id, ok := m.GetTag("batch_id")
+	require.True(t, ok)
+	return id
+}
+
+func TestRoundRobinBalanced(t *testing.T) {
+	b := newBatch("round_robin", 4)
+	require.NoError(t, b.Init())
+
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		m := newMetric(map[string]string{"host": "server01"})
+		for _, m := range b.Apply(m) {
+			counts[batchID(t, m)]++
+		}
+	}
+	require.Equal(t, map[string]int{"0": 25, "1": 25, "2": 25, "3": 25}, counts)
+}
+
+func TestHashByTagStable(t *testing.T) {
+	b := newBatch("hash_by_tag", 8)
+	require.NoError(t, b.Init())
+	other := newBatch("hash_by_tag", 8)
+	require.NoError(t, other.Init())
+
+	for i := 0; i < 50; i++ {
+		tags := map[string]string{"host": "server" + strconv.Itoa(i)}
+		first := batchID(t, b.Apply(newMetric(tags))[0])
+		require.Equal(t, first, batchID(t, b.Apply(newMetric(tags))[0]))
+		require.Equal(t, first, batchID(t, other.Apply(newMetric(tags))[0]))
+	}
+}
+
+func TestHashByTagDistributed(t *testing.T) {
+	b := newBatch("hash_by_tag", 4)
+	require.NoError(t, b.Init())
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		m := newMetric(map[string]string{"host": "server" + strconv.Itoa(i)})
+		counts[batchID(t, b.Apply(m)[0])]++
+	}
+	require.Len(t, counts, 4)
+	for id, n := range counts {
+		require.InDelta(t, 250, n, 75, "batch %s", id)
+	}
+}
+
+func TestHashTags(t *testing.T) {
+	b := newBatch("hash_by_tag", 16)
+	b.HashTags = []string{"host"}
+	require.NoError(t, b.Init())
+
+	for i := 0; i < 20; i++ {
+		host := "server" + strconv.Itoa(i)
+		cpu0 := b.Apply(newMetric(map[string]string{"host": host, "cpu": "cpu0"}))[0]
+		cpu1 := b.Apply(newMetric(map[string]string{"host": host, "cpu": "cpu1"}))[0]
+		require.Equal(t, batchID(t, cpu0), batchID(t, cpu1))
+	}
+}
+
+func TestInit(t *testing.T) {
+	b := newBatch("", 2)
+	require.NoError(t, b.Init())
+	require.Equal(t, "round_robin", b.Mode)
+
+	require.Error(t, newBatch("random", 2).Init())
+	require.Error(t, newBatch("round_robin", 0).Init())
+}
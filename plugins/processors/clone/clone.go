@@ -0,0 +1,63 @@
+package clone
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## All modifications on inputs and aggregators can be overridden on the
+  ## clones, the original metrics pass unchanged:
+  # name_override = "new_name"
+  # name_prefix = "new_name_prefix"
+  # name_suffix = "new_name_suffix"
+
+  ## Tags to be added to the clones (all values must be strings)
+  # [processors.clone.tags]
+  #   additional_tag = "tag_value"
+`
+
+type Clone struct {
+	NameOverride string
+	NamePrefix   string
+	NameSuffix   string
+	Tags         map[string]string
+}
+
+func (c *Clone) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Clone) Description() string {
+	return "Clone metrics and apply modifications to the clones."
+}
+
+// Apply passes the metrics followed by their modified clones.  The clones do
+// not share any modification with the originals, made by this processor or
+// by the later ones.
+func (c *Clone) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, 2*len(in))
+	for _, original := range in {
+		clone := original.Copy()
+		if len(c.NameOverride) > 0 {
+			clone.SetName(c.NameOverride)
+		}
+		if len(c.NamePrefix) > 0 {
+			clone.AddPrefix(c.NamePrefix)
+		}
+		if len(c.NameSuffix) > 0 {
+			clone.AddSuffix(c.NameSuffix)
+		}
+		for key, value := range c.Tags {
+			clone.AddTag(key, value)
+		}
+		out = append(out, original, clone)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("clone", func() telegraf.Processor {
+		return &Clone{}
+	})
+}
@@ -0,0 +1,72 @@
+package clone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1500000000, 0)
+
+func createTestMetric() telegraf.Metric {
+	return testutil.MustMetric("m1",
+		map[string]string{"metric_tag": "from_metric"},
+		map[string]interface{}{"value": int64(1)},
+		now,
+	)
+}
+
+func TestCloneModifications(t *testing.T) {
+	processor := Clone{
+		NamePrefix: "rollup_",
+		NameSuffix: "_5m",
+		Tags:       map[string]string{"metric_tag": "from_config", "added_tag": "from_config"},
+	}
+
+	result := processor.Apply(createTestMetric())
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		createTestMetric(),
+		testutil.MustMetric("rollup_m1_5m",
+			map[string]string{"metric_tag": "from_config", "added_tag": "from_config"},
+			map[string]interface{}{"value": int64(1)},
+			now,
+		),
+	}, result)
+}
+
+func TestCloneNameOverride(t *testing.T) {
+	processor := Clone{NameOverride: "overridden"}
+
+	result := processor.Apply(createTestMetric(), createTestMetric())
+	require.Len(t, result, 4)
+	require.Equal(t, "m1", result[0].Name())
+	require.Equal(t, "overridden", result[1].Name())
+	require.Equal(t, "m1", result[2].Name())
+	require.Equal(t, "overridden", result[3].Name())
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	processor := Clone{}
+
+	// modifications of later processors are not shared
+	result := processor.Apply(createTestMetric())
+	original, clone := result[0], result[1]
+	clone.AddTag("metric_tag", "modified")
+	clone.AddField("value", int64(2))
+	clone.AddField("other", int64(3))
+	original.RemoveTag("metric_tag")
+	original.SetFieldMetadata("value", "unit", "count")
+
+	expected := testutil.MustMetric("m1", map[string]string{},
+		map[string]interface{}{"value": int64(1)}, now)
+	expected.SetFieldMetadata("value", "unit", "count")
+	testutil.RequireMetricEqual(t, expected, original)
+
+	testutil.RequireMetricEqual(t,
+		testutil.MustMetric("m1", map[string]string{"metric_tag": "modified"},
+			map[string]interface{}{"value": int64(2), "other": int64(3)}, now),
+		clone)
+}
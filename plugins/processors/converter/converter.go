@@ -244,7 +244,6 @@ func (p *Converter) convertFields(metric telegraf.Metric) {
 				continue
 			}
 
-			metric.RemoveField(key)
 			metric.AddField(key, v)
 			continue
 		}
@@ -257,7 +256,6 @@ func (p *Converter) convertFields(metric telegraf.Metric) {
 				continue
 			}
 
-			metric.RemoveField(key)
 			metric.AddField(key, v)
 			continue
 		}
@@ -270,7 +268,6 @@ func (p *Converter) convertFields(metric telegraf.Metric) {
 				continue
 			}
 
-			metric.RemoveField(key)
 			metric.AddField(key, v)
 			continue
 		}
@@ -283,7 +280,6 @@ func (p *Converter) convertFields(metric telegraf.Metric) {
 				continue
 			}
 
-			metric.RemoveField(key)
 			metric.AddField(key, v)
 			continue
 		}
@@ -296,7 +292,6 @@ func (p *Converter) convertFields(metric telegraf.Metric) {
 				continue
 			}
 
-			metric.RemoveField(key)
 			metric.AddField(key, v)
 			continue
 		}
@@ -0,0 +1,238 @@
+package deadband
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Minimum change of the numeric fields since their last passed value, as
+  ## an absolute difference or relative to the last passed value (0.01 for
+  ## 1%).  A change reaching either threshold is passed, a threshold of 0 is
+  ## not checked.  By default the fields are passed.
+  # absolute = 0.0
+  # relative = 0.0
+
+  ## Maximum time to suppress the changes below the thresholds, a field is
+  ## passed once this interval elapsed.  0 disables the heartbeat.
+  # heartbeat_interval = "10m"
+
+  ## Maximum number of series to remember, the least recently passed series
+  ## are forgotten first.
+  # max_series = 10000
+
+  ## Thresholds of fields, replacing the thresholds above for these fields.
+  # [[processors.deadband.field]]
+  #   name = "temperature"
+  #   absolute = 0.5
+  # [[processors.deadband.field]]
+  #   name = "pressure"
+  #   relative = 0.01
+`
+
+// Field is the thresholds of a field.
+type Field struct {
+	Name     string  `toml:"name"`
+	Absolute float64 `toml:"absolute"`
+	Relative float64 `toml:"relative"`
+}
+
+type Deadband struct {
+	Absolute          float64           `toml:"absolute"`
+	Relative          float64           `toml:"relative"`
+	HeartbeatInterval internal.Duration `toml:"heartbeat_interval"`
+	MaxSeries         int               `toml:"max_series"`
+	Fields            []Field           `toml:"field"`
+
+	thresholds map[string]Field
+
+	// cache holds the elements of lru by series id, lru orders the series
+	// from the most to the least recently passed.
+	cache     map[uint64]*list.Element
+	lru       *list.List
+	lastPurge time.Time
+}
+
+// series is the last values passed for the fields of a series.
+type series struct {
+	id     uint64
+	fields map[string]value
+}
+
+type value struct {
+	value float64
+	time  time.Time
+}
+
+func (d *Deadband) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Deadband) Description() string {
+	return "Drop the field values that changed less than a threshold since they were last passed."
+}
+
+// Init checks the thresholds.
+func (d *Deadband) Init() error {
+	if d.Absolute < 0 || d.Relative < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	d.thresholds = make(map[string]Field, len(d.Fields))
+	for _, f := range d.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("field name must be set")
+		}
+		if f.Absolute < 0 || f.Relative < 0 {
+			return fmt.Errorf("thresholds of field %s must not be negative", f.Name)
+		}
+		d.thresholds[f.Name] = f
+	}
+	return nil
+}
+
+// Apply removes the field values changed less than their thresholds, and
+// drops the metrics without fields left.
+func (d *Deadband) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		if d.filter(metric) {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// filter removes the fields of the metric within their deadband and
+// remembers the others.  It reports whether the metric has no fields left.
+func (d *Deadband) filter(metric telegraf.Metric) bool {
+	now := metric.Time()
+	d.purge(now)
+
+	id := metric.HashID()
+	e, ok := d.cache[id]
+	if !ok {
+		if d.MaxSeries > 0 && len(d.cache) >= d.MaxSeries {
+			d.evictOldest()
+		}
+		e = d.lru.PushFront(&series{id: id, fields: make(map[string]value)})
+		d.cache[id] = e
+	}
+	s := e.Value.(*series)
+
+	var passed bool
+	for _, field := range metric.FieldList() {
+		threshold, ok := d.threshold(field.Key)
+		if !ok {
+			continue
+		}
+		v, ok := toFloat(field.Value)
+		if !ok {
+			continue
+		}
+
+		last, ok := s.fields[field.Key]
+		if ok && !d.heartbeat(now, last.time) && !threshold.exceeded(last.value, v) {
+			metric.RemoveField(field.Key)
+			continue
+		}
+		s.fields[field.Key] = value{value: v, time: now}
+		passed = true
+	}
+	if passed {
+		d.lru.MoveToFront(e)
+	}
+	return len(metric.FieldList()) == 0
+}
+
+// threshold returns the thresholds of the field, if any is checked.
+func (d *Deadband) threshold(key string) (Field, bool) {
+	f, ok := d.thresholds[key]
+	if !ok {
+		f = Field{Name: key, Absolute: d.Absolute, Relative: d.Relative}
+	}
+	return f, f.Absolute > 0 || f.Relative > 0
+}
+
+// exceeded reports if the change from the last passed value reaches one of
+// the thresholds.
+func (f Field) exceeded(last, v float64) bool {
+	diff := math.Abs(v - last)
+	if f.Absolute > 0 && diff >= f.Absolute {
+		return true
+	}
+	if f.Relative > 0 && diff >= f.Relative*math.Abs(last) {
+		return true
+	}
+	return false
+}
+
+func (d *Deadband) heartbeat(now, last time.Time) bool {
+	return d.HeartbeatInterval.Duration > 0 && now.Sub(last) >= d.HeartbeatInterval.Duration
+}
+
+// purge forgets the fields whose heartbeat is due, as their next value is
+// passed anyway, and the series without fields left.  It runs at most once
+// per interval.
+func (d *Deadband) purge(now time.Time) {
+	if d.HeartbeatInterval.Duration <= 0 || now.Sub(d.lastPurge) < d.HeartbeatInterval.Duration {
+		return
+	}
+	d.lastPurge = now
+	for id, e := range d.cache {
+		s := e.Value.(*series)
+		for key, last := range s.fields {
+			if d.heartbeat(now, last.time) {
+				delete(s.fields, key)
+			}
+		}
+		if len(s.fields) == 0 {
+			d.lru.Remove(e)
+			delete(d.cache, id)
+		}
+	}
+}
+
+// evictOldest forgets the least recently passed series.
+func (d *Deadband) evictOldest() {
+	e := d.lru.Back()
+	if e == nil {
+		return
+	}
+	d.lru.Remove(e)
+	delete(d.cache, e.Value.(*series).id)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func newDeadband() *Deadband {
+	return &Deadband{
+		HeartbeatInterval: internal.Duration{Duration: 10 * time.Minute},
+		MaxSeries:         10000,
+		cache:             make(map[uint64]*list.Element),
+		lru:               list.New(),
+	}
+}
+
+func init() {
+	processors.Add("deadband", func() telegraf.Processor {
+		return newDeadband()
+	})
+}
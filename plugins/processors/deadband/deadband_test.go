@@ -0,0 +1,142 @@
+package deadband
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1500000000, 0)
+
+func newMetric(host string, seconds int, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("sensor",
+		map[string]string{"host": host},
+		fields,
+		start.Add(time.Duration(seconds)*time.Second),
+	)
+	return m
+}
+
+func newTestDeadband(t *testing.T, d *Deadband) *Deadband {
+	n := newDeadband()
+	n.Absolute = d.Absolute
+	n.Relative = d.Relative
+	n.Fields = d.Fields
+	n.HeartbeatInterval = internal.Duration{Duration: time.Minute}
+	require.NoError(t, n.Init())
+	return n
+}
+
+func TestAbsoluteThreshold(t *testing.T) {
+	d := newTestDeadband(t, &Deadband{Absolute: 0.5})
+
+	require.Len(t, d.Apply(newMetric("a", 0, map[string]interface{}{"temperature": 21.0})), 1)
+	require.Len(t, d.Apply(newMetric("a", 10, map[string]interface{}{"temperature": 21.3})), 0)
+	require.Len(t, d.Apply(newMetric("a", 20, map[string]interface{}{"temperature": 20.6})), 0)
+
+	// the change is from the last passed value, not the last received one
+	require.Len(t, d.Apply(newMetric("a", 30, map[string]interface{}{"temperature": 21.5})), 1)
+	require.Len(t, d.Apply(newMetric("a", 40, map[string]interface{}{"temperature": 21.9})), 0)
+	require.Len(t, d.Apply(newMetric("a", 50, map[string]interface{}{"temperature": 20.9})), 1)
+}
+
+func TestRelativeThreshold(t *testing.T) {
+	d := newTestDeadband(t, &Deadband{Relative: 0.1})
+
+	require.Len(t, d.Apply(newMetric("a", 0, map[string]interface{}{"pressure": int64(1000)})), 1)
+	require.Len(t, d.Apply(newMetric("a", 10, map[string]interface{}{"pressure": int64(1099)})), 0)
+	require.Len(t, d.Apply(newMetric("a", 20, map[string]interface{}{"pressure": int64(900)})), 1)
+	require.Len(t, d.Apply(newMetric("a", 30, map[string]interface{}{"pressure": int64(820)})), 0)
+}
+
+func TestHeartbeat(t *testing.T) {
+	d := newTestDeadband(t, &Deadband{Absolute: 1})
+	fields := map[string]interface{}{"temperature": 21.0}
+
+	require.Len(t, d.Apply(newMetric("a", 0, fields)), 1)
+	require.Len(t, d.Apply(newMetric("a", 59, fields)), 0)
+
+	// the heartbeat is passed once the interval elapsed
+	require.Len(t, d.Apply(newMetric("a", 60, fields)), 1)
+	require.Len(t, d.Apply(newMetric("a", 70, fields)), 0)
+	require.Len(t, d.Apply(newMetric("a", 130, fields)), 1)
+}
+
+func TestPerFieldThresholds(t *testing.T) {
+	d := newTestDeadband(t, &Deadband{
+		Absolute: 10,
+		Fields: []Field{
+			{Name: "temperature", Absolute: 0.5},
+			{Name: "humidity", Relative: 0.05},
+		},
+	})
+
+	require.Len(t, d.Apply(newMetric("a", 0, map[string]interface{}{
+		"temperature": 21.0,
+		"humidity":    40.0,
+		"rssi":        int64(-60),
+		"status":      "ok",
+	})), 1)
+
+	// the fields within their deadband are removed, the others passed
+	out := d.Apply(newMetric("a", 10, map[string]interface{}{
+		"temperature": 21.6,
+		"humidity":    41.0,
+		"rssi":        int64(-65),
+		"status":      "ok",
+	}))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"temperature": 21.6, "status": "ok"}, out[0].Fields())
+
+	out = d.Apply(newMetric("a", 20, map[string]interface{}{
+		"temperature": 21.8,
+		"humidity":    42.5,
+		"rssi":        int64(-71),
+	}))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"humidity": 42.5, "rssi": int64(-71)}, out[0].Fields())
+}
+
+func TestSeriesIndependent(t *testing.T) {
+	d := newTestDeadband(t, &Deadband{Absolute: 1})
+
+	out := d.Apply(
+		newMetric("a", 0, map[string]interface{}{"temperature": 21.0}),
+		newMetric("b", 0, map[string]interface{}{"temperature": 21.5}),
+		newMetric("a", 10, map[string]interface{}{"temperature": 21.5}),
+		newMetric("b", 10, map[string]interface{}{"temperature": 23.0}),
+	)
+	require.Len(t, out, 3)
+	require.Equal(t, "b", out[2].Tags()["host"])
+}
+
+func TestWithoutThresholdPassed(t *testing.T) {
+	d := newTestDeadband(t, &Deadband{})
+	fields := map[string]interface{}{"temperature": 21.0}
+
+	require.Len(t, d.Apply(newMetric("a", 0, fields)), 1)
+	require.Len(t, d.Apply(newMetric("a", 10, fields)), 1)
+}
+
+func TestMaxSeries(t *testing.T) {
+	d := newTestDeadband(t, &Deadband{Absolute: 1})
+	d.MaxSeries = 1
+	fields := map[string]interface{}{"temperature": 21.0}
+
+	require.Len(t, d.Apply(newMetric("a", 0, fields)), 1)
+	require.Len(t, d.Apply(newMetric("b", 1, fields)), 1)
+
+	// a was forgotten, its value is passed again
+	require.Len(t, d.Apply(newMetric("a", 2, fields)), 1)
+	require.Len(t, d.cache, 1)
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&Deadband{Absolute: -1}).Init())
+	require.Error(t, (&Deadband{Fields: []Field{{Absolute: 1}}}).Init())
+	require.Error(t, (&Deadband{Fields: []Field{{Name: "temperature", Relative: -0.1}}}).Init())
+}
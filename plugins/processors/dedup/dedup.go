@@ -0,0 +1,152 @@
+package dedup
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Maximum time to suppress metrics whose values did not change, an
+  ## unchanged metric is passed once this interval elapsed.
+  dedup_interval = "600s"
+
+  ## Fields compared to detect a change.  By default all fields are compared.
+  # fields = []
+
+  ## Maximum number of series to remember, the least recently passed series
+  ## are forgotten first.
+  # max_series = 10000
+`
+
+type Dedup struct {
+	DedupInterval internal.Duration `toml:"dedup_interval"`
+	Fields        []string          `toml:"fields"`
+	MaxSeries     int               `toml:"max_series"`
+
+	// cache holds the elements of lru by series id, lru orders the series
+	// from the most to the least recently passed.
+	cache     map[uint64]*list.Element
+	lru       *list.List
+	lastPurge time.Time
+}
+
+// series is the last metric passed for a series.
+type series struct {
+	id     uint64
+	time   time.Time
+	fields map[string]interface{}
+}
+
+func (d *Dedup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Dedup) Description() string {
+	return "Drop metrics whose field values did not change since they were last passed."
+}
+
+func (d *Dedup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		if d.isDuplicate(metric) {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// isDuplicate reports if the metric can be dropped and remembers it
+// otherwise.
+func (d *Dedup) isDuplicate(metric telegraf.Metric) bool {
+	d.purge(metric.Time())
+
+	id := metric.HashID()
+	fields := d.watchedFields(metric)
+	if e, ok := d.cache[id]; ok {
+		s := e.Value.(*series)
+		if metric.Time().Sub(s.time) < d.DedupInterval.Duration && equal(s.fields, fields) {
+			return true
+		}
+		s.time = metric.Time()
+		s.fields = fields
+		d.lru.MoveToFront(e)
+		return false
+	}
+
+	if d.MaxSeries > 0 && len(d.cache) >= d.MaxSeries {
+		d.evictOldest()
+	}
+	d.cache[id] = d.lru.PushFront(&series{id: id, time: metric.Time(), fields: fields})
+	return false
+}
+
+func (d *Dedup) watchedFields(metric telegraf.Metric) map[string]interface{} {
+	if len(d.Fields) == 0 {
+		return metric.Fields()
+	}
+	fields := make(map[string]interface{}, len(d.Fields))
+	for _, k := range d.Fields {
+		if v, ok := metric.GetField(k); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// purge forgets the series whose interval elapsed, as their next metric is
+// passed anyway.  It runs at most once per interval.
+func (d *Dedup) purge(now time.Time) {
+	if now.Sub(d.lastPurge) < d.DedupInterval.Duration {
+		return
+	}
+	d.lastPurge = now
+	for id, e := range d.cache {
+		if now.Sub(e.Value.(*series).time) >= d.DedupInterval.Duration {
+			d.lru.Remove(e)
+			delete(d.cache, id)
+		}
+	}
+}
+
+// evictOldest forgets the least recently passed series.
+func (d *Dedup) evictOldest() {
+	e := d.lru.Back()
+	if e == nil {
+		return
+	}
+	d.lru.Remove(e)
+	delete(d.cache, e.Value.(*series).id)
+}
+
+func equal(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func newDedup() *Dedup {
+	return &Dedup{
+		DedupInterval: internal.Duration{Duration: 10 * time.Minute},
+		MaxSeries:     10000,
+		cache:         make(map[uint64]*list.Element),
+		lru:           list.New(),
+	}
+}
+
+func init() {
+	processors.Add("dedup", func() telegraf.Processor {
+		return newDedup()
+	})
+}
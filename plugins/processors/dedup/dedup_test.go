@@ -0,0 +1,130 @@
+package dedup
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1500000000, 0)
+
+func newMetric(host string, seconds int, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("disk",
+		map[string]string{"host": host},
+		fields,
+		start.Add(time.Duration(seconds)*time.Second),
+	)
+	return m
+}
+
+func newTestDedup() *Dedup {
+	d := newDedup()
+	d.DedupInterval = internal.Duration{Duration: time.Minute}
+	return d
+}
+
+func TestUnchangedDroppedUntilInterval(t *testing.T) {
+	d := newTestDedup()
+	fields := map[string]interface{}{"used": int64(10)}
+
+	require.Len(t, d.Apply(newMetric("a", 0, fields)), 1)
+	require.Len(t, d.Apply(newMetric("a", 10, fields)), 0)
+	require.Len(t, d.Apply(newMetric("a", 59, fields)), 0)
+
+	// The heartbeat is passed once the interval elapsed.
+	require.Len(t, d.Apply(newMetric("a", 60, fields)), 1)
+	require.Len(t, d.Apply(newMetric("a", 70, fields)), 0)
+}
+
+func TestChangedPassed(t *testing.T) {
+	d := newTestDedup()
+
+	require.Len(t, d.Apply(newMetric("a", 0, map[string]interface{}{"used": int64(10)})), 1)
+	require.Len(t, d.Apply(newMetric("a", 10, map[string]interface{}{"used": int64(11)})), 1)
+	require.Len(t, d.Apply(newMetric("a", 20, map[string]interface{}{"used": int64(11), "free": int64(5)})), 1)
+	require.Len(t, d.Apply(newMetric("a", 30, map[string]interface{}{"used": int64(11), "free": int64(5)})), 0)
+}
+
+func TestSeriesIndependent(t *testing.T) {
+	d := newTestDedup()
+	fields := map[string]interface{}{"used": int64(10)}
+
+	result := d.Apply(
+		newMetric("a", 0, fields),
+		newMetric("b", 0, fields),
+		newMetric("a", 10, fields),
+	)
+	require.Len(t, result, 2)
+	require.Equal(t, "a", result[0].Tags()["host"])
+	require.Equal(t, "b", result[1].Tags()["host"])
+}
+
+func TestWatchedFields(t *testing.T) {
+	d := newTestDedup()
+	d.Fields = []string{"used"}
+
+	require.Len(t, d.Apply(newMetric("a", 0, map[string]interface{}{"used": int64(10), "inodes": int64(1)})), 1)
+	require.Len(t, d.Apply(newMetric("a", 10, map[string]interface{}{"used": int64(10), "inodes": int64(2)})), 0)
+	require.Len(t, d.Apply(newMetric("a", 20, map[string]interface{}{"used": int64(12), "inodes": int64(2)})), 1)
+}
+
+func TestMaxSeries(t *testing.T) {
+	d := newTestDedup()
+	d.MaxSeries = 2
+	fields := map[string]interface{}{"used": int64(10)}
+
+	d.Apply(newMetric("a", 0, fields))
+	d.Apply(newMetric("b", 1, fields))
+	d.Apply(newMetric("c", 2, fields))
+	require.Len(t, d.cache, 2)
+
+	// The oldest series was forgotten, so its metric is passed again.
+	require.Len(t, d.Apply(newMetric("a", 3, fields)), 1)
+	require.Len(t, d.Apply(newMetric("c", 4, fields)), 0)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	d := newTestDedup()
+	fields := map[string]interface{}{"used": int64(10)}
+
+	d.Apply(newMetric("a", 0, fields))
+	d.Apply(newMetric("b", 30, fields))
+	d.Apply(newMetric("c", 80, fields))
+	require.Len(t, d.cache, 2)
+}
+
+func TestMaxSeriesEvictsLeastRecentlyPassed(t *testing.T) {
+	d := newTestDedup()
+	d.MaxSeries = 2
+
+	d.Apply(newMetric("a", 0, map[string]interface{}{"used": int64(10)}))
+	d.Apply(newMetric("b", 1, map[string]interface{}{"used": int64(10)}))
+	// a changed, so b becomes the least recently passed series
+	d.Apply(newMetric("a", 2, map[string]interface{}{"used": int64(20)}))
+	d.Apply(newMetric("c", 3, map[string]interface{}{"used": int64(10)}))
+	require.Len(t, d.cache, 2)
+	require.Equal(t, 2, d.lru.Len())
+
+	require.Len(t, d.Apply(newMetric("a", 4, map[string]interface{}{"used": int64(20)})), 0)
+	require.Len(t, d.Apply(newMetric("b", 5, map[string]interface{}{"used": int64(10)})), 1)
+}
+
+func BenchmarkMaxSeries(b *testing.B) {
+	d := newDedup()
+	d.MaxSeries = 1000
+	fields := map[string]interface{}{"used": int64(10)}
+	metrics := make([]telegraf.Metric, 10000)
+	for i := range metrics {
+		metrics[i] = newMetric(strconv.Itoa(i), 0, fields)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		d.Apply(metrics[n%len(metrics)])
+	}
+}
@@ -92,7 +92,6 @@ func (mapping *Mapping) getDestination() string {
 }
 
 func writeField(metric telegraf.Metric, name string, value interface{}) {
-	metric.RemoveField(name)
 	metric.AddField(name, value)
 }
 
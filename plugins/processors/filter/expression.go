@@ -0,0 +1,482 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// node is a node of a parsed expression, evaluated against a metric.
+type node interface {
+	eval(metric telegraf.Metric) interface{}
+}
+
+// literal is a number, string, boolean or null.
+type literal struct {
+	value interface{}
+}
+
+func (n *literal) eval(telegraf.Metric) interface{} {
+	return n.value
+}
+
+// reference is the name of a metric, or one of its tags or fields.  Missing
+// tags and fields evaluate to null.
+type reference struct {
+	kind string
+	key  string
+}
+
+func (n *reference) eval(metric telegraf.Metric) interface{} {
+	switch n.kind {
+	case "tags":
+		if v, ok := metric.GetTag(n.key); ok {
+			return v
+		}
+	case "fields":
+		if v, ok := metric.GetField(n.key); ok {
+			return normalize(v)
+		}
+	default:
+		return metric.Name()
+	}
+	return nil
+}
+
+type not struct {
+	x node
+}
+
+func (n *not) eval(metric telegraf.Metric) interface{} {
+	return !truthy(n.x.eval(metric))
+}
+
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (n *logical) eval(metric telegraf.Metric) interface{} {
+	left := truthy(n.left.eval(metric))
+	if n.op == "&&" {
+		return left && truthy(n.right.eval(metric))
+	}
+	return left || truthy(n.right.eval(metric))
+}
+
+type comparison struct {
+	op          string
+	left, right node
+}
+
+func (n *comparison) eval(metric telegraf.Metric) interface{} {
+	return compare(n.op, n.left.eval(metric), n.right.eval(metric))
+}
+
+// normalize converts the numbers to float64 so they can be compared.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	}
+	return v
+}
+
+// truthy returns true only for the boolean true.
+func truthy(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// compare compares two values.  Values of different types are never equal and
+// cannot be ordered, a comparison of null is only true with == null or
+// != of a value.
+func compare(op string, a, b interface{}) bool {
+	if a == nil || b == nil {
+		switch op {
+		case "==":
+			return a == nil && b == nil
+		case "!=":
+			return a != nil || b != nil
+		}
+		return false
+	}
+
+	var c int
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case a < b:
+			c = -1
+		case a > b:
+			c = 1
+		case a != b:
+			// NaN is neither equal nor ordered
+			return op == "!="
+		}
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return op == "!="
+		}
+		c = strings.Compare(a, b)
+	case bool:
+		b, ok := b.(bool)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		}
+		return false
+	default:
+		return op == "!="
+	}
+
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// tokenize splits an expression into identifiers, numbers, strings and
+// operators.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(expr) && (expr[i] == '_' || isLetter(expr[i]) || isDigit(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[start:i], pos: start})
+		case isDigit(c):
+			start := i
+			for i < len(expr) && (isDigit(expr[i]) || isLetter(expr[i]) || expr[i] == '.' ||
+				((expr[i] == '+' || expr[i] == '-') && (expr[i-1] == 'e' || expr[i-1] == 'E'))) {
+				i++
+			}
+			v, err := strconv.ParseFloat(expr[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s at position %d", expr[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:i], value: v, pos: start})
+		case c == '"':
+			start := i
+			for i++; i < len(expr) && expr[i] != '"'; i++ {
+				if expr[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			s, err := strconv.Unquote(expr[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s at position %d", expr[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: expr[start:i], value: s, pos: start})
+		default:
+			op := ""
+			for _, o := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ".", "-"} {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser is a recursive descent parser of expressions:
+//
+//	expression = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | comparison
+//	comparison = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand    = "(" expression ")" | literal | reference
+//	literal    = [ "-" ] number | string | "true" | "false" | "null"
+//	reference  = "name" | ( "tags" | "fields" ) ( "." identifier | "[" string "]" )
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression parses a boolean expression over the name, tags and fields
+// of a metric.
+func parseExpression(expr string) (node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", expr, err)
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expression()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.unexpected()
+	}
+	if err == nil {
+		err = checkBoolean(n)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", expr, err)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %s at position %d", t.text, t.pos)
+}
+
+func (p *parser) expression() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &not{x: x}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if t.text != "==" && t.text != "!=" {
+		for _, n := range []node{left, right} {
+			if l, ok := n.(*literal); ok {
+				switch l.value.(type) {
+				case float64, string:
+				default:
+					return nil, fmt.Errorf("%s at position %d can only compare numbers or strings", t.text, t.pos)
+				}
+			}
+		}
+	}
+	return &comparison{op: t.text, left: left, right: right}, nil
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literal{value: t.value}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			n, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, p.unexpected()
+			}
+			return n, nil
+		case "-":
+			if num := p.peek(); num.kind == tokenNumber {
+				p.next()
+				return &literal{value: -num.value.(float64)}, nil
+			}
+			return nil, p.unexpected()
+		}
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		case "name":
+			return &reference{kind: "name"}, nil
+		case "tags", "fields":
+			return p.reference(t.text)
+		}
+		return nil, fmt.Errorf("unknown identifier %s at position %d, expected name, tags or fields", t.text, t.pos)
+	}
+	if t.kind != tokenEOF {
+		p.pos--
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) reference(kind string) (node, error) {
+	if p.accept(".") {
+		key := p.peek()
+		if key.kind != tokenIdent {
+			return nil, p.unexpected()
+		}
+		p.next()
+		return &reference{kind: kind, key: key.text}, nil
+	}
+	if p.accept("[") {
+		key := p.peek()
+		if key.kind != tokenString {
+			return nil, p.unexpected()
+		}
+		p.next()
+		if !p.accept("]") {
+			return nil, p.unexpected()
+		}
+		return &reference{kind: kind, key: key.value.(string)}, nil
+	}
+	return nil, p.unexpected()
+}
+
+// checkBoolean returns an error if a literal is used where a boolean is
+// expected, and could never be true.
+func checkBoolean(n node) error {
+	switch n := n.(type) {
+	case *literal:
+		switch v := n.value.(type) {
+		case bool:
+		case nil:
+			return fmt.Errorf("null is not a boolean")
+		default:
+			return fmt.Errorf("%v is not a boolean", v)
+		}
+	case *reference:
+		if n.kind == "name" {
+			return fmt.Errorf("name is not a boolean")
+		}
+		if n.kind == "tags" {
+			return fmt.Errorf("tag %s is not a boolean", n.key)
+		}
+	case *not:
+		return checkBoolean(n.x)
+	case *logical:
+		if err := checkBoolean(n.left); err != nil {
+			return err
+		}
+		return checkBoolean(n.right)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Boolean expression over the name, tags and fields of the metrics, see
+  ## the README for its syntax.
+  expression = 'fields.usage_idle < 10 && tags.cpu == "cpu-total"'
+
+  ## Action for the metrics matching the expression, either "keep" to drop
+  ## the metrics that do not match or "drop" to drop the metrics that match.
+  # action = "keep"
+`
+
+type Filter struct {
+	Expression string `toml:"expression"`
+	Action     string `toml:"action"`
+
+	expr node
+	keep bool
+}
+
+func (f *Filter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *Filter) Description() string {
+	return "Keep or drop metrics matching a boolean expression."
+}
+
+func (f *Filter) Init() error {
+	switch f.Action {
+	case "", "keep":
+		f.keep = true
+	case "drop":
+		f.keep = false
+	default:
+		return fmt.Errorf("invalid action %q, must be keep or drop", f.Action)
+	}
+
+	if f.Expression == "" {
+		return fmt.Errorf("expression must be set")
+	}
+	var err error
+	f.expr, err = parseExpression(f.Expression)
+	return err
+}
+
+func (f *Filter) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		if truthy(f.expr.eval(metric)) != f.keep {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("filter", func() telegraf.Processor {
+		return &Filter{}
+	})
+}
@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetric() telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{
+			"cpu":      "cpu-total",
+			"env":      "prod",
+			"dc.name":  "eu-1",
+			"priority": "10",
+		},
+		map[string]interface{}{
+			"usage_idle":   5.5,
+			"usage_user":   int64(90),
+			"count":        uint64(3),
+			"ok":           true,
+			"state":        "running",
+			"nan":          math.NaN(),
+			"with-hyphens": int64(1),
+		},
+		time.Unix(0, 0))
+}
+
+func TestExpressions(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		// numbers, of all the field types
+		{`fields.usage_idle < 10`, true},
+		{`fields.usage_idle <= 5.5`, true},
+		{`fields.usage_idle > 5.5`, false},
+		{`fields.usage_user >= 90`, true},
+		{`fields.usage_user == 90.0`, true},
+		{`fields.count != 3`, false},
+		{`fields.usage_idle > -1e3`, true},
+		{`fields.usage_user > fields.usage_idle`, true},
+		{`fields.nan == fields.nan`, false},
+		{`fields.nan != 1`, true},
+		{`fields.nan < 1`, false},
+
+		// strings and booleans
+		{`tags.env == "prod"`, true},
+		{`tags.env != "prod"`, false},
+		{`tags["dc.name"] == "eu-1"`, true},
+		{`name == "cpu"`, true},
+		{`fields.state < "stopped"`, true},
+		{`fields.ok`, true},
+		{`fields.ok == false`, false},
+		{`!fields.ok`, false},
+
+		// values of different types are never equal
+		{`tags.priority == 10`, false},
+		{`tags.priority != 10`, true},
+		{`tags.priority > 5`, false},
+		{`fields["with-hyphens"] == true`, false},
+		{`fields.state`, false},
+
+		// missing tags and fields
+		{`fields.missing > 0`, false},
+		{`fields.missing <= 0`, false},
+		{`fields.missing == 0`, false},
+		{`fields.missing != 0`, true},
+		{`fields.missing == null`, true},
+		{`tags.env == null`, false},
+		{`tags.missing != null`, false},
+		{`fields.missing`, false},
+		{`!fields.missing`, true},
+
+		// logical operators and precedence
+		{`fields.usage_user > 80 && tags.env == "prod"`, true},
+		{`fields.usage_user > 95 && tags.env == "prod"`, false},
+		{`fields.usage_user > 95 || tags.env == "prod"`, true},
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`!(fields.usage_idle < 10) || name != "cpu"`, false},
+		{`!!true`, true},
+	}
+
+	metric := testMetric()
+	for _, tt := range tests {
+		expr, err := parseExpression(tt.expr)
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.expected, truthy(expr.eval(metric)), tt.expr)
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{`fields.a >`, `invalid expression "fields.a >": unexpected end of expression`},
+		{`fields.a > 1 1`, `invalid expression "fields.a > 1 1": unexpected 1 at position 13`},
+		{`field.a > 1`, `invalid expression "field.a > 1": unknown identifier field at position 0, expected name, tags or fields`},
+		{`tags. == "a"`, `invalid expression "tags. == \"a\"": unexpected == at position 6`},
+		{`tags[a] == "a"`, `invalid expression "tags[a] == \"a\"": unexpected a at position 5`},
+		{`tags == "a"`, `invalid expression "tags == \"a\"": unexpected == at position 5`},
+		{`(fields.a > 1`, `invalid expression "(fields.a > 1": unexpected end of expression`},
+		{`fields.a > "1`, `invalid expression "fields.a > \"1": unterminated string at position 11`},
+		{`fields.a > 1.2.3`, `invalid expression "fields.a > 1.2.3": invalid number 1.2.3 at position 11`},
+		{`fields.a = 1`, `invalid expression "fields.a = 1": unexpected '=' at position 9`},
+		{`fields.a > true`, `invalid expression "fields.a > true": > at position 9 can only compare numbers or strings`},
+		{`fields.a && 1`, `invalid expression "fields.a && 1": 1 is not a boolean`},
+		{`tags.env`, `invalid expression "tags.env": tag env is not a boolean`},
+		{`!name`, `invalid expression "!name": name is not a boolean`},
+		{`null`, `invalid expression "null": null is not a boolean`},
+		{``, `invalid expression "": unexpected end of expression`},
+	}
+
+	for _, tt := range tests {
+		_, err := parseExpression(tt.expr)
+		require.EqualError(t, err, tt.err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	metrics := func() []telegraf.Metric {
+		return []telegraf.Metric{
+			testutil.MustMetric("cpu",
+				map[string]string{"env": "prod"},
+				map[string]interface{}{"usage": 95.0},
+				time.Unix(0, 0)),
+			testutil.MustMetric("cpu",
+				map[string]string{"env": "dev"},
+				map[string]interface{}{"usage": 99.0},
+				time.Unix(0, 0)),
+			testutil.MustMetric("cpu",
+				map[string]string{"env": "prod"},
+				map[string]interface{}{"usage": 10.0},
+				time.Unix(0, 0)),
+			testutil.MustMetric("mem",
+				map[string]string{"env": "prod"},
+				map[string]interface{}{"used": 10.0},
+				time.Unix(0, 0)),
+		}
+	}
+	expr := `fields.usage > 90 && tags.env == "prod"`
+
+	keep := &Filter{Expression: expr}
+	require.NoError(t, keep.Init())
+	in := metrics()
+	testutil.RequireMetricsEqual(t, in[:1], keep.Apply(metrics()...))
+
+	drop := &Filter{Expression: expr, Action: "drop"}
+	require.NoError(t, drop.Init())
+	testutil.RequireMetricsEqual(t, in[1:], drop.Apply(metrics()...))
+}
+
+func TestInitErrors(t *testing.T) {
+	require.EqualError(t, (&Filter{}).Init(), "expression must be set")
+	require.EqualError(t, (&Filter{Expression: "true", Action: "pass"}).Init(),
+		`invalid action "pass", must be keep or drop`)
+	require.Error(t, (&Filter{Expression: "fields.a >"}).Init())
+}
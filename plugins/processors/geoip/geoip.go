@@ -0,0 +1,212 @@
+package geoip
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/filewatch"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Path of the MaxMind GeoLite2 or GeoIP2 database.  The database is loaded
+  ## again when it changes.
+  database = "/var/lib/GeoIP/GeoLite2-City.mmdb"
+
+  ## Name of the tag or field containing the IP address to look up.
+  source = "client_ip"
+`
+
+// privateNetworks are not routed on the internet and have no location.
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+type GeoIP struct {
+	Database string `toml:"database"`
+	Source   string `toml:"source"`
+
+	// watch is the configuration of the watcher of the database, with the
+	// default intervals unless set by the tests.
+	watch   filewatch.Config
+	watcher *filewatch.Watcher
+
+	mu sync.RWMutex
+	db database
+}
+
+func (g *GeoIP) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GeoIP) Description() string {
+	return "Add the location of an IP address from a MaxMind database as tags."
+}
+
+func (g *GeoIP) Init() error {
+	if g.Database == "" {
+		return fmt.Errorf("database must be set")
+	}
+	if g.Source == "" {
+		return fmt.Errorf("source must be set")
+	}
+
+	// The first contents are loaded by New, the database must be valid for
+	// the processor to start.
+	var loadErr error
+	first := true
+	g.watch.Path = g.Database
+	watcher, err := filewatch.New(g.watch, func(data []byte) {
+		err := g.load(data)
+		if first {
+			first, loadErr = false, err
+		} else if err != nil {
+			log.Printf("E! [processors.geoip] could not load %s, keeping the "+
+				"previous database: %v", g.Database, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if loadErr != nil {
+		watcher.Close()
+		return loadErr
+	}
+	g.watcher = watcher
+	return nil
+}
+
+// Close stops watching the database.
+func (g *GeoIP) Close() error {
+	if g.watcher != nil {
+		g.watcher.Close()
+	}
+	return nil
+}
+
+func (g *GeoIP) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	g.mu.RLock()
+	db := g.db
+	g.mu.RUnlock()
+	if db == nil {
+		return in
+	}
+
+	for _, metric := range in {
+		ip := g.sourceIP(metric)
+		if ip == nil || isPrivate(ip) {
+			continue
+		}
+
+		record, err := db.lookup(ip)
+		if err != nil {
+			log.Printf("E! [processors.geoip] could not look up %s: %v", ip, err)
+			continue
+		}
+		for k, v := range recordTags(record) {
+			metric.AddTag(k, v)
+		}
+	}
+	return in
+}
+
+func (g *GeoIP) sourceIP(metric telegraf.Metric) net.IP {
+	value, ok := metric.GetTag(g.Source)
+	if !ok {
+		field, ok := metric.GetField(g.Source)
+		if !ok {
+			return nil
+		}
+		if value, ok = field.(string); !ok {
+			return nil
+		}
+	}
+	return net.ParseIP(value)
+}
+
+func isPrivate(ip net.IP) bool {
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordTags returns the tags for the values of a City, Country or ASN
+// database record.
+func recordTags(record interface{}) map[string]string {
+	tags := make(map[string]string)
+	if s, ok := lookupPath(record, "country", "iso_code").(string); ok {
+		tags["country"] = s
+	}
+	if s, ok := lookupPath(record, "city", "names", "en").(string); ok {
+		tags["city"] = s
+	}
+	if f, ok := lookupPath(record, "location", "latitude").(float64); ok {
+		tags["latitude"] = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if f, ok := lookupPath(record, "location", "longitude").(float64); ok {
+		tags["longitude"] = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if n, ok := lookupPath(record, "autonomous_system_number").(uint64); ok {
+		tags["asn"] = strconv.FormatUint(n, 10)
+	}
+	return tags
+}
+
+func lookupPath(value interface{}, path ...string) interface{} {
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// load opens the contents of the database, the current database is kept on
+// error.
+func (g *GeoIP) load(data []byte) error {
+	db, err := openMMDB(data)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %v", g.Database, err)
+	}
+
+	g.mu.Lock()
+	g.db = db
+	g.mu.Unlock()
+	return nil
+}
+
+func init() {
+	processors.Add("geoip", func() telegraf.Processor {
+		return &GeoIP{}
+	})
+}
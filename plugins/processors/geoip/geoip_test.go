@@ -0,0 +1,179 @@
+package geoip
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/filewatch"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cityNetworks = map[string]interface{}{
+	"81.2.69.0/24": map[string]interface{}{
+		"city":    map[string]interface{}{"names": map[string]interface{}{"en": "London"}},
+		"country": map[string]interface{}{"iso_code": "GB"},
+		"location": map[string]interface{}{
+			"latitude":  51.5142,
+			"longitude": -0.0931,
+		},
+	},
+	"10.0.0.0/8": map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "XX"},
+	},
+}
+
+func newTestGeoIP(t *testing.T, networks map[string]interface{}) (*GeoIP, func()) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "test.mmdb")
+	writeTestMMDB(t, path, networks)
+
+	g := &GeoIP{
+		Database: path,
+		Source:   "client_ip",
+		watch:    testWatch,
+	}
+	require.NoError(t, g.Init())
+	return g, func() {
+		g.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// testWatch polls the database often so that the tests are quick.
+var testWatch = filewatch.Config{
+	PollInterval: 10 * time.Millisecond,
+	Debounce:     30 * time.Millisecond,
+}
+
+// requireCountry waits for the database to be reloaded and the country of
+// the address to be the expected one.
+func requireCountry(t *testing.T, g *GeoIP, ip, country string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result := g.Apply(newMetric(map[string]string{"client_ip": ip}, nil))
+		if result[0].Tags()["country"] == country {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("country of %s is %q, expected %q", ip, result[0].Tags()["country"], country)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	if fields == nil {
+		fields = map[string]interface{}{"value": 1.0}
+	}
+	m, _ := metric.New("requests", tags, fields, time.Now())
+	return m
+}
+
+func TestGeoIPCity(t *testing.T) {
+	g, cleanup := newTestGeoIP(t, cityNetworks)
+	defer cleanup()
+
+	result := g.Apply(newMetric(map[string]string{"client_ip": "81.2.69.142"}, nil))
+	require.Len(t, result, 1)
+	assert.Equal(t, map[string]string{
+		"client_ip": "81.2.69.142",
+		"city":      "London",
+		"country":   "GB",
+		"latitude":  "51.5142",
+		"longitude": "-0.0931",
+	}, result[0].Tags())
+}
+
+func TestGeoIPField(t *testing.T) {
+	g, cleanup := newTestGeoIP(t, cityNetworks)
+	defer cleanup()
+
+	result := g.Apply(newMetric(nil, map[string]interface{}{"client_ip": "81.2.69.142"}))
+	require.Len(t, result, 1)
+	assert.Equal(t, "London", result[0].Tags()["city"])
+}
+
+func TestGeoIPASN(t *testing.T) {
+	g, cleanup := newTestGeoIP(t, map[string]interface{}{
+		"1.128.0.0/11": map[string]interface{}{
+			"autonomous_system_number":       uint32(1221),
+			"autonomous_system_organization": "Telstra Pty Ltd",
+		},
+	})
+	defer cleanup()
+
+	result := g.Apply(newMetric(map[string]string{"client_ip": "1.128.0.1"}, nil))
+	require.Len(t, result, 1)
+	assert.Equal(t, map[string]string{
+		"client_ip": "1.128.0.1",
+		"asn":       "1221",
+	}, result[0].Tags())
+}
+
+func TestGeoIPSkipped(t *testing.T) {
+	g, cleanup := newTestGeoIP(t, cityNetworks)
+	defer cleanup()
+
+	for _, tags := range []map[string]string{
+		{"client_ip": "10.1.2.3"},
+		{"client_ip": "127.0.0.1"},
+		{"client_ip": "fe80::1"},
+		{"client_ip": "8.8.8.8"},
+		{"client_ip": "not an ip"},
+		{},
+	} {
+		result := g.Apply(newMetric(tags, nil))
+		require.Len(t, result, 1)
+		assert.Equal(t, tags, result[0].Tags())
+	}
+}
+
+func TestGeoIPReload(t *testing.T) {
+	g, cleanup := newTestGeoIP(t, cityNetworks)
+	defer cleanup()
+
+	// The database is replaced by a rename, like by geoipupdate.
+	tmp := g.Database + ".tmp"
+	writeTestMMDB(t, tmp, map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "FR"},
+		},
+	})
+	require.NoError(t, os.Rename(tmp, g.Database))
+	requireCountry(t, g, "81.2.69.142", "FR")
+
+	result := g.Apply(newMetric(map[string]string{"client_ip": "81.2.69.142"}, nil))
+	assert.Equal(t, map[string]string{
+		"client_ip": "81.2.69.142",
+		"country":   "FR",
+	}, result[0].Tags())
+
+	// An invalid database keeps the current one.
+	require.NoError(t, ioutil.WriteFile(g.Database, []byte("invalid"), 0644))
+	time.Sleep(200 * time.Millisecond)
+
+	result = g.Apply(newMetric(map[string]string{"client_ip": "81.2.69.142"}, nil))
+	assert.Equal(t, "FR", result[0].Tags()["country"])
+}
+
+func TestGeoIPInitErrors(t *testing.T) {
+	require.Error(t, (&GeoIP{Source: "client_ip"}).Init())
+	require.Error(t, (&GeoIP{Database: "test.mmdb"}).Init())
+	require.Error(t, (&GeoIP{Database: "missing.mmdb", Source: "client_ip"}).Init())
+
+	// The database must be valid for the processor to start.
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "invalid.mmdb")
+	require.NoError(t, ioutil.WriteFile(path, []byte("invalid"), 0644))
+	require.Error(t, (&GeoIP{Database: path, Source: "client_ip"}).Init())
+}
@@ -0,0 +1,38 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// database looks up the record of an IP address.
+type database interface {
+	// lookup returns the record for the IP address, or nil if there is none.
+	lookup(ip net.IP) (interface{}, error)
+}
+
+// mmdb is a MaxMind DB file loaded in memory.
+type mmdb struct {
+	reader *maxminddb.Reader
+}
+
+func openMMDB(buf []byte) (*mmdb, error) {
+	reader, err := maxminddb.FromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdb{reader: reader}, nil
+}
+
+func (db *mmdb) lookup(ip net.IP) (interface{}, error) {
+	if ip.To4() == nil && db.reader.Metadata.IPVersion == 4 {
+		return nil, nil
+	}
+
+	var record interface{}
+	if err := db.reader.Lookup(ip, &record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
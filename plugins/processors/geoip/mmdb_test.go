@@ -0,0 +1,303 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The functions below write a minimal MaxMind DB file, so the tests do not
+// depend on a binary test database, see https://maxmind.github.io/MaxMind-DB/
+
+// metadataMarker precedes the metadata section at the end of the file.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the size of the zero bytes between the search
+// tree and the data section.
+const dataSectionSeparator = 16
+
+// Data section types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+type testNode struct {
+	children [2]*testNode
+	// data are the indexes of the records plus one, zero if none
+	data [2]int
+}
+
+func encodeControl(typ int, size int) []byte {
+	var buf []byte
+	var ctrl byte
+	if typ <= 7 {
+		ctrl = byte(typ << 5)
+	}
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 285:
+		ctrl |= 29
+		buf = append(buf, byte(size-29))
+	default:
+		ctrl |= 30
+		buf = append(buf, byte((size-285)>>8), byte(size-285))
+	}
+	if typ > 7 {
+		return append([]byte{ctrl, byte(typ - 7)}, buf...)
+	}
+	return append([]byte{ctrl}, buf...)
+}
+
+func encodeValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case string:
+		return append(encodeControl(typeString, len(v)), v...)
+	case float64:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(v))
+		return append(encodeControl(typeDouble, 8), b...)
+	case uint32:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, v)
+		return append(encodeControl(typeUint32, 4), b...)
+	case uint16:
+		return append(encodeControl(typeUint16, 2), byte(v>>8), byte(v))
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		return encodeControl(typeBool, size)
+	case []interface{}:
+		buf := encodeControl(typeArray, len(v))
+		for _, e := range v {
+			buf = append(buf, encodeValue(e)...)
+		}
+		return buf
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf := encodeControl(typeMap, len(v))
+		for _, k := range keys {
+			buf = append(buf, encodeValue(k)...)
+			buf = append(buf, encodeValue(v[k])...)
+		}
+		return buf
+	}
+	panic("unsupported type")
+}
+
+func addressBits(ip net.IP, ipVersion int) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		if ipVersion == 6 {
+			return append(make([]byte, 12), ip4...)
+		}
+		return ip4
+	}
+	return ip.To16()
+}
+
+func buildTestMMDB(t *testing.T, ipVersion, recordSize int, networks map[string]interface{}) []byte {
+	root := &testNode{}
+	var records []interface{}
+	cidrs := make([]string, 0, len(networks))
+	for cidr := range networks {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+
+	for _, cidr := range cidrs {
+		ip, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		ones, _ := network.Mask.Size()
+		addr := addressBits(ip, ipVersion)
+		if ip.To4() != nil && ipVersion == 6 {
+			ones += 96
+		}
+
+		records = append(records, networks[cidr])
+		node := root
+		for i := 0; i < ones; i++ {
+			bit := (addr[i/8] >> (7 - uint(i%8))) & 1
+			if i == ones-1 {
+				node.data[bit] = len(records)
+				break
+			}
+			if node.children[bit] == nil {
+				node.children[bit] = &testNode{}
+			}
+			node = node.children[bit]
+		}
+	}
+
+	// number the nodes breadth first
+	nodes := []*testNode{root}
+	index := map[*testNode]int{root: 0}
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodes[i].children {
+			if child != nil {
+				index[child] = len(nodes)
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	var data []byte
+	offsets := make([]int, len(records))
+	for i, record := range records {
+		offsets[i] = len(data)
+		data = append(data, encodeValue(record)...)
+	}
+
+	nodeCount := len(nodes)
+	var tree []byte
+	for _, node := range nodes {
+		var values [2]uint32
+		for bit := 0; bit < 2; bit++ {
+			switch {
+			case node.children[bit] != nil:
+				values[bit] = uint32(index[node.children[bit]])
+			case node.data[bit] != 0:
+				values[bit] = uint32(nodeCount + dataSectionSeparator + offsets[node.data[bit]-1])
+			default:
+				values[bit] = uint32(nodeCount)
+			}
+		}
+		switch recordSize {
+		case 24:
+			for _, v := range values {
+				tree = append(tree, byte(v>>16), byte(v>>8), byte(v))
+			}
+		case 28:
+			tree = append(tree,
+				byte(values[0]>>16), byte(values[0]>>8), byte(values[0]),
+				byte(values[0]>>20)&0xf0|byte(values[1]>>24)&0x0f,
+				byte(values[1]>>16), byte(values[1]>>8), byte(values[1]))
+		default:
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint32(b, values[0])
+			binary.BigEndian.PutUint32(b[4:], values[1])
+			tree = append(tree, b...)
+		}
+	}
+
+	buf := append(tree, make([]byte, dataSectionSeparator)...)
+	buf = append(buf, data...)
+	buf = append(buf, metadataMarker...)
+	buf = append(buf, encodeValue(map[string]interface{}{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+		"ip_version":                  uint16(ipVersion),
+		"binary_format_major_version": uint16(2),
+		"database_type":               "Test",
+	})...)
+	return buf
+}
+
+func writeTestMMDB(t *testing.T, path string, networks map[string]interface{}) {
+	buf := buildTestMMDB(t, 6, 28, networks)
+	require.NoError(t, ioutil.WriteFile(path, buf, 0644))
+}
+
+func TestMMDBLookup(t *testing.T) {
+	networks := map[string]interface{}{
+		"81.2.69.0/24":   map[string]interface{}{"name": "a"},
+		"81.2.70.0/23":   map[string]interface{}{"name": "b"},
+		"2001:db8::/32":  map[string]interface{}{"name": "c"},
+		"89.160.20.0/28": map[string]interface{}{"name": "d", "values": []interface{}{1.5, true}},
+	}
+
+	for _, ipVersion := range []int{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			db, err := openMMDB(buildTestMMDB(t, ipVersion, recordSize, networks))
+			require.NoError(t, err)
+
+			tests := []struct {
+				ip       string
+				expected interface{}
+			}{
+				{"81.2.69.142", map[string]interface{}{"name": "a"}},
+				{"81.2.71.1", map[string]interface{}{"name": "b"}},
+				{"89.160.20.15", map[string]interface{}{"name": "d", "values": []interface{}{1.5, true}}},
+				{"89.160.20.16", nil},
+				{"8.8.8.8", nil},
+			}
+			if ipVersion == 6 {
+				tests = append(tests, struct {
+					ip       string
+					expected interface{}
+				}{"2001:db8::1", map[string]interface{}{"name": "c"}})
+			}
+
+			for _, tt := range tests {
+				record, err := db.lookup(net.ParseIP(tt.ip))
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, record,
+					"%s with ip version %d and record size %d", tt.ip, ipVersion, recordSize)
+			}
+		}
+	}
+}
+
+func TestMMDBInvalid(t *testing.T) {
+	_, err := openMMDB([]byte("not a database"))
+	require.Error(t, err)
+
+	buf := buildTestMMDB(t, 4, 24, map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{"name": "a"},
+	})
+	_, err = openMMDB(buf[len(buf)-40:])
+	require.Error(t, err)
+}
+
+func TestMMDBCorrupt(t *testing.T) {
+	buf := buildTestMMDB(t, 6, 28, map[string]interface{}{
+		"81.2.69.0/24":  map[string]interface{}{"name": "a", "values": []interface{}{1.5, true}},
+		"2001:db8::/32": map[string]interface{}{"name": "c"},
+	})
+	ips := []net.IP{net.ParseIP("81.2.69.142"), net.ParseIP("2001:db8::1"), net.ParseIP("8.8.8.8")}
+
+	// none of these may panic, they either fail or return some record
+	check := func(buf []byte) {
+		db, err := openMMDB(buf)
+		if err != nil {
+			return
+		}
+		for _, ip := range ips {
+			db.lookup(ip)
+		}
+	}
+
+	for i := 0; i < len(buf); i++ {
+		check(buf[:i])
+		for _, b := range []byte{0x00, 0x1f, 0x3f, 0xff} {
+			corrupt := append([]byte(nil), buf...)
+			corrupt[i] = b
+			check(corrupt)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package ifname
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	// ifNameOID is the ifName column of the ifXTable.
+	ifNameOID = ".1.3.6.1.2.1.31.1.1.1.1"
+	// ifDescrOID is the ifDescr column of the ifTable, walked for the agents
+	// without the ifXTable.
+	ifDescrOID = ".1.3.6.1.2.1.2.2.1.2"
+)
+
+var sampleConfig = `
+  ## Name of the tag or field holding the interface index.
+  tag = "ifIndex"
+
+  ## Name of the tag added with the interface name.
+  dest = "ifName"
+
+  ## Name of the tag holding the address of the agent, in the format
+  ## ADDR[:PORT], like the agent_host tag of the snmp input.
+  agent = "agent_host"
+
+  ## Time the interface names of an agent are kept before being walked again.
+  ## The names are also walked again when an index is missing, at most once
+  ## every min_refresh_interval.
+  # cache_ttl = "8h"
+  # min_refresh_interval = "1m"
+
+  ## Options of the connection to the agents, as in the snmp input.
+  # timeout = "5s"
+  # retries = 3
+  ## SNMP version, values can be 1, 2, or 3
+  # version = 2
+
+  ## SNMP community string.
+  # community = "public"
+
+  ## The GETBULK max-repetitions parameter
+  # max_repetitions = 10
+
+  ## SNMPv3 auth parameters
+  # sec_name = "myuser"
+  # auth_protocol = "md5"      # Values: "MD5", "SHA", ""
+  # auth_password = "pass"
+  # sec_level = "authNoPriv"   # Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+  # context_name = ""
+  # priv_protocol = ""         # Values: "DES", "AES", ""
+  # priv_password = ""
+`
+
+// walker walks a subtree of the MIB of an agent.
+type walker interface {
+	Walk(oid string, fn gosnmp.WalkFunc) error
+}
+
+// names are the interface names of an agent, by index.
+type names struct {
+	byIndex map[uint64]string
+	fetched time.Time
+}
+
+type IfName struct {
+	Tag                string            `toml:"tag"`
+	Dest               string            `toml:"dest"`
+	Agent              string            `toml:"agent"`
+	CacheTTL           internal.Duration `toml:"cache_ttl"`
+	MinRefreshInterval internal.Duration `toml:"min_refresh_interval"`
+
+	snmp.ClientConfig
+
+	mu    sync.Mutex
+	cache map[string]*names
+
+	// connect returns a connected walker of the agent, replaced in tests.
+	connect func(agent string) (walker, func(), error)
+}
+
+func (d *IfName) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *IfName) Description() string {
+	return "Add the names of the interfaces to the metrics with SNMP interface indexes."
+}
+
+func (d *IfName) Init() error {
+	if d.Tag == "" || d.Dest == "" || d.Agent == "" {
+		return fmt.Errorf("tag, dest and agent must be set")
+	}
+	// check the connection options
+	if _, err := snmp.NewClient("localhost", d.ClientConfig); err != nil {
+		return err
+	}
+	d.cache = make(map[string]*names)
+	if d.connect == nil {
+		d.connect = d.connectAgent
+	}
+	return nil
+}
+
+func (d *IfName) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		agent, ok := metric.GetTag(d.Agent)
+		if !ok {
+			continue
+		}
+		index, ok := d.index(metric)
+		if !ok {
+			continue
+		}
+		if name, ok := d.lookup(agent, index, time.Now()); ok {
+			metric.AddTag(d.Dest, name)
+		}
+	}
+	return in
+}
+
+// index returns the interface index of the metric, from the tag or else the
+// field.
+func (d *IfName) index(metric telegraf.Metric) (uint64, bool) {
+	if v, ok := metric.GetTag(d.Tag); ok {
+		index, err := strconv.ParseUint(v, 10, 64)
+		return index, err == nil
+	}
+	v, ok := metric.GetField(d.Tag)
+	if !ok {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case int64:
+		return uint64(v), v >= 0
+	case uint64:
+		return v, true
+	case string:
+		index, err := strconv.ParseUint(v, 10, 64)
+		return index, err == nil
+	}
+	return 0, false
+}
+
+// lookup returns the name of the interface of the agent, walking the names
+// of the agent when not cached, expired, or when the index is missing.
+func (d *IfName) lookup(agent string, index uint64, now time.Time) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cached := d.cache[agent]
+	if cached != nil {
+		age := now.Sub(cached.fetched)
+		name, ok := cached.byIndex[index]
+		if ok && age < d.CacheTTL.Duration {
+			return name, true
+		}
+		if age < d.MinRefreshInterval.Duration {
+			return name, ok
+		}
+	}
+
+	byIndex, err := d.walk(agent)
+	if err != nil {
+		log.Printf("E! [processors.ifname] could not walk the interface names of %s: %v", agent, err)
+		if cached != nil {
+			// keep the previous names until the next refresh
+			cached.fetched = now
+			name, ok := cached.byIndex[index]
+			return name, ok
+		}
+		d.cache[agent] = &names{fetched: now}
+		return "", false
+	}
+	d.cache[agent] = &names{byIndex: byIndex, fetched: now}
+	name, ok := byIndex[index]
+	return name, ok
+}
+
+// walk returns the names of the interfaces of the agent from ifName, or from
+// ifDescr if the agent has no ifName.
+func (d *IfName) walk(agent string) (map[uint64]string, error) {
+	w, closeFn, err := d.connect(agent)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	for _, oid := range []string{ifNameOID, ifDescrOID} {
+		byIndex := make(map[uint64]string)
+		err := w.Walk(oid, func(pdu gosnmp.SnmpPDU) error {
+			i := strings.LastIndex(pdu.Name, ".")
+			index, err := strconv.ParseUint(pdu.Name[i+1:], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid interface index in OID %s", pdu.Name)
+			}
+			if b, ok := pdu.Value.([]byte); ok {
+				byIndex[index] = string(b)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(byIndex) > 0 {
+			return byIndex, nil
+		}
+	}
+	return map[uint64]string{}, nil
+}
+
+// connectAgent connects to the agent with the connection options.
+func (d *IfName) connectAgent(agent string) (walker, func(), error) {
+	gs, err := snmp.NewClient(agent, d.ClientConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := gs.Connect(); err != nil {
+		return nil, nil, fmt.Errorf("setting up connection: %v", err)
+	}
+	return snmpWalker{gs}, func() { gs.Conn.Close() }, nil
+}
+
+// snmpWalker walks with GETBULK requests, or GETNEXT requests with SNMPv1.
+type snmpWalker struct {
+	*gosnmp.GoSNMP
+}
+
+func (w snmpWalker) Walk(oid string, fn gosnmp.WalkFunc) error {
+	if w.Version == gosnmp.Version1 {
+		return w.GoSNMP.Walk(oid, fn)
+	}
+	return w.GoSNMP.BulkWalk(oid, fn)
+}
+
+func init() {
+	processors.Add("ifname", func() telegraf.Processor {
+		return &IfName{
+			Tag:                "ifIndex",
+			Dest:               "ifName",
+			Agent:              "agent_host",
+			CacheTTL:           internal.Duration{Duration: 8 * time.Hour},
+			MinRefreshInterval: internal.Duration{Duration: time.Minute},
+			ClientConfig: snmp.ClientConfig{
+				Timeout:        internal.Duration{Duration: 5 * time.Second},
+				Retries:        3,
+				Version:        2,
+				Community:      "public",
+				MaxRepetitions: 10,
+			},
+		}
+	})
+}
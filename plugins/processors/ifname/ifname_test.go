@@ -0,0 +1,224 @@
+package ifname
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAgent is an agent serving the columns of its tables, counting the walks.
+type mockAgent struct {
+	columns map[string]map[int]string
+	err     error
+	walks   int
+}
+
+func (a *mockAgent) Walk(oid string, fn gosnmp.WalkFunc) error {
+	a.walks++
+	if a.err != nil {
+		return a.err
+	}
+	for index, name := range a.columns[oid] {
+		pdu := gosnmp.SnmpPDU{
+			Name:  fmt.Sprintf("%s.%d", oid, index),
+			Type:  gosnmp.OctetString,
+			Value: []byte(name),
+		}
+		if err := fn(pdu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestIfName(agents map[string]*mockAgent) *IfName {
+	d := &IfName{
+		Tag:                "ifIndex",
+		Dest:               "ifName",
+		Agent:              "agent_host",
+		CacheTTL:           internal.Duration{Duration: time.Hour},
+		MinRefreshInterval: internal.Duration{Duration: time.Minute},
+		connect: func(agent string) (walker, func(), error) {
+			a, ok := agents[agent]
+			if !ok {
+				return nil, nil, fmt.Errorf("no agent %s", agent)
+			}
+			return a, func() {}, nil
+		},
+	}
+	d.ClientConfig.Version = 2
+	if err := d.Init(); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func newMetric(agent string, index interface{}) telegraf.Metric {
+	tags := map[string]string{"agent_host": agent}
+	fields := map[string]interface{}{"ifHCInOctets": int64(42)}
+	if s, ok := index.(string); ok {
+		tags["ifIndex"] = s
+	} else {
+		fields["ifIndex"] = index
+	}
+	m, _ := metric.New("interface", tags, fields, time.Unix(0, 0))
+	return m
+}
+
+func TestResolveTagAndField(t *testing.T) {
+	agent := &mockAgent{columns: map[string]map[int]string{
+		ifNameOID:  {1: "lo", 2: "eth0"},
+		ifDescrOID: {1: "Loopback", 2: "Intel Ethernet"},
+	}}
+	d := newTestIfName(map[string]*mockAgent{"10.0.0.1": agent})
+
+	result := d.Apply(
+		newMetric("10.0.0.1", "2"),
+		newMetric("10.0.0.1", int64(1)),
+		newMetric("10.0.0.1", uint64(2)),
+	)
+	require.Len(t, result, 3)
+	for i, expected := range []string{"eth0", "lo", "eth0"} {
+		name, ok := result[i].GetTag("ifName")
+		require.True(t, ok)
+		require.Equal(t, expected, name)
+	}
+	require.Equal(t, 1, agent.walks)
+}
+
+func TestFallbackIfDescr(t *testing.T) {
+	agent := &mockAgent{columns: map[string]map[int]string{
+		ifDescrOID: {3: "Intel Ethernet"},
+	}}
+	d := newTestIfName(map[string]*mockAgent{"10.0.0.1": agent})
+
+	m := d.Apply(newMetric("10.0.0.1", "3"))[0]
+	name, ok := m.GetTag("ifName")
+	require.True(t, ok)
+	require.Equal(t, "Intel Ethernet", name)
+}
+
+func TestUnresolvedPassed(t *testing.T) {
+	agent := &mockAgent{columns: map[string]map[int]string{
+		ifNameOID: {1: "lo"},
+	}}
+	d := newTestIfName(map[string]*mockAgent{"10.0.0.1": agent})
+
+	m1, _ := metric.New("interface", map[string]string{"ifIndex": "1"},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	m2 := newMetric("10.0.0.2", "1")
+	m3 := newMetric("10.0.0.1", "invalid")
+
+	result := d.Apply(m1, m2, m3)
+	require.Len(t, result, 3)
+	for _, m := range result {
+		require.False(t, m.HasTag("ifName"))
+	}
+}
+
+func TestCachePerAgent(t *testing.T) {
+	a := &mockAgent{columns: map[string]map[int]string{ifNameOID: {1: "eth0"}}}
+	b := &mockAgent{columns: map[string]map[int]string{ifNameOID: {1: "ge-0/0/0"}}}
+	d := newTestIfName(map[string]*mockAgent{"a": a, "b": b})
+
+	now := time.Unix(1500000000, 0)
+	for i := 0; i < 3; i++ {
+		name, ok := d.lookup("a", 1, now)
+		require.True(t, ok)
+		require.Equal(t, "eth0", name)
+		name, ok = d.lookup("b", 1, now)
+		require.True(t, ok)
+		require.Equal(t, "ge-0/0/0", name)
+	}
+	require.Equal(t, 1, a.walks)
+	require.Equal(t, 1, b.walks)
+}
+
+func TestCacheTTL(t *testing.T) {
+	agent := &mockAgent{columns: map[string]map[int]string{ifNameOID: {1: "eth0"}}}
+	d := newTestIfName(map[string]*mockAgent{"10.0.0.1": agent})
+
+	now := time.Unix(1500000000, 0)
+	name, _ := d.lookup("10.0.0.1", 1, now)
+	require.Equal(t, "eth0", name)
+
+	agent.columns[ifNameOID][1] = "eth1"
+	name, _ = d.lookup("10.0.0.1", 1, now.Add(59*time.Minute))
+	require.Equal(t, "eth0", name)
+	require.Equal(t, 1, agent.walks)
+
+	name, _ = d.lookup("10.0.0.1", 1, now.Add(time.Hour))
+	require.Equal(t, "eth1", name)
+	require.Equal(t, 2, agent.walks)
+}
+
+func TestRefreshOnMiss(t *testing.T) {
+	agent := &mockAgent{columns: map[string]map[int]string{ifNameOID: {1: "eth0"}}}
+	d := newTestIfName(map[string]*mockAgent{"10.0.0.1": agent})
+
+	now := time.Unix(1500000000, 0)
+	d.lookup("10.0.0.1", 1, now)
+
+	// the new interface is only walked once the minimum refresh interval
+	// elapsed
+	agent.columns[ifNameOID][2] = "eth1"
+	_, ok := d.lookup("10.0.0.1", 2, now.Add(time.Second))
+	require.False(t, ok)
+	require.Equal(t, 1, agent.walks)
+
+	name, ok := d.lookup("10.0.0.1", 2, now.Add(time.Minute))
+	require.True(t, ok)
+	require.Equal(t, "eth1", name)
+	require.Equal(t, 2, agent.walks)
+}
+
+func TestWalkError(t *testing.T) {
+	agent := &mockAgent{columns: map[string]map[int]string{ifNameOID: {1: "eth0"}}}
+	d := newTestIfName(map[string]*mockAgent{"10.0.0.1": agent})
+
+	now := time.Unix(1500000000, 0)
+	d.lookup("10.0.0.1", 1, now)
+
+	// the previous names are kept when the agent fails
+	agent.err = fmt.Errorf("request timeout")
+	name, ok := d.lookup("10.0.0.1", 1, now.Add(time.Hour))
+	require.True(t, ok)
+	require.Equal(t, "eth0", name)
+	require.Equal(t, 2, agent.walks)
+
+	// and the agent is not walked again before the refresh interval
+	d.lookup("10.0.0.1", 1, now.Add(time.Hour+time.Second))
+	require.Equal(t, 2, agent.walks)
+}
+
+func TestInvalidIndexOID(t *testing.T) {
+	agent := &mockAgent{columns: map[string]map[int]string{}}
+	d := newTestIfName(map[string]*mockAgent{"10.0.0.1": agent})
+	d.connect = func(string) (walker, func(), error) {
+		return walkFunc(func(oid string, fn gosnmp.WalkFunc) error {
+			return fn(gosnmp.SnmpPDU{Name: strings.TrimSuffix(oid, ".1") + ".x", Value: []byte("eth0")})
+		}), func() {}, nil
+	}
+
+	_, err := d.walk("10.0.0.1")
+	require.Error(t, err)
+}
+
+type walkFunc func(oid string, fn gosnmp.WalkFunc) error
+
+func (f walkFunc) Walk(oid string, fn gosnmp.WalkFunc) error {
+	return f(oid, fn)
+}
+
+func TestInitInvalidVersion(t *testing.T) {
+	d := &IfName{Tag: "ifIndex", Dest: "ifName", Agent: "agent_host"}
+	d.ClientConfig.Version = 4
+	require.Error(t, d.Init())
+}
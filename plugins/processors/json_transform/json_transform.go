@@ -0,0 +1,158 @@
+package json_transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/tidwall/gjson"
+)
+
+var sampleConfig = `
+  ## String field containing the JSON document.
+  field = "payload"
+
+  ## Remove the field once its JSON document is transformed.  The field of
+  ## an invalid document is kept.
+  # drop_original = false
+
+  ## How the paths resulting in an array are added, "index" to add each
+  ## element as name_0, name_1, ... or "first" to add the first element as
+  ## name.
+  # array_mode = "index"
+
+  ## Fields to add, by name, from the GJSON paths of the document.  See
+  ## https://github.com/tidwall/gjson#path-syntax for the path syntax.
+  [processors.json_transform.fields]
+    temperature = "sensor.readings.temperature"
+    humidity = "sensor.readings.humidity"
+
+  ## Tags to add, by name, from the GJSON paths of the document.
+  [processors.json_transform.tags]
+    device = "sensor.id"
+`
+
+const (
+	arrayModeIndex = "index"
+	arrayModeFirst = "first"
+)
+
+type JSONTransform struct {
+	Field        string            `toml:"field"`
+	DropOriginal bool              `toml:"drop_original"`
+	ArrayMode    string            `toml:"array_mode"`
+	Fields       map[string]string `toml:"fields"`
+	Tags         map[string]string `toml:"tags"`
+}
+
+func (j *JSONTransform) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *JSONTransform) Description() string {
+	return "Add fields and tags from the JSON document of a string field."
+}
+
+func (j *JSONTransform) Init() error {
+	if j.Field == "" {
+		return fmt.Errorf("field must be set")
+	}
+	switch j.ArrayMode {
+	case "":
+		j.ArrayMode = arrayModeIndex
+	case arrayModeIndex, arrayModeFirst:
+	default:
+		return fmt.Errorf("invalid array_mode %q", j.ArrayMode)
+	}
+	return nil
+}
+
+func (j *JSONTransform) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		j.transform(m)
+	}
+	return in
+}
+
+// transform adds the values of the paths of the document to the metric, the
+// metrics without a valid document are left as is.
+func (j *JSONTransform) transform(m telegraf.Metric) {
+	value, ok := m.GetField(j.Field)
+	if !ok {
+		return
+	}
+	doc, ok := value.(string)
+	if !ok || !json.Valid([]byte(doc)) {
+		log.Printf("D! [processors.json_transform] skipping metric %s: field %s is not a JSON document",
+			m.Name(), j.Field)
+		return
+	}
+
+	for name, path := range j.Fields {
+		for key, v := range j.values(name, gjson.Get(doc, path)) {
+			if field := fieldValue(v); field != nil {
+				m.AddField(key, field)
+			}
+		}
+	}
+	for name, path := range j.Tags {
+		for key, v := range j.values(name, gjson.Get(doc, path)) {
+			if v.Type != gjson.Null {
+				m.AddTag(key, v.String())
+			}
+		}
+	}
+
+	if j.DropOriginal {
+		m.RemoveField(j.Field)
+	}
+}
+
+// values returns the scalar values of the result by name, the elements of
+// the arrays according to the array mode.  The objects are skipped.
+func (j *JSONTransform) values(name string, result gjson.Result) map[string]gjson.Result {
+	values := make(map[string]gjson.Result)
+	switch {
+	case !result.Exists() || result.IsObject():
+	case result.IsArray():
+		for i, element := range result.Array() {
+			if element.IsObject() || element.IsArray() {
+				continue
+			}
+			if j.ArrayMode == arrayModeFirst {
+				values[name] = element
+				break
+			}
+			values[name+"_"+strconv.Itoa(i)] = element
+		}
+	default:
+		values[name] = result
+	}
+	return values
+}
+
+// fieldValue returns the value of a scalar result as a field, the numbers as
+// floats like the json parser.
+func fieldValue(result gjson.Result) interface{} {
+	switch result.Type {
+	case gjson.Number:
+		return result.Float()
+	case gjson.String:
+		return result.String()
+	case gjson.True, gjson.False:
+		return result.Bool()
+	default:
+		return nil
+	}
+}
+
+func init() {
+	processors.Add("json_transform", func() telegraf.Processor {
+		return &JSONTransform{
+			ArrayMode: arrayModeIndex,
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package json_transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const document = `{
+  "sensor": {
+    "id": "th-01",
+    "online": true,
+    "readings": {"temperature": 21.5, "humidity": 40},
+    "history": [20.5, 21, {"skipped": true}, 21.5],
+    "labels": ["indoor", "kitchen"]
+  }
+}`
+
+func newMetric(payload interface{}) telegraf.Metric {
+	return testutil.MustMetric("mqtt_consumer",
+		map[string]string{"topic": "sensors"},
+		map[string]interface{}{"payload": payload},
+		time.Unix(0, 0))
+}
+
+func newTransform(t *testing.T, j *JSONTransform) *JSONTransform {
+	require.NoError(t, j.Init())
+	return j
+}
+
+func TestNestedValues(t *testing.T) {
+	j := newTransform(t, &JSONTransform{
+		Field:        "payload",
+		DropOriginal: true,
+		Fields: map[string]string{
+			"temperature": "sensor.readings.temperature",
+			"humidity":    "sensor.readings.humidity",
+			"online":      "sensor.online",
+			"readings":    "sensor.readings",
+			"missing":     "sensor.missing",
+		},
+		Tags: map[string]string{"device": "sensor.id"},
+	})
+
+	m := newMetric(document)
+	j.Apply(m)
+	require.Equal(t, map[string]interface{}{
+		"temperature": 21.5,
+		"humidity":    40.0,
+		"online":      true,
+	}, m.Fields())
+	require.Equal(t, map[string]string{"topic": "sensors", "device": "th-01"}, m.Tags())
+}
+
+func TestArrayModes(t *testing.T) {
+	fields := map[string]string{"history": "sensor.history"}
+	tags := map[string]string{"label": "sensor.labels"}
+
+	m := newMetric(document)
+	newTransform(t, &JSONTransform{Field: "payload", Fields: fields, Tags: tags}).Apply(m)
+	require.Equal(t, map[string]interface{}{
+		"payload":   document,
+		"history_0": 20.5,
+		"history_1": 21.0,
+		"history_3": 21.5,
+	}, m.Fields())
+	require.Equal(t, map[string]string{"topic": "sensors", "label_0": "indoor", "label_1": "kitchen"}, m.Tags())
+
+	m = newMetric(document)
+	newTransform(t, &JSONTransform{Field: "payload", ArrayMode: "first", Fields: fields, Tags: tags}).Apply(m)
+	require.Equal(t, map[string]interface{}{
+		"payload": document,
+		"history": 20.5,
+	}, m.Fields())
+	require.Equal(t, map[string]string{"topic": "sensors", "label": "indoor"}, m.Tags())
+}
+
+func TestInvalidDocument(t *testing.T) {
+	j := newTransform(t, &JSONTransform{
+		Field:        "payload",
+		DropOriginal: true,
+		Fields:       map[string]string{"temperature": "sensor.readings.temperature"},
+	})
+
+	// the metric is left as is, its field kept
+	for _, payload := range []interface{}{`{"sensor": {`, int64(42)} {
+		m := newMetric(payload)
+		j.Apply(m)
+		require.Equal(t, map[string]interface{}{"payload": payload}, m.Fields())
+	}
+}
+
+func TestInitInvalid(t *testing.T) {
+	require.Error(t, (&JSONTransform{}).Init())
+	require.Error(t, (&JSONTransform{Field: "payload", ArrayMode: "all"}).Init())
+}
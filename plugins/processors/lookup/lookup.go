@@ -0,0 +1,225 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/filewatch"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Path of the file containing the lookup table.  The file is loaded again
+  ## when it changes.
+  file = "/etc/telegraf/hosts.csv"
+
+  ## Format of the file, either "csv" or "json".  By default the format is
+  ## detected from the file extension.
+  ##
+  ## The first row of a CSV file holds the tag names, the first column holds
+  ## the keys.  A JSON file holds an object of keys, each with an object of
+  ## tags:
+  ##   {"server01": {"datacenter": "us-east-1", "team": "web"}}
+  # format = ""
+
+  ## Tag whose value is looked up in the table.
+  key = "host"
+
+  ## Action taken on metrics whose key is not in the table:
+  ##   pass    - leave the metric unchanged
+  ##   default - add the tags of the default table
+  ##   drop    - drop the metric
+  # on_missing = "pass"
+
+  ## Tags added when on_missing is set to "default".
+  # [processors.lookup.default]
+  #   datacenter = "unknown"
+`
+
+type Lookup struct {
+	File      string            `toml:"file"`
+	Format    string            `toml:"format"`
+	Key       string            `toml:"key"`
+	OnMissing string            `toml:"on_missing"`
+	Default   map[string]string `toml:"default"`
+
+	// watch is the configuration of the watcher of the file, with the
+	// default intervals unless set by the tests.
+	watch   filewatch.Config
+	watcher *filewatch.Watcher
+
+	mu    sync.RWMutex
+	table map[string]map[string]string
+}
+
+func (l *Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lookup) Description() string {
+	return "Add tags to metrics from a lookup table keyed by a tag value."
+}
+
+func (l *Lookup) Init() error {
+	if l.File == "" {
+		return fmt.Errorf("file must be set")
+	}
+	if l.Key == "" {
+		return fmt.Errorf("key must be set")
+	}
+	if _, err := l.format(); err != nil {
+		return err
+	}
+	switch l.OnMissing {
+	case "":
+		l.OnMissing = "pass"
+	case "pass", "default", "drop":
+	default:
+		return fmt.Errorf("invalid on_missing %q", l.OnMissing)
+	}
+
+	// The first contents are parsed by New, the table must be valid for the
+	// processor to start.
+	var loadErr error
+	first := true
+	l.watch.Path = l.File
+	watcher, err := filewatch.New(l.watch, func(data []byte) {
+		err := l.load(data)
+		if first {
+			first, loadErr = false, err
+		} else if err != nil {
+			log.Printf("E! [processors.lookup] could not load %s, keeping the "+
+				"previous table: %v", l.File, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if loadErr != nil {
+		watcher.Close()
+		return loadErr
+	}
+	l.watcher = watcher
+	return nil
+}
+
+// Close stops watching the file.
+func (l *Lookup) Close() error {
+	if l.watcher != nil {
+		l.watcher.Close()
+	}
+	return nil
+}
+
+func (l *Lookup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	l.mu.RLock()
+	table := l.table
+	l.mu.RUnlock()
+
+	out := in[:0]
+	for _, metric := range in {
+		key, ok := metric.GetTag(l.Key)
+		if !ok {
+			out = append(out, metric)
+			continue
+		}
+
+		tags, ok := table[key]
+		if !ok {
+			switch l.OnMissing {
+			case "drop":
+				metric.Drop()
+				continue
+			case "default":
+				tags = l.Default
+			}
+		}
+
+		for k, v := range tags {
+			metric.AddTag(k, v)
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// load parses the contents of the file, the current table is kept on
+// error.
+func (l *Lookup) load(data []byte) error {
+	format, _ := l.format()
+	var table map[string]map[string]string
+	var err error
+	switch format {
+	case "csv":
+		table, err = parseCSV(bytes.NewReader(data))
+	case "json":
+		table, err = parseJSON(bytes.NewReader(data))
+	}
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %v", l.File, err)
+	}
+
+	l.mu.Lock()
+	l.table = table
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *Lookup) format() (string, error) {
+	format := l.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(l.File)), ".")
+	}
+	switch format {
+	case "csv", "json":
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid format %q, must be csv or json", format)
+}
+
+func parseCSV(r io.Reader) (map[string]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	header := records[0]
+	table := make(map[string]map[string]string, len(records)-1)
+	for _, record := range records[1:] {
+		tags := make(map[string]string, len(header)-1)
+		for i := 1; i < len(header); i++ {
+			if record[i] != "" {
+				tags[header[i]] = record[i]
+			}
+		}
+		table[record[0]] = tags
+	}
+	return table, nil
+}
+
+func parseJSON(r io.Reader) (map[string]map[string]string, error) {
+	var table map[string]map[string]string
+	if err := json.NewDecoder(r).Decode(&table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func init() {
+	processors.Add("lookup", func() telegraf.Processor {
+		return &Lookup{}
+	})
+}
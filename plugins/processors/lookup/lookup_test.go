@@ -0,0 +1,208 @@
+package lookup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/filewatch"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hostsCSV = `host,datacenter,team
+server01,us-east-1,web
+server02,eu-west-1,
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// testWatch polls the file often so that the tests are quick.
+var testWatch = filewatch.Config{
+	PollInterval: 10 * time.Millisecond,
+	Debounce:     30 * time.Millisecond,
+}
+
+func newTestLookup(t *testing.T, file string) *Lookup {
+	l := &Lookup{
+		File:  file,
+		Key:   "host",
+		watch: testWatch,
+	}
+	require.NoError(t, l.Init())
+	return l
+}
+
+// requireTag waits for the table to be reloaded and the tag of the metric of
+// the host to have the value.
+func requireTag(t *testing.T, l *Lookup, host, key, value string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result := l.Apply(newMetric(host))
+		if result[0].Tags()[key] == value {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tag %s of %s is %q, expected %q", key, host, result[0].Tags()[key], value)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newMetric(host string) telegraf.Metric {
+	tags := map[string]string{}
+	if host != "" {
+		tags["host"] = host
+	}
+	m, _ := metric.New("cpu", tags,
+		map[string]interface{}{"usage_idle": 99.0}, time.Now())
+	return m
+}
+
+func TestLookupCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := newTestLookup(t, writeFile(t, dir, "hosts.csv", hostsCSV))
+	defer l.Close()
+
+	result := l.Apply(newMetric("server01"), newMetric("server02"))
+	require.Len(t, result, 2)
+	assert.Equal(t, map[string]string{
+		"host":       "server01",
+		"datacenter": "us-east-1",
+		"team":       "web",
+	}, result[0].Tags())
+	assert.Equal(t, map[string]string{
+		"host":       "server02",
+		"datacenter": "eu-west-1",
+	}, result[1].Tags())
+}
+
+func TestLookupJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := newTestLookup(t, writeFile(t, dir, "hosts.json",
+		`{"server01": {"datacenter": "us-east-1", "team": "web"}}`))
+	defer l.Close()
+
+	result := l.Apply(newMetric("server01"))
+	require.Len(t, result, 1)
+	assert.Equal(t, map[string]string{
+		"host":       "server01",
+		"datacenter": "us-east-1",
+		"team":       "web",
+	}, result[0].Tags())
+}
+
+func TestLookupMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := writeFile(t, dir, "hosts.csv", hostsCSV)
+
+	l := newTestLookup(t, file)
+	defer l.Close()
+	result := l.Apply(newMetric("server03"), newMetric(""))
+	require.Len(t, result, 2)
+	assert.Equal(t, map[string]string{"host": "server03"}, result[0].Tags())
+	assert.Equal(t, map[string]string{}, result[1].Tags())
+
+	l = &Lookup{
+		File:      file,
+		Key:       "host",
+		OnMissing: "default",
+		Default:   map[string]string{"datacenter": "unknown"},
+	}
+	require.NoError(t, l.Init())
+	defer l.Close()
+	result = l.Apply(newMetric("server03"))
+	require.Len(t, result, 1)
+	assert.Equal(t, map[string]string{
+		"host":       "server03",
+		"datacenter": "unknown",
+	}, result[0].Tags())
+
+	l = &Lookup{
+		File:      file,
+		Key:       "host",
+		OnMissing: "drop",
+	}
+	require.NoError(t, l.Init())
+	defer l.Close()
+	result = l.Apply(newMetric("server03"), newMetric("server01"), newMetric(""))
+	require.Len(t, result, 2)
+	assert.Equal(t, "server01", result[0].Tags()["host"])
+	assert.Equal(t, map[string]string{}, result[1].Tags())
+}
+
+func TestLookupReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := writeFile(t, dir, "hosts.csv", hostsCSV)
+
+	l := newTestLookup(t, file)
+	defer l.Close()
+	result := l.Apply(newMetric("server01"))
+	assert.Equal(t, "us-east-1", result[0].Tags()["datacenter"])
+
+	// The file is replaced by a rename, like by editors.
+	tmp := writeFile(t, dir, ".hosts.csv.tmp", "host,datacenter\nserver01,ap-south-1\n")
+	require.NoError(t, os.Rename(tmp, file))
+	requireTag(t, l, "server01", "datacenter", "ap-south-1")
+	result = l.Apply(newMetric("server01"))
+	assert.Equal(t, map[string]string{
+		"host":       "server01",
+		"datacenter": "ap-south-1",
+	}, result[0].Tags())
+
+	// An invalid file keeps the current table.
+	writeFile(t, dir, "hosts.csv", "host,datacenter\nserver01\n")
+	time.Sleep(200 * time.Millisecond)
+	result = l.Apply(newMetric("server01"))
+	assert.Equal(t, "ap-south-1", result[0].Tags()["datacenter"])
+
+	// The file is read again once valid.
+	writeFile(t, dir, "hosts.csv", "host,datacenter\nserver01,sa-east-1\n")
+	requireTag(t, l, "server01", "datacenter", "sa-east-1")
+}
+
+func TestLookupInitErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := writeFile(t, dir, "hosts.csv", hostsCSV)
+
+	tests := []*Lookup{
+		{Key: "host"},
+		{File: file},
+		{File: file, Key: "host", Format: "yaml"},
+		{File: writeFile(t, dir, "hosts.txt", hostsCSV), Key: "host"},
+		{File: file, Key: "host", OnMissing: "ignore"},
+		{File: filepath.Join(dir, "missing.csv"), Key: "host"},
+	}
+	for _, l := range tests {
+		l.watch = testWatch
+		require.Error(t, l.Init())
+	}
+
+	// The file must be valid for the processor to start.
+	l := &Lookup{
+		File:  writeFile(t, dir, "invalid.csv", "host,datacenter\nserver01\n"),
+		Key:   "host",
+		watch: testWatch,
+	}
+	require.Error(t, l.Init())
+}
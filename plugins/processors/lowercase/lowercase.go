@@ -60,8 +60,12 @@ func toLower(metric telegraf.Metric) telegraf.Metric {
 	for key, value := range metric.Fields() {
 		// The metric interface does not expose fields; we
 		// therefore remove and re-add the affected key.
+		metadata := metric.FieldMetadata(key)
 		metric.RemoveField(key)
 		metric.AddField(strings.ToLower(key), value)
+		for k, v := range metadata {
+			metric.SetFieldMetadata(strings.ToLower(key), k, v)
+		}
 	}
 	return metric
 }
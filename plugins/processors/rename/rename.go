@@ -51,8 +51,12 @@ func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
 
 			if replace.Field != "" {
 				if value, ok := point.GetField(replace.Field); ok {
+					metadata := point.FieldMetadata(replace.Field)
 					point.RemoveField(replace.Field)
 					point.AddField(replace.Dest, value)
+					for k, v := range metadata {
+						point.SetFieldMetadata(replace.Dest, k, v)
+					}
 				}
 				continue
 			}
@@ -59,3 +59,18 @@ func TestFieldRename(t *testing.T) {
 
 	assert.Equal(t, map[string]interface{}{"time": int64(1250), "snakes": true}, results[0].Fields(), "should change field 'time_msec' to 'time'")
 }
+
+func TestFieldRenameKeepsMetadata(t *testing.T) {
+	r := Rename{
+		Replaces: []Replace{
+			{Field: "foo", Dest: "bar"},
+		},
+	}
+	m := newMetric("foo", nil, map[string]interface{}{"foo": 42})
+	m.SetFieldMetadata("foo", "unit", "seconds")
+
+	results := r.Apply(m)
+
+	assert.False(t, results[0].HasField("foo"))
+	assert.Equal(t, map[string]interface{}{"unit": "seconds"}, results[0].FieldMetadata("bar"))
+}
@@ -0,0 +1,240 @@
+package reverse_dns
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Name of the tag or field holding the IP address.
+  source = "source_ip"
+
+  ## Name of the tag added with the host name.
+  dest = "source_name"
+
+  ## DNS servers queried in order, as ADDR[:PORT], until one answers.  The
+  ## resolver of the system is used if empty.
+  # servers = ["192.168.1.1:53", "8.8.8.8:53"]
+
+  ## Timeout of a lookup on each server.
+  # lookup_timeout = "3s"
+
+  ## Time the host names are kept before being looked up again, and the time
+  ## the failed lookups are not retried.
+  # cache_ttl = "24h"
+  # failure_ttl = "1m"
+
+  ## Maximum number of lookups in flight, the metrics of a batch are resolved
+  ## concurrently.
+  # max_parallel_lookups = 10
+`
+
+// resolver resolves the names of an address, like net.Resolver.
+type resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// entry is the host name of an address, empty if the lookup failed.
+type entry struct {
+	name    string
+	expires time.Time
+}
+
+type ReverseDNS struct {
+	Source             string            `toml:"source"`
+	Dest               string            `toml:"dest"`
+	Servers            []string          `toml:"servers"`
+	LookupTimeout      internal.Duration `toml:"lookup_timeout"`
+	CacheTTL           internal.Duration `toml:"cache_ttl"`
+	FailureTTL         internal.Duration `toml:"failure_ttl"`
+	MaxParallelLookups int               `toml:"max_parallel_lookups"`
+
+	resolvers []resolver
+
+	mu        sync.Mutex
+	cache     map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func (r *ReverseDNS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *ReverseDNS) Description() string {
+	return "Add the host names of the IP addresses of the metrics, resolved by reverse DNS."
+}
+
+func (r *ReverseDNS) Init() error {
+	if r.Source == "" || r.Dest == "" {
+		return fmt.Errorf("source and dest must be set")
+	}
+	if r.MaxParallelLookups <= 0 {
+		return fmt.Errorf("invalid max_parallel_lookups %d", r.MaxParallelLookups)
+	}
+
+	if r.resolvers == nil {
+		for _, server := range r.Servers {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+			r.resolvers = append(r.resolvers, newServerResolver(server))
+		}
+		if len(r.resolvers) == 0 {
+			r.resolvers = []resolver{net.DefaultResolver}
+		}
+	}
+	if r.now == nil {
+		r.now = time.Now
+	}
+	r.cache = make(map[string]*entry)
+	return nil
+}
+
+// newServerResolver returns a resolver querying the DNS server only.
+func newServerResolver(server string) resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// Apply tags the metrics with the host names of their addresses.  The
+// addresses of the batch not cached are looked up concurrently, the metrics
+// of the addresses failing to resolve are passed unchanged.
+func (r *ReverseDNS) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := r.now()
+	addrs := make([]string, len(in))
+	var missing []string
+	r.mu.Lock()
+	r.sweep(now)
+	for i, metric := range in {
+		addr, ok := r.address(metric)
+		if !ok {
+			continue
+		}
+		addrs[i] = addr
+		if e, ok := r.cache[addr]; !ok || !now.Before(e.expires) {
+			// marks the address as looked up, until its lookup is done
+			r.cache[addr] = &entry{expires: now.Add(r.FailureTTL.Duration)}
+			missing = append(missing, addr)
+		}
+	}
+	r.mu.Unlock()
+
+	r.resolveAll(missing)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, metric := range in {
+		if addrs[i] == "" {
+			continue
+		}
+		if e, ok := r.cache[addrs[i]]; ok && e.name != "" {
+			metric.AddTag(r.Dest, e.name)
+		}
+	}
+	return in
+}
+
+// address returns the IP address of the metric, from the tag or else the
+// field.
+func (r *ReverseDNS) address(metric telegraf.Metric) (string, bool) {
+	v, ok := metric.GetTag(r.Source)
+	if !ok {
+		field, ok := metric.GetField(r.Source)
+		if !ok {
+			return "", false
+		}
+		if v, ok = field.(string); !ok {
+			return "", false
+		}
+	}
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return "", false
+	}
+	return ip.String(), true
+}
+
+// sweep removes the expired entries of the cache, at most once per cache_ttl.
+func (r *ReverseDNS) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < r.CacheTTL.Duration {
+		return
+	}
+	for addr, e := range r.cache {
+		if !now.Before(e.expires) {
+			delete(r.cache, addr)
+		}
+	}
+	r.lastSweep = now
+}
+
+// resolveAll looks up the addresses with at most max_parallel_lookups lookups
+// in flight, and caches their names.
+func (r *ReverseDNS) resolveAll(addrs []string) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, r.MaxParallelLookups)
+	for _, addr := range addrs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(addr string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			name, err := r.resolve(addr)
+			if err != nil {
+				log.Printf("D! [processors.reverse_dns] could not resolve %s: %v", addr, err)
+				return
+			}
+			r.mu.Lock()
+			r.cache[addr] = &entry{name: name, expires: r.now().Add(r.CacheTTL.Duration)}
+			r.mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+}
+
+// resolve returns the first name of the address, from the first resolver
+// answering.
+func (r *ReverseDNS) resolve(addr string) (string, error) {
+	var err error
+	for _, res := range r.resolvers {
+		var names []string
+		ctx, cancel := context.WithTimeout(context.Background(), r.LookupTimeout.Duration)
+		names, err = res.LookupAddr(ctx, addr)
+		cancel()
+		if err != nil {
+			continue
+		}
+		if len(names) == 0 {
+			return "", fmt.Errorf("no name")
+		}
+		return strings.TrimSuffix(names[0], "."), nil
+	}
+	return "", err
+}
+
+func init() {
+	processors.Add("reverse_dns", func() telegraf.Processor {
+		return &ReverseDNS{
+			LookupTimeout:      internal.Duration{Duration: 3 * time.Second},
+			CacheTTL:           internal.Duration{Duration: 24 * time.Hour},
+			FailureTTL:         internal.Duration{Duration: time.Minute},
+			MaxParallelLookups: 10,
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package reverse_dns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// mockResolver resolves the addresses of its names, counting the lookups and
+// the lookups in flight.
+type mockResolver struct {
+	sync.Mutex
+	names    map[string]string
+	err      error
+	delay    time.Duration
+	lookups  int
+	inFlight int
+	maxInUse int
+}
+
+func (m *mockResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	m.Lock()
+	m.lookups++
+	m.inFlight++
+	if m.inFlight > m.maxInUse {
+		m.maxInUse = m.inFlight
+	}
+	m.Unlock()
+	defer func() {
+		m.Lock()
+		m.inFlight--
+		m.Unlock()
+	}()
+
+	time.Sleep(m.delay)
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	name, ok := m.names[addr]
+	if !ok {
+		return nil, fmt.Errorf("lookup %s: no such host", addr)
+	}
+	return []string{name + "."}, nil
+}
+
+func newTestReverseDNS(t *testing.T, now *time.Time, resolvers ...resolver) *ReverseDNS {
+	r := &ReverseDNS{
+		Source:             "source_ip",
+		Dest:               "source_name",
+		LookupTimeout:      internal.Duration{Duration: time.Second},
+		CacheTTL:           internal.Duration{Duration: time.Hour},
+		FailureTTL:         internal.Duration{Duration: time.Minute},
+		MaxParallelLookups: 2,
+		resolvers:          resolvers,
+		now:                func() time.Time { return *now },
+	}
+	require.NoError(t, r.Init())
+	return r
+}
+
+func newMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("flows", tags, fields, time.Unix(0, 0))
+}
+
+func TestResolveTagAndField(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	res := &mockResolver{names: map[string]string{
+		"10.0.0.1": "db.example.com",
+		"::1":      "localhost",
+	}}
+	r := newTestReverseDNS(t, &now, res)
+
+	result := r.Apply(
+		newMetric(map[string]string{"source_ip": "10.0.0.1"}, map[string]interface{}{"bytes": int64(1)}),
+		newMetric(nil, map[string]interface{}{"source_ip": "0:0::1", "bytes": int64(2)}),
+		newMetric(map[string]string{"source_ip": "10.0.0.1"}, map[string]interface{}{"bytes": int64(3)}),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"source_ip": "10.0.0.1", "source_name": "db.example.com"},
+			map[string]interface{}{"bytes": int64(1)}),
+		newMetric(map[string]string{"source_name": "localhost"},
+			map[string]interface{}{"source_ip": "0:0::1", "bytes": int64(2)}),
+		newMetric(map[string]string{"source_ip": "10.0.0.1", "source_name": "db.example.com"},
+			map[string]interface{}{"bytes": int64(3)}),
+	}, result)
+	// the address is looked up once per batch
+	require.Equal(t, 2, res.lookups)
+}
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	res := &mockResolver{names: map[string]string{"10.0.0.1": "db.example.com"}}
+	r := newTestReverseDNS(t, &now, res)
+	apply := func() string {
+		m := r.Apply(newMetric(map[string]string{"source_ip": "10.0.0.1"},
+			map[string]interface{}{"bytes": int64(1)}))[0]
+		name, _ := m.GetTag("source_name")
+		return name
+	}
+
+	require.Equal(t, "db.example.com", apply())
+	res.names["10.0.0.1"] = "db2.example.com"
+	now = now.Add(59 * time.Minute)
+	require.Equal(t, "db.example.com", apply())
+	require.Equal(t, 1, res.lookups)
+
+	now = now.Add(time.Minute)
+	require.Equal(t, "db2.example.com", apply())
+	require.Equal(t, 2, res.lookups)
+}
+
+func TestFailurePassed(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	res := &mockResolver{names: map[string]string{}}
+	r := newTestReverseDNS(t, &now, res)
+
+	metrics := []telegraf.Metric{
+		newMetric(map[string]string{"source_ip": "10.0.0.1"}, map[string]interface{}{"bytes": int64(1)}),
+		newMetric(map[string]string{"source_ip": "invalid"}, map[string]interface{}{"bytes": int64(1)}),
+		newMetric(nil, map[string]interface{}{"source_ip": int64(1)}),
+	}
+	expected := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		expected = append(expected, m.Copy())
+	}
+	testutil.RequireMetricsEqual(t, expected, r.Apply(metrics...))
+	require.Equal(t, 1, res.lookups)
+
+	// the failed lookups are retried after failure_ttl
+	res.names["10.0.0.1"] = "db.example.com"
+	now = now.Add(30 * time.Second)
+	m := r.Apply(newMetric(map[string]string{"source_ip": "10.0.0.1"}, map[string]interface{}{"bytes": int64(1)}))[0]
+	require.False(t, m.HasTag("source_name"))
+	require.Equal(t, 1, res.lookups)
+
+	now = now.Add(30 * time.Second)
+	m = r.Apply(newMetric(map[string]string{"source_ip": "10.0.0.1"}, map[string]interface{}{"bytes": int64(1)}))[0]
+	name, ok := m.GetTag("source_name")
+	require.True(t, ok)
+	require.Equal(t, "db.example.com", name)
+}
+
+func TestResolversOrder(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	failing := &mockResolver{err: fmt.Errorf("i/o timeout")}
+	second := &mockResolver{names: map[string]string{"10.0.0.1": "db.example.com"}}
+	third := &mockResolver{names: map[string]string{"10.0.0.1": "other.example.com"}}
+	r := newTestReverseDNS(t, &now, failing, second, third)
+
+	m := r.Apply(newMetric(map[string]string{"source_ip": "10.0.0.1"}, map[string]interface{}{"bytes": int64(1)}))[0]
+	name, _ := m.GetTag("source_name")
+	require.Equal(t, "db.example.com", name)
+	require.Equal(t, 1, failing.lookups)
+	require.Equal(t, 0, third.lookups)
+}
+
+func TestMaxParallelLookups(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	res := &mockResolver{names: map[string]string{}, delay: 10 * time.Millisecond}
+	r := newTestReverseDNS(t, &now, res)
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		res.names[fmt.Sprintf("10.0.0.%d", i)] = fmt.Sprintf("host%d", i)
+		metrics = append(metrics, newMetric(map[string]string{"source_ip": fmt.Sprintf("10.0.0.%d", i)},
+			map[string]interface{}{"bytes": int64(1)}))
+	}
+	for i, m := range r.Apply(metrics...) {
+		name, _ := m.GetTag("source_name")
+		require.Equal(t, fmt.Sprintf("host%d", i), name)
+	}
+	require.Equal(t, 10, res.lookups)
+	require.Equal(t, 2, res.maxInUse)
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, r := range []*ReverseDNS{
+		{Dest: "source_name", MaxParallelLookups: 1},
+		{Source: "source_ip", MaxParallelLookups: 1},
+		{Source: "source_ip", Dest: "source_name"},
+	} {
+		require.Error(t, r.Init())
+	}
+}
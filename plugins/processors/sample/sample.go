@@ -0,0 +1,71 @@
+package sample
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/sampler"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Sampling mode, one of:
+  ##   rate:        keep one metric of every "rate" metrics
+  ##   probability: keep each metric with the given probability
+  ##   reservoir:   keep the metrics of at most "size" series, chosen
+  ##                uniformly among the series seen
+  mode = "rate"
+  rate = 10
+  # probability = 0.1
+  # size = 1000
+
+  ## Sample series rather than metrics with the rate and probability modes,
+  ## all the metrics of a series are then either kept or dropped.
+  # per_series = false
+`
+
+type Sample struct {
+	Mode        string  `toml:"mode"`
+	Rate        int     `toml:"rate"`
+	Probability float64 `toml:"probability"`
+	Size        int     `toml:"size"`
+	PerSeries   bool    `toml:"per_series"`
+
+	sampler *sampler.Sampler
+}
+
+func (s *Sample) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sample) Description() string {
+	return "Keep only a sample of the metrics."
+}
+
+func (s *Sample) Init() error {
+	var err error
+	s.sampler, err = sampler.New(sampler.Config{
+		Mode:        s.Mode,
+		Rate:        s.Rate,
+		Probability: s.Probability,
+		Size:        s.Size,
+		PerSeries:   s.PerSeries,
+	})
+	return err
+}
+
+func (s *Sample) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		if !s.sampler.Sample(metric) {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("sample", func() telegraf.Processor {
+		return &Sample{Mode: sampler.ModeRate}
+	})
+}
@@ -0,0 +1,45 @@
+package sample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(host string) telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{"host": host},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0))
+}
+
+func TestApplyRate(t *testing.T) {
+	s := &Sample{Mode: "rate", Rate: 2}
+	require.NoError(t, s.Init())
+
+	out := s.Apply(newMetric("a"), newMetric("b"), newMetric("c"), newMetric("d"))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{newMetric("b"), newMetric("d")}, out)
+	require.Len(t, s.Apply(newMetric("a")), 0)
+	require.Len(t, s.Apply(newMetric("a")), 1)
+}
+
+func TestApplyReservoir(t *testing.T) {
+	s := &Sample{Mode: "reservoir", Size: 1}
+	require.NoError(t, s.Init())
+
+	// only the series with the smallest hash is kept once both are seen
+	s.Apply(newMetric("a"), newMetric("b"))
+	kept := 0
+	for i := 0; i < 10; i++ {
+		kept += len(s.Apply(newMetric("a"), newMetric("b")))
+	}
+	require.Equal(t, 10, kept)
+}
+
+func TestInitError(t *testing.T) {
+	s := &Sample{Mode: "rate"}
+	require.EqualError(t, s.Init(), "rate must be at least 1")
+}
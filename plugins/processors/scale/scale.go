@@ -0,0 +1,133 @@
+package scale
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Linear remappings of the field values from the input range to the output
+  ## range, like raw ADC counts to engineering units.
+  [[processors.scale.scaling]]
+    ## Fields to scale, glob patterns are supported.
+    fields = ["pressure_raw"]
+
+    ## Input and output ranges, the input range must not be empty.  The
+    ## values outside of the input range are extrapolated, or clamped to the
+    ## output range with clamp.
+    in_min = 0.0
+    in_max = 4095.0
+    out_min = 0.0
+    out_max = 10.0
+    # clamp = false
+`
+
+type Scaling struct {
+	Fields []string `toml:"fields"`
+	InMin  float64  `toml:"in_min"`
+	InMax  float64  `toml:"in_max"`
+	OutMin float64  `toml:"out_min"`
+	OutMax float64  `toml:"out_max"`
+	Clamp  bool     `toml:"clamp"`
+
+	filter filter.Filter
+	factor float64
+}
+
+type Scale struct {
+	Scalings []*Scaling `toml:"scaling"`
+}
+
+func (s *Scale) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Scale) Description() string {
+	return "Scale the field values linearly from an input range to an output range."
+}
+
+func (s *Scale) Init() error {
+	for i, sc := range s.Scalings {
+		if err := sc.init(); err != nil {
+			return fmt.Errorf("scaling %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (sc *Scaling) init() error {
+	if len(sc.Fields) == 0 {
+		return fmt.Errorf("no fields")
+	}
+	if sc.InMin == sc.InMax {
+		return fmt.Errorf("in_min and in_max must be different")
+	}
+
+	var err error
+	if sc.filter, err = filter.Compile(sc.Fields); err != nil {
+		return err
+	}
+	sc.factor = (sc.OutMax - sc.OutMin) / (sc.InMax - sc.InMin)
+	return nil
+}
+
+// scale remaps the value, the ranges may be decreasing.
+func (sc *Scaling) scale(v float64) float64 {
+	out := sc.OutMin + (v-sc.InMin)*sc.factor
+	if !sc.Clamp {
+		return out
+	}
+	low, high := sc.OutMin, sc.OutMax
+	if low > high {
+		low, high = high, low
+	}
+	if out < low {
+		return low
+	}
+	if out > high {
+		return high
+	}
+	return out
+}
+
+// Apply scales the numeric fields, with the first scaling matching each
+// field.
+func (s *Scale) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		for _, field := range m.FieldList() {
+			v, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+			for _, sc := range s.Scalings {
+				if sc.filter.Match(field.Key) {
+					m.AddField(field.Key, sc.scale(v))
+					break
+				}
+			}
+		}
+	}
+	return in
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.Add("scale", func() telegraf.Processor {
+		return &Scale{}
+	})
+}
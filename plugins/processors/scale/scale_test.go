@@ -0,0 +1,91 @@
+package scale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("sensors", map[string]string{}, fields, time.Unix(0, 0))
+}
+
+func newScale(t *testing.T, scalings ...*Scaling) *Scale {
+	s := &Scale{Scalings: scalings}
+	require.NoError(t, s.Init())
+	return s
+}
+
+func TestInRange(t *testing.T) {
+	s := newScale(t,
+		&Scaling{Fields: []string{"adc_*"}, InMin: 0, InMax: 4095, OutMin: 0, OutMax: 10},
+		&Scaling{Fields: []string{"current"}, InMin: 4, InMax: 20, OutMin: 0, OutMax: 100},
+		// the first matching scaling applies
+		&Scaling{Fields: []string{"*"}, InMin: 0, InMax: 1, OutMin: 0, OutMax: 2},
+	)
+
+	m := newMetric(map[string]interface{}{
+		"adc_0":   int64(0),
+		"adc_1":   uint64(4095),
+		"adc_2":   2047.5,
+		"current": 12.0,
+		"other":   0.25,
+		"state":   "ok",
+	})
+	s.Apply(m)
+	require.Equal(t, map[string]interface{}{
+		"adc_0":   0.0,
+		"adc_1":   10.0,
+		"adc_2":   5.0,
+		"current": 50.0,
+		"other":   0.5,
+		"state":   "ok",
+	}, m.Fields())
+}
+
+func TestOutOfRange(t *testing.T) {
+	scaling := &Scaling{Fields: []string{"current"}, InMin: 4, InMax: 20, OutMin: 0, OutMax: 100}
+	s := newScale(t, scaling)
+
+	// the values are extrapolated without clamping
+	low := newMetric(map[string]interface{}{"current": 2.0})
+	high := newMetric(map[string]interface{}{"current": int64(24)})
+	s.Apply(low, high)
+	require.Equal(t, -12.5, low.Fields()["current"])
+	require.Equal(t, 125.0, high.Fields()["current"])
+
+	scaling.Clamp = true
+	low = newMetric(map[string]interface{}{"current": 2.0})
+	high = newMetric(map[string]interface{}{"current": int64(24)})
+	s.Apply(low, high)
+	require.Equal(t, 0.0, low.Fields()["current"])
+	require.Equal(t, 100.0, high.Fields()["current"])
+}
+
+func TestDecreasingRange(t *testing.T) {
+	s := newScale(t,
+		&Scaling{Fields: []string{"level"}, InMin: 0, InMax: 1000, OutMin: 100, OutMax: 0, Clamp: true},
+	)
+
+	m1 := newMetric(map[string]interface{}{"level": int64(250)})
+	m2 := newMetric(map[string]interface{}{"level": int64(-100)})
+	m3 := newMetric(map[string]interface{}{"level": int64(1100)})
+	s.Apply(m1, m2, m3)
+	require.Equal(t, 75.0, m1.Fields()["level"])
+	require.Equal(t, 100.0, m2.Fields()["level"])
+	require.Equal(t, 0.0, m3.Fields()["level"])
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, sc := range []*Scaling{
+		{InMin: 0, InMax: 1, OutMin: 0, OutMax: 1},
+		{Fields: []string{"value"}, InMin: 5, InMax: 5, OutMin: 0, OutMax: 1},
+	} {
+		s := &Scale{Scalings: []*Scaling{sc}}
+		require.Error(t, s.Init())
+	}
+}
@@ -0,0 +1,238 @@
+package split
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## The elements are either numbered fields, matched by a regular expression
+  ## with an "index" group, or the values of delimited string fields.  Set
+  ## only one of field_pattern and delimited_fields.
+  ##
+  ## With a "name" group, the fields of the elements are named after it so
+  ## that parallel arrays, like temp_0, hum_0, temp_1, hum_1, give one
+  ## metric per index with the fields temp and hum.  Without it, the
+  ## elements have a single field named after field.
+  field_pattern = '^values_(?P<index>\d+)$'
+
+  ## String fields holding the elements separated by delimiter, the fields
+  ## of the elements are named after them, or after field if there is only
+  ## one.  The values parsing as integers or floats are converted.
+  # delimited_fields = ["values"]
+  # delimiter = ","
+  # convert_numbers = true
+
+  ## Name of the field of the elements with a single field.
+  # field = "value"
+
+  ## Tag holding the index of the elements.
+  # index_tag = "index"
+`
+
+type Split struct {
+	FieldPattern    string   `toml:"field_pattern"`
+	DelimitedFields []string `toml:"delimited_fields"`
+	Delimiter       string   `toml:"delimiter"`
+	ConvertNumbers  bool     `toml:"convert_numbers"`
+	Field           string   `toml:"field"`
+	IndexTag        string   `toml:"index_tag"`
+
+	pattern    *regexp.Regexp
+	indexGroup int
+	nameGroup  int
+}
+
+// element is a metric split from another one.
+type element struct {
+	fields   map[string]interface{}
+	metadata map[string]map[string]interface{}
+}
+
+func (s *Split) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Split) Description() string {
+	return "Split the array-like fields of metrics into one metric per element."
+}
+
+func (s *Split) Init() error {
+	if (s.FieldPattern == "") == (len(s.DelimitedFields) == 0) {
+		return fmt.Errorf("exactly one of field_pattern and delimited_fields must be set")
+	}
+	if s.Field == "" {
+		s.Field = "value"
+	}
+	if s.IndexTag == "" {
+		s.IndexTag = "index"
+	}
+	if s.Delimiter == "" {
+		s.Delimiter = ","
+	}
+
+	if s.FieldPattern == "" {
+		return nil
+	}
+	var err error
+	if s.pattern, err = regexp.Compile(s.FieldPattern); err != nil {
+		return fmt.Errorf("invalid field_pattern: %v", err)
+	}
+	s.indexGroup, s.nameGroup = -1, -1
+	for i, name := range s.pattern.SubexpNames() {
+		switch name {
+		case "index":
+			s.indexGroup = i
+		case "name":
+			s.nameGroup = i
+		}
+	}
+	if s.indexGroup < 0 {
+		return fmt.Errorf("field_pattern %q has no \"index\" group", s.FieldPattern)
+	}
+	return nil
+}
+
+// Apply replaces the metrics with elements by one metric per element, with
+// the tags and timestamp of the original metric.  The original metric is
+// kept with the fields not part of the elements, if any.
+func (s *Split) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		elements := s.split(m)
+		if len(elements) == 0 {
+			out = append(out, m)
+			continue
+		}
+
+		indexes := make([]int, 0, len(elements))
+		for index := range elements {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		for _, index := range indexes {
+			e := elements[index]
+			tags := m.Tags()
+			tags[s.IndexTag] = strconv.Itoa(index)
+			split, err := metric.New(m.Name(), tags, e.fields, m.Time(), m.Type())
+			if err != nil {
+				continue
+			}
+			for field, metadata := range e.metadata {
+				for k, v := range metadata {
+					split.SetFieldMetadata(field, k, v)
+				}
+			}
+			out = append(out, split)
+		}
+
+		if len(m.FieldList()) > 0 {
+			out = append(out, m)
+		} else {
+			m.Drop()
+		}
+	}
+	return out
+}
+
+// split removes the fields of the elements from the metric and returns the
+// elements by index.  The elements missing from ragged arrays are skipped,
+// as are the fields missing from an element.
+func (s *Split) split(m telegraf.Metric) map[int]*element {
+	elements := make(map[int]*element)
+	add := func(index int, name string, value interface{}, metadata map[string]interface{}) {
+		e, ok := elements[index]
+		if !ok {
+			e = &element{fields: make(map[string]interface{})}
+			elements[index] = e
+		}
+		e.fields[name] = value
+		if len(metadata) > 0 {
+			if e.metadata == nil {
+				e.metadata = make(map[string]map[string]interface{})
+			}
+			e.metadata[name] = metadata
+		}
+	}
+
+	// fields are removed from the list while iterating it
+	fields := append([]*telegraf.Field(nil), m.FieldList()...)
+	for _, field := range fields {
+		if s.pattern != nil {
+			match := s.pattern.FindStringSubmatch(field.Key)
+			if match == nil {
+				continue
+			}
+			index, err := strconv.Atoi(match[s.indexGroup])
+			if err != nil {
+				continue
+			}
+			name := s.Field
+			if s.nameGroup >= 0 && match[s.nameGroup] != "" {
+				name = match[s.nameGroup]
+			}
+			add(index, name, field.Value, m.FieldMetadata(field.Key))
+			m.RemoveField(field.Key)
+			continue
+		}
+
+		if !s.isDelimited(field.Key) {
+			continue
+		}
+		value, ok := field.Value.(string)
+		if !ok {
+			continue
+		}
+		name := field.Key
+		if len(s.DelimitedFields) == 1 {
+			name = s.Field
+		}
+		metadata := m.FieldMetadata(field.Key)
+		for index, v := range strings.Split(value, s.Delimiter) {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			add(index, name, s.convert(v), metadata)
+		}
+		m.RemoveField(field.Key)
+	}
+	return elements
+}
+
+func (s *Split) isDelimited(key string) bool {
+	for _, field := range s.DelimitedFields {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Split) convert(v string) interface{} {
+	if !s.ConvertNumbers {
+		return v
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	// NaN and Inf can not be written by most outputs
+	if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	return v
+}
+
+func init() {
+	processors.Add("split", func() telegraf.Processor {
+		return &Split{ConvertNumbers: true}
+	})
+}
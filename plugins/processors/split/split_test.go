@@ -0,0 +1,116 @@
+package split
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Unix(1500000000, 0)
+
+func newSplit(t *testing.T, s *Split) *Split {
+	require.NoError(t, s.Init())
+	return s
+}
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("sensors", map[string]string{"host": "server01"}, fields, now)
+}
+
+func indexed(index string, fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("sensors", map[string]string{"host": "server01", "index": index}, fields, now)
+}
+
+func TestNumberedFields(t *testing.T) {
+	s := newSplit(t, &Split{FieldPattern: `^values_(?P<index>\d+)$`})
+
+	result := s.Apply(newMetric(map[string]interface{}{
+		"values_0": 1.5,
+		"values_1": 2.5,
+		"values_3": 4.5,
+		"count":    int64(3),
+	}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		indexed("0", map[string]interface{}{"value": 1.5}),
+		indexed("1", map[string]interface{}{"value": 2.5}),
+		indexed("3", map[string]interface{}{"value": 4.5}),
+		// the other fields are kept on the original metric
+		newMetric(map[string]interface{}{"count": int64(3)}),
+	}, result)
+}
+
+func TestParallelArrays(t *testing.T) {
+	s := newSplit(t, &Split{
+		FieldPattern: `^(?P<name>\w+)_(?P<index>\d+)$`,
+		IndexTag:     "sensor",
+	})
+
+	// the ragged arrays give elements without some fields
+	m := newMetric(map[string]interface{}{
+		"temp_0": 21.5,
+		"hum_0":  int64(40),
+		"temp_1": 22.5,
+	})
+	m.SetFieldMetadata("temp_1", "unit", "celsius")
+
+	expected := testutil.MustMetric("sensors", map[string]string{"host": "server01", "sensor": "1"},
+		map[string]interface{}{"temp": 22.5}, now)
+	expected.SetFieldMetadata("temp", "unit", "celsius")
+
+	result := s.Apply(m)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("sensors", map[string]string{"host": "server01", "sensor": "0"},
+			map[string]interface{}{"temp": 21.5, "hum": int64(40)}, now),
+		expected,
+	}, result)
+}
+
+func TestDelimitedField(t *testing.T) {
+	s := newSplit(t, &Split{DelimitedFields: []string{"values"}, ConvertNumbers: true})
+
+	result := s.Apply(newMetric(map[string]interface{}{"values": "1, 2.5,,NaN,idle"}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		indexed("0", map[string]interface{}{"value": int64(1)}),
+		indexed("1", map[string]interface{}{"value": 2.5}),
+		indexed("3", map[string]interface{}{"value": "NaN"}),
+		indexed("4", map[string]interface{}{"value": "idle"}),
+	}, result)
+}
+
+func TestDelimitedFields(t *testing.T) {
+	s := newSplit(t, &Split{DelimitedFields: []string{"temp", "state"}, Delimiter: ";"})
+
+	result := s.Apply(newMetric(map[string]interface{}{
+		"temp":  "21;22;23",
+		"state": "ok;failed",
+	}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		indexed("0", map[string]interface{}{"temp": "21", "state": "ok"}),
+		indexed("1", map[string]interface{}{"temp": "22", "state": "failed"}),
+		indexed("2", map[string]interface{}{"temp": "23"}),
+	}, result)
+}
+
+func TestNoElementsPassed(t *testing.T) {
+	s := newSplit(t, &Split{DelimitedFields: []string{"values"}})
+
+	// the fields which are not strings are not split
+	m1 := newMetric(map[string]interface{}{"values": int64(1)})
+	m2 := newMetric(map[string]interface{}{"other": "1,2"})
+	result := s.Apply(m1, m2)
+	require.Equal(t, []telegraf.Metric{m1, m2}, result)
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, s := range []*Split{
+		{},
+		{FieldPattern: `^values_(\d+)$`},
+		{FieldPattern: `^values_(?P<index>\d+`},
+		{FieldPattern: `^values_(?P<index>\d+)$`, DelimitedFields: []string{"values"}},
+	} {
+		require.Error(t, s.Init())
+	}
+}
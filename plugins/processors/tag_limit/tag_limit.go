@@ -0,0 +1,198 @@
+package tag_limit
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// maxTrackedKeys bounds the number of tag keys whose values are counted, the
+// other keys are considered to have the maximum cardinality.
+const maxTrackedKeys = 1000
+
+var sampleConfig = `
+  ## Maximum number of tags of a metric, the tags over the limit are
+  ## dropped.
+  limit = 10
+
+  ## Tags never dropped, they are counted in the limit.
+  # keep = ["host"]
+
+  ## How the tags over the limit are chosen:
+  ##   arbitrary:   the last tags in alphabetical order are dropped
+  ##   cardinality: the tags with the most distinct values are dropped first
+  # mode = "arbitrary"
+
+  ## With the cardinality mode, tags with more distinct values than this
+  ## are always dropped.  By default there is no maximum.
+  # max_cardinality = 0
+
+  ## With the cardinality mode, the distinct values are counted over this
+  ## window of the metric timestamps, and at most max_values values are
+  ## counted for each tag.
+  # window = "1h"
+  # max_values = 1000
+`
+
+type TagLimit struct {
+	Limit          int               `toml:"limit"`
+	Keep           []string          `toml:"keep"`
+	Mode           string            `toml:"mode"`
+	MaxCardinality int               `toml:"max_cardinality"`
+	Window         internal.Duration `toml:"window"`
+	MaxValues      int               `toml:"max_values"`
+
+	keep map[string]bool
+	// values are the hashes of the distinct values of each tag in the
+	// current window, and previous in the window before it.
+	values      map[string]map[uint64]bool
+	previous    map[string]map[uint64]bool
+	windowStart time.Time
+}
+
+func (t *TagLimit) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *TagLimit) Description() string {
+	return "Limit the number of tags of the metrics, dropping the tags over the limit."
+}
+
+func (t *TagLimit) Init() error {
+	switch t.Mode {
+	case "", "arbitrary":
+		t.Mode = "arbitrary"
+		if t.Limit <= 0 {
+			return fmt.Errorf("limit must be set")
+		}
+		if t.MaxCardinality > 0 {
+			return fmt.Errorf("max_cardinality requires the cardinality mode")
+		}
+	case "cardinality":
+		if t.Limit <= 0 && t.MaxCardinality <= 0 {
+			return fmt.Errorf("limit or max_cardinality must be set")
+		}
+		if t.MaxCardinality >= t.MaxValues {
+			return fmt.Errorf("max_cardinality must be lower than max_values")
+		}
+		if t.Window.Duration <= 0 {
+			return fmt.Errorf("window must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid mode %q, must be arbitrary or cardinality", t.Mode)
+	}
+
+	t.keep = make(map[string]bool, len(t.Keep))
+	for _, k := range t.Keep {
+		t.keep[k] = true
+	}
+	t.values = make(map[string]map[uint64]bool)
+	t.previous = make(map[string]map[uint64]bool)
+	return nil
+}
+
+func (t *TagLimit) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		if t.Mode == "cardinality" {
+			t.track(metric)
+		}
+		t.limit(metric)
+	}
+	return in
+}
+
+// track counts the distinct values of the tags of the metric.
+func (t *TagLimit) track(metric telegraf.Metric) {
+	if metric.Time().Sub(t.windowStart) >= t.Window.Duration {
+		t.previous = t.values
+		t.values = make(map[string]map[uint64]bool, len(t.previous))
+		t.windowStart = metric.Time()
+	}
+
+	for _, tag := range metric.TagList() {
+		if t.keep[tag.Key] {
+			continue
+		}
+		values, ok := t.values[tag.Key]
+		if !ok {
+			if len(t.values) >= maxTrackedKeys {
+				continue
+			}
+			values = make(map[uint64]bool)
+			t.values[tag.Key] = values
+		}
+		if len(values) < t.MaxValues {
+			h := fnv.New64a()
+			h.Write([]byte(tag.Value))
+			values[h.Sum64()] = true
+		}
+	}
+}
+
+// cardinality returns the number of distinct values of the tag over the last
+// two windows, so it does not drop when a window starts.
+func (t *TagLimit) cardinality(key string) int {
+	current, ok := t.values[key]
+	if !ok {
+		return t.MaxValues
+	}
+	if n := len(t.previous[key]); n > len(current) {
+		return n
+	}
+	return len(current)
+}
+
+// limit drops the tags of the metric over the limit, the tags to keep are
+// never dropped.
+func (t *TagLimit) limit(metric telegraf.Metric) {
+	// tags are removed from the list while iterating it
+	tags := append([]*telegraf.Tag(nil), metric.TagList()...)
+	var candidates []string
+	for _, tag := range tags {
+		if t.keep[tag.Key] {
+			continue
+		}
+		if t.MaxCardinality > 0 && t.cardinality(tag.Key) > t.MaxCardinality {
+			metric.RemoveTag(tag.Key)
+			continue
+		}
+		candidates = append(candidates, tag.Key)
+	}
+
+	excess := len(metric.TagList()) - t.Limit
+	if t.Limit <= 0 || excess <= 0 {
+		return
+	}
+	// the last tags in alphabetical order are dropped first, or among the
+	// tags with the same cardinality
+	sort.Sort(sort.Reverse(sort.StringSlice(candidates)))
+	if t.Mode == "cardinality" {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return t.cardinality(candidates[i]) > t.cardinality(candidates[j])
+		})
+	}
+	if excess > len(candidates) {
+		excess = len(candidates)
+	}
+	for _, key := range candidates[:excess] {
+		metric.RemoveTag(key)
+	}
+}
+
+func newTagLimit() *TagLimit {
+	return &TagLimit{
+		Window:    internal.Duration{Duration: time.Hour},
+		MaxValues: 1000,
+	}
+}
+
+func init() {
+	processors.Add("tag_limit", func() telegraf.Processor {
+		return newTagLimit()
+	})
+}
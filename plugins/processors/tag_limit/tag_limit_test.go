@@ -0,0 +1,157 @@
+package tag_limit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1500000000, 0)
+
+func newMetric(seconds int, tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric("http",
+		tags,
+		map[string]interface{}{"value": 1},
+		start.Add(time.Duration(seconds)*time.Second))
+}
+
+// requestMetric has a tag per request, and a few tags of low cardinality.
+func requestMetric(i int) telegraf.Metric {
+	return newMetric(i, map[string]string{
+		"host":       "server",
+		"method":     []string{"GET", "POST"}[i%2],
+		"status":     []string{"200", "404", "500"}[i%3],
+		"request_id": fmt.Sprintf("req-%d", i),
+		"user":       fmt.Sprintf("user-%d", i%50),
+	})
+}
+
+func tagKeys(metric telegraf.Metric) []string {
+	var keys []string
+	for _, tag := range metric.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	return keys
+}
+
+func TestArbitrary(t *testing.T) {
+	p := newTagLimit()
+	p.Limit = 3
+	p.Keep = []string{"user"}
+	require.NoError(t, p.Init())
+
+	out := p.Apply(requestMetric(0))
+	require.Equal(t, []string{"host", "method", "user"}, tagKeys(out[0]))
+
+	// metrics under the limit are unchanged
+	m := newMetric(0, map[string]string{"a": "1", "b": "2"})
+	require.Equal(t, []string{"a", "b"}, tagKeys(p.Apply(m)[0]))
+}
+
+func TestCardinalityDropsHighestFirst(t *testing.T) {
+	p := newTagLimit()
+	p.Mode = "cardinality"
+	p.Limit = 3
+	require.NoError(t, p.Init())
+
+	var out []telegraf.Metric
+	for i := 0; i < 100; i++ {
+		out = p.Apply(requestMetric(i))
+	}
+	// request_id then user have the most values
+	require.Equal(t, []string{"host", "method", "status"}, tagKeys(out[0]))
+
+	p.Limit = 4
+	out = p.Apply(requestMetric(100))
+	require.Equal(t, []string{"host", "method", "status", "user"}, tagKeys(out[0]))
+}
+
+func TestCardinalityKeep(t *testing.T) {
+	p := newTagLimit()
+	p.Mode = "cardinality"
+	p.Limit = 2
+	p.Keep = []string{"request_id"}
+	require.NoError(t, p.Init())
+
+	var out []telegraf.Metric
+	for i := 0; i < 100; i++ {
+		out = p.Apply(requestMetric(i))
+	}
+	// the tags to keep count in the limit
+	require.Equal(t, []string{"host", "request_id"}, tagKeys(out[0]))
+}
+
+func TestMaxCardinality(t *testing.T) {
+	p := newTagLimit()
+	p.Mode = "cardinality"
+	p.MaxCardinality = 10
+	require.NoError(t, p.Init())
+
+	var out []telegraf.Metric
+	for i := 0; i < 100; i++ {
+		out = p.Apply(requestMetric(i))
+	}
+	require.Equal(t, []string{"host", "method", "status"}, tagKeys(out[0]))
+}
+
+func TestCardinalityWindow(t *testing.T) {
+	p := newTagLimit()
+	p.Mode = "cardinality"
+	p.MaxCardinality = 10
+	p.Window = internal.Duration{Duration: 100 * time.Second}
+	require.NoError(t, p.Init())
+
+	for i := 0; i < 100; i++ {
+		p.Apply(requestMetric(i))
+	}
+	require.Equal(t, 50, p.cardinality("user"))
+
+	// the values of the previous window are still counted
+	tags := map[string]string{"user": "user-0"}
+	out := p.Apply(newMetric(100, tags))
+	require.Empty(t, out[0].TagList())
+
+	// and forgotten after two windows
+	out = p.Apply(newMetric(200, map[string]string{"user": "user-0"}))
+	require.Equal(t, []string{"user"}, tagKeys(out[0]))
+}
+
+func TestCardinalityBounded(t *testing.T) {
+	p := newTagLimit()
+	p.Mode = "cardinality"
+	p.Limit = 1
+	p.MaxValues = 20
+	require.NoError(t, p.Init())
+
+	for i := 0; i < 100; i++ {
+		p.Apply(requestMetric(i))
+	}
+	require.Len(t, p.values["request_id"], 20)
+	require.Len(t, p.values["user"], 20)
+	require.Len(t, p.values["status"], 3)
+
+	for i := 0; i < 2*maxTrackedKeys; i++ {
+		p.Apply(newMetric(100, map[string]string{fmt.Sprintf("key%d", i): "value"}))
+	}
+	require.Len(t, p.values, maxTrackedKeys)
+	// untracked keys are considered to have the most values
+	require.Equal(t, 20, p.cardinality("key1999"))
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, p := range []*TagLimit{
+		{},
+		{Limit: 1, MaxCardinality: 1},
+		{Limit: 1, Mode: "random"},
+		{Mode: "cardinality", MaxValues: 10, Window: internal.Duration{Duration: time.Hour}},
+		{Mode: "cardinality", Limit: 1, MaxCardinality: 10, MaxValues: 10, Window: internal.Duration{Duration: time.Hour}},
+		{Mode: "cardinality", Limit: 1, MaxValues: 10},
+	} {
+		require.Error(t, p.Init())
+	}
+}
@@ -0,0 +1,171 @@
+package timestamp
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Field holding the time of the metric.  Metrics without the field keep
+  ## their time, as well as metrics whose field could not be parsed.
+  # field = "timestamp"
+
+  ## Format of the field, one of "unix", "unix_ms", "unix_us", "unix_ns" for
+  ## a number of seconds, milliseconds, microseconds or nanoseconds since the
+  ## epoch, or a Go time layout like "2006-01-02T15:04:05Z07:00".
+  # format = "unix"
+
+  ## Timezone of the time layouts without one, "Local" for the local time,
+  ## or a location like "Europe/Paris".
+  # timezone = "UTC"
+
+  ## Round or truncate the time to a multiple of the interval.
+  # round = "0s"
+  # truncate = "0s"
+
+  ## Shift the time by the offset, once the other changes are applied.
+  # offset = "0s"
+`
+
+var epochUnits = map[string]time.Duration{
+	"unix":    time.Second,
+	"unix_ms": time.Millisecond,
+	"unix_us": time.Microsecond,
+	"unix_ns": time.Nanosecond,
+}
+
+type Timestamp struct {
+	Field    string            `toml:"field"`
+	Format   string            `toml:"format"`
+	Timezone string            `toml:"timezone"`
+	Round    internal.Duration `toml:"round"`
+	Truncate internal.Duration `toml:"truncate"`
+	Offset   internal.Duration `toml:"offset"`
+
+	location *time.Location
+}
+
+func (t *Timestamp) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Timestamp) Description() string {
+	return "Set the time of the metrics from a field, round it, or shift it."
+}
+
+func (t *Timestamp) Init() error {
+	if t.Format == "" {
+		t.Format = "unix"
+	}
+	location, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %v", err)
+	}
+	t.location = location
+
+	if t.Round.Duration < 0 || t.Truncate.Duration < 0 {
+		return fmt.Errorf("round and truncate must be positive")
+	}
+	if t.Round.Duration > 0 && t.Truncate.Duration > 0 {
+		return fmt.Errorf("only one of round and truncate can be set")
+	}
+	return nil
+}
+
+func (t *Timestamp) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		tm := metric.Time()
+		if t.Field != "" {
+			if value, ok := metric.GetField(t.Field); ok {
+				parsed, err := t.parse(value)
+				if err != nil {
+					log.Printf("W! [processors.timestamp] Could not parse field %q of %s, keeping its time: %v",
+						t.Field, metric.Name(), err)
+				} else {
+					tm = parsed
+				}
+			}
+		}
+
+		if t.Round.Duration > 0 {
+			tm = tm.Round(t.Round.Duration)
+		} else if t.Truncate.Duration > 0 {
+			tm = tm.Truncate(t.Truncate.Duration)
+		}
+		metric.SetTime(tm.Add(t.Offset.Duration))
+	}
+	return in
+}
+
+// parse returns the time of the field value.
+func (t *Timestamp) parse(value interface{}) (time.Time, error) {
+	unit, ok := epochUnits[t.Format]
+	if !ok {
+		s, ok := value.(string)
+		if !ok {
+			return time.Time{}, fmt.Errorf("value %v is not a string", value)
+		}
+		return time.ParseInLocation(t.Format, s, t.location)
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return epochTime(v, 0, unit), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("value %d is out of range", v)
+		}
+		return epochTime(int64(v), 0, unit), nil
+	case float64:
+		i, frac := math.Modf(v)
+		return epochTime(int64(i), frac, unit), nil
+	case string:
+		// the integer part is parsed apart to not lose precision
+		parts := strings.SplitN(v, ".", 2)
+		i, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		var frac float64
+		if len(parts) == 2 {
+			frac, err = strconv.ParseFloat("0."+parts[1], 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if strings.HasPrefix(parts[0], "-") {
+				frac = -frac
+			}
+		}
+		return epochTime(i, frac, unit), nil
+	default:
+		return time.Time{}, fmt.Errorf("value %v is not a number", value)
+	}
+}
+
+// epochTime returns the time i+frac units after the epoch.
+func epochTime(i int64, frac float64, unit time.Duration) time.Time {
+	perSecond := int64(time.Second / unit)
+	sec, rem := i/perSecond, i%perSecond
+	// the fraction is rounded to the nanosecond
+	fracNsec := frac * float64(unit)
+	if fracNsec < 0 {
+		fracNsec -= 0.5
+	} else {
+		fracNsec += 0.5
+	}
+	return time.Unix(sec, rem*int64(unit)+int64(fracNsec))
+}
+
+func init() {
+	processors.Add("timestamp", func() telegraf.Processor {
+		return &Timestamp{}
+	})
+}
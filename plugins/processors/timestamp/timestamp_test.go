@@ -0,0 +1,123 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var collected = time.Date(2019, 1, 2, 3, 4, 5, 600000000, time.UTC)
+
+func newMetric(value interface{}) telegraf.Metric {
+	fields := map[string]interface{}{"value": 1}
+	if value != nil {
+		fields["timestamp"] = value
+	}
+	return testutil.MustMetric("cpu", map[string]string{}, fields, collected)
+}
+
+func apply(t *testing.T, p *Timestamp, value interface{}) time.Time {
+	require.NoError(t, p.Init())
+	out := p.Apply(newMetric(value))
+	require.Len(t, out, 1)
+	return out[0].Time()
+}
+
+func TestFieldEpoch(t *testing.T) {
+	seconds := time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC)
+	millis := seconds.Add(123 * time.Millisecond)
+	tests := []struct {
+		format   string
+		value    interface{}
+		expected time.Time
+	}{
+		{"unix", int64(1500000000), seconds},
+		{"unix", uint64(1500000000), seconds},
+		{"unix", "1500000000.123", millis},
+		{"unix", "-1.5", time.Unix(-2, 500000000)},
+		{"unix_ms", int64(1500000000123), millis},
+		{"unix_ms", "1500000000123", millis},
+		{"unix_us", int64(1500000000123000), millis},
+		{"unix_ns", int64(1500000000123000000), millis},
+		{"unix_ns", "1500000000123000000", millis},
+	}
+	for _, tt := range tests {
+		p := &Timestamp{Field: "timestamp", Format: tt.format}
+		tm := apply(t, p, tt.value)
+		require.True(t, tt.expected.Equal(tm), "%s %v: %v", tt.format, tt.value, tm)
+	}
+
+	// floats are precise to the microsecond
+	p := &Timestamp{Field: "timestamp"}
+	require.WithinDuration(t, millis, apply(t, p, 1500000000.123), time.Microsecond)
+}
+
+func TestFieldLayout(t *testing.T) {
+	p := &Timestamp{Field: "timestamp", Format: time.RFC3339}
+	tm := apply(t, p, "2018-06-01T12:30:00+02:00")
+	require.True(t, time.Date(2018, 6, 1, 10, 30, 0, 0, time.UTC).Equal(tm))
+}
+
+func TestFieldLayoutTimezone(t *testing.T) {
+	p := &Timestamp{
+		Field:    "timestamp",
+		Format:   "2006-01-02 15:04:05",
+		Timezone: "America/New_York",
+	}
+	tm := apply(t, p, "2018-06-01 12:30:00")
+	require.True(t, time.Date(2018, 6, 1, 16, 30, 0, 0, time.UTC).Equal(tm))
+
+	// UTC by default
+	p.Timezone = ""
+	tm = apply(t, p, "2018-06-01 12:30:00")
+	require.True(t, time.Date(2018, 6, 1, 12, 30, 0, 0, time.UTC).Equal(tm))
+}
+
+func TestFieldParseFailureKeepsTime(t *testing.T) {
+	for _, value := range []interface{}{"yesterday", true, nil} {
+		p := &Timestamp{Field: "timestamp"}
+		require.True(t, collected.Equal(apply(t, p, value)))
+	}
+
+	p := &Timestamp{Field: "timestamp", Format: time.RFC3339}
+	require.True(t, collected.Equal(apply(t, p, int64(1500000000))))
+}
+
+func TestRoundAndTruncate(t *testing.T) {
+	p := &Timestamp{Truncate: internal.Duration{Duration: time.Second}}
+	require.True(t, time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC).Equal(apply(t, p, nil)))
+
+	p = &Timestamp{Round: internal.Duration{Duration: time.Second}}
+	require.True(t, time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC).Equal(apply(t, p, nil)))
+
+	p = &Timestamp{Truncate: internal.Duration{Duration: time.Hour}}
+	require.True(t, time.Date(2019, 1, 2, 3, 0, 0, 0, time.UTC).Equal(apply(t, p, nil)))
+}
+
+func TestOffset(t *testing.T) {
+	p := &Timestamp{Offset: internal.Duration{Duration: -time.Hour}}
+	require.True(t, collected.Add(-time.Hour).Equal(apply(t, p, nil)))
+
+	// the offset is applied after the other changes
+	p = &Timestamp{
+		Field:    "timestamp",
+		Truncate: internal.Duration{Duration: time.Minute},
+		Offset:   internal.Duration{Duration: 30 * time.Second},
+	}
+	tm := apply(t, p, int64(1500000015))
+	require.True(t, time.Unix(1500000030, 0).Equal(tm))
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, p := range []*Timestamp{
+		{Timezone: "Nowhere/Special"},
+		{Round: internal.Duration{Duration: time.Second}, Truncate: internal.Duration{Duration: time.Second}},
+		{Round: internal.Duration{Duration: -time.Second}},
+	} {
+		require.Error(t, p.Init())
+	}
+}
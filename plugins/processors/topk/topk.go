@@ -322,6 +322,11 @@ func (t *TopK) push() []telegraf.Metric {
 		if err != nil {
 			continue
 		}
+		for _, field := range m.FieldList() {
+			for k, v := range field.Metadata {
+				copy.SetFieldMetadata(field.Key, k, v)
+			}
+		}
 		result = append(result, copy)
 	}
 
@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Conversions of the field values between units, of the same dimension.
+  ## The units are:
+  ##   bytes: "B", "KB", "MB", "GB", "TB", "KiB", "MiB", "GiB", "TiB"
+  ##   time: "ns", "us", "ms", "s", "min", "h"
+  ##   temperature: "C", "F", "K"
+  [[processors.unit.conversion]]
+    field = "used"
+    from = "B"
+    to = "GiB"
+    ## Rename the field once converted, it keeps its name if empty.
+    # rename = "used_gib"
+
+  ## Custom linear conversions, the value becomes value * factor + offset.
+  # [[processors.unit.conversion]]
+  #   field = "speed"
+  #   factor = 3.6
+  #   offset = 0.0
+  #   rename = "speed_kmh"
+`
+
+// unit is a linear unit, converted to the base unit of its dimension as
+// value * factor + offset.
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+var units = map[string]unit{
+	"B":   {"bytes", 1, 0},
+	"KB":  {"bytes", 1e3, 0},
+	"MB":  {"bytes", 1e6, 0},
+	"GB":  {"bytes", 1e9, 0},
+	"TB":  {"bytes", 1e12, 0},
+	"KiB": {"bytes", 1 << 10, 0},
+	"MiB": {"bytes", 1 << 20, 0},
+	"GiB": {"bytes", 1 << 30, 0},
+	"TiB": {"bytes", 1 << 40, 0},
+
+	"ns":  {"time", 1e-9, 0},
+	"us":  {"time", 1e-6, 0},
+	"ms":  {"time", 1e-3, 0},
+	"s":   {"time", 1, 0},
+	"min": {"time", 60, 0},
+	"h":   {"time", 3600, 0},
+
+	"C": {"temperature", 1, 0},
+	"F": {"temperature", 5.0 / 9, -32 * 5.0 / 9},
+	"K": {"temperature", 1, -273.15},
+}
+
+type Conversion struct {
+	Field  string   `toml:"field"`
+	From   string   `toml:"from"`
+	To     string   `toml:"to"`
+	Factor *float64 `toml:"factor"`
+	Offset float64  `toml:"offset"`
+	Rename string   `toml:"rename"`
+
+	factor float64
+	offset float64
+}
+
+type Unit struct {
+	Conversions []*Conversion `toml:"conversion"`
+}
+
+func (u *Unit) SampleConfig() string {
+	return sampleConfig
+}
+
+func (u *Unit) Description() string {
+	return "Convert the field values between units."
+}
+
+func (u *Unit) Init() error {
+	for _, c := range u.Conversions {
+		if err := c.init(); err != nil {
+			return fmt.Errorf("conversion of field %q: %v", c.Field, err)
+		}
+	}
+	return nil
+}
+
+// init reduces the conversion to a factor and an offset.
+func (c *Conversion) init() error {
+	if c.Field == "" {
+		return fmt.Errorf("no field")
+	}
+
+	if c.Factor != nil {
+		if c.From != "" || c.To != "" {
+			return fmt.Errorf("only one of the units or a factor can be set")
+		}
+		c.factor, c.offset = *c.Factor, c.Offset
+		return nil
+	}
+
+	from, ok := units[c.From]
+	if !ok {
+		return fmt.Errorf("unknown unit %q", c.From)
+	}
+	to, ok := units[c.To]
+	if !ok {
+		return fmt.Errorf("unknown unit %q", c.To)
+	}
+	if from.dimension != to.dimension {
+		return fmt.Errorf("can not convert %s to %s", from.dimension, to.dimension)
+	}
+
+	// base = value * from.factor + from.offset
+	// result = (base - to.offset) / to.factor
+	c.factor = from.factor / to.factor
+	c.offset = (from.offset - to.offset) / to.factor
+	return nil
+}
+
+func (u *Unit) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		for _, c := range u.Conversions {
+			v, ok := m.GetField(c.Field)
+			if !ok {
+				continue
+			}
+			fv, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+
+			name := c.Field
+			metadata := m.FieldMetadata(c.Field)
+			if c.Rename != "" {
+				m.RemoveField(c.Field)
+				name = c.Rename
+			}
+			m.AddField(name, fv*c.factor+c.offset)
+			if c.Rename != "" {
+				for k, v := range metadata {
+					m.SetFieldMetadata(name, k, v)
+				}
+			}
+		}
+	}
+	return in
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.Add("unit", func() telegraf.Processor {
+		return &Unit{}
+	})
+}
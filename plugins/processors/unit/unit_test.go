@@ -0,0 +1,163 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("mem", map[string]string{}, fields, time.Unix(0, 0))
+}
+
+func TestBytes(t *testing.T) {
+	u := &Unit{
+		Conversions: []*Conversion{
+			{Field: "binary", From: "B", To: "GiB"},
+			{Field: "decimal", From: "B", To: "GB"},
+			{Field: "back", From: "MiB", To: "B", Rename: "back_bytes"},
+		},
+	}
+	require.NoError(t, u.Init())
+
+	m := newMetric(map[string]interface{}{
+		"binary":  int64(3 << 30),
+		"decimal": uint64(3e9),
+		"back":    1.5,
+	})
+	u.Apply(m)
+	require.Equal(t, map[string]interface{}{
+		"binary":     3.0,
+		"decimal":    3.0,
+		"back_bytes": 1.5 * (1 << 20),
+	}, m.Fields())
+}
+
+func TestTime(t *testing.T) {
+	u := &Unit{
+		Conversions: []*Conversion{
+			{Field: "latency", From: "ms", To: "s"},
+			{Field: "uptime", From: "s", To: "h"},
+		},
+	}
+	require.NoError(t, u.Init())
+
+	m := newMetric(map[string]interface{}{
+		"latency": int64(250),
+		"uptime":  int64(7200),
+	})
+	u.Apply(m)
+	require.Equal(t, map[string]interface{}{
+		"latency": 0.25,
+		"uptime":  2.0,
+	}, m.Fields())
+}
+
+func TestTemperature(t *testing.T) {
+	u := &Unit{
+		Conversions: []*Conversion{
+			{Field: "celsius", From: "C", To: "F"},
+			{Field: "fahrenheit", From: "F", To: "C"},
+			{Field: "kelvin", From: "K", To: "C"},
+		},
+	}
+	require.NoError(t, u.Init())
+
+	m := newMetric(map[string]interface{}{
+		"celsius":    100.0,
+		"fahrenheit": -40.0,
+		"kelvin":     int64(0),
+	})
+	u.Apply(m)
+	fields := m.Fields()
+	require.InDelta(t, 212.0, fields["celsius"], 1e-9)
+	require.InDelta(t, -40.0, fields["fahrenheit"], 1e-9)
+	require.InDelta(t, -273.15, fields["kelvin"], 1e-9)
+}
+
+func TestCustom(t *testing.T) {
+	var u Unit
+	err := toml.Unmarshal([]byte(`
+[[conversion]]
+  field = "speed"
+  factor = 3.6
+  rename = "speed_kmh"
+
+[[conversion]]
+  field = "level"
+  factor = 0.5
+  offset = 10.0
+`), &u)
+	require.NoError(t, err)
+	require.NoError(t, u.Init())
+
+	m := newMetric(map[string]interface{}{
+		"speed": int64(10),
+		"level": 4.0,
+	})
+	u.Apply(m)
+	fields := m.Fields()
+	require.Len(t, fields, 2)
+	require.InDelta(t, 36.0, fields["speed_kmh"], 1e-9)
+	require.InDelta(t, 12.0, fields["level"], 1e-9)
+}
+
+func TestUntouched(t *testing.T) {
+	u := &Unit{
+		Conversions: []*Conversion{
+			{Field: "used", From: "B", To: "KB", Rename: "used_kb"},
+			{Field: "state", From: "B", To: "KB"},
+		},
+	}
+	require.NoError(t, u.Init())
+
+	m := newMetric(map[string]interface{}{
+		"free":  int64(1000),
+		"state": "ok",
+	})
+	u.Apply(m)
+	require.Equal(t, map[string]interface{}{
+		"free":  int64(1000),
+		"state": "ok",
+	}, m.Fields())
+}
+
+func TestInitErrors(t *testing.T) {
+	factor := 2.0
+	tests := []struct {
+		name       string
+		conversion *Conversion
+	}{
+		{
+			name:       "no field",
+			conversion: &Conversion{From: "B", To: "KB"},
+		},
+		{
+			name:       "unknown unit",
+			conversion: &Conversion{Field: "a", From: "B", To: "KiBs"},
+		},
+		{
+			name:       "no unit",
+			conversion: &Conversion{Field: "a"},
+		},
+		{
+			name:       "different dimensions",
+			conversion: &Conversion{Field: "a", From: "B", To: "s"},
+		},
+		{
+			name:       "units and factor",
+			conversion: &Conversion{Field: "a", From: "B", To: "KB", Factor: &factor},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &Unit{Conversions: []*Conversion{tt.conversion}}
+			require.Error(t, u.Init())
+		})
+	}
+}
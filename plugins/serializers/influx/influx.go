@@ -27,6 +27,17 @@ const (
 	UintSupport FieldTypeSupport = 1 << iota
 )
 
+// UintConversion is how unsigned integers larger than the maximum signed
+// integer are written when unsigned integers are not supported.
+type UintConversion int
+
+const (
+	// UintClamp writes the maximum signed integer.
+	UintClamp UintConversion = iota
+	// UintWrap writes the two's complement value, so 2^64-1 becomes -1.
+	UintWrap
+)
+
 var (
 	NeedMoreSpace = "need more space"
 	InvalidName   = "invalid name"
@@ -61,11 +72,13 @@ type Serializer struct {
 	bytesWritten     int
 	fieldSortOrder   FieldSortOrder
 	fieldTypeSupport FieldTypeSupport
+	uintConversion   UintConversion
 
 	buf    bytes.Buffer
 	header []byte
 	footer []byte
 	pair   []byte
+	fields []*telegraf.Field
 }
 
 func NewSerializer() *Serializer {
@@ -91,6 +104,10 @@ func (s *Serializer) SetFieldTypeSupport(typeSupport FieldTypeSupport) {
 	s.fieldTypeSupport = typeSupport
 }
 
+func (s *Serializer) SetUintConversion(conversion UintConversion) {
+	s.uintConversion = conversion
+}
+
 // Serialize writes the telegraf.Metric to a byte slice.  May produce multiple
 // lines of output if longer than maximum line length.  Lines are terminated
 // with a newline (LF) char.
@@ -204,15 +221,19 @@ func (s *Serializer) writeMetric(w io.Writer, m telegraf.Metric) error {
 
 	s.buildFooter(m)
 
+	fields := m.FieldList()
 	if s.fieldSortOrder == SortFields {
-		sort.Slice(m.FieldList(), func(i, j int) bool {
-			return m.FieldList()[i].Key < m.FieldList()[j].Key
+		// the field list of the metric must not be modified
+		s.fields = append(s.fields[:0], fields...)
+		fields = s.fields
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Key < fields[j].Key
 		})
 	}
 
 	pairsLen := 0
 	firstField := true
-	for _, field := range m.FieldList() {
+	for _, field := range fields {
 		err = s.buildFieldPair(field.Key, field.Value)
 		if err != nil {
 			log.Printf(
@@ -290,7 +311,7 @@ func (s *Serializer) appendFieldValue(buf []byte, value interface{}) ([]byte, er
 		if s.fieldTypeSupport&UintSupport != 0 {
 			return appendUintField(buf, v), nil
 		} else {
-			if v <= uint64(MaxInt64) {
+			if v <= uint64(MaxInt64) || s.uintConversion == UintWrap {
 				return appendIntField(buf, int64(v)), nil
 			} else {
 				return appendIntField(buf, int64(MaxInt64)), nil
@@ -18,12 +18,13 @@ func MustMetric(v telegraf.Metric, err error) telegraf.Metric {
 }
 
 var tests = []struct {
-	name        string
-	maxBytes    int
-	typeSupport FieldTypeSupport
-	input       telegraf.Metric
-	output      []byte
-	errReason   string
+	name           string
+	maxBytes       int
+	typeSupport    FieldTypeSupport
+	uintConversion UintConversion
+	input          telegraf.Metric
+	output         []byte
+	errReason      string
 }{
 	{
 		name: "minimal",
@@ -201,6 +202,37 @@ var tests = []struct {
 		),
 		output: []byte("cpu value=9223372036854775807i 0\n"),
 	},
+	{
+		name: "uint field no uint support wrap",
+		input: MustMetric(
+			metric.New(
+				"cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"value": uint64(18446744073709551615),
+				},
+				time.Unix(0, 0),
+			),
+		),
+		uintConversion: UintWrap,
+		output:         []byte("cpu value=-1i 0\n"),
+	},
+	{
+		name: "uint field uint support wrap",
+		input: MustMetric(
+			metric.New(
+				"cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"value": uint64(18446744073709551615),
+				},
+				time.Unix(0, 0),
+			),
+		),
+		typeSupport:    UintSupport,
+		uintConversion: UintWrap,
+		output:         []byte("cpu value=18446744073709551615u 0\n"),
+	},
 	{
 		name: "bool field",
 		input: MustMetric(
@@ -426,6 +458,7 @@ func TestSerializer(t *testing.T) {
 			serializer.SetMaxLineBytes(tt.maxBytes)
 			serializer.SetFieldSortOrder(SortFields)
 			serializer.SetFieldTypeSupport(tt.typeSupport)
+			serializer.SetUintConversion(tt.uintConversion)
 			output, err := serializer.Serialize(tt.input)
 			if tt.errReason != "" {
 				require.Error(t, err)
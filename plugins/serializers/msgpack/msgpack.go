@@ -0,0 +1,183 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// serializer encodes each metric as a MessagePack map with the keys name,
+// time, tags and fields, in this order.  The tags and fields are sorted by
+// key, so a metric is always encoded to the same bytes.
+//
+// Integer fields are encoded with the signed formats and unsigned fields with
+// the unsigned formats, except for the positive fixint, so the field types
+// are kept when parsed.
+type serializer struct{}
+
+func NewSerializer() *serializer {
+	return &serializer{}
+}
+
+func (s *serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return appendMetric(nil, metric)
+}
+
+// SerializeBatch encodes the metrics one after another, as a stream of maps.
+func (s *serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, metric := range metrics {
+		var err error
+		buf, err = appendMetric(buf, metric)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func appendMetric(buf []byte, metric telegraf.Metric) ([]byte, error) {
+	buf = appendMapHeader(buf, 4)
+	buf = appendString(buf, "name")
+	buf = appendString(buf, metric.Name())
+	buf = appendString(buf, "time")
+	buf = appendTime(buf, metric.Time())
+
+	buf = appendString(buf, "tags")
+	tags := metric.TagList()
+	buf = appendMapHeader(buf, len(tags))
+	for _, tag := range tags {
+		buf = appendString(buf, tag.Key)
+		buf = appendString(buf, tag.Value)
+	}
+
+	buf = appendString(buf, "fields")
+	fields := metric.FieldList()
+	sorted := make([]*telegraf.Field, len(fields))
+	copy(sorted, fields)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+	buf = appendMapHeader(buf, len(sorted))
+	for _, field := range sorted {
+		buf = appendString(buf, field.Key)
+		switch v := field.Value.(type) {
+		case int64:
+			buf = appendInt(buf, v)
+		case uint64:
+			buf = appendUint(buf, v)
+		case float64:
+			buf = append(buf, 0xcb)
+			buf = appendUint64(buf, math.Float64bits(v))
+		case string:
+			buf = appendString(buf, v)
+		case bool:
+			if v {
+				buf = append(buf, 0xc3)
+			} else {
+				buf = append(buf, 0xc2)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported type %T of field %q", field.Value, field.Key)
+		}
+	}
+	return buf, nil
+}
+
+func appendMapHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(buf, 0xde), uint16(n))
+	default:
+		return appendUint32(append(buf, 0xdf), uint32(n))
+	}
+}
+
+func appendString(buf []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = appendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = appendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+// appendInt appends the integer with the smallest signed format.
+func appendInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= math.MaxInt8:
+		return append(buf, byte(v))
+	case v < 0 && v >= -32:
+		return append(buf, byte(v))
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return append(buf, 0xd0, byte(v))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return appendUint16(append(buf, 0xd1), uint16(v))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return appendUint32(append(buf, 0xd2), uint32(v))
+	default:
+		return appendUint64(append(buf, 0xd3), uint64(v))
+	}
+}
+
+// appendUint appends the integer with the smallest unsigned format.
+func appendUint(buf []byte, v uint64) []byte {
+	switch {
+	case v <= math.MaxUint8:
+		return append(buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return appendUint16(append(buf, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return appendUint32(append(buf, 0xce), uint32(v))
+	default:
+		return appendUint64(append(buf, 0xcf), v)
+	}
+}
+
+// appendTime appends the time with the smallest format of the timestamp
+// extension, of type -1.
+func appendTime(buf []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint32(t.Nanosecond())
+	switch {
+	case sec >= 0 && sec <= math.MaxUint32 && nsec == 0:
+		buf = append(buf, 0xd6, 0xff)
+		return appendUint32(buf, uint32(sec))
+	case sec >= 0 && sec < 1<<34:
+		buf = append(buf, 0xd7, 0xff)
+		return appendUint64(buf, uint64(nsec)<<34|uint64(sec))
+	default:
+		buf = append(buf, 0xc7, 12, 0xff)
+		buf = appendUint32(buf, nsec)
+		return appendUint64(buf, uint64(sec))
+	}
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,127 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+func MustMetric(v telegraf.Metric, err error) telegraf.Metric {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestSerialize(t *testing.T) {
+	m := MustMetric(metric.New(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{
+			"u": uint64(200),
+			"s": "x",
+			"i": int64(-100),
+			"f": 1.5,
+			"b": true,
+		},
+		time.Unix(1500000000, 0),
+	))
+
+	s := NewSerializer()
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	expected := []byte{
+		0x84,
+		0xa4, 'n', 'a', 'm', 'e', 0xa3, 'c', 'p', 'u',
+		0xa4, 't', 'i', 'm', 'e', 0xd6, 0xff, 0x59, 0x68, 0x2f, 0x00,
+		0xa4, 't', 'a', 'g', 's', 0x81, 0xa4, 'h', 'o', 's', 't', 0xa1, 'a',
+		0xa6, 'f', 'i', 'e', 'l', 'd', 's', 0x85,
+		0xa1, 'b', 0xc3,
+		0xa1, 'f', 0xcb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xa1, 'i', 0xd0, 0x9c,
+		0xa1, 's', 0xa1, 'x',
+		0xa1, 'u', 0xcc, 0xc8,
+	}
+	require.Equal(t, expected, buf)
+}
+
+func TestSerializeTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected []byte
+	}{
+		{
+			name:     "seconds",
+			time:     time.Unix(1, 0),
+			expected: []byte{0xd6, 0xff, 0x00, 0x00, 0x00, 0x01},
+		},
+		{
+			name:     "nanoseconds",
+			time:     time.Unix(1, 1),
+			expected: []byte{0xd7, 0xff, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01},
+		},
+		{
+			name: "before epoch",
+			time: time.Unix(-1, 2),
+			expected: []byte{0xc7, 0x0c, 0xff, 0x00, 0x00, 0x00, 0x02,
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := appendTime(nil, tt.time)
+			require.Equal(t, tt.expected, buf)
+		})
+	}
+}
+
+func TestSerializeIntegers(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected []byte
+	}{
+		{int64(1), []byte{0x01}},
+		{int64(-1), []byte{0xff}},
+		{int64(-33), []byte{0xd0, 0xdf}},
+		{int64(200), []byte{0xd1, 0x00, 0xc8}},
+		{int64(-70000), []byte{0xd2, 0xff, 0xfe, 0xee, 0x90}},
+		{int64(1 << 40), []byte{0xd3, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{uint64(1), []byte{0xcc, 0x01}},
+		{uint64(300), []byte{0xcd, 0x01, 0x2c}},
+		{uint64(70000), []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{uint64(1 << 63), []byte{0xcf, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+	}
+	for _, tt := range tests {
+		var buf []byte
+		switch v := tt.value.(type) {
+		case int64:
+			buf = appendInt(nil, v)
+		case uint64:
+			buf = appendUint(nil, v)
+		}
+		require.Equal(t, tt.expected, buf, "%T %v", tt.value, tt.value)
+	}
+}
+
+func TestSerializeBatch(t *testing.T) {
+	m1 := MustMetric(metric.New("a", map[string]string{},
+		map[string]interface{}{"value": int64(1)}, time.Unix(0, 0)))
+	m2 := MustMetric(metric.New("b", map[string]string{},
+		map[string]interface{}{"value": int64(2)}, time.Unix(0, 0)))
+
+	s := NewSerializer()
+	buf, err := s.SerializeBatch([]telegraf.Metric{m1, m2})
+	require.NoError(t, err)
+
+	b1, err := s.Serialize(m1)
+	require.NoError(t, err)
+	b2, err := s.Serialize(m2)
+	require.NoError(t, err)
+	require.Equal(t, append(b1, b2...), buf)
+}
@@ -0,0 +1,282 @@
+package parquet
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	defaultRowGroupRows = 100000
+	defaultRowGroupSize = 128 * 1024 * 1024
+)
+
+// The types of the values of the columns.
+type valueType int
+
+const (
+	typeBool valueType = iota
+	typeInt
+	typeUint
+	typeFloat
+	typeString
+)
+
+// The kinds of columns.
+const (
+	columnMeasurement = iota
+	columnTime
+	columnTag
+	columnField
+)
+
+// Serializer writes each batch of metrics as a Parquet file, in row groups
+// of at most RowGroupRows metrics or of about RowGroupSize bytes.  The
+// metrics can only be written as whole files, not streamed one after the
+// other.
+type Serializer struct {
+	RowGroupRows int
+	RowGroupSize int64
+	Compression  string
+
+	codec int32
+}
+
+func (s *Serializer) Init() error {
+	if s.RowGroupRows == 0 {
+		s.RowGroupRows = defaultRowGroupRows
+	}
+	if s.RowGroupRows < 0 {
+		return fmt.Errorf("invalid parquet_row_group_rows %d", s.RowGroupRows)
+	}
+	if s.RowGroupSize == 0 {
+		s.RowGroupSize = defaultRowGroupSize
+	}
+	if s.RowGroupSize < 0 {
+		return fmt.Errorf("invalid parquet_row_group_size %d", s.RowGroupSize)
+	}
+
+	switch s.Compression {
+	case "", "snappy":
+		s.codec = codecSnappy
+	case "gzip":
+		s.codec = codecGzip
+	case "none":
+		s.codec = codecUncompressed
+	default:
+		return fmt.Errorf("invalid parquet_compression %q, must be snappy, gzip or none",
+			s.Compression)
+	}
+	return nil
+}
+
+// BatchOnly marks the serializer as writing whole files only.
+func (s *Serializer) BatchOnly() {}
+
+// Serialize writes a Parquet file of the single metric.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// SerializeBatch writes a Parquet file of the metrics, with the columns of
+// all the tags and fields of the batch.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	w := &fileWriter{
+		columns: newSchema(metrics),
+		codec:   s.codec,
+	}
+	w.begin()
+
+	start := 0
+	var size int64
+	for i, metric := range metrics {
+		size += metricSize(metric)
+		if i+1-start >= s.RowGroupRows || size >= s.RowGroupSize {
+			if err := w.writeRowGroup(metrics[start : i+1]); err != nil {
+				return nil, err
+			}
+			start = i + 1
+			size = 0
+		}
+	}
+	if start < len(metrics) {
+		if err := w.writeRowGroup(metrics[start:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.end(), nil
+}
+
+// column is a column of the file, holding the metric name, the timestamp, a
+// tag or a field.
+type column struct {
+	name string
+	kind int
+	key  string
+	typ  valueType
+}
+
+// newSchema returns the columns of the metrics: the measurement and the time,
+// then the tags and the fields sorted by key.  The tags and fields named as a
+// previous column are renamed with the _tag or _field suffix.  The type of a
+// field is the type of its values when they all have the same, float when
+// they are all numbers, and string otherwise.
+func newSchema(metrics []telegraf.Metric) []*column {
+	tags := make(map[string]bool)
+	fields := make(map[string]valueType)
+	for _, metric := range metrics {
+		for _, tag := range metric.TagList() {
+			tags[tag.Key] = true
+		}
+		for _, field := range metric.FieldList() {
+			typ := typeOf(field.Value)
+			if prev, ok := fields[field.Key]; ok {
+				typ = unify(prev, typ)
+			}
+			fields[field.Key] = typ
+		}
+	}
+
+	columns := []*column{
+		{name: "measurement", kind: columnMeasurement, typ: typeString},
+		{name: "time", kind: columnTime, typ: typeInt},
+	}
+	names := map[string]bool{"measurement": true, "time": true}
+	add := func(c *column, suffix string) {
+		if names[c.name] {
+			c.name += suffix
+		}
+		for names[c.name] {
+			c.name += "_"
+		}
+		names[c.name] = true
+		columns = append(columns, c)
+	}
+
+	for _, key := range sortedKeys(tags) {
+		add(&column{name: key, kind: columnTag, key: key, typ: typeString}, "_tag")
+	}
+	fieldKeys := make([]string, 0, len(fields))
+	for key := range fields {
+		fieldKeys = append(fieldKeys, key)
+	}
+	sort.Strings(fieldKeys)
+	for _, key := range fieldKeys {
+		add(&column{name: key, kind: columnField, key: key, typ: fields[key]}, "_field")
+	}
+	return columns
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func typeOf(v interface{}) valueType {
+	switch v.(type) {
+	case bool:
+		return typeBool
+	case int64:
+		return typeInt
+	case uint64:
+		return typeUint
+	case float64:
+		return typeFloat
+	default:
+		return typeString
+	}
+}
+
+func unify(a, b valueType) valueType {
+	switch {
+	case a == b:
+		return a
+	case isNumber(a) && isNumber(b):
+		return typeFloat
+	default:
+		return typeString
+	}
+}
+
+func isNumber(typ valueType) bool {
+	return typ == typeInt || typ == typeUint || typ == typeFloat
+}
+
+// optional reports if the values of the column can be null.
+func (c *column) optional() bool {
+	return c.kind == columnTag || c.kind == columnField
+}
+
+// value returns the value of the column for the metric, converted to the type
+// of the column, and false when the metric has no value.
+func (c *column) value(metric telegraf.Metric) (interface{}, bool) {
+	switch c.kind {
+	case columnMeasurement:
+		return metric.Name(), true
+	case columnTime:
+		return metric.Time().UnixNano(), true
+	case columnTag:
+		v, ok := metric.GetTag(c.key)
+		return v, ok
+	}
+
+	v, ok := metric.GetField(c.key)
+	if !ok {
+		return nil, false
+	}
+	switch c.typ {
+	case typeFloat:
+		switch v := v.(type) {
+		case int64:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		}
+	case typeString:
+		switch v := v.(type) {
+		case bool:
+			return strconv.FormatBool(v), true
+		case int64:
+			return strconv.FormatInt(v, 10), true
+		case uint64:
+			return strconv.FormatUint(v, 10), true
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), true
+		case string:
+			return v, true
+		default:
+			return fmt.Sprint(v), true
+		}
+	}
+	return v, true
+}
+
+// metricSize returns about the size of the values of the metric in the file.
+func metricSize(metric telegraf.Metric) int64 {
+	size := int64(4+len(metric.Name())) + 8
+	for _, tag := range metric.TagList() {
+		size += int64(4 + len(tag.Value))
+	}
+	for _, field := range metric.FieldList() {
+		size += valueSize(field.Value)
+	}
+	return size
+}
+
+func valueSize(v interface{}) int64 {
+	switch v := v.(type) {
+	case bool:
+		return 1
+	case string:
+		return int64(4 + len(v))
+	default:
+		return 8
+	}
+}
@@ -0,0 +1,526 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftReader reads the structs of the thrift compact protocol as maps of
+// their field IDs to their values: int64, bool, []byte, []interface{} or
+// nested structs.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+type tStruct map[int16]interface{}
+
+func (r *thriftReader) byte() byte {
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		panic("invalid varint")
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftByte:
+		return int64(int8(r.byte()))
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.varint())
+		r.pos += n
+		return r.buf[r.pos-n : r.pos]
+	case thriftList:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			if header&0x0f == thriftTrue {
+				list[i] = r.byte() == thriftTrue
+			} else {
+				list[i] = r.value(header & 0x0f)
+			}
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", typ))
+}
+
+func (r *thriftReader) readStruct() tStruct {
+	s := make(tStruct)
+	var id int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return s
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		s[id] = r.value(header & 0x0f)
+	}
+}
+
+// schemaColumn is a column of the schema of a file read.
+type schemaColumn struct {
+	name       string
+	typ        int64
+	repetition int64
+	logical    tStruct
+}
+
+// parquetFile is a file read: its schema, the number of rows of its row
+// groups and its rows with the values of their columns.
+type parquetFile struct {
+	schema    []schemaColumn
+	rowGroups []int
+	rows      []map[string]interface{}
+}
+
+// readFile reads the Parquet files written, with the plain encoding and the
+// RLE runs of the definition levels.
+func readFile(t *testing.T, buf []byte) *parquetFile {
+	require.True(t, len(buf) >= 12)
+	require.Equal(t, magic, string(buf[:4]))
+	require.Equal(t, magic, string(buf[len(buf)-4:]))
+	length := int(binary.LittleEndian.Uint32(buf[len(buf)-8:]))
+	r := &thriftReader{buf: buf[:len(buf)-8], pos: len(buf) - 8 - length}
+	meta := r.readStruct()
+	require.Equal(t, len(buf)-8, r.pos)
+	require.Equal(t, int64(1), meta[1])
+
+	f := &parquetFile{}
+	schema := meta[2].([]interface{})
+	root := schema[0].(tStruct)
+	require.Equal(t, int64(len(schema)-1), root[5])
+	for _, e := range schema[1:] {
+		e := e.(tStruct)
+		c := schemaColumn{
+			name:       string(e[4].([]byte)),
+			typ:        e[1].(int64),
+			repetition: e[3].(int64),
+		}
+		if logical, ok := e[10]; ok {
+			c.logical = logical.(tStruct)
+		}
+		f.schema = append(f.schema, c)
+	}
+
+	groups, _ := meta[4].([]interface{})
+	for _, group := range groups {
+		group := group.(tStruct)
+		numRows := int(group[3].(int64))
+		start := len(f.rows)
+		for i := 0; i < numRows; i++ {
+			f.rows = append(f.rows, make(map[string]interface{}))
+		}
+
+		chunks := group[1].([]interface{})
+		require.Len(t, chunks, len(f.schema))
+		for i, chunk := range chunks {
+			c := f.schema[i]
+			cm := chunk.(tStruct)[3].(tStruct)
+			require.Equal(t, c.typ, cm[1])
+			require.Equal(t, c.name, string(cm[3].([]interface{})[0].([]byte)))
+			require.Equal(t, int64(numRows), cm[5])
+
+			p := &thriftReader{buf: buf, pos: int(cm[9].(int64))}
+			row := start
+			for row < start+numRows {
+				header := p.readStruct()
+				require.Equal(t, int64(pageTypeData), header[1])
+				data := buf[p.pos : p.pos+int(header[3].(int64))]
+				p.pos += len(data)
+				page := decompress(t, cm[4].(int64), data)
+				require.Len(t, page, int(header[2].(int64)))
+
+				dph := header[5].(tStruct)
+				n := int(dph[1].(int64))
+				require.Equal(t, int64(encodingPlain), dph[2])
+				levels := make([]bool, n)
+				for j := range levels {
+					levels[j] = true
+				}
+				if c.repetition == repetitionOptional {
+					size := int(binary.LittleEndian.Uint32(page))
+					levels = decodeLevels(t, page[4:4+size], n)
+					page = page[4+size:]
+				}
+				values := decodeValues(t, page, c.typ, levels)
+				for j, v := range values {
+					if v != nil {
+						f.rows[row+j][c.name] = v
+					}
+				}
+				row += n
+			}
+			require.Equal(t, start+numRows, row)
+			require.Equal(t, int64(p.pos)-cm[9].(int64), cm[7])
+		}
+		f.rowGroups = append(f.rowGroups, numRows)
+	}
+	require.Equal(t, int64(len(f.rows)), meta[3])
+	return f
+}
+
+func decompress(t *testing.T, codec int64, data []byte) []byte {
+	switch codec {
+	case codecSnappy:
+		page, err := snappy.Decode(nil, data)
+		require.NoError(t, err)
+		return page
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		page, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		return page
+	}
+	return data
+}
+
+func decodeLevels(t *testing.T, buf []byte, n int) []bool {
+	var levels []bool
+	for len(buf) > 0 {
+		header, size := binary.Uvarint(buf)
+		require.True(t, size > 0)
+		require.Equal(t, uint64(0), header&1, "bit-packed run")
+		for i := uint64(0); i < header>>1; i++ {
+			levels = append(levels, buf[size] == 1)
+		}
+		buf = buf[size+1:]
+	}
+	require.Len(t, levels, n)
+	return levels
+}
+
+func decodeValues(t *testing.T, buf []byte, typ int64, levels []bool) []interface{} {
+	values := make([]interface{}, len(levels))
+	bit := 0
+	for i, defined := range levels {
+		if !defined {
+			continue
+		}
+		switch typ {
+		case physicalBoolean:
+			values[i] = buf[bit/8]&(1<<uint(bit%8)) != 0
+			bit++
+		case physicalInt64:
+			values[i] = int64(binary.LittleEndian.Uint64(buf))
+			buf = buf[8:]
+		case physicalDouble:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+			buf = buf[8:]
+		case physicalByteArray:
+			n := int(binary.LittleEndian.Uint32(buf))
+			values[i] = string(buf[4 : 4+n])
+			buf = buf[4+n:]
+		}
+	}
+	if typ == physicalBoolean {
+		buf = buf[(bit+7)/8:]
+	}
+	require.Empty(t, buf)
+	return values
+}
+
+func newSerializer(t *testing.T, s *Serializer) *Serializer {
+	require.NoError(t, s.Init())
+	return s
+}
+
+func TestSerializeBatch(t *testing.T) {
+	s := newSerializer(t, &Serializer{})
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5, "count": int64(3), "online": true},
+			time.Unix(1500000000, 250000000)),
+		testutil.MustMetric("mem",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"free": uint64(1 << 63), "status": "ok", "online": false},
+			time.Unix(1500000001, 0)),
+	}
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	f := readFile(t, buf)
+
+	var names []string
+	for _, c := range f.schema {
+		names = append(names, c.name)
+	}
+	require.Equal(t, []string{"measurement", "time", "cpu", "host",
+		"count", "free", "online", "status", "usage_idle"}, names)
+
+	types := map[string]int64{
+		"measurement": physicalByteArray,
+		"time":        physicalInt64,
+		"cpu":         physicalByteArray,
+		"host":        physicalByteArray,
+		"count":       physicalInt64,
+		"free":        physicalInt64,
+		"online":      physicalBoolean,
+		"status":      physicalByteArray,
+		"usage_idle":  physicalDouble,
+	}
+	for _, c := range f.schema {
+		require.Equal(t, types[c.name], c.typ, c.name)
+		if c.name == "measurement" || c.name == "time" {
+			require.Equal(t, int64(repetitionRequired), c.repetition, c.name)
+		} else {
+			require.Equal(t, int64(repetitionOptional), c.repetition, c.name)
+		}
+	}
+
+	// the time in nanoseconds, the unsigned integers and the strings
+	require.Equal(t, tStruct{8: tStruct{1: true, 2: tStruct{3: tStruct{}}}},
+		f.schema[1].logical)
+	require.Equal(t, tStruct{10: tStruct{1: int64(64), 2: false}}, f.schema[5].logical)
+	require.Equal(t, tStruct{1: tStruct{}}, f.schema[0].logical)
+	require.Nil(t, f.schema[4].logical)
+
+	require.Equal(t, []int{2}, f.rowGroups)
+	require.Equal(t, []map[string]interface{}{
+		{
+			"measurement": "cpu",
+			"time":        int64(1500000000250000000),
+			"cpu":         "cpu0",
+			"host":        "server01",
+			"count":       int64(3),
+			"online":      true,
+			"usage_idle":  91.5,
+		},
+		{
+			"measurement": "mem",
+			"time":        int64(1500000001000000000),
+			"host":        "server02",
+			"free":        int64(math.MinInt64),
+			"online":      false,
+			"status":      "ok",
+		},
+	}, f.rows)
+}
+
+func TestSerialize(t *testing.T) {
+	s := newSerializer(t, &Serializer{})
+
+	m := testutil.MustMetric("cpu", nil,
+		map[string]interface{}{"value": int64(1)}, time.Unix(0, 0))
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	f := readFile(t, buf)
+	require.Equal(t, []map[string]interface{}{
+		{"measurement": "cpu", "time": int64(0), "value": int64(1)},
+	}, f.rows)
+}
+
+func TestSerializeEmptyBatch(t *testing.T) {
+	s := newSerializer(t, &Serializer{})
+
+	buf, err := s.SerializeBatch(nil)
+	require.NoError(t, err)
+
+	f := readFile(t, buf)
+	require.Len(t, f.schema, 2)
+	require.Empty(t, f.rowGroups)
+	require.Empty(t, f.rows)
+}
+
+func TestSchemaUnion(t *testing.T) {
+	s := newSerializer(t, &Serializer{})
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("a",
+			map[string]string{"host": "server01", "time": "late"},
+			map[string]interface{}{"value": int64(1), "mixed": int64(2), "host": "a"},
+			time.Unix(0, 0)),
+		testutil.MustMetric("b",
+			map[string]string{"region": "eu"},
+			map[string]interface{}{"value": 2.5, "mixed": "two", "extra": true},
+			time.Unix(1, 0)),
+		testutil.MustMetric("c",
+			nil,
+			map[string]interface{}{"value": uint64(3), "mixed": false},
+			time.Unix(2, 0)),
+	}
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	f := readFile(t, buf)
+	columns := make(map[string]int64)
+	var names []string
+	for _, c := range f.schema {
+		names = append(names, c.name)
+		columns[c.name] = c.typ
+	}
+	require.Equal(t, []string{"measurement", "time", "host", "region", "time_tag",
+		"extra", "host_field", "mixed", "value"}, names)
+	require.Equal(t, int64(physicalDouble), columns["value"])
+	require.Equal(t, int64(physicalByteArray), columns["mixed"])
+
+	require.Equal(t, []map[string]interface{}{
+		{
+			"measurement": "a",
+			"time":        int64(0),
+			"host":        "server01",
+			"time_tag":    "late",
+			"value":       1.0,
+			"mixed":       "2",
+			"host_field":  "a",
+		},
+		{
+			"measurement": "b",
+			"time":        int64(1000000000),
+			"region":      "eu",
+			"value":       2.5,
+			"mixed":       "two",
+			"extra":       true,
+		},
+		{
+			"measurement": "c",
+			"time":        int64(2000000000),
+			"value":       3.0,
+			"mixed":       "false",
+		},
+	}, f.rows)
+}
+
+func newMetrics(n int, value string) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	for i := 0; i < n; i++ {
+		fields := map[string]interface{}{"value": int64(i)}
+		if i%2 == 0 {
+			fields["message"] = value
+		}
+		metrics = append(metrics, testutil.MustMetric("log",
+			map[string]string{"host": "server01"}, fields, time.Unix(int64(i), 0)))
+	}
+	return metrics
+}
+
+func requireRows(t *testing.T, f *parquetFile, n int, value string) {
+	require.Len(t, f.rows, n)
+	for i, row := range f.rows {
+		require.Equal(t, int64(i), row["value"])
+		require.Equal(t, int64(i)*1000000000, row["time"])
+		if i%2 == 0 {
+			require.Equal(t, value, row["message"])
+		} else {
+			require.NotContains(t, row, "message")
+		}
+	}
+}
+
+func TestRowGroupRows(t *testing.T) {
+	s := newSerializer(t, &Serializer{RowGroupRows: 3})
+
+	buf, err := s.SerializeBatch(newMetrics(10, "hello"))
+	require.NoError(t, err)
+
+	f := readFile(t, buf)
+	require.Equal(t, []int{3, 3, 3, 1}, f.rowGroups)
+	requireRows(t, f, 10, "hello")
+}
+
+func TestRowGroupSize(t *testing.T) {
+	s := newSerializer(t, &Serializer{RowGroupSize: 500})
+
+	// every other metric has a message of 100 bytes, the row groups are of
+	// about 6 metrics
+	value := strings.Repeat("x", 100)
+	buf, err := s.SerializeBatch(newMetrics(20, value))
+	require.NoError(t, err)
+
+	f := readFile(t, buf)
+	require.True(t, len(f.rowGroups) > 1)
+	for _, n := range f.rowGroups[:len(f.rowGroups)-1] {
+		require.True(t, n < 10, "%d rows", n)
+	}
+	requireRows(t, f, 20, value)
+}
+
+func TestPages(t *testing.T) {
+	s := newSerializer(t, &Serializer{Compression: "none"})
+
+	// the column chunks of the messages are written in several pages
+	value := strings.Repeat("x", 1000)
+	metrics := newMetrics(3000, value)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.True(t, len(buf) > pageSize)
+
+	f := readFile(t, buf)
+	require.Equal(t, []int{3000}, f.rowGroups)
+	requireRows(t, f, 3000, value)
+}
+
+func TestCompression(t *testing.T) {
+	for _, compression := range []string{"", "snappy", "gzip", "none"} {
+		t.Run(compression, func(t *testing.T) {
+			s := newSerializer(t, &Serializer{Compression: compression})
+
+			buf, err := s.SerializeBatch(newMetrics(10, "hello"))
+			require.NoError(t, err)
+
+			f := readFile(t, buf)
+			requireRows(t, f, 10, "hello")
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		serializer *Serializer
+	}{
+		{"rows", &Serializer{RowGroupRows: -1}},
+		{"size", &Serializer{RowGroupSize: -1}},
+		{"compression", &Serializer{Compression: "lz4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.serializer.Init())
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// The types of the thrift compact protocol.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes the structs of the Parquet metadata with the thrift
+// compact protocol.  The fields of each struct must be written in the
+// increasing order of their ID.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last holds the ID of the last field written of the open structs
+	last []int16
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+// begin starts a struct, either the top-level one or an element of a list.
+func (w *thriftWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+// end ends the last struct started.
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+func (w *thriftWriter) boolField(id int16, v bool) {
+	if v {
+		w.field(id, thriftTrue)
+	} else {
+		w.field(id, thriftFalse)
+	}
+}
+
+func (w *thriftWriter) byteField(id int16, v int8) {
+	w.field(id, thriftByte)
+	w.buf.WriteByte(byte(v))
+}
+
+func (w *thriftWriter) i32Field(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64Field(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) stringField(id int16, v string) {
+	w.field(id, thriftBinary)
+	w.string(v)
+}
+
+func (w *thriftWriter) listField(id int16, typ byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | typ)
+	} else {
+		w.buf.WriteByte(0xf0 | typ)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) i32(v int32) {
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) string(v string) {
+	w.varint(uint64(len(v)))
+	w.buf.WriteString(v)
+}
@@ -0,0 +1,358 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+
+	"github.com/golang/snappy"
+
+	"github.com/influxdata/telegraf"
+)
+
+// magic starts and ends the Parquet files.
+const magic = "PAR1"
+
+// pageSize is about the size of the data pages of the column chunks.
+const pageSize = 1024 * 1024
+
+// The compression codecs.
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+)
+
+// The physical types of the values.
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+)
+
+// The repetitions of the columns.
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+)
+
+// The converted types, the annotations of the types before the logical types.
+const (
+	convertedUTF8   = 0
+	convertedUint64 = 14
+)
+
+// The encodings of the values and of the definition levels.
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+const pageTypeData = 0
+
+// fileWriter writes a Parquet file one row group after the other, with a
+// data page of plain encoded values per column chunk of about pageSize.
+type fileWriter struct {
+	buf       bytes.Buffer
+	columns   []*column
+	codec     int32
+	rowGroups []rowGroup
+	numRows   int64
+}
+
+type rowGroup struct {
+	chunks    []columnChunk
+	numRows   int64
+	totalSize int64
+}
+
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+func (w *fileWriter) begin() {
+	w.buf.WriteString(magic)
+}
+
+// writeRowGroup writes the column chunks of the metrics.
+func (w *fileWriter) writeRowGroup(metrics []telegraf.Metric) error {
+	group := rowGroup{numRows: int64(len(metrics))}
+	for _, c := range w.columns {
+		chunk := columnChunk{
+			offset:    int64(w.buf.Len()),
+			numValues: int64(len(metrics)),
+		}
+
+		start := 0
+		var size int64
+		for i, metric := range metrics {
+			if v, ok := c.value(metric); ok {
+				size += valueSize(v)
+			}
+			if size >= pageSize || i == len(metrics)-1 {
+				if err := w.writePage(c, metrics[start:i+1], &chunk); err != nil {
+					return err
+				}
+				start = i + 1
+				size = 0
+			}
+		}
+
+		group.chunks = append(group.chunks, chunk)
+		group.totalSize += chunk.uncompressedSize
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += group.numRows
+	return nil
+}
+
+// writePage writes a data page of the values of the column for the metrics,
+// preceded by their definition levels when the column is optional.
+func (w *fileWriter) writePage(c *column, metrics []telegraf.Metric, chunk *columnChunk) error {
+	var levels []bool
+	var values []interface{}
+	for _, metric := range metrics {
+		v, ok := c.value(metric)
+		levels = append(levels, ok)
+		if ok {
+			values = append(values, v)
+		}
+	}
+
+	var page bytes.Buffer
+	if c.optional() {
+		rle := encodeLevels(levels)
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(len(rle)))
+		page.Write(length[:])
+		page.Write(rle)
+	}
+	encodeValues(&page, c.typ, values)
+
+	data, err := compress(w.codec, page.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var header thriftWriter
+	header.begin()
+	header.i32Field(1, pageTypeData)
+	header.i32Field(2, int32(page.Len()))
+	header.i32Field(3, int32(len(data)))
+	header.structField(5)
+	header.i32Field(1, int32(len(metrics)))
+	header.i32Field(2, encodingPlain)
+	header.i32Field(3, encodingRLE)
+	header.i32Field(4, encodingRLE)
+	header.end()
+	header.end()
+
+	chunk.uncompressedSize += int64(header.buf.Len() + page.Len())
+	chunk.compressedSize += int64(header.buf.Len() + len(data))
+	w.buf.Write(header.buf.Bytes())
+	w.buf.Write(data)
+	return nil
+}
+
+// encodeLevels encodes the definition levels, 1 for the values and 0 for the
+// nulls, in runs of the RLE hybrid encoding with a bit width of 1.
+func encodeLevels(levels []bool) []byte {
+	var buf []byte
+	var b [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		n := binary.PutUvarint(b[:], uint64(j-i)<<1)
+		buf = append(buf, b[:n]...)
+		if levels[i] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		i = j
+	}
+	return buf
+}
+
+// encodeValues writes the values with the plain encoding of their type.
+func encodeValues(buf *bytes.Buffer, typ valueType, values []interface{}) {
+	var b [8]byte
+	switch typ {
+	case typeBool:
+		packed := make([]byte, (len(values)+7)/8)
+		for i, v := range values {
+			if v.(bool) {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		buf.Write(packed)
+	case typeInt:
+		for _, v := range values {
+			binary.LittleEndian.PutUint64(b[:], uint64(v.(int64)))
+			buf.Write(b[:])
+		}
+	case typeUint:
+		for _, v := range values {
+			binary.LittleEndian.PutUint64(b[:], v.(uint64))
+			buf.Write(b[:])
+		}
+	case typeFloat:
+		for _, v := range values {
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.(float64)))
+			buf.Write(b[:])
+		}
+	case typeString:
+		for _, v := range values {
+			s := v.(string)
+			binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+			buf.Write(b[:4])
+			buf.WriteString(s)
+		}
+	}
+}
+
+func compress(codec int32, data []byte) ([]byte, error) {
+	switch codec {
+	case codecSnappy:
+		return snappy.Encode(nil, data), nil
+	case codecGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return data, nil
+	}
+}
+
+// end writes the metadata of the file after its row groups, and returns the
+// file.
+func (w *fileWriter) end() []byte {
+	var meta thriftWriter
+	meta.begin()
+	meta.i32Field(1, 1)
+
+	meta.listField(2, thriftStruct, len(w.columns)+1)
+	meta.begin()
+	meta.stringField(4, "schema")
+	meta.i32Field(5, int32(len(w.columns)))
+	meta.end()
+	for _, c := range w.columns {
+		writeSchemaElement(&meta, c)
+	}
+
+	meta.i64Field(3, w.numRows)
+
+	meta.listField(4, thriftStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		meta.begin()
+		meta.listField(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			writeColumnChunk(&meta, w.columns[i], chunk, w.codec)
+		}
+		meta.i64Field(2, group.totalSize)
+		meta.i64Field(3, group.numRows)
+		meta.end()
+	}
+
+	meta.stringField(6, "telegraf")
+	meta.end()
+
+	w.buf.Write(meta.buf.Bytes())
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.buf.Len()))
+	w.buf.Write(length[:])
+	w.buf.WriteString(magic)
+	return w.buf.Bytes()
+}
+
+func physicalType(typ valueType) int32 {
+	switch typ {
+	case typeBool:
+		return physicalBoolean
+	case typeInt, typeUint:
+		return physicalInt64
+	case typeFloat:
+		return physicalDouble
+	default:
+		return physicalByteArray
+	}
+}
+
+// writeSchemaElement writes the schema element of the column, with the
+// logical type of the strings, of the unsigned integers and of the time in
+// nanoseconds.
+func writeSchemaElement(w *thriftWriter, c *column) {
+	w.begin()
+	w.i32Field(1, physicalType(c.typ))
+	if c.optional() {
+		w.i32Field(3, repetitionOptional)
+	} else {
+		w.i32Field(3, repetitionRequired)
+	}
+	w.stringField(4, c.name)
+
+	switch {
+	case c.kind == columnTime:
+		w.structField(10)
+		w.structField(8)
+		w.boolField(1, true)
+		w.structField(2)
+		w.structField(3)
+		w.end()
+		w.end()
+		w.end()
+		w.end()
+	case c.typ == typeString:
+		w.i32Field(6, convertedUTF8)
+		w.structField(10)
+		w.structField(1)
+		w.end()
+		w.end()
+	case c.typ == typeUint:
+		w.i32Field(6, convertedUint64)
+		w.structField(10)
+		w.structField(10)
+		w.byteField(1, 64)
+		w.boolField(2, false)
+		w.end()
+		w.end()
+	}
+	w.end()
+}
+
+func writeColumnChunk(w *thriftWriter, c *column, chunk columnChunk, codec int32) {
+	w.begin()
+	w.i64Field(2, chunk.offset)
+	w.structField(3)
+	w.i32Field(1, physicalType(c.typ))
+	if c.optional() {
+		w.listField(2, thriftI32, 2)
+		w.i32(encodingPlain)
+		w.i32(encodingRLE)
+	} else {
+		w.listField(2, thriftI32, 1)
+		w.i32(encodingPlain)
+	}
+	w.listField(3, thriftBinary, 1)
+	w.string(c.name)
+	w.i32Field(4, codec)
+	w.i64Field(5, chunk.numValues)
+	w.i64Field(6, chunk.uncompressedSize)
+	w.i64Field(7, chunk.compressedSize)
+	w.i64Field(9, chunk.offset)
+	w.end()
+	w.end()
+}
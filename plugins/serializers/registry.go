@@ -9,7 +9,10 @@ import (
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+	"github.com/influxdata/telegraf/plugins/serializers/parquet"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/template"
 )
 
 // SerializerOutput is an interface for output plugins that are able to
@@ -33,6 +36,16 @@ type Serializer interface {
 	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
 }
 
+// BatchSerializer is a Serializer of a format that is only written as whole
+// batches, such as a file format, and cannot be streamed metric by metric:
+// its batches cannot be appended one to the other.
+type BatchSerializer interface {
+	Serializer
+
+	// BatchOnly marks the serializer as batch only.
+	BatchOnly()
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
@@ -52,6 +65,10 @@ type Config struct {
 	// Support unsigned integer output; influx format only
 	InfluxUintSupport bool
 
+	// Conversion of unsigned integers too large for a signed integer without
+	// unsigned integer support, "clamp" or "wrap"; influx format only
+	InfluxUintOverflow string
+
 	// Prefix to add to all measurements, only supports Graphite
 	Prefix string
 
@@ -64,6 +81,18 @@ type Config struct {
 
 	// Include HEC routing fields for splunkmetric output
 	HecRouting bool
+
+	// Templates of the metrics and of the batches, and terminator of the
+	// metrics; template format only
+	TemplateMetric         string
+	TemplateBatch          string
+	TemplateLineTerminator string
+
+	// Maximum number of metrics and size in bytes of the row groups, and
+	// compression of the pages; parquet format only
+	ParquetRowGroupRows int
+	ParquetRowGroupSize int64
+	ParquetCompression  string
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -79,6 +108,14 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewJsonSerializer(config.TimestampUnits)
 	case "splunkmetric":
 		serializer, err = NewSplunkmetricSerializer(config.HecRouting)
+	case "msgpack":
+		serializer, err = NewMsgpackSerializer()
+	case "template":
+		serializer, err = NewTemplateSerializer(config.TemplateMetric, config.TemplateBatch,
+			config.TemplateLineTerminator)
+	case "parquet":
+		serializer, err = NewParquetSerializer(config.ParquetRowGroupRows,
+			config.ParquetRowGroupSize, config.ParquetCompression)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -89,6 +126,34 @@ func NewJsonSerializer(timestampUnits time.Duration) (Serializer, error) {
 	return json.NewSerializer(timestampUnits)
 }
 
+func NewMsgpackSerializer() (Serializer, error) {
+	return msgpack.NewSerializer(), nil
+}
+
+func NewTemplateSerializer(metricTemplate, batchTemplate, lineTerminator string) (Serializer, error) {
+	s := &template.Serializer{
+		MetricTemplate: metricTemplate,
+		BatchTemplate:  batchTemplate,
+		LineTerminator: lineTerminator,
+	}
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func NewParquetSerializer(rowGroupRows int, rowGroupSize int64, compression string) (Serializer, error) {
+	s := &parquet.Serializer{
+		RowGroupRows: rowGroupRows,
+		RowGroupSize: rowGroupSize,
+		Compression:  compression,
+	}
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 func NewSplunkmetricSerializer(splunkmetric_hec_routing bool) (Serializer, error) {
 	return splunkmetric.NewSerializer(splunkmetric_hec_routing)
 }
@@ -104,10 +169,22 @@ func NewInfluxSerializerConfig(config *Config) (Serializer, error) {
 		typeSupport = typeSupport + influx.UintSupport
 	}
 
+	var conversion influx.UintConversion
+	switch config.InfluxUintOverflow {
+	case "", "clamp":
+		conversion = influx.UintClamp
+	case "wrap":
+		conversion = influx.UintWrap
+	default:
+		return nil, fmt.Errorf("invalid influx_uint_overflow %q, must be clamp or wrap",
+			config.InfluxUintOverflow)
+	}
+
 	s := influx.NewSerializer()
 	s.SetMaxLineBytes(config.InfluxMaxLineBytes)
 	s.SetFieldSortOrder(sort)
 	s.SetFieldTypeSupport(typeSupport)
+	s.SetUintConversion(conversion)
 	return s, nil
 }
 
@@ -0,0 +1,126 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Serializer renders the metrics with the text/template of the metrics, each
+// metric followed by the line terminator.  With a batch template, the batches
+// are rendered at once with it instead.
+type Serializer struct {
+	MetricTemplate string
+	BatchTemplate  string
+	LineTerminator string
+
+	metric *template.Template
+	batch  *template.Template
+}
+
+// Batch is the data of the batch template, the metrics and their rendering
+// by the metric template, without the line terminator.
+type Batch struct {
+	Metrics []telegraf.Metric
+	Lines   []string
+}
+
+func (s *Serializer) Init() error {
+	if s.MetricTemplate == "" {
+		return fmt.Errorf("no metric template")
+	}
+
+	var err error
+	if s.metric, err = template.New("metric").Funcs(funcs).Parse(s.MetricTemplate); err != nil {
+		return fmt.Errorf("invalid metric template: %v", err)
+	}
+	if s.BatchTemplate != "" {
+		if s.batch, err = template.New("batch").Funcs(funcs).Parse(s.BatchTemplate); err != nil {
+			return fmt.Errorf("invalid batch template: %v", err)
+		}
+	}
+
+	// the templates are rendered once, so the references to fields or
+	// methods not existing are reported at startup
+	m, _ := metric.New("cpu", map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage_idle": 99.5}, time.Unix(0, 0))
+	if _, err := s.SerializeBatch([]telegraf.Metric{m}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.render(&buf, metric); err != nil {
+		return nil, err
+	}
+	buf.WriteString(s.LineTerminator)
+	return buf.Bytes(), nil
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	if s.batch == nil {
+		for _, metric := range metrics {
+			if err := s.render(&buf, metric); err != nil {
+				return nil, err
+			}
+			buf.WriteString(s.LineTerminator)
+		}
+		return buf.Bytes(), nil
+	}
+
+	batch := &Batch{Metrics: metrics, Lines: make([]string, 0, len(metrics))}
+	var line bytes.Buffer
+	for _, metric := range metrics {
+		line.Reset()
+		if err := s.render(&line, metric); err != nil {
+			return nil, err
+		}
+		batch.Lines = append(batch.Lines, line.String())
+	}
+	if err := s.batch.Execute(&buf, batch); err != nil {
+		return nil, fmt.Errorf("could not render the batch template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Serializer) render(buf *bytes.Buffer, metric telegraf.Metric) error {
+	if err := s.metric.Execute(buf, metric); err != nil {
+		return fmt.Errorf("could not render the metric template: %v", err)
+	}
+	return nil
+}
+
+// funcs are the helper functions of the templates, named after the ones of
+// the sprig library.
+var funcs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+	"join":       func(sep string, a []string) string { return strings.Join(a, sep) },
+	"quote":      strconv.Quote,
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"toString": func(v interface{}) string { return fmt.Sprint(v) },
+	"date":     func(layout string, t time.Time) string { return t.UTC().Format(layout) },
+	"unix":     func(t time.Time) int64 { return t.Unix() },
+	"unixMilli": func(t time.Time) int64 {
+		return t.UnixNano() / int64(time.Millisecond)
+	},
+	"unixNano": func(t time.Time) int64 { return t.UnixNano() },
+}
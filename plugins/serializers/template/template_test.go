@@ -0,0 +1,80 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSerializer(t *testing.T, s *Serializer) *Serializer {
+	require.NoError(t, s.Init())
+	return s
+}
+
+func newMetric() telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{"host": "server01", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5, "usage_user": 2.5},
+		time.Unix(1500000000, 250000000))
+}
+
+func TestSerializeFieldIteration(t *testing.T) {
+	s := newSerializer(t, &Serializer{
+		MetricTemplate: `{{.Name | upper}}|{{index .Tags "host"}}` +
+			`{{range $k, $v := .Fields}}|{{$k}}={{$v}}{{end}}|{{unixMilli .Time}}`,
+		LineTerminator: "\r\n",
+	})
+
+	buf, err := s.Serialize(newMetric())
+	require.NoError(t, err)
+	require.Equal(t, "CPU|server01|usage_idle=91.5|usage_user=2.5|1500000000250\r\n", string(buf))
+}
+
+func TestSerializeKeyValue(t *testing.T) {
+	// a legacy format with a line per field
+	s := newSerializer(t, &Serializer{
+		MetricTemplate: `{{$m := .}}{{range $k, $v := .Fields}}` +
+			`{{date "2006-01-02T15:04:05Z" $m.Time}} {{replace "_" "." $k}}{{range $tk, $tv := $m.Tags}} {{$tk}}={{quote $tv}}{{end}} {{$v}}` + "\n" +
+			`{{end}}`,
+	})
+
+	buf, err := s.SerializeBatch([]telegraf.Metric{newMetric()})
+	require.NoError(t, err)
+	require.Equal(t, `2017-07-14T02:40:00Z usage.idle cpu="cpu0" host="server01" 91.5
+2017-07-14T02:40:00Z usage.user cpu="cpu0" host="server01" 2.5
+`, string(buf))
+}
+
+func TestSerializeBatchTemplate(t *testing.T) {
+	s := newSerializer(t, &Serializer{
+		MetricTemplate: `{"name":{{quote .Name}},"value":{{index .Fields "usage_idle" | default 0}}}`,
+		BatchTemplate:  `{"count":{{len .Metrics}},"metrics":[{{join "," .Lines}}]}`,
+		LineTerminator: "\n",
+	})
+
+	m := testutil.MustMetric("mem", nil, map[string]interface{}{"used": int64(1)}, time.Unix(0, 0))
+	buf, err := s.SerializeBatch([]telegraf.Metric{newMetric(), m})
+	require.NoError(t, err)
+	require.Equal(t, `{"count":2,"metrics":[{"name":"cpu","value":91.5},{"name":"mem","value":0}]}`, string(buf))
+
+	// the metrics alone are not wrapped
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"mem","value":0}`+"\n", string(buf))
+}
+
+func TestInitInvalid(t *testing.T) {
+	for _, s := range []*Serializer{
+		{},
+		{MetricTemplate: `{{.Name`},
+		{MetricTemplate: `{{.Name | nosuchfunc}}`},
+		{MetricTemplate: `{{.NoSuchMethod}}`},
+		{MetricTemplate: `{{.Name}}`, BatchTemplate: `{{range .Lines}}`},
+		{MetricTemplate: `{{.Name}}`, BatchTemplate: `{{.Metrics.Name}}`},
+	} {
+		require.Error(t, s.Init())
+	}
+}